@@ -0,0 +1,612 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Pagination of list endpoints
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// APIHandler serves the customer self-service billing API. Every route acts
+// on the organization of the tenant in the request context, so it must be
+// mounted behind tenant.Middleware resolving authenticated tenants:
+//
+//	GET    {prefix}/invoices
+//	GET    {prefix}/invoices/{id}
+//	GET    {prefix}/invoices/{id}/pdf
+//	GET    {prefix}/payments
+//	POST   {prefix}/payment-methods
+//	GET    {prefix}/subscriptions/{id}
+//	GET    {prefix}/subscriptions/{id}/upcoming-invoice
+//	GET    {prefix}/subscriptions/{id}/plan-changes
+//	POST   {prefix}/subscriptions/{id}/plan-changes
+//	DELETE {prefix}/subscriptions/{id}/plan-changes/scheduled
+//	GET    {prefix}/subscriptions/{id}/seats
+//	POST   {prefix}/subscriptions/{id}/seats
+//	POST   {prefix}/subscriptions/{id}/pause
+//	POST   {prefix}/subscriptions/{id}/resume
+//	POST   {prefix}/subscriptions/{id}/trial/extend
+//	POST   {prefix}/subscriptions/{id}/trial/convert
+//	POST   {prefix}/trials
+//	GET    {prefix}/entitlements
+//	GET    {prefix}/usage?start=...&end=...
+//	GET    {prefix}/usage/current
+//	GET    {prefix}/credit-notes
+//	GET    {prefix}/credit-notes/{id}
+//	GET    {prefix}/credit-notes/{id}/pdf
+//	GET    {prefix}/budget
+//	PUT    {prefix}/budget
+//	DELETE {prefix}/budget
+//	GET    {prefix}/statement?start=...&end=...&format=json|csv|pdf
+//
+// Mount it with http.StripPrefix, e.g.
+// mux.Handle("/api/v1/billing/", http.StripPrefix("/api/v1/billing", handler))
+type APIHandler struct {
+	invoiceService        *InvoiceService
+	paymentService        *PaymentService
+	subscriptionService   *SubscriptionService
+	metricsCollector      *MetricsCollector
+	creditNoteService     *CreditNoteService
+	pdfService            *PDFService
+	budgetService         *BudgetService
+	reconciliationService *ReconciliationService
+	mux                   *http.ServeMux
+}
+
+// NewAPIHandler creates a new billing API handler. Without a PDF service the
+// PDF routes are not served, without a budget service the budget routes, and
+// without a reconciliation service the statement route.
+func NewAPIHandler(
+	invoiceService *InvoiceService,
+	paymentService *PaymentService,
+	subscriptionService *SubscriptionService,
+	metricsCollector *MetricsCollector,
+	creditNoteService *CreditNoteService,
+	pdfService *PDFService,
+	budgetService *BudgetService,
+	reconciliationService *ReconciliationService,
+) *APIHandler {
+	h := &APIHandler{
+		invoiceService:        invoiceService,
+		paymentService:        paymentService,
+		subscriptionService:   subscriptionService,
+		metricsCollector:      metricsCollector,
+		creditNoteService:     creditNoteService,
+		pdfService:            pdfService,
+		budgetService:         budgetService,
+		reconciliationService: reconciliationService,
+		mux:                   http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("GET /invoices", h.listInvoices)
+	h.mux.HandleFunc("GET /invoices/{id}", h.getInvoice)
+	h.mux.HandleFunc("GET /payments", h.listPayments)
+	h.mux.HandleFunc("POST /payment-methods", h.attachPaymentMethod)
+	h.mux.HandleFunc("GET /subscriptions/{id}", h.getSubscription)
+	h.mux.HandleFunc("GET /subscriptions/{id}/upcoming-invoice", h.getUpcomingInvoice)
+	h.mux.HandleFunc("GET /subscriptions/{id}/plan-changes", h.listPlanChanges)
+	h.mux.HandleFunc("POST /subscriptions/{id}/plan-changes", h.changePlan)
+	h.mux.HandleFunc("DELETE /subscriptions/{id}/plan-changes/scheduled", h.cancelScheduledChange)
+	h.mux.HandleFunc("GET /subscriptions/{id}/seats", h.listSeatChanges)
+	h.mux.HandleFunc("POST /subscriptions/{id}/seats", h.changeQuantity)
+	h.mux.HandleFunc("POST /subscriptions/{id}/pause", h.pauseSubscription)
+	h.mux.HandleFunc("POST /subscriptions/{id}/resume", h.resumeSubscription)
+	h.mux.HandleFunc("POST /subscriptions/{id}/trial/extend", h.extendTrial)
+	h.mux.HandleFunc("POST /subscriptions/{id}/trial/convert", h.convertTrial)
+	h.mux.HandleFunc("POST /trials", h.startTrial)
+	h.mux.HandleFunc("GET /entitlements", h.getEntitlements)
+	h.mux.HandleFunc("GET /usage", h.getUsage)
+	h.mux.HandleFunc("GET /usage/current", h.getCurrentUsage)
+	h.mux.HandleFunc("GET /credit-notes", h.listCreditNotes)
+	h.mux.HandleFunc("GET /credit-notes/{id}", h.getCreditNote)
+	if pdfService != nil {
+		h.mux.HandleFunc("GET /invoices/{id}/pdf", h.getInvoicePDF)
+		h.mux.HandleFunc("GET /credit-notes/{id}/pdf", h.getCreditNotePDF)
+	}
+	if budgetService != nil {
+		h.mux.HandleFunc("GET /budget", h.getBudget)
+		h.mux.HandleFunc("PUT /budget", h.setBudget)
+		h.mux.HandleFunc("DELETE /budget", h.deleteBudget)
+	}
+	if reconciliationService != nil {
+		h.mux.HandleFunc("GET /statement", h.getStatement)
+	}
+
+	return h
+}
+
+// ServeHTTP implements http.Handler. Requests without a tenant are rejected.
+func (h *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, err := tenant.OrganizationID(r.Context()); err != nil {
+		writeAPIError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *APIHandler) listInvoices(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	invoices, err := h.invoiceService.ListInvoices(r.Context(), limit, offset)
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": invoices})
+}
+
+func (h *APIHandler) getInvoice(w http.ResponseWriter, r *http.Request) {
+	invoice, err := h.invoiceService.GetInvoice(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// Invoices of other organizations are reported as missing
+	organizationID, _ := tenant.OrganizationID(r.Context())
+	if invoice.OrganizationID.String() != organizationID {
+		writeAPIError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, invoice)
+}
+
+func (h *APIHandler) getInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	data, err := h.pdfService.GetInvoicePDF(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writePDF(w, data)
+}
+
+func (h *APIHandler) listPayments(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	payments, err := h.paymentService.ListPayments(r.Context(), limit, offset)
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": payments})
+}
+
+func (h *APIHandler) attachPaymentMethod(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PaymentMethodID string `json:"payment_method_id"`
+		SetAsDefault    bool   `json:"set_as_default"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.PaymentMethodID == "" {
+		writeAPIError(w, http.StatusBadRequest, "payment_method_id is required")
+		return
+	}
+
+	if err := h.paymentService.AttachPaymentMethod(r.Context(), req.PaymentMethodID, req.SetAsDefault); err != nil {
+		writeServiceError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) getSubscription(w http.ResponseWriter, r *http.Request) {
+	subscription, err := h.subscriptionService.GetSubscription(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+func (h *APIHandler) getUpcomingInvoice(w http.ResponseWriter, r *http.Request) {
+	// Only subscriptions of the tenant can be previewed
+	subscription, err := h.subscriptionService.GetSubscription(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	invoice, err := h.invoiceService.GetUpcomingInvoice(r.Context(), subscription.ID.String())
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, invoice)
+}
+
+func (h *APIHandler) listPlanChanges(w http.ResponseWriter, r *http.Request) {
+	changes, err := h.subscriptionService.ListPlanChanges(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": changes})
+}
+
+func (h *APIHandler) changePlan(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PlanID      string `json:"plan_id"`
+		AtPeriodEnd bool   `json:"at_period_end"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.PlanID == "" {
+		writeAPIError(w, http.StatusBadRequest, "plan_id is required")
+		return
+	}
+
+	change, err := h.subscriptionService.ChangePlan(r.Context(), r.PathValue("id"), req.PlanID, req.AtPeriodEnd)
+	if err != nil {
+		writeServiceError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, http.StatusCreated, change)
+}
+
+func (h *APIHandler) cancelScheduledChange(w http.ResponseWriter, r *http.Request) {
+	if err := h.subscriptionService.CancelScheduledChange(r.Context(), r.PathValue("id")); err != nil {
+		writeServiceError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) listSeatChanges(w http.ResponseWriter, r *http.Request) {
+	changes, err := h.subscriptionService.ListSeatChanges(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": changes})
+}
+
+func (h *APIHandler) changeQuantity(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Quantity int `json:"quantity"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Quantity < 1 {
+		writeAPIError(w, http.StatusBadRequest, "quantity must be at least 1")
+		return
+	}
+
+	change, err := h.subscriptionService.ChangeQuantity(r.Context(), r.PathValue("id"), req.Quantity)
+	if err != nil {
+		writeServiceError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, http.StatusCreated, change)
+}
+
+func (h *APIHandler) pauseSubscription(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ResumesAt *time.Time `json:"resumes_at"`
+	}
+	if r.ContentLength != 0 && !decodeJSON(w, r, &req) {
+		return
+	}
+
+	subscription, err := h.subscriptionService.PauseSubscription(r.Context(), r.PathValue("id"), req.ResumesAt)
+	if err != nil {
+		writeServiceError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+func (h *APIHandler) resumeSubscription(w http.ResponseWriter, r *http.Request) {
+	subscription, err := h.subscriptionService.ResumeSubscription(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+func (h *APIHandler) startTrial(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PlanID   string `json:"plan_id"`
+		Quantity int    `json:"quantity"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.PlanID == "" {
+		writeAPIError(w, http.StatusBadRequest, "plan_id is required")
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	subscription, err := h.subscriptionService.StartTrial(r.Context(), req.PlanID, req.Quantity)
+	if err != nil {
+		writeServiceError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, http.StatusCreated, subscription)
+}
+
+func (h *APIHandler) extendTrial(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Days int `json:"days"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Days < 1 {
+		writeAPIError(w, http.StatusBadRequest, "days must be at least 1")
+		return
+	}
+
+	subscription, err := h.subscriptionService.ExtendTrial(r.Context(), r.PathValue("id"), req.Days)
+	if err != nil {
+		writeServiceError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+func (h *APIHandler) convertTrial(w http.ResponseWriter, r *http.Request) {
+	subscription, err := h.subscriptionService.ConvertTrial(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+func (h *APIHandler) getEntitlements(w http.ResponseWriter, r *http.Request) {
+	entitlements, err := h.subscriptionService.GetEntitlements(r.Context())
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, entitlements)
+}
+
+func (h *APIHandler) getUsage(w http.ResponseWriter, r *http.Request) {
+	start, err := time.Parse(time.RFC3339, r.URL.Query().Get("start"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "start must be an RFC 3339 timestamp")
+		return
+	}
+	end, err := time.Parse(time.RFC3339, r.URL.Query().Get("end"))
+	if err != nil || !end.After(start) {
+		writeAPIError(w, http.StatusBadRequest, "end must be an RFC 3339 timestamp after start")
+		return
+	}
+
+	organizationID, _ := tenant.OrganizationID(r.Context())
+	usage, err := h.metricsCollector.GetUsageForPeriod(r.Context(), organizationID, start, end)
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"period_start": usage.PeriodStart,
+		"period_end":   usage.PeriodEnd,
+		"metrics":      usage.Metrics,
+	})
+}
+
+func (h *APIHandler) getCurrentUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := h.metricsCollector.GetCurrentUsage(r.Context())
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"metrics": usage})
+}
+
+func (h *APIHandler) listCreditNotes(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	creditNotes, err := h.creditNoteService.ListCreditNotes(r.Context(), limit, offset)
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": creditNotes})
+}
+
+func (h *APIHandler) getCreditNote(w http.ResponseWriter, r *http.Request) {
+	creditNote, err := h.creditNoteService.GetCreditNote(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, creditNote)
+}
+
+func (h *APIHandler) getCreditNotePDF(w http.ResponseWriter, r *http.Request) {
+	data, err := h.pdfService.GetCreditNotePDF(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writePDF(w, data)
+}
+
+func (h *APIHandler) getBudget(w http.ResponseWriter, r *http.Request) {
+	status, err := h.budgetService.GetBudgetStatus(r.Context())
+	if err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (h *APIHandler) setBudget(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MonthlyAmount decimal.Decimal `json:"monthly_amount"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !req.MonthlyAmount.IsPositive() {
+		writeAPIError(w, http.StatusBadRequest, "monthly_amount must be positive")
+		return
+	}
+
+	budget, err := h.budgetService.SetBudget(r.Context(), req.MonthlyAmount)
+	if err != nil {
+		writeServiceError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, http.StatusOK, budget)
+}
+
+func (h *APIHandler) deleteBudget(w http.ResponseWriter, r *http.Request) {
+	if err := h.budgetService.DeleteBudget(r.Context()); err != nil {
+		writeServiceError(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) getStatement(w http.ResponseWriter, r *http.Request) {
+	start, err := time.Parse(time.RFC3339, r.URL.Query().Get("start"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "start must be an RFC 3339 timestamp")
+		return
+	}
+	end, err := time.Parse(time.RFC3339, r.URL.Query().Get("end"))
+	if err != nil || !end.After(start) {
+		writeAPIError(w, http.StatusBadRequest, "end must be an RFC 3339 timestamp after start")
+		return
+	}
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		statement, err := h.reconciliationService.GetStatement(r.Context(), start, end)
+		if err != nil {
+			writeServiceError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, statement)
+	case "csv":
+		data, err := h.reconciliationService.GetStatementCSV(r.Context(), start, end)
+		if err != nil {
+			writeServiceError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeCSV(w, data)
+	case "pdf":
+		if h.pdfService == nil {
+			writeAPIError(w, http.StatusBadRequest, "PDF statements are not available")
+			return
+		}
+		data, err := h.reconciliationService.GetStatementPDF(r.Context(), start, end)
+		if err != nil {
+			writeServiceError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writePDF(w, data)
+	default:
+		writeAPIError(w, http.StatusBadRequest, "format must be json, csv or pdf")
+	}
+}
+
+// pagination parses the limit and offset query parameters
+func pagination(w http.ResponseWriter, r *http.Request) (limit, offset int, ok bool) {
+	limit, offset = defaultPageSize, 0
+
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxPageSize {
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageSize))
+			return 0, 0, false
+		}
+		limit = v
+	}
+
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			writeAPIError(w, http.StatusBadRequest, "offset must not be negative")
+			return 0, 0, false
+		}
+		offset = v
+	}
+
+	return limit, offset, true
+}
+
+// decodeJSON decodes a JSON request body, writing a 400 response on failure
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// writeServiceError maps a service error to a response. Missing records are
+// reported as 404 and missing tenants as 401; other errors use status.
+func writeServiceError(w http.ResponseWriter, err error, status int) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		writeAPIError(w, http.StatusNotFound, "not found")
+	case errors.Is(err, tenant.ErrMissingTenant):
+		writeAPIError(w, http.StatusUnauthorized, "authentication required")
+	case status >= http.StatusInternalServerError:
+		// Internal errors are not exposed to customers
+		writeAPIError(w, status, http.StatusText(status))
+	default:
+		writeAPIError(w, status, err.Error())
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writePDF(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
+
+func writeCSV(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,337 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BillingRunService invoices the subscriptions whose billing period ended and
+// starts their next period. Each period is invoiced once, so failed runs can
+// be repeated safely.
+type BillingRunService struct {
+	db             *gorm.DB
+	config         *Config
+	subscriptions  SubscriptionRepo
+	invoiceService *InvoiceService
+	eventPublisher *BillingEventPublisher
+}
+
+// NewBillingRunService creates a new billing run service. The event publisher
+// is optional.
+func NewBillingRunService(
+	db *gorm.DB,
+	config *Config,
+	repos *Repositories,
+	invoiceService *InvoiceService,
+	eventPublisher *BillingEventPublisher,
+) *BillingRunService {
+	return &BillingRunService{
+		db:             db,
+		config:         config,
+		subscriptions:  repos.Subscriptions,
+		invoiceService: invoiceService,
+		eventPublisher: eventPublisher,
+	}
+}
+
+// ProcessDue performs a billing run and fails when a subscription could not
+// be invoiced
+func (bs *BillingRunService) ProcessDue(ctx context.Context) error {
+	run, err := bs.Run(ctx)
+	if err != nil {
+		return err
+	}
+	if run.Failed > 0 {
+		return fmt.Errorf("billing run %s failed to invoice %d subscriptions", run.ID, run.Failed)
+	}
+	return nil
+}
+
+// Run invoices the active and past due subscriptions whose period ended
+// before the run started, in batches. Subscriptions are retried within the
+// run; the outcome of each is recorded with the run. Subscriptions more than
+// one period behind are invoiced one period per run.
+func (bs *BillingRunService) Run(ctx context.Context) (*models.BillingRun, error) {
+	now := time.Now()
+	run := &models.BillingRun{
+		ID:        uuid.New(),
+		Status:    string(BillingRunStatusRunning),
+		Cutoff:    now,
+		StartedAt: now,
+	}
+	if err := bs.db.WithContext(ctx).Create(run).Error; err != nil {
+		return nil, fmt.Errorf("failed to create billing run: %w", err)
+	}
+
+	runErr := bs.processBatches(ctx, run)
+
+	completedAt := time.Now()
+	run.CompletedAt = &completedAt
+	switch {
+	case runErr != nil:
+		run.Status = string(BillingRunStatusFailed)
+		run.Error = runErr.Error()
+	case run.Failed > 0:
+		run.Status = string(BillingRunStatusCompletedWithErrors)
+	default:
+		run.Status = string(BillingRunStatusCompleted)
+	}
+
+	if err := bs.db.WithContext(ctx).Model(run).Updates(map[string]interface{}{
+		"status":       run.Status,
+		"error":        run.Error,
+		"completed_at": completedAt,
+	}).Error; err != nil {
+		return run, errors.Join(runErr, fmt.Errorf("failed to update billing run: %w", err))
+	}
+
+	return run, runErr
+}
+
+// processBatches invoices the due subscriptions in batches ordered by ID
+func (bs *BillingRunService) processBatches(ctx context.Context, run *models.BillingRun) error {
+	lastID := uuid.Nil
+	for {
+		subscriptions, err := bs.subscriptions.ListDue(ctx, run.Cutoff, lastID, bs.config.BillingRuns.BatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to fetch due subscriptions: %w", err)
+		}
+		if len(subscriptions) == 0 {
+			return nil
+		}
+
+		items := make([]models.BillingRunItem, 0, len(subscriptions))
+		for i := range subscriptions {
+			item := bs.processSubscription(ctx, run, &subscriptions[i])
+			items = append(items, item)
+
+			run.SubscriptionsDue++
+			switch BillingRunItemStatus(item.Status) {
+			case BillingRunItemStatusInvoiced:
+				run.InvoicesCreated++
+			case BillingRunItemStatusAlreadyInvoiced:
+				run.AlreadyInvoiced++
+			default:
+				run.Failed++
+			}
+		}
+
+		err = bs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&items).Error; err != nil {
+				return fmt.Errorf("failed to record billing run items: %w", err)
+			}
+			if err := tx.Model(run).Updates(map[string]interface{}{
+				"subscriptions_due": run.SubscriptionsDue,
+				"invoices_created":  run.InvoicesCreated,
+				"already_invoiced":  run.AlreadyInvoiced,
+				"failed":            run.Failed,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to update billing run: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		lastID = subscriptions[len(subscriptions)-1].ID
+	}
+}
+
+// processSubscription invoices the current period of a subscription,
+// retrying failures with exponential backoff
+func (bs *BillingRunService) processSubscription(
+	ctx context.Context,
+	run *models.BillingRun,
+	subscription *models.Subscription,
+) models.BillingRunItem {
+	item := models.BillingRunItem{
+		ID:             uuid.New(),
+		BillingRunID:   run.ID,
+		SubscriptionID: subscription.ID,
+		PeriodStart:    subscription.CurrentPeriodStart,
+		PeriodEnd:      subscription.CurrentPeriodEnd,
+	}
+	orgCtx := tenant.WithOrganization(ctx, subscription.OrganizationID.String())
+
+	var err error
+	delay := bs.config.BillingRuns.RetryDelay
+	for attempt := 1; attempt <= bs.config.BillingRuns.MaxAttempts; attempt++ {
+		item.Attempts = attempt
+
+		var invoiced *invoicedPeriod
+		invoiced, err = bs.invoiceSubscription(orgCtx, subscription)
+		if err == nil {
+			item.InvoiceID = &invoiced.invoice.ID
+			if invoiced.advance != nil {
+				item.AdvanceInvoiceID = &invoiced.advance.ID
+			}
+			item.Status = string(BillingRunItemStatusAlreadyInvoiced)
+			if invoiced.created || invoiced.advanceCreated {
+				item.Status = string(BillingRunItemStatusInvoiced)
+			}
+
+			// The invoices exist, so events that fail to publish are recorded
+			// rather than retried
+			if notifyErr := bs.notify(orgCtx, subscription, invoiced); notifyErr != nil {
+				item.Error = notifyErr.Error()
+			}
+			return item
+		}
+
+		if attempt == bs.config.BillingRuns.MaxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			err = errors.Join(err, ctx.Err())
+			attempt = bs.config.BillingRuns.MaxAttempts
+		case <-time.After(delay):
+			delay *= 2
+		}
+	}
+
+	item.Status = string(BillingRunItemStatusFailed)
+	item.Error = err.Error()
+	return item
+}
+
+// invoicedPeriod holds the invoices of a billing period of a subscription and
+// whether they were created by the billing run
+type invoicedPeriod struct {
+	invoice        *models.Invoice
+	created        bool
+	advance        *models.Invoice // Invoice of the next period, for plans billed in advance
+	advanceCreated bool
+}
+
+// invoiceSubscription invoices the current period of a subscription and
+// starts its next period, or cancels it at the end of the period. The next
+// period of plans billed in advance is invoiced as it starts.
+func (bs *BillingRunService) invoiceSubscription(
+	ctx context.Context,
+	subscription *models.Subscription,
+) (*invoicedPeriod, error) {
+	invoice, created, err := bs.invoiceService.generatePeriodInvoice(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	invoiced := &invoicedPeriod{invoice: invoice, created: created}
+
+	// Subscriptions changed since they were fetched are left alone
+	periodEnd := subscription.CurrentPeriodEnd
+	if subscription.CancelAtPeriodEnd {
+		canceledAt := time.Now()
+		canceled, err := bs.subscriptions.CancelAfterPeriod(ctx, subscription.ID, periodEnd, canceledAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to advance subscription period: %w", err)
+		}
+		if canceled {
+			subscription.Status = string(SubscriptionStatusCanceled)
+			subscription.CanceledAt = &canceledAt
+		}
+	} else {
+		next := nextPeriodEnd(periodEnd, subscription.Plan.BillingInterval)
+		if _, err := bs.subscriptions.StartNextPeriod(ctx, subscription.ID, periodEnd, next); err != nil {
+			return nil, fmt.Errorf("failed to advance subscription period: %w", err)
+		}
+	}
+
+	if BillingMode(subscription.Plan.BillingMode) == BillingModeAdvance {
+		// The period may have been advanced by an earlier attempt, so the next
+		// period is read back rather than derived
+		next, err := bs.subscriptions.Get(ctx, subscription.ID.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch subscription: %w", err)
+		}
+
+		billable := next.Status == string(SubscriptionStatusActive) || next.Status == string(SubscriptionStatusPastDue)
+		if billable && next.CurrentPeriodStart.Equal(periodEnd) &&
+			BillingMode(next.Plan.BillingMode) == BillingModeAdvance {
+			invoiced.advance, invoiced.advanceCreated, err = bs.invoiceService.generateAdvanceInvoice(ctx, next)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return invoiced, nil
+}
+
+// notify publishes the events of an invoiced subscription
+func (bs *BillingRunService) notify(
+	ctx context.Context,
+	subscription *models.Subscription,
+	invoiced *invoicedPeriod,
+) error {
+	if bs.eventPublisher == nil {
+		return nil
+	}
+
+	if invoiced.created {
+		if err := bs.eventPublisher.PublishInvoiceCreated(ctx, invoiced.invoice); err != nil {
+			return fmt.Errorf("failed to publish invoice created event: %w", err)
+		}
+	}
+	if invoiced.advanceCreated {
+		if err := bs.eventPublisher.PublishInvoiceCreated(ctx, invoiced.advance); err != nil {
+			return fmt.Errorf("failed to publish invoice created event: %w", err)
+		}
+	}
+
+	if subscription.Status == string(SubscriptionStatusCanceled) {
+		if err := bs.eventPublisher.PublishSubscriptionCanceled(ctx, subscription); err != nil {
+			return fmt.Errorf("failed to publish subscription canceled event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetBillingRun retrieves a billing run with its items
+func (bs *BillingRunService) GetBillingRun(ctx context.Context, runID string) (*models.BillingRun, error) {
+	var run models.BillingRun
+	if err := bs.db.WithContext(ctx).
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		First(&run, "id = ?", runID).Error; err != nil {
+		return nil, err
+	}
+
+	return &run, nil
+}
+
+// ListBillingRuns retrieves the history of billing runs, newest first
+func (bs *BillingRunService) ListBillingRuns(
+	ctx context.Context,
+	limit, offset int,
+) ([]models.BillingRun, error) {
+	var runs []models.BillingRun
+
+	query := bs.db.WithContext(ctx).Order("started_at DESC")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	err := query.Find(&runs).Error
+	return runs, err
+}
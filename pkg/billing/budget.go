@@ -0,0 +1,560 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BudgetService manages the monthly spend budgets of organizations. It
+// estimates the spend of the current calendar month in the organization's
+// timezone, forecasts it to the end of the month from the daily spend trend
+// and alerts organizations when their spend reaches Budgets.AlertThresholds.
+//
+// Spend is estimated before taxes, credits and discounts: the plan price of
+// every billable subscription accrues evenly over its billing period, and
+// included usage is spread evenly over the days of the period.
+type BudgetService struct {
+	db                  *gorm.DB
+	config              *Config
+	pricingEngine       *PricingEngine
+	notificationService *NotificationService
+	eventPublisher      *BillingEventPublisher
+}
+
+// NewBudgetService creates a new budget service. The notification service and
+// event publisher are optional.
+func NewBudgetService(
+	db *gorm.DB,
+	config *Config,
+	pricingEngine *PricingEngine,
+	notificationService *NotificationService,
+	eventPublisher *BillingEventPublisher,
+) *BudgetService {
+	return &BudgetService{
+		db:                  db,
+		config:              config,
+		pricingEngine:       pricingEngine,
+		notificationService: notificationService,
+		eventPublisher:      eventPublisher,
+	}
+}
+
+// GetBudget retrieves the budget of the organization of the tenant in ctx
+func (bs *BudgetService) GetBudget(ctx context.Context) (*models.Budget, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var budget models.Budget
+	if err := bs.db.WithContext(ctx).
+		Where("organization_id = ?", organizationID).
+		First(&budget).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch budget: %w", err)
+	}
+	return &budget, nil
+}
+
+// SetBudget sets the monthly budget of the organization of the tenant in ctx,
+// in the organization's currency
+func (bs *BudgetService) SetBudget(ctx context.Context, monthlyAmount decimal.Decimal) (*models.Budget, error) {
+	if !monthlyAmount.IsPositive() {
+		return nil, fmt.Errorf("monthly budget must be positive")
+	}
+
+	organization, err := bs.organization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	budget := &models.Budget{
+		ID:             uuid.New(),
+		OrganizationID: organization.ID,
+		MonthlyAmount:  monthlyAmount.Round(2),
+		Currency:       organization.Currency,
+	}
+	if err := bs.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "organization_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"monthly_amount", "currency", "updated_at"}),
+		}).
+		Create(budget).Error; err != nil {
+		return nil, fmt.Errorf("failed to save budget: %w", err)
+	}
+
+	return bs.GetBudget(ctx)
+}
+
+// DeleteBudget removes the budget of the organization of the tenant in ctx
+func (bs *BudgetService) DeleteBudget(ctx context.Context) error {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := bs.db.WithContext(ctx).
+		Where("organization_id = ?", organizationID).
+		Delete(&models.Budget{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete budget: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to delete budget: %w", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// GetBudgetStatus computes the spend of the organization of the tenant in ctx
+// in the current month, with its forecast and daily breakdown. Organizations
+// without a budget get the spend and forecast only.
+func (bs *BudgetService) GetBudgetStatus(ctx context.Context) (*BudgetStatus, error) {
+	organization, err := bs.organization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var budget *models.Budget
+	var found models.Budget
+	err = bs.db.WithContext(ctx).
+		Where("organization_id = ?", organization.ID).
+		First(&found).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("failed to fetch budget: %w", err)
+	default:
+		budget = &found
+	}
+
+	return bs.budgetStatus(ctx, organization, budget, time.Now())
+}
+
+// CheckBudgets alerts the organizations whose spend in the current month
+// reached a threshold of their budget. Each threshold is alerted once per
+// month; thresholds passed at once are alerted together.
+func (bs *BudgetService) CheckBudgets(ctx context.Context) error {
+	var budgets []models.Budget
+	if err := bs.db.WithContext(ctx).Find(&budgets).Error; err != nil {
+		return fmt.Errorf("failed to fetch budgets: %w", err)
+	}
+
+	var errs []error
+	for i := range budgets {
+		orgCtx := tenant.WithOrganization(ctx, budgets[i].OrganizationID.String())
+		if err := bs.checkBudget(orgCtx, &budgets[i]); err != nil {
+			errs = append(errs, fmt.Errorf("budget %s: %w", budgets[i].ID, err))
+		}
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkBudget alerts the thresholds of a budget reached this month
+func (bs *BudgetService) checkBudget(ctx context.Context, budget *models.Budget) error {
+	organization, err := bs.organization(ctx)
+	if err != nil {
+		return err
+	}
+
+	status, err := bs.budgetStatus(ctx, organization, budget, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var reached []int
+	for _, threshold := range bs.config.Budgets.AlertThresholds {
+		limit := budget.MonthlyAmount.Mul(decimal.NewFromInt(int64(threshold))).Div(decimal.NewFromInt(100))
+		if status.CurrentSpend.GreaterThanOrEqual(limit) {
+			reached = append(reached, threshold)
+		}
+	}
+	if len(reached) == 0 {
+		return nil
+	}
+
+	month := status.MonthStart.Format("2006-01")
+
+	// Alerts are recorded with the notification, so failed notifications are
+	// retried on the next check
+	return bs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var alerted []int
+		if err := tx.Model(&models.BudgetAlert{}).
+			Where("budget_id = ? AND month = ?", budget.ID, month).
+			Pluck("threshold", &alerted).Error; err != nil {
+			return fmt.Errorf("failed to fetch budget alerts: %w", err)
+		}
+		done := make(map[int]bool, len(alerted))
+		for _, threshold := range alerted {
+			done[threshold] = true
+		}
+
+		var alerts []models.BudgetAlert
+		for _, threshold := range reached {
+			if done[threshold] {
+				continue
+			}
+			alerts = append(alerts, models.BudgetAlert{
+				ID:             uuid.New(),
+				BudgetID:       budget.ID,
+				OrganizationID: budget.OrganizationID,
+				Month:          month,
+				Threshold:      threshold,
+				Spend:          status.CurrentSpend,
+				Budget:         budget.MonthlyAmount,
+			})
+		}
+		if len(alerts) == 0 {
+			return nil
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&alerts)
+		if result.Error != nil {
+			return fmt.Errorf("failed to record budget alerts: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			// Alerted concurrently
+			return nil
+		}
+
+		return bs.notify(ctx, budget, status, alerts[len(alerts)-1].Threshold)
+	})
+}
+
+// notify sends the notification and event of the highest threshold reached
+func (bs *BudgetService) notify(
+	ctx context.Context,
+	budget *models.Budget,
+	status *BudgetStatus,
+	threshold int,
+) error {
+	if bs.notificationService != nil {
+		if err := bs.notificationService.SendBudgetThresholdNotification(ctx, budget, status, threshold); err != nil {
+			return fmt.Errorf("failed to send budget threshold notification: %w", err)
+		}
+	}
+	if bs.eventPublisher != nil {
+		if err := bs.eventPublisher.PublishBudgetThresholdReached(ctx, budget, status, threshold); err != nil {
+			return fmt.Errorf("failed to publish budget threshold reached event: %w", err)
+		}
+	}
+	return nil
+}
+
+// budgetStatus computes the spend of an organization in the month of now
+func (bs *BudgetService) budgetStatus(
+	ctx context.Context,
+	organization *models.Organization,
+	budget *models.Budget,
+	now time.Time,
+) (*BudgetStatus, error) {
+	loc, err := time.LoadLocation(organization.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+
+	// The history covers the lookback window and the month so far
+	historyStart := today.AddDate(0, 0, -bs.config.Budgets.ForecastLookback)
+	if monthStart.Before(historyStart) {
+		historyStart = monthStart
+	}
+
+	spend, err := bs.dailySpend(ctx, organization.ID, historyStart, now)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &BudgetStatus{
+		MonthStart:     monthStart,
+		MonthEnd:       monthEnd,
+		Currency:       organization.Currency,
+		CurrentSpend:   decimal.Zero,
+		ForecastMethod: ForecastMethodLinear,
+	}
+
+	// Complete days of the lookback window, oldest first
+	lookback := bs.config.Budgets.ForecastLookback
+	history := spend[len(spend)-1-lookback : len(spend)-1]
+	var weekdays []time.Weekday
+	for i := range history {
+		weekdays = append(weekdays, today.AddDate(0, 0, i-len(history)).Weekday())
+	}
+	trend, factors, method := fitSpendForecast(history, weekdays, bs.config.Budgets.SeasonalMinHistory)
+	status.ForecastMethod = method
+
+	project := func(day time.Time) decimal.Decimal {
+		x := float64(len(history)) + day.Sub(today).Hours()/24
+		value := trend.at(x)
+		if factors != nil {
+			value *= factors[day.Weekday()]
+		}
+		if value < 0 {
+			value = 0
+		}
+		return decimal.NewFromFloat(value)
+	}
+
+	forecast := decimal.Zero
+	for day := monthStart; day.Before(monthEnd); day = day.AddDate(0, 0, 1) {
+		index := len(spend) - 1 - int(today.Sub(day).Hours()/24+0.5)
+		switch {
+		case day.Before(today):
+			status.CurrentSpend = status.CurrentSpend.Add(spend[index])
+			status.Daily = append(status.Daily, DailySpend{Date: day.Format("2006-01-02"), Amount: spend[index].Round(2)})
+		case day.Equal(today):
+			// Today is observed so far and forecast for the rest of the day
+			remaining := 1 - now.Sub(today).Hours()/24
+			rest := project(day).Mul(decimal.NewFromFloat(remaining))
+			status.CurrentSpend = status.CurrentSpend.Add(spend[index])
+			forecast = forecast.Add(rest)
+			status.Daily = append(status.Daily, DailySpend{Date: day.Format("2006-01-02"), Amount: spend[index].Add(rest).Round(2), Forecast: true})
+		default:
+			amount := project(day)
+			forecast = forecast.Add(amount)
+			status.Daily = append(status.Daily, DailySpend{Date: day.Format("2006-01-02"), Amount: amount.Round(2), Forecast: true})
+		}
+	}
+
+	status.CurrentSpend = status.CurrentSpend.Round(2)
+	status.ForecastSpend = status.CurrentSpend.Add(forecast).Round(2)
+
+	if budget != nil {
+		hundred := decimal.NewFromInt(100)
+		percentUsed := status.CurrentSpend.Mul(hundred).Div(budget.MonthlyAmount).Round(2)
+		forecastPercent := status.ForecastSpend.Mul(hundred).Div(budget.MonthlyAmount).Round(2)
+		status.Budget = &budget.MonthlyAmount
+		status.PercentUsed = &percentUsed
+		status.ForecastPercent = &forecastPercent
+	}
+
+	return status, nil
+}
+
+// dailySpend estimates the spend of an organization per day from the day of
+// start to now; the last day is the spend of today so far
+func (bs *BudgetService) dailySpend(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	start, now time.Time,
+) ([]decimal.Decimal, error) {
+	dayOf := func(t time.Time) int {
+		t = t.In(start.Location())
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, start.Location())
+		return int(day.Sub(start).Hours()/24 + 0.5)
+	}
+	days := dayOf(now) + 1
+
+	var subscriptions []models.Subscription
+	if err := bs.db.WithContext(ctx).
+		Preload("Plan").
+		Where("organization_id = ?", organizationID).
+		Where("status IN ?", []SubscriptionStatus{SubscriptionStatusActive, SubscriptionStatusPastDue}).
+		Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch subscriptions: %w", err)
+	}
+
+	var metrics []models.UsageMetric
+	if err := bs.db.WithContext(ctx).
+		Where("organization_id = ?", organizationID).
+		Where("period_start >= ? AND period_start < ?", start, now).
+		Find(&metrics).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch usage metrics: %w", err)
+	}
+
+	// Usage per subscription and day
+	usage := make(map[uuid.UUID][]map[MetricType]decimal.Decimal)
+	for _, metric := range metrics {
+		day := dayOf(metric.PeriodStart)
+		if day < 0 || day >= days {
+			continue
+		}
+		if usage[metric.SubscriptionID] == nil {
+			usage[metric.SubscriptionID] = make([]map[MetricType]decimal.Decimal, days)
+		}
+		if usage[metric.SubscriptionID][day] == nil {
+			usage[metric.SubscriptionID][day] = make(map[MetricType]decimal.Decimal)
+		}
+		metricType := MetricType(metric.MetricType)
+		usage[metric.SubscriptionID][day][metricType] = usage[metric.SubscriptionID][day][metricType].Add(metric.MetricValue)
+	}
+
+	spend := make([]decimal.Decimal, days)
+	for i := range spend {
+		spend[i] = decimal.Zero
+	}
+	todayFraction := decimal.NewFromFloat(now.Sub(start.AddDate(0, 0, days-1)).Hours() / 24)
+
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		periodDays := decimal.NewFromFloat(subscription.CurrentPeriodEnd.Sub(subscription.CurrentPeriodStart).Hours() / 24)
+		if !periodDays.IsPositive() {
+			continue
+		}
+
+		// The plan price accrues daily from the day the subscription started
+		fixed := bs.pricingEngine.EstimateMonthlyCharge(&subscription.Plan, subscription.Quantity, nil)
+		fixedPerDay := fixed.Div(periodDays)
+		first := dayOf(subscription.CreatedAt)
+		if first < 0 {
+			first = 0
+		}
+		for day := first; day < days; day++ {
+			if day == days-1 {
+				spend[day] = spend[day].Add(fixedPerDay.Mul(todayFraction))
+			} else {
+				spend[day] = spend[day].Add(fixedPerDay)
+			}
+		}
+
+		// Daily usage is priced as if it continued for the whole period
+		for day, dayUsage := range usage[subscription.ID] {
+			if dayUsage == nil {
+				continue
+			}
+			scaled := make(map[MetricType]decimal.Decimal, len(dayUsage))
+			for metricType, value := range dayUsage {
+				scaled[metricType] = value.Mul(periodDays)
+			}
+			charge := bs.pricingEngine.EstimateMonthlyCharge(&subscription.Plan, subscription.Quantity, scaled)
+			spend[day] = spend[day].Add(charge.Sub(fixed).Div(periodDays))
+		}
+	}
+
+	return spend, nil
+}
+
+// organization retrieves the organization of the tenant in ctx
+func (bs *BudgetService) organization(ctx context.Context) (*models.Organization, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var organization models.Organization
+	if err := bs.db.WithContext(ctx).
+		First(&organization, "id = ?", organizationID).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch organization: %w", err)
+	}
+	return &organization, nil
+}
+
+// spendTrend is a linear trend of daily spend
+type spendTrend struct {
+	intercept, slope float64
+}
+
+// at returns the trend value of day x
+func (t spendTrend) at(x float64) float64 {
+	return t.intercept + t.slope*x
+}
+
+// fitSpendForecast fits a least squares trend to the daily spend history,
+// oldest first. With at least seasonalMinHistory days, the trend is fitted to
+// the spend adjusted by day of week factors, which are returned for the
+// forecast; otherwise the factors are nil.
+func fitSpendForecast(
+	history []decimal.Decimal,
+	weekdays []time.Weekday,
+	seasonalMinHistory int,
+) (spendTrend, map[time.Weekday]float64, ForecastMethod) {
+	values := make([]float64, len(history))
+	for i, amount := range history {
+		values[i] = amount.InexactFloat64()
+	}
+
+	var factors map[time.Weekday]float64
+	method := ForecastMethodLinear
+	if len(values) >= seasonalMinHistory && len(values) >= 7 {
+		factors = weekdayFactors(values, weekdays)
+		if factors != nil {
+			method = ForecastMethodSeasonal
+		}
+	}
+
+	var xs, ys []float64
+	for i, value := range values {
+		if factors != nil {
+			factor := factors[weekdays[i]]
+			if factor == 0 {
+				continue
+			}
+			value /= factor
+		}
+		xs = append(xs, float64(i))
+		ys = append(ys, value)
+	}
+
+	return fitLinear(xs, ys), factors, method
+}
+
+// weekdayFactors returns the mean spend of each day of the week relative to
+// the overall mean, or nil when there is no spend
+func weekdayFactors(values []float64, weekdays []time.Weekday) map[time.Weekday]float64 {
+	var total float64
+	sums := make(map[time.Weekday]float64, 7)
+	counts := make(map[time.Weekday]int, 7)
+	for i, value := range values {
+		total += value
+		sums[weekdays[i]] += value
+		counts[weekdays[i]]++
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	mean := total / float64(len(values))
+	factors := make(map[time.Weekday]float64, 7)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		factors[weekday] = 1
+		if counts[weekday] > 0 {
+			factors[weekday] = sums[weekday] / float64(counts[weekday]) / mean
+		}
+	}
+	return factors
+}
+
+// fitLinear fits a least squares line; with fewer than two points the trend
+// is flat at their mean
+func fitLinear(xs, ys []float64) spendTrend {
+	n := float64(len(xs))
+	if n == 0 {
+		return spendTrend{}
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+		sumXY += xs[i] * ys[i]
+		sumXX += xs[i] * xs[i]
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if n < 2 || denominator == 0 {
+		return spendTrend{intercept: sumY / n}
+	}
+
+	slope := (n*sumXY - sumX*sumY) / denominator
+	return spendTrend{
+		intercept: (sumY - slope*sumX) / n,
+		slope:     slope,
+	}
+}
@@ -4,11 +4,18 @@
 package billing
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/click2-run/dictamesh/pkg/config"
+	"github.com/click2-run/dictamesh/pkg/scheduler"
+	"github.com/click2-run/dictamesh/pkg/secrets"
 	"github.com/shopspring/decimal"
 )
 
@@ -29,14 +36,47 @@ type Config struct {
 	// Usage metrics settings
 	Usage UsageConfig
 
+	// Kafka metering settings
+	Metering MeteringConfig
+
 	// Notification settings
 	Notifications NotificationConfig
 
+	// Subscription lifecycle settings
+	Subscriptions SubscriptionConfig
+
+	// Billing run settings
+	BillingRuns BillingRunConfig
+
+	// Budget alert and forecast settings
+	Budgets BudgetConfig
+
+	// Plan versioning settings
+	Plans PlanConfig
+
+	// Payout reconciliation settings
+	Reconciliation ReconciliationConfig
+
+	// Dunning settings
+	Dunning DunningConfig
+
+	// Tax settings
+	Tax TaxConfig
+
+	// Document storage settings
+	Storage StorageConfig
+
+	// Invoice branding
+	Branding BrandingConfig
+
 	// Feature flags
 	Features FeatureFlags
 
 	// Rate limiting
-	RateLimits RateLimitConfig
+	RateLimits RateLimitConfig `reload:"true"`
+
+	// credentialsMu guards the credentials rotated by ResolveSecrets
+	credentialsMu sync.RWMutex
 }
 
 // StripeConfig contains Stripe payment provider settings
@@ -51,34 +91,150 @@ type PayPalConfig struct {
 	ClientID     string
 	ClientSecret string
 	Environment  string // sandbox or production
+	ReturnURL    string // Where PayPal sends the customer after approving a payment
+	CancelURL    string // Where PayPal sends the customer after canceling a payment
 	Enabled      bool
 }
 
 // InvoiceConfig contains invoice generation settings
 type InvoiceConfig struct {
-	DueDays         int             // Number of days until invoice is due
-	NumberPrefix    string          // Prefix for invoice numbers (e.g., "INV-")
-	TaxRate         decimal.Decimal // Default tax rate (e.g., 0.10 for 10%)
-	DefaultCurrency string          // Default currency code (ISO 4217)
-	PDFStoragePath  string          // Path to store generated PDF files
+	DueDays          int             // Number of days until invoice is due
+	NumberPrefix     string          // Prefix for invoice numbers (e.g., "INV-")
+	CreditNotePrefix string          // Prefix for credit note numbers (e.g., "CN-")
+	GaplessNumbering bool            // Number invoices without gaps, one at a time
+	TaxRate          decimal.Decimal // Default tax rate (e.g., 0.10 for 10%)
+	DefaultCurrency  string          // Default currency code (ISO 4217)
+	PDFStoragePath   string          // Path to store generated PDF files
+	OverdueSchedule  string          // Cron schedule for overdue invoice processing
+	LateFeeFixed     decimal.Decimal // Fixed late fee added to invoices becoming past due
+	LateFeePercent   decimal.Decimal // Late fee as a fraction of the amount due (e.g., 0.015 for 1.5%)
+	SuspensionGrace  time.Duration   // How long past due invoices wait before suspending the organization (0 to never suspend)
 }
 
 // UsageConfig contains usage metrics collection settings
 type UsageConfig struct {
 	AggregationInterval time.Duration // How often to aggregate usage metrics
+	AggregationDelay    time.Duration // How long to wait for late samples before aggregating an interval
+	MaxBackfill         time.Duration // How far back missed intervals are aggregated
+	Source              string        // Usage source: prometheus or inprocess
+	PrometheusURL       string        // Prometheus HTTP API address for the prometheus source
 	RetentionDays       int           // How long to retain detailed usage data
 	BatchSize           int           // Batch size for metric processing
 	EnableRealTime      bool          // Enable real-time usage tracking
 }
 
+// MeteringConfig contains settings of the Kafka write path of per-request
+// usage (API calls and data transfer)
+type MeteringConfig struct {
+	Enabled        bool          // Publish API calls and transfer to Kafka instead of aggregating them from the usage source
+	Brokers        []string      // Kafka bootstrap servers
+	Topic          string        // Topic of usage events
+	ConsumerGroup  string        // Consumer group of the usage ingesters
+	FlushInterval  time.Duration // How long the producer batches events before writing them
+	Window         time.Duration // Length of the usage windows events are aggregated into
+	BatchSize      int           // Maximum events ingested per transaction
+	DedupRetention time.Duration // How long event keys are kept to drop redelivered events
+}
+
 // NotificationConfig contains notification integration settings
 type NotificationConfig struct {
 	ServiceURL     string        // URL of the notification service
+	APIKey         string        // API key of the notification service, sent as a bearer token
 	RetryAttempts  int           // Number of retry attempts for failed notifications
 	RetryDelay     time.Duration // Delay between retry attempts
 	TimeoutSeconds int           // Timeout for notification requests
 }
 
+// SubscriptionConfig contains subscription lifecycle settings
+type SubscriptionConfig struct {
+	LifecycleSchedule string        // Cron schedule for scheduled plan changes, resumptions and trial conversions
+	MaxPauseDuration  time.Duration // Longest allowed pause (0 = unlimited)
+
+	// Trials
+	TrialDuration         time.Duration // Length of new trials
+	TrialMaxExtension     time.Duration // Longest total extension of a trial
+	TrialReminderDays     []int         // Days before the end of a trial reminders are sent (e.g. 7, 3, 1)
+	TrialReminderSchedule string        // Cron schedule for sending trial reminders
+	TrialExpiryAction     string        // What to do with trials ending without a payment method: suspend or downgrade
+	TrialDowngradePlan    string        // Slug of the plan expired trials are downgraded to
+}
+
+// BillingRunConfig contains settings of the billing runs invoicing ended
+// subscription periods
+type BillingRunConfig struct {
+	Schedule    string        // Cron schedule for billing runs
+	BatchSize   int           // Number of subscriptions fetched per batch
+	MaxAttempts int           // Attempts to invoice a subscription within a run
+	RetryDelay  time.Duration // Delay before the first retry, doubled for each further retry
+}
+
+// BudgetConfig contains organization budget alert and spend forecast settings
+type BudgetConfig struct {
+	AlertSchedule      string // Cron schedule for checking budgets
+	AlertThresholds    []int  // Percentages of the budget alerted on (e.g. 50, 80, 100)
+	ForecastLookback   int    // Days of spend history the forecast is based on
+	SeasonalMinHistory int    // Days of history required for the weekly seasonal forecast
+}
+
+// PlanConfig contains plan versioning and price change settings
+type PlanConfig struct {
+	PriceChangeNotice time.Duration // Minimum notice between scheduling a price change and its effective date
+	NoticeSchedule    string        // Cron schedule for sending price change notices
+}
+
+// ReconciliationConfig contains provider payout reconciliation settings
+type ReconciliationConfig struct {
+	Schedule        string        // Cron schedule for reconciling payouts
+	Lookback        time.Duration // Payouts arriving within this window are reconciled
+	SettlementDelay time.Duration // Succeeded payments not paid out after this long are flagged
+}
+
+// DunningConfig contains failed payment retry settings
+type DunningConfig struct {
+	Enabled          bool            // Retry failed payments automatically
+	RetrySchedule    []time.Duration // Retry offsets from the first failure (e.g. 24h, 72h, 168h)
+	Schedule         string          // Cron schedule for processing due retries
+	ExhaustionAction string          // What to do when retries are exhausted: suspend or cancel
+}
+
+// TaxConfig contains tax calculation settings
+type TaxConfig struct {
+	Provider            string // flat, table, stripe or avalara
+	SellerCountry       string // ISO 3166-1 alpha-2 country of the selling entity
+	EnableReverseCharge bool   // Apply the EU B2B reverse charge to customers with a VAT ID
+	StripeTaxCode       string // Stripe product tax code
+	Avalara             AvalaraConfig
+}
+
+// AvalaraConfig contains Avalara AvaTax settings
+type AvalaraConfig struct {
+	AccountID   string
+	LicenseKey  string
+	CompanyCode string
+	TaxCode     string // AvaTax tax code of the subscription
+	Environment string // sandbox or production
+}
+
+// StorageConfig contains object storage settings for generated documents
+type StorageConfig struct {
+	Backend       string // local or s3
+	PublicBaseURL string // Base URL documents are served from (optional)
+	S3Bucket      string
+	S3Prefix      string // Key prefix within the bucket
+	S3Region      string
+	S3Endpoint    string // Custom endpoint for S3 compatible services (e.g., MinIO)
+}
+
+// BrandingConfig contains the seller details rendered on invoice PDFs
+type BrandingConfig struct {
+	CompanyName    string
+	CompanyAddress []string // Address lines
+	CompanyTaxID   string
+	LogoPath       string // PNG or JPEG logo
+	AccentColor    string // Hex color of headings (e.g., "#1F6FEB")
+	FooterText     string
+}
+
 // FeatureFlags controls which features are enabled
 type FeatureFlags struct {
 	EnableAutoPayment   bool // Automatically charge payment methods
@@ -97,7 +253,30 @@ type RateLimitConfig struct {
 
 // LoadFromEnv loads configuration from environment variables
 func LoadFromEnv() (*Config, error) {
-	config := &Config{
+	config := configFromEnv()
+
+	// Validate required configuration
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+// Load loads configuration through the centralized loader. Environment values
+// read by LoadFromEnv act as defaults; files, prefixed environment variables and
+// flags registered on the loader override them. Rate limits are hot reloadable.
+func Load(ctx context.Context, loader *config.Loader) (*Config, error) {
+	cfg := configFromEnv()
+	if err := loader.Load(ctx, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// configFromEnv builds a configuration from environment variables and defaults
+func configFromEnv() *Config {
+	return &Config{
 		DatabaseDSN: getEnv("BILLING_DATABASE_DSN", ""),
 
 		Stripe: StripeConfig{
@@ -110,31 +289,131 @@ func LoadFromEnv() (*Config, error) {
 			ClientID:     getEnv("PAYPAL_CLIENT_ID", ""),
 			ClientSecret: getEnv("PAYPAL_CLIENT_SECRET", ""),
 			Environment:  getEnv("PAYPAL_ENVIRONMENT", "sandbox"),
+			ReturnURL:    getEnv("PAYPAL_RETURN_URL", "https://app.dictamesh.io/billing/paypal/return"),
+			CancelURL:    getEnv("PAYPAL_CANCEL_URL", "https://app.dictamesh.io/billing/paypal/cancel"),
 			Enabled:      getEnvBool("PAYPAL_ENABLED", false),
 		},
 
 		Invoice: InvoiceConfig{
-			DueDays:         getEnvInt("INVOICE_DUE_DAYS", 30),
-			NumberPrefix:    getEnv("INVOICE_NUMBER_PREFIX", "INV-"),
-			TaxRate:         getEnvDecimal("INVOICE_TAX_RATE", "0.00"),
-			DefaultCurrency: getEnv("INVOICE_DEFAULT_CURRENCY", "USD"),
-			PDFStoragePath:  getEnv("INVOICE_PDF_STORAGE_PATH", "/tmp/invoices"),
+			DueDays:          getEnvInt("INVOICE_DUE_DAYS", 30),
+			NumberPrefix:     getEnv("INVOICE_NUMBER_PREFIX", "INV-"),
+			CreditNotePrefix: getEnv("INVOICE_CREDIT_NOTE_PREFIX", "CN-"),
+			GaplessNumbering: getEnvBool("INVOICE_GAPLESS_NUMBERING", false),
+			TaxRate:          getEnvDecimal("INVOICE_TAX_RATE", "0.00"),
+			DefaultCurrency:  getEnv("INVOICE_DEFAULT_CURRENCY", "USD"),
+			PDFStoragePath:   getEnv("INVOICE_PDF_STORAGE_PATH", "/tmp/invoices"),
+			OverdueSchedule:  getEnv("INVOICE_OVERDUE_SCHEDULE", "0 6 * * *"),
+			LateFeeFixed:     getEnvDecimal("INVOICE_LATE_FEE_FIXED", "0.00"),
+			LateFeePercent:   getEnvDecimal("INVOICE_LATE_FEE_PERCENT", "0.00"),
+			SuspensionGrace:  getEnvDuration("INVOICE_SUSPENSION_GRACE", "336h"),
 		},
 
 		Usage: UsageConfig{
 			AggregationInterval: getEnvDuration("USAGE_AGGREGATION_INTERVAL", "1h"),
+			AggregationDelay:    getEnvDuration("USAGE_AGGREGATION_DELAY", "2m"),
+			MaxBackfill:         getEnvDuration("USAGE_MAX_BACKFILL", "168h"),
+			Source:              getEnv("USAGE_SOURCE", UsageSourcePrometheus),
+			PrometheusURL:       getEnv("USAGE_PROMETHEUS_URL", "http://localhost:9090"),
 			RetentionDays:       getEnvInt("USAGE_RETENTION_DAYS", 90),
 			BatchSize:           getEnvInt("USAGE_BATCH_SIZE", 1000),
 			EnableRealTime:      getEnvBool("USAGE_ENABLE_REALTIME", true),
 		},
 
+		Metering: MeteringConfig{
+			Enabled:        getEnvBool("METERING_ENABLED", false),
+			Brokers:        strings.Split(getEnv("METERING_KAFKA_BROKERS", "localhost:9092"), ","),
+			Topic:          getEnv("METERING_TOPIC", "dictamesh.billing.usage"),
+			ConsumerGroup:  getEnv("METERING_CONSUMER_GROUP", "dictamesh-billing-metering"),
+			FlushInterval:  getEnvDuration("METERING_FLUSH_INTERVAL", "1s"),
+			Window:         getEnvDuration("METERING_WINDOW", "1m"),
+			BatchSize:      getEnvInt("METERING_BATCH_SIZE", 5000),
+			DedupRetention: getEnvDuration("METERING_DEDUP_RETENTION", "168h"),
+		},
+
 		Notifications: NotificationConfig{
 			ServiceURL:     getEnv("NOTIFICATION_SERVICE_URL", "http://localhost:8080"),
+			APIKey:         getEnv("NOTIFICATION_SERVICE_API_KEY", ""),
 			RetryAttempts:  getEnvInt("NOTIFICATION_RETRY_ATTEMPTS", 3),
 			RetryDelay:     getEnvDuration("NOTIFICATION_RETRY_DELAY", "5s"),
 			TimeoutSeconds: getEnvInt("NOTIFICATION_TIMEOUT_SECONDS", 30),
 		},
 
+		Subscriptions: SubscriptionConfig{
+			LifecycleSchedule: getEnv("SUBSCRIPTION_LIFECYCLE_SCHEDULE", "*/15 * * * *"),
+			MaxPauseDuration:  getEnvDuration("SUBSCRIPTION_MAX_PAUSE_DURATION", "2160h"),
+
+			TrialDuration:         getEnvDuration("SUBSCRIPTION_TRIAL_DURATION", "336h"),
+			TrialMaxExtension:     getEnvDuration("SUBSCRIPTION_TRIAL_MAX_EXTENSION", "336h"),
+			TrialReminderDays:     getEnvInts("SUBSCRIPTION_TRIAL_REMINDER_DAYS", "7,3,1"),
+			TrialReminderSchedule: getEnv("SUBSCRIPTION_TRIAL_REMINDER_SCHEDULE", "0 * * * *"),
+			TrialExpiryAction:     getEnv("SUBSCRIPTION_TRIAL_EXPIRY_ACTION", string(TrialExpirySuspend)),
+			TrialDowngradePlan:    getEnv("SUBSCRIPTION_TRIAL_DOWNGRADE_PLAN", "free"),
+		},
+
+		BillingRuns: BillingRunConfig{
+			Schedule:    getEnv("BILLING_RUN_SCHEDULE", "0 * * * *"),
+			BatchSize:   getEnvInt("BILLING_RUN_BATCH_SIZE", 100),
+			MaxAttempts: getEnvInt("BILLING_RUN_MAX_ATTEMPTS", 3),
+			RetryDelay:  getEnvDuration("BILLING_RUN_RETRY_DELAY", "5s"),
+		},
+
+		Budgets: BudgetConfig{
+			AlertSchedule:      getEnv("BUDGET_ALERT_SCHEDULE", "30 * * * *"),
+			AlertThresholds:    getEnvInts("BUDGET_ALERT_THRESHOLDS", "50,80,100"),
+			ForecastLookback:   getEnvInt("BUDGET_FORECAST_LOOKBACK_DAYS", 28),
+			SeasonalMinHistory: getEnvInt("BUDGET_SEASONAL_MIN_HISTORY_DAYS", 14),
+		},
+
+		Plans: PlanConfig{
+			PriceChangeNotice: getEnvDuration("PLAN_PRICE_CHANGE_NOTICE", "720h"),
+			NoticeSchedule:    getEnv("PLAN_PRICE_CHANGE_NOTICE_SCHEDULE", "15 * * * *"),
+		},
+
+		Reconciliation: ReconciliationConfig{
+			Schedule:        getEnv("RECONCILIATION_SCHEDULE", "0 6 * * *"),
+			Lookback:        getEnvDuration("RECONCILIATION_LOOKBACK", "168h"),
+			SettlementDelay: getEnvDuration("RECONCILIATION_SETTLEMENT_DELAY", "168h"),
+		},
+
+		Dunning: DunningConfig{
+			Enabled:          getEnvBool("DUNNING_ENABLED", true),
+			RetrySchedule:    getEnvDurations("DUNNING_RETRY_SCHEDULE", "24h,72h,168h"),
+			Schedule:         getEnv("DUNNING_SCHEDULE", "0 * * * *"),
+			ExhaustionAction: getEnv("DUNNING_EXHAUSTION_ACTION", string(DunningActionSuspend)),
+		},
+
+		Tax: TaxConfig{
+			Provider:            getEnv("TAX_PROVIDER", TaxProviderFlat),
+			SellerCountry:       getEnv("TAX_SELLER_COUNTRY", ""),
+			EnableReverseCharge: getEnvBool("TAX_REVERSE_CHARGE", true),
+			StripeTaxCode:       getEnv("TAX_STRIPE_TAX_CODE", "txcd_10103001"),
+			Avalara: AvalaraConfig{
+				AccountID:   getEnv("AVALARA_ACCOUNT_ID", ""),
+				LicenseKey:  getEnv("AVALARA_LICENSE_KEY", ""),
+				CompanyCode: getEnv("AVALARA_COMPANY_CODE", "DEFAULT"),
+				TaxCode:     getEnv("AVALARA_TAX_CODE", "SW054000"),
+				Environment: getEnv("AVALARA_ENVIRONMENT", "sandbox"),
+			},
+		},
+
+		Storage: StorageConfig{
+			Backend:       getEnv("STORAGE_BACKEND", StorageLocal),
+			PublicBaseURL: getEnv("STORAGE_PUBLIC_BASE_URL", ""),
+			S3Bucket:      getEnv("STORAGE_S3_BUCKET", ""),
+			S3Prefix:      getEnv("STORAGE_S3_PREFIX", "billing"),
+			S3Region:      getEnv("STORAGE_S3_REGION", ""),
+			S3Endpoint:    getEnv("STORAGE_S3_ENDPOINT", ""),
+		},
+
+		Branding: BrandingConfig{
+			CompanyName:    getEnv("BRANDING_COMPANY_NAME", "DictaMesh"),
+			CompanyAddress: getEnvList("BRANDING_COMPANY_ADDRESS", ""),
+			CompanyTaxID:   getEnv("BRANDING_COMPANY_TAX_ID", ""),
+			LogoPath:       getEnv("BRANDING_LOGO_PATH", ""),
+			AccentColor:    getEnv("BRANDING_ACCENT_COLOR", "#1F6FEB"),
+			FooterText:     getEnv("BRANDING_FOOTER_TEXT", "Thank you for your business."),
+		},
+
 		Features: FeatureFlags{
 			EnableAutoPayment:   getEnvBool("FEATURE_AUTO_PAYMENT", true),
 			EnableUsageMetrics:  getEnvBool("FEATURE_USAGE_METRICS", true),
@@ -149,13 +428,6 @@ func LoadFromEnv() (*Config, error) {
 			BurstSize:         getEnvInt("RATE_LIMIT_BURST", 200),
 		},
 	}
-
-	// Validate required configuration
-	if err := config.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
-	}
-
-	return config, nil
 }
 
 // Validate checks if the configuration is valid
@@ -172,14 +444,187 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("PayPal client ID and secret are required when PayPal is enabled")
 	}
 
+	if _, ok := payPalURLs[c.PayPal.Environment]; c.PayPal.Enabled && !ok {
+		return fmt.Errorf("PayPal environment must be sandbox or production")
+	}
+
 	if c.Invoice.DueDays <= 0 {
 		return fmt.Errorf("invoice due days must be positive")
 	}
 
+	if _, err := scheduler.ParseSchedule(c.Invoice.OverdueSchedule); err != nil {
+		return fmt.Errorf("invalid invoice overdue schedule: %w", err)
+	}
+
+	if c.Invoice.LateFeeFixed.IsNegative() || c.Invoice.LateFeePercent.IsNegative() {
+		return fmt.Errorf("invoice late fees must not be negative")
+	}
+
+	if c.Invoice.SuspensionGrace < 0 {
+		return fmt.Errorf("invoice suspension grace must not be negative")
+	}
+
+	if _, err := scheduler.ParseSchedule(c.Subscriptions.LifecycleSchedule); err != nil {
+		return fmt.Errorf("invalid subscription lifecycle schedule: %w", err)
+	}
+
+	if c.Subscriptions.MaxPauseDuration < 0 {
+		return fmt.Errorf("subscription max pause duration must not be negative")
+	}
+
+	if c.Subscriptions.TrialDuration <= 0 || c.Subscriptions.TrialMaxExtension < 0 {
+		return fmt.Errorf("trial duration must be positive and max extension not negative")
+	}
+
+	for i, days := range c.Subscriptions.TrialReminderDays {
+		if days <= 0 || (i > 0 && days >= c.Subscriptions.TrialReminderDays[i-1]) {
+			return fmt.Errorf("trial reminder days must be positive and decreasing")
+		}
+	}
+
+	if _, err := scheduler.ParseSchedule(c.Subscriptions.TrialReminderSchedule); err != nil {
+		return fmt.Errorf("invalid trial reminder schedule: %w", err)
+	}
+
+	switch TrialExpiryAction(c.Subscriptions.TrialExpiryAction) {
+	case TrialExpirySuspend:
+	case TrialExpiryDowngrade:
+		if c.Subscriptions.TrialDowngradePlan == "" {
+			return fmt.Errorf("trial downgrade plan is required to downgrade expired trials")
+		}
+	default:
+		return fmt.Errorf("trial expiry action must be suspend or downgrade")
+	}
+
+	if _, err := scheduler.ParseSchedule(c.BillingRuns.Schedule); err != nil {
+		return fmt.Errorf("invalid billing run schedule: %w", err)
+	}
+
+	if c.BillingRuns.BatchSize <= 0 || c.BillingRuns.MaxAttempts <= 0 {
+		return fmt.Errorf("billing run batch size and max attempts must be positive")
+	}
+
+	if c.BillingRuns.RetryDelay < 0 {
+		return fmt.Errorf("billing run retry delay must not be negative")
+	}
+
+	if _, err := scheduler.ParseSchedule(c.Budgets.AlertSchedule); err != nil {
+		return fmt.Errorf("invalid budget alert schedule: %w", err)
+	}
+
+	for i, threshold := range c.Budgets.AlertThresholds {
+		if threshold <= 0 || (i > 0 && threshold <= c.Budgets.AlertThresholds[i-1]) {
+			return fmt.Errorf("budget alert thresholds must be positive and increasing")
+		}
+	}
+
+	if c.Budgets.ForecastLookback <= 0 || c.Budgets.SeasonalMinHistory <= 0 {
+		return fmt.Errorf("budget forecast lookback and seasonal min history must be positive")
+	}
+
+	if c.Plans.PriceChangeNotice < 0 {
+		return fmt.Errorf("plan price change notice must not be negative")
+	}
+
+	if _, err := scheduler.ParseSchedule(c.Plans.NoticeSchedule); err != nil {
+		return fmt.Errorf("invalid plan price change notice schedule: %w", err)
+	}
+
+	if c.Reconciliation.Lookback <= 0 || c.Reconciliation.SettlementDelay <= 0 {
+		return fmt.Errorf("reconciliation lookback and settlement delay must be positive")
+	}
+
+	if _, err := scheduler.ParseSchedule(c.Reconciliation.Schedule); err != nil {
+		return fmt.Errorf("invalid reconciliation schedule: %w", err)
+	}
+
+	if c.Dunning.Enabled {
+		if len(c.Dunning.RetrySchedule) == 0 {
+			return fmt.Errorf("dunning retry schedule must not be empty")
+		}
+		for i, offset := range c.Dunning.RetrySchedule {
+			if offset <= 0 || (i > 0 && offset <= c.Dunning.RetrySchedule[i-1]) {
+				return fmt.Errorf("dunning retry schedule must be positive and increasing")
+			}
+		}
+		if _, err := scheduler.ParseSchedule(c.Dunning.Schedule); err != nil {
+			return fmt.Errorf("invalid dunning schedule: %w", err)
+		}
+		switch DunningAction(c.Dunning.ExhaustionAction) {
+		case DunningActionSuspend, DunningActionCancel:
+		default:
+			return fmt.Errorf("dunning exhaustion action must be suspend or cancel")
+		}
+	}
+
+	switch c.Tax.Provider {
+	case TaxProviderFlat, TaxProviderTable:
+	case TaxProviderStripe:
+		if !c.Stripe.Enabled {
+			return fmt.Errorf("Stripe must be enabled to use the Stripe tax provider")
+		}
+	case TaxProviderAvalara:
+		if c.Tax.Avalara.AccountID == "" || c.Tax.Avalara.LicenseKey == "" {
+			return fmt.Errorf("Avalara account ID and license key are required when Avalara is the tax provider")
+		}
+		if _, ok := avalaraURLs[c.Tax.Avalara.Environment]; !ok {
+			return fmt.Errorf("Avalara environment must be sandbox or production")
+		}
+	default:
+		return fmt.Errorf("unsupported tax provider: %s", c.Tax.Provider)
+	}
+
+	if c.Tax.SellerCountry != "" && len(c.Tax.SellerCountry) != 2 {
+		return fmt.Errorf("tax seller country must be an ISO 3166-1 alpha-2 code")
+	}
+
+	switch c.Storage.Backend {
+	case StorageLocal:
+	case StorageS3:
+		if c.Storage.S3Bucket == "" {
+			return fmt.Errorf("S3 bucket is required when S3 is the storage backend")
+		}
+	default:
+		return fmt.Errorf("unsupported storage backend: %s", c.Storage.Backend)
+	}
+
+	if _, _, _, err := parseHexColor(c.Branding.AccentColor); err != nil {
+		return fmt.Errorf("invalid branding accent color: %w", err)
+	}
+
 	if c.Usage.AggregationInterval <= 0 {
 		return fmt.Errorf("usage aggregation interval must be positive")
 	}
 
+	if c.Usage.AggregationDelay < 0 || c.Usage.MaxBackfill < 0 {
+		return fmt.Errorf("usage aggregation delay and max backfill must not be negative")
+	}
+
+	switch c.Usage.Source {
+	case UsageSourceInProcess:
+	case UsageSourcePrometheus:
+		if _, err := url.ParseRequestURI(c.Usage.PrometheusURL); err != nil {
+			return fmt.Errorf("invalid usage Prometheus URL: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported usage source: %s", c.Usage.Source)
+	}
+
+	if c.Metering.Enabled {
+		if len(c.Metering.Brokers) == 0 || c.Metering.Brokers[0] == "" {
+			return fmt.Errorf("metering Kafka brokers are required when metering is enabled")
+		}
+		if c.Metering.Topic == "" || c.Metering.ConsumerGroup == "" {
+			return fmt.Errorf("metering topic and consumer group are required when metering is enabled")
+		}
+		if c.Metering.FlushInterval <= 0 || c.Metering.Window <= 0 || c.Metering.BatchSize <= 0 {
+			return fmt.Errorf("metering flush interval, window and batch size must be positive")
+		}
+		if c.Metering.DedupRetention <= 0 {
+			return fmt.Errorf("metering dedup retention must be positive")
+		}
+	}
+
 	if c.Usage.RetentionDays <= 0 {
 		return fmt.Errorf("usage retention days must be positive")
 	}
@@ -187,6 +632,70 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// ResolveSecrets replaces secret references (e.g. "vault://billing/stripe#api_key")
+// in the configuration with their values and keeps them in sync when they are
+// rotated. Rotated provider credentials must be read through StripeAPIKey,
+// StripeWebhookSecret, PayPalCredentials and AvalaraLicenseKey; the database
+// DSN is only read at startup.
+func (c *Config) ResolveSecrets(ctx context.Context, m *secrets.Manager) error {
+	fields := []*string{
+		&c.DatabaseDSN,
+		&c.Stripe.APIKey,
+		&c.Stripe.WebhookSecret,
+		&c.PayPal.ClientID,
+		&c.PayPal.ClientSecret,
+		&c.Tax.Avalara.LicenseKey,
+	}
+
+	refs := make([]string, len(fields))
+	for i, field := range fields {
+		refs[i] = *field
+	}
+
+	if err := m.ResolveAll(ctx, fields...); err != nil {
+		return fmt.Errorf("failed to resolve billing secrets: %w", err)
+	}
+
+	for i, field := range fields {
+		field := field
+		m.OnRotate(refs[i], func(_ string, newValue string) {
+			c.credentialsMu.Lock()
+			defer c.credentialsMu.Unlock()
+			*field = newValue
+		})
+	}
+
+	return nil
+}
+
+// StripeAPIKey returns the current Stripe API key
+func (c *Config) StripeAPIKey() string {
+	c.credentialsMu.RLock()
+	defer c.credentialsMu.RUnlock()
+	return c.Stripe.APIKey
+}
+
+// StripeWebhookSecret returns the current Stripe webhook signing secret
+func (c *Config) StripeWebhookSecret() string {
+	c.credentialsMu.RLock()
+	defer c.credentialsMu.RUnlock()
+	return c.Stripe.WebhookSecret
+}
+
+// PayPalCredentials returns the current PayPal client ID and secret
+func (c *Config) PayPalCredentials() (clientID, clientSecret string) {
+	c.credentialsMu.RLock()
+	defer c.credentialsMu.RUnlock()
+	return c.PayPal.ClientID, c.PayPal.ClientSecret
+}
+
+// AvalaraLicenseKey returns the current Avalara license key
+func (c *Config) AvalaraLicenseKey() string {
+	c.credentialsMu.RLock()
+	defer c.credentialsMu.RUnlock()
+	return c.Tax.Avalara.LicenseKey
+}
+
 // Helper functions for environment variable parsing
 
 func getEnv(key, defaultValue string) string {
@@ -224,6 +733,60 @@ func getEnvDuration(key string, defaultValue string) time.Duration {
 	return duration
 }
 
+func getEnvDurations(key string, defaultValue string) []time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if durations, err := parseDurations(value); err == nil {
+			return durations
+		}
+	}
+	durations, _ := parseDurations(defaultValue)
+	return durations
+}
+
+func parseDurations(value string) ([]time.Duration, error) {
+	var durations []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		duration, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		durations = append(durations, duration)
+	}
+	return durations, nil
+}
+
+func getEnvInts(key string, defaultValue string) []int {
+	if value := os.Getenv(key); value != "" {
+		if ints, err := parseInts(value); err == nil {
+			return ints
+		}
+	}
+	ints, _ := parseInts(defaultValue)
+	return ints
+}
+
+func parseInts(value string) ([]int, error) {
+	var ints []int
+	for _, part := range strings.Split(value, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		ints = append(ints, i)
+	}
+	return ints, nil
+}
+
+func getEnvList(key string, defaultValue string) []string {
+	var items []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), "|") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getEnvDecimal(key string, defaultValue string) decimal.Decimal {
 	if value := os.Getenv(key); value != "" {
 		if dec, err := decimal.NewFromString(value); err == nil {
@@ -0,0 +1,301 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// CouponService manages coupons, promotion codes and their redemption
+type CouponService struct {
+	db             *gorm.DB
+	eventPublisher *BillingEventPublisher
+}
+
+// NewCouponService creates a new coupon service. The event publisher is optional.
+func NewCouponService(db *gorm.DB, eventPublisher *BillingEventPublisher) *CouponService {
+	return &CouponService{
+		db:             db,
+		eventPublisher: eventPublisher,
+	}
+}
+
+// CreateCoupon validates and stores a new coupon
+func (cs *CouponService) CreateCoupon(ctx context.Context, coupon *models.Coupon) error {
+	if err := validateCoupon(coupon); err != nil {
+		return err
+	}
+
+	if coupon.ID == uuid.Nil {
+		coupon.ID = uuid.New()
+	}
+	coupon.TimesRedeemed = 0
+	coupon.IsActive = true
+	coupon.Currency = strings.ToUpper(coupon.Currency)
+
+	if err := cs.db.WithContext(ctx).Create(coupon).Error; err != nil {
+		return fmt.Errorf("failed to create coupon: %w", err)
+	}
+	return nil
+}
+
+// validateCoupon checks the discount and duration of a coupon
+func validateCoupon(coupon *models.Coupon) error {
+	if coupon.Name == "" {
+		return fmt.Errorf("coupon name is required")
+	}
+
+	switch CouponDiscountType(coupon.DiscountType) {
+	case CouponDiscountPercentage:
+		if coupon.PercentOff.LessThanOrEqual(decimal.Zero) || coupon.PercentOff.GreaterThan(decimal.NewFromInt(100)) {
+			return fmt.Errorf("percent off must be between 0 and 100")
+		}
+	case CouponDiscountFixed:
+		if coupon.AmountOff.LessThanOrEqual(decimal.Zero) {
+			return fmt.Errorf("amount off must be positive")
+		}
+		if len(coupon.Currency) != 3 {
+			return fmt.Errorf("fixed amount coupons require a currency")
+		}
+	default:
+		return fmt.Errorf("unsupported discount type: %s", coupon.DiscountType)
+	}
+
+	switch CouponDuration(coupon.Duration) {
+	case CouponDurationForever, CouponDurationOnce:
+		coupon.DurationInMonths = nil
+	case CouponDurationRepeating:
+		if coupon.DurationInMonths == nil || *coupon.DurationInMonths <= 0 {
+			return fmt.Errorf("repeating coupons require a positive duration in months")
+		}
+	default:
+		return fmt.Errorf("unsupported coupon duration: %s", coupon.Duration)
+	}
+
+	if coupon.MaxRedemptions != nil && *coupon.MaxRedemptions <= 0 {
+		return fmt.Errorf("max redemptions must be positive")
+	}
+
+	return nil
+}
+
+// CreatePromotionCode creates a customer-facing code for a coupon. Codes are
+// case-insensitive and stored upper case.
+func (cs *CouponService) CreatePromotionCode(ctx context.Context, code *models.PromotionCode) error {
+	code.Code = strings.ToUpper(strings.TrimSpace(code.Code))
+	if code.Code == "" {
+		return fmt.Errorf("promotion code is required")
+	}
+	if code.MaxRedemptions != nil && *code.MaxRedemptions <= 0 {
+		return fmt.Errorf("max redemptions must be positive")
+	}
+
+	var coupon models.Coupon
+	if err := cs.db.WithContext(ctx).First(&coupon, "id = ?", code.CouponID).Error; err != nil {
+		return fmt.Errorf("failed to fetch coupon: %w", err)
+	}
+	if !coupon.IsActive {
+		return fmt.Errorf("coupon is not active")
+	}
+
+	if code.ID == uuid.Nil {
+		code.ID = uuid.New()
+	}
+	code.TimesRedeemed = 0
+	code.IsActive = true
+
+	if err := cs.db.WithContext(ctx).Create(code).Error; err != nil {
+		return fmt.Errorf("failed to create promotion code: %w", err)
+	}
+	return nil
+}
+
+// DeactivateCoupon stops a coupon and its promotion codes from being redeemed.
+// Existing redemptions keep applying.
+func (cs *CouponService) DeactivateCoupon(ctx context.Context, couponID string) error {
+	return cs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Coupon{}).
+			Where("id = ?", couponID).
+			Update("is_active", false).Error; err != nil {
+			return fmt.Errorf("failed to deactivate coupon: %w", err)
+		}
+		if err := tx.Model(&models.PromotionCode{}).
+			Where("coupon_id = ?", couponID).
+			Update("is_active", false).Error; err != nil {
+			return fmt.Errorf("failed to deactivate promotion codes: %w", err)
+		}
+		return nil
+	})
+}
+
+// RedeemCode applies a promotion code to a subscription of the organization of
+// the tenant in ctx. A subscription carries at most one active discount.
+func (cs *CouponService) RedeemCode(
+	ctx context.Context,
+	code string,
+	subscriptionID string,
+) (*models.CouponRedemption, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID, err := uuid.Parse(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid organization ID: %w", err)
+	}
+
+	var redemption *models.CouponRedemption
+	err = cs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var promo models.PromotionCode
+		if err := tx.Preload("Coupon").
+			Where("code = ?", strings.ToUpper(strings.TrimSpace(code))).
+			First(&promo).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("invalid promotion code")
+			}
+			return fmt.Errorf("failed to fetch promotion code: %w", err)
+		}
+
+		now := time.Now()
+		if err := checkRedeemable(&promo, orgID, now); err != nil {
+			return err
+		}
+
+		var subscription models.Subscription
+		if err := tx.Where("id = ? AND organization_id = ?", subscriptionID, orgID).
+			First(&subscription).Error; err != nil {
+			return fmt.Errorf("failed to fetch subscription: %w", err)
+		}
+		if subscription.Status == string(SubscriptionStatusCanceled) {
+			return fmt.Errorf("cannot redeem a coupon on a canceled subscription")
+		}
+
+		var active int64
+		if err := tx.Model(&models.CouponRedemption{}).
+			Where("subscription_id = ? AND status = ?", subscription.ID, RedemptionStatusActive).
+			Count(&active).Error; err != nil {
+			return fmt.Errorf("failed to check existing discounts: %w", err)
+		}
+		if active > 0 {
+			return fmt.Errorf("subscription already has an active discount")
+		}
+
+		// Count the redemption against both limits; the conditional updates
+		// keep concurrent redemptions within the limits
+		result := tx.Model(&models.Coupon{}).
+			Where("id = ?", promo.CouponID).
+			Where("max_redemptions IS NULL OR times_redeemed < max_redemptions").
+			Update("times_redeemed", gorm.Expr("times_redeemed + 1"))
+		if result.Error != nil {
+			return fmt.Errorf("failed to update coupon: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("coupon redemption limit reached")
+		}
+
+		result = tx.Model(&models.PromotionCode{}).
+			Where("id = ?", promo.ID).
+			Where("max_redemptions IS NULL OR times_redeemed < max_redemptions").
+			Update("times_redeemed", gorm.Expr("times_redeemed + 1"))
+		if result.Error != nil {
+			return fmt.Errorf("failed to update promotion code: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("promotion code redemption limit reached")
+		}
+
+		promoID := promo.ID
+		redemption = &models.CouponRedemption{
+			ID:              uuid.New(),
+			CouponID:        promo.CouponID,
+			PromotionCodeID: &promoID,
+			OrganizationID:  orgID,
+			SubscriptionID:  subscription.ID,
+			RedeemedAt:      now,
+			Status:          string(RedemptionStatusActive),
+		}
+		if CouponDuration(promo.Coupon.Duration) == CouponDurationRepeating && promo.Coupon.DurationInMonths != nil {
+			endsAt := now.AddDate(0, *promo.Coupon.DurationInMonths, 0)
+			redemption.EndsAt = &endsAt
+		}
+
+		if err := tx.Create(redemption).Error; err != nil {
+			return fmt.Errorf("failed to create coupon redemption: %w", err)
+		}
+		redemption.Coupon = promo.Coupon
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if cs.eventPublisher != nil {
+		if err := cs.eventPublisher.PublishCouponRedeemed(ctx, redemption, code); err != nil {
+			return redemption, fmt.Errorf("failed to publish coupon redeemed event: %w", err)
+		}
+	}
+
+	return redemption, nil
+}
+
+// checkRedeemable checks that a promotion code and its coupon can be redeemed
+// by an organization
+func checkRedeemable(promo *models.PromotionCode, organizationID uuid.UUID, now time.Time) error {
+	if !promo.IsActive || !promo.Coupon.IsActive {
+		return fmt.Errorf("promotion code is not active")
+	}
+	if promo.ExpiresAt != nil && now.After(*promo.ExpiresAt) {
+		return fmt.Errorf("promotion code has expired")
+	}
+	if promo.Coupon.RedeemBy != nil && now.After(*promo.Coupon.RedeemBy) {
+		return fmt.Errorf("coupon has expired")
+	}
+	if promo.OrganizationID != nil && *promo.OrganizationID != organizationID {
+		return fmt.Errorf("promotion code is not valid for this organization")
+	}
+	return nil
+}
+
+// RemoveDiscount removes the active discount of a subscription of the
+// organization of the tenant in ctx
+func (cs *CouponService) RemoveDiscount(ctx context.Context, subscriptionID string) error {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return err
+	}
+
+	return cs.db.WithContext(ctx).
+		Model(&models.CouponRedemption{}).
+		Where("subscription_id = ? AND organization_id = ?", subscriptionID, organizationID).
+		Where("status = ?", RedemptionStatusActive).
+		Update("status", RedemptionStatusRemoved).Error
+}
+
+// ListRedemptions retrieves the coupon redemptions of the organization of the
+// tenant in ctx
+func (cs *CouponService) ListRedemptions(ctx context.Context) ([]models.CouponRedemption, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var redemptions []models.CouponRedemption
+	err = cs.db.WithContext(ctx).
+		Preload("Coupon").
+		Where("organization_id = ?", organizationID).
+		Order("redeemed_at DESC").
+		Find(&redemptions).Error
+	return redemptions, err
+}
@@ -0,0 +1,299 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreditNoteService issues credit notes against invoices
+type CreditNoteService struct {
+	db     *gorm.DB
+	config *Config
+}
+
+// NewCreditNoteService creates a new credit note service
+func NewCreditNoteService(db *gorm.DB, config *Config) *CreditNoteService {
+	return &CreditNoteService{
+		db:     db,
+		config: config,
+	}
+}
+
+// CreditNoteLine is a line of a credit note to issue
+type CreditNoteLine struct {
+	InvoiceLineItemID string // Optional invoice line item being credited
+	Description       string // Defaults to the description of the invoice line item
+	Quantity          decimal.Decimal
+	UnitPrice         decimal.Decimal
+}
+
+// IssueCreditNote issues a credit note against a paid invoice, e.g. for an
+// overcharge or an SLA credit. The line amounts exclude tax, which is
+// credited at the rate of the invoice. The total becomes an account credit
+// applied to future invoices.
+func (cs *CreditNoteService) IssueCreditNote(
+	ctx context.Context,
+	invoiceID string,
+	lines []CreditNoteLine,
+	reason string,
+) (*models.CreditNote, error) {
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("credit note must have at least one line")
+	}
+
+	var creditNote *models.CreditNote
+	err := cs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var invoice models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("LineItems").
+			First(&invoice, "id = ?", invoiceID).Error; err != nil {
+			return fmt.Errorf("failed to fetch invoice: %w", err)
+		}
+
+		if invoice.Status != string(InvoiceStatusPaid) {
+			return fmt.Errorf("credit notes can only be issued against paid invoices")
+		}
+
+		lineItems, err := creditNoteLineItems(&invoice, lines)
+		if err != nil {
+			return err
+		}
+
+		subtotal := decimal.Zero
+		for _, item := range lineItems {
+			subtotal = subtotal.Add(item.Amount)
+		}
+		taxAmount := subtotal.Mul(invoiceTaxRate(&invoice)).Round(2)
+		total := subtotal.Add(taxAmount)
+
+		if !total.IsPositive() {
+			return fmt.Errorf("credit note total must be positive")
+		}
+		creditable := invoice.TotalAmount.Sub(invoice.AmountCredited)
+		if total.GreaterThan(creditable) {
+			return fmt.Errorf("credit note total cannot exceed the creditable amount of %s", creditable.StringFixed(2))
+		}
+
+		// Credit the amount to future invoices
+		now := time.Now()
+		credit := &models.Credit{
+			ID:              uuid.New(),
+			OrganizationID:  invoice.OrganizationID,
+			Amount:          total,
+			Currency:        invoice.Currency,
+			RemainingAmount: total,
+			Reason:          "credit_note",
+			Description:     fmt.Sprintf("Credit note for invoice %s", invoice.InvoiceNumber),
+			ValidFrom:       now,
+			Status:          string(CreditStatusActive),
+		}
+		if err := tx.Create(credit).Error; err != nil {
+			return fmt.Errorf("failed to create credit: %w", err)
+		}
+
+		creditNote = &models.CreditNote{
+			CreditID:  &credit.ID,
+			Subtotal:  subtotal,
+			TaxAmount: taxAmount,
+			Reason:    reason,
+			LineItems: lineItems,
+		}
+		return cs.issue(tx, &invoice, creditNote)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return creditNote, nil
+}
+
+// creditNoteLineItems builds the line items of a credit note
+func creditNoteLineItems(invoice *models.Invoice, lines []CreditNoteLine) ([]models.CreditNoteLineItem, error) {
+	items := make([]models.CreditNoteLineItem, 0, len(lines))
+	for _, line := range lines {
+		item := models.CreditNoteLineItem{
+			ID:          uuid.New(),
+			Description: line.Description,
+			Quantity:    line.Quantity,
+			UnitPrice:   line.UnitPrice,
+		}
+		if item.Quantity.IsZero() {
+			item.Quantity = decimal.NewFromInt(1)
+		}
+
+		if line.InvoiceLineItemID != "" {
+			var invoiceItem *models.InvoiceLineItem
+			for i := range invoice.LineItems {
+				if invoice.LineItems[i].ID.String() == line.InvoiceLineItemID {
+					invoiceItem = &invoice.LineItems[i]
+				}
+			}
+			if invoiceItem == nil {
+				return nil, fmt.Errorf("line item %s is not on invoice %s", line.InvoiceLineItemID, invoice.InvoiceNumber)
+			}
+			item.InvoiceLineItemID = &invoiceItem.ID
+			if item.Description == "" {
+				item.Description = invoiceItem.Description
+			}
+		}
+
+		if item.Description == "" {
+			return nil, fmt.Errorf("credit note line must have a description")
+		}
+		if item.Quantity.IsNegative() || item.UnitPrice.IsNegative() {
+			return nil, fmt.Errorf("credit note lines cannot be negative")
+		}
+
+		item.Amount = item.Quantity.Mul(item.UnitPrice).Round(2)
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// refundCreditNote issues the credit note of a refund within tx. Amounts
+// already credited are not credited again, so nil is returned when nothing
+// remains to credit.
+func (cs *CreditNoteService) refundCreditNote(
+	tx *gorm.DB,
+	invoice *models.Invoice,
+	refund *models.Refund,
+) (*models.CreditNote, error) {
+	total := decimal.Min(refund.Amount, invoice.TotalAmount.Sub(invoice.AmountCredited))
+	if !total.IsPositive() {
+		return nil, nil
+	}
+
+	// Refunds include tax
+	subtotal := total.Div(decimal.NewFromInt(1).Add(invoiceTaxRate(invoice))).Round(2)
+
+	creditNote := &models.CreditNote{
+		RefundID:  &refund.ID,
+		Subtotal:  subtotal,
+		TaxAmount: total.Sub(subtotal),
+		Reason:    refund.Reason,
+		LineItems: []models.CreditNoteLineItem{{
+			ID:          uuid.New(),
+			Description: fmt.Sprintf("Refund of payment for invoice %s", invoice.InvoiceNumber),
+			Quantity:    decimal.NewFromInt(1),
+			UnitPrice:   subtotal,
+			Amount:      subtotal,
+		}},
+	}
+	if err := cs.issue(tx, invoice, creditNote); err != nil {
+		return nil, err
+	}
+	return creditNote, nil
+}
+
+// issue numbers and stores a credit note within tx and reduces the amount
+// billed on its invoice
+func (cs *CreditNoteService) issue(tx *gorm.DB, invoice *models.Invoice, creditNote *models.CreditNote) error {
+	number, err := cs.generateCreditNoteNumber(tx)
+	if err != nil {
+		return fmt.Errorf("failed to generate credit note number: %w", err)
+	}
+
+	creditNote.ID = uuid.New()
+	creditNote.OrganizationID = invoice.OrganizationID
+	creditNote.InvoiceID = invoice.ID
+	creditNote.CreditNoteNumber = number
+	creditNote.TotalAmount = creditNote.Subtotal.Add(creditNote.TaxAmount)
+	creditNote.Currency = invoice.Currency
+	creditNote.Status = string(CreditNoteStatusIssued)
+	creditNote.IssuedAt = time.Now()
+
+	// Line items are created with the credit note
+	if err := tx.Create(creditNote).Error; err != nil {
+		return fmt.Errorf("failed to create credit note: %w", err)
+	}
+
+	if err := tx.Model(&models.Invoice{}).
+		Where("id = ?", invoice.ID).
+		Updates(map[string]interface{}{
+			"amount_credited": gorm.Expr("amount_credited + ?", creditNote.TotalAmount),
+			"amount_due":      gorm.Expr("GREATEST(amount_due - ?, 0)", creditNote.TotalAmount),
+		}).Error; err != nil {
+		return fmt.Errorf("failed to update invoice: %w", err)
+	}
+
+	invoice.AmountCredited = invoice.AmountCredited.Add(creditNote.TotalAmount)
+	return nil
+}
+
+// invoiceTaxRate returns the effective tax rate of an invoice
+func invoiceTaxRate(invoice *models.Invoice) decimal.Decimal {
+	if !invoice.Subtotal.IsPositive() {
+		return decimal.Zero
+	}
+	return invoice.TaxAmount.Div(invoice.Subtotal)
+}
+
+// generateCreditNoteNumber allocates the next credit note number. Credit
+// notes are numbered separately from invoices, gaplessly when invoices are.
+func (cs *CreditNoteService) generateCreditNoteNumber(tx *gorm.DB) (string, error) {
+	db := cs.db.WithContext(tx.Statement.Context)
+	if cs.config.Invoice.GaplessNumbering {
+		db = tx
+	}
+
+	// Generate credit note number: CN-2025-000042
+	return nextDocumentNumber(db, cs.config.Invoice.CreditNotePrefix, time.Now().Year())
+}
+
+// GetCreditNote retrieves a credit note of the organization of the tenant in ctx
+func (cs *CreditNoteService) GetCreditNote(ctx context.Context, creditNoteID string) (*models.CreditNote, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var creditNote models.CreditNote
+	if err := cs.db.WithContext(ctx).
+		Preload("LineItems").
+		Preload("Invoice").
+		Where("organization_id = ?", organizationID).
+		First(&creditNote, "id = ?", creditNoteID).Error; err != nil {
+		return nil, err
+	}
+
+	return &creditNote, nil
+}
+
+// ListCreditNotes retrieves credit notes for the organization of the tenant in ctx
+func (cs *CreditNoteService) ListCreditNotes(
+	ctx context.Context,
+	limit, offset int,
+) ([]models.CreditNote, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var creditNotes []models.CreditNote
+
+	query := cs.db.WithContext(ctx).
+		Where("organization_id = ?", organizationID).
+		Order("issued_at DESC")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	err = query.Find(&creditNotes).Error
+	return creditNotes, err
+}
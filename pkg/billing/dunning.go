@@ -0,0 +1,382 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// dunningResolutions maps the exhaustion action to the recorded resolution
+var dunningResolutions = map[DunningAction]string{
+	DunningActionSuspend: "suspended",
+	DunningActionCancel:  "canceled",
+}
+
+// DunningService retries failed invoice payments on a configurable schedule,
+// escalates notifications with every failed attempt and suspends or cancels
+// the subscription once the retries are exhausted
+type DunningService struct {
+	db                  *gorm.DB
+	config              *Config
+	paymentService      *PaymentService
+	notificationService *NotificationService
+	eventPublisher      *BillingEventPublisher
+}
+
+// NewDunningService creates a new dunning service and attaches it to the
+// payment service so failed payments enter dunning automatically. The event
+// publisher is optional.
+func NewDunningService(
+	db *gorm.DB,
+	config *Config,
+	paymentService *PaymentService,
+	notificationService *NotificationService,
+	eventPublisher *BillingEventPublisher,
+) *DunningService {
+	ds := &DunningService{
+		db:                  db,
+		config:              config,
+		paymentService:      paymentService,
+		notificationService: notificationService,
+		eventPublisher:      eventPublisher,
+	}
+	paymentService.dunning = ds
+	return ds
+}
+
+// HandlePaymentFailure starts dunning for the invoice of a failed payment.
+// Invoices already in dunning are left to ProcessRetries.
+func (ds *DunningService) HandlePaymentFailure(ctx context.Context, payment *models.Payment) error {
+	if !ds.config.Dunning.Enabled || payment.InvoiceID == uuid.Nil {
+		return nil
+	}
+
+	var existing models.DunningState
+	err := ds.db.WithContext(ctx).Where("invoice_id = ?", payment.InvoiceID).First(&existing).Error
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to fetch dunning state: %w", err)
+	}
+
+	invoice, err := ds.paymentService.invoiceService.GetInvoice(ctx, payment.InvoiceID.String())
+	if err != nil {
+		return fmt.Errorf("failed to fetch invoice: %w", err)
+	}
+
+	now := time.Now()
+	nextRetry := now.Add(ds.config.Dunning.RetrySchedule[0])
+	paymentID := payment.ID
+	state := &models.DunningState{
+		ID:                 uuid.New(),
+		OrganizationID:     payment.OrganizationID,
+		InvoiceID:          payment.InvoiceID,
+		SubscriptionID:     invoice.SubscriptionID,
+		Status:             string(DunningStatusActive),
+		NextRetryAt:        &nextRetry,
+		LastPaymentID:      &paymentID,
+		LastFailureCode:    payment.FailureCode,
+		LastFailureMessage: payment.FailureMessage,
+		StartedAt:          now,
+	}
+
+	err = ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(state).Error; err != nil {
+			return fmt.Errorf("failed to create dunning state: %w", err)
+		}
+		return ds.setSubscriptionStatus(tx, invoice.SubscriptionID, SubscriptionStatusActive, SubscriptionStatusPastDue)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := ds.notificationService.SendPaymentFailedNotification(ctx, payment, invoice); err != nil {
+		return fmt.Errorf("failed to send payment failed notification: %w", err)
+	}
+
+	return nil
+}
+
+// Resolve ends the active dunning of an invoice after it was paid and
+// restores the subscription and organization
+func (ds *DunningService) Resolve(ctx context.Context, invoiceID string) error {
+	var state models.DunningState
+	err := ds.db.WithContext(ctx).
+		Where("invoice_id = ?", invoiceID).
+		Where("status IN ?", []DunningStatus{DunningStatusActive, DunningStatusExhausted}).
+		First(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch dunning state: %w", err)
+	}
+
+	return ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&state).Updates(map[string]interface{}{
+			"status":        DunningStatusRecovered,
+			"resolution":    "paid",
+			"resolved_at":   now,
+			"next_retry_at": nil,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update dunning state: %w", err)
+		}
+
+		if err := ds.setSubscriptionStatus(tx, state.SubscriptionID, SubscriptionStatusPastDue, SubscriptionStatusActive); err != nil {
+			return err
+		}
+
+		return reactivateOrganization(tx, state.OrganizationID)
+	})
+}
+
+// reactivateOrganization reactivates a suspended organization unless another
+// invoice still suspends it, through exhausted dunning or by staying past due
+func reactivateOrganization(tx *gorm.DB, organizationID uuid.UUID) error {
+	var exhausted int64
+	if err := tx.Model(&models.DunningState{}).
+		Where("organization_id = ? AND status = ?", organizationID, DunningStatusExhausted).
+		Count(&exhausted).Error; err != nil {
+		return fmt.Errorf("failed to count exhausted dunning: %w", err)
+	}
+	var suspended int64
+	if err := tx.Model(&models.Invoice{}).
+		Where("organization_id = ? AND status = ?", organizationID, InvoiceStatusPastDue).
+		Where("suspended_at IS NOT NULL").
+		Count(&suspended).Error; err != nil {
+		return fmt.Errorf("failed to count suspended invoices: %w", err)
+	}
+	if exhausted > 0 || suspended > 0 {
+		return nil
+	}
+
+	if err := tx.Model(&models.Organization{}).
+		Where("id = ? AND status = ?", organizationID, OrganizationStatusSuspended).
+		Update("status", OrganizationStatusActive).Error; err != nil {
+		return fmt.Errorf("failed to reactivate organization: %w", err)
+	}
+	return nil
+}
+
+// ProcessRetries retries the payments of all invoices whose next dunning
+// attempt is due
+func (ds *DunningService) ProcessRetries(ctx context.Context) error {
+	if !ds.config.Dunning.Enabled {
+		return nil
+	}
+
+	var states []models.DunningState
+	if err := ds.db.WithContext(ctx).
+		Where("status = ?", DunningStatusActive).
+		Where("next_retry_at <= ?", time.Now()).
+		Order("next_retry_at ASC").
+		Find(&states).Error; err != nil {
+		return fmt.Errorf("failed to fetch due dunning states: %w", err)
+	}
+
+	var errs []error
+	for i := range states {
+		orgCtx := tenant.WithOrganization(ctx, states[i].OrganizationID.String())
+		if err := ds.retry(orgCtx, &states[i]); err != nil {
+			errs = append(errs, fmt.Errorf("invoice %s: %w", states[i].InvoiceID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// retry performs a single dunning attempt
+func (ds *DunningService) retry(ctx context.Context, state *models.DunningState) error {
+	invoice, err := ds.paymentService.invoiceService.GetInvoice(ctx, state.InvoiceID.String())
+	if err != nil {
+		return fmt.Errorf("failed to fetch invoice: %w", err)
+	}
+
+	// Paid or written off outside of dunning
+	switch InvoiceStatus(invoice.Status) {
+	case InvoiceStatusPaid:
+		return ds.Resolve(ctx, state.InvoiceID.String())
+	case InvoiceStatusVoid, InvoiceStatusUncollectible:
+		now := time.Now()
+		return ds.db.WithContext(ctx).Model(state).Updates(map[string]interface{}{
+			"status":        DunningStatusExhausted,
+			"resolved_at":   now,
+			"next_retry_at": nil,
+		}).Error
+	}
+
+	payment, chargeErr := ds.paymentService.ChargeInvoice(ctx, state.InvoiceID.String())
+	if chargeErr == nil && payment.Status == string(PaymentStatusSucceeded) {
+		// ChargeInvoice resolves the dunning state
+		return nil
+	}
+
+	now := time.Now()
+	attempt := state.AttemptCount + 1
+	updates := map[string]interface{}{
+		"attempt_count": attempt,
+		"last_retry_at": now,
+	}
+	if payment != nil {
+		updates["last_payment_id"] = payment.ID
+		updates["last_failure_code"] = payment.FailureCode
+		updates["last_failure_message"] = payment.FailureMessage
+		if chargeErr != nil && payment.FailureMessage == "" {
+			updates["last_failure_message"] = chargeErr.Error()
+		}
+	} else if chargeErr != nil {
+		updates["last_failure_message"] = chargeErr.Error()
+	}
+
+	schedule := ds.config.Dunning.RetrySchedule
+	if attempt >= len(schedule) {
+		return ds.exhaust(ctx, state, invoice, updates)
+	}
+
+	nextRetry := state.StartedAt.Add(schedule[attempt])
+	if nextRetry.Before(now) {
+		nextRetry = now
+	}
+	updates["next_retry_at"] = nextRetry
+
+	if err := ds.db.WithContext(ctx).Model(state).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update dunning state: %w", err)
+	}
+	state.AttemptCount = attempt
+	state.NextRetryAt = &nextRetry
+	if message, ok := updates["last_failure_message"].(string); ok {
+		state.LastFailureMessage = message
+	}
+
+	finalNotice := attempt == len(schedule)-1
+	if err := ds.notificationService.SendDunningRetryFailedNotification(ctx, state, invoice, finalNotice); err != nil {
+		return fmt.Errorf("failed to send dunning notification: %w", err)
+	}
+
+	return nil
+}
+
+// exhaust applies the configured exhaustion action once every retry failed
+func (ds *DunningService) exhaust(
+	ctx context.Context,
+	state *models.DunningState,
+	invoice *models.Invoice,
+	updates map[string]interface{},
+) error {
+	action := DunningAction(ds.config.Dunning.ExhaustionAction)
+	now := time.Now()
+
+	updates["status"] = DunningStatusExhausted
+	updates["resolution"] = dunningResolutions[action]
+	updates["resolved_at"] = now
+	updates["next_retry_at"] = nil
+
+	err := ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(state).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update dunning state: %w", err)
+		}
+
+		switch action {
+		case DunningActionCancel:
+			if state.SubscriptionID != uuid.Nil {
+				if err := tx.Model(&models.Subscription{}).
+					Where("id = ?", state.SubscriptionID).
+					Updates(map[string]interface{}{
+						"status":              SubscriptionStatusCanceled,
+						"canceled_at":         now,
+						"cancellation_reason": "Payment retries exhausted",
+					}).Error; err != nil {
+					return fmt.Errorf("failed to cancel subscription: %w", err)
+				}
+			}
+			if err := tx.Model(&models.Invoice{}).
+				Where("id = ? AND status IN ?", state.InvoiceID, []InvoiceStatus{InvoiceStatusOpen, InvoiceStatusPastDue}).
+				Update("status", InvoiceStatusUncollectible).Error; err != nil {
+				return fmt.Errorf("failed to mark invoice uncollectible: %w", err)
+			}
+		default:
+			if err := tx.Model(&models.Organization{}).
+				Where("id = ?", state.OrganizationID).
+				Update("status", OrganizationStatusSuspended).Error; err != nil {
+				return fmt.Errorf("failed to suspend organization: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if state.SubscriptionID == uuid.Nil {
+		return nil
+	}
+
+	var subscription models.Subscription
+	if err := ds.db.WithContext(ctx).
+		Preload("Plan").
+		First(&subscription, "id = ?", state.SubscriptionID).Error; err != nil {
+		return fmt.Errorf("failed to fetch subscription: %w", err)
+	}
+
+	if action == DunningActionCancel {
+		if err := ds.notificationService.SendSubscriptionCanceledNotification(ctx, &subscription); err != nil {
+			return fmt.Errorf("failed to send cancellation notification: %w", err)
+		}
+		if ds.eventPublisher != nil {
+			if err := ds.eventPublisher.PublishSubscriptionCanceled(ctx, &subscription); err != nil {
+				return fmt.Errorf("failed to publish cancellation event: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if err := ds.notificationService.SendSubscriptionSuspendedNotification(ctx, &subscription, invoice); err != nil {
+		return fmt.Errorf("failed to send suspension notification: %w", err)
+	}
+	return nil
+}
+
+// GetDunningStates returns the dunning history of the organization of the
+// tenant in ctx, most recent first
+func (ds *DunningService) GetDunningStates(ctx context.Context) ([]models.DunningState, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []models.DunningState
+	err = ds.db.WithContext(ctx).
+		Where("organization_id = ?", organizationID).
+		Order("started_at DESC").
+		Find(&states).Error
+	return states, err
+}
+
+// setSubscriptionStatus moves a subscription from one status to another
+func (ds *DunningService) setSubscriptionStatus(
+	tx *gorm.DB,
+	subscriptionID uuid.UUID,
+	from, to SubscriptionStatus,
+) error {
+	if subscriptionID == uuid.Nil {
+		return nil
+	}
+	if err := tx.Model(&models.Subscription{}).
+		Where("id = ? AND status = ?", subscriptionID, from).
+		Update("status", to).Error; err != nil {
+		return fmt.Errorf("failed to update subscription status: %w", err)
+	}
+	return nil
+}
@@ -10,6 +10,10 @@ import (
 	"time"
 
 	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/events"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // EventBus defines the interface for publishing events
@@ -17,9 +21,15 @@ type EventBus interface {
 	Publish(ctx context.Context, topic string, key string, value interface{}) error
 }
 
-// BillingEventPublisher publishes billing events to Kafka
+// eventSource is the source of billing events in their envelope
+const eventSource = "dictamesh/billing"
+
+// BillingEventPublisher publishes billing events to Kafka, directly or
+// through the transactional outbox
 type BillingEventPublisher struct {
-	eventBus EventBus
+	eventBus    EventBus
+	outbox      *gorm.DB            // Database of the outbox, if events are enqueued
+	partitioner *events.Partitioner // Topic and key of events, if configured
 }
 
 // NewBillingEventPublisher creates a new event publisher
@@ -29,6 +39,27 @@ func NewBillingEventPublisher(eventBus EventBus) *BillingEventPublisher {
 	}
 }
 
+// NewOutboxEventPublisher creates an event publisher that enqueues events in
+// the outbox of db, for an events.OutboxRelay to publish. Events published
+// with the transaction of a change, see transact, commit or roll back with it.
+func NewOutboxEventPublisher(db *gorm.DB) *BillingEventPublisher {
+	return &BillingEventPublisher{
+		outbox: db,
+	}
+}
+
+// SetPartitioner routes events with partitioner, e.g. to key them by
+// invoice or isolate large organizations. Without one, events go to the
+// topic of their type, keyed by organization.
+func (p *BillingEventPublisher) SetPartitioner(partitioner *events.Partitioner) {
+	p.partitioner = partitioner
+}
+
+// Transactional reports whether events are enqueued in the outbox
+func (p *BillingEventPublisher) Transactional() bool {
+	return p != nil && p.outbox != nil
+}
+
 // Event structures for different billing events
 
 // SubscriptionCreatedEvent represents a subscription creation event
@@ -107,6 +138,7 @@ type InvoiceOverdueEvent struct {
 	InvoiceNumber  string    `json:"invoice_number"`
 	OrganizationID string    `json:"organization_id"`
 	AmountDue      string    `json:"amount_due"`
+	LateFee        string    `json:"late_fee"`
 	Currency       string    `json:"currency"`
 	DueDate        time.Time `json:"due_date"`
 	DaysOverdue    int       `json:"days_overdue"`
@@ -142,6 +174,24 @@ type PaymentFailedEvent struct {
 	FailedAt       time.Time `json:"failed_at"`
 }
 
+// PaymentRefundedEvent represents a full or partial refund event
+type PaymentRefundedEvent struct {
+	EventID        string    `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	OccurredAt     time.Time `json:"occurred_at"`
+	RefundID       string    `json:"refund_id"`
+	PaymentID      string    `json:"payment_id"`
+	OrganizationID string    `json:"organization_id"`
+	InvoiceID      string    `json:"invoice_id"`
+	CreditNoteID   string    `json:"credit_note_id,omitempty"`
+	Amount         string    `json:"amount"`
+	AmountRefunded string    `json:"amount_refunded"` // Total refunded on the payment
+	Currency       string    `json:"currency"`
+	FullyRefunded  bool      `json:"fully_refunded"`
+	Reason         string    `json:"reason,omitempty"`
+	RefundedAt     time.Time `json:"refunded_at"`
+}
+
 // UsageThresholdReachedEvent represents a usage threshold event
 type UsageThresholdReachedEvent struct {
 	EventID        string    `json:"event_id"`
@@ -154,6 +204,21 @@ type UsageThresholdReachedEvent struct {
 	PercentUsed    int       `json:"percent_used"`
 }
 
+// BudgetThresholdReachedEvent represents a budget threshold reached event
+type BudgetThresholdReachedEvent struct {
+	EventID        string    `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	OccurredAt     time.Time `json:"occurred_at"`
+	OrganizationID string    `json:"organization_id"`
+	BudgetID       string    `json:"budget_id"`
+	Month          string    `json:"month"`
+	Threshold      int       `json:"threshold"`
+	CurrentSpend   string    `json:"current_spend"`
+	ForecastSpend  string    `json:"forecast_spend"`
+	Budget         string    `json:"budget"`
+	Currency       string    `json:"currency"`
+}
+
 // CreditAppliedEvent represents a credit application event
 type CreditAppliedEvent struct {
 	EventID        string    `json:"event_id"`
@@ -167,6 +232,91 @@ type CreditAppliedEvent struct {
 	Reason         string    `json:"reason"`
 }
 
+// CouponRedeemedEvent represents a coupon redemption event
+type CouponRedeemedEvent struct {
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	OccurredAt     time.Time  `json:"occurred_at"`
+	RedemptionID   string     `json:"redemption_id"`
+	CouponID       string     `json:"coupon_id"`
+	PromotionCode  string     `json:"promotion_code"`
+	OrganizationID string     `json:"organization_id"`
+	SubscriptionID string     `json:"subscription_id"`
+	DiscountType   string     `json:"discount_type"`
+	PercentOff     string     `json:"percent_off,omitempty"`
+	AmountOff      string     `json:"amount_off,omitempty"`
+	Currency       string     `json:"currency,omitempty"`
+	Duration       string     `json:"duration"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+}
+
+// DiscountAppliedEvent represents a coupon discount applied to an invoice
+type DiscountAppliedEvent struct {
+	EventID        string    `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	OccurredAt     time.Time `json:"occurred_at"`
+	InvoiceID      string    `json:"invoice_id"`
+	InvoiceNumber  string    `json:"invoice_number"`
+	OrganizationID string    `json:"organization_id"`
+	CouponID       string    `json:"coupon_id"`
+	RedemptionID   string    `json:"redemption_id"`
+	Amount         string    `json:"amount"`
+	Currency       string    `json:"currency"`
+}
+
+// PlanChangedEvent represents an applied or scheduled plan change
+type PlanChangedEvent struct {
+	EventID         string    `json:"event_id"`
+	EventType       string    `json:"event_type"`
+	OccurredAt      time.Time `json:"occurred_at"`
+	ChangeID        string    `json:"change_id"`
+	SubscriptionID  string    `json:"subscription_id"`
+	OrganizationID  string    `json:"organization_id"`
+	FromPlanID      string    `json:"from_plan_id"`
+	ToPlanID        string    `json:"to_plan_id"`
+	ChangeType      string    `json:"change_type"`
+	Status          string    `json:"status"`
+	EffectiveAt     time.Time `json:"effective_at"`
+	ProrationAmount string    `json:"proration_amount"`
+	Currency        string    `json:"currency"`
+}
+
+// SubscriptionPausedEvent represents a subscription pause event
+type SubscriptionPausedEvent struct {
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	OccurredAt     time.Time  `json:"occurred_at"`
+	SubscriptionID string     `json:"subscription_id"`
+	OrganizationID string     `json:"organization_id"`
+	PausedAt       time.Time  `json:"paused_at"`
+	ResumesAt      *time.Time `json:"resumes_at,omitempty"`
+}
+
+// SubscriptionResumedEvent represents a subscription resumption event
+type SubscriptionResumedEvent struct {
+	EventID        string    `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	OccurredAt     time.Time `json:"occurred_at"`
+	SubscriptionID string    `json:"subscription_id"`
+	OrganizationID string    `json:"organization_id"`
+	PeriodEnd      time.Time `json:"period_end"`
+}
+
+// TrialConvertedEvent represents the conversion of a trial to a paid subscription
+type TrialConvertedEvent struct {
+	EventID        string    `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	OccurredAt     time.Time `json:"occurred_at"`
+	SubscriptionID string    `json:"subscription_id"`
+	OrganizationID string    `json:"organization_id"`
+	PlanID         string    `json:"plan_id"`
+	PlanName       string    `json:"plan_name"`
+	PeriodStart    time.Time `json:"period_start"`
+	PeriodEnd      time.Time `json:"period_end"`
+	Amount         string    `json:"amount"`
+	Currency       string    `json:"currency"`
+}
+
 // PublishSubscriptionCreated publishes a subscription created event
 func (p *BillingEventPublisher) PublishSubscriptionCreated(
 	ctx context.Context,
@@ -268,6 +418,7 @@ func (p *BillingEventPublisher) PublishInvoiceOverdue(
 		InvoiceNumber:  invoice.InvoiceNumber,
 		OrganizationID: invoice.OrganizationID.String(),
 		AmountDue:      invoice.AmountDue.String(),
+		LateFee:        invoice.LateFee.String(),
 		Currency:       invoice.Currency,
 		DueDate:        invoice.DueDate,
 		DaysOverdue:    daysOverdue,
@@ -320,6 +471,34 @@ func (p *BillingEventPublisher) PublishPaymentFailed(
 	return p.publish(ctx, string(EventPaymentFailed), payment.OrganizationID.String(), event)
 }
 
+// PublishPaymentRefunded publishes a payment refunded event
+func (p *BillingEventPublisher) PublishPaymentRefunded(
+	ctx context.Context,
+	payment *models.Payment,
+	refund *models.Refund,
+) error {
+	event := PaymentRefundedEvent{
+		EventID:        generateEventID(),
+		EventType:      string(EventPaymentRefunded),
+		OccurredAt:     time.Now(),
+		RefundID:       refund.ID.String(),
+		PaymentID:      payment.ID.String(),
+		OrganizationID: payment.OrganizationID.String(),
+		InvoiceID:      payment.InvoiceID.String(),
+		Amount:         refund.Amount.String(),
+		AmountRefunded: payment.AmountRefunded.String(),
+		Currency:       refund.Currency,
+		FullyRefunded:  payment.Status == string(PaymentStatusRefunded),
+		Reason:         refund.Reason,
+		RefundedAt:     *refund.RefundedAt,
+	}
+	if refund.CreditNoteID != nil {
+		event.CreditNoteID = refund.CreditNoteID.String()
+	}
+
+	return p.publish(ctx, string(EventPaymentRefunded), payment.OrganizationID.String(), event)
+}
+
 // PublishUsageThresholdReached publishes a usage threshold reached event
 func (p *BillingEventPublisher) PublishUsageThresholdReached(
 	ctx context.Context,
@@ -342,6 +521,30 @@ func (p *BillingEventPublisher) PublishUsageThresholdReached(
 	return p.publish(ctx, string(EventUsageThresholdReached), organizationID, event)
 }
 
+// PublishBudgetThresholdReached publishes a budget threshold reached event
+func (p *BillingEventPublisher) PublishBudgetThresholdReached(
+	ctx context.Context,
+	budget *models.Budget,
+	status *BudgetStatus,
+	threshold int,
+) error {
+	event := BudgetThresholdReachedEvent{
+		EventID:        generateEventID(),
+		EventType:      string(EventBudgetThresholdReached),
+		OccurredAt:     time.Now(),
+		OrganizationID: budget.OrganizationID.String(),
+		BudgetID:       budget.ID.String(),
+		Month:          status.MonthStart.Format("2006-01"),
+		Threshold:      threshold,
+		CurrentSpend:   status.CurrentSpend.String(),
+		ForecastSpend:  status.ForecastSpend.String(),
+		Budget:         budget.MonthlyAmount.String(),
+		Currency:       budget.Currency,
+	}
+
+	return p.publish(ctx, string(EventBudgetThresholdReached), budget.OrganizationID.String(), event)
+}
+
 // PublishCreditApplied publishes a credit applied event
 func (p *BillingEventPublisher) PublishCreditApplied(
 	ctx context.Context,
@@ -364,9 +567,161 @@ func (p *BillingEventPublisher) PublishCreditApplied(
 	return p.publish(ctx, string(EventCreditApplied), credit.OrganizationID.String(), event)
 }
 
-// publish publishes an event to Kafka
+// PublishCouponRedeemed publishes a coupon redeemed event
+func (p *BillingEventPublisher) PublishCouponRedeemed(
+	ctx context.Context,
+	redemption *models.CouponRedemption,
+	promotionCode string,
+) error {
+	event := CouponRedeemedEvent{
+		EventID:        generateEventID(),
+		EventType:      string(EventCouponRedeemed),
+		OccurredAt:     time.Now(),
+		RedemptionID:   redemption.ID.String(),
+		CouponID:       redemption.CouponID.String(),
+		PromotionCode:  promotionCode,
+		OrganizationID: redemption.OrganizationID.String(),
+		SubscriptionID: redemption.SubscriptionID.String(),
+		DiscountType:   redemption.Coupon.DiscountType,
+		Currency:       redemption.Coupon.Currency,
+		Duration:       redemption.Coupon.Duration,
+		EndsAt:         redemption.EndsAt,
+	}
+	if CouponDiscountType(redemption.Coupon.DiscountType) == CouponDiscountPercentage {
+		event.PercentOff = redemption.Coupon.PercentOff.String()
+	} else {
+		event.AmountOff = redemption.Coupon.AmountOff.String()
+	}
+
+	return p.publish(ctx, string(EventCouponRedeemed), redemption.OrganizationID.String(), event)
+}
+
+// PublishDiscountApplied publishes a discount applied event for every coupon
+// discount line item of an invoice
+func (p *BillingEventPublisher) PublishDiscountApplied(
+	ctx context.Context,
+	invoice *models.Invoice,
+) error {
+	for _, lineItem := range invoice.LineItems {
+		if lineItem.ItemType != string(LineItemTypeDiscount) {
+			continue
+		}
+
+		couponID, _ := lineItem.Metadata["coupon_id"].(string)
+		redemptionID, _ := lineItem.Metadata["redemption_id"].(string)
+
+		event := DiscountAppliedEvent{
+			EventID:        generateEventID(),
+			EventType:      string(EventDiscountApplied),
+			OccurredAt:     time.Now(),
+			InvoiceID:      invoice.ID.String(),
+			InvoiceNumber:  invoice.InvoiceNumber,
+			OrganizationID: invoice.OrganizationID.String(),
+			CouponID:       couponID,
+			RedemptionID:   redemptionID,
+			Amount:         lineItem.Amount.Neg().String(),
+			Currency:       invoice.Currency,
+		}
+
+		if err := p.publish(ctx, string(EventDiscountApplied), invoice.OrganizationID.String(), event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PublishPlanChanged publishes a plan changed event, or a plan change
+// scheduled event for changes taking effect at the end of the period
+func (p *BillingEventPublisher) PublishPlanChanged(
+	ctx context.Context,
+	change *models.PlanChange,
+) error {
+	eventType := EventPlanChanged
+	if change.Status == string(PlanChangeStatusScheduled) {
+		eventType = EventPlanChangeScheduled
+	}
+
+	event := PlanChangedEvent{
+		EventID:         generateEventID(),
+		EventType:       string(eventType),
+		OccurredAt:      time.Now(),
+		ChangeID:        change.ID.String(),
+		SubscriptionID:  change.SubscriptionID.String(),
+		OrganizationID:  change.OrganizationID.String(),
+		FromPlanID:      change.FromPlanID.String(),
+		ToPlanID:        change.ToPlanID.String(),
+		ChangeType:      change.ChangeType,
+		Status:          change.Status,
+		EffectiveAt:     change.EffectiveAt,
+		ProrationAmount: change.ProrationAmount.String(),
+		Currency:        change.Currency,
+	}
+
+	return p.publish(ctx, string(eventType), change.OrganizationID.String(), event)
+}
+
+// PublishSubscriptionPaused publishes a subscription paused event
+func (p *BillingEventPublisher) PublishSubscriptionPaused(
+	ctx context.Context,
+	subscription *models.Subscription,
+) error {
+	event := SubscriptionPausedEvent{
+		EventID:        generateEventID(),
+		EventType:      string(EventSubscriptionPaused),
+		OccurredAt:     time.Now(),
+		SubscriptionID: subscription.ID.String(),
+		OrganizationID: subscription.OrganizationID.String(),
+		PausedAt:       *subscription.PausedAt,
+		ResumesAt:      subscription.ResumesAt,
+	}
+
+	return p.publish(ctx, string(EventSubscriptionPaused), subscription.OrganizationID.String(), event)
+}
+
+// PublishSubscriptionResumed publishes a subscription resumed event
+func (p *BillingEventPublisher) PublishSubscriptionResumed(
+	ctx context.Context,
+	subscription *models.Subscription,
+) error {
+	event := SubscriptionResumedEvent{
+		EventID:        generateEventID(),
+		EventType:      string(EventSubscriptionResumed),
+		OccurredAt:     time.Now(),
+		SubscriptionID: subscription.ID.String(),
+		OrganizationID: subscription.OrganizationID.String(),
+		PeriodEnd:      subscription.CurrentPeriodEnd,
+	}
+
+	return p.publish(ctx, string(EventSubscriptionResumed), subscription.OrganizationID.String(), event)
+}
+
+// PublishTrialConverted publishes a trial converted event
+func (p *BillingEventPublisher) PublishTrialConverted(
+	ctx context.Context,
+	subscription *models.Subscription,
+) error {
+	event := TrialConvertedEvent{
+		EventID:        generateEventID(),
+		EventType:      string(EventTrialConverted),
+		OccurredAt:     time.Now(),
+		SubscriptionID: subscription.ID.String(),
+		OrganizationID: subscription.OrganizationID.String(),
+		PlanID:         subscription.PlanID.String(),
+		PlanName:       subscription.Plan.Name,
+		PeriodStart:    subscription.CurrentPeriodStart,
+		PeriodEnd:      subscription.CurrentPeriodEnd,
+		Amount:         subscription.Plan.BasePrice.String(),
+		Currency:       subscription.Plan.Currency,
+	}
+
+	return p.publish(ctx, string(EventTrialConverted), subscription.OrganizationID.String(), event)
+}
+
+// publish publishes an event to Kafka, or enqueues it in the outbox within
+// the transaction of ctx
 func (p *BillingEventPublisher) publish(ctx context.Context, topic string, key string, event interface{}) error {
-	if p.eventBus == nil {
+	if p.eventBus == nil && p.outbox == nil {
 		return fmt.Errorf("event bus not configured")
 	}
 
@@ -381,11 +736,72 @@ func (p *BillingEventPublisher) publish(ctx context.Context, topic string, key s
 		return fmt.Errorf("invalid JSON event")
 	}
 
+	// Billing events are keyed by organization; make sure the tenant travels
+	// with the event even when published from a background job
+	if _, ok := tenant.FromContext(ctx); !ok {
+		ctx = tenant.WithOrganization(ctx, key)
+	}
+
+	// Wrap the event in the canonical envelope, keeping its own ID and time
+	envelope, err := events.NewEnvelope(ctx, topic, eventSource, eventBytes)
+	if err != nil {
+		return err
+	}
+	var header struct {
+		EventID    string    `json:"event_id"`
+		OccurredAt time.Time `json:"occurred_at"`
+	}
+	if err := json.Unmarshal(eventBytes, &header); err == nil {
+		if header.EventID != "" {
+			envelope.EventID = header.EventID
+		}
+		if !header.OccurredAt.IsZero() {
+			envelope.OccurredAt = header.OccurredAt.UTC()
+		}
+	}
+
+	if p.partitioner != nil {
+		topic, key = p.partitioner.Route(envelope)
+	}
+
+	if p.outbox != nil {
+		return events.Enqueue(ctx, conn(ctx, p.outbox), topic, key, envelope)
+	}
+
 	// Publish to event bus
-	return p.eventBus.Publish(ctx, topic, key, event)
+	return p.eventBus.Publish(ctx, topic, key, envelope)
+}
+
+// transact runs change in a transaction of db, then publishes its events
+// with publish. With an outbox publisher, events are enqueued in the same
+// transaction and commit with the change; otherwise they are published once
+// the change commits. Nothing is published when change fails. The ctx of
+// change and publish runs GORM repositories in the transaction.
+func transact(
+	ctx context.Context,
+	db *gorm.DB,
+	publisher *BillingEventPublisher,
+	change func(ctx context.Context) error,
+	publish func(ctx context.Context, publisher *BillingEventPublisher) error,
+) error {
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := withTx(ctx, tx)
+		if err := change(txCtx); err != nil {
+			return err
+		}
+		if publisher.Transactional() {
+			return publish(txCtx, publisher)
+		}
+		return nil
+	})
+	if err != nil || publisher == nil || publisher.Transactional() {
+		return err
+	}
+	return publish(ctx, publisher)
 }
 
-// generateEventID generates a unique event ID
+// generateEventID generates a unique event ID, which is also the dedup key
+// of the event in the outbox
 func generateEventID() string {
-	return fmt.Sprintf("evt_%d", time.Now().UnixNano())
+	return "evt_" + uuid.NewString()
 }
@@ -5,10 +5,12 @@ package billing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
@@ -18,39 +20,101 @@ import (
 type InvoiceService struct {
 	db             *gorm.DB
 	config         *Config
+	invoices       InvoiceRepo
+	subscriptions  SubscriptionRepo
 	pricingEngine  *PricingEngine
 	metricsCollector *MetricsCollector
+	notificationService *NotificationService
+	eventPublisher      *BillingEventPublisher
+	lineage             LineageRecorder
 }
 
-// NewInvoiceService creates a new invoice service
+// LineageRecorder records that a consumer was derived from catalog entities
+// of an organization. *lineage.Service of pkg/database implements it.
+type LineageRecorder interface {
+	RecordDerivation(ctx context.Context, organizationID, consumerKind, consumerID string, catalogIDs []string) error
+}
+
+// NewInvoiceService creates a new invoice service. The notification service
+// and event publisher are optional.
 func NewInvoiceService(
 	db *gorm.DB,
 	config *Config,
+	repos *Repositories,
 	pricingEngine *PricingEngine,
 	metricsCollector *MetricsCollector,
+	notificationService *NotificationService,
+	eventPublisher *BillingEventPublisher,
 ) *InvoiceService {
 	return &InvoiceService{
 		db:             db,
 		config:         config,
+		invoices:       repos.Invoices,
+		subscriptions:  repos.Subscriptions,
 		pricingEngine:  pricingEngine,
 		metricsCollector: metricsCollector,
+		notificationService: notificationService,
+		eventPublisher:      eventPublisher,
 	}
 }
 
-// GenerateInvoice generates an invoice for a subscription billing period
+// SetLineage records each generated invoice as derived from the catalog
+// entities whose usage it bills, i.e. the resource IDs of the usage metrics
+// of its period
+func (is *InvoiceService) SetLineage(recorder LineageRecorder) {
+	is.lineage = recorder
+}
+
+// GenerateInvoice generates an invoice for the current billing period of a
+// subscription. Each period is invoiced once; when the period was already
+// invoiced, its invoice is returned. For plans billed in advance, periods
+// whose base fee was invoiced in advance are invoiced for their usage.
 func (is *InvoiceService) GenerateInvoice(
 	ctx context.Context,
 	subscriptionID string,
 ) (*models.Invoice, error) {
 	// 1. Fetch subscription with plan and organization
-	var subscription models.Subscription
-	if err := is.db.WithContext(ctx).
-		Preload("Plan").
-		Preload("Organization").
-		First(&subscription, "id = ?", subscriptionID).Error; err != nil {
+	subscription, err := is.subscriptions.Get(ctx, subscriptionID)
+	if err != nil {
 		return nil, fmt.Errorf("failed to fetch subscription: %w", err)
 	}
 
+	invoice, _, err := is.generatePeriodInvoice(ctx, subscription)
+	return invoice, err
+}
+
+// generatePeriodInvoice generates the invoice of the billing period of a
+// subscription with its plan and organization loaded, and reports whether it
+// was created by this call
+func (is *InvoiceService) generatePeriodInvoice(
+	ctx context.Context,
+	subscription *models.Subscription,
+) (*models.Invoice, bool, error) {
+	if subscription.Status == string(SubscriptionStatusPaused) {
+		return nil, false, fmt.Errorf("subscription is paused")
+	}
+
+	// Periods whose base fee was invoiced in advance are left with their usage
+	if BillingMode(subscription.Plan.BillingMode) == BillingModeAdvance {
+		advance, err := is.invoiceByIdempotencyKey(ctx, advanceInvoiceKey(subscription))
+		if err != nil {
+			return nil, false, err
+		}
+		if advance != nil {
+			return is.generateUsageInvoice(ctx, subscription, advance)
+		}
+	}
+
+	// Return the invoice of an already invoiced period
+	idempotencyKey := periodInvoiceKey(subscription)
+	existing, err := is.invoiceByIdempotencyKey(ctx, idempotencyKey)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
 	// 2. Fetch usage metrics for the billing period
 	usage, err := is.metricsCollector.GetUsageForPeriod(
 		ctx,
@@ -59,45 +123,204 @@ func (is *InvoiceService) GenerateInvoice(
 		subscription.CurrentPeriodEnd,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch usage metrics: %w", err)
+		return nil, false, fmt.Errorf("failed to fetch usage metrics: %w", err)
 	}
 
 	// 3. Fetch available credits
-	var credits []models.Credit
-	if err := is.db.WithContext(ctx).
-		Where("organization_id = ?", subscription.OrganizationID).
-		Where("status = ?", CreditStatusActive).
-		Where("valid_from <= ?", time.Now()).
-		Where("valid_until IS NULL OR valid_until >= ?", time.Now()).
-		Where("remaining_amount > 0").
-		Order("valid_from ASC").
-		Find(&credits).Error; err != nil {
-		return nil, fmt.Errorf("failed to fetch credits: %w", err)
+	credits, err := is.availableCredits(ctx, subscription.OrganizationID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	redemptions, err := is.activeRedemptions(ctx, subscription.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	seats, err := is.seatSegments(ctx, subscription)
+	if err != nil {
+		return nil, false, err
 	}
 
 	// 4. Calculate charges
 	calc, err := is.pricingEngine.CalculateSubscriptionCharge(
-		&subscription,
+		ctx,
+		subscription,
 		&subscription.Plan,
+		seats,
 		usage,
 		credits,
+		redemptions,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to calculate charges: %w", err)
+		return nil, false, fmt.Errorf("failed to calculate charges: %w", err)
+	}
+
+	return is.createPeriodInvoice(ctx, subscription, idempotencyKey, calc, credits, redemptions)
+}
+
+// GenerateAdvanceInvoice generates the invoice of the base fee of the current
+// billing period of a subscription whose plan is billed in advance. Like
+// period invoices, each period is invoiced in advance once.
+func (is *InvoiceService) GenerateAdvanceInvoice(
+	ctx context.Context,
+	subscriptionID string,
+) (*models.Invoice, error) {
+	subscription, err := is.subscriptions.Get(ctx, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription: %w", err)
+	}
+
+	invoice, _, err := is.generateAdvanceInvoice(ctx, subscription)
+	return invoice, err
+}
+
+// generateAdvanceInvoice generates the advance invoice of the billing period
+// of a subscription with its plan and organization loaded, and reports
+// whether it was created by this call
+func (is *InvoiceService) generateAdvanceInvoice(
+	ctx context.Context,
+	subscription *models.Subscription,
+) (*models.Invoice, bool, error) {
+	if BillingMode(subscription.Plan.BillingMode) != BillingModeAdvance {
+		return nil, false, fmt.Errorf("plan %s is billed in arrears", subscription.Plan.Slug)
+	}
+	switch SubscriptionStatus(subscription.Status) {
+	case SubscriptionStatusActive, SubscriptionStatusPastDue:
+	default:
+		return nil, false, fmt.Errorf("cannot invoice a %s subscription in advance", subscription.Status)
+	}
+
+	idempotencyKey := advanceInvoiceKey(subscription)
+	existing, err := is.invoiceByIdempotencyKey(ctx, idempotencyKey)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	// A period invoiced in arrears already includes its base fee
+	invoiced, err := is.invoiceByIdempotencyKey(ctx, periodInvoiceKey(subscription))
+	if err != nil {
+		return nil, false, err
+	}
+	if invoiced != nil {
+		return nil, false, fmt.Errorf("period was already invoiced by invoice %s", invoiced.InvoiceNumber)
+	}
+
+	credits, err := is.availableCredits(ctx, subscription.OrganizationID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	redemptions, err := is.activeRedemptions(ctx, subscription.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	calc, err := is.pricingEngine.CalculateAdvanceCharge(
+		ctx,
+		subscription,
+		&subscription.Plan,
+		credits,
+		redemptions,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to calculate charges: %w", err)
+	}
+
+	return is.createPeriodInvoice(ctx, subscription, idempotencyKey, calc, credits, redemptions)
+}
+
+// generateUsageInvoice generates the invoice of the usage of the billing
+// period of a subscription whose base fee was invoiced in advance. Periods
+// without usage charges are not invoiced again: their advance invoice is
+// returned instead.
+func (is *InvoiceService) generateUsageInvoice(
+	ctx context.Context,
+	subscription *models.Subscription,
+	advance *models.Invoice,
+) (*models.Invoice, bool, error) {
+	idempotencyKey := usageInvoiceKey(subscription)
+	existing, err := is.invoiceByIdempotencyKey(ctx, idempotencyKey)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	usage, err := is.metricsCollector.GetUsageForPeriod(
+		ctx,
+		subscription.OrganizationID.String(),
+		subscription.CurrentPeriodStart,
+		subscription.CurrentPeriodEnd,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch usage metrics: %w", err)
 	}
 
-	// 5. Generate invoice number
-	invoiceNumber, err := is.generateInvoiceNumber(ctx)
+	credits, err := is.availableCredits(ctx, subscription.OrganizationID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate invoice number: %w", err)
+		return nil, false, err
 	}
 
-	// 6. Create invoice record
+	calc, err := is.pricingEngine.CalculateUsageCharge(
+		ctx,
+		subscription,
+		&subscription.Plan,
+		usage,
+		credits,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to calculate charges: %w", err)
+	}
+	if calc.Subtotal.IsZero() {
+		return advance, false, nil
+	}
+
+	return is.createPeriodInvoice(ctx, subscription, idempotencyKey, calc, credits, nil)
+}
+
+// availableCredits returns the active credits of an organization that can be
+// applied now, oldest first
+func (is *InvoiceService) availableCredits(
+	ctx context.Context,
+	organizationID uuid.UUID,
+) ([]models.Credit, error) {
+	var credits []models.Credit
+	if err := is.db.WithContext(ctx).
+		Where("organization_id = ?", organizationID).
+		Where("status = ?", CreditStatusActive).
+		Where("valid_from <= ?", time.Now()).
+		Where("valid_until IS NULL OR valid_until >= ?", time.Now()).
+		Where("remaining_amount > 0").
+		Order("valid_from ASC").
+		Find(&credits).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch credits: %w", err)
+	}
+	return credits, nil
+}
+
+// createPeriodInvoice saves the invoice of the charges of the current period
+// of a subscription under an idempotency key, applying its credits and
+// counting the period on the applied coupon redemptions. When a concurrent
+// call saved an invoice with the key first, that invoice is returned.
+func (is *InvoiceService) createPeriodInvoice(
+	ctx context.Context,
+	subscription *models.Subscription,
+	idempotencyKey string,
+	calc *ChargeCalculation,
+	credits []models.Credit,
+	redemptions []models.CouponRedemption,
+) (*models.Invoice, bool, error) {
+	// 5. Create invoice record
 	invoice := &models.Invoice{
 		ID:             uuid.New(),
 		OrganizationID: subscription.OrganizationID,
 		SubscriptionID: subscription.ID,
-		InvoiceNumber:  invoiceNumber,
+		IdempotencyKey: &idempotencyKey,
 		PeriodStart:    subscription.CurrentPeriodStart,
 		PeriodEnd:      subscription.CurrentPeriodEnd,
 		Subtotal:       calc.Subtotal,
@@ -110,8 +333,11 @@ func (is *InvoiceService) GenerateInvoice(
 		InvoiceDate:    time.Now(),
 		DueDate:        time.Now().AddDate(0, 0, is.config.Invoice.DueDays),
 	}
+	for _, lineItem := range calc.LineItems {
+		invoice.LineItems = append(invoice.LineItems, *newLineItemModel(invoice.ID, lineItem))
+	}
 
-	// 7. Begin transaction
+	// 6. Begin transaction
 	tx := is.db.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -119,78 +345,258 @@ func (is *InvoiceService) GenerateInvoice(
 		}
 	}()
 
-	// 8. Save invoice
-	if err := tx.Create(invoice).Error; err != nil {
+	// 7. Generate invoice number
+	invoiceNumber, err := is.generateInvoiceNumber(ctx, tx)
+	if err != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("failed to create invoice: %w", err)
+		return nil, false, fmt.Errorf("failed to generate invoice number: %w", err)
 	}
+	invoice.InvoiceNumber = invoiceNumber
 
-	// 9. Save line items
-	for _, lineItem := range calc.LineItems {
-		dbLineItem := &models.InvoiceLineItem{
-			ID:          uuid.New(),
-			InvoiceID:   invoice.ID,
-			Description: lineItem.Description,
-			Quantity:    lineItem.Quantity,
-			UnitPrice:   lineItem.UnitPrice,
-			Amount:      lineItem.Amount,
-			ItemType:    string(lineItem.ItemType),
-			MetricType:  string(lineItem.MetricType),
-			PeriodStart: lineItem.PeriodStart,
-			PeriodEnd:   lineItem.PeriodEnd,
-			Metadata:    models.JSONB(lineItem.Metadata),
+	// 8. Save invoice with its line items, unless a concurrent call invoiced
+	// the period first
+	created, err := is.invoices.Create(withTx(ctx, tx), invoice)
+	if err != nil {
+		tx.Rollback()
+		return nil, false, fmt.Errorf("failed to create invoice: %w", err)
+	}
+	if !created {
+		tx.Rollback()
+		existing, err := is.invoiceByIdempotencyKey(ctx, idempotencyKey)
+		if err != nil {
+			return nil, false, err
 		}
-
-		if err := tx.Create(dbLineItem).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("failed to create line item: %w", err)
+		if existing == nil {
+			return nil, false, fmt.Errorf("invoice for period %s was not found", idempotencyKey)
 		}
+		return existing, false, nil
 	}
 
-	// 10. Update credits if applied
+	// 9. Update credits if applied
 	if calc.Credits.GreaterThan(decimal.Zero) {
 		if err := is.applyCreditsToInvoice(tx, credits, calc.Credits); err != nil {
 			tx.Rollback()
-			return nil, fmt.Errorf("failed to apply credits: %w", err)
+			return nil, false, fmt.Errorf("failed to apply credits: %w", err)
+		}
+	}
+
+	// 10. Track coupon periods
+	if calc.Discount.GreaterThan(decimal.Zero) {
+		if err := is.applyDiscountsToInvoice(tx, calc, redemptions, invoice.PeriodEnd); err != nil {
+			tx.Rollback()
+			return nil, false, fmt.Errorf("failed to apply discounts: %w", err)
 		}
 	}
 
 	// 11. Commit transaction
 	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	// 12. Load invoice with line items
+	invoice, err = is.invoices.Get(ctx, invoice.ID.String())
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reload invoice: %w", err)
+	}
+
+	// 13. Record the entities the invoice was derived from
+	if err := is.recordLineage(ctx, invoice); err != nil {
+		return nil, false, err
+	}
+
+	return invoice, true, nil
+}
+
+// periodInvoiceKey returns the idempotency key of the invoice for the current
+// billing period of a subscription
+func periodInvoiceKey(subscription *models.Subscription) string {
+	return fmt.Sprintf("subscription:%s:%d-%d",
+		subscription.ID,
+		subscription.CurrentPeriodStart.Unix(),
+		subscription.CurrentPeriodEnd.Unix(),
+	)
+}
+
+// advanceInvoiceKey returns the idempotency key of the invoice billing the
+// base fee of the current period of a subscription in advance
+func advanceInvoiceKey(subscription *models.Subscription) string {
+	return periodInvoiceKey(subscription) + ":advance"
+}
+
+// usageInvoiceKey returns the idempotency key of the invoice billing the usage
+// of the current period of a subscription billed in advance
+func usageInvoiceKey(subscription *models.Subscription) string {
+	return periodInvoiceKey(subscription) + ":usage"
+}
+
+// seatSegments splits the current period of a subscription into segments
+// with a constant seat count, from the seat changes made during the period.
+// Without proration the period is charged at the current seat count.
+func (is *InvoiceService) seatSegments(
+	ctx context.Context,
+	subscription *models.Subscription,
+) ([]SeatSegment, error) {
+	if !is.config.Features.EnableProration {
+		return nil, nil
+	}
+
+	var changes []models.SeatChange
 	if err := is.db.WithContext(ctx).
-		Preload("LineItems").
-		Preload("Organization").
-		Preload("Subscription").
-		First(invoice, "id = ?", invoice.ID).Error; err != nil {
-		return nil, fmt.Errorf("failed to reload invoice: %w", err)
+		Where("subscription_id = ?", subscription.ID).
+		Where("effective_at > ?", subscription.CurrentPeriodStart).
+		Order("effective_at ASC, created_at ASC").
+		Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch seat changes: %w", err)
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	periodEnd := subscription.CurrentPeriodEnd
+	segments := []SeatSegment{{
+		Quantity: changes[0].FromQuantity,
+		Start:    subscription.CurrentPeriodStart,
+	}}
+	for _, change := range changes {
+		if !change.EffectiveAt.Before(periodEnd) {
+			break
+		}
+		last := &segments[len(segments)-1]
+		if change.ToQuantity == last.Quantity {
+			continue
+		}
+		if !change.EffectiveAt.After(last.Start) {
+			last.Quantity = change.ToQuantity
+			continue
+		}
+		last.End = change.EffectiveAt
+		segments = append(segments, SeatSegment{
+			Quantity: change.ToQuantity,
+			Start:    change.EffectiveAt,
+		})
 	}
+	segments[len(segments)-1].End = periodEnd
 
+	return segments, nil
+}
+
+// invoiceByIdempotencyKey returns the invoice with an idempotency key, or nil
+// if there is none
+func (is *InvoiceService) invoiceByIdempotencyKey(ctx context.Context, key string) (*models.Invoice, error) {
+	invoice, err := is.invoices.GetByIdempotencyKey(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch invoice: %w", err)
+	}
 	return invoice, nil
 }
 
-// generateInvoiceNumber generates a unique invoice number
-func (is *InvoiceService) generateInvoiceNumber(ctx context.Context) (string, error) {
-	// Get the latest invoice for the current year
-	var count int64
-	year := time.Now().Year()
-	prefix := fmt.Sprintf("%s%d-", is.config.Invoice.NumberPrefix, year)
+// GenerateProrationInvoice generates an invoice for the prorated charge of
+// an immediate plan change, covering the rest of the current period
+func (is *InvoiceService) GenerateProrationInvoice(
+	ctx context.Context,
+	subscription *models.Subscription,
+	calc *ChargeCalculation,
+	changeDate time.Time,
+) (*models.Invoice, error) {
+	now := time.Now()
+	invoice := &models.Invoice{
+		ID:             uuid.New(),
+		OrganizationID: subscription.OrganizationID,
+		SubscriptionID: subscription.ID,
+		PeriodStart:    changeDate,
+		PeriodEnd:      subscription.CurrentPeriodEnd,
+		Subtotal:       calc.Subtotal,
+		TaxAmount:      calc.TaxAmount,
+		TotalAmount:    calc.Total,
+		AmountDue:      calc.Total,
+		AmountPaid:     decimal.Zero,
+		Currency:       subscription.Plan.Currency,
+		Status:         string(InvoiceStatusOpen),
+		InvoiceDate:    now,
+		DueDate:        now.AddDate(0, 0, is.config.Invoice.DueDays),
+	}
+	for _, lineItem := range calc.LineItems {
+		invoice.LineItems = append(invoice.LineItems, *newLineItemModel(invoice.ID, lineItem))
+	}
 
-	err := is.db.WithContext(ctx).
-		Model(&models.Invoice{}).
-		Where("invoice_number LIKE ?", prefix+"%").
-		Count(&count).Error
+	err := is.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		invoiceNumber, err := is.generateInvoiceNumber(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("failed to generate invoice number: %w", err)
+		}
+		invoice.InvoiceNumber = invoiceNumber
 
+		if _, err := is.invoices.Create(withTx(ctx, tx), invoice); err != nil {
+			return fmt.Errorf("failed to create invoice: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if err := is.recordLineage(ctx, invoice); err != nil {
+		return nil, err
+	}
+
+	return is.GetInvoice(ctx, invoice.ID.String())
+}
+
+// recordLineage records an invoice as derived from the catalog entities whose
+// usage was metered in its period
+func (is *InvoiceService) recordLineage(ctx context.Context, invoice *models.Invoice) error {
+	if is.lineage == nil {
+		return nil
+	}
+
+	var catalogIDs []string
+	err := is.db.WithContext(ctx).
+		Model(&models.UsageMetric{}).
+		Where("organization_id = ? AND resource_id <> ''", invoice.OrganizationID).
+		Where("period_start >= ? AND period_end <= ?", invoice.PeriodStart, invoice.PeriodEnd).
+		Distinct().
+		Pluck("resource_id", &catalogIDs).Error
+	if err != nil {
+		return fmt.Errorf("failed to fetch invoiced resources: %w", err)
+	}
+	if len(catalogIDs) == 0 {
+		return nil
+	}
+
+	if err := is.lineage.RecordDerivation(ctx, invoice.OrganizationID.String(), "invoice", invoice.ID.String(), catalogIDs); err != nil {
+		return fmt.Errorf("failed to record invoice lineage: %w", err)
+	}
+	return nil
+}
+
+// newLineItemModel converts a calculated line item into its database model
+func newLineItemModel(invoiceID uuid.UUID, lineItem InvoiceLineItem) *models.InvoiceLineItem {
+	return &models.InvoiceLineItem{
+		ID:          uuid.New(),
+		InvoiceID:   invoiceID,
+		Description: lineItem.Description,
+		Quantity:    lineItem.Quantity,
+		UnitPrice:   lineItem.UnitPrice,
+		Amount:      lineItem.Amount,
+		ItemType:    string(lineItem.ItemType),
+		MetricType:  string(lineItem.MetricType),
+		PeriodStart: lineItem.PeriodStart,
+		PeriodEnd:   lineItem.PeriodEnd,
+		Metadata:    models.JSONB(lineItem.Metadata),
+	}
+}
+
+// generateInvoiceNumber allocates the next invoice number. With gapless
+// numbering the number is allocated within tx, so invoices that are rolled
+// back release their number and invoices are numbered one at a time.
+// Otherwise it is committed immediately and rolled back invoices leave gaps.
+func (is *InvoiceService) generateInvoiceNumber(ctx context.Context, tx *gorm.DB) (string, error) {
+	db := is.db.WithContext(ctx)
+	if is.config.Invoice.GaplessNumbering {
+		db = tx
 	}
 
 	// Generate invoice number: INV-2025-001234
-	invoiceNumber := fmt.Sprintf("%s%06d", prefix, count+1)
-	return invoiceNumber, nil
+	return nextDocumentNumber(db, is.config.Invoice.NumberPrefix, time.Now().Year())
 }
 
 // applyCreditsToInvoice deducts credits and updates their remaining amounts
@@ -230,84 +636,132 @@ func (is *InvoiceService) applyCreditsToInvoice(
 	return nil
 }
 
+// activeRedemptions returns the active coupon redemptions of a subscription
+func (is *InvoiceService) activeRedemptions(
+	ctx context.Context,
+	subscriptionID uuid.UUID,
+) ([]models.CouponRedemption, error) {
+	var redemptions []models.CouponRedemption
+	if err := is.db.WithContext(ctx).
+		Preload("Coupon").
+		Where("subscription_id = ?", subscriptionID).
+		Where("status = ?", RedemptionStatusActive).
+		Order("redeemed_at ASC").
+		Find(&redemptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch coupon redemptions: %w", err)
+	}
+	return redemptions, nil
+}
+
+// applyDiscountsToInvoice counts the invoiced period on each applied coupon
+// redemption and expires redemptions that will not apply again
+func (is *InvoiceService) applyDiscountsToInvoice(
+	tx *gorm.DB,
+	calc *ChargeCalculation,
+	redemptions []models.CouponRedemption,
+	periodEnd time.Time,
+) error {
+	applied := make(map[string]bool)
+	for _, lineItem := range calc.LineItems {
+		if lineItem.ItemType != LineItemTypeDiscount {
+			continue
+		}
+		if id, ok := lineItem.Metadata["redemption_id"].(string); ok {
+			applied[id] = true
+		}
+	}
+
+	for i := range redemptions {
+		redemption := &redemptions[i]
+		if !applied[redemption.ID.String()] {
+			continue
+		}
+
+		updates := map[string]interface{}{
+			"periods_applied": gorm.Expr("periods_applied + 1"),
+		}
+
+		switch CouponDuration(redemption.Coupon.Duration) {
+		case CouponDurationOnce:
+			updates["status"] = RedemptionStatusExpired
+		case CouponDurationRepeating:
+			if redemption.EndsAt != nil && !periodEnd.Before(*redemption.EndsAt) {
+				updates["status"] = RedemptionStatusExpired
+			}
+		}
+
+		if err := tx.Model(redemption).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // FinalizeInvoice marks an invoice as finalized and ready for payment
 func (is *InvoiceService) FinalizeInvoice(ctx context.Context, invoiceID string) error {
-	return is.db.WithContext(ctx).
-		Model(&models.Invoice{}).
-		Where("id = ?", invoiceID).
-		Where("status = ?", InvoiceStatusDraft).
-		Update("status", InvoiceStatusOpen).Error
+	return is.invoices.SetStatus(ctx, invoiceID, InvoiceStatusOpen, InvoiceStatusDraft)
 }
 
-// MarkInvoiceAsPaid marks an invoice as paid
+// MarkInvoiceAsPaid marks an invoice as paid and publishes the invoice paid
+// event with the change. Paying an invoice that
+// suspended its organization reactivates the organization, unless another
+// invoice still suspends it.
 func (is *InvoiceService) MarkInvoiceAsPaid(
 	ctx context.Context,
 	invoiceID string,
 	paymentID string,
 	paidAmount decimal.Decimal,
 ) error {
-	now := time.Now()
-
-	updates := map[string]interface{}{
-		"amount_paid": paidAmount,
-		"status":      InvoiceStatusPaid,
-		"paid_at":     now,
+	var invoice *models.Invoice
+	err := transact(ctx, is.db, is.eventPublisher,
+		func(ctx context.Context) error {
+			if err := is.invoices.MarkPaid(ctx, invoiceID, paidAmount, time.Now()); err != nil {
+				return err
+			}
+			var err error
+			if invoice, err = is.invoices.Get(ctx, invoiceID); err != nil {
+				return fmt.Errorf("failed to fetch invoice: %w", err)
+			}
+			return nil
+		},
+		func(ctx context.Context, publisher *BillingEventPublisher) error {
+			if err := publisher.PublishInvoicePaid(ctx, invoice, paymentID); err != nil {
+				return fmt.Errorf("failed to publish invoice paid event: %w", err)
+			}
+			return nil
+		})
+	if err != nil {
+		return err
 	}
-
-	return is.db.WithContext(ctx).
-		Model(&models.Invoice{}).
-		Where("id = ?", invoiceID).
-		Updates(updates).Error
+	if invoice.SuspendedAt == nil {
+		return nil
+	}
+	return reactivateOrganization(is.db.WithContext(ctx), invoice.OrganizationID)
 }
 
 // VoidInvoice voids an invoice
 func (is *InvoiceService) VoidInvoice(ctx context.Context, invoiceID string) error {
-	return is.db.WithContext(ctx).
-		Model(&models.Invoice{}).
-		Where("id = ?", invoiceID).
-		Where("status != ?", InvoiceStatusPaid).
-		Update("status", InvoiceStatusVoid).Error
+	return is.invoices.SetStatus(ctx, invoiceID, InvoiceStatusVoid,
+		InvoiceStatusDraft, InvoiceStatusOpen, InvoiceStatusPastDue, InvoiceStatusUncollectible)
 }
 
 // GetInvoice retrieves an invoice by ID
 func (is *InvoiceService) GetInvoice(ctx context.Context, invoiceID string) (*models.Invoice, error) {
-	var invoice models.Invoice
-	err := is.db.WithContext(ctx).
-		Preload("LineItems").
-		Preload("Organization").
-		Preload("Subscription").
-		First(&invoice, "id = ?", invoiceID).Error
-
-	if err != nil {
-		return nil, err
-	}
-
-	return &invoice, nil
+	return is.invoices.Get(ctx, invoiceID)
 }
 
-// ListInvoices retrieves invoices for an organization
+// ListInvoices retrieves invoices for the organization of the tenant in ctx
 func (is *InvoiceService) ListInvoices(
 	ctx context.Context,
-	organizationID string,
 	limit, offset int,
 ) ([]models.Invoice, error) {
-	var invoices []models.Invoice
-
-	query := is.db.WithContext(ctx).
-		Preload("LineItems").
-		Where("organization_id = ?", organizationID).
-		Order("invoice_date DESC")
-
-	if limit > 0 {
-		query = query.Limit(limit)
-	}
-
-	if offset > 0 {
-		query = query.Offset(offset)
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	err := query.Find(&invoices).Error
-	return invoices, err
+	return is.invoices.ListByOrganization(ctx, organizationID, limit, offset)
 }
 
 // GetUpcomingInvoice calculates what the next invoice will look like
@@ -316,11 +770,8 @@ func (is *InvoiceService) GetUpcomingInvoice(
 	subscriptionID string,
 ) (*models.Invoice, error) {
 	// 1. Fetch subscription
-	var subscription models.Subscription
-	if err := is.db.WithContext(ctx).
-		Preload("Plan").
-		Preload("Organization").
-		First(&subscription, "id = ?", subscriptionID).Error; err != nil {
+	subscription, err := is.subscriptions.Get(ctx, subscriptionID)
+	if err != nil {
 		return nil, fmt.Errorf("failed to fetch subscription: %w", err)
 	}
 
@@ -345,13 +796,42 @@ func (is *InvoiceService) GetUpcomingInvoice(
 		return nil, fmt.Errorf("failed to fetch credits: %w", err)
 	}
 
-	// 4. Calculate charges
-	calc, err := is.pricingEngine.CalculateSubscriptionCharge(
-		&subscription,
-		&subscription.Plan,
-		usage,
-		credits,
-	)
+	// 4. Calculate charges, of the usage only when the base fee of the period
+	// was invoiced in advance
+	var advance *models.Invoice
+	if BillingMode(subscription.Plan.BillingMode) == BillingModeAdvance {
+		advance, err = is.invoiceByIdempotencyKey(ctx, advanceInvoiceKey(subscription))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var calc *ChargeCalculation
+	if advance != nil {
+		calc, err = is.pricingEngine.CalculateUsageCharge(ctx, subscription, &subscription.Plan, usage, credits)
+	} else {
+		var redemptions []models.CouponRedemption
+		redemptions, err = is.activeRedemptions(ctx, subscription.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		var seats []SeatSegment
+		seats, err = is.seatSegments(ctx, subscription)
+		if err != nil {
+			return nil, err
+		}
+
+		calc, err = is.pricingEngine.CalculateSubscriptionCharge(
+			ctx,
+			subscription,
+			&subscription.Plan,
+			seats,
+			usage,
+			credits,
+			redemptions,
+		)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate charges: %w", err)
 	}
@@ -372,7 +852,7 @@ func (is *InvoiceService) GetUpcomingInvoice(
 		InvoiceDate:    subscription.CurrentPeriodEnd,
 		DueDate:        subscription.CurrentPeriodEnd.AddDate(0, 0, is.config.Invoice.DueDays),
 		Organization:   subscription.Organization,
-		Subscription:   subscription,
+		Subscription:   *subscription,
 	}
 
 	// Convert line items
@@ -392,29 +872,134 @@ func (is *InvoiceService) GetUpcomingInvoice(
 	return invoice, nil
 }
 
-// ProcessOverdueInvoices marks overdue invoices and triggers notifications
+// ProcessOverdueInvoices moves open invoices past their due date to past due,
+// adding the configured late fee, and notifies their organizations. Once
+// Invoice.SuspensionGrace has passed since the due date of an invoice still
+// past due, its organization is suspended.
 func (is *InvoiceService) ProcessOverdueInvoices(ctx context.Context) error {
 	now := time.Now()
 
-	// Find invoices that are past due
-	var overdueInvoices []models.Invoice
-	err := is.db.WithContext(ctx).
-		Where("status = ?", InvoiceStatusOpen).
-		Where("due_date < ?", now).
-		Find(&overdueInvoices).Error
-
+	overdueInvoices, err := is.invoices.ListOverdue(ctx, now)
 	if err != nil {
 		return fmt.Errorf("failed to fetch overdue invoices: %w", err)
 	}
 
-	for _, invoice := range overdueInvoices {
-		// Update status (in a real implementation, you might have different overdue statuses)
-		// For now, we'll just trigger an event for notification
-		// The invoice remains "open" but we can track it's overdue by comparing due_date
+	var errs []error
+	for i := range overdueInvoices {
+		invoice := &overdueInvoices[i]
+		orgCtx := tenant.WithOrganization(ctx, invoice.OrganizationID.String())
+		if err := is.markPastDue(orgCtx, invoice, now); err != nil {
+			errs = append(errs, fmt.Errorf("invoice %s: %w", invoice.ID, err))
+		}
+	}
+
+	grace := is.config.Invoice.SuspensionGrace
+	if grace <= 0 {
+		return errors.Join(errs...)
+	}
+
+	unsuspended, err := is.invoices.ListUnsuspended(ctx, now.Add(-grace))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to fetch past due invoices: %w", err))
+		return errors.Join(errs...)
+	}
+	for i := range unsuspended {
+		invoice := &unsuspended[i]
+		orgCtx := tenant.WithOrganization(ctx, invoice.OrganizationID.String())
+		if err := is.suspendForInvoice(orgCtx, invoice, now); err != nil {
+			errs = append(errs, fmt.Errorf("invoice %s: %w", invoice.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// markPastDue moves an overdue invoice to past due with its late fee and
+// publishes the overdue event with the change, then notifies the organization
+func (is *InvoiceService) markPastDue(ctx context.Context, invoice *models.Invoice, now time.Time) error {
+	var lateFee *models.InvoiceLineItem
+	if fee := is.lateFee(invoice); fee.IsPositive() {
+		lateFee = newLineItemModel(invoice.ID, InvoiceLineItem{
+			Description: "Late payment fee",
+			Quantity:    decimal.NewFromInt(1),
+			UnitPrice:   fee,
+			Amount:      fee,
+			ItemType:    LineItemTypeLateFee,
+		})
+	}
+
+	var marked bool
+	err := transact(ctx, is.db, is.eventPublisher,
+		func(ctx context.Context) error {
+			var err error
+			if marked, err = is.invoices.MarkPastDue(ctx, invoice, lateFee, now); err != nil {
+				return fmt.Errorf("failed to mark invoice past due: %w", err)
+			}
+			return nil
+		},
+		func(ctx context.Context, publisher *BillingEventPublisher) error {
+			if !marked {
+				return nil
+			}
+			if err := publisher.PublishInvoiceOverdue(ctx, invoice); err != nil {
+				return fmt.Errorf("failed to publish overdue event: %w", err)
+			}
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+	if !marked {
+		// Paid or voided since it was listed
+		return nil
+	}
+
+	if is.notificationService != nil {
+		if err := is.notificationService.SendInvoiceOverdueNotification(ctx, invoice); err != nil {
+			return fmt.Errorf("failed to send overdue notification: %w", err)
+		}
+	}
+	return nil
+}
 
-		// TODO: Publish event for overdue invoice
-		// eventBus.Publish(EventInvoiceOverdue, invoice)
+// lateFee returns the late fee of an overdue invoice: the fixed fee plus a
+// fraction of the amount due
+func (is *InvoiceService) lateFee(invoice *models.Invoice) decimal.Decimal {
+	if !invoice.AmountDue.IsPositive() {
+		return decimal.Zero
 	}
+	return is.config.Invoice.LateFeeFixed.
+		Add(invoice.AmountDue.Mul(is.config.Invoice.LateFeePercent)).
+		Round(2)
+}
 
+// suspendForInvoice suspends the organization of an invoice that stayed past
+// due beyond the grace window and notifies it
+func (is *InvoiceService) suspendForInvoice(ctx context.Context, invoice *models.Invoice, now time.Time) error {
+	err := is.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := is.invoices.MarkSuspended(withTx(ctx, tx), invoice.ID.String(), now); err != nil {
+			return fmt.Errorf("failed to mark invoice suspended: %w", err)
+		}
+		if err := tx.Model(&models.Organization{}).
+			Where("id = ? AND status = ?", invoice.OrganizationID, OrganizationStatusActive).
+			Update("status", OrganizationStatusSuspended).Error; err != nil {
+			return fmt.Errorf("failed to suspend organization: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if is.notificationService == nil || invoice.SubscriptionID == uuid.Nil {
+		return nil
+	}
+	subscription, err := is.subscriptions.Get(ctx, invoice.SubscriptionID.String())
+	if err != nil {
+		return fmt.Errorf("failed to fetch subscription: %w", err)
+	}
+	if err := is.notificationService.SendSubscriptionSuspendedNotification(ctx, subscription, invoice); err != nil {
+		return fmt.Errorf("failed to send suspension notification: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/scheduler"
+)
+
+// Scheduled job names
+const (
+	JobUsageAggregation = "billing.usage_aggregation"
+	JobOverdueInvoices  = "billing.overdue_invoices"
+	JobDunningRetries   = "billing.dunning_retries"
+	JobSubscriptions    = "billing.subscription_lifecycle"
+	JobTrialReminders   = "billing.trial_reminders"
+	JobBillingRuns      = "billing.billing_runs"
+	JobBudgetAlerts     = "billing.budget_alerts"
+	JobMeteringPrune    = "billing.metering_prune"
+	JobPriceChanges     = "billing.price_change_notices"
+	JobReconciliation   = "billing.payout_reconciliation"
+)
+
+// RegisterJobs registers the billing background jobs with the scheduler. The
+// dunning, subscription, billing run, budget, plan and reconciliation services
+// are optional.
+func RegisterJobs(
+	s *scheduler.Scheduler,
+	config *Config,
+	metricsCollector *MetricsCollector,
+	invoiceService *InvoiceService,
+	dunningService *DunningService,
+	subscriptionService *SubscriptionService,
+	billingRunService *BillingRunService,
+	budgetService *BudgetService,
+	planService *PlanService,
+	reconciliationService *ReconciliationService,
+) error {
+	if config.Features.EnableUsageMetrics {
+		err := s.Register(scheduler.Job{
+			Name:     JobUsageAggregation,
+			Schedule: scheduler.Every(config.Usage.AggregationInterval),
+			Timeout:  config.Usage.AggregationInterval,
+			Run:      metricsCollector.AggregateUsageMetrics,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register usage aggregation job: %w", err)
+		}
+	}
+
+	if config.Metering.Enabled {
+		err := s.Register(scheduler.Job{
+			Name:     JobMeteringPrune,
+			Schedule: scheduler.Every(time.Hour),
+			Timeout:  15 * time.Minute,
+			Run:      metricsCollector.PruneUsageEventKeys,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register metering prune job: %w", err)
+		}
+	}
+
+	err := s.Register(scheduler.Job{
+		Name:     JobOverdueInvoices,
+		Schedule: config.Invoice.OverdueSchedule,
+		Timeout:  30 * time.Minute,
+		Run:      invoiceService.ProcessOverdueInvoices,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register overdue invoices job: %w", err)
+	}
+
+	if config.Dunning.Enabled && dunningService != nil {
+		err := s.Register(scheduler.Job{
+			Name:     JobDunningRetries,
+			Schedule: config.Dunning.Schedule,
+			Timeout:  30 * time.Minute,
+			Run:      dunningService.ProcessRetries,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register dunning retries job: %w", err)
+		}
+	}
+
+	if subscriptionService != nil {
+		err := s.Register(scheduler.Job{
+			Name:     JobSubscriptions,
+			Schedule: config.Subscriptions.LifecycleSchedule,
+			Timeout:  15 * time.Minute,
+			Run:      subscriptionService.ProcessLifecycle,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register subscription lifecycle job: %w", err)
+		}
+
+		err = s.Register(scheduler.Job{
+			Name:     JobTrialReminders,
+			Schedule: config.Subscriptions.TrialReminderSchedule,
+			Timeout:  15 * time.Minute,
+			Run:      subscriptionService.ProcessTrialReminders,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register trial reminders job: %w", err)
+		}
+	}
+
+	if billingRunService != nil {
+		err := s.Register(scheduler.Job{
+			Name:     JobBillingRuns,
+			Schedule: config.BillingRuns.Schedule,
+			Timeout:  time.Hour,
+			Run:      billingRunService.ProcessDue,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register billing runs job: %w", err)
+		}
+	}
+
+	if budgetService != nil {
+		err := s.Register(scheduler.Job{
+			Name:     JobBudgetAlerts,
+			Schedule: config.Budgets.AlertSchedule,
+			Timeout:  30 * time.Minute,
+			Run:      budgetService.CheckBudgets,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register budget alerts job: %w", err)
+		}
+	}
+
+	if planService != nil {
+		err := s.Register(scheduler.Job{
+			Name:     JobPriceChanges,
+			Schedule: config.Plans.NoticeSchedule,
+			Timeout:  30 * time.Minute,
+			Run:      planService.ProcessPriceChangeNotices,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register price change notices job: %w", err)
+		}
+	}
+
+	if reconciliationService != nil {
+		err := s.Register(scheduler.Job{
+			Name:     JobReconciliation,
+			Schedule: config.Reconciliation.Schedule,
+			Timeout:  time.Hour,
+			Run:      reconciliationService.ReconcilePayouts,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register payout reconciliation job: %w", err)
+		}
+	}
+
+	return nil
+}
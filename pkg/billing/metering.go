@@ -0,0 +1,355 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/segmentio/kafka-go"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// UsageSourceKafka is the source recorded with usage ingested from the
+// metering topic
+const UsageSourceKafka = "kafka"
+
+// meteredMetrics are the metric types written through Kafka when metering is
+// enabled; the usage aggregation job skips them
+var meteredMetrics = map[MetricType]bool{
+	MetricTypeAPICalls:      true,
+	MetricTypeTransferGBIn:  true,
+	MetricTypeTransferGBOut: true,
+}
+
+// eventKeyChunkSize bounds the number of event keys inserted per statement
+const eventKeyChunkSize = 1000
+
+// UsageEvent is a usage event of the metering topic. Field names are kept
+// short since an event is published per request.
+type UsageEvent struct {
+	Key            string          `json:"k"` // Unique per recorded usage, used to drop redelivered events
+	OrganizationID string          `json:"o"`
+	MetricType     MetricType      `json:"m"`
+	Value          decimal.Decimal `json:"v"` // In the unit of the metric type (count, GB)
+	Time           int64           `json:"t"` // Unix milliseconds
+}
+
+// UsageMeter publishes usage events to the metering topic. Events are
+// batched and written in the background, so recording usage never waits for
+// Kafka; events that cannot be written are counted as dropped.
+type UsageMeter struct {
+	writer  *kafka.Writer
+	dropped prometheus.Counter
+}
+
+// NewUsageMeter creates a usage meter writing to Metering.Topic
+func NewUsageMeter(config *Config) *UsageMeter {
+	m := &UsageMeter{
+		dropped: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "dictamesh_billing_metering_dropped_events_total",
+			Help: "Usage events that could not be published to the metering topic",
+		}),
+	}
+
+	m.writer = &kafka.Writer{
+		Addr:         kafka.TCP(config.Metering.Brokers...),
+		Topic:        config.Metering.Topic,
+		Balancer:     &kafka.Hash{}, // Events of an organization share a partition
+		BatchSize:    config.Metering.BatchSize,
+		BatchTimeout: config.Metering.FlushInterval,
+		RequiredAcks: kafka.RequireAll,
+		Async:        true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				m.dropped.Add(float64(len(messages)))
+			}
+		},
+	}
+
+	return m
+}
+
+// Record publishes a usage event of an organization
+func (m *UsageMeter) Record(organizationID string, metricType MetricType, value decimal.Decimal) {
+	event := UsageEvent{
+		Key:            uuid.NewString(),
+		OrganizationID: organizationID,
+		MetricType:     metricType,
+		Value:          value,
+		Time:           time.Now().UnixMilli(),
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		m.dropped.Inc()
+		return
+	}
+
+	// Asynchronous writes only fail once the writer is closed
+	if err := m.writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   []byte(organizationID),
+		Value: payload,
+	}); err != nil {
+		m.dropped.Inc()
+	}
+}
+
+// Close writes the pending events and closes the meter
+func (m *UsageMeter) Close() error {
+	return m.writer.Close()
+}
+
+// UsageIngester consumes the metering topic and stores the events as usage
+// metrics, aggregated per organization, metric type and Metering.Window.
+// Offsets are committed once the usage of a batch is stored, so events are
+// delivered at least once; the keys of ingested events are stored with the
+// usage, so redelivered events are counted once.
+type UsageIngester struct {
+	db            *gorm.DB
+	config        *Config
+	usage         UsageRepo
+	subscriptions SubscriptionRepo
+	reader        *kafka.Reader
+
+	ingested   prometheus.Counter
+	duplicates prometheus.Counter
+	invalid    prometheus.Counter
+}
+
+// NewUsageIngester creates a usage ingester in the consumer group
+// Metering.ConsumerGroup
+func NewUsageIngester(db *gorm.DB, config *Config, repos *Repositories) *UsageIngester {
+	return &UsageIngester{
+		db:            db,
+		config:        config,
+		usage:         repos.Usage,
+		subscriptions: repos.Subscriptions,
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: config.Metering.Brokers,
+			GroupID: config.Metering.ConsumerGroup,
+			Topic:   config.Metering.Topic,
+		}),
+
+		ingested: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "dictamesh_billing_metering_ingested_events_total",
+			Help: "Usage events stored from the metering topic",
+		}),
+		duplicates: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "dictamesh_billing_metering_duplicate_events_total",
+			Help: "Redelivered usage events dropped by the ingester",
+		}),
+		invalid: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "dictamesh_billing_metering_invalid_events_total",
+			Help: "Malformed usage events skipped by the ingester",
+		}),
+	}
+}
+
+// Run ingests usage events until ctx is canceled. Events are stored in
+// batches of up to Metering.BatchSize, at least once per Metering.Window;
+// events fetched but not stored when ctx is canceled are redelivered.
+func (ui *UsageIngester) Run(ctx context.Context) error {
+	defer ui.reader.Close()
+
+	for {
+		messages, err := ui.fetch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch usage events: %w", err)
+		}
+		if len(messages) == 0 {
+			continue
+		}
+
+		if err := ui.flush(ctx, messages); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// fetch collects the events of a batch, until the batch is full or the
+// window elapsed
+func (ui *UsageIngester) fetch(ctx context.Context) ([]kafka.Message, error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, ui.config.Metering.Window)
+	defer cancel()
+
+	var messages []kafka.Message
+	for len(messages) < ui.config.Metering.BatchSize {
+		message, err := ui.reader.FetchMessage(fetchCtx)
+		if err != nil {
+			if fetchCtx.Err() != nil && ctx.Err() == nil {
+				return messages, nil
+			}
+			return messages, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// flush stores a batch, retrying with exponential backoff until it is stored,
+// and commits its offsets
+func (ui *UsageIngester) flush(ctx context.Context, messages []kafka.Message) error {
+	events := ui.decode(messages)
+
+	delay := time.Second
+	for {
+		inserted, err := ui.store(ctx, events)
+		if err == nil {
+			ui.ingested.Add(float64(inserted))
+			ui.duplicates.Add(float64(len(events) - inserted))
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > ui.config.Metering.Window {
+			delay = ui.config.Metering.Window
+		}
+	}
+
+	if err := ui.reader.CommitMessages(ctx, messages...); err != nil {
+		return fmt.Errorf("failed to commit usage events: %w", err)
+	}
+	return nil
+}
+
+// decode decodes the events of a batch by key. Malformed events can never be
+// stored, so they are skipped.
+func (ui *UsageIngester) decode(messages []kafka.Message) map[string]UsageEvent {
+	events := make(map[string]UsageEvent, len(messages))
+	for _, message := range messages {
+		var event UsageEvent
+		err := json.Unmarshal(message.Value, &event)
+		if err == nil {
+			_, err = uuid.Parse(event.OrganizationID)
+		}
+		if err != nil || event.Key == "" || len(event.Key) > 64 || !meteredMetrics[event.MetricType] {
+			ui.invalid.Inc()
+			continue
+		}
+		events[event.Key] = event
+	}
+	return events
+}
+
+// usageWindowKey identifies the usage of an organization in a window
+type usageWindowKey struct {
+	organizationID uuid.UUID
+	metricType     MetricType
+	windowStart    time.Time
+}
+
+// store aggregates the events not ingested before and stores them as usage
+// metrics, together with their keys. It returns the number of events stored.
+func (ui *UsageIngester) store(ctx context.Context, events map[string]UsageEvent) (int, error) {
+	var inserted []string
+	err := ui.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		keys := make([]string, 0, len(events))
+		for key := range events {
+			keys = append(keys, key)
+		}
+		var err error
+		inserted, err = insertEventKeys(tx, keys)
+		if err != nil {
+			return err
+		}
+
+		window := ui.config.Metering.Window
+		usage := make(map[usageWindowKey]decimal.Decimal)
+		for _, key := range inserted {
+			event := events[key]
+			windowKey := usageWindowKey{
+				organizationID: uuid.MustParse(event.OrganizationID),
+				metricType:     event.MetricType,
+				windowStart:    time.UnixMilli(event.Time).UTC().Truncate(window),
+			}
+			usage[windowKey] = usage[windowKey].Add(event.Value)
+		}
+
+		samples := make([]UsageSample, 0, len(usage))
+		for key, value := range usage {
+			samples = append(samples, UsageSample{
+				OrganizationID: key.organizationID,
+				MetricType:     key.metricType,
+				Value:          value,
+				PeriodStart:    key.windowStart,
+				PeriodEnd:      key.windowStart.Add(window),
+			})
+		}
+
+		txCtx := withTx(ctx, tx)
+		metrics, err := usageMetrics(txCtx, ui.subscriptions, samples, UsageSourceKafka)
+		if err != nil {
+			return err
+		}
+		if err := ui.usage.Create(txCtx, metrics); err != nil {
+			return fmt.Errorf("failed to store usage metrics: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(inserted), nil
+}
+
+// insertEventKeys stores event keys and returns the keys not stored before
+func insertEventKeys(tx *gorm.DB, keys []string) ([]string, error) {
+	var inserted []string
+	for start := 0; start < len(keys); start += eventKeyChunkSize {
+		end := start + eventKeyChunkSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		chunk := keys[start:end]
+
+		args := make([]interface{}, len(chunk))
+		for i, key := range chunk {
+			args[i] = key
+		}
+		values := strings.TrimSuffix(strings.Repeat("(?, NOW()),", len(chunk)), ",")
+
+		var stored []string
+		if err := tx.Raw(
+			"INSERT INTO dictamesh_billing_usage_event_keys (key, received_at) VALUES "+values+
+				" ON CONFLICT (key) DO NOTHING RETURNING key",
+			args...,
+		).Scan(&stored).Error; err != nil {
+			return nil, fmt.Errorf("failed to store usage event keys: %w", err)
+		}
+		inserted = append(inserted, stored...)
+	}
+	return inserted, nil
+}
+
+// PruneUsageEventKeys removes the keys of usage events ingested longer than
+// Metering.DedupRetention ago. Events redelivered after that are counted
+// again.
+func (mc *MetricsCollector) PruneUsageEventKeys(ctx context.Context) error {
+	if err := mc.db.WithContext(ctx).
+		Where("received_at < ?", time.Now().Add(-mc.config.Metering.DedupRetention)).
+		Delete(&models.UsageEventKey{}).Error; err != nil {
+		return fmt.Errorf("failed to prune usage event keys: %w", err)
+	}
+	return nil
+}
@@ -5,20 +5,28 @@ package billing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // MetricsCollector handles usage metrics collection and aggregation
 type MetricsCollector struct {
-	db     *gorm.DB
-	config *Config
+	db            *gorm.DB
+	config        *Config
+	usage         UsageRepo
+	subscriptions SubscriptionRepo
+	source        UsageSource
+	meter         *UsageMeter
 
 	// Prometheus metrics
 	apiCallsTotal      *prometheus.CounterVec
@@ -29,11 +37,15 @@ type MetricsCollector struct {
 	kafkaEventsTotal   *prometheus.CounterVec
 }
 
-// NewMetricsCollector creates a new metrics collector
-func NewMetricsCollector(db *gorm.DB, config *Config) *MetricsCollector {
-	return &MetricsCollector{
-		db:     db,
-		config: config,
+// NewMetricsCollector creates a new metrics collector aggregating usage from
+// the source selected by Usage.Source. With Metering.Enabled, API calls and
+// transfer are also published to the metering topic.
+func NewMetricsCollector(db *gorm.DB, config *Config, repos *Repositories) (*MetricsCollector, error) {
+	mc := &MetricsCollector{
+		db:            db,
+		config:        config,
+		usage:         repos.Usage,
+		subscriptions: repos.Subscriptions,
 
 		apiCallsTotal: promauto.NewCounterVec(
 			prometheus.CounterOpts{
@@ -84,11 +96,41 @@ func NewMetricsCollector(db *gorm.DB, config *Config) *MetricsCollector {
 			[]string{"organization_id", "topic"},
 		),
 	}
+
+	switch config.Usage.Source {
+	case UsageSourcePrometheus:
+		source, err := NewPrometheusUsageSource(config.Usage.PrometheusURL)
+		if err != nil {
+			return nil, err
+		}
+		mc.source = source
+	case UsageSourceInProcess:
+		mc.source = NewInProcessUsageSource(mc)
+	default:
+		return nil, fmt.Errorf("unsupported usage source: %s", config.Usage.Source)
+	}
+
+	if config.Metering.Enabled {
+		mc.meter = NewUsageMeter(config)
+	}
+
+	return mc, nil
+}
+
+// Close publishes the pending usage events
+func (mc *MetricsCollector) Close() error {
+	if mc.meter == nil {
+		return nil
+	}
+	return mc.meter.Close()
 }
 
 // RecordAPICall records an API call metric
 func (mc *MetricsCollector) RecordAPICall(organizationID, endpoint, method string) {
 	mc.apiCallsTotal.WithLabelValues(organizationID, endpoint, method).Inc()
+	if mc.meter != nil {
+		mc.meter.Record(organizationID, MetricTypeAPICalls, decimal.NewFromInt(1))
+	}
 }
 
 // RecordStorage records current storage usage
@@ -99,6 +141,17 @@ func (mc *MetricsCollector) RecordStorage(organizationID, storageType string, by
 // RecordTransfer records data transfer
 func (mc *MetricsCollector) RecordTransfer(organizationID, direction string, bytes int64) {
 	mc.transferBytesTotal.WithLabelValues(organizationID, direction).Add(float64(bytes))
+	if mc.meter == nil {
+		return
+	}
+
+	gb := decimal.NewFromInt(bytes).Div(decimal.NewFromInt(bytesPerGB))
+	switch direction {
+	case "in":
+		mc.meter.Record(organizationID, MetricTypeTransferGBIn, gb)
+	case "out":
+		mc.meter.Record(organizationID, MetricTypeTransferGBOut, gb)
+	}
 }
 
 // RecordQuery records a query execution
@@ -116,151 +169,120 @@ func (mc *MetricsCollector) RecordKafkaEvent(organizationID, topic string) {
 	mc.kafkaEventsTotal.WithLabelValues(organizationID, topic).Inc()
 }
 
-// AggregateUsageMetrics aggregates Prometheus metrics into database records
+// AggregateUsageMetrics stores the usage of every aggregation interval that
+// ended since the last run. Intervals missed while the job was not running are
+// backfilled up to Usage.MaxBackfill; each interval is stored exactly once.
+// With Metering.Enabled, the metered metrics are left to the UsageIngester.
 func (mc *MetricsCollector) AggregateUsageMetrics(ctx context.Context) error {
-	now := time.Now()
-	periodStart := now.Add(-mc.config.Usage.AggregationInterval)
-	periodEnd := now
-
-	// Get all organizations with subscriptions
-	var subscriptions []models.Subscription
-	if err := mc.db.WithContext(ctx).
-		Preload("Organization").
-		Where("status = ?", SubscriptionStatusActive).
-		Find(&subscriptions).Error; err != nil {
-		return fmt.Errorf("failed to fetch subscriptions: %w", err)
-	}
-
-	for _, sub := range subscriptions {
-		orgID := sub.OrganizationID.String()
+	step := mc.config.Usage.AggregationInterval
+	end := time.Now().Add(-mc.config.Usage.AggregationDelay).Truncate(step)
 
-		// Aggregate API calls
-		if err := mc.aggregateAPICallMetrics(ctx, orgID, sub.ID, periodStart, periodEnd); err != nil {
-			return fmt.Errorf("failed to aggregate API calls for org %s: %w", orgID, err)
+	backfill := mc.config.Usage.MaxBackfill
+	if backfill < step {
+		backfill = step
+	}
+	earliest := end.Add(-backfill).Truncate(step)
+
+	return mc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The cursor advances in the same transaction as the usage is stored
+		var cursor models.UsageCursor
+		start := end.Add(-step)
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&cursor, "source = ?", mc.source.Name()).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			cursor.Source = mc.source.Name()
+		case err != nil:
+			return fmt.Errorf("failed to fetch usage cursor: %w", err)
+		default:
+			start = cursor.LastPeriodEnd
 		}
 
-		// Aggregate storage
-		if err := mc.aggregateStorageMetrics(ctx, orgID, sub.ID, periodStart, periodEnd); err != nil {
-			return fmt.Errorf("failed to aggregate storage for org %s: %w", orgID, err)
+		if start.Before(earliest) {
+			start = earliest
 		}
-
-		// Aggregate data transfer
-		if err := mc.aggregateTransferMetrics(ctx, orgID, sub.ID, periodStart, periodEnd); err != nil {
-			return fmt.Errorf("failed to aggregate transfer for org %s: %w", orgID, err)
+		if !start.Before(end) {
+			return nil
 		}
 
-		// Aggregate query duration
-		if err := mc.aggregateQueryMetrics(ctx, orgID, sub.ID, periodStart, periodEnd); err != nil {
-			return fmt.Errorf("failed to aggregate queries for org %s: %w", orgID, err)
+		samples, err := mc.source.Usage(ctx, tx, start, end, step)
+		if err != nil {
+			return err
 		}
-	}
 
-	return nil
-}
-
-// aggregateAPICallMetrics aggregates API call metrics
-func (mc *MetricsCollector) aggregateAPICallMetrics(
-	ctx context.Context,
-	organizationID string,
-	subscriptionID interface{},
-	periodStart, periodEnd time.Time,
-) error {
-	// In a real implementation, you would query Prometheus for the metric values
-	// For now, we'll simulate with a direct counter read
-	// This is a simplified example - in production, you'd use the Prometheus API
-
-	metric := &models.UsageMetric{
-		OrganizationID: mustParseUUID(organizationID),
-		SubscriptionID: subscriptionID.(interface{ String() string }).String(),
-		MetricType:     string(MetricTypeAPICalls),
-		MetricValue:    decimal.NewFromInt(0), // Would be fetched from Prometheus
-		MetricUnit:     "count",
-		RecordedAt:     time.Now(),
-		PeriodStart:    periodStart,
-		PeriodEnd:      periodEnd,
-	}
+		if mc.config.Metering.Enabled {
+			unmetered := samples[:0]
+			for _, sample := range samples {
+				if !meteredMetrics[sample.MetricType] {
+					unmetered = append(unmetered, sample)
+				}
+			}
+			samples = unmetered
+		}
 
-	return mc.db.WithContext(ctx).Create(metric).Error
-}
+		txCtx := withTx(ctx, tx)
+		metrics, err := usageMetrics(txCtx, mc.subscriptions, samples, mc.source.Name())
+		if err != nil {
+			return err
+		}
 
-// aggregateStorageMetrics aggregates storage metrics
-func (mc *MetricsCollector) aggregateStorageMetrics(
-	ctx context.Context,
-	organizationID string,
-	subscriptionID interface{},
-	periodStart, periodEnd time.Time,
-) error {
-	metric := &models.UsageMetric{
-		OrganizationID: mustParseUUID(organizationID),
-		SubscriptionID: subscriptionID.(interface{ String() string }).String(),
-		MetricType:     string(MetricTypeStorageGB),
-		MetricValue:    decimal.NewFromInt(0), // Would be fetched from Prometheus
-		MetricUnit:     "GB",
-		RecordedAt:     time.Now(),
-		PeriodStart:    periodStart,
-		PeriodEnd:      periodEnd,
-	}
+		if err := mc.usage.Create(txCtx, metrics); err != nil {
+			return fmt.Errorf("failed to store usage metrics: %w", err)
+		}
 
-	return mc.db.WithContext(ctx).Create(metric).Error
+		cursor.LastPeriodEnd = end
+		if err := tx.Save(&cursor).Error; err != nil {
+			return fmt.Errorf("failed to update usage cursor: %w", err)
+		}
+		return nil
+	})
 }
 
-// aggregateTransferMetrics aggregates data transfer metrics
-func (mc *MetricsCollector) aggregateTransferMetrics(
+// usageMetrics converts usage samples of a source to usage metrics of the
+// billable subscriptions. Usage of organizations without one is not stored.
+func usageMetrics(
 	ctx context.Context,
-	organizationID string,
-	subscriptionID interface{},
-	periodStart, periodEnd time.Time,
-) error {
-	// Aggregate inbound transfer
-	metricIn := &models.UsageMetric{
-		OrganizationID: mustParseUUID(organizationID),
-		SubscriptionID: subscriptionID.(interface{ String() string }).String(),
-		MetricType:     string(MetricTypeTransferGBIn),
-		MetricValue:    decimal.NewFromInt(0), // Would be fetched from Prometheus
-		MetricUnit:     "GB",
-		RecordedAt:     time.Now(),
-		PeriodStart:    periodStart,
-		PeriodEnd:      periodEnd,
+	repo SubscriptionRepo,
+	samples []UsageSample,
+	source string,
+) ([]models.UsageMetric, error) {
+	if len(samples) == 0 {
+		return nil, nil
 	}
 
-	if err := mc.db.WithContext(ctx).Create(metricIn).Error; err != nil {
-		return err
+	subscriptions, err := repo.ListBillable(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscriptions: %w", err)
 	}
 
-	// Aggregate outbound transfer
-	metricOut := &models.UsageMetric{
-		OrganizationID: mustParseUUID(organizationID),
-		SubscriptionID: subscriptionID.(interface{ String() string }).String(),
-		MetricType:     string(MetricTypeTransferGBOut),
-		MetricValue:    decimal.NewFromInt(0), // Would be fetched from Prometheus
-		MetricUnit:     "GB",
-		RecordedAt:     time.Now(),
-		PeriodStart:    periodStart,
-		PeriodEnd:      periodEnd,
+	subscriptionIDs := make(map[uuid.UUID]uuid.UUID, len(subscriptions))
+	for _, sub := range subscriptions {
+		subscriptionIDs[sub.OrganizationID] = sub.ID
 	}
 
-	return mc.db.WithContext(ctx).Create(metricOut).Error
-}
+	now := time.Now()
+	metrics := make([]models.UsageMetric, 0, len(samples))
+	for _, sample := range samples {
+		subscriptionID, ok := subscriptionIDs[sample.OrganizationID]
+		if !ok {
+			continue
+		}
 
-// aggregateQueryMetrics aggregates query processing metrics
-func (mc *MetricsCollector) aggregateQueryMetrics(
-	ctx context.Context,
-	organizationID string,
-	subscriptionID interface{},
-	periodStart, periodEnd time.Time,
-) error {
-	metric := &models.UsageMetric{
-		OrganizationID: mustParseUUID(organizationID),
-		SubscriptionID: subscriptionID.(interface{ String() string }).String(),
-		MetricType:     string(MetricTypeQuerySeconds),
-		MetricValue:    decimal.NewFromInt(0), // Would be fetched from Prometheus
-		MetricUnit:     "seconds",
-		RecordedAt:     time.Now(),
-		PeriodStart:    periodStart,
-		PeriodEnd:      periodEnd,
+		metrics = append(metrics, models.UsageMetric{
+			ID:             uuid.New(),
+			OrganizationID: sample.OrganizationID,
+			SubscriptionID: subscriptionID,
+			MetricType:     string(sample.MetricType),
+			MetricValue:    sample.Value.Round(6),
+			MetricUnit:     metricUnits[sample.MetricType],
+			RecordedAt:     now,
+			PeriodStart:    sample.PeriodStart,
+			PeriodEnd:      sample.PeriodEnd,
+			Metadata:       models.JSONB{"source": source},
+		})
 	}
 
-	return mc.db.WithContext(ctx).Create(metric).Error
+	return metrics, nil
 }
 
 // GetUsageForPeriod retrieves aggregated usage for a billing period
@@ -269,14 +291,7 @@ func (mc *MetricsCollector) GetUsageForPeriod(
 	organizationID string,
 	periodStart, periodEnd time.Time,
 ) (*UsageAggregation, error) {
-	var metrics []models.UsageMetric
-
-	err := mc.db.WithContext(ctx).
-		Where("organization_id = ?", organizationID).
-		Where("period_start >= ?", periodStart).
-		Where("period_end <= ?", periodEnd).
-		Find(&metrics).Error
-
+	metrics, err := mc.usage.ListForPeriod(ctx, organizationID, periodStart, periodEnd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch usage metrics: %w", err)
 	}
@@ -301,21 +316,19 @@ func (mc *MetricsCollector) GetUsageForPeriod(
 	return agg, nil
 }
 
-// GetCurrentUsage retrieves current usage (real-time)
+// GetCurrentUsage retrieves current usage (real-time) for the tenant in ctx
 func (mc *MetricsCollector) GetCurrentUsage(
 	ctx context.Context,
-	organizationID string,
 ) (map[MetricType]decimal.Decimal, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// This would query Prometheus for real-time metrics
 	// For now, we'll return the most recent aggregated values
 
-	var metrics []models.UsageMetric
-
-	err := mc.db.WithContext(ctx).
-		Where("organization_id = ?", organizationID).
-		Where("recorded_at >= ?", time.Now().Add(-1*time.Hour)).
-		Find(&metrics).Error
-
+	metrics, err := mc.usage.ListRecordedSince(ctx, organizationID, time.Now().Add(-1*time.Hour))
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch current usage: %w", err)
 	}
@@ -332,29 +345,3 @@ func (mc *MetricsCollector) GetCurrentUsage(
 
 	return usage, nil
 }
-
-// StartAggregationWorker starts a background worker to aggregate metrics periodically
-func (mc *MetricsCollector) StartAggregationWorker(ctx context.Context) {
-	ticker := time.NewTicker(mc.config.Usage.AggregationInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			if err := mc.AggregateUsageMetrics(ctx); err != nil {
-				// Log error (in production, use proper logging)
-				fmt.Printf("Error aggregating metrics: %v\n", err)
-			}
-		}
-	}
-}
-
-// Helper functions
-
-func mustParseUUID(s string) interface{} {
-	// In real implementation, properly parse UUID
-	// This is simplified for the example
-	return s
-}
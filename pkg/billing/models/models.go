@@ -60,6 +60,7 @@ type Organization struct {
 	Timezone          string `gorm:"type:varchar(50);default:'UTC'" json:"timezone"`
 
 	// Payment
+	PaymentProvider        string `gorm:"type:varchar(20);default:'stripe'" json:"payment_provider"`
 	DefaultPaymentMethodID string `gorm:"type:varchar(255)" json:"default_payment_method_id,omitempty"`
 	StripeCustomerID       string `gorm:"type:varchar(255)" json:"stripe_customer_id,omitempty"`
 	AutoPay                bool   `gorm:"default:false" json:"auto_pay"`
@@ -78,17 +79,25 @@ func (Organization) TableName() string {
 	return "dictamesh_billing_organizations"
 }
 
-// SubscriptionPlan represents a product offering
+// SubscriptionPlan represents a version of a product offering. Versions of a
+// plan share its slug; subscriptions reference the version they were sold,
+// which cannot change once published.
 type SubscriptionPlan struct {
 	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	Name        string    `gorm:"type:varchar(100);not null" json:"name"`
-	Slug        string    `gorm:"type:varchar(50);not null;uniqueIndex" json:"slug"`
+	Slug        string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_dictamesh_billing_plan_slug_version" json:"slug"`
 	Description string    `gorm:"type:text" json:"description,omitempty"`
 
+	// Versioning
+	Version       int        `gorm:"not null;default:1;uniqueIndex:idx_dictamesh_billing_plan_slug_version" json:"version"`
+	VersionStatus string     `gorm:"type:varchar(20);not null;default:'published'" json:"version_status"` // draft, published or retired
+	PublishedAt   *time.Time `json:"published_at,omitempty"`
+
 	// Pricing
 	BasePrice       decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"base_price"`
 	Currency        string          `gorm:"type:varchar(3);default:'USD'" json:"currency"`
 	BillingInterval string          `gorm:"type:varchar(20);not null" json:"billing_interval"`
+	BillingMode     string          `gorm:"type:varchar(20);not null;default:'arrears'" json:"billing_mode"` // arrears or advance
 
 	// Features
 	Features JSONB `gorm:"type:jsonb;default:'{}'" json:"features,omitempty"`
@@ -144,6 +153,10 @@ type Subscription struct {
 	CanceledAt         *time.Time `json:"canceled_at,omitempty"`
 	CancellationReason string     `gorm:"type:text" json:"cancellation_reason,omitempty"`
 
+	// Pause
+	PausedAt  *time.Time `json:"paused_at,omitempty"`
+	ResumesAt *time.Time `gorm:"index" json:"resumes_at,omitempty"` // Automatic resumption (nil = manual)
+
 	// Pricing overrides
 	CustomPricing JSONB `gorm:"type:jsonb" json:"custom_pricing,omitempty"`
 
@@ -205,6 +218,10 @@ type Invoice struct {
 	// Invoice identification
 	InvoiceNumber string `gorm:"type:varchar(50);not null;uniqueIndex" json:"invoice_number"`
 
+	// Identifies the subscription period of period invoices, so each period
+	// is invoiced once (nil for proration invoices)
+	IdempotencyKey *string `gorm:"type:varchar(255);uniqueIndex" json:"-"`
+
 	// Billing period
 	PeriodStart time.Time `gorm:"not null" json:"period_start"`
 	PeriodEnd   time.Time `gorm:"not null" json:"period_end"`
@@ -217,6 +234,9 @@ type Invoice struct {
 	AmountPaid  decimal.Decimal `gorm:"type:decimal(12,2);default:0" json:"amount_paid"`
 	Currency    string          `gorm:"type:varchar(3);default:'USD'" json:"currency"`
 
+	// Amount reduced by credit notes
+	AmountCredited decimal.Decimal `gorm:"type:decimal(12,2);default:0" json:"amount_credited"`
+
 	// Status
 	Status string `gorm:"type:varchar(20);default:'draft';index" json:"status"`
 
@@ -225,6 +245,11 @@ type Invoice struct {
 	DueDate     time.Time  `gorm:"not null;index" json:"due_date"`
 	PaidAt      *time.Time `json:"paid_at,omitempty"`
 
+	// Overdue handling
+	OverdueAt   *time.Time      `json:"overdue_at,omitempty"`
+	LateFee     decimal.Decimal `gorm:"type:decimal(12,2);not null;default:0" json:"late_fee"`
+	SuspendedAt *time.Time      `json:"suspended_at,omitempty"` // Organization suspended for this invoice
+
 	// Payment provider
 	StripeInvoiceID string `gorm:"type:varchar(255)" json:"stripe_invoice_id,omitempty"`
 
@@ -287,8 +312,9 @@ type Payment struct {
 	Invoice      Invoice      `gorm:"foreignKey:InvoiceID" json:"invoice,omitempty"`
 
 	// Payment details
-	Amount   decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"amount"`
-	Currency string          `gorm:"type:varchar(3);default:'USD'" json:"currency"`
+	Amount         decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"amount"`
+	AmountRefunded decimal.Decimal `gorm:"type:decimal(12,2);default:0" json:"amount_refunded"`
+	Currency       string          `gorm:"type:varchar(3);default:'USD'" json:"currency"`
 
 	// Status
 	Status string `gorm:"type:varchar(20);default:'pending';index" json:"status"`
@@ -411,3 +437,574 @@ type AuditLog struct {
 func (AuditLog) TableName() string {
 	return "dictamesh_billing_audit_log"
 }
+
+// DunningState tracks the collection of an invoice whose payment failed
+type DunningState struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;index" json:"organization_id"`
+	InvoiceID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"invoice_id"`
+	SubscriptionID uuid.UUID `gorm:"type:uuid;index" json:"subscription_id,omitempty"`
+
+	// Relationships
+	Invoice Invoice `gorm:"foreignKey:InvoiceID" json:"invoice,omitempty"`
+
+	// Retry progress
+	Status       string     `gorm:"type:varchar(20);default:'active';index:idx_dunning_status_next_retry" json:"status"`
+	AttemptCount int        `gorm:"default:0" json:"attempt_count"`
+	NextRetryAt  *time.Time `gorm:"index:idx_dunning_status_next_retry" json:"next_retry_at,omitempty"`
+	LastRetryAt  *time.Time `json:"last_retry_at,omitempty"`
+
+	// Last failure
+	LastPaymentID      *uuid.UUID `gorm:"type:uuid" json:"last_payment_id,omitempty"`
+	LastFailureCode    string     `gorm:"type:varchar(50)" json:"last_failure_code,omitempty"`
+	LastFailureMessage string     `gorm:"type:text" json:"last_failure_message,omitempty"`
+
+	// Outcome
+	StartedAt  time.Time  `gorm:"not null;default:now()" json:"started_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	Resolution string     `gorm:"type:varchar(20)" json:"resolution,omitempty"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (DunningState) TableName() string {
+	return "dictamesh_billing_dunning_states"
+}
+
+// Coupon represents a reusable discount
+type Coupon struct {
+	ID   uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name string    `gorm:"type:varchar(255);not null" json:"name"`
+
+	// Discount
+	DiscountType string          `gorm:"type:varchar(20);not null" json:"discount_type"` // percentage or fixed
+	PercentOff   decimal.Decimal `gorm:"type:decimal(5,2);default:0" json:"percent_off"`
+	AmountOff    decimal.Decimal `gorm:"type:decimal(12,2);default:0" json:"amount_off"`
+	Currency     string          `gorm:"type:varchar(3)" json:"currency,omitempty"` // Required for fixed discounts
+
+	// Duration
+	Duration         string `gorm:"type:varchar(20);not null" json:"duration"` // forever, once or repeating
+	DurationInMonths *int   `json:"duration_in_months,omitempty"`
+
+	// Redemption limits
+	MaxRedemptions *int       `json:"max_redemptions,omitempty"`
+	TimesRedeemed  int        `gorm:"default:0" json:"times_redeemed"`
+	RedeemBy       *time.Time `json:"redeem_by,omitempty"`
+
+	// Status
+	IsActive bool `gorm:"default:true" json:"is_active"`
+
+	// Metadata
+	Metadata JSONB `gorm:"type:jsonb" json:"metadata,omitempty"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (Coupon) TableName() string {
+	return "dictamesh_billing_coupons"
+}
+
+// PromotionCode is a customer-facing code that redeems a coupon
+type PromotionCode struct {
+	ID       uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CouponID uuid.UUID `gorm:"type:uuid;not null;index" json:"coupon_id"`
+	Code     string    `gorm:"type:varchar(50);not null;uniqueIndex" json:"code"`
+
+	// Relationships
+	Coupon Coupon `gorm:"foreignKey:CouponID" json:"coupon,omitempty"`
+
+	// Restrictions
+	OrganizationID *uuid.UUID `gorm:"type:uuid" json:"organization_id,omitempty"` // Restrict to one organization
+	MaxRedemptions *int       `json:"max_redemptions,omitempty"`
+	TimesRedeemed  int        `gorm:"default:0" json:"times_redeemed"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+
+	// Status
+	IsActive bool `gorm:"default:true" json:"is_active"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (PromotionCode) TableName() string {
+	return "dictamesh_billing_promotion_codes"
+}
+
+// CouponRedemption is a coupon applied to a subscription
+type CouponRedemption struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CouponID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"coupon_id"`
+	PromotionCodeID *uuid.UUID `gorm:"type:uuid" json:"promotion_code_id,omitempty"`
+	OrganizationID  uuid.UUID  `gorm:"type:uuid;not null;index" json:"organization_id"`
+	SubscriptionID  uuid.UUID  `gorm:"type:uuid;not null;index" json:"subscription_id"`
+
+	// Relationships
+	Coupon Coupon `gorm:"foreignKey:CouponID" json:"coupon,omitempty"`
+
+	// Validity
+	RedeemedAt     time.Time  `gorm:"not null;default:now()" json:"redeemed_at"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"` // NULL = forever or once
+	PeriodsApplied int        `gorm:"default:0" json:"periods_applied"`
+
+	// Status
+	Status string `gorm:"type:varchar(20);default:'active';index" json:"status"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (CouponRedemption) TableName() string {
+	return "dictamesh_billing_coupon_redemptions"
+}
+
+// TaxRate is a tax rate of a jurisdiction used by the table tax provider
+type TaxRate struct {
+	ID      uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Country string    `gorm:"type:varchar(2);not null;index:idx_tax_rate_jurisdiction" json:"country"` // ISO 3166-1 alpha-2
+	State   string    `gorm:"type:varchar(100);not null;default:'';index:idx_tax_rate_jurisdiction" json:"state,omitempty"`
+
+	// Rate
+	TaxType string          `gorm:"type:varchar(20);not null" json:"tax_type"` // vat, gst, hst, pst, qst, sales_tax
+	Name    string          `gorm:"type:varchar(100);not null" json:"name"`
+	Rate    decimal.Decimal `gorm:"type:decimal(7,6);not null" json:"rate"` // Fraction, e.g. 0.19
+
+	// Status
+	IsActive bool `gorm:"default:true" json:"is_active"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (TaxRate) TableName() string {
+	return "dictamesh_billing_tax_rates"
+}
+
+// PlanChange represents an immediate or scheduled change of the plan of a subscription
+type PlanChange struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	SubscriptionID uuid.UUID `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;index" json:"organization_id"`
+	FromPlanID     uuid.UUID `gorm:"type:uuid;not null" json:"from_plan_id"`
+	ToPlanID       uuid.UUID `gorm:"type:uuid;not null" json:"to_plan_id"`
+
+	// Relationships
+	FromPlan SubscriptionPlan `gorm:"foreignKey:FromPlanID" json:"from_plan,omitempty"`
+	ToPlan   SubscriptionPlan `gorm:"foreignKey:ToPlanID" json:"to_plan,omitempty"`
+
+	// Change details
+	ChangeType  string     `gorm:"type:varchar(20);not null" json:"change_type"` // upgrade or downgrade
+	Status      string     `gorm:"type:varchar(20);default:'scheduled';index:idx_plan_change_status_effective" json:"status"`
+	EffectiveAt time.Time  `gorm:"not null;index:idx_plan_change_status_effective" json:"effective_at"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+
+	// Changes scheduled by a price change move subscriptions between versions
+	// of a plan; the customer is notified in advance
+	PriceChangeID *uuid.UUID `gorm:"type:uuid;index" json:"price_change_id,omitempty"`
+	NotifiedAt    *time.Time `json:"notified_at,omitempty"`
+
+	// Proration of immediate changes
+	ProrationAmount    decimal.Decimal `gorm:"type:decimal(12,2);default:0" json:"proration_amount"`
+	Currency           string          `gorm:"type:varchar(3);default:'USD'" json:"currency"`
+	ProrationInvoiceID *uuid.UUID      `gorm:"type:uuid" json:"proration_invoice_id,omitempty"`
+	ProrationCreditID  *uuid.UUID      `gorm:"type:uuid" json:"proration_credit_id,omitempty"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (PlanChange) TableName() string {
+	return "dictamesh_billing_plan_changes"
+}
+
+// Refund represents a full or partial refund of a payment
+type Refund struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	PaymentID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"payment_id"`
+	InvoiceID      uuid.UUID  `gorm:"type:uuid;index" json:"invoice_id,omitempty"`
+	OrganizationID uuid.UUID  `gorm:"type:uuid;not null;index" json:"organization_id"`
+	CreditNoteID   *uuid.UUID `gorm:"type:uuid" json:"credit_note_id,omitempty"`
+
+	// Relationships
+	Payment Payment `gorm:"foreignKey:PaymentID" json:"payment,omitempty"`
+
+	// Refund details
+	Amount   decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"amount"`
+	Currency string          `gorm:"type:varchar(3);default:'USD'" json:"currency"`
+	Reason   string          `gorm:"type:text" json:"reason,omitempty"`
+
+	// Status
+	Status string `gorm:"type:varchar(20);default:'pending';index" json:"status"`
+
+	// Provider details
+	Provider         string `gorm:"type:varchar(20);not null" json:"provider"`
+	ProviderRefundID string `gorm:"type:varchar(255)" json:"provider_refund_id,omitempty"`
+
+	// Error handling
+	FailureMessage string `gorm:"type:text" json:"failure_message,omitempty"`
+
+	// Timestamps
+	RefundedAt *time.Time `json:"refunded_at,omitempty"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (Refund) TableName() string {
+	return "dictamesh_billing_refunds"
+}
+
+// CreditNote represents a reduction of the amount billed on an invoice
+type CreditNote struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OrganizationID uuid.UUID  `gorm:"type:uuid;not null;index" json:"organization_id"`
+	InvoiceID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"invoice_id"`
+	RefundID       *uuid.UUID `gorm:"type:uuid" json:"refund_id,omitempty"` // Set when the amount was refunded
+	CreditID       *uuid.UUID `gorm:"type:uuid" json:"credit_id,omitempty"` // Set when the amount is credited to future invoices
+
+	// Relationships
+	Organization Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	Invoice      Invoice      `gorm:"foreignKey:InvoiceID" json:"invoice,omitempty"`
+
+	// Credit note identification, numbered separately from invoices
+	CreditNoteNumber string `gorm:"type:varchar(50);not null;uniqueIndex" json:"credit_note_number"`
+
+	// Amounts
+	Subtotal    decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"subtotal"`
+	TaxAmount   decimal.Decimal `gorm:"type:decimal(12,2);default:0" json:"tax_amount"`
+	TotalAmount decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"total_amount"`
+	Currency    string          `gorm:"type:varchar(3);default:'USD'" json:"currency"`
+
+	// Reason
+	Reason string `gorm:"type:text" json:"reason,omitempty"`
+
+	// Status
+	Status   string    `gorm:"type:varchar(20);default:'issued';index" json:"status"`
+	IssuedAt time.Time `gorm:"not null;default:now()" json:"issued_at"`
+
+	// PDF
+	PDFURL         string     `gorm:"type:text" json:"pdf_url,omitempty"`
+	PDFGeneratedAt *time.Time `json:"pdf_generated_at,omitempty"`
+
+	// Line items
+	LineItems []CreditNoteLineItem `gorm:"foreignKey:CreditNoteID" json:"line_items,omitempty"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (CreditNote) TableName() string {
+	return "dictamesh_billing_credit_notes"
+}
+
+// CreditNoteLineItem represents a line item on a credit note
+type CreditNoteLineItem struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CreditNoteID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"credit_note_id"`
+	InvoiceLineItemID *uuid.UUID `gorm:"type:uuid" json:"invoice_line_item_id,omitempty"` // Invoice line being credited
+
+	// Line item details
+	Description string          `gorm:"type:text;not null" json:"description"`
+	Quantity    decimal.Decimal `gorm:"type:decimal(20,6);not null" json:"quantity"`
+	UnitPrice   decimal.Decimal `gorm:"type:decimal(12,6);not null" json:"unit_price"`
+	Amount      decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"amount"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (CreditNoteLineItem) TableName() string {
+	return "dictamesh_billing_credit_note_line_items"
+}
+
+// UsageCursor records the end of the last aggregated usage interval of a
+// usage source
+type UsageCursor struct {
+	Source        string    `gorm:"type:varchar(20);primary_key" json:"source"`
+	LastPeriodEnd time.Time `gorm:"not null" json:"last_period_end"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (UsageCursor) TableName() string {
+	return "dictamesh_billing_usage_cursors"
+}
+
+// UsageCounter records the last aggregated value of an in-process counter.
+// The epoch identifies the process, so counter resets are detected.
+type UsageCounter struct {
+	OrganizationID uuid.UUID       `gorm:"type:uuid;primary_key" json:"organization_id"`
+	MetricType     string          `gorm:"type:varchar(50);primary_key" json:"metric_type"`
+	Epoch          uuid.UUID       `gorm:"type:uuid;not null" json:"epoch"`
+	Value          decimal.Decimal `gorm:"type:decimal(30,6);not null" json:"value"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (UsageCounter) TableName() string {
+	return "dictamesh_billing_usage_counters"
+}
+
+// BillingRun records a run of the billing orchestrator invoicing the
+// subscriptions whose period ended
+type BillingRun struct {
+	ID uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+
+	// Status
+	Status string `gorm:"type:varchar(30);default:'running';index" json:"status"`
+
+	// Subscriptions with periods ending at or before the cutoff are due
+	Cutoff time.Time `gorm:"not null" json:"cutoff"`
+
+	// Results
+	SubscriptionsDue int    `gorm:"default:0" json:"subscriptions_due"`
+	InvoicesCreated  int    `gorm:"default:0" json:"invoices_created"`
+	AlreadyInvoiced  int    `gorm:"default:0" json:"already_invoiced"`
+	Failed           int    `gorm:"default:0" json:"failed"`
+	Error            string `gorm:"type:text" json:"error,omitempty"`
+
+	// Timestamps
+	StartedAt   time.Time  `gorm:"not null;index" json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	// Items
+	Items []BillingRunItem `gorm:"foreignKey:BillingRunID" json:"items,omitempty"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (BillingRun) TableName() string {
+	return "dictamesh_billing_runs"
+}
+
+// BillingRunItem records the outcome of a subscription in a billing run
+type BillingRunItem struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	BillingRunID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"billing_run_id"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	InvoiceID      *uuid.UUID `gorm:"type:uuid" json:"invoice_id,omitempty"`
+
+	// Invoice of the next period, for plans billed in advance
+	AdvanceInvoiceID *uuid.UUID `gorm:"type:uuid" json:"advance_invoice_id,omitempty"`
+
+	// Invoiced period
+	PeriodStart time.Time `gorm:"not null" json:"period_start"`
+	PeriodEnd   time.Time `gorm:"not null" json:"period_end"`
+
+	// Outcome
+	Status   string `gorm:"type:varchar(20);not null" json:"status"`
+	Attempts int    `gorm:"default:0" json:"attempts"`
+	Error    string `gorm:"type:text" json:"error,omitempty"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (BillingRunItem) TableName() string {
+	return "dictamesh_billing_run_items"
+}
+
+// SeatChange records a change of the seat count of a subscription. The seat
+// history of a period is used to prorate its charges.
+type SeatChange struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	SubscriptionID uuid.UUID `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;index" json:"organization_id"`
+
+	// Change
+	FromQuantity int       `gorm:"not null" json:"from_quantity"`
+	ToQuantity   int       `gorm:"not null" json:"to_quantity"`
+	EffectiveAt  time.Time `gorm:"not null" json:"effective_at"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (SeatChange) TableName() string {
+	return "dictamesh_billing_seat_changes"
+}
+
+// Budget is the monthly spend budget of an organization
+type Budget struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"organization_id"`
+
+	// Budget
+	MonthlyAmount decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"monthly_amount"`
+	Currency      string          `gorm:"type:varchar(3);not null" json:"currency"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (Budget) TableName() string {
+	return "dictamesh_billing_budgets"
+}
+
+// BudgetAlert records a budget threshold reached in a month, so that each
+// threshold is alerted once per month
+type BudgetAlert struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	BudgetID       uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_dictamesh_billing_budget_alert_month" json:"budget_id"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;index" json:"organization_id"`
+
+	// Alert
+	Month     string          `gorm:"type:varchar(7);not null;uniqueIndex:idx_dictamesh_billing_budget_alert_month" json:"month"` // YYYY-MM
+	Threshold int             `gorm:"not null;uniqueIndex:idx_dictamesh_billing_budget_alert_month" json:"threshold"` // Percentage of the budget
+	Spend     decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"spend"`
+	Budget    decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"budget"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (BudgetAlert) TableName() string {
+	return "dictamesh_billing_budget_alerts"
+}
+
+// UsageEventKey records the key of an ingested usage event, so that events
+// delivered more than once are counted once
+type UsageEventKey struct {
+	Key        string    `gorm:"type:varchar(64);primary_key" json:"key"`
+	ReceivedAt time.Time `gorm:"not null;index" json:"received_at"`
+}
+
+// TableName overrides the default table name
+func (UsageEventKey) TableName() string {
+	return "dictamesh_billing_usage_event_keys"
+}
+
+// PriceChange moves the subscriptions of a plan version to another version of
+// the plan at an effective date, through scheduled plan changes
+type PriceChange struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	FromPlanID uuid.UUID `gorm:"type:uuid;not null;index" json:"from_plan_id"`
+	ToPlanID   uuid.UUID `gorm:"type:uuid;not null" json:"to_plan_id"`
+
+	// Relationships
+	FromPlan SubscriptionPlan `gorm:"foreignKey:FromPlanID" json:"from_plan,omitempty"`
+	ToPlan   SubscriptionPlan `gorm:"foreignKey:ToPlanID" json:"to_plan,omitempty"`
+
+	// Schedule
+	EffectiveAt   time.Time `gorm:"not null" json:"effective_at"`
+	Status        string    `gorm:"type:varchar(20);default:'scheduled';index" json:"status"`
+	Subscriptions int       `gorm:"default:0" json:"subscriptions"` // Subscriptions scheduled to move
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (PriceChange) TableName() string {
+	return "dictamesh_billing_price_changes"
+}
+
+// TrialReminder records a reminder sent before the end of a trial. Reminders
+// are keyed by the trial end, so extended trials are reminded again.
+type TrialReminder struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	SubscriptionID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_dictamesh_billing_trial_reminder" json:"subscription_id"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;index" json:"organization_id"`
+
+	// Reminder
+	TrialEnd   time.Time `gorm:"not null;uniqueIndex:idx_dictamesh_billing_trial_reminder" json:"trial_end"`
+	DaysBefore int       `gorm:"not null;uniqueIndex:idx_dictamesh_billing_trial_reminder" json:"days_before"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (TrialReminder) TableName() string {
+	return "dictamesh_billing_trial_reminders"
+}
+
+// Payout records a provider payout and the outcome of reconciling its
+// balance transactions against the internal payments and refunds
+type Payout struct {
+	ID               uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Provider         string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_dictamesh_billing_payout_provider" json:"provider"`
+	ProviderPayoutID string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_dictamesh_billing_payout_provider" json:"provider_payout_id"`
+
+	// Relationships
+	Items []ReconciliationItem `gorm:"foreignKey:PayoutID" json:"items,omitempty"`
+
+	// Payout details
+	Amount      decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"amount"`
+	Currency    string          `gorm:"type:varchar(3);not null" json:"currency"`
+	ArrivalDate time.Time       `gorm:"not null;index" json:"arrival_date"`
+
+	// Reconciliation
+	Status       string          `gorm:"type:varchar(20);not null;index" json:"status"`
+	NetAmount    decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"net_amount"` // Sum of the transaction net amounts
+	FeeAmount    decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"fee_amount"`
+	MatchedItems int             `gorm:"default:0" json:"matched_items"`
+	FlaggedItems int             `gorm:"default:0" json:"flagged_items"`
+	ReconciledAt time.Time       `gorm:"not null" json:"reconciled_at"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (Payout) TableName() string {
+	return "dictamesh_billing_payouts"
+}
+
+// ReconciliationItem records a balance transaction of a payout and the
+// internal payment or refund it was matched against
+type ReconciliationItem struct {
+	ID                    uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	PayoutID              uuid.UUID  `gorm:"type:uuid;not null;index" json:"payout_id"`
+	PaymentID             *uuid.UUID `gorm:"type:uuid;index" json:"payment_id,omitempty"`
+	RefundID              *uuid.UUID `gorm:"type:uuid" json:"refund_id,omitempty"`
+	OrganizationID        *uuid.UUID `gorm:"type:uuid;index" json:"organization_id,omitempty"`
+	ProviderTransactionID string     `gorm:"type:varchar(255);not null" json:"provider_transaction_id"`
+
+	// Transaction details
+	Type     string          `gorm:"type:varchar(20);not null" json:"type"`
+	Amount   decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"amount"`
+	Fee      decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"fee"`
+	Net      decimal.Decimal `gorm:"type:decimal(12,2);not null" json:"net"`
+	Currency string          `gorm:"type:varchar(3);not null" json:"currency"`
+
+	// Reconciliation
+	Status string `gorm:"type:varchar(20);not null;index" json:"status"`
+	Detail string `gorm:"type:text" json:"detail,omitempty"`
+
+	// Audit
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (ReconciliationItem) TableName() string {
+	return "dictamesh_billing_reconciliation_items"
+}
@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/shopspring/decimal"
 )
 
 // NotificationService handles sending billing-related notifications
@@ -25,7 +27,8 @@ func NewNotificationService(config *Config) *NotificationService {
 	return &NotificationService{
 		config: config,
 		client: &http.Client{
-			Timeout: time.Duration(config.Notifications.TimeoutSeconds) * time.Second,
+			Timeout:   time.Duration(config.Notifications.TimeoutSeconds) * time.Second,
+			Transport: tenant.NewTransport(nil),
 		},
 	}
 }
@@ -137,6 +140,7 @@ func (ns *NotificationService) SendInvoiceOverdueNotification(
 	data := map[string]interface{}{
 		"InvoiceNumber": invoice.InvoiceNumber,
 		"Amount":        invoice.AmountDue.StringFixed(2),
+		"LateFee":       invoice.LateFee.StringFixed(2),
 		"Currency":      invoice.Currency,
 		"DueDate":       invoice.DueDate.Format("Jan 2, 2006"),
 		"DaysOverdue":   daysOverdue,
@@ -206,6 +210,240 @@ func (ns *NotificationService) SendSubscriptionCanceledNotification(
 	return ns.sendNotification(ctx, notification)
 }
 
+// SendPlanChangedNotification sends notification when the plan of a
+// subscription changes or a change is scheduled. The change must have its
+// plans loaded.
+func (ns *NotificationService) SendPlanChangedNotification(
+	ctx context.Context,
+	change *models.PlanChange,
+) error {
+	data := map[string]interface{}{
+		"FromPlanName":    change.FromPlan.Name,
+		"ToPlanName":      change.ToPlan.Name,
+		"ChangeType":      change.ChangeType,
+		"Scheduled":       change.Status == string(PlanChangeStatusScheduled),
+		"EffectiveDate":   change.EffectiveAt.Format("Jan 2, 2006"),
+		"Amount":          change.ToPlan.BasePrice.StringFixed(2),
+		"ProrationAmount": change.ProrationAmount.Abs().StringFixed(2),
+		"ProrationCredit": change.ProrationAmount.IsNegative(),
+		"Currency":        change.Currency,
+		"SubscriptionURL": fmt.Sprintf("https://app.dictamesh.io/subscriptions/%s", change.SubscriptionID),
+	}
+
+	notification := &NotificationRequest{
+		RecipientID:   change.OrganizationID.String(),
+		RecipientType: "organization",
+		TemplateCode:  "billing_subscription_plan_changed",
+		Channels:      []string{"email"},
+		Priority:      "normal",
+		Data:          data,
+	}
+
+	return ns.sendNotification(ctx, notification)
+}
+
+// SendPriceChangeNotification sends advance notice of a price change moving
+// a subscription to another version of its plan
+func (ns *NotificationService) SendPriceChangeNotification(
+	ctx context.Context,
+	change *models.PlanChange,
+	oldPrice, newPrice decimal.Decimal,
+) error {
+	data := map[string]interface{}{
+		"PlanName":        change.ToPlan.Name,
+		"EffectiveDate":   change.EffectiveAt.Format("Jan 2, 2006"),
+		"OldAmount":       oldPrice.StringFixed(2),
+		"NewAmount":       newPrice.StringFixed(2),
+		"Increase":        newPrice.GreaterThan(oldPrice),
+		"Currency":        change.Currency,
+		"SubscriptionURL": fmt.Sprintf("https://app.dictamesh.io/subscriptions/%s", change.SubscriptionID),
+	}
+
+	notification := &NotificationRequest{
+		RecipientID:   change.OrganizationID.String(),
+		RecipientType: "organization",
+		TemplateCode:  "billing_plan_price_change",
+		Channels:      []string{"email"},
+		Priority:      "high",
+		Data:          data,
+	}
+
+	return ns.sendNotification(ctx, notification)
+}
+
+// SendSubscriptionPausedNotification sends notification when a subscription is paused
+func (ns *NotificationService) SendSubscriptionPausedNotification(
+	ctx context.Context,
+	subscription *models.Subscription,
+) error {
+	data := map[string]interface{}{
+		"PlanName":        subscription.Plan.Name,
+		"PauseDate":       subscription.PausedAt.Format("Jan 2, 2006"),
+		"SubscriptionURL": fmt.Sprintf("https://app.dictamesh.io/subscriptions/%s", subscription.ID),
+	}
+	if subscription.ResumesAt != nil {
+		data["ResumeDate"] = subscription.ResumesAt.Format("Jan 2, 2006")
+	}
+
+	notification := &NotificationRequest{
+		RecipientID:   subscription.OrganizationID.String(),
+		RecipientType: "organization",
+		TemplateCode:  "billing_subscription_paused",
+		Channels:      []string{"email"},
+		Priority:      "normal",
+		Data:          data,
+	}
+
+	return ns.sendNotification(ctx, notification)
+}
+
+// SendSubscriptionResumedNotification sends notification when a subscription is resumed
+func (ns *NotificationService) SendSubscriptionResumedNotification(
+	ctx context.Context,
+	subscription *models.Subscription,
+) error {
+	data := map[string]interface{}{
+		"PlanName":         subscription.Plan.Name,
+		"CurrentPeriodEnd": subscription.CurrentPeriodEnd.Format("Jan 2, 2006"),
+		"SubscriptionURL":  fmt.Sprintf("https://app.dictamesh.io/subscriptions/%s", subscription.ID),
+	}
+
+	notification := &NotificationRequest{
+		RecipientID:   subscription.OrganizationID.String(),
+		RecipientType: "organization",
+		TemplateCode:  "billing_subscription_resumed",
+		Channels:      []string{"email"},
+		Priority:      "normal",
+		Data:          data,
+	}
+
+	return ns.sendNotification(ctx, notification)
+}
+
+// SendTrialConvertedNotification sends notification when a trial converts to
+// a paid subscription
+func (ns *NotificationService) SendTrialConvertedNotification(
+	ctx context.Context,
+	subscription *models.Subscription,
+) error {
+	data := map[string]interface{}{
+		"PlanName":           subscription.Plan.Name,
+		"Amount":             subscription.Plan.BasePrice.StringFixed(2),
+		"Currency":           subscription.Plan.Currency,
+		"CurrentPeriodStart": subscription.CurrentPeriodStart.Format("Jan 2, 2006"),
+		"CurrentPeriodEnd":   subscription.CurrentPeriodEnd.Format("Jan 2, 2006"),
+		"SubscriptionURL":    fmt.Sprintf("https://app.dictamesh.io/subscriptions/%s", subscription.ID),
+	}
+
+	notification := &NotificationRequest{
+		RecipientID:   subscription.OrganizationID.String(),
+		RecipientType: "organization",
+		TemplateCode:  "billing_trial_converted",
+		Channels:      []string{"email"},
+		Priority:      "normal",
+		Data:          data,
+	}
+
+	return ns.sendNotification(ctx, notification)
+}
+
+// SendTrialEndingNotification sends a reminder before the end of a trial
+func (ns *NotificationService) SendTrialEndingNotification(
+	ctx context.Context,
+	subscription *models.Subscription,
+	daysLeft int,
+) error {
+	data := map[string]interface{}{
+		"PlanName":         subscription.Plan.Name,
+		"DaysLeft":         daysLeft,
+		"TrialEnd":         subscription.TrialEnd.Format("Jan 2, 2006"),
+		"Amount":           subscription.Plan.BasePrice.StringFixed(2),
+		"Currency":         subscription.Plan.Currency,
+		"HasPaymentMethod": subscription.Organization.DefaultPaymentMethodID != "",
+		"Downgrade":        ns.config.Subscriptions.TrialExpiryAction == string(TrialExpiryDowngrade),
+		"SubscriptionURL":  fmt.Sprintf("https://app.dictamesh.io/subscriptions/%s", subscription.ID),
+	}
+
+	notification := &NotificationRequest{
+		RecipientID:   subscription.OrganizationID.String(),
+		RecipientType: "organization",
+		TemplateCode:  "billing_trial_ending",
+		Channels:      []string{"email"},
+		Priority:      "high",
+		Data:          data,
+	}
+
+	return ns.sendNotification(ctx, notification)
+}
+
+// SendDunningRetryFailedNotification sends notification when a dunning retry
+// fails. The final notice is sent before the last retry.
+func (ns *NotificationService) SendDunningRetryFailedNotification(
+	ctx context.Context,
+	state *models.DunningState,
+	invoice *models.Invoice,
+	finalNotice bool,
+) error {
+	data := map[string]interface{}{
+		"InvoiceNumber":     invoice.InvoiceNumber,
+		"Amount":            invoice.AmountDue.StringFixed(2),
+		"Currency":          invoice.Currency,
+		"AttemptCount":      state.AttemptCount,
+		"AttemptsRemaining": len(ns.config.Dunning.RetrySchedule) - state.AttemptCount,
+		"FailureReason":     state.LastFailureMessage,
+		"ExhaustionAction":  ns.config.Dunning.ExhaustionAction,
+		"PaymentURL":        fmt.Sprintf("https://app.dictamesh.io/invoices/%s/pay", invoice.ID),
+	}
+	if state.NextRetryAt != nil {
+		data["NextRetryDate"] = state.NextRetryAt.Format("Jan 2, 2006")
+	}
+
+	templateCode, priority := "billing_dunning_retry_failed", "high"
+	if finalNotice {
+		templateCode, priority = "billing_dunning_final_notice", "urgent"
+	}
+
+	notification := &NotificationRequest{
+		RecipientID:   state.OrganizationID.String(),
+		RecipientType: "organization",
+		TemplateCode:  templateCode,
+		Channels:      []string{"email"},
+		Priority:      priority,
+		Data:          data,
+	}
+
+	return ns.sendNotification(ctx, notification)
+}
+
+// SendSubscriptionSuspendedNotification sends notification when a subscription
+// is suspended because payment retries were exhausted or an invoice stayed
+// past due
+func (ns *NotificationService) SendSubscriptionSuspendedNotification(
+	ctx context.Context,
+	subscription *models.Subscription,
+	invoice *models.Invoice,
+) error {
+	data := map[string]interface{}{
+		"PlanName":       subscription.Plan.Name,
+		"InvoiceNumber":  invoice.InvoiceNumber,
+		"Amount":         invoice.AmountDue.StringFixed(2),
+		"Currency":       invoice.Currency,
+		"SuspensionDate": time.Now().Format("Jan 2, 2006"),
+		"PaymentURL":     fmt.Sprintf("https://app.dictamesh.io/invoices/%s/pay", invoice.ID),
+	}
+
+	notification := &NotificationRequest{
+		RecipientID:   subscription.OrganizationID.String(),
+		RecipientType: "organization",
+		TemplateCode:  "billing_subscription_suspended",
+		Channels:      []string{"email"},
+		Priority:      "urgent",
+		Data:          data,
+	}
+
+	return ns.sendNotification(ctx, notification)
+}
+
 // SendUsageThresholdNotification sends notification when usage threshold is reached
 func (ns *NotificationService) SendUsageThresholdNotification(
 	ctx context.Context,
@@ -234,6 +472,36 @@ func (ns *NotificationService) SendUsageThresholdNotification(
 	return ns.sendNotification(ctx, notification)
 }
 
+// SendBudgetThresholdNotification sends notification when the spend of the
+// month reaches a threshold of the organization's budget
+func (ns *NotificationService) SendBudgetThresholdNotification(
+	ctx context.Context,
+	budget *models.Budget,
+	status *BudgetStatus,
+	threshold int,
+) error {
+	data := map[string]interface{}{
+		"Threshold":     threshold,
+		"Month":         status.MonthStart.Format("January 2006"),
+		"CurrentSpend":  status.CurrentSpend.StringFixed(2),
+		"ForecastSpend": status.ForecastSpend.StringFixed(2),
+		"Budget":        budget.MonthlyAmount.StringFixed(2),
+		"Currency":      budget.Currency,
+		"BudgetURL":     "https://app.dictamesh.io/billing/budget",
+	}
+
+	notification := &NotificationRequest{
+		RecipientID:   budget.OrganizationID.String(),
+		RecipientType: "organization",
+		TemplateCode:  "billing_budget_threshold_reached",
+		Channels:      []string{"email"},
+		Priority:      "high",
+		Data:          data,
+	}
+
+	return ns.sendNotification(ctx, notification)
+}
+
 // SendUpcomingRenewalNotification sends notification before subscription renewal
 func (ns *NotificationService) SendUpcomingRenewalNotification(
 	ctx context.Context,
@@ -268,6 +536,11 @@ func (ns *NotificationService) sendNotification(
 	ctx context.Context,
 	notification *NotificationRequest,
 ) error {
+	// Billing notifications are always sent on behalf of the recipient organization
+	if _, ok := tenant.FromContext(ctx); !ok && notification.RecipientType == "organization" {
+		ctx = tenant.WithOrganization(ctx, notification.RecipientID)
+	}
+
 	// Marshal notification to JSON
 	payload, err := json.Marshal(notification)
 	if err != nil {
@@ -284,6 +557,7 @@ func (ns *NotificationService) sendNotification(
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	ns.authorize(req)
 
 	// Send request with retries
 	var lastErr error
@@ -308,73 +582,209 @@ func (ns *NotificationService) sendNotification(
 		ns.config.Notifications.RetryAttempts, lastErr)
 }
 
-// CreateBillingTemplates creates email templates for billing notifications
+// CreateBillingTemplates creates email templates for billing notifications,
+// named by the template codes of the notifications. Templates that already
+// exist are left unchanged.
 // This function should be called during system initialization
 func (ns *NotificationService) CreateBillingTemplates(ctx context.Context) error {
 	templates := []map[string]interface{}{
 		{
-			"template_code": "billing_invoice_generated",
-			"name":          "Invoice Generated",
-			"description":   "Sent when a new invoice is generated",
-			"channels":      []string{"email"},
-			"subject":       "Your DictaMesh Invoice #{{.InvoiceNumber}}",
-			"body_html":     getInvoiceGeneratedTemplate(),
+			"name":        "billing_invoice_generated",
+			"description": "Sent when a new invoice is generated",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Your DictaMesh Invoice #{{.InvoiceNumber}}",
+					"body_html": getInvoiceGeneratedTemplate(),
+				},
+			},
+			"variables": []string{"InvoiceNumber", "PeriodStart", "PeriodEnd", "Currency", "Subtotal", "Tax", "Total", "InvoiceURL", "AutoPay", "DueDate"},
+		},
+		{
+			"name":        "billing_payment_succeeded",
+			"description": "Sent when a payment is successfully processed",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Payment Received - Invoice #{{.InvoiceNumber}}",
+					"body_html": getPaymentSucceededTemplate(),
+				},
+			},
+			"variables": []string{"InvoiceNumber", "Currency", "Amount", "PaymentMethod", "TransactionID", "PaymentDate", "ReceiptURL"},
 		},
 		{
-			"template_code": "billing_payment_succeeded",
-			"name":          "Payment Received",
-			"description":   "Sent when a payment is successfully processed",
-			"channels":      []string{"email"},
-			"subject":       "Payment Received - Invoice #{{.InvoiceNumber}}",
-			"body_html":     getPaymentSucceededTemplate(),
+			"name":        "billing_payment_failed",
+			"description": "Sent when a payment fails",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Action Required: Payment Failed for Invoice #{{.InvoiceNumber}}",
+					"body_html": getPaymentFailedTemplate(),
+				},
+			},
+			"variables": []string{"InvoiceNumber", "FailureReason", "PaymentURL", "DueDate"},
 		},
 		{
-			"template_code": "billing_payment_failed",
-			"name":          "Payment Failed",
-			"description":   "Sent when a payment fails",
-			"channels":      []string{"email"},
-			"subject":       "Action Required: Payment Failed for Invoice #{{.InvoiceNumber}}",
-			"body_html":     getPaymentFailedTemplate(),
+			"name":        "billing_invoice_overdue",
+			"description": "Sent when an invoice becomes overdue",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Overdue Invoice #{{.InvoiceNumber}} - Payment Required",
+					"body_html": getInvoiceOverdueTemplate(),
+				},
+			},
+			"variables": []string{"InvoiceNumber", "DaysOverdue", "Currency", "Amount", "DueDate", "PaymentURL"},
 		},
 		{
-			"template_code": "billing_invoice_overdue",
-			"name":          "Invoice Overdue",
-			"description":   "Sent when an invoice becomes overdue",
-			"channels":      []string{"email"},
-			"subject":       "Overdue Invoice #{{.InvoiceNumber}} - Payment Required",
-			"body_html":     getInvoiceOverdueTemplate(),
+			"name":        "billing_dunning_retry_failed",
+			"description": "Sent when an automatic payment retry fails",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Action Required: Payment Retry Failed for Invoice #{{.InvoiceNumber}}",
+					"body_html": getDunningRetryFailedTemplate(),
+				},
+			},
+			"variables": []string{"InvoiceNumber", "Currency", "Amount", "FailureReason", "NextRetryDate", "AttemptsRemaining", "PaymentURL"},
 		},
 		{
-			"template_code": "billing_subscription_created",
-			"name":          "Subscription Created",
-			"description":   "Sent when a new subscription is created",
-			"channels":      []string{"email"},
-			"subject":       "Welcome to {{.PlanName}}!",
-			"body_html":     getSubscriptionCreatedTemplate(),
+			"name":        "billing_dunning_final_notice",
+			"description": "Sent before the last automatic payment retry",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Final Notice: Invoice #{{.InvoiceNumber}} Remains Unpaid",
+					"body_html": getDunningFinalNoticeTemplate(),
+				},
+			},
+			"variables": []string{"InvoiceNumber", "Currency", "Amount", "AttemptCount", "NextRetryDate", "ExhaustionAction", "PaymentURL"},
 		},
 		{
-			"template_code": "billing_subscription_canceled",
-			"name":          "Subscription Canceled",
-			"description":   "Sent when a subscription is canceled",
-			"channels":      []string{"email"},
-			"subject":       "Your {{.PlanName}} subscription has been canceled",
-			"body_html":     getSubscriptionCanceledTemplate(),
+			"name":        "billing_subscription_suspended",
+			"description": "Sent when a subscription is suspended for non-payment",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Your {{.PlanName}} subscription has been suspended",
+					"body_html": getSubscriptionSuspendedTemplate(),
+				},
+			},
+			"variables": []string{"PlanName", "SuspensionDate", "InvoiceNumber", "Currency", "Amount", "PaymentURL"},
 		},
 		{
-			"template_code": "billing_usage_threshold_reached",
-			"name":          "Usage Threshold Reached",
-			"description":   "Sent when usage reaches a threshold",
-			"channels":      []string{"email"},
-			"subject":       "Usage Alert: {{.MetricType}} at {{.PercentUsed}}%",
-			"body_html":     getUsageThresholdTemplate(),
+			"name":        "billing_subscription_created",
+			"description": "Sent when a new subscription is created",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Welcome to {{.PlanName}}!",
+					"body_html": getSubscriptionCreatedTemplate(),
+				},
+			},
+			"variables": []string{"PlanName", "BillingCycle", "Currency", "Amount", "CurrentPeriodStart", "CurrentPeriodEnd", "SubscriptionURL"},
 		},
 		{
-			"template_code": "billing_upcoming_renewal",
-			"name":          "Upcoming Renewal",
-			"description":   "Sent before subscription renewal",
-			"channels":      []string{"email"},
-			"subject":       "Your {{.PlanName}} subscription renews in {{.DaysUntilRenewal}} days",
-			"body_html":     getUpcomingRenewalTemplate(),
+			"name":        "billing_subscription_canceled",
+			"description": "Sent when a subscription is canceled",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Your {{.PlanName}} subscription has been canceled",
+					"body_html": getSubscriptionCanceledTemplate(),
+				},
+			},
+			"variables": []string{"PlanName", "CancellationDate", "EndDate", "Reason"},
+		},
+		{
+			"name":        "billing_subscription_plan_changed",
+			"description": "Sent when a plan change is applied or scheduled",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Your subscription {{if .Scheduled}}will change{{else}}changed{{end}} to {{.ToPlanName}}",
+					"body_html": getPlanChangedTemplate(),
+				},
+			},
+			"variables": []string{"Scheduled", "ToPlanName", "FromPlanName", "EffectiveDate", "Currency", "Amount", "ProrationAmount", "ProrationCredit", "SubscriptionURL"},
+		},
+		{
+			"name":        "billing_plan_price_change",
+			"description": "Sent in advance of a price change of the plan of a subscription",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Pricing of your {{.PlanName}} plan changes on {{.EffectiveDate}}",
+					"body_html": getPriceChangeTemplate(),
+				},
+			},
+			"variables": []string{"PlanName", "EffectiveDate", "Currency", "OldAmount", "NewAmount", "Increase", "SubscriptionURL"},
+		},
+		{
+			"name":        "billing_subscription_paused",
+			"description": "Sent when a subscription is paused",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Your {{.PlanName}} subscription has been paused",
+					"body_html": getSubscriptionPausedTemplate(),
+				},
+			},
+			"variables": []string{"PlanName", "PauseDate", "ResumeDate", "SubscriptionURL"},
+		},
+		{
+			"name":        "billing_subscription_resumed",
+			"description": "Sent when a paused subscription is resumed",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Your {{.PlanName}} subscription has been resumed",
+					"body_html": getSubscriptionResumedTemplate(),
+				},
+			},
+			"variables": []string{"PlanName", "CurrentPeriodEnd", "SubscriptionURL"},
+		},
+		{
+			"name":        "billing_trial_converted",
+			"description": "Sent when a trial converts to a paid subscription",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Your {{.PlanName}} trial has ended",
+					"body_html": getTrialConvertedTemplate(),
+				},
+			},
+			"variables": []string{"PlanName", "Currency", "Amount", "CurrentPeriodStart", "CurrentPeriodEnd", "SubscriptionURL"},
+		},
+		{
+			"name":        "billing_trial_ending",
+			"description": "Sent before the end of a trial",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Your {{.PlanName}} trial ends in {{.DaysLeft}} days",
+					"body_html": getTrialEndingTemplate(),
+				},
+			},
+			"variables": []string{"PlanName", "DaysLeft", "TrialEnd", "HasPaymentMethod", "Currency", "Amount", "Downgrade", "SubscriptionURL"},
+		},
+		{
+			"name":        "billing_usage_threshold_reached",
+			"description": "Sent when usage reaches a threshold",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Usage Alert: {{.MetricType}} at {{.PercentUsed}}%",
+					"body_html": getUsageThresholdTemplate(),
+				},
+			},
+			"variables": []string{"MetricType", "PercentUsed", "CurrentUsage", "Threshold", "UsageURL"},
+		},
+		{
+			"name":        "billing_budget_threshold_reached",
+			"description": "Sent when the spend of the month reaches a threshold of the budget",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Budget Alert: {{.Threshold}}% of your {{.Month}} budget",
+					"body_html": getBudgetThresholdTemplate(),
+				},
+			},
+			"variables": []string{"Threshold", "Month", "CurrentSpend", "Currency", "Budget", "ForecastSpend", "BudgetURL"},
+		},
+		{
+			"name":        "billing_upcoming_renewal",
+			"description": "Sent before subscription renewal",
+			"channels": map[string]interface{}{
+				"email": map[string]string{
+					"subject":   "Your {{.PlanName}} subscription renews in {{.DaysUntilRenewal}} days",
+					"body_html": getUpcomingRenewalTemplate(),
+				},
+			},
+			"variables": []string{"PlanName", "DaysUntilRenewal", "RenewalDate", "Currency", "Amount", "InvoiceURL"},
 		},
 	}
 
@@ -393,17 +803,32 @@ func (ns *NotificationService) CreateBillingTemplates(ctx context.Context) error
 		}
 
 		req.Header.Set("Content-Type", "application/json")
+		ns.authorize(req)
 
 		resp, err := ns.client.Do(req)
 		if err != nil {
 			return fmt.Errorf("failed to create template: %w", err)
 		}
 		resp.Body.Close()
+
+		// Templates created on a previous start are kept
+		if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
+			return fmt.Errorf("failed to create template %s: notification service returned status %d",
+				template["name"], resp.StatusCode)
+		}
 	}
 
 	return nil
 }
 
+// authorize authenticates a request to the notification service with its
+// API key, when one is configured
+func (ns *NotificationService) authorize(req *http.Request) {
+	if ns.config.Notifications.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+ns.config.Notifications.APIKey)
+	}
+}
+
 // Email template HTML content
 
 func getInvoiceGeneratedTemplate() string {
@@ -485,6 +910,52 @@ Due Date: {{.DueDate}}</p>
 `
 }
 
+func getDunningRetryFailedTemplate() string {
+	return `
+<!DOCTYPE html>
+<html>
+<head><style>body{font-family:Arial,sans-serif;color:#c00;}</style></head>
+<body>
+<h1>Payment Retry Failed</h1>
+<p>We retried the payment for invoice #{{.InvoiceNumber}} ({{.Currency}} {{.Amount}}) but it failed again.</p>
+<p><strong>Reason:</strong> {{.FailureReason}}</p>
+<p>We will retry on {{.NextRetryDate}}. {{.AttemptsRemaining}} attempts remain.</p>
+<p><a href="{{.PaymentURL}}">Update Payment Method</a></p>
+</body>
+</html>
+`
+}
+
+func getDunningFinalNoticeTemplate() string {
+	return `
+<!DOCTYPE html>
+<html>
+<head><style>body{font-family:Arial,sans-serif;color:#c00;}</style></head>
+<body>
+<h1>Final Notice</h1>
+<p>Invoice #{{.InvoiceNumber}} ({{.Currency}} {{.Amount}}) is still unpaid after {{.AttemptCount}} attempts.</p>
+<p>We will make a final attempt on {{.NextRetryDate}}. If it fails, your subscription will be {{if eq .ExhaustionAction "cancel"}}canceled{{else}}suspended{{end}}.</p>
+<p><a href="{{.PaymentURL}}">Pay Now</a></p>
+</body>
+</html>
+`
+}
+
+func getSubscriptionSuspendedTemplate() string {
+	return `
+<!DOCTYPE html>
+<html>
+<head><style>body{font-family:Arial,sans-serif;color:#c00;}</style></head>
+<body>
+<h1>Subscription Suspended</h1>
+<p>Your {{.PlanName}} subscription was suspended on {{.SuspensionDate}} because invoice #{{.InvoiceNumber}} could not be collected.</p>
+<p>Pay the outstanding {{.Currency}} {{.Amount}} to restore access.</p>
+<p><a href="{{.PaymentURL}}">Pay Now</a></p>
+</body>
+</html>
+`
+}
+
 func getSubscriptionCreatedTemplate() string {
 	return `
 <!DOCTYPE html>
@@ -523,6 +994,109 @@ Service End Date: {{.EndDate}}</p>
 `
 }
 
+func getPlanChangedTemplate() string {
+	return `
+<!DOCTYPE html>
+<html>
+<head><style>body{font-family:Arial,sans-serif;}</style></head>
+<body>
+<h1>{{if .Scheduled}}Plan Change Scheduled{{else}}Plan Changed{{end}}</h1>
+<p>Your subscription {{if .Scheduled}}will move{{else}}moved{{end}} from {{.FromPlanName}} to {{.ToPlanName}} on {{.EffectiveDate}}.</p>
+<p>New Amount: {{.Currency}} {{.Amount}} per billing period</p>
+{{if ne .ProrationAmount "0.00"}}
+<p>{{if .ProrationCredit}}A prorated credit of {{.Currency}} {{.ProrationAmount}} will be applied to your next invoice.{{else}}A prorated charge of {{.Currency}} {{.ProrationAmount}} has been invoiced.{{end}}</p>
+{{end}}
+<p><a href="{{.SubscriptionURL}}">View Subscription</a></p>
+</body>
+</html>
+`
+}
+
+func getPriceChangeTemplate() string {
+	return `
+<!DOCTYPE html>
+<html>
+<head><style>body{font-family:Arial,sans-serif;}</style></head>
+<body>
+<h1>Upcoming Price Change</h1>
+<p>The pricing of your {{.PlanName}} plan changes on {{.EffectiveDate}}, from your next billing period starting on or after that date.</p>
+<p>Current Amount: {{.Currency}} {{.OldAmount}} per billing period</p>
+<p>New Amount: {{.Currency}} {{.NewAmount}} per billing period</p>
+{{if .Increase}}<p>You can change or cancel your subscription before the change takes effect.</p>{{end}}
+<p><a href="{{.SubscriptionURL}}">View Subscription</a></p>
+</body>
+</html>
+`
+}
+
+func getSubscriptionPausedTemplate() string {
+	return `
+<!DOCTYPE html>
+<html>
+<head><style>body{font-family:Arial,sans-serif;}</style></head>
+<body>
+<h1>Subscription Paused</h1>
+<p>Your {{.PlanName}} subscription was paused on {{.PauseDate}}. You will not be billed while it is paused.</p>
+{{if .ResumeDate}}
+<p>It will resume automatically on {{.ResumeDate}}.</p>
+{{end}}
+<p><a href="{{.SubscriptionURL}}">Resume Subscription</a></p>
+</body>
+</html>
+`
+}
+
+func getSubscriptionResumedTemplate() string {
+	return `
+<!DOCTYPE html>
+<html>
+<head><style>body{font-family:Arial,sans-serif;}</style></head>
+<body>
+<h1>Subscription Resumed</h1>
+<p>Your {{.PlanName}} subscription is active again.</p>
+<p>Current Period Ends: {{.CurrentPeriodEnd}}</p>
+<p><a href="{{.SubscriptionURL}}">View Subscription</a></p>
+</body>
+</html>
+`
+}
+
+func getTrialConvertedTemplate() string {
+	return `
+<!DOCTYPE html>
+<html>
+<head><style>body{font-family:Arial,sans-serif;}</style></head>
+<body>
+<h1>Your Trial Has Ended</h1>
+<p>Your {{.PlanName}} subscription is now active.</p>
+<p>Amount: {{.Currency}} {{.Amount}}<br>
+Current Period: {{.CurrentPeriodStart}} - {{.CurrentPeriodEnd}}</p>
+<p><a href="{{.SubscriptionURL}}">View Subscription</a></p>
+<p>Thank you for choosing DictaMesh!</p>
+</body>
+</html>
+`
+}
+
+func getTrialEndingTemplate() string {
+	return `
+<!DOCTYPE html>
+<html>
+<head><style>body{font-family:Arial,sans-serif;}</style></head>
+<body>
+<h1>Your Trial Ends Soon</h1>
+<p>Your {{.PlanName}} trial ends on {{.TrialEnd}}.</p>
+{{if .HasPaymentMethod}}
+<p>Your subscription will then continue at {{.Currency}} {{.Amount}} per billing period, charged to your default payment method.</p>
+{{else}}
+<p>Add a payment method to keep your {{.PlanName}} subscription. {{if .Downgrade}}Otherwise your subscription will be downgraded.{{else}}Otherwise your subscription will be suspended.{{end}}</p>
+{{end}}
+<p><a href="{{.SubscriptionURL}}">Manage Subscription</a></p>
+</body>
+</html>
+`
+}
+
 func getUsageThresholdTemplate() string {
 	return `
 <!DOCTYPE html>
@@ -540,6 +1114,23 @@ Limit: {{.Threshold}}</p>
 `
 }
 
+func getBudgetThresholdTemplate() string {
+	return `
+<!DOCTYPE html>
+<html>
+<head><style>body{font-family:Arial,sans-serif;}</style></head>
+<body>
+<h1>Budget Alert</h1>
+<p>Your spend for {{.Month}} has reached {{.Threshold}}% of your budget.</p>
+<p>Spend so far: {{.CurrentSpend}} {{.Currency}}<br>
+Budget: {{.Budget}} {{.Currency}}<br>
+Forecast for the month: {{.ForecastSpend}} {{.Currency}}</p>
+<p><a href="{{.BudgetURL}}">View Budget</a></p>
+</body>
+</html>
+`
+}
+
 func getUpcomingRenewalTemplate() string {
 	return `
 <!DOCTYPE html>
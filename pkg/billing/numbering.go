@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// nextDocumentNumber allocates the next number of a document series, e.g.
+// INV-2025-000042 for the 42nd invoice of 2025. Series are stored per prefix
+// and year in dictamesh_billing_number_sequences; the row stays locked until
+// the transaction of db ends, so concurrent allocations never return the
+// same number.
+func nextDocumentNumber(db *gorm.DB, prefix string, year int) (string, error) {
+	var value int64
+	if err := db.Raw(`
+		INSERT INTO dictamesh_billing_number_sequences (prefix, year, last_value)
+		VALUES (?, ?, 1)
+		ON CONFLICT (prefix, year) DO UPDATE
+			SET last_value = dictamesh_billing_number_sequences.last_value + 1,
+				updated_at = NOW()
+		RETURNING last_value`,
+		prefix, year,
+	).Scan(&value).Error; err != nil {
+		return "", fmt.Errorf("failed to allocate document number: %w", err)
+	}
+
+	return fmt.Sprintf("%s%d-%06d", prefix, year, value), nil
+}
@@ -5,95 +5,158 @@ package billing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
-	"github.com/stripe/stripe-go/v75"
-	"github.com/stripe/stripe-go/v75/customer"
-	"github.com/stripe/stripe-go/v75/paymentintent"
-	"github.com/stripe/stripe-go/v75/paymentmethod"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // PaymentService handles payment processing
 type PaymentService struct {
 	db             *gorm.DB
 	config         *Config
+	payments       PaymentRepo
 	invoiceService *InvoiceService
+	creditNotes    *CreditNoteService
+	eventPublisher *BillingEventPublisher
+	dunning        *DunningService // Set by NewDunningService
+	providers      map[PaymentProviderName]PaymentProvider
 }
 
-// NewPaymentService creates a new payment service
+// NewPaymentService creates a new payment service with the enabled providers
 func NewPaymentService(
 	db *gorm.DB,
 	config *Config,
+	repos *Repositories,
 	invoiceService *InvoiceService,
+	creditNotes *CreditNoteService,
+	eventPublisher *BillingEventPublisher,
 ) *PaymentService {
-	// Initialize Stripe
-	if config.Stripe.Enabled {
-		stripe.Key = config.Stripe.APIKey
-	}
-
-	return &PaymentService{
+	ps := &PaymentService{
 		db:             db,
 		config:         config,
+		payments:       repos.Payments,
 		invoiceService: invoiceService,
+		creditNotes:    creditNotes,
+		eventPublisher: eventPublisher,
+		providers:      make(map[PaymentProviderName]PaymentProvider),
+	}
+
+	if config.Stripe.Enabled {
+		ps.RegisterProvider(NewStripePaymentProvider(config))
 	}
+
+	if config.PayPal.Enabled {
+		ps.RegisterProvider(NewPayPalPaymentProvider(config))
+	}
+
+	return ps
 }
 
-// CreateStripeCustomer creates a Stripe customer for an organization
-func (ps *PaymentService) CreateStripeCustomer(
+// RegisterProvider makes a payment provider available to organizations,
+// replacing any provider with the same name
+func (ps *PaymentService) RegisterProvider(provider PaymentProvider) {
+	ps.providers[provider.Name()] = provider
+}
+
+// providerFor returns the payment provider of an organization. Nil means
+// payments are collected manually.
+func (ps *PaymentService) providerFor(org *models.Organization) PaymentProvider {
+	name := PaymentProviderName(org.PaymentProvider)
+	if name == "" {
+		name = PaymentProviderStripe
+	}
+	return ps.providers[name]
+}
+
+// SetPaymentProvider selects the payment provider of the tenant in ctx. The
+// default payment method belongs to the previous provider and is cleared.
+func (ps *PaymentService) SetPaymentProvider(ctx context.Context, name PaymentProviderName) error {
+	if _, ok := ps.providers[name]; !ok && name != PaymentProviderManual {
+		return fmt.Errorf("payment provider %s is not enabled", name)
+	}
+
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := ps.db.WithContext(ctx).
+		Model(&models.Organization{}).
+		Where("id = ?", organizationID).
+		Updates(map[string]interface{}{
+			"payment_provider":          string(name),
+			"default_payment_method_id": "",
+		}).Error; err != nil {
+		return fmt.Errorf("failed to update organization: %w", err)
+	}
+
+	return nil
+}
+
+// CreateCustomer registers an organization with its payment provider
+func (ps *PaymentService) CreateCustomer(
 	ctx context.Context,
 	org *models.Organization,
 ) (string, error) {
-	if !ps.config.Stripe.Enabled {
-		return "", fmt.Errorf("Stripe is not enabled")
+	provider := ps.providerFor(org)
+	if provider == nil {
+		return "", fmt.Errorf("organization has no payment provider")
 	}
 
-	params := &stripe.CustomerParams{
-		Email: stripe.String(org.BillingEmail),
-		Name:  stripe.String(org.Name),
-		Metadata: map[string]string{
-			"organization_id": org.ID.String(),
-		},
+	customerID, err := provider.CreateCustomer(ctx, org)
+	if err != nil {
+		return "", err
 	}
 
-	if org.AddressLine1 != "" {
-		params.Address = &stripe.AddressParams{
-			Line1:      stripe.String(org.AddressLine1),
-			Line2:      stripe.String(org.AddressLine2),
-			City:       stripe.String(org.City),
-			State:      stripe.String(org.State),
-			PostalCode: stripe.String(org.PostalCode),
-			Country:    stripe.String(org.Country),
+	// Stripe customers are stored on the organization
+	if provider.Name() == PaymentProviderStripe {
+		if err := ps.db.WithContext(ctx).
+			Model(org).
+			Update("stripe_customer_id", customerID).Error; err != nil {
+			return "", fmt.Errorf("failed to update organization: %w", err)
 		}
 	}
 
-	cust, err := customer.New(params)
-	if err != nil {
-		return "", fmt.Errorf("failed to create Stripe customer: %w", err)
-	}
+	return customerID, nil
+}
 
-	// Update organization with Stripe customer ID
-	if err := ps.db.WithContext(ctx).
-		Model(org).
-		Update("stripe_customer_id", cust.ID).Error; err != nil {
-		return "", fmt.Errorf("failed to update organization: %w", err)
+// customerID returns the provider customer ID of an organization, creating
+// the customer if needed
+func (ps *PaymentService) customerID(
+	ctx context.Context,
+	provider PaymentProvider,
+	org *models.Organization,
+) (string, error) {
+	if provider.Name() == PaymentProviderStripe && org.StripeCustomerID != "" {
+		return org.StripeCustomerID, nil
 	}
 
-	return cust.ID, nil
+	customerID, err := ps.CreateCustomer(ctx, org)
+	if err != nil {
+		return "", err
+	}
+	if provider.Name() == PaymentProviderStripe {
+		org.StripeCustomerID = customerID
+	}
+	return customerID, nil
 }
 
-// AttachPaymentMethod attaches a payment method to a customer
+// AttachPaymentMethod attaches a payment method to the customer of the tenant in ctx
 func (ps *PaymentService) AttachPaymentMethod(
 	ctx context.Context,
-	organizationID, paymentMethodID string,
+	paymentMethodID string,
 	setAsDefault bool,
 ) error {
-	if !ps.config.Stripe.Enabled {
-		return fmt.Errorf("Stripe is not enabled")
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return err
 	}
 
 	// Fetch organization
@@ -102,37 +165,24 @@ func (ps *PaymentService) AttachPaymentMethod(
 		return fmt.Errorf("failed to fetch organization: %w", err)
 	}
 
-	// Ensure organization has a Stripe customer
-	if org.StripeCustomerID == "" {
-		customerID, err := ps.CreateStripeCustomer(ctx, &org)
-		if err != nil {
-			return err
-		}
-		org.StripeCustomerID = customerID
+	provider := ps.providerFor(&org)
+	if provider == nil {
+		return fmt.Errorf("organization has no payment provider")
 	}
 
 	// Attach payment method to customer
-	params := &stripe.PaymentMethodAttachParams{
-		Customer: stripe.String(org.StripeCustomerID),
-	}
-
-	if _, err := paymentmethod.Attach(paymentMethodID, params); err != nil {
-		return fmt.Errorf("failed to attach payment method: %w", err)
+	if attacher, ok := provider.(PaymentMethodAttacher); ok {
+		customerID, err := ps.customerID(ctx, provider, &org)
+		if err != nil {
+			return err
+		}
+		if err := attacher.AttachPaymentMethod(ctx, customerID, paymentMethodID, setAsDefault); err != nil {
+			return err
+		}
 	}
 
 	// Set as default if requested
 	if setAsDefault {
-		updateParams := &stripe.CustomerParams{
-			InvoiceSettings: &stripe.CustomerInvoiceSettingsParams{
-				DefaultPaymentMethod: stripe.String(paymentMethodID),
-			},
-		}
-
-		if _, err := customer.Update(org.StripeCustomerID, updateParams); err != nil {
-			return fmt.Errorf("failed to set default payment method: %w", err)
-		}
-
-		// Update organization
 		if err := ps.db.WithContext(ctx).
 			Model(&org).
 			Update("default_payment_method_id", paymentMethodID).Error; err != nil {
@@ -143,198 +193,205 @@ func (ps *PaymentService) AttachPaymentMethod(
 	return nil
 }
 
-// ChargeInvoice charges a payment method for an invoice
+// ChargeInvoice charges the payment provider of the organization for an
+// invoice. The invoice is locked while its payment is created, and it is not
+// charged again while a pending or succeeded payment exists for it.
 func (ps *PaymentService) ChargeInvoice(
 	ctx context.Context,
 	invoiceID string,
 ) (*models.Payment, error) {
-	// 1. Fetch invoice
-	invoice, err := ps.invoiceService.GetInvoice(ctx, invoiceID)
+	var invoice models.Invoice
+	var org models.Organization
+	var payment *models.Payment
+	var provider PaymentProvider
+
+	err := ps.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1. Lock invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&invoice, "id = ?", invoiceID).Error; err != nil {
+			return fmt.Errorf("failed to fetch invoice: %w", err)
+		}
+
+		// 2. Check if already paid or being paid
+		if invoice.Status == string(InvoiceStatusPaid) {
+			return fmt.Errorf("invoice already paid")
+		}
+		var inFlight int64
+		if err := tx.Model(&models.Payment{}).
+			Where("invoice_id = ? AND status IN ?", invoice.ID, []PaymentStatus{
+				PaymentStatusPending,
+				PaymentStatusSucceeded,
+			}).
+			Count(&inFlight).Error; err != nil {
+			return fmt.Errorf("failed to count payments: %w", err)
+		}
+		if inFlight > 0 {
+			return fmt.Errorf("invoice already has a pending or succeeded payment")
+		}
+
+		// 3. Fetch organization
+		if err := tx.First(&org, "id = ?", invoice.OrganizationID).Error; err != nil {
+			return fmt.Errorf("failed to fetch organization: %w", err)
+		}
+		provider = ps.providerFor(&org)
+
+		// 4. Create payment record
+		payment = &models.Payment{
+			ID:              uuid.New(),
+			OrganizationID:  invoice.OrganizationID,
+			InvoiceID:       invoice.ID,
+			Amount:          invoice.AmountDue,
+			Currency:        invoice.Currency,
+			Status:          string(PaymentStatusPending),
+			Provider:        string(PaymentProviderManual),
+			PaymentMethodID: org.DefaultPaymentMethodID,
+		}
+		if provider != nil {
+			payment.Provider = string(provider.Name())
+		}
+		if err := ps.payments.Create(withTx(ctx, tx), payment); err != nil {
+			return fmt.Errorf("failed to create payment record: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch invoice: %w", err)
+		return nil, err
 	}
 
-	// 2. Check if already paid
-	if invoice.Status == string(InvoiceStatusPaid) {
-		return nil, fmt.Errorf("invoice already paid")
+	// Manual payments stay pending until they are recorded
+	if provider == nil {
+		return payment, nil
 	}
 
-	// 3. Fetch organization
-	var org models.Organization
-	if err := ps.db.WithContext(ctx).First(&org, "id = ?", invoice.OrganizationID).Error; err != nil {
-		return nil, fmt.Errorf("failed to fetch organization: %w", err)
-	}
-
-	// 4. Create payment record
-	payment := &models.Payment{
-		ID:             uuid.New(),
-		OrganizationID: invoice.OrganizationID,
-		InvoiceID:      invoice.ID,
-		Amount:         invoice.AmountDue,
-		Currency:       invoice.Currency,
-		Status:         string(PaymentStatusPending),
-		Provider:       string(PaymentProviderStripe),
-		PaymentMethodID: org.DefaultPaymentMethodID,
-		ProviderCustomerID: org.StripeCustomerID,
-	}
-
-	// Save payment record
-	if err := ps.db.WithContext(ctx).Create(payment).Error; err != nil {
-		return nil, fmt.Errorf("failed to create payment record: %w", err)
-	}
-
-	// 5. Process payment with Stripe
-	if ps.config.Stripe.Enabled {
-		if err := ps.processStripePayment(ctx, payment, invoice, &org); err != nil {
-			// Update payment as failed
-			now := time.Now()
-			ps.db.WithContext(ctx).Model(payment).Updates(map[string]interface{}{
-				"status":         PaymentStatusFailed,
-				"failed_at":      now,
-				"failure_message": err.Error(),
-			})
-			return payment, err
+	// 5. Process payment with the provider
+	result, err := ps.charge(ctx, provider, payment, &invoice, &org)
+	if err != nil {
+		result = &PaymentResult{
+			Status:         PaymentStatusFailed,
+			FailureMessage: err.Error(),
 		}
 	}
 
-	// 6. Reload payment with updates
-	if err := ps.db.WithContext(ctx).First(payment, "id = ?", payment.ID).Error; err != nil {
-		return nil, fmt.Errorf("failed to reload payment: %w", err)
+	// 6. Record the outcome; failed payments enter dunning
+	if applyErr := ps.applyResult(ctx, payment, result); applyErr != nil {
+		return payment, errors.Join(err, applyErr)
 	}
 
-	return payment, nil
+	// 7. Reload payment with updates
+	reloaded, reloadErr := ps.payments.Get(ctx, payment.ID.String())
+	if reloadErr != nil {
+		return payment, errors.Join(err, fmt.Errorf("failed to reload payment: %w", reloadErr))
+	}
+	payment = reloaded
+
+	return payment, err
 }
 
-// processStripePayment processes a payment via Stripe
-func (ps *PaymentService) processStripePayment(
+// charge sends a payment to its provider
+func (ps *PaymentService) charge(
 	ctx context.Context,
+	provider PaymentProvider,
 	payment *models.Payment,
 	invoice *models.Invoice,
 	org *models.Organization,
-) error {
-	// Convert amount to cents
-	amountCents := payment.Amount.Mul(decimal.NewFromInt(100)).IntPart()
-
-	// Create payment intent
-	params := &stripe.PaymentIntentParams{
-		Amount:   stripe.Int64(amountCents),
-		Currency: stripe.String(invoice.Currency),
-		Customer: stripe.String(org.StripeCustomerID),
-		PaymentMethod: stripe.String(payment.PaymentMethodID),
-		Confirm: stripe.Bool(true), // Automatically confirm
-		OffSession: stripe.Bool(true), // For subscription billing
-		Metadata: map[string]string{
-			"invoice_id":      invoice.ID.String(),
-			"organization_id": org.ID.String(),
-			"payment_id":      payment.ID.String(),
-		},
-	}
-
-	pi, err := paymentintent.New(params)
+) (*PaymentResult, error) {
+	customerID, err := ps.customerID(ctx, provider, org)
 	if err != nil {
-		return fmt.Errorf("failed to create payment intent: %w", err)
+		return nil, err
 	}
 
-	// Update payment record
 	now := time.Now()
-	updates := map[string]interface{}{
-		"provider_payment_id": pi.ID,
-		"attempted_at":        now,
-	}
-
-	if pi.Status == stripe.PaymentIntentStatusSucceeded {
-		updates["status"] = PaymentStatusSucceeded
-		updates["succeeded_at"] = now
-
-		// Mark invoice as paid
-		if err := ps.invoiceService.MarkInvoiceAsPaid(ctx, invoice.ID.String(), payment.ID.String(), payment.Amount); err != nil {
-			return fmt.Errorf("failed to mark invoice as paid: %w", err)
-		}
-	} else if pi.Status == stripe.PaymentIntentStatusRequiresAction ||
-		pi.Status == stripe.PaymentIntentStatusRequiresPaymentMethod {
-		updates["status"] = PaymentStatusPending
-	} else {
-		updates["status"] = PaymentStatusFailed
-		updates["failed_at"] = now
-		if pi.LastPaymentError != nil {
-			updates["failure_code"] = pi.LastPaymentError.Code
-			updates["failure_message"] = pi.LastPaymentError.Message
-		}
-	}
-
-	return ps.db.WithContext(ctx).Model(payment).Updates(updates).Error
-}
-
-// HandleWebhook processes payment provider webhooks
-func (ps *PaymentService) HandleWebhook(
-	ctx context.Context,
-	provider PaymentProvider,
-	eventType string,
-	payload map[string]interface{},
-) error {
-	switch provider {
-	case PaymentProviderStripe:
-		return ps.handleStripeWebhook(ctx, eventType, payload)
-	default:
-		return fmt.Errorf("unsupported payment provider: %s", provider)
+	payment.ProviderCustomerID = customerID
+	payment.AttemptedAt = &now
+	if err := ps.payments.Update(ctx, payment, "provider_customer_id", "attempted_at"); err != nil {
+		return nil, fmt.Errorf("failed to update payment: %w", err)
 	}
-}
 
-// handleStripeWebhook handles Stripe webhook events
-func (ps *PaymentService) handleStripeWebhook(
-	ctx context.Context,
-	eventType string,
-	payload map[string]interface{},
-) error {
-	switch eventType {
-	case "payment_intent.succeeded":
-		return ps.handlePaymentIntentSucceeded(ctx, payload)
-	case "payment_intent.payment_failed":
-		return ps.handlePaymentIntentFailed(ctx, payload)
-	case "customer.subscription.updated":
-		// Handle subscription updates
-		return nil
-	case "invoice.payment_succeeded":
-		// Handle invoice payment success
-		return nil
-	default:
-		// Unknown event type, ignore
-		return nil
-	}
+	return provider.Charge(ctx, &ChargeRequest{
+		Payment:      payment,
+		Invoice:      invoice,
+		Organization: org,
+	})
 }
 
-// handlePaymentIntentSucceeded handles successful payment intents
-func (ps *PaymentService) handlePaymentIntentSucceeded(
+// applyResult records the state reported by a provider and publishes the
+// payment succeeded or failed event with the change. Succeeded payments mark
+// their invoice as paid; failed payments enter dunning.
+func (ps *PaymentService) applyResult(
 	ctx context.Context,
-	payload map[string]interface{},
+	payment *models.Payment,
+	result *PaymentResult,
 ) error {
-	// Extract payment intent ID
-	paymentIntentID, ok := payload["id"].(string)
-	if !ok {
-		return fmt.Errorf("invalid payment intent ID")
-	}
-
-	// Find payment by provider payment ID
-	var payment models.Payment
-	if err := ps.db.WithContext(ctx).
-		Where("provider_payment_id = ?", paymentIntentID).
-		First(&payment).Error; err != nil {
-		return fmt.Errorf("payment not found: %w", err)
-	}
-
-	// Update payment status
 	now := time.Now()
-	updates := map[string]interface{}{
-		"status":       PaymentStatusSucceeded,
-		"succeeded_at": now,
-	}
-
-	if err := ps.db.WithContext(ctx).Model(&payment).Updates(updates).Error; err != nil {
-		return fmt.Errorf("failed to update payment: %w", err)
+	payment.Status = string(result.Status)
+	columns := []string{"status"}
+	if result.ProviderPaymentID != "" {
+		payment.ProviderPaymentID = result.ProviderPaymentID
+		columns = append(columns, "provider_payment_id")
+	}
+
+	metadata := payment.Metadata
+	if metadata == nil {
+		metadata = models.JSONB{}
+	}
+	for k, v := range result.Metadata {
+		metadata[k] = v
+	}
+	if result.ActionURL != "" {
+		metadata["action_url"] = result.ActionURL
+	}
+	if len(metadata) > 0 {
+		payment.Metadata = metadata
+		columns = append(columns, "metadata")
+	}
+
+	switch result.Status {
+	case PaymentStatusSucceeded:
+		payment.SucceededAt = &now
+		columns = append(columns, "succeeded_at")
+	case PaymentStatusFailed:
+		payment.FailedAt = &now
+		payment.FailureCode = result.FailureCode
+		payment.FailureMessage = result.FailureMessage
+		columns = append(columns, "failed_at", "failure_code", "failure_message")
+	}
+
+	err := transact(ctx, ps.db, ps.eventPublisher,
+		func(ctx context.Context) error {
+			if err := ps.payments.Update(ctx, payment, columns...); err != nil {
+				return fmt.Errorf("failed to update payment: %w", err)
+			}
+
+			// Payment methods saved by the provider become the default
+			if result.PaymentMethodID != "" {
+				if err := conn(ctx, ps.db).
+					Model(&models.Organization{}).
+					Where("id = ? AND (default_payment_method_id IS NULL OR default_payment_method_id = '')", payment.OrganizationID).
+					Update("default_payment_method_id", result.PaymentMethodID).Error; err != nil {
+					return fmt.Errorf("failed to update organization: %w", err)
+				}
+			}
+			return nil
+		},
+		func(ctx context.Context, publisher *BillingEventPublisher) error {
+			var err error
+			switch result.Status {
+			case PaymentStatusSucceeded:
+				err = publisher.PublishPaymentSucceeded(ctx, payment)
+			case PaymentStatusFailed:
+				err = publisher.PublishPaymentFailed(ctx, payment)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to publish payment %s event: %w", result.Status, err)
+			}
+			return nil
+		})
+	if err != nil {
+		return err
 	}
 
-	// Mark invoice as paid
-	if payment.InvoiceID.String() != "" {
+	switch result.Status {
+	case PaymentStatusSucceeded:
 		if err := ps.invoiceService.MarkInvoiceAsPaid(
 			ctx,
 			payment.InvoiceID.String(),
@@ -343,117 +400,271 @@ func (ps *PaymentService) handlePaymentIntentSucceeded(
 		); err != nil {
 			return fmt.Errorf("failed to mark invoice as paid: %w", err)
 		}
+		return ps.resolveDunning(ctx, payment.InvoiceID.String())
+	case PaymentStatusFailed:
+		return ps.handleFailure(ctx, payment)
 	}
 
-	// TODO: Publish event
-	// eventBus.Publish(EventPaymentSucceeded, payment)
-
 	return nil
 }
 
-// handlePaymentIntentFailed handles failed payment intents
-func (ps *PaymentService) handlePaymentIntentFailed(
+// HandleWebhook processes payment provider webhooks
+func (ps *PaymentService) HandleWebhook(
 	ctx context.Context,
+	providerName PaymentProviderName,
+	eventType string,
 	payload map[string]interface{},
 ) error {
-	// Extract payment intent ID
-	paymentIntentID, ok := payload["id"].(string)
+	provider, ok := ps.providers[providerName]
 	if !ok {
-		return fmt.Errorf("invalid payment intent ID")
+		return fmt.Errorf("unsupported payment provider: %s", providerName)
+	}
+
+	result, err := provider.HandleWebhook(ctx, eventType, payload)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		// Event does not concern a payment
+		return nil
 	}
 
 	// Find payment by provider payment ID
-	var payment models.Payment
-	if err := ps.db.WithContext(ctx).
-		Where("provider_payment_id = ?", paymentIntentID).
-		First(&payment).Error; err != nil {
+	payment, err := ps.payments.GetByProviderPaymentID(ctx, providerName, result.ProviderPaymentID)
+	if err != nil {
 		return fmt.Errorf("payment not found: %w", err)
 	}
 
-	// Extract failure reason
-	var failureMessage string
-	if lastError, ok := payload["last_payment_error"].(map[string]interface{}); ok {
-		if msg, ok := lastError["message"].(string); ok {
-			failureMessage = msg
-		}
+	// Webhooks are delivered at least once
+	if payment.Status == string(result.Status) {
+		return nil
 	}
 
-	// Update payment status
-	now := time.Now()
-	updates := map[string]interface{}{
-		"status":          PaymentStatusFailed,
-		"failed_at":       now,
-		"failure_message": failureMessage,
-	}
+	return ps.applyResult(ctx, payment, result)
+}
 
-	if err := ps.db.WithContext(ctx).Model(&payment).Updates(updates).Error; err != nil {
-		return fmt.Errorf("failed to update payment: %w", err)
+// handleFailure hands a failed payment over to dunning
+func (ps *PaymentService) handleFailure(ctx context.Context, payment *models.Payment) error {
+	if ps.dunning == nil {
+		return nil
 	}
+	if err := ps.dunning.HandlePaymentFailure(ctx, payment); err != nil {
+		return fmt.Errorf("failed to start dunning: %w", err)
+	}
+	return nil
+}
 
-	// TODO: Publish event
-	// eventBus.Publish(EventPaymentFailed, payment)
-
+// resolveDunning ends the dunning of a paid invoice
+func (ps *PaymentService) resolveDunning(ctx context.Context, invoiceID string) error {
+	if ps.dunning == nil {
+		return nil
+	}
+	if err := ps.dunning.Resolve(ctx, invoiceID); err != nil {
+		return fmt.Errorf("failed to resolve dunning: %w", err)
+	}
 	return nil
 }
 
-// ListPayments retrieves payments for an organization
+// ListPayments retrieves payments for the organization of the tenant in ctx
 func (ps *PaymentService) ListPayments(
 	ctx context.Context,
-	organizationID string,
 	limit, offset int,
 ) ([]models.Payment, error) {
-	var payments []models.Payment
-
-	query := ps.db.WithContext(ctx).
-		Where("organization_id = ?", organizationID).
-		Order("created_at DESC")
-
-	if limit > 0 {
-		query = query.Limit(limit)
-	}
-
-	if offset > 0 {
-		query = query.Offset(offset)
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	err := query.Find(&payments).Error
-	return payments, err
+	return ps.payments.ListByOrganization(ctx, organizationID, limit, offset)
 }
 
-// RefundPayment refunds a payment
+// RefundPayment refunds all or part of a payment. The refunded amount is
+// deducted from the invoice and recorded on a credit note.
 func (ps *PaymentService) RefundPayment(
 	ctx context.Context,
 	paymentID string,
 	amount *decimal.Decimal,
-) error {
-	// Fetch payment
+	reason string,
+) (*models.Refund, error) {
 	var payment models.Payment
-	if err := ps.db.WithContext(ctx).First(&payment, "id = ?", paymentID).Error; err != nil {
-		return fmt.Errorf("failed to fetch payment: %w", err)
-	}
+	var refund *models.Refund
 
-	if payment.Status != string(PaymentStatusSucceeded) {
-		return fmt.Errorf("can only refund succeeded payments")
-	}
+	// Reserve the refund so concurrent refunds cannot exceed the payment
+	err := ps.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&payment, "id = ?", paymentID).Error; err != nil {
+			return fmt.Errorf("failed to fetch payment: %w", err)
+		}
 
-	// Determine refund amount
-	refundAmount := payment.Amount
-	if amount != nil {
-		refundAmount = *amount
-	}
+		if payment.Status != string(PaymentStatusSucceeded) &&
+			payment.Status != string(PaymentStatusPartiallyRefunded) {
+			return fmt.Errorf("can only refund succeeded payments")
+		}
 
-	if refundAmount.GreaterThan(payment.Amount) {
-		return fmt.Errorf("refund amount cannot exceed payment amount")
+		// Amount already refunded or being refunded
+		var reserved decimal.Decimal
+		if err := tx.Model(&models.Refund{}).
+			Where("payment_id = ? AND status != ?", payment.ID, RefundStatusFailed).
+			Select("COALESCE(SUM(amount), 0)").
+			Row().Scan(&reserved); err != nil {
+			return fmt.Errorf("failed to sum refunds: %w", err)
+		}
+		refundable := payment.Amount.Sub(reserved)
+
+		// Determine refund amount
+		refundAmount := refundable
+		if amount != nil {
+			refundAmount = *amount
+		}
+
+		if !refundAmount.IsPositive() {
+			return fmt.Errorf("refund amount must be positive")
+		}
+		if refundAmount.GreaterThan(refundable) {
+			return fmt.Errorf("refund amount cannot exceed the refundable amount of %s", refundable.StringFixed(2))
+		}
+
+		refund = &models.Refund{
+			ID:             uuid.New(),
+			PaymentID:      payment.ID,
+			InvoiceID:      payment.InvoiceID,
+			OrganizationID: payment.OrganizationID,
+			Amount:         refundAmount,
+			Currency:       payment.Currency,
+			Reason:         reason,
+			Status:         string(RefundStatusPending),
+			Provider:       payment.Provider,
+		}
+		if err := tx.Create(refund).Error; err != nil {
+			return fmt.Errorf("failed to create refund: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Payments collected manually are refunded manually
+	result := &RefundResult{Status: RefundStatusSucceeded}
+	if provider, ok := ps.providers[PaymentProviderName(payment.Provider)]; ok {
+		result, err = provider.Refund(ctx, &RefundRequest{
+			RefundID: refund.ID.String(),
+			Payment:  &payment,
+			Amount:   refund.Amount,
+			Reason:   reason,
+		})
+		if err == nil && result.Status == RefundStatusFailed {
+			err = fmt.Errorf("refund was declined by %s", payment.Provider)
+		}
+		if err != nil {
+			if updateErr := ps.db.WithContext(ctx).Model(refund).Updates(map[string]interface{}{
+				"status":          RefundStatusFailed,
+				"failure_message": err.Error(),
+			}).Error; updateErr != nil {
+				return refund, errors.Join(err, fmt.Errorf("failed to update refund: %w", updateErr))
+			}
+			return refund, err
+		}
 	}
 
-	// TODO: Process refund with Stripe
-	// For now, just update the status
+	if err := ps.applyRefund(ctx, &payment, refund, result); err != nil {
+		return refund, err
+	}
+	return refund, nil
+}
 
+// applyRefund deducts an accepted refund from its payment and invoice and
+// publishes the payment refunded event with the change
+func (ps *PaymentService) applyRefund(
+	ctx context.Context,
+	payment *models.Payment,
+	refund *models.Refund,
+	result *RefundResult,
+) error {
 	now := time.Now()
-	updates := map[string]interface{}{
-		"status":      PaymentStatusRefunded,
-		"refunded_at": now,
+
+	return transact(ctx, ps.db, ps.eventPublisher,
+		func(ctx context.Context) error {
+			tx := conn(ctx, ps.db)
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(payment, "id = ?", payment.ID).Error; err != nil {
+				return fmt.Errorf("failed to fetch payment: %w", err)
+			}
+
+			payment.AmountRefunded = payment.AmountRefunded.Add(refund.Amount)
+			payment.Status = string(PaymentStatusPartiallyRefunded)
+			if payment.AmountRefunded.GreaterThanOrEqual(payment.Amount) {
+				payment.Status = string(PaymentStatusRefunded)
+			}
+			payment.RefundedAt = &now
+
+			if err := tx.Model(payment).Updates(map[string]interface{}{
+				"amount_refunded": payment.AmountRefunded,
+				"status":          payment.Status,
+				"refunded_at":     now,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to update payment: %w", err)
+			}
+
+			refundUpdates := map[string]interface{}{
+				"status":             result.Status,
+				"provider_refund_id": result.ProviderRefundID,
+				"refunded_at":        now,
+			}
+
+			if payment.InvoiceID != uuid.Nil {
+				var invoice models.Invoice
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+					First(&invoice, "id = ?", payment.InvoiceID).Error; err != nil {
+					return fmt.Errorf("failed to fetch invoice: %w", err)
+				}
+
+				if err := tx.Model(&invoice).
+					Update("amount_paid", gorm.Expr("GREATEST(amount_paid - ?, 0)", refund.Amount)).Error; err != nil {
+					return fmt.Errorf("failed to update invoice: %w", err)
+				}
+
+				if ps.creditNotes != nil {
+					creditNote, err := ps.creditNotes.refundCreditNote(tx, &invoice, refund)
+					if err != nil {
+						return err
+					}
+					if creditNote != nil {
+						refund.CreditNoteID = &creditNote.ID
+						refundUpdates["credit_note_id"] = creditNote.ID
+					}
+				}
+			}
+
+			if err := tx.Model(refund).Updates(refundUpdates).Error; err != nil {
+				return fmt.Errorf("failed to update refund: %w", err)
+			}
+			refund.Status = string(result.Status)
+			refund.ProviderRefundID = result.ProviderRefundID
+			refund.RefundedAt = &now
+
+			return nil
+		},
+		func(ctx context.Context, publisher *BillingEventPublisher) error {
+			if err := publisher.PublishPaymentRefunded(ctx, payment, refund); err != nil {
+				return fmt.Errorf("failed to publish payment refunded event: %w", err)
+			}
+			return nil
+		})
+}
+
+// ListRefunds retrieves the refunds of a payment of the organization of the
+// tenant in ctx
+func (ps *PaymentService) ListRefunds(ctx context.Context, paymentID string) ([]models.Refund, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return ps.db.WithContext(ctx).Model(&payment).Updates(updates).Error
+	var refunds []models.Refund
+	err = ps.db.WithContext(ctx).
+		Where("organization_id = ? AND payment_id = ?", organizationID, paymentID).
+		Order("created_at DESC").
+		Find(&refunds).Error
+	return refunds, err
 }
@@ -0,0 +1,376 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+)
+
+// payPalURLs are the PayPal REST API base URLs by environment
+var payPalURLs = map[string]string{
+	"sandbox":    "https://api-m.sandbox.paypal.com",
+	"production": "https://api-m.paypal.com",
+}
+
+// PayPalPaymentProvider collects payments with PayPal Checkout orders. The
+// first payment is approved by the customer; PayPal then vaults the account
+// so later invoices are charged without customer interaction.
+type PayPalPaymentProvider struct {
+	config *Config
+	client *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+// NewPayPalPaymentProvider creates a PayPal payment provider
+func NewPayPalPaymentProvider(config *Config) *PayPalPaymentProvider {
+	return &PayPalPaymentProvider{
+		config: config,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name implements PaymentProvider
+func (p *PayPalPaymentProvider) Name() PaymentProviderName {
+	return PaymentProviderPayPal
+}
+
+// CreateCustomer implements PaymentProvider. PayPal creates vault customers
+// on first use, identified by the organization ID.
+func (p *PayPalPaymentProvider) CreateCustomer(_ context.Context, org *models.Organization) (string, error) {
+	return org.ID.String(), nil
+}
+
+// payPalOrder is the subset of a PayPal order used for billing
+type payPalOrder struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	PaymentSource struct {
+		PayPal struct {
+			Attributes struct {
+				Vault struct {
+					ID string `json:"id"`
+				} `json:"vault"`
+			} `json:"attributes"`
+		} `json:"paypal"`
+	} `json:"payment_source"`
+	PurchaseUnits []struct {
+		Payments struct {
+			Captures []struct {
+				ID     string `json:"id"`
+				Status string `json:"status"`
+			} `json:"captures"`
+		} `json:"payments"`
+	} `json:"purchase_units"`
+	Links []struct {
+		Href string `json:"href"`
+		Rel  string `json:"rel"`
+	} `json:"links"`
+}
+
+// Charge implements PaymentProvider. With a vaulted account the order is
+// captured immediately; otherwise the payment stays pending until the
+// customer approves it at the returned action URL.
+func (p *PayPalPaymentProvider) Charge(ctx context.Context, req *ChargeRequest) (*PaymentResult, error) {
+	payment := req.Payment
+
+	paypalSource := map[string]interface{}{}
+	if payment.PaymentMethodID != "" {
+		paypalSource["vault_id"] = payment.PaymentMethodID
+	} else {
+		paypalSource["experience_context"] = map[string]interface{}{
+			"return_url":  p.config.PayPal.ReturnURL,
+			"cancel_url":  p.config.PayPal.CancelURL,
+			"user_action": "PAY_NOW",
+		}
+		paypalSource["attributes"] = map[string]interface{}{
+			"customer": map[string]string{"id": payment.ProviderCustomerID},
+			"vault": map[string]string{
+				"store_in_vault": "ON_SUCCESS",
+				"usage_type":     "MERCHANT",
+			},
+		}
+	}
+
+	body := map[string]interface{}{
+		"intent": "CAPTURE",
+		"purchase_units": []map[string]interface{}{{
+			"reference_id": req.Invoice.ID.String(),
+			"invoice_id":   req.Invoice.InvoiceNumber,
+			"custom_id":    payment.ID.String(),
+			"amount": map[string]string{
+				"currency_code": strings.ToUpper(payment.Currency),
+				"value":         payment.Amount.StringFixed(2),
+			},
+		}},
+		"payment_source": map[string]interface{}{
+			"paypal": paypalSource,
+		},
+	}
+
+	var order payPalOrder
+	if err := p.do(ctx, http.MethodPost, "/v2/checkout/orders", payment.ID.String(), body, &order); err != nil {
+		return nil, fmt.Errorf("failed to create PayPal order: %w", err)
+	}
+
+	return payPalOrderResult(&order), nil
+}
+
+// payPalOrderResult maps a PayPal order to a payment result
+func payPalOrderResult(order *payPalOrder) *PaymentResult {
+	result := &PaymentResult{
+		ProviderPaymentID: order.ID,
+		Status:            PaymentStatusPending,
+		PaymentMethodID:   order.PaymentSource.PayPal.Attributes.Vault.ID,
+	}
+
+	for _, unit := range order.PurchaseUnits {
+		for _, capture := range unit.Payments.Captures {
+			result.Metadata = map[string]interface{}{"capture_id": capture.ID}
+			switch capture.Status {
+			case "COMPLETED":
+				result.Status = PaymentStatusSucceeded
+			case "DECLINED", "FAILED":
+				result.Status = PaymentStatusFailed
+				result.FailureCode = strings.ToLower(capture.Status)
+				result.FailureMessage = "PayPal capture " + strings.ToLower(capture.Status)
+			}
+		}
+	}
+
+	if order.Status == "PAYER_ACTION_REQUIRED" {
+		for _, link := range order.Links {
+			if link.Rel == "payer-action" || link.Rel == "approve" {
+				result.ActionURL = link.Href
+			}
+		}
+	}
+
+	return result
+}
+
+// Refund implements PaymentProvider
+func (p *PayPalPaymentProvider) Refund(ctx context.Context, req *RefundRequest) (*RefundResult, error) {
+	captureID, _ := req.Payment.Metadata["capture_id"].(string)
+	if captureID == "" {
+		return nil, fmt.Errorf("payment has no PayPal capture")
+	}
+
+	body := map[string]interface{}{
+		"amount": map[string]string{
+			"currency_code": strings.ToUpper(req.Payment.Currency),
+			"value":         req.Amount.StringFixed(2),
+		},
+		"note_to_payer": req.Reason,
+	}
+
+	var refund struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	path := "/v2/payments/captures/" + url.PathEscape(captureID) + "/refund"
+	if err := p.do(ctx, http.MethodPost, path, req.RefundID, body, &refund); err != nil {
+		return nil, fmt.Errorf("failed to create PayPal refund: %w", err)
+	}
+
+	result := &RefundResult{ProviderRefundID: refund.ID}
+	switch refund.Status {
+	case "COMPLETED":
+		result.Status = RefundStatusSucceeded
+	case "CANCELLED", "FAILED":
+		result.Status = RefundStatusFailed
+	default:
+		result.Status = RefundStatusPending
+	}
+
+	return result, nil
+}
+
+// payPalCapture is the subset of a PayPal capture used for billing
+type payPalCapture struct {
+	ID                string `json:"id"`
+	Status            string `json:"status"`
+	SupplementaryData struct {
+		RelatedIDs struct {
+			OrderID string `json:"order_id"`
+		} `json:"related_ids"`
+	} `json:"supplementary_data"`
+}
+
+// HandleWebhook implements PaymentProvider. Approved orders are captured.
+// Webhook payloads are not trusted: PayPal only captures orders its customer
+// approved, and captures are read back from the PayPal API, so a forged
+// event cannot change the state of a payment.
+func (p *PayPalPaymentProvider) HandleWebhook(
+	ctx context.Context,
+	eventType string,
+	payload map[string]interface{},
+) (*PaymentResult, error) {
+	resource, ok := payload["resource"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid PayPal webhook resource")
+	}
+
+	switch eventType {
+	case "CHECKOUT.ORDER.APPROVED":
+		orderID, _ := resource["id"].(string)
+		if orderID == "" {
+			return nil, fmt.Errorf("invalid PayPal order ID")
+		}
+
+		var order payPalOrder
+		path := "/v2/checkout/orders/" + url.PathEscape(orderID) + "/capture"
+		if err := p.do(ctx, http.MethodPost, path, orderID+"-capture", nil, &order); err != nil {
+			return nil, fmt.Errorf("failed to capture PayPal order: %w", err)
+		}
+		return payPalOrderResult(&order), nil
+
+	case "PAYMENT.CAPTURE.COMPLETED", "PAYMENT.CAPTURE.DENIED", "PAYMENT.CAPTURE.DECLINED":
+		captureID, _ := resource["id"].(string)
+		if captureID == "" {
+			return nil, fmt.Errorf("invalid PayPal capture ID")
+		}
+
+		var capture payPalCapture
+		path := "/v2/payments/captures/" + url.PathEscape(captureID)
+		if err := p.do(ctx, http.MethodGet, path, "", nil, &capture); err != nil {
+			return nil, fmt.Errorf("failed to get PayPal capture: %w", err)
+		}
+		if capture.SupplementaryData.RelatedIDs.OrderID == "" {
+			return nil, fmt.Errorf("PayPal capture has no related order")
+		}
+
+		result := &PaymentResult{
+			ProviderPaymentID: capture.SupplementaryData.RelatedIDs.OrderID,
+			Metadata:          map[string]interface{}{"capture_id": capture.ID},
+		}
+		switch capture.Status {
+		case "COMPLETED":
+			result.Status = PaymentStatusSucceeded
+		case "DECLINED", "FAILED":
+			result.Status = PaymentStatusFailed
+			result.FailureCode = "capture_denied"
+			result.FailureMessage = "PayPal denied the payment"
+		default:
+			// Pending or refunded since, nothing to apply
+			return nil, nil
+		}
+		return result, nil
+
+	default:
+		// Unknown event type, ignore
+		return nil, nil
+	}
+}
+
+// do calls the PayPal REST API. The request ID makes retries idempotent.
+func (p *PayPalPaymentProvider) do(
+	ctx context.Context,
+	method, path, requestID string,
+	body interface{},
+	out interface{},
+) error {
+	token, err := p.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, payPalURLs[p.config.PayPal.Environment]+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Prefer", "return=representation")
+	if requestID != "" {
+		req.Header.Set("PayPal-Request-Id", requestID)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call PayPal: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Name    string `json:"name"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("PayPal returned status %d: %s %s", resp.StatusCode, apiErr.Name, apiErr.Message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode PayPal response: %w", err)
+	}
+	return nil
+}
+
+// token returns a cached OAuth access token, requesting a new one shortly
+// before it expires
+func (p *PayPalPaymentProvider) token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.accessToken != "" && time.Now().Before(p.tokenExpiry) {
+		return p.accessToken, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		payPalURLs[p.config.PayPal.Environment]+"/v1/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.config.PayPalCredentials())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request PayPal token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("PayPal token request returned status %d", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode PayPal token: %w", err)
+	}
+
+	p.accessToken = token.AccessToken
+	p.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return p.accessToken, nil
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/shopspring/decimal"
+)
+
+// PaymentProvider collects payments through an external payment service.
+// PaymentService selects the provider per organization.
+type PaymentProvider interface {
+	Name() PaymentProviderName
+
+	// CreateCustomer registers an organization and returns its customer ID
+	CreateCustomer(ctx context.Context, org *models.Organization) (string, error)
+
+	// Charge collects the amount of a payment. Failed charges are reported
+	// in the result; errors mean the provider could not be reached.
+	Charge(ctx context.Context, req *ChargeRequest) (*PaymentResult, error)
+
+	// Refund refunds part or all of a succeeded payment
+	Refund(ctx context.Context, req *RefundRequest) (*RefundResult, error)
+
+	// HandleWebhook translates a webhook into the state of the payment it
+	// concerns. Events that do not concern a payment return nil.
+	HandleWebhook(ctx context.Context, eventType string, payload map[string]interface{}) (*PaymentResult, error)
+}
+
+// PaymentMethodAttacher is implemented by providers that require payment
+// methods to be attached to the customer before they can be charged
+type PaymentMethodAttacher interface {
+	AttachPaymentMethod(ctx context.Context, customerID, paymentMethodID string, setAsDefault bool) error
+}
+
+// PayoutReporter is implemented by providers that pay collected funds out in
+// payouts. Payouts are reconciled against the internal payments and refunds.
+type PayoutReporter interface {
+	// ListPayouts returns the paid payouts arriving in [start, end) with
+	// their balance transactions
+	ListPayouts(ctx context.Context, start, end time.Time) ([]ProviderPayout, error)
+}
+
+// ProviderPayout is a payout reported by a provider
+type ProviderPayout struct {
+	ID           string
+	Amount       decimal.Decimal
+	Currency     string
+	ArrivalDate  time.Time
+	Transactions []ProviderTransaction
+}
+
+// ProviderTransaction is a balance transaction settled by a payout
+type ProviderTransaction struct {
+	ID                string
+	Type              string // charge, refund, fee or adjustment
+	ProviderPaymentID string // Payment the charge or refund belongs to
+	ProviderRefundID  string
+	Amount            decimal.Decimal // Gross amount, negative for refunds
+	Fee               decimal.Decimal
+	Net               decimal.Decimal
+	Currency          string
+	Created           time.Time
+}
+
+// Provider transaction types
+const (
+	ProviderTransactionCharge     = "charge"
+	ProviderTransactionRefund     = "refund"
+	ProviderTransactionFee        = "fee"
+	ProviderTransactionAdjustment = "adjustment" // Disputes, reversals and other movements
+)
+
+// ChargeRequest describes a payment to collect
+type ChargeRequest struct {
+	Payment      *models.Payment // Carries the amount, customer and payment method
+	Invoice      *models.Invoice
+	Organization *models.Organization
+}
+
+// PaymentResult is the state of a payment reported by a provider
+type PaymentResult struct {
+	ProviderPaymentID string
+	Status            PaymentStatus
+	FailureCode       string
+	FailureMessage    string
+	ActionURL         string                 // Where the customer completes a pending payment (e.g. PayPal approval)
+	PaymentMethodID   string                 // Payment method saved for future charges (e.g. PayPal vault ID)
+	Metadata          map[string]interface{} // Provider details stored with the payment
+}
+
+// RefundRequest describes a refund of a payment
+type RefundRequest struct {
+	RefundID string // Idempotency key for retries
+	Payment  *models.Payment
+	Amount   decimal.Decimal
+	Reason   string
+}
+
+// RefundResult is the outcome of a refund
+type RefundResult struct {
+	ProviderRefundID string
+	Status           RefundStatus
+}
@@ -0,0 +1,340 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/shopspring/decimal"
+	"github.com/stripe/stripe-go/v75"
+	"github.com/stripe/stripe-go/v75/balancetransaction"
+	"github.com/stripe/stripe-go/v75/customer"
+	"github.com/stripe/stripe-go/v75/paymentintent"
+	"github.com/stripe/stripe-go/v75/paymentmethod"
+	"github.com/stripe/stripe-go/v75/payout"
+	"github.com/stripe/stripe-go/v75/refund"
+)
+
+// StripePaymentProvider collects card and bank payments with Stripe
+// PaymentIntents
+type StripePaymentProvider struct {
+	config *Config
+}
+
+// NewStripePaymentProvider creates a Stripe payment provider
+func NewStripePaymentProvider(config *Config) *StripePaymentProvider {
+	return &StripePaymentProvider{config: config}
+}
+
+// backend returns the Stripe API backend and the current API key. Clients are
+// created per call so that a rotated key takes effect immediately.
+func (p *StripePaymentProvider) backend() (stripe.Backend, string) {
+	return stripe.GetBackend(stripe.APIBackend), p.config.StripeAPIKey()
+}
+
+// Name implements PaymentProvider
+func (p *StripePaymentProvider) Name() PaymentProviderName {
+	return PaymentProviderStripe
+}
+
+// CreateCustomer implements PaymentProvider
+func (p *StripePaymentProvider) CreateCustomer(ctx context.Context, org *models.Organization) (string, error) {
+	params := &stripe.CustomerParams{
+		Email: stripe.String(org.BillingEmail),
+		Name:  stripe.String(org.Name),
+		Metadata: map[string]string{
+			"organization_id": org.ID.String(),
+		},
+	}
+	params.Context = ctx
+
+	if org.AddressLine1 != "" {
+		params.Address = &stripe.AddressParams{
+			Line1:      stripe.String(org.AddressLine1),
+			Line2:      stripe.String(org.AddressLine2),
+			City:       stripe.String(org.City),
+			State:      stripe.String(org.State),
+			PostalCode: stripe.String(org.PostalCode),
+			Country:    stripe.String(org.Country),
+		}
+	}
+
+	b, key := p.backend()
+	cust, err := (&customer.Client{B: b, Key: key}).New(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Stripe customer: %w", err)
+	}
+	return cust.ID, nil
+}
+
+// AttachPaymentMethod implements PaymentMethodAttacher
+func (p *StripePaymentProvider) AttachPaymentMethod(
+	ctx context.Context,
+	customerID, paymentMethodID string,
+	setAsDefault bool,
+) error {
+	params := &stripe.PaymentMethodAttachParams{
+		Customer: stripe.String(customerID),
+	}
+	params.Context = ctx
+
+	b, key := p.backend()
+	if _, err := (&paymentmethod.Client{B: b, Key: key}).Attach(paymentMethodID, params); err != nil {
+		return fmt.Errorf("failed to attach payment method: %w", err)
+	}
+
+	if setAsDefault {
+		updateParams := &stripe.CustomerParams{
+			InvoiceSettings: &stripe.CustomerInvoiceSettingsParams{
+				DefaultPaymentMethod: stripe.String(paymentMethodID),
+			},
+		}
+		updateParams.Context = ctx
+
+		if _, err := (&customer.Client{B: b, Key: key}).Update(customerID, updateParams); err != nil {
+			return fmt.Errorf("failed to set default payment method: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Charge implements PaymentProvider. Payments are confirmed off-session with
+// the stored payment method.
+func (p *StripePaymentProvider) Charge(ctx context.Context, req *ChargeRequest) (*PaymentResult, error) {
+	payment := req.Payment
+
+	params := &stripe.PaymentIntentParams{
+		Amount:        stripe.Int64(toCents(payment.Amount)),
+		Currency:      stripe.String(strings.ToLower(payment.Currency)),
+		Customer:      stripe.String(payment.ProviderCustomerID),
+		PaymentMethod: stripe.String(payment.PaymentMethodID),
+		Confirm:       stripe.Bool(true), // Automatically confirm
+		OffSession:    stripe.Bool(true), // For subscription billing
+		Metadata: map[string]string{
+			"invoice_id":      req.Invoice.ID.String(),
+			"organization_id": req.Organization.ID.String(),
+			"payment_id":      payment.ID.String(),
+		},
+	}
+	params.Context = ctx
+	params.SetIdempotencyKey(payment.ID.String())
+
+	b, key := p.backend()
+	pi, err := (&paymentintent.Client{B: b, Key: key}).New(params)
+	if err != nil {
+		// Declines are returned as errors carrying the payment intent
+		if stripeErr, ok := err.(*stripe.Error); ok && stripeErr.Type == stripe.ErrorTypeCard {
+			result := &PaymentResult{
+				Status:         PaymentStatusFailed,
+				FailureCode:    string(stripeErr.Code),
+				FailureMessage: stripeErr.Msg,
+			}
+			if stripeErr.PaymentIntent != nil {
+				result.ProviderPaymentID = stripeErr.PaymentIntent.ID
+			}
+			return result, nil
+		}
+		return nil, fmt.Errorf("failed to create payment intent: %w", err)
+	}
+
+	return paymentIntentResult(pi), nil
+}
+
+// paymentIntentResult maps a payment intent to a payment result
+func paymentIntentResult(pi *stripe.PaymentIntent) *PaymentResult {
+	result := &PaymentResult{ProviderPaymentID: pi.ID}
+
+	switch pi.Status {
+	case stripe.PaymentIntentStatusSucceeded:
+		result.Status = PaymentStatusSucceeded
+	case stripe.PaymentIntentStatusRequiresAction,
+		stripe.PaymentIntentStatusRequiresPaymentMethod,
+		stripe.PaymentIntentStatusProcessing:
+		result.Status = PaymentStatusPending
+		if pi.NextAction != nil && pi.NextAction.RedirectToURL != nil {
+			result.ActionURL = pi.NextAction.RedirectToURL.URL
+		}
+	case stripe.PaymentIntentStatusCanceled:
+		result.Status = PaymentStatusCanceled
+	default:
+		result.Status = PaymentStatusFailed
+	}
+
+	if pi.LastPaymentError != nil && result.Status != PaymentStatusSucceeded {
+		result.FailureCode = string(pi.LastPaymentError.Code)
+		result.FailureMessage = pi.LastPaymentError.Msg
+	}
+
+	return result
+}
+
+// Refund implements PaymentProvider
+func (p *StripePaymentProvider) Refund(ctx context.Context, req *RefundRequest) (*RefundResult, error) {
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(req.Payment.ProviderPaymentID),
+		Amount:        stripe.Int64(toCents(req.Amount)),
+		Metadata: map[string]string{
+			"payment_id": req.Payment.ID.String(),
+			"reason":     req.Reason,
+		},
+	}
+	params.Context = ctx
+	params.SetIdempotencyKey(req.RefundID)
+
+	b, key := p.backend()
+	r, err := (&refund.Client{B: b, Key: key}).New(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Stripe refund: %w", err)
+	}
+
+	result := &RefundResult{ProviderRefundID: r.ID}
+	switch r.Status {
+	case stripe.RefundStatusSucceeded:
+		result.Status = RefundStatusSucceeded
+	case stripe.RefundStatusFailed, stripe.RefundStatusCanceled:
+		result.Status = RefundStatusFailed
+	default:
+		result.Status = RefundStatusPending
+	}
+
+	return result, nil
+}
+
+// HandleWebhook implements PaymentProvider. Webhook payloads are not
+// trusted: the payment intent of the event is read back from the Stripe API,
+// so a forged event cannot change the state of a payment.
+func (p *StripePaymentProvider) HandleWebhook(
+	ctx context.Context,
+	eventType string,
+	payload map[string]interface{},
+) (*PaymentResult, error) {
+	switch eventType {
+	case "payment_intent.succeeded", "payment_intent.payment_failed":
+	default:
+		// Unknown event type, ignore
+		return nil, nil
+	}
+
+	paymentIntentID, ok := payload["id"].(string)
+	if !ok || paymentIntentID == "" {
+		return nil, fmt.Errorf("invalid payment intent ID")
+	}
+
+	params := &stripe.PaymentIntentParams{}
+	params.Context = ctx
+	b, key := p.backend()
+	pi, err := (&paymentintent.Client{B: b, Key: key}).Get(paymentIntentID, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment intent: %w", err)
+	}
+
+	return paymentIntentResult(pi), nil
+}
+
+// ListPayouts implements PayoutReporter
+func (p *StripePaymentProvider) ListPayouts(ctx context.Context, start, end time.Time) ([]ProviderPayout, error) {
+	params := &stripe.PayoutListParams{
+		ArrivalDateRange: &stripe.RangeQueryParams{
+			GreaterThanOrEqual: start.Unix(),
+			LesserThan:         end.Unix(),
+		},
+		Status: stripe.String(string(stripe.PayoutStatusPaid)),
+	}
+	params.Context = ctx
+
+	var payouts []ProviderPayout
+	b, key := p.backend()
+	iter := (&payout.Client{B: b, Key: key}).List(params)
+	for iter.Next() {
+		po := iter.Payout()
+		transactions, err := p.payoutTransactions(ctx, po.ID)
+		if err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, ProviderPayout{
+			ID:           po.ID,
+			Amount:       fromCents(po.Amount),
+			Currency:     strings.ToUpper(string(po.Currency)),
+			ArrivalDate:  time.Unix(po.ArrivalDate, 0).UTC(),
+			Transactions: transactions,
+		})
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list Stripe payouts: %w", err)
+	}
+
+	return payouts, nil
+}
+
+// payoutTransactions returns the balance transactions settled by a payout.
+// The transaction of the payout itself is left out.
+func (p *StripePaymentProvider) payoutTransactions(ctx context.Context, payoutID string) ([]ProviderTransaction, error) {
+	params := &stripe.BalanceTransactionListParams{
+		Payout: stripe.String(payoutID),
+	}
+	params.Context = ctx
+	params.AddExpand("data.source")
+
+	var transactions []ProviderTransaction
+	b, key := p.backend()
+	iter := (&balancetransaction.Client{B: b, Key: key}).List(params)
+	for iter.Next() {
+		bt := iter.BalanceTransaction()
+		if bt.Type == stripe.BalanceTransactionTypePayout {
+			continue
+		}
+
+		transaction := ProviderTransaction{
+			ID:       bt.ID,
+			Type:     ProviderTransactionAdjustment,
+			Amount:   fromCents(bt.Amount),
+			Fee:      fromCents(bt.Fee),
+			Net:      fromCents(bt.Net),
+			Currency: strings.ToUpper(string(bt.Currency)),
+			Created:  time.Unix(bt.Created, 0).UTC(),
+		}
+
+		switch bt.Type {
+		case stripe.BalanceTransactionTypeCharge, stripe.BalanceTransactionTypePayment:
+			transaction.Type = ProviderTransactionCharge
+			if bt.Source != nil && bt.Source.Charge != nil && bt.Source.Charge.PaymentIntent != nil {
+				transaction.ProviderPaymentID = bt.Source.Charge.PaymentIntent.ID
+			}
+		case stripe.BalanceTransactionTypeRefund, stripe.BalanceTransactionTypePaymentRefund:
+			transaction.Type = ProviderTransactionRefund
+			if bt.Source != nil && bt.Source.Refund != nil {
+				transaction.ProviderRefundID = bt.Source.Refund.ID
+				if bt.Source.Refund.PaymentIntent != nil {
+					transaction.ProviderPaymentID = bt.Source.Refund.PaymentIntent.ID
+				}
+			}
+		case stripe.BalanceTransactionTypeStripeFee,
+			stripe.BalanceTransactionTypeStripeFxFee,
+			stripe.BalanceTransactionTypeTaxFee:
+			transaction.Type = ProviderTransactionFee
+		}
+
+		transactions = append(transactions, transaction)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list balance transactions of payout %s: %w", payoutID, err)
+	}
+
+	return transactions, nil
+}
+
+// toCents converts an amount to the smallest currency unit
+func toCents(amount decimal.Decimal) int64 {
+	return amount.Mul(decimal.NewFromInt(100)).Round(0).IntPart()
+}
+
+// fromCents converts an amount in the smallest currency unit
+func fromCents(cents int64) decimal.Decimal {
+	return decimal.New(cents, -2)
+}
@@ -0,0 +1,598 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/go-pdf/fpdf"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Line item table column widths in mm (A4 minus 15mm margins)
+const (
+	pdfColDescription = 95.0
+	pdfColQuantity    = 25.0
+	pdfColUnitPrice   = 30.0
+	pdfColAmount      = 30.0
+	pdfRowHeight      = 6.0
+)
+
+// PDFService renders invoices, credit notes and account statements to PDF.
+// Invoices and credit notes are stored in object storage.
+type PDFService struct {
+	db      *gorm.DB
+	config  *Config
+	storage ObjectStorage
+}
+
+// NewPDFService creates a new PDF service
+func NewPDFService(db *gorm.DB, config *Config, storage ObjectStorage) *PDFService {
+	return &PDFService{
+		db:      db,
+		config:  config,
+		storage: storage,
+	}
+}
+
+// GenerateInvoicePDF renders an invoice, stores the PDF and records its URL
+// on the invoice. Existing PDFs are replaced.
+func (ps *PDFService) GenerateInvoicePDF(ctx context.Context, invoiceID string) (*models.Invoice, error) {
+	invoice, err := ps.getInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := ps.generate(ctx, invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// GetInvoicePDF returns the PDF of an invoice, generating it on first access.
+// When ctx carries a tenant, only invoices of its organization are returned.
+func (ps *PDFService) GetInvoicePDF(ctx context.Context, invoiceID string) ([]byte, error) {
+	invoice, err := ps.getInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if invoice.PDFGeneratedAt == nil {
+		return ps.generate(ctx, invoice)
+	}
+
+	data, err := ps.storage.Get(ctx, invoicePDFKey(invoice))
+	if err != nil {
+		// The stored document may have been removed; render it again
+		return ps.generate(ctx, invoice)
+	}
+	return data, nil
+}
+
+// GenerateCreditNotePDF renders a credit note, stores the PDF and records its
+// URL on the credit note. Existing PDFs are replaced.
+func (ps *PDFService) GenerateCreditNotePDF(ctx context.Context, creditNoteID string) (*models.CreditNote, error) {
+	creditNote, err := ps.getCreditNote(ctx, creditNoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := ps.generateCreditNote(ctx, creditNote); err != nil {
+		return nil, err
+	}
+	return creditNote, nil
+}
+
+// GetCreditNotePDF returns the PDF of a credit note, generating it on first
+// access. When ctx carries a tenant, only credit notes of its organization are
+// returned.
+func (ps *PDFService) GetCreditNotePDF(ctx context.Context, creditNoteID string) ([]byte, error) {
+	creditNote, err := ps.getCreditNote(ctx, creditNoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	if creditNote.PDFGeneratedAt == nil {
+		return ps.generateCreditNote(ctx, creditNote)
+	}
+
+	data, err := ps.storage.Get(ctx, creditNotePDFKey(creditNote))
+	if err != nil {
+		// The stored document may have been removed; render it again
+		return ps.generateCreditNote(ctx, creditNote)
+	}
+	return data, nil
+}
+
+// getInvoice loads an invoice with everything rendered on its PDF
+func (ps *PDFService) getInvoice(ctx context.Context, invoiceID string) (*models.Invoice, error) {
+	query := ps.db.WithContext(ctx).
+		Preload("LineItems", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Organization")
+
+	if t, ok := tenant.FromContext(ctx); ok {
+		query = query.Where("organization_id = ?", t.OrganizationID)
+	}
+
+	var invoice models.Invoice
+	if err := query.First(&invoice, "id = ?", invoiceID).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch invoice: %w", err)
+	}
+	return &invoice, nil
+}
+
+// generate renders and stores the PDF of an invoice
+func (ps *PDFService) generate(ctx context.Context, invoice *models.Invoice) ([]byte, error) {
+	data, err := ps.RenderInvoice(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := ps.storage.Put(ctx, invoicePDFKey(invoice), data, "application/pdf")
+	if err != nil {
+		return nil, fmt.Errorf("failed to store invoice PDF: %w", err)
+	}
+
+	now := time.Now()
+	if err := ps.db.WithContext(ctx).
+		Model(&models.Invoice{}).
+		Where("id = ?", invoice.ID).
+		Updates(map[string]interface{}{
+			"pdf_url":          url,
+			"pdf_generated_at": now,
+		}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update invoice PDF: %w", err)
+	}
+
+	invoice.PDFURL = url
+	invoice.PDFGeneratedAt = &now
+	return data, nil
+}
+
+// getCreditNote loads a credit note with everything rendered on its PDF
+func (ps *PDFService) getCreditNote(ctx context.Context, creditNoteID string) (*models.CreditNote, error) {
+	query := ps.db.WithContext(ctx).
+		Preload("LineItems", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Invoice").
+		Preload("Organization")
+
+	if t, ok := tenant.FromContext(ctx); ok {
+		query = query.Where("organization_id = ?", t.OrganizationID)
+	}
+
+	var creditNote models.CreditNote
+	if err := query.First(&creditNote, "id = ?", creditNoteID).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch credit note: %w", err)
+	}
+	return &creditNote, nil
+}
+
+// generateCreditNote renders and stores the PDF of a credit note
+func (ps *PDFService) generateCreditNote(ctx context.Context, creditNote *models.CreditNote) ([]byte, error) {
+	data, err := ps.RenderCreditNote(creditNote)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := ps.storage.Put(ctx, creditNotePDFKey(creditNote), data, "application/pdf")
+	if err != nil {
+		return nil, fmt.Errorf("failed to store credit note PDF: %w", err)
+	}
+
+	now := time.Now()
+	if err := ps.db.WithContext(ctx).
+		Model(&models.CreditNote{}).
+		Where("id = ?", creditNote.ID).
+		Updates(map[string]interface{}{
+			"pdf_url":          url,
+			"pdf_generated_at": now,
+		}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update credit note PDF: %w", err)
+	}
+
+	creditNote.PDFURL = url
+	creditNote.PDFGeneratedAt = &now
+	return data, nil
+}
+
+// creditNotePDFKey returns the storage key of a credit note PDF
+func creditNotePDFKey(creditNote *models.CreditNote) string {
+	return fmt.Sprintf("credit-notes/%s/%s.pdf", creditNote.OrganizationID, creditNote.CreditNoteNumber)
+}
+
+// invoicePDFKey returns the storage key of an invoice PDF
+func invoicePDFKey(invoice *models.Invoice) string {
+	return fmt.Sprintf("invoices/%s/%s.pdf", invoice.OrganizationID, invoice.InvoiceNumber)
+}
+
+// RenderInvoice renders an invoice to PDF. The invoice must have its line
+// items and organization loaded.
+func (ps *PDFService) RenderInvoice(invoice *models.Invoice) ([]byte, error) {
+	doc := &pdfDocument{
+		Title:    "Invoice " + invoice.InvoiceNumber,
+		Heading:  "INVOICE",
+		Currency: invoice.Currency,
+		Details: [][2]string{
+			{"Invoice number", invoice.InvoiceNumber},
+			{"Invoice date", invoice.InvoiceDate.Format("2006-01-02")},
+			{"Due date", invoice.DueDate.Format("2006-01-02")},
+			{"Billing period", invoice.PeriodStart.Format("2006-01-02") + " - " + invoice.PeriodEnd.Format("2006-01-02")},
+			{"Status", strings.ToUpper(invoice.Status)},
+		},
+		Organization: &invoice.Organization,
+	}
+
+	var taxItems []models.InvoiceLineItem
+	for _, item := range invoice.LineItems {
+		if LineItemType(item.ItemType) == LineItemTypeTax {
+			taxItems = append(taxItems, item)
+			continue
+		}
+		doc.Rows = append(doc.Rows, lineItemRow(item.Description, item.Quantity, item.UnitPrice, item.Amount, doc.Currency))
+	}
+
+	// Totals with the tax breakdown
+	doc.total("Subtotal", invoice.Subtotal, false)
+	for _, item := range taxItems {
+		if reverse, _ := item.Metadata["reverse_charge"].(bool); reverse {
+			doc.Note = item.Description
+			doc.total("VAT (reverse charge)", item.Amount, false)
+			continue
+		}
+		doc.total(item.Description, item.Amount, false)
+	}
+	if len(taxItems) == 0 && invoice.TaxAmount.GreaterThan(decimal.Zero) {
+		doc.total("Tax", invoice.TaxAmount, false)
+	}
+	doc.total("Total", invoice.TotalAmount, true)
+	if invoice.AmountCredited.GreaterThan(decimal.Zero) {
+		doc.total("Credited", invoice.AmountCredited.Neg(), false)
+	}
+	doc.total("Amount paid", invoice.AmountPaid.Neg(), false)
+	doc.total("Amount due", invoice.AmountDue, true)
+
+	return ps.render(doc)
+}
+
+// RenderCreditNote renders a credit note to PDF. The credit note must have
+// its line items, invoice and organization loaded.
+func (ps *PDFService) RenderCreditNote(creditNote *models.CreditNote) ([]byte, error) {
+	settlement := "Refunded"
+	if creditNote.RefundID == nil {
+		settlement = "Credited to account"
+	}
+
+	doc := &pdfDocument{
+		Title:    "Credit note " + creditNote.CreditNoteNumber,
+		Heading:  "CREDIT NOTE",
+		Currency: creditNote.Currency,
+		Details: [][2]string{
+			{"Credit note number", creditNote.CreditNoteNumber},
+			{"Issue date", creditNote.IssuedAt.Format("2006-01-02")},
+			{"Invoice", creditNote.Invoice.InvoiceNumber},
+			{"Settlement", settlement},
+			{"Status", strings.ToUpper(creditNote.Status)},
+		},
+		Organization: &creditNote.Organization,
+		Note:         creditNote.Reason,
+	}
+
+	for _, item := range creditNote.LineItems {
+		doc.Rows = append(doc.Rows, lineItemRow(item.Description, item.Quantity, item.UnitPrice, item.Amount, doc.Currency))
+	}
+
+	doc.total("Subtotal", creditNote.Subtotal, false)
+	if creditNote.TaxAmount.GreaterThan(decimal.Zero) {
+		doc.total("Tax", creditNote.TaxAmount, false)
+	}
+	doc.total("Total credited", creditNote.TotalAmount, true)
+
+	return ps.render(doc)
+}
+
+// RenderStatement renders the account statement of an organization to PDF
+func (ps *PDFService) RenderStatement(statement *AccountStatement, org *models.Organization) ([]byte, error) {
+	period := statement.PeriodStart.Format("2006-01-02") + " - " + statement.PeriodEnd.Format("2006-01-02")
+	doc := &pdfDocument{
+		Title:    "Account statement " + period,
+		Heading:  "ACCOUNT STATEMENT",
+		Currency: statement.Currency,
+		Details: [][2]string{
+			{"Statement period", period},
+			{"Generated", statement.GeneratedAt.Format("2006-01-02")},
+			{"Currency", statement.Currency},
+		},
+		Organization: org,
+		Columns:      [4]string{"Transaction", "Debit", "Credit", "Balance"},
+		Note:         "A positive balance is owed; a negative balance is unused credit.",
+	}
+
+	for _, entry := range statement.Entries {
+		description := entry.Date.Format("2006-01-02") + "  " + entry.Description
+		if entry.Reference != "" {
+			description += " (" + entry.Reference + ")"
+		}
+		doc.Rows = append(doc.Rows, pdfRow{
+			Description: description,
+			Cells: [3]string{
+				formatStatementAmount(entry.Debit, statement.Currency),
+				formatStatementAmount(entry.Credit, statement.Currency),
+				formatMoney(entry.Balance, statement.Currency),
+			},
+		})
+	}
+
+	doc.total("Opening balance", statement.OpeningBalance, false)
+	doc.total("Debits", statement.TotalDebits, false)
+	doc.total("Credits", statement.TotalCredits.Neg(), false)
+	doc.total("Closing balance", statement.ClosingBalance, true)
+
+	return ps.render(doc)
+}
+
+// formatStatementAmount formats a debit or credit, leaving zero amounts blank
+func formatStatementAmount(amount decimal.Decimal, currency string) string {
+	if amount.IsZero() {
+		return ""
+	}
+	return formatMoney(amount, currency)
+}
+
+// pdfDocument is the content of a billing document PDF
+type pdfDocument struct {
+	Title        string
+	Heading      string
+	Currency     string
+	Details      [][2]string
+	Organization *models.Organization
+	Columns      [4]string // Table headers, line item columns when empty
+	Rows         []pdfRow
+	Totals       []pdfTotal
+	Note         string // Printed below the totals
+}
+
+// lineItemColumns are the table headers of invoices and credit notes
+var lineItemColumns = [4]string{"Description", "Quantity", "Unit price", "Amount"}
+
+// pdfRow is a table row of a PDF: a description followed by three
+// right-aligned cells
+type pdfRow struct {
+	Description string
+	Cells       [3]string
+}
+
+// lineItemRow returns the table row of a line item
+func lineItemRow(description string, quantity, unitPrice, amount decimal.Decimal, currency string) pdfRow {
+	return pdfRow{
+		Description: description,
+		Cells: [3]string{
+			quantity.String(),
+			formatMoney(unitPrice.Round(2), currency),
+			formatMoney(amount, currency),
+		},
+	}
+}
+
+// pdfTotal is a row of the totals of a PDF
+type pdfTotal struct {
+	Label  string
+	Amount decimal.Decimal
+	Bold   bool
+}
+
+// total appends a row to the totals of a document
+func (d *pdfDocument) total(label string, amount decimal.Decimal, bold bool) {
+	d.Totals = append(d.Totals, pdfTotal{label, amount, bold})
+}
+
+// render renders a billing document with the configured branding
+func (ps *PDFService) render(doc *pdfDocument) ([]byte, error) {
+	branding := ps.config.Branding
+	accentR, accentG, accentB, err := parseHexColor(branding.AccentColor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid accent color: %w", err)
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(doc.Title, true)
+	pdf.SetAuthor(branding.CompanyName, true)
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(128, 128, 128)
+		footer := fmt.Sprintf("%s  -  Page %d/{nb}", branding.FooterText, pdf.PageNo())
+		if branding.FooterText == "" {
+			footer = fmt.Sprintf("Page %d/{nb}", pdf.PageNo())
+		}
+		pdf.CellFormat(0, 10, tr(footer), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	// Seller: logo on the left, name and address on the right
+	if branding.LogoPath != "" {
+		pdf.ImageOptions(branding.LogoPath, 15, 15, 0, 18, false, fpdf.ImageOptions{ReadDpi: true}, 0, "")
+	}
+	pdf.SetXY(105, 15)
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(90, 7, tr(branding.CompanyName), "", 2, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, line := range branding.CompanyAddress {
+		pdf.CellFormat(90, 4.5, tr(line), "", 2, "R", false, 0, "")
+	}
+	if branding.CompanyTaxID != "" {
+		pdf.CellFormat(90, 4.5, tr("Tax ID: "+branding.CompanyTaxID), "", 2, "R", false, 0, "")
+	}
+
+	// Title and document details
+	pdf.SetXY(15, 45)
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.SetTextColor(accentR, accentG, accentB)
+	pdf.CellFormat(0, 10, doc.Heading, "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	top := pdf.GetY()
+	pdf.SetTextColor(0, 0, 0)
+	for _, detail := range doc.Details {
+		pdf.SetX(115)
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(30, 5, tr(detail[0]), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(50, 5, tr(detail[1]), "", 1, "R", false, 0, "")
+	}
+	bottom := pdf.GetY()
+
+	// Customer
+	pdf.SetXY(15, top)
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetTextColor(accentR, accentG, accentB)
+	pdf.CellFormat(90, 5, "BILL TO", "", 2, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Helvetica", "", 9)
+	for _, line := range billToLines(doc.Organization) {
+		pdf.CellFormat(90, 4.5, tr(line), "", 2, "L", false, 0, "")
+	}
+	if pdf.GetY() > bottom {
+		bottom = pdf.GetY()
+	}
+	pdf.SetY(bottom + 8)
+
+	// Line items
+	columns := doc.Columns
+	if columns == [4]string{} {
+		columns = lineItemColumns
+	}
+	tableHeader := func() {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(accentR, accentG, accentB)
+		pdf.SetTextColor(255, 255, 255)
+		pdf.CellFormat(pdfColDescription, 7, columns[0], "", 0, "L", true, 0, "")
+		pdf.CellFormat(pdfColQuantity, 7, columns[1], "", 0, "R", true, 0, "")
+		pdf.CellFormat(pdfColUnitPrice, 7, columns[2], "", 0, "R", true, 0, "")
+		pdf.CellFormat(pdfColAmount, 7, columns[3], "", 1, "R", true, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetFont("Helvetica", "", 9)
+	}
+	tableHeader()
+
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottomMargin := pdf.GetMargins()
+
+	for _, row := range doc.Rows {
+		lines := pdf.SplitLines([]byte(tr(row.Description)), pdfColDescription-2)
+		height := pdfRowHeight * float64(len(lines))
+		if pdf.GetY()+height > pageHeight-bottomMargin {
+			pdf.AddPage()
+			tableHeader()
+		}
+
+		x, y := pdf.GetX(), pdf.GetY()
+		pdf.MultiCell(pdfColDescription, pdfRowHeight, tr(row.Description), "B", "L", false)
+		pdf.SetXY(x+pdfColDescription, y)
+		pdf.CellFormat(pdfColQuantity, height, row.Cells[0], "B", 0, "R", false, 0, "")
+		pdf.CellFormat(pdfColUnitPrice, height, row.Cells[1], "B", 0, "R", false, 0, "")
+		pdf.CellFormat(pdfColAmount, height, row.Cells[2], "B", 1, "R", false, 0, "")
+	}
+
+	// Totals
+	pdf.Ln(4)
+	for _, total := range doc.Totals {
+		style := ""
+		if total.Bold {
+			style = "B"
+		}
+		pdf.SetX(105)
+		pdf.SetFont("Helvetica", style, 9)
+		pdf.CellFormat(60, 6, tr(total.Label), "", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 6, formatMoney(total.Amount, doc.Currency), "", 1, "R", false, 0, "")
+	}
+
+	if doc.Note != "" {
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.MultiCell(0, 4.5, tr(doc.Note), "", "L", false)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render %s PDF: %w", strings.ToLower(doc.Heading), err)
+	}
+	return buf.Bytes(), nil
+}
+
+// billToLines returns the name and address lines of an organization
+func billToLines(org *models.Organization) []string {
+	name := org.CompanyName
+	if name == "" {
+		name = org.Name
+	}
+
+	lines := []string{name}
+	for _, line := range []string{org.AddressLine1, org.AddressLine2} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	locality := strings.TrimSpace(strings.Join(nonEmpty(org.PostalCode, org.City, org.State), " "))
+	if locality != "" {
+		lines = append(lines, locality)
+	}
+	if org.Country != "" {
+		lines = append(lines, strings.ToUpper(org.Country))
+	}
+	if org.TaxID != "" {
+		lines = append(lines, "Tax ID: "+org.TaxID)
+	}
+	if org.BillingEmail != "" {
+		lines = append(lines, org.BillingEmail)
+	}
+	return lines
+}
+
+// nonEmpty returns the non-empty values
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// formatMoney formats an amount with its currency code
+func formatMoney(amount decimal.Decimal, currency string) string {
+	return fmt.Sprintf("%s %s", currency, amount.StringFixed(2))
+}
+
+// parseHexColor parses a "#RRGGBB" color
+func parseHexColor(color string) (int, int, int, error) {
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) != 6 {
+		return 0, 0, 0, fmt.Errorf("color must be in #RRGGBB format: %q", color)
+	}
+
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("color must be in #RRGGBB format: %q", color)
+	}
+	return int(value >> 16 & 0xff), int(value >> 8 & 0xff), int(value & 0xff), nil
+}
@@ -0,0 +1,410 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PlanService manages the versions of subscription plans. Versions are
+// edited as drafts and immutable once published; publishing a version makes
+// it the one offered to new subscribers, while existing subscriptions stay on
+// the version they were sold. Price changes move the subscriptions of a
+// version to another version at an effective date announced in advance.
+type PlanService struct {
+	db                  *gorm.DB
+	config              *Config
+	pricingEngine       *PricingEngine
+	notificationService *NotificationService
+}
+
+// NewPlanService creates a new plan service. The notification service is
+// optional.
+func NewPlanService(
+	db *gorm.DB,
+	config *Config,
+	pricingEngine *PricingEngine,
+	notificationService *NotificationService,
+) *PlanService {
+	return &PlanService{
+		db:                  db,
+		config:              config,
+		pricingEngine:       pricingEngine,
+		notificationService: notificationService,
+	}
+}
+
+// planColumns are the columns of a plan version editable while it is a draft
+var planColumns = []string{
+	"name", "description", "base_price", "currency", "billing_interval", "billing_mode", "features",
+	"included_api_calls", "included_storage_gb", "included_data_transfer_gb", "included_seats", "max_adapters",
+	"price_per_api_call", "price_per_gb_storage", "price_per_gb_transfer", "price_per_additional_seat",
+	"is_public",
+}
+
+// GetVersion retrieves a plan version
+func (ps *PlanService) GetVersion(ctx context.Context, planID string) (*models.SubscriptionPlan, error) {
+	var plan models.SubscriptionPlan
+	if err := ps.db.WithContext(ctx).First(&plan, "id = ?", planID).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch plan: %w", err)
+	}
+	return &plan, nil
+}
+
+// CurrentVersion retrieves the version of a plan offered to new subscribers
+func (ps *PlanService) CurrentVersion(ctx context.Context, slug string) (*models.SubscriptionPlan, error) {
+	var plan models.SubscriptionPlan
+	if err := ps.db.WithContext(ctx).
+		Where("slug = ? AND is_active = ?", slug, true).
+		First(&plan).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch current plan version: %w", err)
+	}
+	return &plan, nil
+}
+
+// ListVersions retrieves the versions of a plan, latest first
+func (ps *PlanService) ListVersions(ctx context.Context, slug string) ([]models.SubscriptionPlan, error) {
+	var plans []models.SubscriptionPlan
+	err := ps.db.WithContext(ctx).
+		Where("slug = ?", slug).
+		Order("version DESC").
+		Find(&plans).Error
+	return plans, err
+}
+
+// CreateDraft creates the next version of a plan as a draft, copied from its
+// latest version. A plan has at most one draft.
+func (ps *PlanService) CreateDraft(ctx context.Context, slug string) (*models.SubscriptionPlan, error) {
+	var draft models.SubscriptionPlan
+	err := ps.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest models.SubscriptionPlan
+		if err := tx.Where("slug = ?", slug).
+			Order("version DESC").
+			First(&latest).Error; err != nil {
+			return fmt.Errorf("failed to fetch plan: %w", err)
+		}
+		if latest.VersionStatus == string(PlanVersionDraft) {
+			return fmt.Errorf("plan %s already has a draft version", slug)
+		}
+
+		draft = latest
+		draft.ID = uuid.New()
+		draft.Version = latest.Version + 1
+		draft.VersionStatus = string(PlanVersionDraft)
+		draft.PublishedAt = nil
+		draft.IsActive = false
+		draft.CreatedAt = time.Time{}
+		draft.UpdatedAt = time.Time{}
+
+		// Select all columns so that is_active is not replaced by its default
+		if err := tx.Select("*").Create(&draft).Error; err != nil {
+			return fmt.Errorf("failed to create draft version: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// UpdateDraft saves the pricing, billing mode, limits and description of a
+// draft version
+func (ps *PlanService) UpdateDraft(ctx context.Context, plan *models.SubscriptionPlan) error {
+	switch BillingCycle(plan.BillingInterval) {
+	case BillingCycleMonthly, BillingCycleAnnual:
+	default:
+		return fmt.Errorf("invalid billing interval: %s", plan.BillingInterval)
+	}
+	switch BillingMode(plan.BillingMode) {
+	case BillingModeArrears, BillingModeAdvance:
+	default:
+		return fmt.Errorf("invalid billing mode: %s", plan.BillingMode)
+	}
+	if plan.BasePrice.IsNegative() {
+		return fmt.Errorf("base price must not be negative")
+	}
+
+	result := ps.db.WithContext(ctx).
+		Model(&models.SubscriptionPlan{}).
+		Where("id = ? AND version_status = ?", plan.ID, PlanVersionDraft).
+		Select(planColumns).
+		Updates(plan)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update draft version: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("plan %s is not a draft version", plan.ID)
+	}
+	return nil
+}
+
+// PublishVersion publishes a draft version and makes it the version offered
+// to new subscribers. Subscriptions of earlier versions keep their version.
+func (ps *PlanService) PublishVersion(ctx context.Context, planID string) (*models.SubscriptionPlan, error) {
+	var plan models.SubscriptionPlan
+	err := ps.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND version_status = ?", planID, PlanVersionDraft).
+			First(&plan).Error; err != nil {
+			return fmt.Errorf("failed to fetch draft version: %w", err)
+		}
+
+		if err := tx.Model(&models.SubscriptionPlan{}).
+			Where("slug = ? AND id <> ? AND is_active = ?", plan.Slug, plan.ID, true).
+			Update("is_active", false).Error; err != nil {
+			return fmt.Errorf("failed to deactivate previous version: %w", err)
+		}
+
+		now := time.Now()
+		plan.VersionStatus = string(PlanVersionPublished)
+		plan.PublishedAt = &now
+		plan.IsActive = true
+		if err := tx.Model(&plan).Updates(map[string]interface{}{
+			"version_status": plan.VersionStatus,
+			"published_at":   now,
+			"is_active":      true,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to publish version: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// RetireVersion retires a published version no longer offered, once no
+// subscription is on it or scheduled to move to it
+func (ps *PlanService) RetireVersion(ctx context.Context, planID string) error {
+	return ps.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var plan models.SubscriptionPlan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&plan, "id = ?", planID).Error; err != nil {
+			return fmt.Errorf("failed to fetch plan: %w", err)
+		}
+		if plan.VersionStatus != string(PlanVersionPublished) || plan.IsActive {
+			return fmt.Errorf("only published versions no longer offered can be retired")
+		}
+
+		var subscriptions int64
+		if err := tx.Model(&models.Subscription{}).
+			Where("plan_id = ? AND status <> ?", plan.ID, SubscriptionStatusCanceled).
+			Count(&subscriptions).Error; err != nil {
+			return fmt.Errorf("failed to count subscriptions: %w", err)
+		}
+		var changes int64
+		if err := tx.Model(&models.PlanChange{}).
+			Where("to_plan_id = ? AND status = ?", plan.ID, PlanChangeStatusScheduled).
+			Count(&changes).Error; err != nil {
+			return fmt.Errorf("failed to count scheduled plan changes: %w", err)
+		}
+		if subscriptions > 0 || changes > 0 {
+			return fmt.Errorf("version %d of plan %s still has subscriptions", plan.Version, plan.Slug)
+		}
+
+		if err := tx.Model(&plan).Update("version_status", PlanVersionRetired).Error; err != nil {
+			return fmt.Errorf("failed to retire version: %w", err)
+		}
+		return nil
+	})
+}
+
+// SchedulePriceChange moves the subscriptions of a plan version to another
+// published version of the plan at their first renewal after effectiveAt,
+// which must be at least Plans.PriceChangeNotice away. Subscribers are
+// notified in advance by ProcessPriceChangeNotices. Subscriptions that
+// already have a scheduled plan change keep it.
+func (ps *PlanService) SchedulePriceChange(
+	ctx context.Context,
+	fromPlanID string,
+	toPlanID string,
+	effectiveAt time.Time,
+) (*models.PriceChange, error) {
+	from, err := ps.GetVersion(ctx, fromPlanID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := ps.GetVersion(ctx, toPlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	if from.ID == to.ID || from.Slug != to.Slug {
+		return nil, fmt.Errorf("price changes move subscriptions between versions of a plan")
+	}
+	if to.VersionStatus != string(PlanVersionPublished) {
+		return nil, fmt.Errorf("version %d of plan %s is not published", to.Version, to.Slug)
+	}
+	if from.Currency != to.Currency || from.BillingInterval != to.BillingInterval {
+		return nil, fmt.Errorf("price changes cannot change the currency or billing interval")
+	}
+	if notice := ps.config.Plans.PriceChangeNotice; effectiveAt.Before(time.Now().Add(notice)) {
+		return nil, fmt.Errorf("price changes must be scheduled at least %s in advance", notice)
+	}
+
+	priceChange := &models.PriceChange{
+		ID:          uuid.New(),
+		FromPlanID:  from.ID,
+		ToPlanID:    to.ID,
+		EffectiveAt: effectiveAt,
+		Status:      string(PriceChangeStatusScheduled),
+	}
+
+	err = ps.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(priceChange).Error; err != nil {
+			return fmt.Errorf("failed to create price change: %w", err)
+		}
+
+		var subscriptions []models.Subscription
+		if err := tx.
+			Where("plan_id = ? AND status IN ?", from.ID, []SubscriptionStatus{
+				SubscriptionStatusActive,
+				SubscriptionStatusTrialing,
+				SubscriptionStatusPastDue,
+			}).
+			Where("NOT EXISTS (?)", tx.Model(&models.PlanChange{}).
+				Select("1").
+				Where("dictamesh_billing_plan_changes.subscription_id = dictamesh_billing_subscriptions.id").
+				Where("dictamesh_billing_plan_changes.status = ?", PlanChangeStatusScheduled)).
+			Find(&subscriptions).Error; err != nil {
+			return fmt.Errorf("failed to fetch subscriptions: %w", err)
+		}
+		if len(subscriptions) == 0 {
+			return nil
+		}
+
+		changes := make([]models.PlanChange, 0, len(subscriptions))
+		for _, subscription := range subscriptions {
+			changeType := PlanChangeDowngrade
+			if ps.pricingEngine.SubscriptionPrice(to, subscription.Quantity).
+				GreaterThan(ps.pricingEngine.SubscriptionPrice(from, subscription.Quantity)) {
+				changeType = PlanChangeUpgrade
+			}
+			changes = append(changes, models.PlanChange{
+				ID:              uuid.New(),
+				SubscriptionID:  subscription.ID,
+				OrganizationID:  subscription.OrganizationID,
+				FromPlanID:      from.ID,
+				ToPlanID:        to.ID,
+				ChangeType:      string(changeType),
+				Status:          string(PlanChangeStatusScheduled),
+				EffectiveAt:     effectiveAt,
+				ProrationAmount: decimal.Zero,
+				Currency:        to.Currency,
+				PriceChangeID:   &priceChange.ID,
+			})
+		}
+		if err := tx.CreateInBatches(changes, 500).Error; err != nil {
+			return fmt.Errorf("failed to schedule plan changes: %w", err)
+		}
+
+		priceChange.Subscriptions = len(changes)
+		if err := tx.Model(priceChange).Update("subscriptions", priceChange.Subscriptions).Error; err != nil {
+			return fmt.Errorf("failed to update price change: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	priceChange.FromPlan = *from
+	priceChange.ToPlan = *to
+	return priceChange, nil
+}
+
+// CancelPriceChange cancels a price change and the plan changes it scheduled
+// that were not applied yet
+func (ps *PlanService) CancelPriceChange(ctx context.Context, priceChangeID string) error {
+	return ps.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.PriceChange{}).
+			Where("id = ? AND status = ?", priceChangeID, PriceChangeStatusScheduled).
+			Update("status", PriceChangeStatusCanceled)
+		if result.Error != nil {
+			return fmt.Errorf("failed to cancel price change: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("price change %s is not scheduled", priceChangeID)
+		}
+
+		if err := tx.Model(&models.PlanChange{}).
+			Where("price_change_id = ? AND status = ?", priceChangeID, PlanChangeStatusScheduled).
+			Update("status", PlanChangeStatusCanceled).Error; err != nil {
+			return fmt.Errorf("failed to cancel scheduled plan changes: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListPriceChanges retrieves the price changes of a plan, latest first
+func (ps *PlanService) ListPriceChanges(ctx context.Context, slug string) ([]models.PriceChange, error) {
+	var priceChanges []models.PriceChange
+	err := ps.db.WithContext(ctx).
+		Preload("FromPlan").
+		Preload("ToPlan").
+		Joins("JOIN dictamesh_billing_subscription_plans p ON p.id = dictamesh_billing_price_changes.from_plan_id").
+		Where("p.slug = ?", slug).
+		Order("dictamesh_billing_price_changes.created_at DESC").
+		Find(&priceChanges).Error
+	return priceChanges, err
+}
+
+// ProcessPriceChangeNotices notifies the subscribers of scheduled price
+// changes that were not notified yet
+func (ps *PlanService) ProcessPriceChangeNotices(ctx context.Context) error {
+	if ps.notificationService == nil {
+		return nil
+	}
+
+	var changes []models.PlanChange
+	if err := ps.db.WithContext(ctx).
+		Preload("FromPlan").
+		Preload("ToPlan").
+		Where("status = ? AND price_change_id IS NOT NULL AND notified_at IS NULL", PlanChangeStatusScheduled).
+		Order("effective_at ASC").
+		Find(&changes).Error; err != nil {
+		return fmt.Errorf("failed to fetch unnotified price changes: %w", err)
+	}
+
+	var errs []error
+	for i := range changes {
+		orgCtx := tenant.WithOrganization(ctx, changes[i].OrganizationID.String())
+		if err := ps.notifyPriceChange(orgCtx, &changes[i]); err != nil {
+			errs = append(errs, fmt.Errorf("plan change %s: %w", changes[i].ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notifyPriceChange sends the notice of a scheduled price change with its
+// plans loaded
+func (ps *PlanService) notifyPriceChange(ctx context.Context, change *models.PlanChange) error {
+	var subscription models.Subscription
+	if err := ps.db.WithContext(ctx).First(&subscription, "id = ?", change.SubscriptionID).Error; err != nil {
+		return fmt.Errorf("failed to fetch subscription: %w", err)
+	}
+
+	oldPrice := ps.pricingEngine.SubscriptionPrice(&change.FromPlan, subscription.Quantity)
+	newPrice := ps.pricingEngine.SubscriptionPrice(&change.ToPlan, subscription.Quantity)
+	if err := ps.notificationService.SendPriceChangeNotification(ctx, change, oldPrice, newPrice); err != nil {
+		return fmt.Errorf("failed to send price change notification: %w", err)
+	}
+
+	if err := ps.db.WithContext(ctx).Model(change).Update("notified_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to mark price change notified: %w", err)
+	}
+	return nil
+}
@@ -4,7 +4,9 @@
 package billing
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Click2-Run/dictamesh/pkg/billing/models"
@@ -13,112 +15,190 @@ import (
 
 // PricingEngine handles all pricing calculations
 type PricingEngine struct {
-	config *Config
+	config     *Config
+	taxService *TaxService
 }
 
-// NewPricingEngine creates a new pricing engine
-func NewPricingEngine(config *Config) *PricingEngine {
+// NewPricingEngine creates a new pricing engine. Without a tax service the
+// flat Invoice.TaxRate applies.
+func NewPricingEngine(config *Config, taxService *TaxService) *PricingEngine {
+	if taxService == nil {
+		taxService = NewTaxServiceWithProvider(config, NewFlatTaxProvider(config.Invoice.TaxRate))
+	}
 	return &PricingEngine{
-		config: config,
+		config:     config,
+		taxService: taxService,
 	}
 }
 
-// CalculateSubscriptionCharge calculates the charge for a subscription period
+// CalculateSubscriptionCharge calculates the charge for a subscription period.
+// Seats are the seat counts over the period; seats are charged per segment,
+// prorated by its share of the period. Without seats the subscription quantity
+// applies to the whole period. Redemptions are the coupons applied to the
+// subscription, with Coupon loaded. Taxes are based on
+// subscription.Organization, which must be loaded.
 func (pe *PricingEngine) CalculateSubscriptionCharge(
+	ctx context.Context,
 	subscription *models.Subscription,
 	plan *models.SubscriptionPlan,
+	seats []SeatSegment,
 	usage *UsageAggregation,
 	credits []models.Credit,
+	redemptions []models.CouponRedemption,
 ) (*ChargeCalculation, error) {
-	calc := &ChargeCalculation{
-		UsageCharges: make(map[MetricType]decimal.Decimal),
-		LineItems:    []InvoiceLineItem{},
+	calc := newChargeCalculation()
+
+	if len(seats) == 0 {
+		seats = []SeatSegment{{
+			Quantity: subscription.Quantity,
+			Start:    subscription.CurrentPeriodStart,
+			End:      subscription.CurrentPeriodEnd,
+		}}
 	}
 
 	// 1. Base subscription charge
-	baseCharge := plan.BasePrice.Mul(decimal.NewFromInt(int64(subscription.Quantity)))
-	calc.BaseCharge = baseCharge
-	calc.LineItems = append(calc.LineItems, InvoiceLineItem{
-		Description: fmt.Sprintf("%s Plan (%s)", plan.Name, subscription.CurrentPeriodStart.Format("Jan 2006")),
-		Quantity:    decimal.NewFromInt(int64(subscription.Quantity)),
-		UnitPrice:   plan.BasePrice,
-		Amount:      baseCharge,
-		ItemType:    LineItemTypeSubscriptionBase,
-		PeriodStart: &subscription.CurrentPeriodStart,
-		PeriodEnd:   &subscription.CurrentPeriodEnd,
-	})
+	for _, segment := range seats {
+		pe.addSeatCharges(calc, plan, segment, subscription.CurrentPeriodStart, subscription.CurrentPeriodEnd)
+	}
 
 	// 2. Usage-based charges
-	if usage != nil && pe.config.Features.EnableUsageMetrics {
-		// API Calls
-		if apiCallsCharge, lineItem := pe.calculateUsageCharge(
-			MetricTypeAPICalls,
-			usage.Metrics[MetricTypeAPICalls],
-			decimal.NewFromInt(int64(plan.IncludedAPICalls)),
-			plan.PricePerAPICall,
-			"API Call",
-			subscription.CurrentPeriodStart,
-			subscription.CurrentPeriodEnd,
-		); apiCallsCharge.GreaterThan(decimal.Zero) {
-			calc.UsageCharges[MetricTypeAPICalls] = apiCallsCharge
-			calc.LineItems = append(calc.LineItems, lineItem)
-		}
+	pe.addUsageCharges(calc, subscription, plan, usage)
 
-		// Storage
-		if storageCharge, lineItem := pe.calculateUsageCharge(
-			MetricTypeStorageGB,
-			usage.Metrics[MetricTypeStorageGB],
-			decimal.NewFromInt(int64(plan.IncludedStorageGB)),
-			plan.PricePerGBStorage,
-			"GB Storage",
-			subscription.CurrentPeriodStart,
-			subscription.CurrentPeriodEnd,
-		); storageCharge.GreaterThan(decimal.Zero) {
-			calc.UsageCharges[MetricTypeStorageGB] = storageCharge
-			calc.LineItems = append(calc.LineItems, lineItem)
-		}
+	return pe.completeCharge(ctx, calc, subscription, plan, credits, redemptions)
+}
 
-		// Data Transfer
-		totalTransfer := usage.Metrics[MetricTypeTransferGBIn].Add(usage.Metrics[MetricTypeTransferGBOut])
-		if transferCharge, lineItem := pe.calculateUsageCharge(
-			MetricTypeTransferGBOut,
-			totalTransfer,
-			decimal.NewFromInt(int64(plan.IncludedDataTransferGB)),
-			plan.PricePerGBTransfer,
-			"GB Data Transfer",
-			subscription.CurrentPeriodStart,
-			subscription.CurrentPeriodEnd,
-		); transferCharge.GreaterThan(decimal.Zero) {
-			calc.UsageCharges[MetricTypeTransferGBOut] = transferCharge
-			calc.LineItems = append(calc.LineItems, lineItem)
-		}
+// CalculateAdvanceCharge calculates the base fee of the current period of a
+// subscription billed in advance, for its current seat count. Periods shorter
+// than the billing interval, like the first period of a subscription aligned
+// to the calendar month, are prorated.
+func (pe *PricingEngine) CalculateAdvanceCharge(
+	ctx context.Context,
+	subscription *models.Subscription,
+	plan *models.SubscriptionPlan,
+	credits []models.Credit,
+	redemptions []models.CouponRedemption,
+) (*ChargeCalculation, error) {
+	calc := newChargeCalculation()
+
+	start := subscription.CurrentPeriodStart
+	pe.addSeatCharges(calc, plan, SeatSegment{
+		Quantity: subscription.Quantity,
+		Start:    start,
+		End:      subscription.CurrentPeriodEnd,
+	}, start, nextPeriodEnd(start, plan.BillingInterval))
+
+	return pe.completeCharge(ctx, calc, subscription, plan, credits, redemptions)
+}
+
+// CalculateUsageCharge calculates the usage charges of the current period of
+// a subscription whose base fee was billed in advance. Coupons discount the
+// advance charge only.
+func (pe *PricingEngine) CalculateUsageCharge(
+	ctx context.Context,
+	subscription *models.Subscription,
+	plan *models.SubscriptionPlan,
+	usage *UsageAggregation,
+	credits []models.Credit,
+) (*ChargeCalculation, error) {
+	calc := newChargeCalculation()
+	pe.addUsageCharges(calc, subscription, plan, usage)
+	return pe.completeCharge(ctx, calc, subscription, plan, credits, nil)
+}
+
+// newChargeCalculation returns an empty charge calculation
+func newChargeCalculation() *ChargeCalculation {
+	return &ChargeCalculation{
+		UsageCharges: make(map[MetricType]decimal.Decimal),
+		LineItems:    []InvoiceLineItem{},
 	}
+}
 
-	// 3. Add-on charges (additional seats)
-	if subscription.Quantity > plan.IncludedSeats {
-		additionalSeats := subscription.Quantity - plan.IncludedSeats
-		addonCharge := plan.PricePerAdditionalSeat.Mul(decimal.NewFromInt(int64(additionalSeats)))
-		calc.AddonCharges = calc.AddonCharges.Add(addonCharge)
-		calc.LineItems = append(calc.LineItems, InvoiceLineItem{
-			Description: fmt.Sprintf("Additional Seats (%d)", additionalSeats),
-			Quantity:    decimal.NewFromInt(int64(additionalSeats)),
-			UnitPrice:   plan.PricePerAdditionalSeat,
-			Amount:      addonCharge,
-			ItemType:    LineItemTypeAddonSeats,
-			PeriodStart: &subscription.CurrentPeriodStart,
-			PeriodEnd:   &subscription.CurrentPeriodEnd,
-		})
+// addUsageCharges adds the overage charges of the usage of the current period
+// of a subscription
+func (pe *PricingEngine) addUsageCharges(
+	calc *ChargeCalculation,
+	subscription *models.Subscription,
+	plan *models.SubscriptionPlan,
+	usage *UsageAggregation,
+) {
+	if usage == nil || !pe.config.Features.EnableUsageMetrics {
+		return
 	}
 
-	// 4. Calculate subtotal
+	// API Calls
+	if apiCallsCharge, lineItem := pe.calculateUsageCharge(
+		MetricTypeAPICalls,
+		usage.Metrics[MetricTypeAPICalls],
+		decimal.NewFromInt(int64(plan.IncludedAPICalls)),
+		plan.PricePerAPICall,
+		"API Call",
+		subscription.CurrentPeriodStart,
+		subscription.CurrentPeriodEnd,
+	); apiCallsCharge.GreaterThan(decimal.Zero) {
+		calc.UsageCharges[MetricTypeAPICalls] = apiCallsCharge
+		calc.LineItems = append(calc.LineItems, lineItem)
+	}
+
+	// Storage
+	if storageCharge, lineItem := pe.calculateUsageCharge(
+		MetricTypeStorageGB,
+		usage.Metrics[MetricTypeStorageGB],
+		decimal.NewFromInt(int64(plan.IncludedStorageGB)),
+		plan.PricePerGBStorage,
+		"GB Storage",
+		subscription.CurrentPeriodStart,
+		subscription.CurrentPeriodEnd,
+	); storageCharge.GreaterThan(decimal.Zero) {
+		calc.UsageCharges[MetricTypeStorageGB] = storageCharge
+		calc.LineItems = append(calc.LineItems, lineItem)
+	}
+
+	// Data Transfer
+	totalTransfer := usage.Metrics[MetricTypeTransferGBIn].Add(usage.Metrics[MetricTypeTransferGBOut])
+	if transferCharge, lineItem := pe.calculateUsageCharge(
+		MetricTypeTransferGBOut,
+		totalTransfer,
+		decimal.NewFromInt(int64(plan.IncludedDataTransferGB)),
+		plan.PricePerGBTransfer,
+		"GB Data Transfer",
+		subscription.CurrentPeriodStart,
+		subscription.CurrentPeriodEnd,
+	); transferCharge.GreaterThan(decimal.Zero) {
+		calc.UsageCharges[MetricTypeTransferGBOut] = transferCharge
+		calc.LineItems = append(calc.LineItems, lineItem)
+	}
+}
+
+// completeCharge applies coupon discounts, credits and tax to the charges of
+// a calculation and totals it
+func (pe *PricingEngine) completeCharge(
+	ctx context.Context,
+	calc *ChargeCalculation,
+	subscription *models.Subscription,
+	plan *models.SubscriptionPlan,
+	credits []models.Credit,
+	redemptions []models.CouponRedemption,
+) (*ChargeCalculation, error) {
+	// 3. Calculate subtotal
 	calc.Subtotal = calc.BaseCharge.Add(calc.AddonCharges)
 	for _, charge := range calc.UsageCharges {
 		calc.Subtotal = calc.Subtotal.Add(charge)
 	}
 
+	// 4. Apply coupon discounts
+	for i := range redemptions {
+		remaining := calc.Subtotal.Sub(calc.Discount)
+		discount, lineItem, ok := pe.calculateDiscount(&redemptions[i], plan.Currency, remaining, subscription.CurrentPeriodStart)
+		if !ok {
+			continue
+		}
+		calc.Discount = calc.Discount.Add(discount)
+		calc.LineItems = append(calc.LineItems, lineItem)
+	}
+
 	// 5. Apply credits
 	if pe.config.Features.EnableCredits {
-		creditAmount := pe.applyCredits(credits, calc.Subtotal)
+		creditAmount := pe.applyCredits(credits, calc.Subtotal.Sub(calc.Discount))
 		if creditAmount.GreaterThan(decimal.Zero) {
 			calc.Credits = creditAmount
 			calc.LineItems = append(calc.LineItems, InvoiceLineItem{
@@ -132,26 +212,86 @@ func (pe *PricingEngine) CalculateSubscriptionCharge(
 	}
 
 	// 6. Calculate tax
-	taxableAmount := calc.Subtotal.Sub(calc.Credits)
+	taxableAmount := calc.Subtotal.Sub(calc.Discount).Sub(calc.Credits)
 	if taxableAmount.GreaterThan(decimal.Zero) {
-		calc.TaxAmount = taxableAmount.Mul(pe.config.Invoice.TaxRate)
-		if calc.TaxAmount.GreaterThan(decimal.Zero) {
-			calc.LineItems = append(calc.LineItems, InvoiceLineItem{
-				Description: fmt.Sprintf("Tax (%s%%)", pe.config.Invoice.TaxRate.Mul(decimal.NewFromInt(100)).String()),
-				Quantity:    decimal.NewFromInt(1),
-				UnitPrice:   calc.TaxAmount,
-				Amount:      calc.TaxAmount,
-				ItemType:    LineItemTypeTax,
-			})
+		tax, err := pe.taxService.Calculate(ctx, &subscription.Organization, plan.Currency, taxableAmount)
+		if err != nil {
+			return nil, err
 		}
+		calc.TaxAmount = tax.Total
+		calc.LineItems = append(calc.LineItems, tax.LineItems()...)
 	}
 
 	// 7. Calculate total
-	calc.Total = calc.Subtotal.Sub(calc.Credits).Add(calc.TaxAmount)
+	calc.Total = calc.Subtotal.Sub(calc.Discount).Sub(calc.Credits).Add(calc.TaxAmount)
 
 	return calc, nil
 }
 
+// addSeatCharges adds the base and additional seat charges of a seat segment
+// of the period [periodStart, periodEnd). Segments shorter than the period are
+// prorated: the unit prices are reduced to the share of the period the
+// segment covers.
+func (pe *PricingEngine) addSeatCharges(
+	calc *ChargeCalculation,
+	plan *models.SubscriptionPlan,
+	segment SeatSegment,
+	periodStart, periodEnd time.Time,
+) {
+	basePrice := plan.BasePrice
+	seatPrice := plan.PricePerAdditionalSeat
+	baseDescription := fmt.Sprintf("%s Plan (%s)", plan.Name, periodStart.Format("Jan 2006"))
+	seatDescription := "Additional Seats (%d)"
+	var metadata map[string]interface{}
+
+	prorated := segment.Start.After(periodStart) || segment.End.Before(periodEnd)
+	if prorated {
+		ratio := decimal.Zero
+		if total := periodEnd.Sub(periodStart).Seconds(); total > 0 {
+			ratio = decimal.NewFromFloat(segment.End.Sub(segment.Start).Seconds() / total)
+		}
+		basePrice = basePrice.Mul(ratio).Round(6)
+		seatPrice = seatPrice.Mul(ratio).Round(6)
+
+		dates := fmt.Sprintf("%s - %s", segment.Start.Format("Jan 2, 2006"), segment.End.Format("Jan 2, 2006"))
+		baseDescription = fmt.Sprintf("%s Plan, %d seats (%s)", plan.Name, segment.Quantity, dates)
+		seatDescription = "Additional Seats (%d, " + dates + ")"
+		metadata = map[string]interface{}{"proration_ratio": ratio.StringFixed(6)}
+	}
+
+	start, end := segment.Start, segment.End
+	quantity := decimal.NewFromInt(int64(segment.Quantity))
+	baseCharge := basePrice.Mul(quantity).Round(2)
+	calc.BaseCharge = calc.BaseCharge.Add(baseCharge)
+	calc.LineItems = append(calc.LineItems, InvoiceLineItem{
+		Description: baseDescription,
+		Quantity:    quantity,
+		UnitPrice:   basePrice,
+		Amount:      baseCharge,
+		ItemType:    LineItemTypeSubscriptionBase,
+		PeriodStart: &start,
+		PeriodEnd:   &end,
+		Metadata:    metadata,
+	})
+
+	// Add-on charges (additional seats)
+	if segment.Quantity > plan.IncludedSeats {
+		additionalSeats := segment.Quantity - plan.IncludedSeats
+		addonCharge := seatPrice.Mul(decimal.NewFromInt(int64(additionalSeats))).Round(2)
+		calc.AddonCharges = calc.AddonCharges.Add(addonCharge)
+		calc.LineItems = append(calc.LineItems, InvoiceLineItem{
+			Description: fmt.Sprintf(seatDescription, additionalSeats),
+			Quantity:    decimal.NewFromInt(int64(additionalSeats)),
+			UnitPrice:   seatPrice,
+			Amount:      addonCharge,
+			ItemType:    LineItemTypeAddonSeats,
+			PeriodStart: &start,
+			PeriodEnd:   &end,
+			Metadata:    metadata,
+		})
+	}
+}
+
 // calculateUsageCharge calculates the charge for a single usage metric
 func (pe *PricingEngine) calculateUsageCharge(
 	metricType MetricType,
@@ -202,6 +342,65 @@ func (pe *PricingEngine) calculateUsageCharge(
 	return charge, lineItem
 }
 
+// calculateDiscount calculates the discount of a coupon redemption for a
+// period starting at periodStart. It reports false when the redemption does
+// not apply.
+func (pe *PricingEngine) calculateDiscount(
+	redemption *models.CouponRedemption,
+	currency string,
+	amount decimal.Decimal,
+	periodStart time.Time,
+) (decimal.Decimal, InvoiceLineItem, bool) {
+	coupon := &redemption.Coupon
+
+	if redemption.Status != string(RedemptionStatusActive) || amount.LessThanOrEqual(decimal.Zero) {
+		return decimal.Zero, InvoiceLineItem{}, false
+	}
+
+	switch CouponDuration(coupon.Duration) {
+	case CouponDurationOnce:
+		if redemption.PeriodsApplied > 0 {
+			return decimal.Zero, InvoiceLineItem{}, false
+		}
+	case CouponDurationRepeating:
+		if redemption.EndsAt != nil && !periodStart.Before(*redemption.EndsAt) {
+			return decimal.Zero, InvoiceLineItem{}, false
+		}
+	}
+
+	var discount decimal.Decimal
+	var description string
+	switch CouponDiscountType(coupon.DiscountType) {
+	case CouponDiscountPercentage:
+		discount = amount.Mul(coupon.PercentOff).Div(decimal.NewFromInt(100)).Round(2)
+		description = fmt.Sprintf("Discount: %s (%s%% off)", coupon.Name, coupon.PercentOff.String())
+	case CouponDiscountFixed:
+		if !strings.EqualFold(coupon.Currency, currency) {
+			return decimal.Zero, InvoiceLineItem{}, false
+		}
+		discount = decimal.Min(coupon.AmountOff, amount)
+		description = fmt.Sprintf("Discount: %s (%s %s off)", coupon.Name, coupon.Currency, coupon.AmountOff.StringFixed(2))
+	default:
+		return decimal.Zero, InvoiceLineItem{}, false
+	}
+
+	if discount.LessThanOrEqual(decimal.Zero) {
+		return decimal.Zero, InvoiceLineItem{}, false
+	}
+
+	return discount, InvoiceLineItem{
+		Description: description,
+		Quantity:    decimal.NewFromInt(1),
+		UnitPrice:   discount.Neg(),
+		Amount:      discount.Neg(),
+		ItemType:    LineItemTypeDiscount,
+		Metadata: map[string]interface{}{
+			"coupon_id":     coupon.ID.String(),
+			"redemption_id": redemption.ID.String(),
+		},
+	}, true
+}
+
 // CalculateTieredPrice calculates price using volume-based tiers
 func (pe *PricingEngine) CalculateTieredPrice(
 	usage decimal.Decimal,
@@ -295,6 +494,59 @@ func (pe *PricingEngine) CalculateProration(
 	return proration.Round(2)
 }
 
+// SubscriptionPrice returns the recurring price of a plan for a number of
+// seats, including additional seats
+func (pe *PricingEngine) SubscriptionPrice(plan *models.SubscriptionPlan, quantity int) decimal.Decimal {
+	price := plan.BasePrice.Mul(decimal.NewFromInt(int64(quantity)))
+	if quantity > plan.IncludedSeats {
+		additionalSeats := decimal.NewFromInt(int64(quantity - plan.IncludedSeats))
+		price = price.Add(plan.PricePerAdditionalSeat.Mul(additionalSeats))
+	}
+	return price
+}
+
+// CalculateProrationCharge calculates the charge of a prorated amount owed for
+// an immediate upgrade, including tax. Taxes are based on
+// subscription.Organization, which must be loaded.
+func (pe *PricingEngine) CalculateProrationCharge(
+	ctx context.Context,
+	subscription *models.Subscription,
+	fromPlan, toPlan *models.SubscriptionPlan,
+	amount decimal.Decimal,
+	changeDate time.Time,
+) (*ChargeCalculation, error) {
+	periodEnd := subscription.CurrentPeriodEnd
+	calc := &ChargeCalculation{
+		UsageCharges: make(map[MetricType]decimal.Decimal),
+		BaseCharge:   amount,
+		Subtotal:     amount,
+		LineItems: []InvoiceLineItem{{
+			Description: fmt.Sprintf("Proration: %s to %s (%s - %s)",
+				fromPlan.Name, toPlan.Name, changeDate.Format("Jan 2, 2006"), periodEnd.Format("Jan 2, 2006")),
+			Quantity:    decimal.NewFromInt(1),
+			UnitPrice:   amount,
+			Amount:      amount,
+			ItemType:    LineItemTypeProration,
+			PeriodStart: &changeDate,
+			PeriodEnd:   &periodEnd,
+			Metadata: map[string]interface{}{
+				"from_plan_id": fromPlan.ID.String(),
+				"to_plan_id":   toPlan.ID.String(),
+			},
+		}},
+	}
+
+	tax, err := pe.taxService.Calculate(ctx, &subscription.Organization, toPlan.Currency, amount)
+	if err != nil {
+		return nil, err
+	}
+	calc.TaxAmount = tax.Total
+	calc.LineItems = append(calc.LineItems, tax.LineItems()...)
+	calc.Total = calc.Subtotal.Add(calc.TaxAmount)
+
+	return calc, nil
+}
+
 // EstimateMonthlyCharge estimates the monthly charge for a subscription
 func (pe *PricingEngine) EstimateMonthlyCharge(
 	plan *models.SubscriptionPlan,
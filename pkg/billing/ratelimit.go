@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/gateway/ratelimit"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+// NewPlanRateLimits creates a resolver of API rate limits from the
+// rate_limit_rps and rate_limit_burst features of the plan of each
+// organization's current subscription. Subscriptions are looked up by
+// organization, not by the tier the tenant claims, and cached for ttl.
+// Plans without these features fall back to the default per-tier limits.
+func NewPlanRateLimits(db *gorm.DB, ttl time.Duration) *ratelimit.OrganizationPlans {
+	lookup := func(ctx context.Context, organizationID string) (*ratelimit.Plan, error) {
+		var subscription models.Subscription
+		err := db.WithContext(ctx).
+			Preload("Plan").
+			Where("organization_id = ? AND status IN ?", organizationID, []SubscriptionStatus{
+				SubscriptionStatusActive,
+				SubscriptionStatusTrialing,
+				SubscriptionStatusPastDue,
+			}).
+			Order("created_at DESC").
+			First(&subscription).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch subscription: %w", err)
+		}
+
+		return &ratelimit.Plan{
+			Tier:     tenant.Tier(subscription.Plan.Slug),
+			Features: subscription.Plan.Features,
+		}, nil
+	}
+
+	return ratelimit.NewOrganizationPlans(lookup, ratelimit.DefaultPlans(), ttl)
+}
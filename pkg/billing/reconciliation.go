@@ -0,0 +1,602 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// settledPaymentStatuses are the payment statuses of collected funds
+var settledPaymentStatuses = []string{
+	string(PaymentStatusSucceeded),
+	string(PaymentStatusRefunded),
+	string(PaymentStatusPartiallyRefunded),
+}
+
+// ReconciliationService reconciles the payouts of payment providers against
+// the internal payments and refunds, and produces the account statements of
+// organizations.
+//
+// Each balance transaction of a payout is matched by its provider payment or
+// refund ID. Transactions without an internal record, with a different
+// amount or currency, or matching a record that did not succeed are flagged
+// for review, as are payouts whose transactions do not add up to the payout
+// amount.
+type ReconciliationService struct {
+	db             *gorm.DB
+	config         *Config
+	paymentService *PaymentService
+	pdfService     *PDFService
+}
+
+// NewReconciliationService creates a new reconciliation service. Without a
+// PDF service statements are not rendered to PDF.
+func NewReconciliationService(
+	db *gorm.DB,
+	config *Config,
+	paymentService *PaymentService,
+	pdfService *PDFService,
+) *ReconciliationService {
+	return &ReconciliationService{
+		db:             db,
+		config:         config,
+		paymentService: paymentService,
+		pdfService:     pdfService,
+	}
+}
+
+// ReconcilePayouts reconciles the payouts arriving within
+// Reconciliation.Lookback of every provider reporting payouts. Reconciled
+// payouts are skipped; mismatched payouts are reconciled again, so records
+// corrected since are no longer flagged.
+func (rs *ReconciliationService) ReconcilePayouts(ctx context.Context) error {
+	end := time.Now()
+	start := end.Add(-rs.config.Reconciliation.Lookback)
+
+	for name, provider := range rs.paymentService.providers {
+		reporter, ok := provider.(PayoutReporter)
+		if !ok {
+			continue
+		}
+
+		payouts, err := reporter.ListPayouts(ctx, start, end)
+		if err != nil {
+			return fmt.Errorf("failed to list %s payouts: %w", name, err)
+		}
+		for i := range payouts {
+			if _, err := rs.ReconcilePayout(ctx, name, &payouts[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ReconcilePayout matches the transactions of a payout against the internal
+// payments and refunds and records the outcome
+func (rs *ReconciliationService) ReconcilePayout(
+	ctx context.Context,
+	provider PaymentProviderName,
+	payout *ProviderPayout,
+) (*models.Payout, error) {
+	var existing models.Payout
+	err := rs.db.WithContext(ctx).
+		Where("provider = ? AND provider_payout_id = ?", provider, payout.ID).
+		First(&existing).Error
+	if err == nil && existing.Status == string(PayoutStatusReconciled) {
+		return &existing, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to fetch payout: %w", err)
+	}
+
+	record := &models.Payout{
+		ID:               uuid.New(),
+		Provider:         string(provider),
+		ProviderPayoutID: payout.ID,
+		Amount:           payout.Amount,
+		Currency:         payout.Currency,
+		ArrivalDate:      payout.ArrivalDate,
+		Status:           string(PayoutStatusReconciled),
+		NetAmount:        decimal.Zero,
+		FeeAmount:        decimal.Zero,
+		ReconciledAt:     time.Now(),
+	}
+	if existing.ID != uuid.Nil {
+		record.ID = existing.ID
+		record.CreatedAt = existing.CreatedAt
+	}
+
+	items := make([]models.ReconciliationItem, 0, len(payout.Transactions))
+	for _, transaction := range payout.Transactions {
+		item, err := rs.matchTransaction(ctx, provider, transaction)
+		if err != nil {
+			return nil, err
+		}
+		item.PayoutID = record.ID
+		items = append(items, *item)
+
+		record.NetAmount = record.NetAmount.Add(transaction.Net)
+		record.FeeAmount = record.FeeAmount.Add(transaction.Fee)
+		if item.Status == string(ReconciliationItemMatched) {
+			record.MatchedItems++
+		} else {
+			record.FlaggedItems++
+		}
+	}
+	if record.FlaggedItems > 0 || !record.NetAmount.Equal(record.Amount) {
+		record.Status = string(PayoutStatusMismatched)
+	}
+
+	err = rs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if existing.ID != uuid.Nil {
+			if err := tx.Where("payout_id = ?", record.ID).
+				Delete(&models.ReconciliationItem{}).Error; err != nil {
+				return fmt.Errorf("failed to delete reconciliation items: %w", err)
+			}
+			if err := tx.Omit("Items").Save(record).Error; err != nil {
+				return fmt.Errorf("failed to update payout: %w", err)
+			}
+		} else if err := tx.Omit("Items").Create(record).Error; err != nil {
+			return fmt.Errorf("failed to create payout: %w", err)
+		}
+		if len(items) > 0 {
+			if err := tx.CreateInBatches(items, 100).Error; err != nil {
+				return fmt.Errorf("failed to create reconciliation items: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	record.Items = items
+	return record, nil
+}
+
+// matchTransaction matches a balance transaction against the internal
+// payment or refund it settles
+func (rs *ReconciliationService) matchTransaction(
+	ctx context.Context,
+	provider PaymentProviderName,
+	transaction ProviderTransaction,
+) (*models.ReconciliationItem, error) {
+	item := &models.ReconciliationItem{
+		ID:                    uuid.New(),
+		ProviderTransactionID: transaction.ID,
+		Type:                  transaction.Type,
+		Amount:                transaction.Amount,
+		Fee:                   transaction.Fee,
+		Net:                   transaction.Net,
+		Currency:              transaction.Currency,
+		Status:                string(ReconciliationItemMatched),
+	}
+
+	switch transaction.Type {
+	case ProviderTransactionCharge:
+		var payment models.Payment
+		err := gorm.ErrRecordNotFound
+		if transaction.ProviderPaymentID != "" {
+			err = rs.db.WithContext(ctx).
+				Where("provider = ? AND provider_payment_id = ?", provider, transaction.ProviderPaymentID).
+				First(&payment).Error
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			item.Status = string(ReconciliationItemUnmatched)
+			item.Detail = fmt.Sprintf("no payment with provider ID %q", transaction.ProviderPaymentID)
+			return item, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch payment: %w", err)
+		}
+
+		item.PaymentID = &payment.ID
+		item.OrganizationID = &payment.OrganizationID
+		checkSettlement(item, payment.Status, settledPaymentStatuses, payment.Amount, payment.Currency)
+
+	case ProviderTransactionRefund:
+		var refund models.Refund
+		err := gorm.ErrRecordNotFound
+		if transaction.ProviderRefundID != "" {
+			err = rs.db.WithContext(ctx).
+				Where("provider = ? AND provider_refund_id = ?", provider, transaction.ProviderRefundID).
+				First(&refund).Error
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			item.Status = string(ReconciliationItemUnmatched)
+			item.Detail = fmt.Sprintf("no refund with provider ID %q", transaction.ProviderRefundID)
+			return item, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch refund: %w", err)
+		}
+
+		item.PaymentID = &refund.PaymentID
+		item.RefundID = &refund.ID
+		item.OrganizationID = &refund.OrganizationID
+		// Refunds are debited from the balance
+		checkSettlement(item, refund.Status, []string{string(RefundStatusSucceeded)}, refund.Amount.Neg(), refund.Currency)
+
+	case ProviderTransactionFee:
+		// Provider fees have no internal record
+
+	default:
+		item.Status = string(ReconciliationItemUnmatched)
+		item.Detail = "adjustment requires review"
+	}
+
+	return item, nil
+}
+
+// checkSettlement flags a matched item whose internal record has not
+// succeeded or was recorded with a different amount or currency
+func checkSettlement(
+	item *models.ReconciliationItem,
+	status string,
+	settled []string,
+	amount decimal.Decimal,
+	currency string,
+) {
+	succeeded := false
+	for _, s := range settled {
+		if status == s {
+			succeeded = true
+		}
+	}
+
+	switch {
+	case !succeeded:
+		item.Status = string(ReconciliationItemStatusMismatch)
+		item.Detail = fmt.Sprintf("internal record is %s", status)
+	case !strings.EqualFold(currency, item.Currency):
+		item.Status = string(ReconciliationItemAmountMismatch)
+		item.Detail = fmt.Sprintf("recorded in %s, settled in %s", strings.ToUpper(currency), item.Currency)
+	case !amount.Equal(item.Amount):
+		item.Status = string(ReconciliationItemAmountMismatch)
+		item.Detail = fmt.Sprintf("recorded %s, settled %s", amount.StringFixed(2), item.Amount.StringFixed(2))
+	}
+}
+
+// ListMismatchedPayouts returns the payouts needing review with their
+// flagged transactions, most recent first
+func (rs *ReconciliationService) ListMismatchedPayouts(ctx context.Context, limit, offset int) ([]models.Payout, error) {
+	var payouts []models.Payout
+	if err := rs.db.WithContext(ctx).
+		Preload("Items", "status <> ?", ReconciliationItemMatched).
+		Where("status = ?", PayoutStatusMismatched).
+		Order("arrival_date DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&payouts).Error; err != nil {
+		return nil, fmt.Errorf("failed to list mismatched payouts: %w", err)
+	}
+	return payouts, nil
+}
+
+// UnsettledPayments returns the payments collected by providers reporting
+// payouts that were not paid out within Reconciliation.SettlementDelay.
+// Payments are checked for Reconciliation.Lookback after the delay, so
+// payments collected before reconciliation was enabled are not reported
+// forever.
+func (rs *ReconciliationService) UnsettledPayments(ctx context.Context) ([]models.Payment, error) {
+	var providers []string
+	for name, provider := range rs.paymentService.providers {
+		if _, ok := provider.(PayoutReporter); ok {
+			providers = append(providers, string(name))
+		}
+	}
+	if len(providers) == 0 {
+		return nil, nil
+	}
+
+	cutoff := time.Now().Add(-rs.config.Reconciliation.SettlementDelay)
+	var payments []models.Payment
+	if err := rs.db.WithContext(ctx).
+		Where("provider IN ? AND status IN ?", providers, settledPaymentStatuses).
+		Where("succeeded_at >= ? AND succeeded_at < ?", cutoff.Add(-rs.config.Reconciliation.Lookback), cutoff).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM dictamesh_billing_reconciliation_items i
+			WHERE i.payment_id = dictamesh_billing_payments.id AND i.type = ?
+		)`, ProviderTransactionCharge).
+		Order("succeeded_at ASC").
+		Find(&payments).Error; err != nil {
+		return nil, fmt.Errorf("failed to list unsettled payments: %w", err)
+	}
+	return payments, nil
+}
+
+// GetStatement returns the account statement of the organization of the
+// tenant in ctx for [start, end), in the currency of the organization
+func (rs *ReconciliationService) GetStatement(ctx context.Context, start, end time.Time) (*AccountStatement, error) {
+	statement, _, err := rs.statement(ctx, start, end)
+	return statement, err
+}
+
+// GetStatementCSV returns the account statement of the tenant in ctx as CSV
+func (rs *ReconciliationService) GetStatementCSV(ctx context.Context, start, end time.Time) ([]byte, error) {
+	statement, _, err := rs.statement(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return StatementCSV(statement)
+}
+
+// GetStatementPDF returns the account statement of the tenant in ctx as PDF
+func (rs *ReconciliationService) GetStatementPDF(ctx context.Context, start, end time.Time) ([]byte, error) {
+	if rs.pdfService == nil {
+		return nil, fmt.Errorf("PDF statements are not enabled")
+	}
+
+	statement, org, err := rs.statement(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return rs.pdfService.RenderStatement(statement, org)
+}
+
+// statement builds the account statement of the tenant in ctx. Every entry
+// up to end is loaded; the entries before start make the opening balance.
+func (rs *ReconciliationService) statement(
+	ctx context.Context,
+	start, end time.Time,
+) (*AccountStatement, *models.Organization, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !end.After(start) {
+		return nil, nil, fmt.Errorf("statement end must be after start")
+	}
+
+	var org models.Organization
+	if err := rs.db.WithContext(ctx).First(&org, "id = ?", organizationID).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch organization: %w", err)
+	}
+
+	entries, err := rs.statementEntries(ctx, &org, end)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	statement := &AccountStatement{
+		OrganizationID: org.ID.String(),
+		Currency:       org.Currency,
+		PeriodStart:    start,
+		PeriodEnd:      end,
+		OpeningBalance: decimal.Zero,
+		TotalDebits:    decimal.Zero,
+		TotalCredits:   decimal.Zero,
+		Entries:        []StatementEntry{},
+		GeneratedAt:    time.Now(),
+	}
+
+	balance := decimal.Zero
+	for _, entry := range entries {
+		balance = balance.Add(entry.Debit).Sub(entry.Credit)
+		if entry.Date.Before(start) {
+			statement.OpeningBalance = balance
+			continue
+		}
+
+		entry.Balance = balance
+		statement.TotalDebits = statement.TotalDebits.Add(entry.Debit)
+		statement.TotalCredits = statement.TotalCredits.Add(entry.Credit)
+		statement.Entries = append(statement.Entries, entry)
+	}
+	statement.ClosingBalance = balance
+
+	return statement, &org, nil
+}
+
+// statementEntries loads the transactions of an organization in its currency
+// before end, in date order.
+//
+// Invoices are debited before credits were applied; credit notes issued to
+// the account are entered once, as credit notes rather than as the account
+// credit they grant.
+func (rs *ReconciliationService) statementEntries(
+	ctx context.Context,
+	org *models.Organization,
+	end time.Time,
+) ([]StatementEntry, error) {
+	db := rs.db.WithContext(ctx)
+	var entries []StatementEntry
+
+	var invoices []models.Invoice
+	if err := db.
+		Preload("LineItems", "item_type = ?", LineItemTypeCredit).
+		Where("organization_id = ? AND currency = ?", org.ID, org.Currency).
+		Where("status NOT IN ?", []string{string(InvoiceStatusDraft), string(InvoiceStatusVoid)}).
+		Where("invoice_date < ?", end).
+		Find(&invoices).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch invoices: %w", err)
+	}
+	for _, invoice := range invoices {
+		amount := invoice.TotalAmount
+		for _, item := range invoice.LineItems {
+			amount = amount.Add(item.Amount.Abs())
+		}
+		entries = append(entries, StatementEntry{
+			Date:        invoice.InvoiceDate,
+			Type:        StatementEntryInvoice,
+			Reference:   invoice.InvoiceNumber,
+			Description: "Invoice",
+			Debit:       amount,
+			Credit:      decimal.Zero,
+		})
+	}
+
+	var payments []models.Payment
+	if err := db.
+		Preload("Invoice").
+		Where("organization_id = ? AND currency = ?", org.ID, org.Currency).
+		Where("status IN ? AND succeeded_at < ?", settledPaymentStatuses, end).
+		Find(&payments).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch payments: %w", err)
+	}
+	for _, payment := range payments {
+		entries = append(entries, StatementEntry{
+			Date:        *payment.SucceededAt,
+			Type:        StatementEntryPayment,
+			Reference:   payment.Invoice.InvoiceNumber,
+			Description: "Payment",
+			Debit:       decimal.Zero,
+			Credit:      payment.Amount,
+		})
+	}
+
+	var refunds []models.Refund
+	if err := db.
+		Preload("Payment.Invoice").
+		Where("organization_id = ? AND currency = ?", org.ID, org.Currency).
+		Where("status = ? AND COALESCE(refunded_at, created_at) < ?", RefundStatusSucceeded, end).
+		Find(&refunds).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch refunds: %w", err)
+	}
+	for _, refund := range refunds {
+		date := refund.CreatedAt
+		if refund.RefundedAt != nil {
+			date = *refund.RefundedAt
+		}
+		entries = append(entries, StatementEntry{
+			Date:        date,
+			Type:        StatementEntryRefund,
+			Reference:   refund.Payment.Invoice.InvoiceNumber,
+			Description: "Refund",
+			Debit:       refund.Amount,
+			Credit:      decimal.Zero,
+		})
+	}
+
+	var creditNotes []models.CreditNote
+	if err := db.
+		Where("organization_id = ? AND currency = ?", org.ID, org.Currency).
+		Where("status = ? AND issued_at < ?", CreditNoteStatusIssued, end).
+		Find(&creditNotes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch credit notes: %w", err)
+	}
+	for _, creditNote := range creditNotes {
+		entries = append(entries, StatementEntry{
+			Date:        creditNote.IssuedAt,
+			Type:        StatementEntryCreditNote,
+			Reference:   creditNote.CreditNoteNumber,
+			Description: "Credit note",
+			Debit:       decimal.Zero,
+			Credit:      creditNote.TotalAmount,
+		})
+	}
+
+	var credits []models.Credit
+	if err := db.
+		Where("organization_id = ? AND currency = ?", org.ID, org.Currency).
+		Where("valid_from < ?", end).
+		Find(&credits).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch credits: %w", err)
+	}
+	for _, credit := range credits {
+		if credit.Reason != "credit_note" {
+			entries = append(entries, StatementEntry{
+				Date:        credit.ValidFrom,
+				Type:        StatementEntryCredit,
+				Description: creditDescription(&credit),
+				Debit:       decimal.Zero,
+				Credit:      credit.Amount,
+			})
+		}
+
+		// Unused credit leaves the balance when it expires or is voided
+		if !credit.RemainingAmount.IsPositive() {
+			continue
+		}
+		var date time.Time
+		switch CreditStatus(credit.Status) {
+		case CreditStatusExpired:
+			date = credit.UpdatedAt
+			if credit.ValidUntil != nil {
+				date = *credit.ValidUntil
+			}
+		case CreditStatusVoided:
+			date = credit.UpdatedAt
+		default:
+			continue
+		}
+		if date.Before(end) {
+			entries = append(entries, StatementEntry{
+				Date:        date,
+				Type:        StatementEntryCreditExpired,
+				Description: "Unused credit " + credit.Status,
+				Debit:       credit.RemainingAmount,
+				Credit:      decimal.Zero,
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.Before(entries[j].Date)
+	})
+	return entries, nil
+}
+
+// creditDescription returns the statement description of a credit grant
+func creditDescription(credit *models.Credit) string {
+	if credit.Description != "" {
+		return credit.Description
+	}
+	return "Account credit"
+}
+
+// StatementCSV renders an account statement as CSV, with the opening and
+// closing balances as the first and last rows
+func StatementCSV(statement *AccountStatement) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	amount := func(v decimal.Decimal) string {
+		if v.IsZero() {
+			return ""
+		}
+		return v.StringFixed(2)
+	}
+
+	rows := [][]string{
+		{"date", "type", "reference", "description", "debit", "credit", "balance", "currency"},
+		{statement.PeriodStart.Format(time.RFC3339), "opening_balance", "", "Opening balance", "", "",
+			statement.OpeningBalance.StringFixed(2), statement.Currency},
+	}
+	for _, entry := range statement.Entries {
+		rows = append(rows, []string{
+			entry.Date.Format(time.RFC3339),
+			string(entry.Type),
+			entry.Reference,
+			entry.Description,
+			amount(entry.Debit),
+			amount(entry.Credit),
+			entry.Balance.StringFixed(2),
+			statement.Currency,
+		})
+	}
+	rows = append(rows, []string{statement.PeriodEnd.Format(time.RFC3339), "closing_balance", "", "Closing balance",
+		amount(statement.TotalDebits), amount(statement.TotalCredits), statement.ClosingBalance.StringFixed(2),
+		statement.Currency})
+
+	if err := w.WriteAll(rows); err != nil {
+		return nil, fmt.Errorf("failed to write statement CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
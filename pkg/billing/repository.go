@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Repositories holds the stores of invoices, payments, subscriptions and
+// usage the billing services depend on. Lookups of missing records return
+// gorm.ErrRecordNotFound.
+type Repositories struct {
+	Invoices      InvoiceRepo
+	Payments      PaymentRepo
+	Subscriptions SubscriptionRepo
+	Usage         UsageRepo
+}
+
+// NewGormRepositories creates repositories backed by the database
+func NewGormRepositories(db *gorm.DB, config *Config) *Repositories {
+	return &Repositories{
+		Invoices:      NewGormInvoiceRepo(db),
+		Payments:      NewGormPaymentRepo(db),
+		Subscriptions: NewGormSubscriptionRepo(db),
+		Usage:         NewGormUsageRepo(db, config.Usage.BatchSize),
+	}
+}
+
+// NewMemoryRepositories creates in-memory repositories, for tests
+func NewMemoryRepositories() *Repositories {
+	return &Repositories{
+		Invoices:      NewMemoryInvoiceRepo(),
+		Payments:      NewMemoryPaymentRepo(),
+		Subscriptions: NewMemorySubscriptionRepo(),
+		Usage:         NewMemoryUsageRepo(),
+	}
+}
+
+// InvoiceRepo stores invoices. Single invoices are returned with their line
+// items, organization and subscription.
+type InvoiceRepo interface {
+	Get(ctx context.Context, invoiceID string) (*models.Invoice, error)
+
+	// GetByIdempotencyKey returns nil when no invoice has the key
+	GetByIdempotencyKey(ctx context.Context, key string) (*models.Invoice, error)
+
+	// ListByOrganization returns the invoices of an organization with their
+	// line items, newest first
+	ListByOrganization(ctx context.Context, organizationID string, limit, offset int) ([]models.Invoice, error)
+
+	// ListOverdue returns the open invoices due before now
+	ListOverdue(ctx context.Context, now time.Time) ([]models.Invoice, error)
+
+	// Create saves an invoice with its line items. It reports false, saving
+	// nothing, when an invoice with the same idempotency key exists.
+	Create(ctx context.Context, invoice *models.Invoice) (bool, error)
+
+	// SetStatus changes the status of an invoice, if its status is one of
+	// from. Without from, any status is changed.
+	SetStatus(ctx context.Context, invoiceID string, status InvoiceStatus, from ...InvoiceStatus) error
+
+	// MarkPaid marks an invoice as paid
+	MarkPaid(ctx context.Context, invoiceID string, amountPaid decimal.Decimal, paidAt time.Time) error
+
+	// MarkPastDue moves an open invoice to past due, adding lateFee, if not
+	// nil, to its line items and amount due. It reports false, changing
+	// nothing, when the invoice is no longer open; otherwise invoice is
+	// updated in place.
+	MarkPastDue(ctx context.Context, invoice *models.Invoice, lateFee *models.InvoiceLineItem, overdueAt time.Time) (bool, error)
+
+	// ListUnsuspended returns the past due invoices due before dueBefore that
+	// have not suspended their organization yet
+	ListUnsuspended(ctx context.Context, dueBefore time.Time) ([]models.Invoice, error)
+
+	// MarkSuspended records that a past due invoice suspended its organization
+	MarkSuspended(ctx context.Context, invoiceID string, suspendedAt time.Time) error
+}
+
+// PaymentRepo stores payments
+type PaymentRepo interface {
+	Get(ctx context.Context, paymentID string) (*models.Payment, error)
+	GetByProviderPaymentID(ctx context.Context, provider PaymentProviderName, providerPaymentID string) (*models.Payment, error)
+
+	// ListByOrganization returns the payments of an organization, newest first
+	ListByOrganization(ctx context.Context, organizationID string, limit, offset int) ([]models.Payment, error)
+
+	Create(ctx context.Context, payment *models.Payment) error
+
+	// Update saves columns of a payment, or all of them without columns
+	Update(ctx context.Context, payment *models.Payment, columns ...string) error
+}
+
+// SubscriptionRepo stores subscriptions. Subscriptions are returned with their
+// plan and organization.
+type SubscriptionRepo interface {
+	Get(ctx context.Context, subscriptionID string) (*models.Subscription, error)
+	GetForOrganization(ctx context.Context, organizationID, subscriptionID string) (*models.Subscription, error)
+
+	// ListBillable returns the active, trialing and past due subscriptions
+	ListBillable(ctx context.Context) ([]models.Subscription, error)
+
+	// ListDue returns up to limit active and past due subscriptions whose
+	// period ended by cutoff, with IDs after afterID in ascending order
+	ListDue(ctx context.Context, cutoff time.Time, afterID uuid.UUID, limit int) ([]models.Subscription, error)
+
+	// StartNextPeriod moves an active or past due subscription whose period
+	// ends at periodEnd to the period ending at nextPeriodEnd, and reports
+	// whether it did
+	StartNextPeriod(ctx context.Context, subscriptionID uuid.UUID, periodEnd, nextPeriodEnd time.Time) (bool, error)
+
+	// CancelAfterPeriod cancels an active or past due subscription whose
+	// period ends at periodEnd, and reports whether it did
+	CancelAfterPeriod(ctx context.Context, subscriptionID uuid.UUID, periodEnd, canceledAt time.Time) (bool, error)
+}
+
+// UsageRepo stores usage metrics
+type UsageRepo interface {
+	// ListForPeriod returns the usage of an organization within a period
+	ListForPeriod(ctx context.Context, organizationID string, periodStart, periodEnd time.Time) ([]models.UsageMetric, error)
+
+	// ListRecordedSince returns the usage of an organization recorded since a time
+	ListRecordedSince(ctx context.Context, organizationID string, since time.Time) ([]models.UsageMetric, error)
+
+	Create(ctx context.Context, metrics []models.UsageMetric) error
+}
+
+// applyPastDue updates an invoice moved to past due
+func applyPastDue(invoice *models.Invoice, lateFee *models.InvoiceLineItem, overdueAt time.Time) {
+	invoice.Status = string(InvoiceStatusPastDue)
+	invoice.OverdueAt = &overdueAt
+	if lateFee == nil {
+		return
+	}
+	invoice.LateFee = invoice.LateFee.Add(lateFee.Amount)
+	invoice.TotalAmount = invoice.TotalAmount.Add(lateFee.Amount)
+	invoice.AmountDue = invoice.AmountDue.Add(lateFee.Amount)
+	invoice.LineItems = append(invoice.LineItems, *lateFee)
+}
+
+// txKey is the context key of the transaction GORM repositories run in
+type txKey struct{}
+
+// withTx returns a copy of ctx in which GORM repositories run in tx, so their
+// changes commit or roll back with the rest of the transaction
+func withTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// conn returns the transaction of ctx, or db
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,455 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormInvoiceRepo stores invoices in the database
+type GormInvoiceRepo struct {
+	db *gorm.DB
+}
+
+// NewGormInvoiceRepo creates a new database invoice repository
+func NewGormInvoiceRepo(db *gorm.DB) *GormInvoiceRepo {
+	return &GormInvoiceRepo{db: db}
+}
+
+// Get retrieves an invoice by ID
+func (r *GormInvoiceRepo) Get(ctx context.Context, invoiceID string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := r.preloaded(ctx).First(&invoice, "id = ?", invoiceID).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// GetByIdempotencyKey retrieves the invoice with an idempotency key
+func (r *GormInvoiceRepo) GetByIdempotencyKey(ctx context.Context, key string) (*models.Invoice, error) {
+	var invoices []models.Invoice
+	if err := r.preloaded(ctx).
+		Where("idempotency_key = ?", key).
+		Limit(1).
+		Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return nil, nil
+	}
+	return &invoices[0], nil
+}
+
+// ListByOrganization retrieves the invoices of an organization
+func (r *GormInvoiceRepo) ListByOrganization(
+	ctx context.Context,
+	organizationID string,
+	limit, offset int,
+) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+
+	query := conn(ctx, r.db).
+		Preload("LineItems").
+		Where("organization_id = ?", organizationID).
+		Order("invoice_date DESC")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	err := query.Find(&invoices).Error
+	return invoices, err
+}
+
+// ListOverdue retrieves the open invoices past their due date
+func (r *GormInvoiceRepo) ListOverdue(ctx context.Context, now time.Time) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	err := conn(ctx, r.db).
+		Where("status = ?", InvoiceStatusOpen).
+		Where("due_date < ?", now).
+		Find(&invoices).Error
+	return invoices, err
+}
+
+// Create saves an invoice and its line items, unless its idempotency key is
+// taken
+func (r *GormInvoiceRepo) Create(ctx context.Context, invoice *models.Invoice) (bool, error) {
+	created := false
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "idempotency_key"}},
+			DoNothing: true,
+		}).Omit(clause.Associations).Create(invoice)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		for i := range invoice.LineItems {
+			invoice.LineItems[i].InvoiceID = invoice.ID
+		}
+		if len(invoice.LineItems) > 0 {
+			if err := tx.Create(&invoice.LineItems).Error; err != nil {
+				return err
+			}
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// SetStatus changes the status of an invoice
+func (r *GormInvoiceRepo) SetStatus(
+	ctx context.Context,
+	invoiceID string,
+	status InvoiceStatus,
+	from ...InvoiceStatus,
+) error {
+	query := conn(ctx, r.db).
+		Model(&models.Invoice{}).
+		Where("id = ?", invoiceID)
+	if len(from) > 0 {
+		query = query.Where("status IN ?", from)
+	}
+	return query.Update("status", status).Error
+}
+
+// MarkPaid marks an invoice as paid
+func (r *GormInvoiceRepo) MarkPaid(
+	ctx context.Context,
+	invoiceID string,
+	amountPaid decimal.Decimal,
+	paidAt time.Time,
+) error {
+	return conn(ctx, r.db).
+		Model(&models.Invoice{}).
+		Where("id = ?", invoiceID).
+		Updates(map[string]interface{}{
+			"amount_paid": amountPaid,
+			"status":      InvoiceStatusPaid,
+			"paid_at":     paidAt,
+		}).Error
+}
+
+// MarkPastDue moves an open invoice to past due, adding the late fee
+func (r *GormInvoiceRepo) MarkPastDue(
+	ctx context.Context,
+	invoice *models.Invoice,
+	lateFee *models.InvoiceLineItem,
+	overdueAt time.Time,
+) (bool, error) {
+	marked := false
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"status":     InvoiceStatusPastDue,
+			"overdue_at": overdueAt,
+		}
+		if lateFee != nil {
+			updates["late_fee"] = gorm.Expr("late_fee + ?", lateFee.Amount)
+			updates["total_amount"] = gorm.Expr("total_amount + ?", lateFee.Amount)
+			updates["amount_due"] = gorm.Expr("amount_due + ?", lateFee.Amount)
+		}
+		result := tx.Model(&models.Invoice{}).
+			Where("id = ? AND status = ?", invoice.ID, InvoiceStatusOpen).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if lateFee != nil {
+			lateFee.InvoiceID = invoice.ID
+			if err := tx.Create(lateFee).Error; err != nil {
+				return err
+			}
+		}
+		marked = true
+		return nil
+	})
+	if err != nil || !marked {
+		return false, err
+	}
+
+	applyPastDue(invoice, lateFee, overdueAt)
+	return true, nil
+}
+
+// ListUnsuspended retrieves the past due invoices due before dueBefore that
+// have not suspended their organization
+func (r *GormInvoiceRepo) ListUnsuspended(ctx context.Context, dueBefore time.Time) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	err := conn(ctx, r.db).
+		Where("status = ?", InvoiceStatusPastDue).
+		Where("due_date < ?", dueBefore).
+		Where("suspended_at IS NULL").
+		Find(&invoices).Error
+	return invoices, err
+}
+
+// MarkSuspended records that an invoice suspended its organization
+func (r *GormInvoiceRepo) MarkSuspended(ctx context.Context, invoiceID string, suspendedAt time.Time) error {
+	return conn(ctx, r.db).
+		Model(&models.Invoice{}).
+		Where("id = ?", invoiceID).
+		Update("suspended_at", suspendedAt).Error
+}
+
+// preloaded returns a query loading the relations of invoices
+func (r *GormInvoiceRepo) preloaded(ctx context.Context) *gorm.DB {
+	return conn(ctx, r.db).
+		Preload("LineItems").
+		Preload("Organization").
+		Preload("Subscription")
+}
+
+// GormPaymentRepo stores payments in the database
+type GormPaymentRepo struct {
+	db *gorm.DB
+}
+
+// NewGormPaymentRepo creates a new database payment repository
+func NewGormPaymentRepo(db *gorm.DB) *GormPaymentRepo {
+	return &GormPaymentRepo{db: db}
+}
+
+// Get retrieves a payment by ID
+func (r *GormPaymentRepo) Get(ctx context.Context, paymentID string) (*models.Payment, error) {
+	var payment models.Payment
+	if err := conn(ctx, r.db).First(&payment, "id = ?", paymentID).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// GetByProviderPaymentID retrieves a payment by the ID its provider gave it
+func (r *GormPaymentRepo) GetByProviderPaymentID(
+	ctx context.Context,
+	provider PaymentProviderName,
+	providerPaymentID string,
+) (*models.Payment, error) {
+	var payment models.Payment
+	if err := conn(ctx, r.db).
+		Where("provider = ? AND provider_payment_id = ?", string(provider), providerPaymentID).
+		First(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// ListByOrganization retrieves the payments of an organization
+func (r *GormPaymentRepo) ListByOrganization(
+	ctx context.Context,
+	organizationID string,
+	limit, offset int,
+) ([]models.Payment, error) {
+	var payments []models.Payment
+
+	query := conn(ctx, r.db).
+		Where("organization_id = ?", organizationID).
+		Order("created_at DESC")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	err := query.Find(&payments).Error
+	return payments, err
+}
+
+// Create saves a new payment
+func (r *GormPaymentRepo) Create(ctx context.Context, payment *models.Payment) error {
+	return conn(ctx, r.db).Omit(clause.Associations).Create(payment).Error
+}
+
+// Update saves columns of a payment
+func (r *GormPaymentRepo) Update(ctx context.Context, payment *models.Payment, columns ...string) error {
+	if len(columns) == 0 {
+		return conn(ctx, r.db).Omit(clause.Associations).Save(payment).Error
+	}
+	return conn(ctx, r.db).Model(payment).Select(columns).Updates(payment).Error
+}
+
+// GormSubscriptionRepo stores subscriptions in the database
+type GormSubscriptionRepo struct {
+	db *gorm.DB
+}
+
+// NewGormSubscriptionRepo creates a new database subscription repository
+func NewGormSubscriptionRepo(db *gorm.DB) *GormSubscriptionRepo {
+	return &GormSubscriptionRepo{db: db}
+}
+
+// Get retrieves a subscription by ID
+func (r *GormSubscriptionRepo) Get(ctx context.Context, subscriptionID string) (*models.Subscription, error) {
+	var subscription models.Subscription
+	if err := r.preloaded(ctx).First(&subscription, "id = ?", subscriptionID).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// GetForOrganization retrieves a subscription of an organization by ID
+func (r *GormSubscriptionRepo) GetForOrganization(
+	ctx context.Context,
+	organizationID, subscriptionID string,
+) (*models.Subscription, error) {
+	var subscription models.Subscription
+	if err := r.preloaded(ctx).
+		Where("id = ? AND organization_id = ?", subscriptionID, organizationID).
+		First(&subscription).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// ListBillable retrieves the subscriptions usage is recorded for
+func (r *GormSubscriptionRepo) ListBillable(ctx context.Context) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := conn(ctx, r.db).
+		Where("status IN ?", []SubscriptionStatus{
+			SubscriptionStatusActive,
+			SubscriptionStatusTrialing,
+			SubscriptionStatusPastDue,
+		}).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// ListDue retrieves a batch of subscriptions whose period ended
+func (r *GormSubscriptionRepo) ListDue(
+	ctx context.Context,
+	cutoff time.Time,
+	afterID uuid.UUID,
+	limit int,
+) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.preloaded(ctx).
+		Where("status IN ?", []SubscriptionStatus{SubscriptionStatusActive, SubscriptionStatusPastDue}).
+		Where("current_period_end <= ?", cutoff).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// StartNextPeriod starts the next period of a subscription
+func (r *GormSubscriptionRepo) StartNextPeriod(
+	ctx context.Context,
+	subscriptionID uuid.UUID,
+	periodEnd, nextPeriodEnd time.Time,
+) (bool, error) {
+	return r.endPeriod(ctx, subscriptionID, periodEnd, map[string]interface{}{
+		"current_period_start": periodEnd,
+		"current_period_end":   nextPeriodEnd,
+	})
+}
+
+// CancelAfterPeriod cancels a subscription at the end of its period
+func (r *GormSubscriptionRepo) CancelAfterPeriod(
+	ctx context.Context,
+	subscriptionID uuid.UUID,
+	periodEnd, canceledAt time.Time,
+) (bool, error) {
+	return r.endPeriod(ctx, subscriptionID, periodEnd, map[string]interface{}{
+		"status":      SubscriptionStatusCanceled,
+		"canceled_at": canceledAt,
+	})
+}
+
+// endPeriod applies updates to a subscription still in the period ending at
+// periodEnd
+func (r *GormSubscriptionRepo) endPeriod(
+	ctx context.Context,
+	subscriptionID uuid.UUID,
+	periodEnd time.Time,
+	updates map[string]interface{},
+) (bool, error) {
+	result := conn(ctx, r.db).
+		Model(&models.Subscription{}).
+		Where("id = ? AND current_period_end = ?", subscriptionID, periodEnd).
+		Where("status IN ?", []SubscriptionStatus{SubscriptionStatusActive, SubscriptionStatusPastDue}).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// preloaded returns a query loading the relations of subscriptions
+func (r *GormSubscriptionRepo) preloaded(ctx context.Context) *gorm.DB {
+	return conn(ctx, r.db).
+		Preload("Plan").
+		Preload("Organization")
+}
+
+// GormUsageRepo stores usage metrics in the database
+type GormUsageRepo struct {
+	db        *gorm.DB
+	batchSize int
+}
+
+// NewGormUsageRepo creates a new database usage repository inserting metrics
+// in batches of batchSize
+func NewGormUsageRepo(db *gorm.DB, batchSize int) *GormUsageRepo {
+	return &GormUsageRepo{db: db, batchSize: batchSize}
+}
+
+// ListForPeriod retrieves the usage of an organization within a period
+func (r *GormUsageRepo) ListForPeriod(
+	ctx context.Context,
+	organizationID string,
+	periodStart, periodEnd time.Time,
+) ([]models.UsageMetric, error) {
+	var metrics []models.UsageMetric
+	err := conn(ctx, r.db).
+		Where("organization_id = ?", organizationID).
+		Where("period_start >= ?", periodStart).
+		Where("period_end <= ?", periodEnd).
+		Find(&metrics).Error
+	return metrics, err
+}
+
+// ListRecordedSince retrieves the usage of an organization recorded since a
+// time
+func (r *GormUsageRepo) ListRecordedSince(
+	ctx context.Context,
+	organizationID string,
+	since time.Time,
+) ([]models.UsageMetric, error) {
+	var metrics []models.UsageMetric
+	err := conn(ctx, r.db).
+		Where("organization_id = ?", organizationID).
+		Where("recorded_at >= ?", since).
+		Find(&metrics).Error
+	return metrics, err
+}
+
+// Create saves usage metrics
+func (r *GormUsageRepo) Create(ctx context.Context, metrics []models.UsageMetric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	return conn(ctx, r.db).CreateInBatches(metrics, r.batchSize).Error
+}
@@ -0,0 +1,549 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// The in-memory repositories are fakes for tests. They ignore transactions
+// and return the relations of records as they were stored.
+
+// MemoryInvoiceRepo stores invoices in memory
+type MemoryInvoiceRepo struct {
+	mu       sync.Mutex
+	invoices map[uuid.UUID]models.Invoice
+}
+
+// NewMemoryInvoiceRepo creates a new in-memory invoice repository
+func NewMemoryInvoiceRepo() *MemoryInvoiceRepo {
+	return &MemoryInvoiceRepo{invoices: make(map[uuid.UUID]models.Invoice)}
+}
+
+// Get retrieves an invoice by ID
+func (r *MemoryInvoiceRepo) Get(_ context.Context, invoiceID string) (*models.Invoice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, err := uuid.Parse(invoiceID)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	invoice, ok := r.invoices[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &invoice, nil
+}
+
+// GetByIdempotencyKey retrieves the invoice with an idempotency key
+func (r *MemoryInvoiceRepo) GetByIdempotencyKey(_ context.Context, key string) (*models.Invoice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, invoice := range r.invoices {
+		if invoice.IdempotencyKey != nil && *invoice.IdempotencyKey == key {
+			return &invoice, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListByOrganization retrieves the invoices of an organization
+func (r *MemoryInvoiceRepo) ListByOrganization(
+	_ context.Context,
+	organizationID string,
+	limit, offset int,
+) ([]models.Invoice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var invoices []models.Invoice
+	for _, invoice := range r.invoices {
+		if invoice.OrganizationID.String() == organizationID {
+			invoices = append(invoices, invoice)
+		}
+	}
+	sort.Slice(invoices, func(i, j int) bool {
+		return invoices[i].InvoiceDate.After(invoices[j].InvoiceDate)
+	})
+	return page(invoices, limit, offset), nil
+}
+
+// ListOverdue retrieves the open invoices past their due date
+func (r *MemoryInvoiceRepo) ListOverdue(_ context.Context, now time.Time) ([]models.Invoice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var invoices []models.Invoice
+	for _, invoice := range r.invoices {
+		if invoice.Status == string(InvoiceStatusOpen) && invoice.DueDate.Before(now) {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+// Create saves an invoice and its line items, unless its idempotency key is
+// taken
+func (r *MemoryInvoiceRepo) Create(_ context.Context, invoice *models.Invoice) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if invoice.IdempotencyKey != nil {
+		for _, existing := range r.invoices {
+			if existing.IdempotencyKey != nil && *existing.IdempotencyKey == *invoice.IdempotencyKey {
+				return false, nil
+			}
+		}
+	}
+
+	if invoice.ID == uuid.Nil {
+		invoice.ID = uuid.New()
+	}
+	now := time.Now()
+	invoice.CreatedAt = now
+	invoice.UpdatedAt = now
+	for i := range invoice.LineItems {
+		invoice.LineItems[i].InvoiceID = invoice.ID
+	}
+
+	stored := *invoice
+	stored.LineItems = append([]models.InvoiceLineItem(nil), invoice.LineItems...)
+	r.invoices[invoice.ID] = stored
+	return true, nil
+}
+
+// SetStatus changes the status of an invoice
+func (r *MemoryInvoiceRepo) SetStatus(
+	_ context.Context,
+	invoiceID string,
+	status InvoiceStatus,
+	from ...InvoiceStatus,
+) error {
+	return r.update(invoiceID, func(invoice *models.Invoice) {
+		if len(from) > 0 && !hasInvoiceStatus(from, InvoiceStatus(invoice.Status)) {
+			return
+		}
+		invoice.Status = string(status)
+	})
+}
+
+// MarkPaid marks an invoice as paid
+func (r *MemoryInvoiceRepo) MarkPaid(
+	_ context.Context,
+	invoiceID string,
+	amountPaid decimal.Decimal,
+	paidAt time.Time,
+) error {
+	return r.update(invoiceID, func(invoice *models.Invoice) {
+		invoice.AmountPaid = amountPaid
+		invoice.Status = string(InvoiceStatusPaid)
+		invoice.PaidAt = &paidAt
+	})
+}
+
+// MarkPastDue moves an open invoice to past due, adding the late fee
+func (r *MemoryInvoiceRepo) MarkPastDue(
+	_ context.Context,
+	invoice *models.Invoice,
+	lateFee *models.InvoiceLineItem,
+	overdueAt time.Time,
+) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.invoices[invoice.ID]
+	if !ok || stored.Status != string(InvoiceStatusOpen) {
+		return false, nil
+	}
+	if lateFee != nil {
+		lateFee.InvoiceID = invoice.ID
+	}
+	stored.LineItems = append([]models.InvoiceLineItem(nil), stored.LineItems...)
+	applyPastDue(&stored, lateFee, overdueAt)
+	stored.UpdatedAt = time.Now()
+	r.invoices[invoice.ID] = stored
+
+	applyPastDue(invoice, lateFee, overdueAt)
+	return true, nil
+}
+
+// ListUnsuspended retrieves the past due invoices due before dueBefore that
+// have not suspended their organization
+func (r *MemoryInvoiceRepo) ListUnsuspended(_ context.Context, dueBefore time.Time) ([]models.Invoice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var invoices []models.Invoice
+	for _, invoice := range r.invoices {
+		if invoice.Status == string(InvoiceStatusPastDue) &&
+			invoice.DueDate.Before(dueBefore) &&
+			invoice.SuspendedAt == nil {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+// MarkSuspended records that an invoice suspended its organization
+func (r *MemoryInvoiceRepo) MarkSuspended(_ context.Context, invoiceID string, suspendedAt time.Time) error {
+	return r.update(invoiceID, func(invoice *models.Invoice) {
+		invoice.SuspendedAt = &suspendedAt
+	})
+}
+
+// update applies a change to a stored invoice. Like the database updates,
+// missing invoices are not an error.
+func (r *MemoryInvoiceRepo) update(invoiceID string, change func(*models.Invoice)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, err := uuid.Parse(invoiceID)
+	if err != nil {
+		return nil
+	}
+	invoice, ok := r.invoices[id]
+	if !ok {
+		return nil
+	}
+	change(&invoice)
+	invoice.UpdatedAt = time.Now()
+	r.invoices[id] = invoice
+	return nil
+}
+
+// hasInvoiceStatus reports whether statuses contains status
+func hasInvoiceStatus(statuses []InvoiceStatus, status InvoiceStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// MemoryPaymentRepo stores payments in memory
+type MemoryPaymentRepo struct {
+	mu       sync.Mutex
+	payments map[uuid.UUID]models.Payment
+}
+
+// NewMemoryPaymentRepo creates a new in-memory payment repository
+func NewMemoryPaymentRepo() *MemoryPaymentRepo {
+	return &MemoryPaymentRepo{payments: make(map[uuid.UUID]models.Payment)}
+}
+
+// Get retrieves a payment by ID
+func (r *MemoryPaymentRepo) Get(_ context.Context, paymentID string) (*models.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, err := uuid.Parse(paymentID)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	payment, ok := r.payments[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &payment, nil
+}
+
+// GetByProviderPaymentID retrieves a payment by the ID its provider gave it
+func (r *MemoryPaymentRepo) GetByProviderPaymentID(
+	_ context.Context,
+	provider PaymentProviderName,
+	providerPaymentID string,
+) (*models.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, payment := range r.payments {
+		if payment.Provider == string(provider) && payment.ProviderPaymentID == providerPaymentID {
+			return &payment, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// ListByOrganization retrieves the payments of an organization
+func (r *MemoryPaymentRepo) ListByOrganization(
+	_ context.Context,
+	organizationID string,
+	limit, offset int,
+) ([]models.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var payments []models.Payment
+	for _, payment := range r.payments {
+		if payment.OrganizationID.String() == organizationID {
+			payments = append(payments, payment)
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool {
+		return payments[i].CreatedAt.After(payments[j].CreatedAt)
+	})
+	return page(payments, limit, offset), nil
+}
+
+// Create saves a new payment
+func (r *MemoryPaymentRepo) Create(_ context.Context, payment *models.Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if payment.ID == uuid.Nil {
+		payment.ID = uuid.New()
+	}
+	now := time.Now()
+	payment.CreatedAt = now
+	payment.UpdatedAt = now
+	r.payments[payment.ID] = *payment
+	return nil
+}
+
+// Update saves a payment. Columns are ignored: the whole payment is saved.
+func (r *MemoryPaymentRepo) Update(_ context.Context, payment *models.Payment, _ ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.payments[payment.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	payment.UpdatedAt = time.Now()
+	r.payments[payment.ID] = *payment
+	return nil
+}
+
+// MemorySubscriptionRepo stores subscriptions in memory
+type MemorySubscriptionRepo struct {
+	mu            sync.Mutex
+	subscriptions map[uuid.UUID]models.Subscription
+}
+
+// NewMemorySubscriptionRepo creates a new in-memory subscription repository
+func NewMemorySubscriptionRepo() *MemorySubscriptionRepo {
+	return &MemorySubscriptionRepo{subscriptions: make(map[uuid.UUID]models.Subscription)}
+}
+
+// Put stores a subscription, with the plan and organization to return it with
+func (r *MemorySubscriptionRepo) Put(subscription models.Subscription) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscriptions[subscription.ID] = subscription
+}
+
+// Get retrieves a subscription by ID
+func (r *MemorySubscriptionRepo) Get(_ context.Context, subscriptionID string) (*models.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, err := uuid.Parse(subscriptionID)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &subscription, nil
+}
+
+// GetForOrganization retrieves a subscription of an organization by ID
+func (r *MemorySubscriptionRepo) GetForOrganization(
+	ctx context.Context,
+	organizationID, subscriptionID string,
+) (*models.Subscription, error) {
+	subscription, err := r.Get(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.OrganizationID.String() != organizationID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return subscription, nil
+}
+
+// ListBillable retrieves the subscriptions usage is recorded for
+func (r *MemorySubscriptionRepo) ListBillable(_ context.Context) ([]models.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var subscriptions []models.Subscription
+	for _, subscription := range r.subscriptions {
+		switch SubscriptionStatus(subscription.Status) {
+		case SubscriptionStatusActive, SubscriptionStatusTrialing, SubscriptionStatusPastDue:
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+// ListDue retrieves a batch of subscriptions whose period ended
+func (r *MemorySubscriptionRepo) ListDue(
+	_ context.Context,
+	cutoff time.Time,
+	afterID uuid.UUID,
+	limit int,
+) ([]models.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var subscriptions []models.Subscription
+	for _, subscription := range r.subscriptions {
+		if !billableAtPeriodEnd(&subscription) || subscription.CurrentPeriodEnd.After(cutoff) {
+			continue
+		}
+		if subscription.ID.String() <= afterID.String() {
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].ID.String() < subscriptions[j].ID.String()
+	})
+	return page(subscriptions, limit, 0), nil
+}
+
+// StartNextPeriod starts the next period of a subscription
+func (r *MemorySubscriptionRepo) StartNextPeriod(
+	_ context.Context,
+	subscriptionID uuid.UUID,
+	periodEnd, nextPeriodEnd time.Time,
+) (bool, error) {
+	return r.endPeriod(subscriptionID, periodEnd, func(subscription *models.Subscription) {
+		subscription.CurrentPeriodStart = periodEnd
+		subscription.CurrentPeriodEnd = nextPeriodEnd
+	}), nil
+}
+
+// CancelAfterPeriod cancels a subscription at the end of its period
+func (r *MemorySubscriptionRepo) CancelAfterPeriod(
+	_ context.Context,
+	subscriptionID uuid.UUID,
+	periodEnd, canceledAt time.Time,
+) (bool, error) {
+	return r.endPeriod(subscriptionID, periodEnd, func(subscription *models.Subscription) {
+		subscription.Status = string(SubscriptionStatusCanceled)
+		subscription.CanceledAt = &canceledAt
+	}), nil
+}
+
+// endPeriod applies a change to a subscription still in the period ending at
+// periodEnd
+func (r *MemorySubscriptionRepo) endPeriod(
+	subscriptionID uuid.UUID,
+	periodEnd time.Time,
+	change func(*models.Subscription),
+) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subscription, ok := r.subscriptions[subscriptionID]
+	if !ok || !billableAtPeriodEnd(&subscription) || !subscription.CurrentPeriodEnd.Equal(periodEnd) {
+		return false
+	}
+	change(&subscription)
+	subscription.UpdatedAt = time.Now()
+	r.subscriptions[subscriptionID] = subscription
+	return true
+}
+
+// billableAtPeriodEnd reports whether billing runs invoice a subscription
+func billableAtPeriodEnd(subscription *models.Subscription) bool {
+	return subscription.Status == string(SubscriptionStatusActive) ||
+		subscription.Status == string(SubscriptionStatusPastDue)
+}
+
+// MemoryUsageRepo stores usage metrics in memory
+type MemoryUsageRepo struct {
+	mu      sync.Mutex
+	metrics []models.UsageMetric
+}
+
+// NewMemoryUsageRepo creates a new in-memory usage repository
+func NewMemoryUsageRepo() *MemoryUsageRepo {
+	return &MemoryUsageRepo{}
+}
+
+// ListForPeriod retrieves the usage of an organization within a period
+func (r *MemoryUsageRepo) ListForPeriod(
+	_ context.Context,
+	organizationID string,
+	periodStart, periodEnd time.Time,
+) ([]models.UsageMetric, error) {
+	return r.filter(func(metric *models.UsageMetric) bool {
+		return metric.OrganizationID.String() == organizationID &&
+			!metric.PeriodStart.Before(periodStart) &&
+			!metric.PeriodEnd.After(periodEnd)
+	}), nil
+}
+
+// ListRecordedSince retrieves the usage of an organization recorded since a
+// time
+func (r *MemoryUsageRepo) ListRecordedSince(
+	_ context.Context,
+	organizationID string,
+	since time.Time,
+) ([]models.UsageMetric, error) {
+	return r.filter(func(metric *models.UsageMetric) bool {
+		return metric.OrganizationID.String() == organizationID && !metric.RecordedAt.Before(since)
+	}), nil
+}
+
+// Create saves usage metrics
+func (r *MemoryUsageRepo) Create(_ context.Context, metrics []models.UsageMetric) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, metric := range metrics {
+		if metric.ID == uuid.Nil {
+			metric.ID = uuid.New()
+		}
+		if metric.RecordedAt.IsZero() {
+			metric.RecordedAt = now
+		}
+		metric.CreatedAt = now
+		r.metrics = append(r.metrics, metric)
+	}
+	return nil
+}
+
+// filter returns the stored metrics matching a predicate
+func (r *MemoryUsageRepo) filter(match func(*models.UsageMetric) bool) []models.UsageMetric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var metrics []models.UsageMetric
+	for i := range r.metrics {
+		if match(&r.metrics[i]) {
+			metrics = append(metrics, r.metrics[i])
+		}
+	}
+	return metrics
+}
+
+// page returns the records of a page of a sorted list
+func page[T any](records []T, limit, offset int) []T {
+	if offset > 0 {
+		if offset >= len(records) {
+			return nil
+		}
+		records = records[offset:]
+	}
+	if limit > 0 && limit < len(records) {
+		records = records[:limit]
+	}
+	return records
+}
@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Object storage backends
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
+// ObjectStorage stores generated billing documents
+type ObjectStorage interface {
+	// Put stores an object and returns its URL
+	Put(ctx context.Context, key string, data []byte, contentType string) (string, error)
+	// Get reads an object
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// NewObjectStorage creates the object storage configured in Storage
+func NewObjectStorage(ctx context.Context, config *Config) (ObjectStorage, error) {
+	switch config.Storage.Backend {
+	case "", StorageLocal:
+		return NewLocalStorage(config.Invoice.PDFStoragePath, config.Storage.PublicBaseURL), nil
+	case StorageS3:
+		return NewS3Storage(ctx, config.Storage)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %s", config.Storage.Backend)
+	}
+}
+
+// LocalStorage stores objects on the local filesystem
+type LocalStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStorage creates a filesystem storage rooted at dir. Object URLs are
+// baseURL joined with the key, or file paths when baseURL is empty.
+func NewLocalStorage(dir, baseURL string) *LocalStorage {
+	return &LocalStorage{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Put implements ObjectStorage
+func (s *LocalStorage) Put(_ context.Context, key string, data []byte, _ string) (string, error) {
+	filename, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o750); err != nil {
+		return "", fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0o640); err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+
+	if s.baseURL == "" {
+		return filename, nil
+	}
+	return s.baseURL + "/" + key, nil
+}
+
+// Get implements ObjectStorage
+func (s *LocalStorage) Get(_ context.Context, key string) ([]byte, error) {
+	filename, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return data, nil
+}
+
+// path maps a key to a file below the storage directory
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" {
+		return "", fmt.Errorf("invalid object key: %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(clean)), nil
+}
+
+// S3Storage stores objects in an S3 compatible bucket
+type S3Storage struct {
+	client *s3.Client
+	bucket string
+	prefix string
+	urlFmt string
+}
+
+// NewS3Storage creates an S3 storage. Credentials are taken from the default
+// AWS credential chain.
+func NewS3Storage(ctx context.Context, config StorageConfig) (*S3Storage, error) {
+	if config.S3Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+
+	opts := []func(*awsconfig.LoadOptions) error{}
+	if config.S3Region != "" {
+		opts = append(opts, awsconfig.WithRegion(config.S3Region))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if config.S3Endpoint != "" {
+			o.BaseEndpoint = aws.String(config.S3Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+
+	urlFmt := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%%s", config.S3Bucket, cfg.Region)
+	if config.S3Endpoint != "" {
+		urlFmt = strings.TrimSuffix(config.S3Endpoint, "/") + "/" + config.S3Bucket + "/%s"
+	}
+	if config.PublicBaseURL != "" {
+		urlFmt = strings.TrimSuffix(config.PublicBaseURL, "/") + "/%s"
+	}
+
+	return &S3Storage{
+		client: client,
+		bucket: config.S3Bucket,
+		prefix: strings.Trim(config.S3Prefix, "/"),
+		urlFmt: urlFmt,
+	}, nil
+}
+
+// Put implements ObjectStorage
+func (s *S3Storage) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	objectKey := s.objectKey(key)
+
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(objectKey),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload object: %w", err)
+	}
+
+	return fmt.Sprintf(s.urlFmt, objectKey), nil
+}
+
+// Get implements ObjectStorage
+func (s *S3Storage) Get(ctx context.Context, key string) ([]byte, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download object: %w", err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return data, nil
+}
+
+// objectKey prefixes a key with the configured prefix
+func (s *S3Storage) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
@@ -0,0 +1,746 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SubscriptionService manages the lifecycle of subscriptions: plan changes
+// with proration, pausing and resuming, and trials
+type SubscriptionService struct {
+	db                  *gorm.DB
+	config              *Config
+	subscriptions       SubscriptionRepo
+	pricingEngine       *PricingEngine
+	invoiceService      *InvoiceService
+	notificationService *NotificationService
+	eventPublisher      *BillingEventPublisher
+}
+
+// NewSubscriptionService creates a new subscription service. The
+// notification service and event publisher are optional.
+func NewSubscriptionService(
+	db *gorm.DB,
+	config *Config,
+	repos *Repositories,
+	pricingEngine *PricingEngine,
+	invoiceService *InvoiceService,
+	notificationService *NotificationService,
+	eventPublisher *BillingEventPublisher,
+) *SubscriptionService {
+	return &SubscriptionService{
+		db:                  db,
+		config:              config,
+		subscriptions:       repos.Subscriptions,
+		pricingEngine:       pricingEngine,
+		invoiceService:      invoiceService,
+		notificationService: notificationService,
+		eventPublisher:      eventPublisher,
+	}
+}
+
+// GetSubscription retrieves a subscription of the organization of the tenant
+// in ctx
+func (ss *SubscriptionService) GetSubscription(ctx context.Context, subscriptionID string) (*models.Subscription, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := ss.subscriptions.GetForOrganization(ctx, organizationID, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+// ChangePlan moves a subscription of the organization of the tenant in ctx to
+// another plan. Immediate changes are prorated: upgrades are invoiced for the
+// rest of the period and downgrades credited. Changes at the period end are
+// scheduled and applied by ProcessLifecycle; a new schedule replaces the
+// previous one.
+func (ss *SubscriptionService) ChangePlan(
+	ctx context.Context,
+	subscriptionID string,
+	planID string,
+	atPeriodEnd bool,
+) (*models.PlanChange, error) {
+	subscription, err := ss.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch SubscriptionStatus(subscription.Status) {
+	case SubscriptionStatusActive, SubscriptionStatusTrialing:
+	default:
+		return nil, fmt.Errorf("cannot change the plan of a %s subscription", subscription.Status)
+	}
+
+	var plan models.SubscriptionPlan
+	if err := ss.db.WithContext(ctx).
+		Where("id = ? AND is_active = ?", planID, true).
+		First(&plan).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch plan: %w", err)
+	}
+	if plan.ID == subscription.PlanID {
+		return nil, fmt.Errorf("subscription is already on this plan")
+	}
+	if plan.Currency != subscription.Plan.Currency {
+		return nil, fmt.Errorf("cannot change to a plan billed in %s", plan.Currency)
+	}
+	if plan.BillingInterval != subscription.Plan.BillingInterval && !atPeriodEnd {
+		return nil, fmt.Errorf("billing interval changes take effect at the end of the period")
+	}
+
+	oldPrice := ss.pricingEngine.SubscriptionPrice(&subscription.Plan, subscription.Quantity)
+	newPrice := ss.pricingEngine.SubscriptionPrice(&plan, subscription.Quantity)
+	changeType := PlanChangeDowngrade
+	if newPrice.GreaterThan(oldPrice) {
+		changeType = PlanChangeUpgrade
+	}
+
+	change := &models.PlanChange{
+		ID:              uuid.New(),
+		SubscriptionID:  subscription.ID,
+		OrganizationID:  subscription.OrganizationID,
+		FromPlanID:      subscription.PlanID,
+		ToPlanID:        plan.ID,
+		ChangeType:      string(changeType),
+		ProrationAmount: decimal.Zero,
+		Currency:        plan.Currency,
+	}
+
+	if atPeriodEnd {
+		change.Status = string(PlanChangeStatusScheduled)
+		change.EffectiveAt = subscription.CurrentPeriodEnd
+
+		err := ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := cancelScheduledChanges(tx, subscription.ID); err != nil {
+				return err
+			}
+			if err := tx.Create(change).Error; err != nil {
+				return fmt.Errorf("failed to schedule plan change: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if err := ss.applyImmediateChange(ctx, subscription, &plan, change, oldPrice, newPrice); err != nil {
+			return nil, err
+		}
+	}
+
+	change.FromPlan = subscription.Plan
+	change.ToPlan = plan
+	if err := ss.notifyPlanChanged(ctx, change); err != nil {
+		return change, err
+	}
+	return change, nil
+}
+
+// applyImmediateChange switches the plan of a subscription now and settles the
+// proration. Trials are not prorated.
+func (ss *SubscriptionService) applyImmediateChange(
+	ctx context.Context,
+	subscription *models.Subscription,
+	plan *models.SubscriptionPlan,
+	change *models.PlanChange,
+	oldPrice, newPrice decimal.Decimal,
+) error {
+	now := time.Now()
+	change.Status = string(PlanChangeStatusApplied)
+	change.EffectiveAt = now
+	change.AppliedAt = &now
+
+	if subscription.Status != string(SubscriptionStatusTrialing) {
+		change.ProrationAmount = ss.pricingEngine.CalculateProration(
+			oldPrice,
+			newPrice,
+			subscription.CurrentPeriodStart,
+			subscription.CurrentPeriodEnd,
+			now,
+		)
+	}
+
+	// Price the upgrade before changing anything so tax failures abort the change
+	var charge *ChargeCalculation
+	if change.ProrationAmount.GreaterThan(decimal.Zero) {
+		var err error
+		charge, err = ss.pricingEngine.CalculateProrationCharge(ctx, subscription, &subscription.Plan, plan, change.ProrationAmount, now)
+		if err != nil {
+			return fmt.Errorf("failed to calculate proration charge: %w", err)
+		}
+	}
+
+	err := ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := cancelScheduledChanges(tx, subscription.ID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Subscription{}).
+			Where("id = ?", subscription.ID).
+			Update("plan_id", plan.ID).Error; err != nil {
+			return fmt.Errorf("failed to update subscription plan: %w", err)
+		}
+
+		// Downgrades are credited against future invoices
+		if change.ProrationAmount.LessThan(decimal.Zero) {
+			credit := &models.Credit{
+				ID:              uuid.New(),
+				OrganizationID:  subscription.OrganizationID,
+				Amount:          change.ProrationAmount.Neg(),
+				Currency:        plan.Currency,
+				RemainingAmount: change.ProrationAmount.Neg(),
+				Reason:          "proration",
+				Description:     fmt.Sprintf("Prorated credit for the change from %s to %s", subscription.Plan.Name, plan.Name),
+				ValidFrom:       now,
+				Status:          string(CreditStatusActive),
+			}
+			if err := tx.Create(credit).Error; err != nil {
+				return fmt.Errorf("failed to create proration credit: %w", err)
+			}
+			change.ProrationCreditID = &credit.ID
+		}
+
+		if err := tx.Create(change).Error; err != nil {
+			return fmt.Errorf("failed to record plan change: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if charge != nil {
+		invoice, err := ss.invoiceService.GenerateProrationInvoice(ctx, subscription, charge, now)
+		if err != nil {
+			return fmt.Errorf("failed to generate proration invoice: %w", err)
+		}
+		change.ProrationInvoiceID = &invoice.ID
+		if err := ss.db.WithContext(ctx).Model(change).
+			Update("proration_invoice_id", invoice.ID).Error; err != nil {
+			return fmt.Errorf("failed to link proration invoice: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// cancelScheduledChanges cancels the pending plan change of a subscription
+func cancelScheduledChanges(tx *gorm.DB, subscriptionID uuid.UUID) error {
+	if err := tx.Model(&models.PlanChange{}).
+		Where("subscription_id = ? AND status = ?", subscriptionID, PlanChangeStatusScheduled).
+		Update("status", PlanChangeStatusCanceled).Error; err != nil {
+		return fmt.Errorf("failed to cancel scheduled plan change: %w", err)
+	}
+	return nil
+}
+
+// CancelScheduledChange cancels the pending plan change of a subscription of
+// the organization of the tenant in ctx. Changes scheduled by a price change
+// of the plan cannot be canceled.
+func (ss *SubscriptionService) CancelScheduledChange(ctx context.Context, subscriptionID string) error {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := ss.db.WithContext(ctx).
+		Model(&models.PlanChange{}).
+		Where("subscription_id = ? AND organization_id = ?", subscriptionID, organizationID).
+		Where("status = ? AND price_change_id IS NULL", PlanChangeStatusScheduled).
+		Update("status", PlanChangeStatusCanceled)
+	if result.Error != nil {
+		return fmt.Errorf("failed to cancel scheduled plan change: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("subscription has no scheduled plan change that can be canceled")
+	}
+	return nil
+}
+
+// ListPlanChanges retrieves the plan changes of a subscription of the
+// organization of the tenant in ctx
+func (ss *SubscriptionService) ListPlanChanges(ctx context.Context, subscriptionID string) ([]models.PlanChange, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []models.PlanChange
+	err = ss.db.WithContext(ctx).
+		Preload("FromPlan").
+		Preload("ToPlan").
+		Where("subscription_id = ? AND organization_id = ?", subscriptionID, organizationID).
+		Order("created_at DESC").
+		Find(&changes).Error
+	return changes, err
+}
+
+// ChangeQuantity changes the seat count of a subscription of the organization
+// of the tenant in ctx, effective now. The change is recorded so that the
+// period is invoiced per segment with the seats it had.
+func (ss *SubscriptionService) ChangeQuantity(
+	ctx context.Context,
+	subscriptionID string,
+	quantity int,
+) (*models.SeatChange, error) {
+	if quantity < 1 {
+		return nil, fmt.Errorf("quantity must be at least 1")
+	}
+
+	subscription, err := ss.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch SubscriptionStatus(subscription.Status) {
+	case SubscriptionStatusActive, SubscriptionStatusTrialing, SubscriptionStatusPastDue:
+	default:
+		return nil, fmt.Errorf("cannot change the seats of a %s subscription", subscription.Status)
+	}
+
+	var change *models.SeatChange
+	err = ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.Subscription
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&current, "id = ?", subscription.ID).Error; err != nil {
+			return fmt.Errorf("failed to lock subscription: %w", err)
+		}
+		if current.Quantity == quantity {
+			return fmt.Errorf("subscription already has %d seats", quantity)
+		}
+
+		change = &models.SeatChange{
+			ID:             uuid.New(),
+			SubscriptionID: current.ID,
+			OrganizationID: current.OrganizationID,
+			FromQuantity:   current.Quantity,
+			ToQuantity:     quantity,
+			EffectiveAt:    time.Now(),
+		}
+		if err := tx.Create(change).Error; err != nil {
+			return fmt.Errorf("failed to record seat change: %w", err)
+		}
+
+		if err := tx.Model(&current).Update("quantity", quantity).Error; err != nil {
+			return fmt.Errorf("failed to update subscription quantity: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// ListSeatChanges retrieves the seat changes of a subscription of the
+// organization of the tenant in ctx, newest first
+func (ss *SubscriptionService) ListSeatChanges(ctx context.Context, subscriptionID string) ([]models.SeatChange, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []models.SeatChange
+	err = ss.db.WithContext(ctx).
+		Where("subscription_id = ? AND organization_id = ?", subscriptionID, organizationID).
+		Order("effective_at DESC").
+		Find(&changes).Error
+	return changes, err
+}
+
+// PauseSubscription pauses an active subscription of the organization of the
+// tenant in ctx. Paused subscriptions are not invoiced. Without resumesAt the
+// subscription stays paused until resumed, up to Subscriptions.MaxPauseDuration.
+func (ss *SubscriptionService) PauseSubscription(
+	ctx context.Context,
+	subscriptionID string,
+	resumesAt *time.Time,
+) (*models.Subscription, error) {
+	subscription, err := ss.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.Status != string(SubscriptionStatusActive) {
+		return nil, fmt.Errorf("cannot pause a %s subscription", subscription.Status)
+	}
+
+	now := time.Now()
+	if resumesAt != nil && !resumesAt.After(now) {
+		return nil, fmt.Errorf("resume date must be in the future")
+	}
+	if maxPause := ss.config.Subscriptions.MaxPauseDuration; maxPause > 0 {
+		latest := now.Add(maxPause)
+		if resumesAt == nil {
+			resumesAt = &latest
+		} else if resumesAt.After(latest) {
+			return nil, fmt.Errorf("subscriptions can be paused for at most %s", maxPause)
+		}
+	}
+
+	result := ss.db.WithContext(ctx).
+		Model(&models.Subscription{}).
+		Where("id = ? AND status = ?", subscription.ID, SubscriptionStatusActive).
+		Updates(map[string]interface{}{
+			"status":     SubscriptionStatusPaused,
+			"paused_at":  now,
+			"resumes_at": resumesAt,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to pause subscription: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("subscription is no longer active")
+	}
+
+	subscription.Status = string(SubscriptionStatusPaused)
+	subscription.PausedAt = &now
+	subscription.ResumesAt = resumesAt
+
+	if ss.eventPublisher != nil {
+		if err := ss.eventPublisher.PublishSubscriptionPaused(ctx, subscription); err != nil {
+			return subscription, fmt.Errorf("failed to publish subscription paused event: %w", err)
+		}
+	}
+	if ss.notificationService != nil {
+		if err := ss.notificationService.SendSubscriptionPausedNotification(ctx, subscription); err != nil {
+			return subscription, fmt.Errorf("failed to send subscription paused notification: %w", err)
+		}
+	}
+
+	return subscription, nil
+}
+
+// ResumeSubscription resumes a paused subscription of the organization of the
+// tenant in ctx
+func (ss *SubscriptionService) ResumeSubscription(ctx context.Context, subscriptionID string) (*models.Subscription, error) {
+	subscription, err := ss.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ss.resume(ctx, subscription); err != nil {
+		return subscription, err
+	}
+	return subscription, nil
+}
+
+// resume reactivates a paused subscription. The current period is extended by
+// the time spent paused.
+func (ss *SubscriptionService) resume(ctx context.Context, subscription *models.Subscription) error {
+	if subscription.Status != string(SubscriptionStatusPaused) {
+		return fmt.Errorf("cannot resume a %s subscription", subscription.Status)
+	}
+
+	now := time.Now()
+	periodEnd := subscription.CurrentPeriodEnd
+	if subscription.PausedAt != nil {
+		periodEnd = periodEnd.Add(now.Sub(*subscription.PausedAt))
+	}
+
+	result := ss.db.WithContext(ctx).
+		Model(&models.Subscription{}).
+		Where("id = ? AND status = ?", subscription.ID, SubscriptionStatusPaused).
+		Updates(map[string]interface{}{
+			"status":             SubscriptionStatusActive,
+			"paused_at":          nil,
+			"resumes_at":         nil,
+			"current_period_end": periodEnd,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to resume subscription: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("subscription is no longer paused")
+	}
+
+	subscription.Status = string(SubscriptionStatusActive)
+	subscription.PausedAt = nil
+	subscription.ResumesAt = nil
+	subscription.CurrentPeriodEnd = periodEnd
+
+	if ss.eventPublisher != nil {
+		if err := ss.eventPublisher.PublishSubscriptionResumed(ctx, subscription); err != nil {
+			return fmt.Errorf("failed to publish subscription resumed event: %w", err)
+		}
+	}
+	if ss.notificationService != nil {
+		if err := ss.notificationService.SendSubscriptionResumedNotification(ctx, subscription); err != nil {
+			return fmt.Errorf("failed to send subscription resumed notification: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ConvertTrial ends the trial of a subscription of the organization of the
+// tenant in ctx and starts its first paid period
+func (ss *SubscriptionService) ConvertTrial(ctx context.Context, subscriptionID string) (*models.Subscription, error) {
+	subscription, err := ss.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ss.convertTrial(ctx, subscription); err != nil {
+		return subscription, err
+	}
+	return subscription, nil
+}
+
+// convertTrial activates a trialing subscription with a new billing period
+// starting now, and invoices the base fee of plans billed in advance. With
+// automatic payments enabled, the invoices of organizations with a default
+// payment method are then charged to it.
+func (ss *SubscriptionService) convertTrial(ctx context.Context, subscription *models.Subscription) error {
+	if subscription.Status != string(SubscriptionStatusTrialing) {
+		return fmt.Errorf("subscription is not trialing")
+	}
+
+	now := time.Now()
+	trialEnd := now
+	if subscription.TrialEnd != nil && subscription.TrialEnd.Before(now) {
+		trialEnd = *subscription.TrialEnd
+	}
+	periodEnd := FirstPeriodEnd(now, &subscription.Plan)
+
+	result := ss.db.WithContext(ctx).
+		Model(&models.Subscription{}).
+		Where("id = ? AND status = ?", subscription.ID, SubscriptionStatusTrialing).
+		Updates(map[string]interface{}{
+			"status":               SubscriptionStatusActive,
+			"trial_end":            trialEnd,
+			"current_period_start": now,
+			"current_period_end":   periodEnd,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to convert trial: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("subscription is no longer trialing")
+	}
+
+	subscription.Status = string(SubscriptionStatusActive)
+	subscription.TrialEnd = &trialEnd
+	subscription.CurrentPeriodStart = now
+	subscription.CurrentPeriodEnd = periodEnd
+
+	organization := &subscription.Organization
+	if ss.config.Features.EnableAutoPayment && organization.DefaultPaymentMethodID != "" && !organization.AutoPay {
+		if err := ss.db.WithContext(ctx).
+			Model(&models.Organization{}).
+			Where("id = ?", subscription.OrganizationID).
+			Update("auto_pay", true).Error; err != nil {
+			return fmt.Errorf("failed to enable automatic payments: %w", err)
+		}
+		organization.AutoPay = true
+	}
+
+	if err := ss.invoiceInAdvance(ctx, subscription); err != nil {
+		return err
+	}
+
+	if ss.eventPublisher != nil {
+		if err := ss.eventPublisher.PublishTrialConverted(ctx, subscription); err != nil {
+			return fmt.Errorf("failed to publish trial converted event: %w", err)
+		}
+	}
+	if ss.notificationService != nil {
+		if err := ss.notificationService.SendTrialConvertedNotification(ctx, subscription); err != nil {
+			return fmt.Errorf("failed to send trial converted notification: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// invoiceInAdvance generates the advance invoice of the first period of a
+// subscription whose plan is billed in advance
+func (ss *SubscriptionService) invoiceInAdvance(ctx context.Context, subscription *models.Subscription) error {
+	if BillingMode(subscription.Plan.BillingMode) != BillingModeAdvance {
+		return nil
+	}
+
+	invoice, created, err := ss.invoiceService.generateAdvanceInvoice(ctx, subscription)
+	if err != nil {
+		return fmt.Errorf("failed to generate advance invoice: %w", err)
+	}
+	if created && ss.eventPublisher != nil {
+		if err := ss.eventPublisher.PublishInvoiceCreated(ctx, invoice); err != nil {
+			return fmt.Errorf("failed to publish invoice created event: %w", err)
+		}
+	}
+	return nil
+}
+
+// FirstPeriodEnd returns the end of the first billing period of a
+// subscription to a plan starting at start. Plans billed in advance are
+// aligned to calendar months: unless it starts on the first of a month (UTC),
+// their first period ends at the start of the next month and is prorated.
+func FirstPeriodEnd(start time.Time, plan *models.SubscriptionPlan) time.Time {
+	if BillingMode(plan.BillingMode) != BillingModeAdvance {
+		return nextPeriodEnd(start, plan.BillingInterval)
+	}
+	start = start.UTC()
+	monthStart := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if start.Equal(monthStart) {
+		return nextPeriodEnd(start, plan.BillingInterval)
+	}
+	return monthStart.AddDate(0, 1, 0)
+}
+
+// nextPeriodEnd returns the end of a billing period starting at start
+func nextPeriodEnd(start time.Time, billingInterval string) time.Time {
+	if BillingCycle(billingInterval) == BillingCycleAnnual {
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// ProcessLifecycle applies due scheduled plan changes, resumes subscriptions
+// whose pause ended and converts expired trials. Trials of organizations
+// without a default payment method are downgraded or become incomplete
+// instead, per Subscriptions.TrialExpiryAction.
+func (ss *SubscriptionService) ProcessLifecycle(ctx context.Context) error {
+	now := time.Now()
+	var errs []error
+
+	var changes []models.PlanChange
+	if err := ss.db.WithContext(ctx).
+		Preload("FromPlan").
+		Preload("ToPlan").
+		Where("status = ? AND effective_at <= ?", PlanChangeStatusScheduled, now).
+		Order("effective_at ASC").
+		Find(&changes).Error; err != nil {
+		return fmt.Errorf("failed to fetch scheduled plan changes: %w", err)
+	}
+	for i := range changes {
+		orgCtx := tenant.WithOrganization(ctx, changes[i].OrganizationID.String())
+		if err := ss.applyScheduledChange(orgCtx, &changes[i]); err != nil {
+			errs = append(errs, fmt.Errorf("plan change %s: %w", changes[i].ID, err))
+		}
+	}
+
+	var paused []models.Subscription
+	if err := ss.db.WithContext(ctx).
+		Preload("Plan").
+		Where("status = ? AND resumes_at <= ?", SubscriptionStatusPaused, now).
+		Find(&paused).Error; err != nil {
+		return fmt.Errorf("failed to fetch paused subscriptions: %w", err)
+	}
+	for i := range paused {
+		orgCtx := tenant.WithOrganization(ctx, paused[i].OrganizationID.String())
+		if err := ss.resume(orgCtx, &paused[i]); err != nil {
+			errs = append(errs, fmt.Errorf("subscription %s: %w", paused[i].ID, err))
+		}
+	}
+
+	var trials []models.Subscription
+	if err := ss.db.WithContext(ctx).
+		Preload("Plan").
+		Preload("Organization").
+		Where("status = ? AND trial_end <= ?", SubscriptionStatusTrialing, now).
+		Find(&trials).Error; err != nil {
+		return fmt.Errorf("failed to fetch expired trials: %w", err)
+	}
+	for i := range trials {
+		orgCtx := tenant.WithOrganization(ctx, trials[i].OrganizationID.String())
+		if err := ss.expireTrial(orgCtx, &trials[i]); err != nil {
+			errs = append(errs, fmt.Errorf("subscription %s: %w", trials[i].ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// applyScheduledChange switches a subscription to the plan of a due scheduled
+// change. Changes of subscriptions that are no longer active are canceled.
+func (ss *SubscriptionService) applyScheduledChange(ctx context.Context, change *models.PlanChange) error {
+	var subscription models.Subscription
+	if err := ss.db.WithContext(ctx).First(&subscription, "id = ?", change.SubscriptionID).Error; err != nil {
+		return fmt.Errorf("failed to fetch subscription: %w", err)
+	}
+
+	switch SubscriptionStatus(subscription.Status) {
+	case SubscriptionStatusActive, SubscriptionStatusPastDue:
+		// Changes at the end of a period wait until the billing run invoiced
+		// the period at the old plan
+		if !subscription.CurrentPeriodEnd.After(change.EffectiveAt) {
+			return nil
+		}
+	case SubscriptionStatusTrialing:
+	default:
+		return ss.db.WithContext(ctx).Model(change).Update("status", PlanChangeStatusCanceled).Error
+	}
+
+	now := time.Now()
+	err := ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Subscription{}).
+			Where("id = ?", subscription.ID).
+			Update("plan_id", change.ToPlanID).Error; err != nil {
+			return fmt.Errorf("failed to update subscription plan: %w", err)
+		}
+		if err := tx.Model(change).Updates(map[string]interface{}{
+			"status":     PlanChangeStatusApplied,
+			"applied_at": now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update plan change: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	change.Status = string(PlanChangeStatusApplied)
+	change.AppliedAt = &now
+	return ss.notifyPlanChanged(ctx, change)
+}
+
+// expireTrial converts an expired trial, or downgrades it or marks it
+// incomplete when the organization cannot be charged
+func (ss *SubscriptionService) expireTrial(ctx context.Context, subscription *models.Subscription) error {
+	if subscription.Organization.DefaultPaymentMethodID != "" {
+		return ss.convertTrial(ctx, subscription)
+	}
+	if TrialExpiryAction(ss.config.Subscriptions.TrialExpiryAction) == TrialExpiryDowngrade {
+		return ss.downgradeTrial(ctx, subscription)
+	}
+
+	if err := ss.db.WithContext(ctx).
+		Model(&models.Subscription{}).
+		Where("id = ? AND status = ?", subscription.ID, SubscriptionStatusTrialing).
+		Update("status", SubscriptionStatusIncomplete).Error; err != nil {
+		return fmt.Errorf("failed to mark subscription incomplete: %w", err)
+	}
+	return nil
+}
+
+// notifyPlanChanged publishes the event and sends the notification of a plan
+// change with its plans loaded
+func (ss *SubscriptionService) notifyPlanChanged(ctx context.Context, change *models.PlanChange) error {
+	if ss.eventPublisher != nil {
+		if err := ss.eventPublisher.PublishPlanChanged(ctx, change); err != nil {
+			return fmt.Errorf("failed to publish plan changed event: %w", err)
+		}
+	}
+	if ss.notificationService != nil {
+		if err := ss.notificationService.SendPlanChangedNotification(ctx, change); err != nil {
+			return fmt.Errorf("failed to send plan changed notification: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Tax providers
+const (
+	TaxProviderFlat    = "flat"    // Single rate from Invoice.TaxRate
+	TaxProviderTable   = "table"   // Per-jurisdiction rates from dictamesh_billing_tax_rates
+	TaxProviderStripe  = "stripe"  // Stripe Tax
+	TaxProviderAvalara = "avalara" // Avalara AvaTax
+)
+
+// euCountries are the EU member states (ISO 3166-1 alpha-2), used for the
+// intra-community B2B reverse charge
+var euCountries = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "CY": true, "CZ": true, "DE": true,
+	"DK": true, "EE": true, "ES": true, "FI": true, "FR": true, "GR": true,
+	"HR": true, "HU": true, "IE": true, "IT": true, "LT": true, "LU": true,
+	"LV": true, "MT": true, "NL": true, "PL": true, "PT": true, "RO": true,
+	"SE": true, "SI": true, "SK": true,
+}
+
+// TaxRequest describes a taxable amount sold to an organization
+type TaxRequest struct {
+	OrganizationID string
+	Country        string // ISO 3166-1 alpha-2
+	State          string
+	City           string
+	PostalCode     string
+	AddressLine1   string
+	TaxID          string // VAT/GST registration of the customer
+	Currency       string
+	Amount         decimal.Decimal
+}
+
+// TaxLine is the tax of a single jurisdiction
+type TaxLine struct {
+	Jurisdiction  string          // e.g. "DE", "US-CA"
+	TaxType       string          // e.g. vat, gst, sales_tax
+	Name          string          // Display name, e.g. "VAT"
+	Rate          decimal.Decimal // Fraction, e.g. 0.19
+	TaxableAmount decimal.Decimal
+	Amount        decimal.Decimal
+}
+
+// TaxResult is the outcome of a tax calculation
+type TaxResult struct {
+	Provider      string
+	Lines         []TaxLine
+	Total         decimal.Decimal
+	ReverseCharge bool // The customer accounts for VAT (EU B2B)
+}
+
+// TaxProvider calculates taxes for a request
+type TaxProvider interface {
+	Name() string
+	CalculateTax(ctx context.Context, req *TaxRequest) (*TaxResult, error)
+}
+
+// TaxService calculates taxes based on the organization address and tax ID
+// through a pluggable provider
+type TaxService struct {
+	config   *Config
+	provider TaxProvider
+}
+
+// NewTaxService creates a tax service using the provider configured in
+// Tax.Provider
+func NewTaxService(db *gorm.DB, config *Config) (*TaxService, error) {
+	var provider TaxProvider
+	switch config.Tax.Provider {
+	case "", TaxProviderFlat:
+		provider = NewFlatTaxProvider(config.Invoice.TaxRate)
+	case TaxProviderTable:
+		provider = NewTableTaxProvider(db)
+	case TaxProviderStripe:
+		provider = NewStripeTaxProvider(config)
+	case TaxProviderAvalara:
+		provider = NewAvalaraTaxProvider(config)
+	default:
+		return nil, fmt.Errorf("unsupported tax provider: %s", config.Tax.Provider)
+	}
+
+	return NewTaxServiceWithProvider(config, provider), nil
+}
+
+// NewTaxServiceWithProvider creates a tax service using a custom provider
+func NewTaxServiceWithProvider(config *Config, provider TaxProvider) *TaxService {
+	return &TaxService{
+		config:   config,
+		provider: provider,
+	}
+}
+
+// Calculate calculates the tax of an amount sold to an organization
+func (ts *TaxService) Calculate(
+	ctx context.Context,
+	org *models.Organization,
+	currency string,
+	amount decimal.Decimal,
+) (*TaxResult, error) {
+	req := &TaxRequest{
+		OrganizationID: org.ID.String(),
+		Country:        strings.ToUpper(org.Country),
+		State:          strings.ToUpper(org.State),
+		City:           org.City,
+		PostalCode:     org.PostalCode,
+		AddressLine1:   org.AddressLine1,
+		TaxID:          strings.TrimSpace(org.TaxID),
+		Currency:       currency,
+		Amount:         amount,
+	}
+
+	if ts.isReverseCharge(req) {
+		return &TaxResult{
+			Provider:      ts.provider.Name(),
+			Total:         decimal.Zero,
+			ReverseCharge: true,
+			Lines: []TaxLine{{
+				Jurisdiction:  req.Country,
+				TaxType:       "vat",
+				Name:          "VAT reverse charge",
+				Rate:          decimal.Zero,
+				TaxableAmount: amount,
+				Amount:        decimal.Zero,
+			}},
+		}, nil
+	}
+
+	result, err := ts.provider.CalculateTax(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate tax with %s: %w", ts.provider.Name(), err)
+	}
+
+	result.Total = decimal.Zero
+	for _, line := range result.Lines {
+		result.Total = result.Total.Add(line.Amount)
+	}
+	return result, nil
+}
+
+// isReverseCharge reports whether a sale is an intra-EU B2B supply where the
+// customer accounts for VAT
+func (ts *TaxService) isReverseCharge(req *TaxRequest) bool {
+	if !ts.config.Tax.EnableReverseCharge || req.TaxID == "" {
+		return false
+	}
+	seller := strings.ToUpper(ts.config.Tax.SellerCountry)
+	return euCountries[seller] && euCountries[req.Country] && seller != req.Country
+}
+
+// LineItems converts a tax result into invoice line items
+func (r *TaxResult) LineItems() []InvoiceLineItem {
+	var items []InvoiceLineItem
+	for _, line := range r.Lines {
+		if line.Amount.IsZero() && !r.ReverseCharge {
+			continue
+		}
+
+		description := fmt.Sprintf("%s %s (%s%%)", line.Name, line.Jurisdiction, line.Rate.Mul(decimal.NewFromInt(100)).String())
+		if r.ReverseCharge {
+			description = "VAT reverse charged: the customer is liable for VAT (Art. 196 Directive 2006/112/EC)"
+		}
+
+		items = append(items, InvoiceLineItem{
+			Description: description,
+			Quantity:    decimal.NewFromInt(1),
+			UnitPrice:   line.Amount,
+			Amount:      line.Amount,
+			ItemType:    LineItemTypeTax,
+			Metadata: map[string]interface{}{
+				"provider":       r.Provider,
+				"jurisdiction":   line.Jurisdiction,
+				"tax_type":       line.TaxType,
+				"rate":           line.Rate.String(),
+				"taxable_amount": line.TaxableAmount.StringFixed(2),
+				"reverse_charge": r.ReverseCharge,
+			},
+		})
+	}
+	return items
+}
+
+// FlatTaxProvider applies a single rate regardless of jurisdiction
+type FlatTaxProvider struct {
+	rate decimal.Decimal
+}
+
+// NewFlatTaxProvider creates a flat rate tax provider
+func NewFlatTaxProvider(rate decimal.Decimal) *FlatTaxProvider {
+	return &FlatTaxProvider{rate: rate}
+}
+
+// Name implements TaxProvider
+func (p *FlatTaxProvider) Name() string {
+	return TaxProviderFlat
+}
+
+// CalculateTax implements TaxProvider
+func (p *FlatTaxProvider) CalculateTax(_ context.Context, req *TaxRequest) (*TaxResult, error) {
+	result := &TaxResult{Provider: TaxProviderFlat}
+	if p.rate.GreaterThan(decimal.Zero) {
+		result.Lines = append(result.Lines, TaxLine{
+			Jurisdiction:  req.Country,
+			TaxType:       "tax",
+			Name:          "Tax",
+			Rate:          p.rate,
+			TaxableAmount: req.Amount,
+			Amount:        req.Amount.Mul(p.rate).Round(2),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,272 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/shopspring/decimal"
+	"github.com/stripe/stripe-go/v75"
+	"github.com/stripe/stripe-go/v75/tax/calculation"
+	"gorm.io/gorm"
+)
+
+// TableTaxProvider applies the rates of dictamesh_billing_tax_rates. Every
+// active rate of the customer country without a state, plus every rate of
+// the customer state, becomes a tax line (e.g. Canadian GST and PST).
+type TableTaxProvider struct {
+	db *gorm.DB
+}
+
+// NewTableTaxProvider creates a table based tax provider
+func NewTableTaxProvider(db *gorm.DB) *TableTaxProvider {
+	return &TableTaxProvider{db: db}
+}
+
+// Name implements TaxProvider
+func (p *TableTaxProvider) Name() string {
+	return TaxProviderTable
+}
+
+// CalculateTax implements TaxProvider
+func (p *TableTaxProvider) CalculateTax(ctx context.Context, req *TaxRequest) (*TaxResult, error) {
+	result := &TaxResult{Provider: TaxProviderTable}
+	if req.Country == "" {
+		return result, nil
+	}
+
+	var rates []models.TaxRate
+	if err := p.db.WithContext(ctx).
+		Where("is_active = ?", true).
+		Where("country = ?", req.Country).
+		Where("state = '' OR state = ?", req.State).
+		Order("state ASC, tax_type ASC").
+		Find(&rates).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch tax rates: %w", err)
+	}
+
+	for _, rate := range rates {
+		jurisdiction := rate.Country
+		if rate.State != "" {
+			jurisdiction += "-" + rate.State
+		}
+		result.Lines = append(result.Lines, TaxLine{
+			Jurisdiction:  jurisdiction,
+			TaxType:       rate.TaxType,
+			Name:          rate.Name,
+			Rate:          rate.Rate,
+			TaxableAmount: req.Amount,
+			Amount:        req.Amount.Mul(rate.Rate).Round(2),
+		})
+	}
+
+	return result, nil
+}
+
+// StripeTaxProvider calculates taxes with Stripe Tax
+type StripeTaxProvider struct {
+	config *Config
+}
+
+// NewStripeTaxProvider creates a Stripe Tax provider
+func NewStripeTaxProvider(config *Config) *StripeTaxProvider {
+	return &StripeTaxProvider{config: config}
+}
+
+// Name implements TaxProvider
+func (p *StripeTaxProvider) Name() string {
+	return TaxProviderStripe
+}
+
+// CalculateTax implements TaxProvider
+func (p *StripeTaxProvider) CalculateTax(ctx context.Context, req *TaxRequest) (*TaxResult, error) {
+	amountCents := req.Amount.Mul(decimal.NewFromInt(100)).IntPart()
+
+	params := &stripe.TaxCalculationParams{
+		Currency: stripe.String(strings.ToLower(req.Currency)),
+		CustomerDetails: &stripe.TaxCalculationCustomerDetailsParams{
+			Address: &stripe.AddressParams{
+				Line1:      stripe.String(req.AddressLine1),
+				City:       stripe.String(req.City),
+				State:      stripe.String(req.State),
+				PostalCode: stripe.String(req.PostalCode),
+				Country:    stripe.String(req.Country),
+			},
+			AddressSource: stripe.String("billing"),
+		},
+		LineItems: []*stripe.TaxCalculationLineItemParams{{
+			Amount:      stripe.Int64(amountCents),
+			Reference:   stripe.String("subscription"),
+			TaxBehavior: stripe.String("exclusive"),
+			TaxCode:     stripe.String(p.config.Tax.StripeTaxCode),
+		}},
+	}
+	params.Context = ctx
+
+	calc, err := (&calculation.Client{
+		B:   stripe.GetBackend(stripe.APIBackend),
+		Key: p.config.StripeAPIKey(),
+	}).New(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Stripe tax calculation: %w", err)
+	}
+
+	result := &TaxResult{Provider: TaxProviderStripe}
+	for _, breakdown := range calc.TaxBreakdown {
+		if breakdown.TaxabilityReason == stripe.TaxCalculationTaxBreakdownTaxabilityReasonReverseCharge {
+			result.ReverseCharge = true
+		}
+		if breakdown.TaxRateDetails == nil {
+			continue
+		}
+
+		details := breakdown.TaxRateDetails
+		jurisdiction := details.Country
+		if details.State != "" {
+			jurisdiction += "-" + details.State
+		}
+		percentage, _ := decimal.NewFromString(details.PercentageDecimal)
+
+		result.Lines = append(result.Lines, TaxLine{
+			Jurisdiction:  jurisdiction,
+			TaxType:       string(details.TaxType),
+			Name:          strings.ToUpper(strings.ReplaceAll(string(details.TaxType), "_", " ")),
+			Rate:          percentage.Div(decimal.NewFromInt(100)),
+			TaxableAmount: decimal.New(breakdown.TaxableAmount, -2),
+			Amount:        decimal.New(breakdown.Amount, -2),
+		})
+	}
+
+	return result, nil
+}
+
+// avalaraURLs are the AvaTax REST API base URLs by environment
+var avalaraURLs = map[string]string{
+	"sandbox":    "https://sandbox-rest.avatax.com",
+	"production": "https://rest.avatax.com",
+}
+
+// AvalaraTaxProvider calculates taxes with Avalara AvaTax
+type AvalaraTaxProvider struct {
+	config *Config
+	client *http.Client
+}
+
+// NewAvalaraTaxProvider creates an Avalara AvaTax provider
+func NewAvalaraTaxProvider(config *Config) *AvalaraTaxProvider {
+	return &AvalaraTaxProvider{
+		config: config,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name implements TaxProvider
+func (p *AvalaraTaxProvider) Name() string {
+	return TaxProviderAvalara
+}
+
+// avalaraTransaction is the response of the create transaction API
+type avalaraTransaction struct {
+	TotalTax decimal.Decimal `json:"totalTax"`
+	Summary  []struct {
+		Country   string          `json:"country"`
+		Region    string          `json:"region"`
+		JurisType string          `json:"jurisType"`
+		JurisName string          `json:"jurisName"`
+		TaxType   string          `json:"taxType"`
+		TaxName   string          `json:"taxName"`
+		Rate      decimal.Decimal `json:"rate"`
+		Taxable   decimal.Decimal `json:"taxable"`
+		Tax       decimal.Decimal `json:"tax"`
+	} `json:"summary"`
+}
+
+// CalculateTax implements TaxProvider. Calculations are made as uncommitted
+// sales orders.
+func (p *AvalaraTaxProvider) CalculateTax(ctx context.Context, req *TaxRequest) (*TaxResult, error) {
+	cfg := &p.config.Tax.Avalara
+
+	body := map[string]interface{}{
+		"type":         "SalesOrder",
+		"companyCode":  cfg.CompanyCode,
+		"date":         time.Now().Format("2006-01-02"),
+		"customerCode": req.OrganizationID,
+		"currencyCode": req.Currency,
+		"addresses": map[string]interface{}{
+			"singleLocation": map[string]string{
+				"line1":      req.AddressLine1,
+				"city":       req.City,
+				"region":     req.State,
+				"postalCode": req.PostalCode,
+				"country":    req.Country,
+			},
+		},
+		"lines": []map[string]interface{}{{
+			"number":  "1",
+			"amount":  req.Amount,
+			"taxCode": cfg.TaxCode,
+		}},
+	}
+	if req.TaxID != "" {
+		body["businessIdentificationNo"] = req.TaxID
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal AvaTax request: %w", err)
+	}
+
+	url := avalaraURLs[cfg.Environment] + "/api/v2/transactions/create"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.SetBasicAuth(cfg.AccountID, p.config.AvalaraLicenseKey())
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call AvaTax: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("AvaTax returned status %d", resp.StatusCode)
+	}
+
+	var transaction avalaraTransaction
+	if err := json.NewDecoder(resp.Body).Decode(&transaction); err != nil {
+		return nil, fmt.Errorf("failed to decode AvaTax response: %w", err)
+	}
+
+	result := &TaxResult{Provider: TaxProviderAvalara}
+	for _, summary := range transaction.Summary {
+		jurisdiction := summary.Country
+		if summary.Region != "" {
+			jurisdiction += "-" + summary.Region
+		}
+		name := summary.TaxName
+		if name == "" {
+			name = summary.JurisName
+		}
+
+		result.Lines = append(result.Lines, TaxLine{
+			Jurisdiction:  jurisdiction,
+			TaxType:       strings.ToLower(summary.TaxType),
+			Name:          name,
+			Rate:          summary.Rate,
+			TaxableAmount: summary.Taxable,
+			Amount:        summary.Tax.Round(2),
+		})
+	}
+
+	return result, nil
+}
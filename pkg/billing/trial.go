@@ -0,0 +1,372 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StartTrial starts a trial of a plan for the organization of the tenant in
+// ctx. The trial lasts Subscriptions.TrialDuration and is entitled to the
+// features and limits of the plan; it is not invoiced. Organizations get one
+// trial, and only while they have no other subscription.
+func (ss *SubscriptionService) StartTrial(
+	ctx context.Context,
+	planID string,
+	quantity int,
+) (*models.Subscription, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if quantity < 1 {
+		return nil, fmt.Errorf("quantity must be at least 1")
+	}
+
+	var plan models.SubscriptionPlan
+	if err := ss.db.WithContext(ctx).
+		Where("id = ? AND is_active = ?", planID, true).
+		First(&plan).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch plan: %w", err)
+	}
+
+	now := time.Now()
+	trialEnd := now.Add(ss.config.Subscriptions.TrialDuration)
+	subscription := &models.Subscription{
+		ID:                 uuid.New(),
+		PlanID:             plan.ID,
+		Status:             string(SubscriptionStatusTrialing),
+		CurrentPeriodStart: now,
+		CurrentPeriodEnd:   trialEnd,
+		TrialStart:         &now,
+		TrialEnd:           &trialEnd,
+		Quantity:           quantity,
+	}
+
+	err = ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the organization so concurrent requests start one trial
+		var organization models.Organization
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&organization, "id = ?", organizationID).Error; err != nil {
+			return fmt.Errorf("failed to fetch organization: %w", err)
+		}
+		if plan.Currency != organization.Currency {
+			return fmt.Errorf("cannot start a trial of a plan billed in %s", plan.Currency)
+		}
+
+		var existing int64
+		if err := tx.Model(&models.Subscription{}).
+			Where("organization_id = ?", organization.ID).
+			Where("trial_start IS NOT NULL OR status <> ?", SubscriptionStatusCanceled).
+			Count(&existing).Error; err != nil {
+			return fmt.Errorf("failed to check subscriptions: %w", err)
+		}
+		if existing > 0 {
+			return fmt.Errorf("organization is not eligible for a trial")
+		}
+
+		subscription.OrganizationID = organization.ID
+		if err := tx.Create(subscription).Error; err != nil {
+			return fmt.Errorf("failed to create subscription: %w", err)
+		}
+		subscription.Organization = organization
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	subscription.Plan = plan
+
+	if ss.eventPublisher != nil {
+		if err := ss.eventPublisher.PublishSubscriptionCreated(ctx, subscription); err != nil {
+			return subscription, fmt.Errorf("failed to publish subscription created event: %w", err)
+		}
+	}
+	if ss.notificationService != nil {
+		if err := ss.notificationService.SendSubscriptionCreatedNotification(ctx, subscription); err != nil {
+			return subscription, fmt.Errorf("failed to send subscription created notification: %w", err)
+		}
+	}
+
+	return subscription, nil
+}
+
+// ExtendTrial extends the trial of a subscription of the organization of the
+// tenant in ctx by a number of days. Trials are extended by at most
+// Subscriptions.TrialMaxExtension in total.
+func (ss *SubscriptionService) ExtendTrial(
+	ctx context.Context,
+	subscriptionID string,
+	days int,
+) (*models.Subscription, error) {
+	if days < 1 {
+		return nil, fmt.Errorf("trials are extended by at least one day")
+	}
+
+	subscription, err := ss.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.Status != string(SubscriptionStatusTrialing) || subscription.TrialEnd == nil {
+		return nil, fmt.Errorf("subscription is not trialing")
+	}
+	if !subscription.TrialEnd.After(time.Now()) {
+		return nil, fmt.Errorf("trial has already ended")
+	}
+
+	trialStart := subscription.CreatedAt
+	if subscription.TrialStart != nil {
+		trialStart = *subscription.TrialStart
+	}
+	trialEnd := subscription.TrialEnd.AddDate(0, 0, days)
+	maxEnd := trialStart.Add(ss.config.Subscriptions.TrialDuration + ss.config.Subscriptions.TrialMaxExtension)
+	if trialEnd.After(maxEnd) {
+		return nil, fmt.Errorf("trial cannot be extended past %s", maxEnd.Format(time.RFC3339))
+	}
+
+	// The trial is extended once when requests race
+	result := ss.db.WithContext(ctx).
+		Model(&models.Subscription{}).
+		Where("id = ? AND status = ? AND trial_end = ?", subscription.ID, SubscriptionStatusTrialing, subscription.TrialEnd).
+		Updates(map[string]interface{}{
+			"trial_end":          trialEnd,
+			"current_period_end": trialEnd,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to extend trial: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("trial changed concurrently")
+	}
+
+	subscription.TrialEnd = &trialEnd
+	subscription.CurrentPeriodEnd = trialEnd
+	return subscription, nil
+}
+
+// GetEntitlements returns the entitlements of the live subscription of the
+// organization of the tenant in ctx. Trials are entitled to their plan.
+func (ss *SubscriptionService) GetEntitlements(ctx context.Context) (*Entitlements, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscription models.Subscription
+	if err := ss.db.WithContext(ctx).
+		Preload("Plan").
+		Where("organization_id = ? AND status IN ?", organizationID, []SubscriptionStatus{
+			SubscriptionStatusActive,
+			SubscriptionStatusTrialing,
+			SubscriptionStatusPastDue,
+		}).
+		Order("created_at DESC").
+		First(&subscription).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription: %w", err)
+	}
+
+	plan := subscription.Plan
+	seats := plan.IncludedSeats
+	if subscription.Quantity > seats {
+		seats = subscription.Quantity
+	}
+	entitlements := &Entitlements{
+		SubscriptionID:         subscription.ID.String(),
+		Plan:                   plan.Slug,
+		PlanVersion:            plan.Version,
+		Status:                 SubscriptionStatus(subscription.Status),
+		Features:               plan.Features,
+		IncludedAPICalls:       plan.IncludedAPICalls,
+		IncludedStorageGB:      plan.IncludedStorageGB,
+		IncludedDataTransferGB: plan.IncludedDataTransferGB,
+		Seats:                  seats,
+		MaxAdapters:            plan.MaxAdapters,
+	}
+	if subscription.Status == string(SubscriptionStatusTrialing) {
+		entitlements.TrialEnd = subscription.TrialEnd
+	}
+	return entitlements, nil
+}
+
+// ProcessTrialReminders reminds organizations of the end of their trial
+// Subscriptions.TrialReminderDays before it
+func (ss *SubscriptionService) ProcessTrialReminders(ctx context.Context) error {
+	reminderDays := ss.config.Subscriptions.TrialReminderDays
+	if len(reminderDays) == 0 || ss.notificationService == nil {
+		return nil
+	}
+
+	now := time.Now()
+	var trials []models.Subscription
+	if err := ss.db.WithContext(ctx).
+		Preload("Plan").
+		Preload("Organization").
+		Where("status = ? AND trial_end > ? AND trial_end <= ?",
+			SubscriptionStatusTrialing, now, now.AddDate(0, 0, reminderDays[0])).
+		Find(&trials).Error; err != nil {
+		return fmt.Errorf("failed to fetch ending trials: %w", err)
+	}
+
+	var errs []error
+	for i := range trials {
+		orgCtx := tenant.WithOrganization(ctx, trials[i].OrganizationID.String())
+		if err := ss.remindTrial(orgCtx, &trials[i], now); err != nil {
+			errs = append(errs, fmt.Errorf("subscription %s: %w", trials[i].ID, err))
+		}
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// remindTrial sends the reminders of a trial that are due. Reminders due at
+// once are sent as one, with the days left until the end of the trial.
+func (ss *SubscriptionService) remindTrial(ctx context.Context, subscription *models.Subscription, now time.Time) error {
+	var due []int
+	for _, days := range ss.config.Subscriptions.TrialReminderDays {
+		if !subscription.TrialEnd.AddDate(0, 0, -days).After(now) {
+			due = append(due, days)
+		}
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	// Reminders are recorded with the notification, so failed notifications
+	// are retried on the next run
+	return ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var sent []int
+		if err := tx.Model(&models.TrialReminder{}).
+			Where("subscription_id = ? AND trial_end = ?", subscription.ID, subscription.TrialEnd).
+			Pluck("days_before", &sent).Error; err != nil {
+			return fmt.Errorf("failed to fetch trial reminders: %w", err)
+		}
+		done := make(map[int]bool, len(sent))
+		for _, days := range sent {
+			done[days] = true
+		}
+
+		var reminders []models.TrialReminder
+		for _, days := range due {
+			if done[days] {
+				continue
+			}
+			reminders = append(reminders, models.TrialReminder{
+				ID:             uuid.New(),
+				SubscriptionID: subscription.ID,
+				OrganizationID: subscription.OrganizationID,
+				TrialEnd:       *subscription.TrialEnd,
+				DaysBefore:     days,
+			})
+		}
+		if len(reminders) == 0 {
+			return nil
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reminders)
+		if result.Error != nil {
+			return fmt.Errorf("failed to record trial reminders: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			// Reminded concurrently
+			return nil
+		}
+
+		daysLeft := int(subscription.TrialEnd.Sub(now).Hours()/24) + 1
+		if err := ss.notificationService.SendTrialEndingNotification(ctx, subscription, daysLeft); err != nil {
+			return fmt.Errorf("failed to send trial ending notification: %w", err)
+		}
+		return nil
+	})
+}
+
+// downgradeTrial moves an expired trial to the current version of
+// Subscriptions.TrialDowngradePlan and starts its first period on that plan
+func (ss *SubscriptionService) downgradeTrial(ctx context.Context, subscription *models.Subscription) error {
+	var plan models.SubscriptionPlan
+	if err := ss.db.WithContext(ctx).
+		Where("slug = ? AND is_active = ?", ss.config.Subscriptions.TrialDowngradePlan, true).
+		First(&plan).Error; err != nil {
+		return fmt.Errorf("failed to fetch trial downgrade plan: %w", err)
+	}
+	if plan.ID == subscription.PlanID {
+		return ss.convertTrial(ctx, subscription)
+	}
+
+	now := time.Now()
+	trialEnd := now
+	if subscription.TrialEnd != nil && subscription.TrialEnd.Before(now) {
+		trialEnd = *subscription.TrialEnd
+	}
+	periodEnd := FirstPeriodEnd(now, &plan)
+
+	change := &models.PlanChange{
+		ID:              uuid.New(),
+		SubscriptionID:  subscription.ID,
+		OrganizationID:  subscription.OrganizationID,
+		FromPlanID:      subscription.PlanID,
+		ToPlanID:        plan.ID,
+		ChangeType:      string(PlanChangeDowngrade),
+		Status:          string(PlanChangeStatusApplied),
+		EffectiveAt:     now,
+		AppliedAt:       &now,
+		ProrationAmount: decimal.Zero,
+		Currency:        plan.Currency,
+	}
+
+	err := ss.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := cancelScheduledChanges(tx, subscription.ID); err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Subscription{}).
+			Where("id = ? AND status = ?", subscription.ID, SubscriptionStatusTrialing).
+			Updates(map[string]interface{}{
+				"plan_id":              plan.ID,
+				"status":               SubscriptionStatusActive,
+				"trial_end":            trialEnd,
+				"current_period_start": now,
+				"current_period_end":   periodEnd,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to downgrade trial: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("subscription is no longer trialing")
+		}
+
+		if err := tx.Create(change).Error; err != nil {
+			return fmt.Errorf("failed to record plan change: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	change.FromPlan = subscription.Plan
+	change.ToPlan = plan
+	subscription.PlanID = plan.ID
+	subscription.Plan = plan
+	subscription.Status = string(SubscriptionStatusActive)
+	subscription.TrialEnd = &trialEnd
+	subscription.CurrentPeriodStart = now
+	subscription.CurrentPeriodEnd = periodEnd
+	if err := ss.invoiceInAdvance(ctx, subscription); err != nil {
+		return err
+	}
+	return ss.notifyPlanChanged(ctx, change)
+}
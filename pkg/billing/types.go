@@ -17,6 +17,15 @@ const (
 	BillingCycleAnnual  BillingCycle = "annual"
 )
 
+// BillingMode represents when the base fee of a plan is invoiced. Usage is
+// always invoiced in arrears.
+type BillingMode string
+
+const (
+	BillingModeArrears BillingMode = "arrears" // Base fee and usage invoiced at the end of the period
+	BillingModeAdvance BillingMode = "advance" // Base fee invoiced at the start of the period
+)
+
 // SubscriptionStatus represents the current state of a subscription
 type SubscriptionStatus string
 
@@ -26,6 +35,49 @@ const (
 	SubscriptionStatusPastDue    SubscriptionStatus = "past_due"
 	SubscriptionStatusTrialing   SubscriptionStatus = "trialing"
 	SubscriptionStatusIncomplete SubscriptionStatus = "incomplete"
+	SubscriptionStatusPaused     SubscriptionStatus = "paused"
+)
+
+// PlanChangeType represents the direction of a plan change
+type PlanChangeType string
+
+const (
+	PlanChangeUpgrade   PlanChangeType = "upgrade"
+	PlanChangeDowngrade PlanChangeType = "downgrade"
+)
+
+// PlanChangeStatus represents the current state of a plan change
+type PlanChangeStatus string
+
+const (
+	PlanChangeStatusScheduled PlanChangeStatus = "scheduled"
+	PlanChangeStatusApplied   PlanChangeStatus = "applied"
+	PlanChangeStatusCanceled  PlanChangeStatus = "canceled"
+)
+
+// TrialExpiryAction is what happens to a trial ending without a payment method
+type TrialExpiryAction string
+
+const (
+	TrialExpirySuspend   TrialExpiryAction = "suspend"   // The subscription becomes incomplete until converted
+	TrialExpiryDowngrade TrialExpiryAction = "downgrade" // The subscription moves to Subscriptions.TrialDowngradePlan
+)
+
+// PlanVersionStatus represents the lifecycle state of a plan version
+type PlanVersionStatus string
+
+const (
+	PlanVersionDraft     PlanVersionStatus = "draft"     // Editable, not offered
+	PlanVersionPublished PlanVersionStatus = "published" // Immutable, offered while current
+	PlanVersionRetired   PlanVersionStatus = "retired"   // Immutable, no subscriptions left
+)
+
+// PriceChangeStatus represents the current state of a scheduled price change
+type PriceChangeStatus string
+
+const (
+	PriceChangeStatusScheduled PriceChangeStatus = "scheduled"
+	PriceChangeStatusCanceled  PriceChangeStatus = "canceled"
 )
 
 // InvoiceStatus represents the current state of an invoice
@@ -34,6 +86,7 @@ type InvoiceStatus string
 const (
 	InvoiceStatusDraft         InvoiceStatus = "draft"
 	InvoiceStatusOpen          InvoiceStatus = "open"
+	InvoiceStatusPastDue       InvoiceStatus = "past_due"
 	InvoiceStatusPaid          InvoiceStatus = "paid"
 	InvoiceStatusVoid          InvoiceStatus = "void"
 	InvoiceStatusUncollectible InvoiceStatus = "uncollectible"
@@ -48,6 +101,101 @@ const (
 	PaymentStatusFailed   PaymentStatus = "failed"
 	PaymentStatusRefunded PaymentStatus = "refunded"
 	PaymentStatusCanceled PaymentStatus = "canceled"
+
+	// PaymentStatusPartiallyRefunded means part of the amount was refunded
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
+)
+
+// RefundStatus represents the current state of a refund
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusSucceeded RefundStatus = "succeeded"
+	RefundStatusFailed    RefundStatus = "failed"
+)
+
+// CreditNoteStatus represents the current state of a credit note
+type CreditNoteStatus string
+
+const (
+	CreditNoteStatusIssued CreditNoteStatus = "issued"
+	CreditNoteStatusVoid   CreditNoteStatus = "void"
+)
+
+// BillingRunStatus represents the current state of a billing run
+type BillingRunStatus string
+
+const (
+	BillingRunStatusRunning             BillingRunStatus = "running"
+	BillingRunStatusCompleted           BillingRunStatus = "completed"
+	BillingRunStatusCompletedWithErrors BillingRunStatus = "completed_with_errors"
+	BillingRunStatusFailed              BillingRunStatus = "failed"
+)
+
+// BillingRunItemStatus represents the outcome of a subscription in a billing run
+type BillingRunItemStatus string
+
+const (
+	BillingRunItemStatusInvoiced        BillingRunItemStatus = "invoiced"         // Invoice created by the run
+	BillingRunItemStatusAlreadyInvoiced BillingRunItemStatus = "already_invoiced" // Period was invoiced before
+	BillingRunItemStatusFailed          BillingRunItemStatus = "failed"
+)
+
+// PayoutStatus represents the outcome of reconciling a provider payout
+type PayoutStatus string
+
+const (
+	PayoutStatusReconciled PayoutStatus = "reconciled" // Every transaction matches the internal records
+	PayoutStatusMismatched PayoutStatus = "mismatched" // At least one transaction needs review
+)
+
+// ReconciliationItemStatus represents the outcome of matching a provider
+// balance transaction against the internal records
+type ReconciliationItemStatus string
+
+const (
+	ReconciliationItemMatched        ReconciliationItemStatus = "matched"
+	ReconciliationItemUnmatched      ReconciliationItemStatus = "unmatched"       // No internal payment or refund
+	ReconciliationItemAmountMismatch ReconciliationItemStatus = "amount_mismatch" // Amounts or currencies differ
+	ReconciliationItemStatusMismatch ReconciliationItemStatus = "status_mismatch" // Internal record is not succeeded
+)
+
+// StatementEntryType represents the kind of an account statement entry
+type StatementEntryType string
+
+const (
+	StatementEntryInvoice       StatementEntryType = "invoice"
+	StatementEntryPayment       StatementEntryType = "payment"
+	StatementEntryRefund        StatementEntryType = "refund"
+	StatementEntryCreditNote    StatementEntryType = "credit_note"
+	StatementEntryCredit        StatementEntryType = "credit"         // Account credit granted
+	StatementEntryCreditExpired StatementEntryType = "credit_expired" // Unused account credit expired
+)
+
+// ForecastMethod represents how a spend forecast was computed
+type ForecastMethod string
+
+const (
+	ForecastMethodLinear   ForecastMethod = "linear"   // Linear trend of daily spend
+	ForecastMethodSeasonal ForecastMethod = "seasonal" // Linear trend with day of week factors
+)
+
+// DunningStatus represents the current state of a dunning process
+type DunningStatus string
+
+const (
+	DunningStatusActive    DunningStatus = "active"
+	DunningStatusRecovered DunningStatus = "recovered"
+	DunningStatusExhausted DunningStatus = "exhausted"
+)
+
+// DunningAction is taken on a subscription when payment retries are exhausted
+type DunningAction string
+
+const (
+	DunningActionSuspend DunningAction = "suspend"
+	DunningActionCancel  DunningAction = "cancel"
 )
 
 // OrganizationStatus represents the current state of a billing organization
@@ -86,15 +234,17 @@ const (
 	LineItemTypeCredit           LineItemType = "credit"
 	LineItemTypeTax              LineItemType = "tax"
 	LineItemTypeDiscount         LineItemType = "discount"
+	LineItemTypeProration        LineItemType = "proration"
+	LineItemTypeLateFee          LineItemType = "late_fee"
 )
 
-// PaymentProvider represents payment processing providers
-type PaymentProvider string
+// PaymentProviderName identifies a payment processing provider
+type PaymentProviderName string
 
 const (
-	PaymentProviderStripe PaymentProvider = "stripe"
-	PaymentProviderPayPal PaymentProvider = "paypal"
-	PaymentProviderManual PaymentProvider = "manual"
+	PaymentProviderStripe PaymentProviderName = "stripe"
+	PaymentProviderPayPal PaymentProviderName = "paypal"
+	PaymentProviderManual PaymentProviderName = "manual" // Collected outside the billing system
 )
 
 // CreditStatus represents the current state of a credit
@@ -107,6 +257,32 @@ const (
 	CreditStatusVoided    CreditStatus = "voided"
 )
 
+// CouponDiscountType represents how a coupon discounts a charge
+type CouponDiscountType string
+
+const (
+	CouponDiscountPercentage CouponDiscountType = "percentage"
+	CouponDiscountFixed      CouponDiscountType = "fixed"
+)
+
+// CouponDuration represents how long a redeemed coupon keeps applying
+type CouponDuration string
+
+const (
+	CouponDurationForever   CouponDuration = "forever"
+	CouponDurationOnce      CouponDuration = "once"
+	CouponDurationRepeating CouponDuration = "repeating" // For DurationInMonths
+)
+
+// RedemptionStatus represents the current state of a coupon redemption
+type RedemptionStatus string
+
+const (
+	RedemptionStatusActive  RedemptionStatus = "active"
+	RedemptionStatusExpired RedemptionStatus = "expired"
+	RedemptionStatusRemoved RedemptionStatus = "removed"
+)
+
 // Money represents a monetary amount with currency
 type Money struct {
 	Amount   decimal.Decimal
@@ -201,12 +377,70 @@ type UsageAggregation struct {
 	Metrics        map[MetricType]decimal.Decimal
 }
 
+// BudgetStatus represents the spend of an organization in the current month
+// and its forecast to the end of the month. Budget fields are nil without a
+// budget.
+type BudgetStatus struct {
+	MonthStart      time.Time        `json:"month_start"`
+	MonthEnd        time.Time        `json:"month_end"`
+	Currency        string           `json:"currency"`
+	Budget          *decimal.Decimal `json:"budget,omitempty"`
+	CurrentSpend    decimal.Decimal  `json:"current_spend"`
+	ForecastSpend   decimal.Decimal  `json:"forecast_spend"`
+	PercentUsed     *decimal.Decimal `json:"percent_used,omitempty"`
+	ForecastPercent *decimal.Decimal `json:"forecast_percent,omitempty"`
+	ForecastMethod  ForecastMethod   `json:"forecast_method"`
+	Daily           []DailySpend     `json:"daily"`
+}
+
+// DailySpend represents the observed or forecast spend of a day of the month
+type DailySpend struct {
+	Date     string          `json:"date"` // YYYY-MM-DD in the organization's timezone
+	Amount   decimal.Decimal `json:"amount"`
+	Forecast bool            `json:"forecast"`
+}
+
+// AccountStatement represents the invoices, payments, refunds and credits of
+// an organization in a period with the running balance. A positive balance is
+// owed by the organization; a negative balance is unused credit.
+type AccountStatement struct {
+	OrganizationID string           `json:"organization_id"`
+	Currency       string           `json:"currency"`
+	PeriodStart    time.Time        `json:"period_start"`
+	PeriodEnd      time.Time        `json:"period_end"`
+	OpeningBalance decimal.Decimal  `json:"opening_balance"`
+	TotalDebits    decimal.Decimal  `json:"total_debits"`
+	TotalCredits   decimal.Decimal  `json:"total_credits"`
+	ClosingBalance decimal.Decimal  `json:"closing_balance"`
+	Entries        []StatementEntry `json:"entries"`
+	GeneratedAt    time.Time        `json:"generated_at"`
+}
+
+// StatementEntry represents a transaction of an account statement
+type StatementEntry struct {
+	Date        time.Time          `json:"date"`
+	Type        StatementEntryType `json:"type"`
+	Reference   string             `json:"reference"` // Invoice or credit note number, payment or refund ID
+	Description string             `json:"description"`
+	Debit       decimal.Decimal    `json:"debit"`   // Increases the amount owed
+	Credit      decimal.Decimal    `json:"credit"`  // Decreases the amount owed
+	Balance     decimal.Decimal    `json:"balance"` // Running balance after the entry
+}
+
+// SeatSegment is a part of a billing period with a constant number of seats
+type SeatSegment struct {
+	Quantity int
+	Start    time.Time
+	End      time.Time
+}
+
 // ChargeCalculation represents the result of pricing calculation
 type ChargeCalculation struct {
 	BaseCharge      decimal.Decimal
 	UsageCharges    map[MetricType]decimal.Decimal
 	AddonCharges    decimal.Decimal
 	Subtotal        decimal.Decimal
+	Discount        decimal.Decimal
 	Credits         decimal.Decimal
 	TaxAmount       decimal.Decimal
 	Total           decimal.Decimal
@@ -223,9 +457,25 @@ type SubscriptionChange struct {
 	EffectiveDate  time.Time
 }
 
+// Entitlements are the features and limits an organization is entitled to
+// through its subscription, including during a trial
+type Entitlements struct {
+	SubscriptionID         string                 `json:"subscription_id"`
+	Plan                   string                 `json:"plan"` // Plan slug
+	PlanVersion            int                    `json:"plan_version"`
+	Status                 SubscriptionStatus     `json:"status"`
+	TrialEnd               *time.Time             `json:"trial_end,omitempty"`
+	Features               map[string]interface{} `json:"features"`
+	IncludedAPICalls       int                    `json:"included_api_calls"`
+	IncludedStorageGB      int                    `json:"included_storage_gb"`
+	IncludedDataTransferGB int                    `json:"included_data_transfer_gb"`
+	Seats                  int                    `json:"seats"`
+	MaxAdapters            int                    `json:"max_adapters"`
+}
+
 // WebhookEvent represents a payment provider webhook event
 type WebhookEvent struct {
-	Provider  PaymentProvider
+	Provider  PaymentProviderName
 	EventType string
 	EventID   string
 	Payload   map[string]interface{}
@@ -254,6 +504,15 @@ const (
 	EventInvoiceOverdue           EventType = "billing.invoice.overdue"
 	EventPaymentSucceeded         EventType = "billing.payment.succeeded"
 	EventPaymentFailed            EventType = "billing.payment.failed"
+	EventPaymentRefunded          EventType = "billing.payment.refunded"
 	EventUsageThresholdReached    EventType = "billing.usage.threshold_reached"
+	EventBudgetThresholdReached   EventType = "billing.budget.threshold_reached"
 	EventCreditApplied            EventType = "billing.credit.applied"
+	EventCouponRedeemed           EventType = "billing.coupon.redeemed"
+	EventDiscountApplied          EventType = "billing.discount.applied"
+	EventPlanChanged              EventType = "billing.subscription.plan_changed"
+	EventPlanChangeScheduled      EventType = "billing.subscription.plan_change_scheduled"
+	EventSubscriptionPaused       EventType = "billing.subscription.paused"
+	EventSubscriptionResumed      EventType = "billing.subscription.resumed"
+	EventTrialConverted           EventType = "billing.subscription.trial_converted"
 )
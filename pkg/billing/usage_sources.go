@@ -0,0 +1,370 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package billing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Click2-Run/dictamesh/pkg/billing/models"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Usage sources
+const (
+	UsageSourcePrometheus = "prometheus" // Range queries against the Prometheus HTTP API
+	UsageSourceInProcess  = "inprocess"  // Counters of this process (single instance deployments)
+)
+
+// Storage is billed in GB-months, so summing the intervals of a billing
+// period yields the average GB stored
+const (
+	bytesPerGB    = 1 << 30
+	hoursPerMonth = 730
+)
+
+// metricUnits are the units usage metrics are stored in
+var metricUnits = map[MetricType]string{
+	MetricTypeAPICalls:      "count",
+	MetricTypeStorageGB:     "GB-month",
+	MetricTypeTransferGBIn:  "GB",
+	MetricTypeTransferGBOut: "GB",
+	MetricTypeQuerySeconds:  "seconds",
+}
+
+// UsageSample is the usage of an organization during an interval
+type UsageSample struct {
+	OrganizationID uuid.UUID
+	MetricType     MetricType
+	Value          decimal.Decimal
+	PeriodStart    time.Time
+	PeriodEnd      time.Time
+}
+
+// UsageSource reports the usage recorded by a MetricsCollector
+type UsageSource interface {
+	Name() string
+
+	// Usage returns the usage of every organization for each interval of
+	// length step between start and end. Sources that keep state persist it
+	// with tx, so it is committed together with the aggregated usage.
+	Usage(ctx context.Context, tx *gorm.DB, start, end time.Time, step time.Duration) ([]UsageSample, error)
+}
+
+// PrometheusUsageSource queries usage from the Prometheus server scraping the
+// billing metrics. Prometheus handles counter resets and keeps the history
+// needed to backfill missed intervals.
+type PrometheusUsageSource struct {
+	api promv1.API
+}
+
+// NewPrometheusUsageSource creates a Prometheus usage source
+func NewPrometheusUsageSource(address string) (*PrometheusUsageSource, error) {
+	client, err := api.NewClient(api.Config{Address: address})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Prometheus client: %w", err)
+	}
+	return &PrometheusUsageSource{api: promv1.NewAPI(client)}, nil
+}
+
+// Name implements UsageSource
+func (s *PrometheusUsageSource) Name() string {
+	return UsageSourcePrometheus
+}
+
+// prometheusUsageQueries are the PromQL queries of every metric type. %[1]s
+// is the interval; results are per organization.
+var prometheusUsageQueries = []struct {
+	metricType MetricType
+	query      string
+	scale      func(value float64, step time.Duration) float64
+}{
+	{
+		metricType: MetricTypeAPICalls,
+		query:      `sum by (organization_id) (increase(dictamesh_billing_api_calls_total[%[1]s]))`,
+	},
+	{
+		metricType: MetricTypeStorageGB,
+		query:      `sum by (organization_id) (avg_over_time(dictamesh_billing_storage_bytes[%[1]s]))`,
+		scale:      storageGBMonths,
+	},
+	{
+		metricType: MetricTypeTransferGBIn,
+		query:      `sum by (organization_id) (increase(dictamesh_billing_transfer_bytes_total{direction="in"}[%[1]s]))`,
+		scale:      bytesToGB,
+	},
+	{
+		metricType: MetricTypeTransferGBOut,
+		query:      `sum by (organization_id) (increase(dictamesh_billing_transfer_bytes_total{direction="out"}[%[1]s]))`,
+		scale:      bytesToGB,
+	},
+	{
+		metricType: MetricTypeQuerySeconds,
+		query:      `sum by (organization_id) (increase(dictamesh_billing_query_duration_seconds_sum[%[1]s]))`,
+	},
+}
+
+// Usage implements UsageSource. Each point of the range query at time t
+// covers the interval ending at t.
+func (s *PrometheusUsageSource) Usage(
+	ctx context.Context,
+	_ *gorm.DB,
+	start, end time.Time,
+	step time.Duration,
+) ([]UsageSample, error) {
+	interval := model.Duration(step).String()
+	r := promv1.Range{Start: start.Add(step), End: end, Step: step}
+
+	var samples []UsageSample
+	for _, q := range prometheusUsageQueries {
+		value, _, err := s.api.QueryRange(ctx, fmt.Sprintf(q.query, interval), r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s usage: %w", q.metricType, err)
+		}
+
+		matrix, ok := value.(model.Matrix)
+		if !ok {
+			return nil, fmt.Errorf("unexpected Prometheus result type for %s usage: %s", q.metricType, value.Type())
+		}
+
+		for _, stream := range matrix {
+			orgID, err := uuid.Parse(string(stream.Metric["organization_id"]))
+			if err != nil {
+				// Series without a valid organization are not billable
+				continue
+			}
+
+			for _, point := range stream.Values {
+				v := float64(point.Value)
+				if q.scale != nil {
+					v = q.scale(v, step)
+				}
+				if v <= 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+					continue
+				}
+
+				periodEnd := point.Timestamp.Time()
+				samples = append(samples, UsageSample{
+					OrganizationID: orgID,
+					MetricType:     q.metricType,
+					Value:          decimal.NewFromFloat(v),
+					PeriodStart:    periodEnd.Add(-step),
+					PeriodEnd:      periodEnd,
+				})
+			}
+		}
+	}
+
+	return samples, nil
+}
+
+// InProcessUsageSource reads the counters of its MetricsCollector. The last
+// aggregated value of every counter is stored in dictamesh_billing_usage_counters
+// with the epoch of the process, so a restart is detected as a counter reset.
+// Usage recorded between the last run and a restart is lost, and only the
+// counters of the instance running the aggregation are read.
+type InProcessUsageSource struct {
+	collector *MetricsCollector
+	epoch     uuid.UUID
+}
+
+// NewInProcessUsageSource creates a usage source reading the counters of a
+// metrics collector
+func NewInProcessUsageSource(collector *MetricsCollector) *InProcessUsageSource {
+	return &InProcessUsageSource{
+		collector: collector,
+		epoch:     uuid.New(),
+	}
+}
+
+// Name implements UsageSource
+func (s *InProcessUsageSource) Name() string {
+	return UsageSourceInProcess
+}
+
+// usageCounterKey identifies a counter of an organization
+type usageCounterKey struct {
+	organizationID uuid.UUID
+	metricType     MetricType
+}
+
+// Usage implements UsageSource. Counter increases since the last run are
+// spread evenly over the intervals between start and end; storage is billed
+// at its current level for every interval.
+func (s *InProcessUsageSource) Usage(
+	_ context.Context,
+	tx *gorm.DB,
+	start, end time.Time,
+	step time.Duration,
+) ([]UsageSample, error) {
+	mc := s.collector
+	current := make(map[usageCounterKey]float64)
+	addAll := func(metricType MetricType, values map[uuid.UUID]float64) {
+		for orgID, v := range values {
+			current[usageCounterKey{orgID, metricType}] += v
+		}
+	}
+
+	addAll(MetricTypeAPICalls, collectByOrganization(mc.apiCallsTotal, nil, counterValue))
+	addAll(MetricTypeTransferGBIn, collectByOrganization(mc.transferBytesTotal, labelEquals("direction", "in"), counterValue))
+	addAll(MetricTypeTransferGBOut, collectByOrganization(mc.transferBytesTotal, labelEquals("direction", "out"), counterValue))
+	addAll(MetricTypeQuerySeconds, collectByOrganization(mc.queryDuration, nil, histogramSum))
+
+	// Compute increases against the stored counter values
+	var stored []models.UsageCounter
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch usage counters: %w", err)
+	}
+	previous := make(map[usageCounterKey]models.UsageCounter, len(stored))
+	for _, counter := range stored {
+		previous[usageCounterKey{counter.OrganizationID, MetricType(counter.MetricType)}] = counter
+	}
+
+	var intervals []time.Time
+	for t := start; t.Before(end); t = t.Add(step) {
+		intervals = append(intervals, t)
+	}
+
+	var samples []UsageSample
+	counters := make([]models.UsageCounter, 0, len(current))
+	for key, value := range current {
+		increase := value
+		if prev, ok := previous[key]; ok && prev.Epoch == s.epoch {
+			if delta := value - prev.Value.InexactFloat64(); delta >= 0 {
+				increase = delta
+			}
+		}
+
+		counters = append(counters, models.UsageCounter{
+			OrganizationID: key.organizationID,
+			MetricType:     string(key.metricType),
+			Epoch:          s.epoch,
+			Value:          decimal.NewFromFloat(value),
+		})
+
+		if key.metricType == MetricTypeTransferGBIn || key.metricType == MetricTypeTransferGBOut {
+			increase = bytesToGB(increase, step)
+		}
+		if increase <= 0 {
+			continue
+		}
+
+		perInterval := decimal.NewFromFloat(increase).Div(decimal.NewFromInt(int64(len(intervals))))
+		for _, periodStart := range intervals {
+			samples = append(samples, UsageSample{
+				OrganizationID: key.organizationID,
+				MetricType:     key.metricType,
+				Value:          perInterval,
+				PeriodStart:    periodStart,
+				PeriodEnd:      periodStart.Add(step),
+			})
+		}
+	}
+
+	// Storage is a level rather than a counter
+	for orgID, bytes := range collectByOrganization(mc.storageBytes, nil, gaugeValue) {
+		gbMonths := storageGBMonths(bytes, step)
+		if gbMonths <= 0 {
+			continue
+		}
+		for _, periodStart := range intervals {
+			samples = append(samples, UsageSample{
+				OrganizationID: orgID,
+				MetricType:     MetricTypeStorageGB,
+				Value:          decimal.NewFromFloat(gbMonths),
+				PeriodStart:    periodStart,
+				PeriodEnd:      periodStart.Add(step),
+			})
+		}
+	}
+
+	if len(counters) > 0 {
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&counters).Error; err != nil {
+			return nil, fmt.Errorf("failed to store usage counters: %w", err)
+		}
+	}
+
+	return samples, nil
+}
+
+// collectByOrganization sums the values of a collector per organization_id
+// label. Series with an invalid organization ID are skipped.
+func collectByOrganization(
+	collector prometheus.Collector,
+	filter func(*dto.Metric) bool,
+	value func(*dto.Metric) float64,
+) map[uuid.UUID]float64 {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(ch)
+		close(ch)
+	}()
+
+	result := make(map[uuid.UUID]float64)
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			continue
+		}
+		if filter != nil && !filter(&m) {
+			continue
+		}
+
+		orgID, err := uuid.Parse(labelValue(&m, "organization_id"))
+		if err != nil {
+			continue
+		}
+		result[orgID] += value(&m)
+	}
+	return result
+}
+
+// labelValue returns the value of a label of a metric
+func labelValue(m *dto.Metric, name string) string {
+	for _, label := range m.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
+
+// labelEquals matches metrics with a label value
+func labelEquals(name, value string) func(*dto.Metric) bool {
+	return func(m *dto.Metric) bool {
+		return labelValue(m, name) == value
+	}
+}
+
+func counterValue(m *dto.Metric) float64 {
+	return m.GetCounter().GetValue()
+}
+
+func gaugeValue(m *dto.Metric) float64 {
+	return m.GetGauge().GetValue()
+}
+
+func histogramSum(m *dto.Metric) float64 {
+	return m.GetHistogram().GetSampleSum()
+}
+
+// bytesToGB converts bytes to GB
+func bytesToGB(bytes float64, _ time.Duration) float64 {
+	return bytes / bytesPerGB
+}
+
+// storageGBMonths converts bytes stored during an interval to GB-months
+func storageGBMonths(bytes float64, step time.Duration) float64 {
+	return bytes / bytesPerGB * step.Hours() / hoursPerMonth
+}
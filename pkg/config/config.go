@@ -0,0 +1,380 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package config provides a centralized configuration loader for DictaMesh.
+// It merges configuration files, environment variables and command line flags
+// into the existing Config structs of each package, validates the result and
+// hot reloads non-critical settings (log levels, rate limits) when files change.
+//
+// Sources are applied in order of increasing precedence:
+//
+//	struct defaults < files < environment < flags
+package config
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Validator is implemented by Config structs that can validate themselves
+type Validator interface {
+	Validate() error
+}
+
+// SecretResolver resolves secret references in string settings
+// (satisfied by *secrets.Manager)
+type SecretResolver interface {
+	Resolve(ctx context.Context, value string) (string, error)
+}
+
+// Options configures a Loader
+type Options struct {
+	// Files are YAML or JSON files merged in order; later files win
+	Files []string
+
+	// EnvPrefix is prepended to environment variable names
+	// (e.g., "DICTAMESH_BILLING" -> DICTAMESH_BILLING_STRIPE_API_KEY)
+	EnvPrefix string
+
+	// Reloadable lists additional dotted paths that may be hot reloaded, on
+	// top of fields tagged reload:"true". Entries match whole path segments,
+	// so "log_level" matches "observability.log_level".
+	Reloadable []string
+
+	// WatchInterval is how often files are checked for changes (default 10s)
+	WatchInterval time.Duration
+
+	// Secrets resolves secret references after merging (optional)
+	Secrets SecretResolver
+}
+
+// Change describes the outcome of a reload
+type Change struct {
+	// Updated lists the reloadable paths that were applied
+	Updated []string
+
+	// Ignored lists changed paths that require a restart to take effect
+	Ignored []string
+}
+
+// ChangeFunc is invoked after reloadable settings were applied
+type ChangeFunc func(change Change)
+
+// Loader merges configuration sources into a Config struct
+type Loader struct {
+	opts Options
+
+	mu        sync.RWMutex
+	flags     map[string]*flagValue
+	callbacks []ChangeFunc
+
+	// state captured by Load for reloads
+	target   interface{}
+	defaults reflect.Value
+	modTimes map[string]time.Time
+}
+
+// NewLoader creates a new configuration loader
+func NewLoader(opts Options) *Loader {
+	if opts.WatchInterval <= 0 {
+		opts.WatchInterval = 10 * time.Second
+	}
+
+	return &Loader{
+		opts:     opts,
+		flags:    make(map[string]*flagValue),
+		modTimes: make(map[string]time.Time),
+	}
+}
+
+// RegisterFlags defines a flag for every setting of target on fs. Flag names
+// are dotted kebab-case paths (e.g., --stripe.api-key). Only flags explicitly
+// set on the command line override other sources.
+func (l *Loader) RegisterFlags(fs *flag.FlagSet, target interface{}) error {
+	fields, err := walk(target, false)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, f := range fields {
+		if !isLeaf(f.value.Type()) {
+			continue
+		}
+		fv := &flagValue{
+			def:    fmt.Sprint(f.value.Interface()),
+			isBool: f.value.Kind() == reflect.Bool,
+		}
+		name := flagName(f.path)
+		fs.Var(fv, name, f.usage)
+		l.flags[f.path] = fv
+	}
+	return nil
+}
+
+// Load merges all sources into target (a pointer to a Config struct whose
+// current values act as defaults), resolves secrets and validates the result
+func (l *Loader) Load(ctx context.Context, target interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config target must be a pointer to a struct, got %T", target)
+	}
+
+	// Snapshot defaults so reloads start from the same base layer
+	defaults := reflect.New(v.Elem().Type()).Elem()
+	defaults.Set(v.Elem())
+
+	if err := l.apply(ctx, target); err != nil {
+		return err
+	}
+
+	l.target = target
+	l.defaults = defaults
+	return nil
+}
+
+// OnChange registers a callback invoked after a reload applied changes
+func (l *Loader) OnChange(fn ChangeFunc) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.callbacks = append(l.callbacks, fn)
+}
+
+// RLock locks the loaded config for reading. Readers of reloadable settings
+// that do not use OnChange must hold it to avoid racing with reloads.
+func (l *Loader) RLock() {
+	l.mu.RLock()
+}
+
+// RUnlock undoes a single RLock call
+func (l *Loader) RUnlock() {
+	l.mu.RUnlock()
+}
+
+// apply merges every source into target and validates it
+func (l *Loader) apply(ctx context.Context, target interface{}) error {
+	fields, err := walk(target, true)
+	if err != nil {
+		return err
+	}
+
+	index := make(map[string]*field, len(fields))
+	for _, f := range fields {
+		index[f.path] = f
+	}
+
+	// Files
+	for _, path := range l.opts.Files {
+		if err := l.applyFile(path, index); err != nil {
+			return err
+		}
+	}
+
+	// Environment
+	for _, f := range fields {
+		if !isLeaf(f.value.Type()) {
+			continue
+		}
+		name := envName(l.opts.EnvPrefix, f.path)
+		if raw, ok := os.LookupEnv(name); ok {
+			if err := setString(f.value, raw); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", name, err)
+			}
+		}
+	}
+
+	// Flags
+	for path, fv := range l.flags {
+		f, ok := index[path]
+		if !ok || !fv.set {
+			continue
+		}
+		if err := setString(f.value, fv.raw); err != nil {
+			return fmt.Errorf("invalid value for --%s: %w", flagName(path), err)
+		}
+	}
+
+	// Secret references
+	if l.opts.Secrets != nil {
+		for _, f := range fields {
+			if f.value.Kind() != reflect.String {
+				continue
+			}
+			resolved, err := l.opts.Secrets.Resolve(ctx, f.value.String())
+			if err != nil {
+				return fmt.Errorf("failed to resolve secret for %s: %w", f.path, err)
+			}
+			f.value.SetString(resolved)
+		}
+	}
+
+	if validator, ok := target.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// applyFile merges a YAML or JSON file into the indexed fields
+func (l *Loader) applyFile(path string, index map[string]*field) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat config file %s: %w", path, err)
+	}
+	l.modTimes[path] = info.ModTime()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	var tree map[string]interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	for p, f := range index {
+		raw, ok := lookup(tree, p)
+		if !ok {
+			continue
+		}
+		if err := setValue(f.value, raw); err != nil {
+			return fmt.Errorf("invalid value for %s in %s: %w", p, path, err)
+		}
+	}
+	return nil
+}
+
+// lookup finds a dotted path in a decoded file
+func lookup(tree map[string]interface{}, path string) (interface{}, bool) {
+	var node interface{} = tree
+	for _, key := range strings.Split(path, ".") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if node, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// setValue assigns a decoded file value to v
+func setValue(v reflect.Value, raw interface{}) error {
+	if raw == nil {
+		return nil
+	}
+
+	if isLeaf(v.Type()) {
+		if items, ok := raw.([]interface{}); ok && v.Kind() == reflect.Slice {
+			strs := make([]string, len(items))
+			for i, item := range items {
+				strs[i] = fmt.Sprint(item)
+			}
+			v.Set(reflect.ValueOf(strs).Convert(v.Type()))
+			return nil
+		}
+		return setString(v, fmt.Sprint(raw))
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected a mapping, got %T", raw)
+		}
+		var fields []*field
+		walkStruct(v, "", false, false, &fields)
+		for _, f := range fields {
+			sub, ok := lookup(m, f.path)
+			if !ok {
+				continue
+			}
+			if err := setValue(f.value, sub); err != nil {
+				return fmt.Errorf("%s: %w", f.path, err)
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected a mapping, got %T", raw)
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map key type %s", v.Type().Key())
+		}
+		// Build a fresh map so the defaults snapshot is never mutated
+		fresh := reflect.MakeMapWithSize(v.Type(), len(m))
+		for k, sub := range m {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, sub); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			fresh.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), elem)
+		}
+		v.Set(fresh)
+		return nil
+
+	case reflect.Slice:
+		items, ok := raw.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected a sequence, got %T", raw)
+		}
+		fresh := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(fresh.Index(i), item); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		v.Set(fresh)
+		return nil
+	}
+
+	return fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// flagValue records a raw flag value for deferred parsing
+type flagValue struct {
+	def    string
+	raw    string
+	set    bool
+	isBool bool
+}
+
+func (f *flagValue) String() string {
+	if f == nil {
+		return ""
+	}
+	if f.set {
+		return f.raw
+	}
+	return f.def
+}
+
+func (f *flagValue) Set(s string) error {
+	f.raw = s
+	f.set = true
+	return nil
+}
+
+// IsBoolFlag allows boolean flags to be passed without a value
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// field is a leaf setting of a configuration struct
+type field struct {
+	path       string // dotted snake_case path (e.g., "stripe.api_key")
+	value      reflect.Value
+	reloadable bool
+	usage      string
+}
+
+// walk collects the settable leaves of the struct pointed to by target. When
+// clone is set, pointer sub-structs are copied before being walked so writes
+// never reach a struct shared with another config value.
+// Struct tags:
+//
+//	config:"name"    overrides the key name ("-" skips the field)
+//	reload:"true"    marks the field as safe to hot reload
+//	usage:"..."      flag help text
+func walk(target interface{}, clone bool) ([]*field, error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config target must be a pointer to a struct, got %T", target)
+	}
+
+	var fields []*field
+	walkStruct(v.Elem(), "", false, clone, &fields)
+	return fields, nil
+}
+
+func walkStruct(v reflect.Value, prefix string, reloadable, clone bool, out *[]*field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := sf.Tag.Get("config")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = snakeCase(sf.Name)
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		fieldReloadable := reloadable || sf.Tag.Get("reload") == "true"
+		fv := v.Field(i)
+
+		if isLeaf(fv.Type()) {
+			*out = append(*out, &field{
+				path:       path,
+				value:      fv,
+				reloadable: fieldReloadable,
+				usage:      sf.Tag.Get("usage"),
+			})
+			continue
+		}
+
+		switch fv.Kind() {
+		case reflect.Struct:
+			walkStruct(fv, path, fieldReloadable, clone, out)
+		case reflect.Ptr:
+			if fv.Type().Elem().Kind() != reflect.Struct {
+				continue
+			}
+			if fv.IsNil() || clone {
+				fresh := reflect.New(fv.Type().Elem())
+				if !fv.IsNil() {
+					fresh.Elem().Set(fv.Elem())
+				}
+				fv.Set(fresh)
+			}
+			walkStruct(fv.Elem(), path, fieldReloadable, clone, out)
+		default:
+			// Maps and slices of structs are only settable from files
+			*out = append(*out, &field{
+				path:       path,
+				value:      fv,
+				reloadable: fieldReloadable,
+				usage:      sf.Tag.Get("usage"),
+			})
+		}
+	}
+}
+
+// isLeaf reports whether values of t can be set from a single string
+func isLeaf(t reflect.Type) bool {
+	if t == durationType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// setString parses s into the leaf value v
+func setString(v reflect.Value, s string) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok && v.Type() != durationType {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			slice.Index(i).SetString(item)
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// snakeCase converts a Go identifier to snake_case, keeping acronyms together
+// (APIKey -> api_key, DatabaseDSN -> database_dsn)
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// envName converts a dotted path to an environment variable name
+func envName(prefix, path string) string {
+	name := strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+	if prefix != "" {
+		return prefix + "_" + name
+	}
+	return name
+}
+
+// flagName converts a dotted path to a command line flag name
+func flagName(path string) string {
+	return strings.ReplaceAll(path, "_", "-")
+}
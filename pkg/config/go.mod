@@ -0,0 +1,8 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+module github.com/click2-run/dictamesh/pkg/config
+
+go 1.21
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Watch polls the configuration files for changes until ctx is canceled and
+// reloads the configuration when any of them is modified. Only reloadable
+// settings are applied to the loaded config; other changes are reported as
+// ignored. Reload failures are passed to onError (which may be nil) and leave
+// the current configuration untouched.
+func (l *Loader) Watch(ctx context.Context, onError func(error)) {
+	ticker := time.NewTicker(l.opts.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !l.filesChanged() {
+				continue
+			}
+			if _, err := l.Reload(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Reload re-reads every source into a fresh copy of the defaults, validates
+// it and applies the reloadable settings that changed to the loaded config
+func (l *Loader) Reload(ctx context.Context) (Change, error) {
+	l.mu.Lock()
+
+	if l.target == nil {
+		l.mu.Unlock()
+		return Change{}, fmt.Errorf("config has not been loaded")
+	}
+
+	candidate := reflect.New(l.defaults.Type())
+	candidate.Elem().Set(l.defaults)
+
+	if err := l.apply(ctx, candidate.Interface()); err != nil {
+		l.mu.Unlock()
+		return Change{}, fmt.Errorf("failed to reload configuration: %w", err)
+	}
+
+	current, err := walk(l.target, false)
+	if err != nil {
+		l.mu.Unlock()
+		return Change{}, err
+	}
+	next, err := walk(candidate.Interface(), false)
+	if err != nil {
+		l.mu.Unlock()
+		return Change{}, err
+	}
+
+	nextByPath := make(map[string]*field, len(next))
+	for _, f := range next {
+		nextByPath[f.path] = f
+	}
+
+	var change Change
+	for _, f := range current {
+		n, ok := nextByPath[f.path]
+		if !ok || reflect.DeepEqual(f.value.Interface(), n.value.Interface()) {
+			continue
+		}
+
+		if f.reloadable || l.isReloadable(f.path) {
+			f.value.Set(n.value)
+			change.Updated = append(change.Updated, f.path)
+		} else {
+			change.Ignored = append(change.Ignored, f.path)
+		}
+	}
+
+	callbacks := append([]ChangeFunc(nil), l.callbacks...)
+	l.mu.Unlock()
+
+	if len(change.Updated) > 0 || len(change.Ignored) > 0 {
+		for _, fn := range callbacks {
+			fn(change)
+		}
+	}
+
+	return change, nil
+}
+
+// isReloadable reports whether path matches an entry of Options.Reloadable
+func (l *Loader) isReloadable(path string) bool {
+	padded := "." + path + "."
+	for _, entry := range l.opts.Reloadable {
+		if strings.Contains(padded, "."+entry+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// filesChanged reports whether any configuration file was modified since it
+// was last read
+func (l *Loader) filesChanged() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, path := range l.opts.Files {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(l.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
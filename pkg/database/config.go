@@ -36,7 +36,7 @@ type Config struct {
 	// Observability
 	EnableMetrics bool
 	EnableTracing bool
-	LogLevel      string
+	LogLevel      string `reload:"true"`
 }
 
 // DefaultConfig returns a production-ready default configuration
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package lineage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteJSON writes the graph as JSON
+func (g *Graph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(g); err != nil {
+		return fmt.Errorf("failed to encode lineage graph: %w", err)
+	}
+	return nil
+}
+
+// nodeShapes gives each node kind a distinct Graphviz shape
+var nodeShapes = map[NodeKind]string{
+	KindEntity:       "box",
+	KindAdapter:      "cylinder",
+	KindSync:         "ellipse",
+	KindTransform:    "hexagon",
+	KindEmbedding:    "diamond",
+	KindNotification: "note",
+	KindInvoice:      "component",
+}
+
+// WriteDOT writes the graph in Graphviz DOT format
+func (g *Graph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph lineage {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, "  node [fontname=\"Helvetica\", fontsize=10];")
+
+	for _, node := range g.Nodes {
+		shape := nodeShapes[node.Kind]
+		if shape == "" {
+			shape = "ellipse"
+		}
+
+		attrs := fmt.Sprintf("label=%s, shape=%s", dotQuote(string(node.Kind)+"\n"+node.ID), shape)
+		if node == g.Root {
+			attrs += ", style=bold"
+		}
+		fmt.Fprintf(bw, "  %s [%s];\n", dotQuote(node.Key()), attrs)
+	}
+
+	for _, edge := range g.Edges {
+		fmt.Fprintf(bw, "  %s -> %s [label=%s];\n",
+			dotQuote(edge.Upstream.Key()),
+			dotQuote(edge.Downstream.Key()),
+			dotQuote(string(edge.Relation)),
+		)
+	}
+
+	fmt.Fprintln(bw, "}")
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write lineage graph: %w", err)
+	}
+	return nil
+}
+
+// dotQuote quotes a DOT identifier
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package lineage
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+)
+
+// maxDepth bounds traversals requested over HTTP
+const maxDepth = 10
+
+// Handler serves the lineage query API:
+//
+//	GET {prefix}/{kind}/{id}?direction=both&depth=3&format=json|dot
+//
+// Graphs are those of the organization of the tenant in the request context,
+// so mount it behind tenant.Middleware with a resolver that verifies callers;
+// requests without a tenant are rejected. Mount it with http.StripPrefix, e.g.
+// mux.Handle("/api/v1/lineage/", http.StripPrefix("/api/v1/lineage", authenticated(lineage.NewHandler(svc))))
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new lineage HTTP handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if _, err := tenant.OrganizationID(r.Context()); err != nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	parts := strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		writeError(w, http.StatusNotFound, "expected /{kind}/{id}")
+		return
+	}
+	root := Node{Kind: NodeKind(parts[0]), ID: parts[1]}
+
+	opts := QueryOptions{Direction: Direction(r.URL.Query().Get("direction"))}
+	switch opts.Direction {
+	case "", Upstream, Downstream, Both:
+	default:
+		writeError(w, http.StatusBadRequest, "direction must be upstream, downstream or both")
+		return
+	}
+
+	if raw := r.URL.Query().Get("depth"); raw != "" {
+		depth, err := strconv.Atoi(raw)
+		if err != nil || depth < 1 || depth > maxDepth {
+			writeError(w, http.StatusBadRequest, "depth must be between 1 and 10")
+			return
+		}
+		opts.Depth = depth
+	}
+
+	graph, err := h.service.Query(r.Context(), root, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		_ = graph.WriteDOT(w)
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		_ = graph.WriteJSON(w)
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json or dot")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,265 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package lineage records and queries data lineage: which adapter produced
+// each catalog entity, which syncs and transforms touched it, and which
+// downstream consumers (embeddings, notifications, invoices) derived from it.
+// Lineage is scoped by organization: edges are recorded for, and queries
+// only return edges of, the tenant in the context.
+package lineage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/database/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NodeKind identifies the type of a lineage node
+type NodeKind string
+
+const (
+	KindEntity       NodeKind = "entity"
+	KindAdapter      NodeKind = "adapter"
+	KindSync         NodeKind = "sync"
+	KindTransform    NodeKind = "transform"
+	KindEmbedding    NodeKind = "embedding"
+	KindNotification NodeKind = "notification"
+	KindInvoice      NodeKind = "invoice"
+)
+
+// Relation describes how a downstream node relates to its upstream node
+type Relation string
+
+const (
+	RelationProduced    Relation = "produced"    // adapter -> entity
+	RelationSynced      Relation = "synced"      // sync -> entity
+	RelationTransformed Relation = "transformed" // entity -> entity, transform -> entity
+	RelationDerived     Relation = "derived"     // entity -> consumer
+)
+
+// Node is a vertex of the lineage graph
+type Node struct {
+	Kind NodeKind `json:"kind"`
+	ID   string   `json:"id"`
+}
+
+// Key returns a unique string for the node
+func (n Node) Key() string {
+	return string(n.Kind) + ":" + n.ID
+}
+
+// Entity returns the node of a catalog entity
+func Entity(catalogID string) Node {
+	return Node{Kind: KindEntity, ID: catalogID}
+}
+
+// Edge is a directed lineage edge from Upstream to Downstream
+type Edge struct {
+	Upstream        Node                   `json:"upstream"`
+	Downstream      Node                   `json:"downstream"`
+	Relation        Relation               `json:"relation"`
+	RunID           string                 `json:"run_id,omitempty"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	OccurrenceCount int                    `json:"occurrence_count,omitempty"`
+	FirstSeenAt     time.Time              `json:"first_seen_at,omitempty"`
+	LastSeenAt      time.Time              `json:"last_seen_at,omitempty"`
+}
+
+// Service records and queries lineage
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService creates a new lineage service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Record upserts a lineage edge for the organization of the tenant in ctx.
+// Repeated observations of the same edge update its last run, metadata and
+// occurrence count.
+func (s *Service) Record(ctx context.Context, edge Edge) error {
+	if edge.Upstream.ID == "" || edge.Downstream.ID == "" {
+		return fmt.Errorf("lineage edge requires upstream and downstream IDs")
+	}
+
+	// Entity-to-entity transformations are also kept in DataLineage
+	if edge.Upstream.Kind == KindEntity && edge.Downstream.Kind == KindEntity {
+		transformationType, _ := edge.Metadata["transformation_type"].(string)
+		logic, _ := edge.Metadata["transformation_logic"].(string)
+		return s.RecordTransform(ctx, edge.Upstream.ID, edge.Downstream.ID, transformationType, logic)
+	}
+
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to record lineage: %w", err)
+	}
+	return s.upsert(s.db.WithContext(ctx), organizationID, edge)
+}
+
+// upsert records an edge of an organization in the lineage edges table
+func (s *Service) upsert(db *gorm.DB, organizationID string, edge Edge) error {
+	now := time.Now()
+	row := models.LineageEdge{
+		OrganizationID:  organizationID,
+		UpstreamKind:    string(edge.Upstream.Kind),
+		UpstreamID:      edge.Upstream.ID,
+		DownstreamKind:  string(edge.Downstream.Kind),
+		DownstreamID:    edge.Downstream.ID,
+		Relation:        string(edge.Relation),
+		Metadata:        models.JSONB(edge.Metadata),
+		OccurrenceCount: 1,
+		FirstSeenAt:     now,
+		LastSeenAt:      now,
+	}
+	if edge.RunID != "" {
+		row.LastRunID = &edge.RunID
+	}
+
+	result := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "organization_id"},
+			{Name: "upstream_kind"}, {Name: "upstream_id"},
+			{Name: "downstream_kind"}, {Name: "downstream_id"},
+			{Name: "relation"},
+		},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"last_run_id":      gorm.Expr("COALESCE(EXCLUDED.last_run_id, dictamesh_lineage_edges.last_run_id)"),
+			"metadata":         gorm.Expr("COALESCE(EXCLUDED.metadata, dictamesh_lineage_edges.metadata)"),
+			"occurrence_count": gorm.Expr("dictamesh_lineage_edges.occurrence_count + 1"),
+			"last_seen_at":     now,
+		}),
+	}).Create(&row)
+	if result.Error != nil {
+		return fmt.Errorf("failed to record lineage: %w", result.Error)
+	}
+	return nil
+}
+
+// RecordProduced records that an adapter produced a catalog entity
+func (s *Service) RecordProduced(ctx context.Context, catalogID, adapter string) error {
+	return s.Record(ctx, Edge{
+		Upstream:   Node{Kind: KindAdapter, ID: adapter},
+		Downstream: Entity(catalogID),
+		Relation:   RelationProduced,
+	})
+}
+
+// RecordSync records that a sync run touched a catalog entity
+func (s *Service) RecordSync(ctx context.Context, catalogID, syncID string, metadata map[string]interface{}) error {
+	return s.Record(ctx, Edge{
+		Upstream:   Node{Kind: KindSync, ID: syncID},
+		Downstream: Entity(catalogID),
+		Relation:   RelationSynced,
+		RunID:      syncID,
+		Metadata:   metadata,
+	})
+}
+
+// RecordDerived records that a downstream consumer (embedding, notification,
+// invoice, ...) was derived from a catalog entity
+func (s *Service) RecordDerived(ctx context.Context, catalogID string, consumer Node, metadata map[string]interface{}) error {
+	return s.Record(ctx, Edge{
+		Upstream:   Entity(catalogID),
+		Downstream: consumer,
+		Relation:   RelationDerived,
+		Metadata:   metadata,
+	})
+}
+
+// RecordDerivation records that the consumer of the given kind and ID was
+// derived from each of the catalog entities, for an organization. It lets
+// services outside a tenant context, like the notification rule engine and
+// billing, record lineage through a narrow interface of their own.
+func (s *Service) RecordDerivation(ctx context.Context, organizationID, consumerKind, consumerID string, catalogIDs []string) error {
+	ctx = tenant.WithOrganization(ctx, organizationID)
+	consumer := Node{Kind: NodeKind(consumerKind), ID: consumerID}
+	for _, catalogID := range catalogIDs {
+		if err := s.RecordDerived(ctx, catalogID, consumer, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordTransform records an entity-to-entity transformation for the
+// organization of the tenant in ctx. It is kept in DataLineage as well as in
+// the organization's lineage edges.
+func (s *Service) RecordTransform(ctx context.Context, upstreamCatalogID, downstreamCatalogID, transformationType, logic string) error {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to record transformation lineage: %w", err)
+	}
+
+	edge := Edge{
+		Upstream:   Entity(upstreamCatalogID),
+		Downstream: Entity(downstreamCatalogID),
+		Relation:   RelationTransformed,
+	}
+	if transformationType != "" || logic != "" {
+		edge.Metadata = make(map[string]interface{})
+		if transformationType != "" {
+			edge.Metadata["transformation_type"] = transformationType
+		}
+		if logic != "" {
+			edge.Metadata["transformation_logic"] = logic
+		}
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.upsert(tx, organizationID, edge); err != nil {
+			return err
+		}
+
+		query := tx.Where("upstream_catalog_id = ? AND downstream_catalog_id = ?", upstreamCatalogID, downstreamCatalogID)
+		if transformationType != "" {
+			query = query.Where("transformation_type = ?", transformationType)
+		} else {
+			query = query.Where("transformation_type IS NULL")
+		}
+
+		now := time.Now()
+
+		var existing models.DataLineage
+		err := query.First(&existing).Error
+		if err == nil {
+			updates := map[string]interface{}{
+				"last_flow_at":     now,
+				"data_flow_active": true,
+			}
+			if logic != "" {
+				updates["transformation_logic"] = logic
+			}
+			if err := tx.Model(&existing).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update transformation lineage: %w", err)
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to find transformation lineage: %w", err)
+		}
+
+		row := models.DataLineage{
+			UpstreamCatalogID:   upstreamCatalogID,
+			DownstreamCatalogID: downstreamCatalogID,
+			DataFlowActive:      true,
+			LastFlowAt:          &now,
+		}
+		if transformationType != "" {
+			row.TransformationType = &transformationType
+		}
+		if logic != "" {
+			row.TransformationLogic = &logic
+		}
+		if err := tx.Create(&row).Error; err != nil {
+			return fmt.Errorf("failed to record transformation lineage: %w", err)
+		}
+		return nil
+	})
+}
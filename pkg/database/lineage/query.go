@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package lineage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/click2-run/dictamesh/pkg/database/models"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+)
+
+// Direction selects which side of a node is traversed
+type Direction string
+
+const (
+	Upstream   Direction = "upstream"
+	Downstream Direction = "downstream"
+	Both       Direction = "both"
+)
+
+// QueryOptions bounds a lineage traversal
+type QueryOptions struct {
+	Direction Direction
+	Depth     int // Maximum number of hops (default 3)
+	MaxNodes  int // Maximum number of nodes returned (default 500)
+}
+
+// Graph is a lineage subgraph
+type Graph struct {
+	Root      Node   `json:"root"`
+	Nodes     []Node `json:"nodes"`
+	Edges     []Edge `json:"edges"`
+	Truncated bool   `json:"truncated"`
+}
+
+// Query traverses the lineage graph of the organization of the tenant in ctx
+// from root breadth-first. Edges of other organizations are never returned.
+func (s *Service) Query(ctx context.Context, root Node, opts QueryOptions) (*Graph, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Direction == "" {
+		opts.Direction = Both
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = 3
+	}
+	if opts.MaxNodes <= 0 {
+		opts.MaxNodes = 500
+	}
+
+	graph := &Graph{Root: root, Nodes: []Node{root}}
+	seenNodes := map[string]bool{root.Key(): true}
+	seenEdges := make(map[string]bool)
+
+	directions := []Direction{opts.Direction}
+	if opts.Direction == Both {
+		directions = []Direction{Upstream, Downstream}
+	}
+
+	for _, direction := range directions {
+		frontier := []Node{root}
+
+		for depth := 0; depth < opts.Depth && len(frontier) > 0; depth++ {
+			edges, err := s.neighbors(ctx, organizationID, frontier, direction)
+			if err != nil {
+				return nil, err
+			}
+
+			var next []Node
+			for _, edge := range edges {
+				edgeKey := edge.Upstream.Key() + ">" + edge.Downstream.Key() + ":" + string(edge.Relation)
+				if seenEdges[edgeKey] {
+					continue
+				}
+
+				node := edge.Downstream
+				if direction == Upstream {
+					node = edge.Upstream
+				}
+				if !seenNodes[node.Key()] {
+					if len(graph.Nodes) >= opts.MaxNodes {
+						graph.Truncated = true
+						continue
+					}
+					seenNodes[node.Key()] = true
+					graph.Nodes = append(graph.Nodes, node)
+					next = append(next, node)
+				}
+
+				seenEdges[edgeKey] = true
+				graph.Edges = append(graph.Edges, edge)
+			}
+
+			frontier = next
+		}
+	}
+
+	return graph, nil
+}
+
+// neighbors returns the edges of an organization adjacent to the frontier in
+// one direction
+func (s *Service) neighbors(ctx context.Context, organizationID string, frontier []Node, direction Direction) ([]Edge, error) {
+	var edges []Edge
+
+	// One query per node kind
+	byKind := make(map[NodeKind][]string)
+	for _, node := range frontier {
+		byKind[node.Kind] = append(byKind[node.Kind], node.ID)
+	}
+
+	kindColumn, idColumn := "downstream_kind", "downstream_id"
+	if direction == Downstream {
+		kindColumn, idColumn = "upstream_kind", "upstream_id"
+	}
+
+	for kind, ids := range byKind {
+		var rows []models.LineageEdge
+		err := s.db.WithContext(ctx).
+			Where("organization_id = ?", organizationID).
+			Where(kindColumn+" = ? AND "+idColumn+" IN ?", string(kind), ids).
+			Find(&rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to query lineage edges: %w", err)
+		}
+		for _, row := range rows {
+			edges = append(edges, edgeFromRow(row))
+		}
+	}
+
+	return edges, nil
+}
+
+func edgeFromRow(row models.LineageEdge) Edge {
+	edge := Edge{
+		Upstream:        Node{Kind: NodeKind(row.UpstreamKind), ID: row.UpstreamID},
+		Downstream:      Node{Kind: NodeKind(row.DownstreamKind), ID: row.DownstreamID},
+		Relation:        Relation(row.Relation),
+		Metadata:        row.Metadata,
+		OccurrenceCount: row.OccurrenceCount,
+		FirstSeenAt:     row.FirstSeenAt,
+		LastSeenAt:      row.LastSeenAt,
+	}
+	if row.LastRunID != nil {
+		edge.RunID = *row.LastRunID
+	}
+	return edge
+}
@@ -0,0 +1,30 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Scheduler Schema
+-- Run history of periodic jobs executed by pkg/scheduler
+
+CREATE TABLE IF NOT EXISTS dictamesh_scheduler_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_name VARCHAR(255) NOT NULL,
+    instance VARCHAR(255) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    trigger VARCHAR(50) NOT NULL DEFAULT 'schedule',
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    error TEXT,
+
+    CONSTRAINT valid_run_status CHECK (status IN ('succeeded', 'failed')),
+    CONSTRAINT valid_run_trigger CHECK (trigger IN ('schedule', 'manual'))
+);
+
+CREATE INDEX idx_dictamesh_scheduler_runs_job
+    ON dictamesh_scheduler_runs(job_name, started_at DESC);
+CREATE INDEX idx_dictamesh_scheduler_runs_failed
+    ON dictamesh_scheduler_runs(job_name, started_at DESC) WHERE status = 'failed';
+CREATE INDEX idx_dictamesh_scheduler_runs_started
+    ON dictamesh_scheduler_runs(started_at);
+
+COMMENT ON TABLE dictamesh_scheduler_runs IS
+    'DictaMesh: Run history of scheduled jobs';
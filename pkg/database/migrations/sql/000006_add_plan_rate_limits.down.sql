@@ -0,0 +1,9 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove plan rate limits
+
+UPDATE dictamesh_billing_subscription_plans
+SET features = features - 'rate_limit_rps' - 'rate_limit_burst',
+    updated_at = NOW()
+WHERE features ? 'rate_limit_rps' OR features ? 'rate_limit_burst';
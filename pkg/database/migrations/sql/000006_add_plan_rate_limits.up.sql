@@ -0,0 +1,24 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - API rate limits per subscription plan
+-- Limits are stored as plan features and enforced by pkg/gateway/ratelimit
+
+UPDATE dictamesh_billing_subscription_plans
+SET features = COALESCE(features, '{}'::jsonb) || jsonb_build_object(
+        'rate_limit_rps', CASE slug
+            WHEN 'free' THEN 5
+            WHEN 'starter' THEN 50
+            WHEN 'professional' THEN 200
+            WHEN 'enterprise' THEN 1000
+        END,
+        'rate_limit_burst', CASE slug
+            WHEN 'free' THEN 20
+            WHEN 'starter' THEN 100
+            WHEN 'professional' THEN 400
+            WHEN 'enterprise' THEN 2000
+        END
+    ),
+    updated_at = NOW()
+WHERE slug IN ('free', 'starter', 'professional', 'enterprise')
+  AND NOT (COALESCE(features, '{}'::jsonb) ? 'rate_limit_rps');
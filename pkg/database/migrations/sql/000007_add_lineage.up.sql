@@ -0,0 +1,43 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Data Lineage
+-- Generic lineage edges between catalog entities and the adapters, syncs,
+-- transforms and downstream consumers (embeddings, notifications, invoices)
+-- around them. Entity-to-entity transformations stay in dictamesh_data_lineage.
+
+CREATE TABLE IF NOT EXISTS dictamesh_lineage_edges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- Upstream node
+    upstream_kind VARCHAR(50) NOT NULL,
+    upstream_id VARCHAR(255) NOT NULL,
+
+    -- Downstream node
+    downstream_kind VARCHAR(50) NOT NULL,
+    downstream_id VARCHAR(255) NOT NULL,
+
+    -- produced | synced | transformed | derived
+    relation VARCHAR(50) NOT NULL,
+
+    -- Observability
+    last_run_id VARCHAR(255),
+    metadata JSONB,
+    occurrence_count INTEGER NOT NULL DEFAULT 1,
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT valid_lineage_relation CHECK (relation IN ('produced', 'synced', 'transformed', 'derived'))
+);
+
+CREATE UNIQUE INDEX idx_dictamesh_lineage_edge
+    ON dictamesh_lineage_edges(upstream_kind, upstream_id, downstream_kind, downstream_id, relation);
+CREATE INDEX idx_dictamesh_lineage_edge_upstream
+    ON dictamesh_lineage_edges(upstream_kind, upstream_id);
+CREATE INDEX idx_dictamesh_lineage_edge_downstream
+    ON dictamesh_lineage_edges(downstream_kind, downstream_id);
+CREATE INDEX idx_dictamesh_lineage_edge_last_seen
+    ON dictamesh_lineage_edges(last_seen_at);
+
+COMMENT ON TABLE dictamesh_lineage_edges IS
+    'DictaMesh: Lineage of catalog entities across adapters, syncs and downstream consumers';
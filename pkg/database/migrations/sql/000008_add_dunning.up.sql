@@ -0,0 +1,43 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Dunning
+-- Retry state of invoices whose payment failed, driven by billing.DunningService
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_dunning_states (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES dictamesh_billing_organizations(id) ON DELETE CASCADE,
+    invoice_id UUID NOT NULL REFERENCES dictamesh_billing_invoices(id) ON DELETE CASCADE,
+    subscription_id UUID REFERENCES dictamesh_billing_subscriptions(id) ON DELETE SET NULL,
+
+    -- Retry progress
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    attempt_count INT NOT NULL DEFAULT 0,
+    next_retry_at TIMESTAMPTZ,
+    last_retry_at TIMESTAMPTZ,
+
+    -- Last failure
+    last_payment_id UUID REFERENCES dictamesh_billing_payments(id) ON DELETE SET NULL,
+    last_failure_code VARCHAR(50),
+    last_failure_message TEXT,
+
+    -- Outcome
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ,
+    resolution VARCHAR(20),
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT uq_dunning_invoice UNIQUE (invoice_id),
+    CONSTRAINT chk_dunning_status CHECK (status IN ('active', 'recovered', 'exhausted')),
+    CONSTRAINT chk_dunning_resolution CHECK (resolution IS NULL OR resolution IN ('paid', 'suspended', 'canceled'))
+);
+
+CREATE INDEX idx_dictamesh_billing_dunning_org ON dictamesh_billing_dunning_states(organization_id);
+CREATE INDEX idx_dictamesh_billing_dunning_due
+    ON dictamesh_billing_dunning_states(next_retry_at) WHERE status = 'active';
+
+COMMENT ON TABLE dictamesh_billing_dunning_states IS
+    'DictaMesh: Dunning state and payment retry schedule of unpaid invoices';
@@ -0,0 +1,8 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove coupons and promotion codes
+
+DROP TABLE IF EXISTS dictamesh_billing_coupon_redemptions CASCADE;
+DROP TABLE IF EXISTS dictamesh_billing_promotion_codes CASCADE;
+DROP TABLE IF EXISTS dictamesh_billing_coupons CASCADE;
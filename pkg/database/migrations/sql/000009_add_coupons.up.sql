@@ -0,0 +1,98 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Coupons and promotion codes
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_coupons (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+
+    -- Discount
+    discount_type VARCHAR(20) NOT NULL,
+    percent_off DECIMAL(5,2) DEFAULT 0,
+    amount_off DECIMAL(12,2) DEFAULT 0,
+    currency VARCHAR(3),
+
+    -- Duration
+    duration VARCHAR(20) NOT NULL,
+    duration_in_months INT,
+
+    -- Redemption limits
+    max_redemptions INT,
+    times_redeemed INT NOT NULL DEFAULT 0,
+    redeem_by TIMESTAMPTZ,
+
+    -- Status
+    is_active BOOLEAN NOT NULL DEFAULT true,
+
+    -- Metadata
+    metadata JSONB,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_coupon_discount_type CHECK (discount_type IN ('percentage', 'fixed')),
+    CONSTRAINT chk_coupon_percent_off CHECK (percent_off >= 0 AND percent_off <= 100),
+    CONSTRAINT chk_coupon_amount_off CHECK (amount_off >= 0),
+    CONSTRAINT chk_coupon_fixed_currency CHECK (discount_type <> 'fixed' OR currency IS NOT NULL),
+    CONSTRAINT chk_coupon_duration CHECK (duration IN ('forever', 'once', 'repeating')),
+    CONSTRAINT chk_coupon_duration_months CHECK (duration <> 'repeating' OR duration_in_months > 0)
+);
+
+COMMENT ON TABLE dictamesh_billing_coupons IS 'DictaMesh: Reusable percentage and fixed amount discounts';
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_promotion_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    coupon_id UUID NOT NULL REFERENCES dictamesh_billing_coupons(id) ON DELETE CASCADE,
+    code VARCHAR(50) NOT NULL,
+
+    -- Restrictions
+    organization_id UUID REFERENCES dictamesh_billing_organizations(id) ON DELETE CASCADE,
+    max_redemptions INT,
+    times_redeemed INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+
+    -- Status
+    is_active BOOLEAN NOT NULL DEFAULT true,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT uq_promotion_code UNIQUE (code)
+);
+
+CREATE INDEX idx_dictamesh_billing_promo_coupon ON dictamesh_billing_promotion_codes(coupon_id);
+
+COMMENT ON TABLE dictamesh_billing_promotion_codes IS 'DictaMesh: Customer-facing codes that redeem coupons';
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_coupon_redemptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    coupon_id UUID NOT NULL REFERENCES dictamesh_billing_coupons(id) ON DELETE RESTRICT,
+    promotion_code_id UUID REFERENCES dictamesh_billing_promotion_codes(id) ON DELETE SET NULL,
+    organization_id UUID NOT NULL REFERENCES dictamesh_billing_organizations(id) ON DELETE CASCADE,
+    subscription_id UUID NOT NULL REFERENCES dictamesh_billing_subscriptions(id) ON DELETE CASCADE,
+
+    -- Validity
+    redeemed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ends_at TIMESTAMPTZ,
+    periods_applied INT NOT NULL DEFAULT 0,
+
+    -- Status
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_redemption_status CHECK (status IN ('active', 'expired', 'removed'))
+);
+
+CREATE INDEX idx_dictamesh_billing_redemption_coupon ON dictamesh_billing_coupon_redemptions(coupon_id);
+CREATE INDEX idx_dictamesh_billing_redemption_org ON dictamesh_billing_coupon_redemptions(organization_id);
+-- A subscription carries at most one active discount
+CREATE UNIQUE INDEX idx_dictamesh_billing_redemption_active
+    ON dictamesh_billing_coupon_redemptions(subscription_id) WHERE status = 'active';
+
+COMMENT ON TABLE dictamesh_billing_coupon_redemptions IS 'DictaMesh: Coupons applied to subscriptions';
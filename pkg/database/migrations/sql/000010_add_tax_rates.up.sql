@@ -0,0 +1,32 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Per-jurisdiction tax rates
+-- Used by the "table" tax provider; a state of '' applies to the whole country
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_tax_rates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    country VARCHAR(2) NOT NULL,
+    state VARCHAR(100) NOT NULL DEFAULT '',
+
+    -- Rate
+    tax_type VARCHAR(20) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    rate DECIMAL(7,6) NOT NULL,
+
+    -- Status
+    is_active BOOLEAN NOT NULL DEFAULT true,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT uq_tax_rate UNIQUE (country, state, tax_type),
+    CONSTRAINT chk_tax_rate CHECK (rate >= 0 AND rate < 1),
+    CONSTRAINT chk_tax_type CHECK (tax_type IN ('vat', 'gst', 'hst', 'pst', 'qst', 'sales_tax'))
+);
+
+CREATE INDEX idx_dictamesh_billing_tax_rates_jurisdiction
+    ON dictamesh_billing_tax_rates(country, state) WHERE is_active = true;
+
+COMMENT ON TABLE dictamesh_billing_tax_rates IS 'DictaMesh: Tax rates per country and state for invoice taxation';
@@ -0,0 +1,24 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove subscription lifecycle
+
+DROP TABLE IF EXISTS dictamesh_billing_plan_changes CASCADE;
+
+DROP INDEX IF EXISTS idx_dictamesh_billing_sub_resumes;
+
+ALTER TABLE dictamesh_billing_invoice_line_items
+    DROP CONSTRAINT IF EXISTS chk_item_type,
+    ADD CONSTRAINT chk_item_type CHECK (item_type IN (
+        'subscription_base', 'usage_api_calls', 'usage_storage', 'usage_transfer',
+        'addon_seats', 'addon_support', 'credit', 'tax', 'discount'
+    )) NOT VALID;
+
+UPDATE dictamesh_billing_subscriptions SET status = 'active' WHERE status = 'paused';
+
+ALTER TABLE dictamesh_billing_subscriptions
+    DROP COLUMN IF EXISTS resumes_at,
+    DROP COLUMN IF EXISTS paused_at,
+    DROP CONSTRAINT IF EXISTS chk_subscription_status,
+    ADD CONSTRAINT chk_subscription_status
+        CHECK (status IN ('active', 'canceled', 'past_due', 'trialing', 'incomplete'));
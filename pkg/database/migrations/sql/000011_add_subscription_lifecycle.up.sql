@@ -0,0 +1,59 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Subscription lifecycle
+-- Pausing subscriptions and plan change history, driven by billing.SubscriptionService
+
+ALTER TABLE dictamesh_billing_subscriptions
+    DROP CONSTRAINT IF EXISTS chk_subscription_status,
+    ADD CONSTRAINT chk_subscription_status
+        CHECK (status IN ('active', 'canceled', 'past_due', 'trialing', 'incomplete', 'paused')),
+    ADD COLUMN IF NOT EXISTS paused_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS resumes_at TIMESTAMPTZ;
+
+ALTER TABLE dictamesh_billing_invoice_line_items
+    DROP CONSTRAINT IF EXISTS chk_item_type,
+    ADD CONSTRAINT chk_item_type CHECK (item_type IN (
+        'subscription_base', 'usage_api_calls', 'usage_storage', 'usage_transfer',
+        'addon_seats', 'addon_support', 'credit', 'tax', 'discount', 'proration'
+    ));
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_billing_sub_resumes
+    ON dictamesh_billing_subscriptions(resumes_at) WHERE status = 'paused';
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_plan_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES dictamesh_billing_subscriptions(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES dictamesh_billing_organizations(id) ON DELETE CASCADE,
+    from_plan_id UUID NOT NULL REFERENCES dictamesh_billing_subscription_plans(id),
+    to_plan_id UUID NOT NULL REFERENCES dictamesh_billing_subscription_plans(id),
+
+    -- Change details
+    change_type VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    effective_at TIMESTAMPTZ NOT NULL,
+    applied_at TIMESTAMPTZ,
+
+    -- Proration of immediate changes
+    proration_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    proration_invoice_id UUID REFERENCES dictamesh_billing_invoices(id) ON DELETE SET NULL,
+    proration_credit_id UUID REFERENCES dictamesh_billing_credits(id) ON DELETE SET NULL,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_plan_change_type CHECK (change_type IN ('upgrade', 'downgrade')),
+    CONSTRAINT chk_plan_change_status CHECK (status IN ('scheduled', 'applied', 'canceled'))
+);
+
+CREATE INDEX idx_dictamesh_billing_plan_change_sub ON dictamesh_billing_plan_changes(subscription_id);
+CREATE INDEX idx_dictamesh_billing_plan_change_org ON dictamesh_billing_plan_changes(organization_id);
+CREATE INDEX idx_dictamesh_billing_plan_change_due
+    ON dictamesh_billing_plan_changes(effective_at) WHERE status = 'scheduled';
+CREATE UNIQUE INDEX idx_dictamesh_billing_plan_change_scheduled
+    ON dictamesh_billing_plan_changes(subscription_id) WHERE status = 'scheduled';
+
+COMMENT ON TABLE dictamesh_billing_plan_changes IS
+    'DictaMesh: Immediate and scheduled plan changes of subscriptions';
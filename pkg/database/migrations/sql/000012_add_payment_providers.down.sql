@@ -0,0 +1,10 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove payment providers
+
+DROP INDEX IF EXISTS idx_dictamesh_billing_org_payment_provider;
+
+ALTER TABLE dictamesh_billing_organizations
+    DROP CONSTRAINT IF EXISTS chk_org_payment_provider,
+    DROP COLUMN IF EXISTS payment_provider;
@@ -0,0 +1,15 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Payment providers
+-- Per-organization selection of the provider charging its invoices
+
+ALTER TABLE dictamesh_billing_organizations
+    ADD COLUMN IF NOT EXISTS payment_provider VARCHAR(20) NOT NULL DEFAULT 'stripe',
+    ADD CONSTRAINT chk_org_payment_provider CHECK (payment_provider ~ '^[a-z0-9_]+$');
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_billing_org_payment_provider
+    ON dictamesh_billing_organizations(payment_provider);
+
+COMMENT ON COLUMN dictamesh_billing_organizations.payment_provider IS
+    'DictaMesh: Payment provider charging the invoices of the organization (stripe, paypal, manual, ...)';
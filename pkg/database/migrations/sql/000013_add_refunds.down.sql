@@ -0,0 +1,18 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove refunds and credit notes
+
+DROP TABLE IF EXISTS dictamesh_billing_refunds CASCADE;
+DROP TABLE IF EXISTS dictamesh_billing_credit_notes CASCADE;
+
+ALTER TABLE dictamesh_billing_invoices
+    DROP COLUMN IF EXISTS amount_credited;
+
+UPDATE dictamesh_billing_payments SET status = 'refunded' WHERE status = 'partially_refunded';
+
+ALTER TABLE dictamesh_billing_payments
+    DROP COLUMN IF EXISTS amount_refunded,
+    DROP CONSTRAINT IF EXISTS chk_payment_status,
+    ADD CONSTRAINT chk_payment_status
+        CHECK (status IN ('pending', 'succeeded', 'failed', 'refunded', 'canceled'));
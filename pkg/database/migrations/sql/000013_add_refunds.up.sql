@@ -0,0 +1,91 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Refunds and credit notes
+-- Partial refund accounting, driven by billing.PaymentService
+
+ALTER TABLE dictamesh_billing_payments
+    DROP CONSTRAINT IF EXISTS chk_payment_status,
+    ADD CONSTRAINT chk_payment_status
+        CHECK (status IN ('pending', 'succeeded', 'failed', 'refunded', 'partially_refunded', 'canceled')),
+    ADD COLUMN IF NOT EXISTS amount_refunded DECIMAL(12,2) NOT NULL DEFAULT 0;
+
+ALTER TABLE dictamesh_billing_invoices
+    ADD COLUMN IF NOT EXISTS amount_credited DECIMAL(12,2) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_credit_notes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES dictamesh_billing_organizations(id) ON DELETE CASCADE,
+    invoice_id UUID NOT NULL REFERENCES dictamesh_billing_invoices(id) ON DELETE CASCADE,
+    refund_id UUID,
+
+    -- Credit note identification, numbered separately from invoices
+    credit_note_number VARCHAR(50) NOT NULL UNIQUE,
+
+    -- Amounts
+    total_amount DECIMAL(12,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+
+    -- Reason
+    reason TEXT,
+
+    -- Status
+    status VARCHAR(20) NOT NULL DEFAULT 'issued',
+    issued_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_credit_note_status CHECK (status IN ('issued', 'void')),
+    CONSTRAINT chk_credit_note_amount CHECK (total_amount > 0)
+);
+
+CREATE INDEX idx_dictamesh_billing_credit_note_org ON dictamesh_billing_credit_notes(organization_id);
+CREATE INDEX idx_dictamesh_billing_credit_note_invoice ON dictamesh_billing_credit_notes(invoice_id);
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_refunds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payment_id UUID NOT NULL REFERENCES dictamesh_billing_payments(id) ON DELETE CASCADE,
+    invoice_id UUID REFERENCES dictamesh_billing_invoices(id) ON DELETE SET NULL,
+    organization_id UUID NOT NULL REFERENCES dictamesh_billing_organizations(id) ON DELETE CASCADE,
+    credit_note_id UUID REFERENCES dictamesh_billing_credit_notes(id) ON DELETE SET NULL,
+
+    -- Refund details
+    amount DECIMAL(12,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    reason TEXT,
+
+    -- Status
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+
+    -- Provider details
+    provider VARCHAR(20) NOT NULL,
+    provider_refund_id VARCHAR(255),
+
+    -- Error handling
+    failure_message TEXT,
+
+    -- Timestamps
+    refunded_at TIMESTAMPTZ,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_refund_status CHECK (status IN ('pending', 'succeeded', 'failed')),
+    CONSTRAINT chk_refund_amount CHECK (amount > 0)
+);
+
+CREATE INDEX idx_dictamesh_billing_refund_payment ON dictamesh_billing_refunds(payment_id);
+CREATE INDEX idx_dictamesh_billing_refund_org ON dictamesh_billing_refunds(organization_id);
+CREATE INDEX idx_dictamesh_billing_refund_provider ON dictamesh_billing_refunds(provider, provider_refund_id);
+
+ALTER TABLE dictamesh_billing_credit_notes
+    ADD CONSTRAINT fk_credit_note_refund
+        FOREIGN KEY (refund_id) REFERENCES dictamesh_billing_refunds(id) ON DELETE SET NULL;
+
+COMMENT ON TABLE dictamesh_billing_refunds IS
+    'DictaMesh: Full and partial refunds of payments';
+COMMENT ON TABLE dictamesh_billing_credit_notes IS
+    'DictaMesh: Credit notes reducing the amount billed on invoices';
@@ -0,0 +1,13 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove credit note line items
+
+DROP TABLE IF EXISTS dictamesh_billing_credit_note_line_items CASCADE;
+
+ALTER TABLE dictamesh_billing_credit_notes
+    DROP COLUMN IF EXISTS pdf_generated_at,
+    DROP COLUMN IF EXISTS pdf_url,
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS subtotal,
+    DROP COLUMN IF EXISTS credit_id;
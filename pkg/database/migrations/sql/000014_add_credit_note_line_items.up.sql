@@ -0,0 +1,38 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Credit note line items
+-- Itemized credit notes credited to future invoices, driven by billing.CreditNoteService
+
+ALTER TABLE dictamesh_billing_credit_notes
+    ADD COLUMN IF NOT EXISTS credit_id UUID REFERENCES dictamesh_billing_credits(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS subtotal DECIMAL(12,2),
+    ADD COLUMN IF NOT EXISTS tax_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS pdf_url TEXT,
+    ADD COLUMN IF NOT EXISTS pdf_generated_at TIMESTAMPTZ;
+
+UPDATE dictamesh_billing_credit_notes SET subtotal = total_amount WHERE subtotal IS NULL;
+
+ALTER TABLE dictamesh_billing_credit_notes
+    ALTER COLUMN subtotal SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_credit_note_line_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    credit_note_id UUID NOT NULL REFERENCES dictamesh_billing_credit_notes(id) ON DELETE CASCADE,
+    invoice_line_item_id UUID REFERENCES dictamesh_billing_invoice_line_items(id) ON DELETE SET NULL,
+
+    -- Line item details
+    description TEXT NOT NULL,
+    quantity DECIMAL(20,6) NOT NULL,
+    unit_price DECIMAL(12,6) NOT NULL,
+    amount DECIMAL(12,2) NOT NULL,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_dictamesh_billing_credit_note_item_note
+    ON dictamesh_billing_credit_note_line_items(credit_note_id);
+
+COMMENT ON TABLE dictamesh_billing_credit_note_line_items IS
+    'DictaMesh: Line items of credit notes';
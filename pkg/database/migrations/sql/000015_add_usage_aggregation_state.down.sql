@@ -0,0 +1,7 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove usage aggregation state
+
+DROP TABLE IF EXISTS dictamesh_billing_usage_counters CASCADE;
+DROP TABLE IF EXISTS dictamesh_billing_usage_cursors CASCADE;
@@ -0,0 +1,31 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Usage aggregation state
+-- Progress of billing.MetricsCollector per usage source and last values of in-process counters
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_usage_cursors (
+    source VARCHAR(20) PRIMARY KEY,
+    last_period_end TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_usage_cursor_source CHECK (source IN ('prometheus', 'inprocess'))
+);
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_usage_counters (
+    organization_id UUID NOT NULL,
+    metric_type VARCHAR(50) NOT NULL,
+
+    -- Process that reported the value; a new epoch means the counter was reset
+    epoch UUID NOT NULL,
+    value DECIMAL(30,6) NOT NULL,
+
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (organization_id, metric_type)
+);
+
+COMMENT ON TABLE dictamesh_billing_usage_cursors IS
+    'DictaMesh: End of the last aggregated usage interval per usage source';
+COMMENT ON TABLE dictamesh_billing_usage_counters IS
+    'DictaMesh: Last aggregated values of in-process usage counters';
@@ -0,0 +1,12 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove billing runs
+
+DROP TABLE IF EXISTS dictamesh_billing_run_items CASCADE;
+DROP TABLE IF EXISTS dictamesh_billing_runs CASCADE;
+
+DROP INDEX IF EXISTS idx_dictamesh_billing_invoice_idempotency_key;
+
+ALTER TABLE dictamesh_billing_invoices
+    DROP COLUMN IF EXISTS idempotency_key;
@@ -0,0 +1,73 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Billing runs
+-- Idempotent period invoices and run history of billing.BillingRunService
+
+-- Identifies the subscription period of period invoices; proration invoices
+-- have none
+ALTER TABLE dictamesh_billing_invoices
+    ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dictamesh_billing_invoice_idempotency_key
+    ON dictamesh_billing_invoices(idempotency_key);
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- Status
+    status VARCHAR(30) NOT NULL DEFAULT 'running',
+
+    -- Subscriptions with periods ending at or before the cutoff are due
+    cutoff TIMESTAMPTZ NOT NULL,
+
+    -- Results
+    subscriptions_due INTEGER NOT NULL DEFAULT 0,
+    invoices_created INTEGER NOT NULL DEFAULT 0,
+    already_invoiced INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+
+    -- Timestamps
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_billing_run_status
+        CHECK (status IN ('running', 'completed', 'completed_with_errors', 'failed'))
+);
+
+CREATE INDEX idx_dictamesh_billing_run_started ON dictamesh_billing_runs(started_at DESC);
+CREATE INDEX idx_dictamesh_billing_run_status ON dictamesh_billing_runs(status);
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_run_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    billing_run_id UUID NOT NULL REFERENCES dictamesh_billing_runs(id) ON DELETE CASCADE,
+    subscription_id UUID NOT NULL REFERENCES dictamesh_billing_subscriptions(id) ON DELETE CASCADE,
+    invoice_id UUID REFERENCES dictamesh_billing_invoices(id) ON DELETE SET NULL,
+
+    -- Invoiced period
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end TIMESTAMPTZ NOT NULL,
+
+    -- Outcome
+    status VARCHAR(20) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_billing_run_item_status CHECK (status IN ('invoiced', 'already_invoiced', 'failed'))
+);
+
+CREATE INDEX idx_dictamesh_billing_run_item_run ON dictamesh_billing_run_items(billing_run_id);
+CREATE INDEX idx_dictamesh_billing_run_item_subscription ON dictamesh_billing_run_items(subscription_id);
+
+COMMENT ON TABLE dictamesh_billing_runs IS
+    'DictaMesh: History of billing runs invoicing ended subscription periods';
+COMMENT ON TABLE dictamesh_billing_run_items IS
+    'DictaMesh: Outcome of each subscription in a billing run';
@@ -0,0 +1,40 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Document number sequences
+-- Invoice and credit note numbers per prefix and year, allocated by billing.nextDocumentNumber
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_number_sequences (
+    prefix VARCHAR(20) NOT NULL,
+    year INTEGER NOT NULL,
+    last_value BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (prefix, year),
+    CONSTRAINT chk_number_sequence_value CHECK (last_value >= 0)
+);
+
+-- Continue the series of existing documents (e.g. INV-2025-000042)
+INSERT INTO dictamesh_billing_number_sequences (prefix, year, last_value)
+SELECT prefix, year, MAX(value)
+FROM (
+    SELECT
+        substring(invoice_number FROM '^(.*)\d{4}-\d+$') AS prefix,
+        substring(invoice_number FROM '(\d{4})-\d+$')::INTEGER AS year,
+        substring(invoice_number FROM '-(\d+)$')::BIGINT AS value
+    FROM dictamesh_billing_invoices
+    WHERE invoice_number ~ '\d{4}-\d+$'
+    UNION ALL
+    SELECT
+        substring(credit_note_number FROM '^(.*)\d{4}-\d+$'),
+        substring(credit_note_number FROM '(\d{4})-\d+$')::INTEGER,
+        substring(credit_note_number FROM '-(\d+)$')::BIGINT
+    FROM dictamesh_billing_credit_notes
+    WHERE credit_note_number ~ '\d{4}-\d+$'
+) AS documents
+GROUP BY prefix, year
+ON CONFLICT (prefix, year) DO UPDATE
+    SET last_value = GREATEST(dictamesh_billing_number_sequences.last_value, EXCLUDED.last_value);
+
+COMMENT ON TABLE dictamesh_billing_number_sequences IS
+    'DictaMesh: Last allocated invoice and credit note number per prefix and year';
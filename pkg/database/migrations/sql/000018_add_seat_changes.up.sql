@@ -0,0 +1,29 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Seat changes
+-- Seat count history of subscriptions, used to prorate seat charges per period segment
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_seat_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES dictamesh_billing_subscriptions(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES dictamesh_billing_organizations(id) ON DELETE CASCADE,
+
+    -- Change
+    from_quantity INTEGER NOT NULL,
+    to_quantity INTEGER NOT NULL,
+    effective_at TIMESTAMPTZ NOT NULL,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_seat_change_quantity CHECK (from_quantity >= 1 AND to_quantity >= 1)
+);
+
+CREATE INDEX idx_dictamesh_billing_seat_change_subscription
+    ON dictamesh_billing_seat_changes(subscription_id, effective_at);
+CREATE INDEX idx_dictamesh_billing_seat_change_organization
+    ON dictamesh_billing_seat_changes(organization_id);
+
+COMMENT ON TABLE dictamesh_billing_seat_changes IS
+    'DictaMesh: Effective-dated seat count changes of subscriptions';
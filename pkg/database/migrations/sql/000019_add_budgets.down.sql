@@ -0,0 +1,7 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove budgets
+
+DROP TABLE IF EXISTS dictamesh_billing_budget_alerts CASCADE;
+DROP TABLE IF EXISTS dictamesh_billing_budgets CASCADE;
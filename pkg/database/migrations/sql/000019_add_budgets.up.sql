@@ -0,0 +1,51 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Budgets
+-- Monthly spend budgets of organizations and the alerts of billing.BudgetService
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_budgets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES dictamesh_billing_organizations(id) ON DELETE CASCADE,
+
+    -- Budget
+    monthly_amount DECIMAL(12,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_budget_amount CHECK (monthly_amount > 0)
+);
+
+CREATE UNIQUE INDEX idx_dictamesh_billing_budget_organization
+    ON dictamesh_billing_budgets(organization_id);
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_budget_alerts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    budget_id UUID NOT NULL REFERENCES dictamesh_billing_budgets(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES dictamesh_billing_organizations(id) ON DELETE CASCADE,
+
+    -- Alert
+    month VARCHAR(7) NOT NULL, -- YYYY-MM in the organization's timezone
+    threshold INTEGER NOT NULL,
+    spend DECIMAL(12,2) NOT NULL,
+    budget DECIMAL(12,2) NOT NULL,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_budget_alert_threshold CHECK (threshold > 0)
+);
+
+-- Each threshold is alerted once per month
+CREATE UNIQUE INDEX idx_dictamesh_billing_budget_alert_month
+    ON dictamesh_billing_budget_alerts(budget_id, month, threshold);
+CREATE INDEX idx_dictamesh_billing_budget_alert_organization
+    ON dictamesh_billing_budget_alerts(organization_id);
+
+COMMENT ON TABLE dictamesh_billing_budgets IS
+    'DictaMesh: Monthly spend budgets of organizations';
+COMMENT ON TABLE dictamesh_billing_budget_alerts IS
+    'DictaMesh: Budget thresholds reached per month';
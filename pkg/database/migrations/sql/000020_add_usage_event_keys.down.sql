@@ -0,0 +1,6 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove usage event keys
+
+DROP TABLE IF EXISTS dictamesh_billing_usage_event_keys CASCADE;
@@ -0,0 +1,17 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Usage event keys
+-- Keys of usage events ingested from Kafka by billing.UsageIngester
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_usage_event_keys (
+    key VARCHAR(64) PRIMARY KEY,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Keys older than the dedup retention are pruned
+CREATE INDEX idx_dictamesh_billing_usage_event_key_received
+    ON dictamesh_billing_usage_event_keys(received_at);
+
+COMMENT ON TABLE dictamesh_billing_usage_event_keys IS
+    'DictaMesh: Keys of ingested usage events, used to drop redelivered events';
@@ -0,0 +1,25 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove plan versions
+-- Fails if a plan has more than one version
+
+ALTER TABLE dictamesh_billing_plan_changes
+    DROP COLUMN IF EXISTS notified_at,
+    DROP COLUMN IF EXISTS price_change_id;
+
+DROP TABLE IF EXISTS dictamesh_billing_price_changes CASCADE;
+
+DROP TRIGGER IF EXISTS dictamesh_billing_plan_version_immutable ON dictamesh_billing_subscription_plans;
+DROP FUNCTION IF EXISTS dictamesh_billing_plan_version_immutable();
+
+DROP INDEX IF EXISTS idx_dictamesh_billing_plan_current;
+DROP INDEX IF EXISTS idx_dictamesh_billing_plan_draft;
+DROP INDEX IF EXISTS idx_dictamesh_billing_plan_slug_version;
+
+ALTER TABLE dictamesh_billing_subscription_plans
+    DROP CONSTRAINT IF EXISTS chk_plan_version_status,
+    DROP COLUMN IF EXISTS published_at,
+    DROP COLUMN IF EXISTS version_status,
+    DROP COLUMN IF EXISTS version,
+    ADD CONSTRAINT dictamesh_billing_subscription_plans_slug_key UNIQUE (slug);
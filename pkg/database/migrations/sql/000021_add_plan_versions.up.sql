@@ -0,0 +1,92 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Plan versions
+-- Versions of a plan share its slug. Subscriptions stay on the version they
+-- were sold until a price change, managed by billing.PlanService, moves them.
+
+ALTER TABLE dictamesh_billing_subscription_plans
+    DROP CONSTRAINT IF EXISTS dictamesh_billing_subscription_plans_slug_key,
+    ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS version_status VARCHAR(20) NOT NULL DEFAULT 'published',
+    ADD COLUMN IF NOT EXISTS published_at TIMESTAMPTZ,
+    ADD CONSTRAINT chk_plan_version_status
+        CHECK (version_status IN ('draft', 'published', 'retired'));
+
+UPDATE dictamesh_billing_subscription_plans SET published_at = created_at WHERE published_at IS NULL;
+
+CREATE UNIQUE INDEX idx_dictamesh_billing_plan_slug_version
+    ON dictamesh_billing_subscription_plans(slug, version);
+
+-- A plan has at most one draft and one offered version
+CREATE UNIQUE INDEX idx_dictamesh_billing_plan_draft
+    ON dictamesh_billing_subscription_plans(slug) WHERE version_status = 'draft';
+CREATE UNIQUE INDEX idx_dictamesh_billing_plan_current
+    ON dictamesh_billing_subscription_plans(slug) WHERE is_active = true;
+
+-- Published versions are priced for their subscribers and cannot be edited
+CREATE OR REPLACE FUNCTION dictamesh_billing_plan_version_immutable()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.version_status <> 'draft' AND (
+        NEW.slug IS DISTINCT FROM OLD.slug OR
+        NEW.version IS DISTINCT FROM OLD.version OR
+        NEW.base_price IS DISTINCT FROM OLD.base_price OR
+        NEW.currency IS DISTINCT FROM OLD.currency OR
+        NEW.billing_interval IS DISTINCT FROM OLD.billing_interval OR
+        NEW.features IS DISTINCT FROM OLD.features OR
+        NEW.included_api_calls IS DISTINCT FROM OLD.included_api_calls OR
+        NEW.included_storage_gb IS DISTINCT FROM OLD.included_storage_gb OR
+        NEW.included_data_transfer_gb IS DISTINCT FROM OLD.included_data_transfer_gb OR
+        NEW.included_seats IS DISTINCT FROM OLD.included_seats OR
+        NEW.max_adapters IS DISTINCT FROM OLD.max_adapters OR
+        NEW.price_per_api_call IS DISTINCT FROM OLD.price_per_api_call OR
+        NEW.price_per_gb_storage IS DISTINCT FROM OLD.price_per_gb_storage OR
+        NEW.price_per_gb_transfer IS DISTINCT FROM OLD.price_per_gb_transfer OR
+        NEW.price_per_additional_seat IS DISTINCT FROM OLD.price_per_additional_seat OR
+        NEW.version_status = 'draft'
+    ) THEN
+        RAISE EXCEPTION 'version % of plan % is % and cannot be edited',
+            OLD.version, OLD.slug, OLD.version_status;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER dictamesh_billing_plan_version_immutable
+    BEFORE UPDATE ON dictamesh_billing_subscription_plans
+    FOR EACH ROW
+    EXECUTE FUNCTION dictamesh_billing_plan_version_immutable();
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_price_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    from_plan_id UUID NOT NULL REFERENCES dictamesh_billing_subscription_plans(id),
+    to_plan_id UUID NOT NULL REFERENCES dictamesh_billing_subscription_plans(id),
+
+    -- Schedule
+    effective_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    subscriptions INT NOT NULL DEFAULT 0,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_price_change_status CHECK (status IN ('scheduled', 'canceled'))
+);
+
+CREATE INDEX idx_dictamesh_billing_price_change_from ON dictamesh_billing_price_changes(from_plan_id);
+CREATE INDEX idx_dictamesh_billing_price_change_status ON dictamesh_billing_price_changes(status);
+
+COMMENT ON TABLE dictamesh_billing_price_changes IS
+    'DictaMesh: Effective-dated moves of plan version cohorts to another version';
+
+ALTER TABLE dictamesh_billing_plan_changes
+    ADD COLUMN IF NOT EXISTS price_change_id UUID REFERENCES dictamesh_billing_price_changes(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS notified_at TIMESTAMPTZ;
+
+CREATE INDEX idx_dictamesh_billing_plan_change_price_change
+    ON dictamesh_billing_plan_changes(price_change_id);
+CREATE INDEX idx_dictamesh_billing_plan_change_unnotified
+    ON dictamesh_billing_plan_changes(effective_at)
+    WHERE status = 'scheduled' AND price_change_id IS NOT NULL AND notified_at IS NULL;
@@ -0,0 +1,7 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove trial reminders
+
+DROP INDEX IF EXISTS idx_dictamesh_billing_sub_trial_end;
+DROP TABLE IF EXISTS dictamesh_billing_trial_reminders CASCADE;
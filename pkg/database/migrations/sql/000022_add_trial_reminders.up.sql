@@ -0,0 +1,32 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Trial reminders
+-- Reminders sent before the end of trials by billing.SubscriptionService
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_trial_reminders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES dictamesh_billing_subscriptions(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES dictamesh_billing_organizations(id) ON DELETE CASCADE,
+
+    -- Reminder
+    trial_end TIMESTAMPTZ NOT NULL,
+    days_before INTEGER NOT NULL,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_trial_reminder_days CHECK (days_before > 0)
+);
+
+-- Each reminder is sent once per trial end, so extended trials are reminded again
+CREATE UNIQUE INDEX idx_dictamesh_billing_trial_reminder
+    ON dictamesh_billing_trial_reminders(subscription_id, trial_end, days_before);
+CREATE INDEX idx_dictamesh_billing_trial_reminder_organization
+    ON dictamesh_billing_trial_reminders(organization_id);
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_billing_sub_trial_end
+    ON dictamesh_billing_subscriptions(trial_end) WHERE status = 'trialing';
+
+COMMENT ON TABLE dictamesh_billing_trial_reminders IS
+    'DictaMesh: Reminders sent before the end of trials';
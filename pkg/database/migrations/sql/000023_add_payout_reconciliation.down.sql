@@ -0,0 +1,7 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove payout reconciliation
+
+DROP TABLE IF EXISTS dictamesh_billing_reconciliation_items CASCADE;
+DROP TABLE IF EXISTS dictamesh_billing_payouts CASCADE;
@@ -0,0 +1,76 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Payout reconciliation
+-- Provider payouts reconciled by billing.ReconciliationService
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_payouts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider VARCHAR(20) NOT NULL,
+    provider_payout_id VARCHAR(255) NOT NULL,
+
+    -- Payout details
+    amount DECIMAL(12, 2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    arrival_date TIMESTAMPTZ NOT NULL,
+
+    -- Reconciliation
+    status VARCHAR(20) NOT NULL,
+    net_amount DECIMAL(12, 2) NOT NULL,
+    fee_amount DECIMAL(12, 2) NOT NULL,
+    matched_items INTEGER NOT NULL DEFAULT 0,
+    flagged_items INTEGER NOT NULL DEFAULT 0,
+    reconciled_at TIMESTAMPTZ NOT NULL,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_payout_status CHECK (status IN ('reconciled', 'mismatched'))
+);
+
+CREATE UNIQUE INDEX idx_dictamesh_billing_payout_provider
+    ON dictamesh_billing_payouts(provider, provider_payout_id);
+CREATE INDEX idx_dictamesh_billing_payout_arrival ON dictamesh_billing_payouts(arrival_date);
+CREATE INDEX idx_dictamesh_billing_payout_status ON dictamesh_billing_payouts(status);
+
+CREATE TABLE IF NOT EXISTS dictamesh_billing_reconciliation_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payout_id UUID NOT NULL REFERENCES dictamesh_billing_payouts(id) ON DELETE CASCADE,
+    payment_id UUID REFERENCES dictamesh_billing_payments(id) ON DELETE SET NULL,
+    refund_id UUID REFERENCES dictamesh_billing_refunds(id) ON DELETE SET NULL,
+    organization_id UUID REFERENCES dictamesh_billing_organizations(id) ON DELETE SET NULL,
+    provider_transaction_id VARCHAR(255) NOT NULL,
+
+    -- Transaction details
+    type VARCHAR(20) NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    fee DECIMAL(12, 2) NOT NULL,
+    net DECIMAL(12, 2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+
+    -- Reconciliation
+    status VARCHAR(20) NOT NULL,
+    detail TEXT,
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_reconciliation_item_status CHECK (
+        status IN ('matched', 'unmatched', 'amount_mismatch', 'status_mismatch')
+    )
+);
+
+CREATE INDEX idx_dictamesh_billing_reconciliation_item_payout
+    ON dictamesh_billing_reconciliation_items(payout_id);
+CREATE INDEX idx_dictamesh_billing_reconciliation_item_payment
+    ON dictamesh_billing_reconciliation_items(payment_id) WHERE payment_id IS NOT NULL;
+CREATE INDEX idx_dictamesh_billing_reconciliation_item_organization
+    ON dictamesh_billing_reconciliation_items(organization_id);
+CREATE INDEX idx_dictamesh_billing_reconciliation_item_flagged
+    ON dictamesh_billing_reconciliation_items(status) WHERE status <> 'matched';
+
+COMMENT ON TABLE dictamesh_billing_payouts IS
+    'DictaMesh: Provider payouts reconciled against internal payments';
+COMMENT ON TABLE dictamesh_billing_reconciliation_items IS
+    'DictaMesh: Payout balance transactions and the internal records they match';
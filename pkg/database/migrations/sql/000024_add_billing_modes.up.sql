@@ -0,0 +1,46 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Billing modes
+-- Plans billed in advance invoice their base fee at the start of each period
+-- and usage at its end; plans billed in arrears invoice both at the end.
+
+ALTER TABLE dictamesh_billing_subscription_plans
+    ADD COLUMN IF NOT EXISTS billing_mode VARCHAR(20) NOT NULL DEFAULT 'arrears',
+    ADD CONSTRAINT chk_plan_billing_mode CHECK (billing_mode IN ('arrears', 'advance'));
+
+-- The billing mode is part of the price of a published version
+CREATE OR REPLACE FUNCTION dictamesh_billing_plan_version_immutable()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.version_status <> 'draft' AND (
+        NEW.slug IS DISTINCT FROM OLD.slug OR
+        NEW.version IS DISTINCT FROM OLD.version OR
+        NEW.base_price IS DISTINCT FROM OLD.base_price OR
+        NEW.currency IS DISTINCT FROM OLD.currency OR
+        NEW.billing_interval IS DISTINCT FROM OLD.billing_interval OR
+        NEW.billing_mode IS DISTINCT FROM OLD.billing_mode OR
+        NEW.features IS DISTINCT FROM OLD.features OR
+        NEW.included_api_calls IS DISTINCT FROM OLD.included_api_calls OR
+        NEW.included_storage_gb IS DISTINCT FROM OLD.included_storage_gb OR
+        NEW.included_data_transfer_gb IS DISTINCT FROM OLD.included_data_transfer_gb OR
+        NEW.included_seats IS DISTINCT FROM OLD.included_seats OR
+        NEW.max_adapters IS DISTINCT FROM OLD.max_adapters OR
+        NEW.price_per_api_call IS DISTINCT FROM OLD.price_per_api_call OR
+        NEW.price_per_gb_storage IS DISTINCT FROM OLD.price_per_gb_storage OR
+        NEW.price_per_gb_transfer IS DISTINCT FROM OLD.price_per_gb_transfer OR
+        NEW.price_per_additional_seat IS DISTINCT FROM OLD.price_per_additional_seat OR
+        NEW.version_status = 'draft'
+    ) THEN
+        RAISE EXCEPTION 'version % of plan % is % and cannot be edited',
+            OLD.version, OLD.slug, OLD.version_status;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE dictamesh_billing_run_items
+    ADD COLUMN IF NOT EXISTS advance_invoice_id UUID REFERENCES dictamesh_billing_invoices(id) ON DELETE SET NULL;
+
+COMMENT ON COLUMN dictamesh_billing_subscription_plans.billing_mode IS
+    'DictaMesh: When the base fee is invoiced: arrears (period end) or advance (period start)';
@@ -0,0 +1,24 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Remove overdue invoices
+
+DROP INDEX IF EXISTS idx_dictamesh_billing_invoice_past_due;
+
+UPDATE dictamesh_billing_invoices SET status = 'open' WHERE status = 'past_due';
+DELETE FROM dictamesh_billing_invoice_line_items WHERE item_type = 'late_fee';
+
+ALTER TABLE dictamesh_billing_invoice_line_items
+    DROP CONSTRAINT IF EXISTS chk_item_type,
+    ADD CONSTRAINT chk_item_type CHECK (item_type IN (
+        'subscription_base', 'usage_api_calls', 'usage_storage', 'usage_transfer',
+        'addon_seats', 'addon_support', 'credit', 'tax', 'discount', 'proration'
+    ));
+
+ALTER TABLE dictamesh_billing_invoices
+    DROP COLUMN IF EXISTS suspended_at,
+    DROP COLUMN IF EXISTS late_fee,
+    DROP COLUMN IF EXISTS overdue_at,
+    DROP CONSTRAINT IF EXISTS chk_invoice_status,
+    ADD CONSTRAINT chk_invoice_status
+        CHECK (status IN ('draft', 'open', 'paid', 'void', 'uncollectible'));
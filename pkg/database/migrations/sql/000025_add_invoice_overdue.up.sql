@@ -0,0 +1,32 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Billing - Overdue invoices
+-- Open invoices past their due date become past due, may be charged a late
+-- fee and suspend their organization once the grace window ends.
+
+ALTER TABLE dictamesh_billing_invoices
+    DROP CONSTRAINT IF EXISTS chk_invoice_status,
+    ADD CONSTRAINT chk_invoice_status
+        CHECK (status IN ('draft', 'open', 'past_due', 'paid', 'void', 'uncollectible')),
+    ADD COLUMN IF NOT EXISTS overdue_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS late_fee DECIMAL(12,2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ;
+
+ALTER TABLE dictamesh_billing_invoice_line_items
+    DROP CONSTRAINT IF EXISTS chk_item_type,
+    ADD CONSTRAINT chk_item_type CHECK (item_type IN (
+        'subscription_base', 'usage_api_calls', 'usage_storage', 'usage_transfer',
+        'addon_seats', 'addon_support', 'credit', 'tax', 'discount', 'proration',
+        'late_fee'
+    ));
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_billing_invoice_past_due
+    ON dictamesh_billing_invoices(due_date) WHERE status = 'past_due';
+
+COMMENT ON COLUMN dictamesh_billing_invoices.overdue_at IS
+    'DictaMesh: When the invoice became past due';
+COMMENT ON COLUMN dictamesh_billing_invoices.late_fee IS
+    'DictaMesh: Late fee added to the invoice when it became past due';
+COMMENT ON COLUMN dictamesh_billing_invoices.suspended_at IS
+    'DictaMesh: When the organization was suspended for the invoice being past due';
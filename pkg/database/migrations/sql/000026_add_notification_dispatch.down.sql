@@ -0,0 +1,9 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Notifications - Remove dispatch
+
+DROP INDEX IF EXISTS idx_dictamesh_notifications_due;
+
+ALTER TABLE dictamesh_notifications
+    DROP COLUMN IF EXISTS category;
@@ -0,0 +1,16 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Notifications - Dispatch
+-- The dispatcher claims due notifications by priority and selects their
+-- channel from the preferences of their category.
+
+ALTER TABLE dictamesh_notifications
+    ADD COLUMN IF NOT EXISTS category VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_notifications_due
+    ON dictamesh_notifications(scheduled_at, next_retry_at)
+    WHERE status IN ('PENDING', 'RETRYING');
+
+COMMENT ON COLUMN dictamesh_notifications.category IS
+    'DictaMesh: Preference category of the notification, e.g. billing';
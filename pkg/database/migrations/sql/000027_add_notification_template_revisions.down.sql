@@ -0,0 +1,17 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Notifications - Remove template revisions
+
+ALTER TABLE dictamesh_notifications
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS template_revision;
+
+DROP TRIGGER IF EXISTS dictamesh_notification_template_revision_immutable
+    ON dictamesh_notification_template_revisions;
+DROP FUNCTION IF EXISTS dictamesh_notification_template_revision_immutable();
+
+DROP TABLE IF EXISTS dictamesh_notification_template_revisions;
+
+ALTER TABLE dictamesh_notification_templates
+    DROP COLUMN IF EXISTS revision;
@@ -0,0 +1,60 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Notifications - Template revisions
+-- Every save of a template adds an immutable revision. Notifications record
+-- the revision they were rendered from, so their content can be reproduced.
+
+ALTER TABLE dictamesh_notification_templates
+    ADD COLUMN IF NOT EXISTS revision INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS dictamesh_notification_template_revisions (
+    template_id UUID NOT NULL REFERENCES dictamesh_notification_templates(id),
+    revision INT NOT NULL,
+
+    -- Content at the revision
+    channels JSONB NOT NULL,
+    translations JSONB,
+    variables JSONB,
+    version VARCHAR(50),
+
+    -- Audit
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_by VARCHAR(255),
+
+    PRIMARY KEY (template_id, revision)
+);
+
+COMMENT ON TABLE dictamesh_notification_template_revisions IS
+    'DictaMesh: Immutable revisions of notification templates';
+
+CREATE OR REPLACE FUNCTION dictamesh_notification_template_revision_immutable()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'revision % of template % cannot be changed',
+        OLD.revision, OLD.template_id;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER dictamesh_notification_template_revision_immutable
+    BEFORE UPDATE OR DELETE ON dictamesh_notification_template_revisions
+    FOR EACH ROW
+    EXECUTE FUNCTION dictamesh_notification_template_revision_immutable();
+
+-- Existing templates become their first revision
+INSERT INTO dictamesh_notification_template_revisions
+    (template_id, revision, channels, translations, variables, version, created_at, created_by)
+SELECT id, 1, channels, translations, variables, version, updated_at, created_by
+FROM dictamesh_notification_templates
+WHERE revision = 0;
+
+UPDATE dictamesh_notification_templates SET revision = 1 WHERE revision = 0;
+
+ALTER TABLE dictamesh_notifications
+    ADD COLUMN IF NOT EXISTS template_revision INT,
+    ADD COLUMN IF NOT EXISTS locale VARCHAR(10);
+
+COMMENT ON COLUMN dictamesh_notifications.template_revision IS
+    'DictaMesh: Template revision the notification was rendered from';
+COMMENT ON COLUMN dictamesh_notifications.locale IS
+    'DictaMesh: Locale the notification was rendered in';
@@ -0,0 +1,7 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Notifications - Remove in-app inbox
+
+DROP INDEX IF EXISTS idx_dictamesh_notifications_inbox_unread;
+DROP INDEX IF EXISTS idx_dictamesh_notifications_inbox;
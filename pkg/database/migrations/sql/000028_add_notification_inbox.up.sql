@@ -0,0 +1,14 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Notifications - In-app inbox
+-- Delivered IN_APP notifications are the inbox of their recipient; read_at
+-- tracks their read state.
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_notifications_inbox
+    ON dictamesh_notifications(recipient_id, created_at DESC)
+    WHERE selected_channel = 'IN_APP' AND recipient_type = 'USER';
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_notifications_inbox_unread
+    ON dictamesh_notifications(recipient_id)
+    WHERE selected_channel = 'IN_APP' AND recipient_type = 'USER' AND read_at IS NULL;
@@ -0,0 +1,9 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Notifications - Organization recipients
+-- Billing notifies organizations through the notification API.
+
+ALTER TABLE dictamesh_notifications DROP CONSTRAINT IF EXISTS valid_recipient_type;
+ALTER TABLE dictamesh_notifications ADD CONSTRAINT valid_recipient_type
+    CHECK (recipient_type IN ('USER', 'ROLE', 'GROUP', 'SYSTEM', 'ORGANIZATION'));
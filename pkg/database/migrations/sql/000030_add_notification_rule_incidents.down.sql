@@ -0,0 +1,9 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Notifications - Remove rule incidents
+
+ALTER TABLE dictamesh_notification_rules
+    DROP COLUMN IF EXISTS escalation_policy,
+    DROP COLUMN IF EXISTS resolve_pattern,
+    DROP COLUMN IF EXISTS incident_key;
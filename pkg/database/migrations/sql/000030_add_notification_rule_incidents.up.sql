@@ -0,0 +1,19 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Notifications - Rule incidents
+-- Events of a rule sharing an incident key open one PagerDuty incident,
+-- routed by the escalation policy of the rule and resolved by events matching
+-- the resolve pattern.
+
+ALTER TABLE dictamesh_notification_rules
+    ADD COLUMN IF NOT EXISTS incident_key TEXT,
+    ADD COLUMN IF NOT EXISTS resolve_pattern TEXT,
+    ADD COLUMN IF NOT EXISTS escalation_policy VARCHAR(100);
+
+COMMENT ON COLUMN dictamesh_notification_rules.incident_key IS
+    'CEL expression identifying the incident of an event; the event ID when empty';
+COMMENT ON COLUMN dictamesh_notification_rules.resolve_pattern IS
+    'CEL expression matching events that resolve the incident of their key';
+COMMENT ON COLUMN dictamesh_notification_rules.escalation_policy IS
+    'Name of the PagerDuty escalation policy incidents are routed to';
@@ -0,0 +1,32 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Notifications - Suppression lists
+-- Addresses nothing is delivered to on a channel, or on every channel when
+-- the channel is empty: hard bounces, complaints and unsubscribes.
+
+CREATE TABLE IF NOT EXISTS dictamesh_notification_suppressions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    channel VARCHAR(50) NOT NULL DEFAULT '',
+    address VARCHAR(320) NOT NULL,
+
+    reason VARCHAR(20) NOT NULL,
+    source VARCHAR(100),
+    notification_id UUID,
+    expires_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT valid_suppression_reason
+        CHECK (reason IN ('HARD_BOUNCE', 'COMPLAINT', 'UNSUBSCRIBE', 'MANUAL'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dictamesh_notification_suppressions_address
+    ON dictamesh_notification_suppressions(address, channel);
+CREATE INDEX IF NOT EXISTS idx_dictamesh_notification_suppressions_created
+    ON dictamesh_notification_suppressions(created_at DESC);
+
+COMMENT ON TABLE dictamesh_notification_suppressions IS
+    'DictaMesh: Addresses suppressed per channel or globally';
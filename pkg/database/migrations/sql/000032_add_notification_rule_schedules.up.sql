@@ -0,0 +1,25 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Notifications - Rule schedules
+-- Next activation of each rule with a cron or interval schedule. Scheduler
+-- instances claim due rows with FOR UPDATE SKIP LOCKED, so each activation
+-- fires on one instance.
+
+CREATE TABLE IF NOT EXISTS dictamesh_notification_rule_schedules (
+    rule_id UUID PRIMARY KEY REFERENCES dictamesh_notification_rules(id) ON DELETE CASCADE,
+
+    spec TEXT NOT NULL,
+
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_run_at TIMESTAMPTZ,
+    last_instance VARCHAR(255),
+
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_notification_rule_schedules_next
+    ON dictamesh_notification_rule_schedules(next_run_at);
+
+COMMENT ON TABLE dictamesh_notification_rule_schedules IS
+    'DictaMesh: Next activation of recurring notification rules';
@@ -0,0 +1,7 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Notifications - Remove span IDs
+
+ALTER TABLE dictamesh_notifications
+    DROP COLUMN IF EXISTS span_id;
@@ -0,0 +1,12 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Notifications - Span IDs
+-- The span that submitted a notification, so that its dispatch continues the
+-- trace of the event or request it was submitted for.
+
+ALTER TABLE dictamesh_notifications
+    ADD COLUMN IF NOT EXISTS span_id VARCHAR(16);
+
+COMMENT ON COLUMN dictamesh_notifications.span_id IS
+    'DictaMesh: Span that submitted the notification, in trace trace_id';
@@ -0,0 +1,6 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Events - Remove the transactional outbox
+
+DROP TABLE IF EXISTS dictamesh_event_outbox;
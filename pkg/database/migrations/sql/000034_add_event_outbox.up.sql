@@ -0,0 +1,36 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Events - Transactional outbox
+-- Events are written in the transaction of the change they describe and
+-- relayed to Kafka once committed. Relay instances claim pending rows with
+-- FOR UPDATE SKIP LOCKED; the dedup key travels with the message so
+-- consumers can drop the copies of a relay that failed to record a publish.
+
+CREATE TABLE IF NOT EXISTS dictamesh_event_outbox (
+    id BIGSERIAL PRIMARY KEY,
+
+    dedup_key VARCHAR(255) NOT NULL,
+    topic VARCHAR(255) NOT NULL,
+    message_key VARCHAR(255) NOT NULL DEFAULT '',
+    payload BYTEA NOT NULL,
+    headers JSONB NOT NULL DEFAULT '{}',
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMPTZ,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+
+    CONSTRAINT uq_dictamesh_event_outbox_dedup_key UNIQUE (dedup_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_event_outbox_pending
+    ON dictamesh_event_outbox(id) WHERE published_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_event_outbox_published
+    ON dictamesh_event_outbox(published_at) WHERE published_at IS NOT NULL;
+
+COMMENT ON TABLE dictamesh_event_outbox IS
+    'DictaMesh: Events committed with their changes and awaiting relay to Kafka';
+COMMENT ON COLUMN dictamesh_event_outbox.dedup_key IS
+    'DictaMesh: Event ID; enqueuing an event twice stores it once';
@@ -0,0 +1,27 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Events - Idempotency store
+-- Consumers claim the event IDs they process, per scope (usually their
+-- consumer group), and record them processed once handled, so redelivered
+-- events are skipped. Claims expire, so the events of a consumer that died
+-- while processing them are processed again.
+
+CREATE TABLE IF NOT EXISTS dictamesh_event_idempotency (
+    scope VARCHAR(255) NOT NULL,
+    event_key VARCHAR(255) NOT NULL,
+
+    claimed_until TIMESTAMPTZ NOT NULL,
+    processed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (scope, event_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_event_idempotency_processed
+    ON dictamesh_event_idempotency(processed_at) WHERE processed_at IS NOT NULL;
+
+COMMENT ON TABLE dictamesh_event_idempotency IS
+    'DictaMesh: Events claimed and processed by consumers, to skip redeliveries';
+COMMENT ON COLUMN dictamesh_event_idempotency.event_key IS
+    'DictaMesh: Dedup key or event ID of the event';
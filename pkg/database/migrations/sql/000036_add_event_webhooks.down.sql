@@ -0,0 +1,7 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Events - Remove the webhook bridge
+
+DROP TABLE IF EXISTS dictamesh_webhook_deliveries;
+DROP TABLE IF EXISTS dictamesh_webhook_subscriptions;
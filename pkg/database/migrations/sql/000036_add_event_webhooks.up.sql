@@ -0,0 +1,70 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Events - Webhook bridge
+-- Organizations subscribe HTTPS endpoints to event types. The bridge records
+-- a delivery per subscription of each event consumed, then posts due
+-- deliveries signed with the subscription secret, claiming them with
+-- FOR UPDATE SKIP LOCKED and rescheduling failures with backoff.
+-- Subscriptions whose deliveries keep failing are disabled.
+
+CREATE TABLE IF NOT EXISTS dictamesh_webhook_subscriptions (
+    id UUID PRIMARY KEY,
+    organization_id VARCHAR(255) NOT NULL,
+
+    url TEXT NOT NULL,
+    event_types JSONB NOT NULL DEFAULT '[]',
+    description TEXT,
+    secret VARCHAR(255) NOT NULL,
+
+    consecutive_failures INT NOT NULL DEFAULT 0,
+    disabled_at TIMESTAMPTZ,
+    disabled_reason TEXT,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_webhook_subscriptions_organization
+    ON dictamesh_webhook_subscriptions(organization_id) WHERE disabled_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS dictamesh_webhook_deliveries (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES dictamesh_webhook_subscriptions(id) ON DELETE CASCADE,
+    organization_id VARCHAR(255) NOT NULL,
+
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(255) NOT NULL,
+    payload BYTEA NOT NULL,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status_code INT,
+    last_error TEXT,
+    last_attempt_at TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT uq_dictamesh_webhook_deliveries_event UNIQUE (subscription_id, event_id),
+    CONSTRAINT chk_dictamesh_webhook_deliveries_status CHECK (status IN ('pending', 'delivered', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_webhook_deliveries_due
+    ON dictamesh_webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_webhook_deliveries_history
+    ON dictamesh_webhook_deliveries(subscription_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_dictamesh_webhook_deliveries_finished
+    ON dictamesh_webhook_deliveries(created_at) WHERE status <> 'pending';
+
+COMMENT ON TABLE dictamesh_webhook_subscriptions IS
+    'DictaMesh: Endpoints of organizations subscribed to event types';
+COMMENT ON COLUMN dictamesh_webhook_subscriptions.event_types IS
+    'DictaMesh: Event types by name, e.g. billing.invoice.created, or prefix, e.g. billing.*';
+COMMENT ON COLUMN dictamesh_webhook_subscriptions.consecutive_failures IS
+    'DictaMesh: Deliveries given up in a row; the subscription is disabled past a threshold';
+COMMENT ON TABLE dictamesh_webhook_deliveries IS
+    'DictaMesh: Events delivered to webhook subscriptions, with the outcome of their last attempt';
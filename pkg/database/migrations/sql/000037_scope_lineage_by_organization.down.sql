@@ -0,0 +1,27 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Data Lineage - Remove the organization scope
+
+DROP INDEX IF EXISTS idx_dictamesh_lineage_edge;
+DROP INDEX IF EXISTS idx_dictamesh_lineage_edge_upstream;
+DROP INDEX IF EXISTS idx_dictamesh_lineage_edge_downstream;
+
+-- Keep the most recently seen copy of edges recorded by several organizations
+DELETE FROM dictamesh_lineage_edges a
+    USING dictamesh_lineage_edges b
+    WHERE a.upstream_kind = b.upstream_kind
+      AND a.upstream_id = b.upstream_id
+      AND a.downstream_kind = b.downstream_kind
+      AND a.downstream_id = b.downstream_id
+      AND a.relation = b.relation
+      AND (a.last_seen_at, a.id) < (b.last_seen_at, b.id);
+
+ALTER TABLE dictamesh_lineage_edges DROP COLUMN IF EXISTS organization_id;
+
+CREATE UNIQUE INDEX idx_dictamesh_lineage_edge
+    ON dictamesh_lineage_edges(upstream_kind, upstream_id, downstream_kind, downstream_id, relation);
+CREATE INDEX idx_dictamesh_lineage_edge_upstream
+    ON dictamesh_lineage_edges(upstream_kind, upstream_id);
+CREATE INDEX idx_dictamesh_lineage_edge_downstream
+    ON dictamesh_lineage_edges(downstream_kind, downstream_id);
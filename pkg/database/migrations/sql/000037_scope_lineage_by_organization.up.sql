@@ -0,0 +1,26 @@
+-- SPDX-License-Identifier: AGPL-3.0-or-later
+-- Copyright (C) 2025 Controle Digital Ltda
+
+-- DictaMesh Data Lineage - Organization scope
+-- Lineage edges belong to the organization they were recorded for and are
+-- only queried within it. Edges recorded before this migration have no
+-- organization and are no longer returned by queries.
+
+ALTER TABLE dictamesh_lineage_edges
+    ADD COLUMN IF NOT EXISTS organization_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE dictamesh_lineage_edges
+    ALTER COLUMN organization_id DROP DEFAULT;
+
+DROP INDEX IF EXISTS idx_dictamesh_lineage_edge;
+DROP INDEX IF EXISTS idx_dictamesh_lineage_edge_upstream;
+DROP INDEX IF EXISTS idx_dictamesh_lineage_edge_downstream;
+
+CREATE UNIQUE INDEX idx_dictamesh_lineage_edge
+    ON dictamesh_lineage_edges(organization_id, upstream_kind, upstream_id, downstream_kind, downstream_id, relation);
+CREATE INDEX idx_dictamesh_lineage_edge_upstream
+    ON dictamesh_lineage_edges(organization_id, upstream_kind, upstream_id);
+CREATE INDEX idx_dictamesh_lineage_edge_downstream
+    ON dictamesh_lineage_edges(organization_id, downstream_kind, downstream_id);
+
+COMMENT ON COLUMN dictamesh_lineage_edges.organization_id IS
+    'DictaMesh: Billing organization the edge was recorded for';
@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package models
+
+import (
+	"time"
+)
+
+// LineageEdge records that a downstream node was produced, touched or derived
+// from an upstream node within an organization. Nodes are identified by kind
+// (entity, adapter, sync, embedding, invoice, ...) and ID. Entity-to-entity
+// transformations are also kept in DataLineage.
+type LineageEdge struct {
+	ID              string    `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID  string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_lineage_edge,priority:1;index:idx_lineage_edge_upstream,priority:1;index:idx_lineage_edge_downstream,priority:1"`
+	UpstreamKind    string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_lineage_edge,priority:2;index:idx_lineage_edge_upstream,priority:2"`
+	UpstreamID      string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_lineage_edge,priority:3;index:idx_lineage_edge_upstream,priority:3"`
+	DownstreamKind  string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_lineage_edge,priority:4;index:idx_lineage_edge_downstream,priority:2"`
+	DownstreamID    string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_lineage_edge,priority:5;index:idx_lineage_edge_downstream,priority:3"`
+	Relation        string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_lineage_edge,priority:6"`
+	LastRunID       *string   `gorm:"type:varchar(255)"`
+	Metadata        JSONB     `gorm:"type:jsonb"`
+	OccurrenceCount int       `gorm:"default:1"`
+	FirstSeenAt     time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	LastSeenAt      time.Time `gorm:"default:CURRENT_TIMESTAMP;index:idx_lineage_edge_last_seen"`
+}
+
+// TableName returns the table name
+func (LineageEdge) TableName() string {
+	return "dictamesh_lineage_edges"
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/pgvector/pgvector-go"
+
+	"github.com/click2-run/dictamesh/pkg/database/lineage"
 )
 
 // EmbeddingModel represents an embedding model configuration
@@ -67,7 +69,8 @@ type RelevantChunk struct {
 
 // VectorSearch provides vector similarity search capabilities
 type VectorSearch struct {
-	db *Database
+	db      *Database
+	lineage *lineage.Service
 }
 
 // NewVectorSearch creates a new vector search instance
@@ -75,6 +78,25 @@ func NewVectorSearch(db *Database) *VectorSearch {
 	return &VectorSearch{db: db}
 }
 
+// SetLineage records embeddings and document chunks stored from then on as
+// derived from their catalog entity. Stores must then be called with the
+// tenant of the entity in the context.
+func (vs *VectorSearch) SetLineage(service *lineage.Service) {
+	vs.lineage = service
+}
+
+// recordDerived records that an embedding was derived from an entity
+func (vs *VectorSearch) recordDerived(ctx context.Context, catalogID, embeddingID, model string) error {
+	if vs.lineage == nil {
+		return nil
+	}
+	consumer := lineage.Node{Kind: lineage.KindEmbedding, ID: embeddingID}
+	if err := vs.lineage.RecordDerived(ctx, catalogID, consumer, map[string]interface{}{"embedding_model": model}); err != nil {
+		return fmt.Errorf("failed to record embedding lineage: %w", err)
+	}
+	return nil
+}
+
 // StoreEmbedding stores an entity embedding
 func (vs *VectorSearch) StoreEmbedding(ctx context.Context, embedding *EntityEmbedding) error {
 	query := `
@@ -107,7 +129,7 @@ func (vs *VectorSearch) StoreEmbedding(ctx context.Context, embedding *EntityEmb
 		return fmt.Errorf("failed to store embedding: %w", err)
 	}
 
-	return nil
+	return vs.recordDerived(ctx, embedding.CatalogID, embedding.ID, embedding.EmbeddingModel)
 }
 
 // StoreDocumentChunk stores a document chunk with embedding
@@ -144,7 +166,7 @@ func (vs *VectorSearch) StoreDocumentChunk(ctx context.Context, chunk *DocumentC
 		return fmt.Errorf("failed to store document chunk: %w", err)
 	}
 
-	return nil
+	return vs.recordDerived(ctx, chunk.CatalogID, chunk.ID, chunk.EmbeddingModel)
 }
 
 // FindSimilarEntities finds entities similar to the query embedding
@@ -325,7 +347,7 @@ func (vs *VectorSearch) DeleteDocumentChunks(ctx context.Context, catalogID stri
 
 // BatchStoreChunks stores multiple document chunks in a transaction
 func (vs *VectorSearch) BatchStoreChunks(ctx context.Context, chunks []DocumentChunk) error {
-	return vs.db.WithPgxTransaction(ctx, func(tx pgx.Tx) error {
+	err := vs.db.WithPgxTransaction(ctx, func(tx pgx.Tx) error {
 		for i := range chunks {
 			query := `
 				INSERT INTO dictamesh_document_chunks (
@@ -340,9 +362,10 @@ func (vs *VectorSearch) BatchStoreChunks(ctx context.Context, chunks []DocumentC
 					preceding_context = EXCLUDED.preceding_context,
 					following_context = EXCLUDED.following_context,
 					metadata = EXCLUDED.metadata
+				RETURNING id
 			`
 
-			_, err := tx.Exec(ctx, query,
+			err := tx.QueryRow(ctx, query,
 				chunks[i].CatalogID,
 				chunks[i].ChunkIndex,
 				chunks[i].ChunkText,
@@ -352,7 +375,7 @@ func (vs *VectorSearch) BatchStoreChunks(ctx context.Context, chunks []DocumentC
 				chunks[i].PrecedingContext,
 				chunks[i].FollowingContext,
 				chunks[i].Metadata,
-			)
+			).Scan(&chunks[i].ID)
 
 			if err != nil {
 				return fmt.Errorf("failed to store chunk %d: %w", i, err)
@@ -360,4 +383,14 @@ func (vs *VectorSearch) BatchStoreChunks(ctx context.Context, chunks []DocumentC
 		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	for i := range chunks {
+		if err := vs.recordDerived(ctx, chunks[i].CatalogID, chunks[i].ID, chunks[i].EmbeddingModel); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,310 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"go.uber.org/zap"
+)
+
+// Replays of a request without a limit are capped at maxReplayLimit records
+const maxReplayLimit = 10000
+
+// Pages of webhook delivery history
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// ReplayRequest is a replay submitted to the dead-letter API
+type ReplayRequest struct {
+	Limit int `json:"limit,omitempty"` // Records to replay, up to maxReplayLimit
+}
+
+// DeadLetterHandler serves the HTTP API of the dead-letter topics:
+//
+//	GET  /api/v1/events/dlq
+//	POST /api/v1/events/dlq/{topic}/replay
+//
+// Requests authenticate with one of DeadLetter.APIKeys as a bearer token;
+// without keys, every request is rejected.
+type DeadLetterHandler struct {
+	deadLetters *DeadLetters
+	logger      *zap.Logger
+	mux         *http.ServeMux
+}
+
+// NewDeadLetterHandler creates the dead-letter API handler
+func NewDeadLetterHandler(deadLetters *DeadLetters, logger *zap.Logger) *DeadLetterHandler {
+	h := &DeadLetterHandler{
+		deadLetters: deadLetters,
+		logger:      logger,
+		mux:         http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("GET /api/v1/events/dlq", h.list)
+	h.mux.HandleFunc("POST /api/v1/events/dlq/{topic}/replay", h.replay)
+
+	return h
+}
+
+// ServeHTTP implements http.Handler. Requests without a valid API key are
+// rejected.
+func (h *DeadLetterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authenticated(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="events"`)
+		writeAPIError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// authenticated reports whether a request carries one of the API keys
+func (h *DeadLetterHandler) authenticated(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	for _, key := range h.deadLetters.config.DeadLetter.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *DeadLetterHandler) list(w http.ResponseWriter, r *http.Request) {
+	queues, err := h.deadLetters.List(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list dead-letter topics", zap.Error(err))
+		writeAPIError(w, http.StatusBadGateway, "failed to list dead-letter topics")
+		return
+	}
+	if queues == nil {
+		queues = []DeadLetterQueue{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"queues": queues})
+}
+
+func (h *DeadLetterHandler) replay(w http.ResponseWriter, r *http.Request) {
+	var req ReplayRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	if req.Limit < 0 {
+		writeAPIError(w, http.StatusBadRequest, "limit must not be negative")
+		return
+	}
+	if req.Limit == 0 || req.Limit > maxReplayLimit {
+		req.Limit = maxReplayLimit
+	}
+
+	result, err := h.deadLetters.Replay(r.Context(), r.PathValue("topic"), req.Limit)
+	switch {
+	case errors.Is(err, ErrNotDeadLetterTopic):
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrTopicNotFound):
+		writeAPIError(w, http.StatusNotFound, err.Error())
+	case err != nil && result == nil:
+		h.logger.Error("failed to replay dead-letter topic", zap.Error(err))
+		writeAPIError(w, http.StatusBadGateway, "failed to replay dead-letter topic")
+	case err != nil:
+		// Part of the records were replayed; report how many
+		h.logger.Error("failed to replay dead-letter topic", zap.Error(err))
+		writeJSON(w, http.StatusBadGateway, map[string]interface{}{
+			"error":  "replay failed part way",
+			"result": result,
+		})
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// SubscriptionRequest is a webhook subscription submitted to the webhook API
+type SubscriptionRequest struct {
+	URL         string   `json:"url"`
+	EventTypes  []string `json:"event_types"`
+	Description string   `json:"description,omitempty"`
+}
+
+// WebhookHandler serves the HTTP API of the webhook subscriptions of the
+// organization of the request:
+//
+//	GET    /api/v1/events/webhooks
+//	POST   /api/v1/events/webhooks
+//	GET    /api/v1/events/webhooks/{id}
+//	DELETE /api/v1/events/webhooks/{id}
+//	POST   /api/v1/events/webhooks/{id}/enable
+//	GET    /api/v1/events/webhooks/{id}/deliveries
+//
+// The organization is the tenant of the request context, set by
+// tenant.Middleware; requests without one are rejected.
+type WebhookHandler struct {
+	bridge *WebhookBridge
+	logger *zap.Logger
+	mux    *http.ServeMux
+}
+
+// NewWebhookHandler creates the webhook API handler
+func NewWebhookHandler(bridge *WebhookBridge, logger *zap.Logger) *WebhookHandler {
+	h := &WebhookHandler{
+		bridge: bridge,
+		logger: logger,
+		mux:    http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("GET /api/v1/events/webhooks", h.list)
+	h.mux.HandleFunc("POST /api/v1/events/webhooks", h.subscribe)
+	h.mux.HandleFunc("GET /api/v1/events/webhooks/{id}", h.get)
+	h.mux.HandleFunc("DELETE /api/v1/events/webhooks/{id}", h.unsubscribe)
+	h.mux.HandleFunc("POST /api/v1/events/webhooks/{id}/enable", h.enable)
+	h.mux.HandleFunc("GET /api/v1/events/webhooks/{id}/deliveries", h.deliveries)
+
+	return h
+}
+
+// ServeHTTP implements http.Handler. Requests without a tenant are
+// rejected.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, err := tenant.OrganizationID(r.Context()); err != nil {
+		writeAPIError(w, http.StatusUnauthorized, "organization required")
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *WebhookHandler) list(w http.ResponseWriter, r *http.Request) {
+	organizationID, _ := tenant.OrganizationID(r.Context())
+	subscriptions, err := h.bridge.Subscriptions(r.Context(), organizationID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if subscriptions == nil {
+		subscriptions = []WebhookSubscription{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"subscriptions": subscriptions})
+}
+
+// subscribe creates a subscription and returns it with its secret, which is
+// not returned again
+func (h *WebhookHandler) subscribe(w http.ResponseWriter, r *http.Request) {
+	var req SubscriptionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	organizationID, _ := tenant.OrganizationID(r.Context())
+	subscription := &WebhookSubscription{
+		OrganizationID: organizationID,
+		URL:            req.URL,
+		EventTypes:     req.EventTypes,
+		Description:    req.Description,
+	}
+	if err := h.bridge.Subscribe(r.Context(), subscription); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, struct {
+		*WebhookSubscription
+		Secret string `json:"secret"`
+	}{subscription, subscription.Secret})
+}
+
+func (h *WebhookHandler) get(w http.ResponseWriter, r *http.Request) {
+	organizationID, _ := tenant.OrganizationID(r.Context())
+	subscription, err := h.bridge.Subscription(r.Context(), organizationID, r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+func (h *WebhookHandler) unsubscribe(w http.ResponseWriter, r *http.Request) {
+	organizationID, _ := tenant.OrganizationID(r.Context())
+	if err := h.bridge.Unsubscribe(r.Context(), organizationID, r.PathValue("id")); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) enable(w http.ResponseWriter, r *http.Request) {
+	organizationID, _ := tenant.OrganizationID(r.Context())
+	subscription, err := h.bridge.Enable(r.Context(), organizationID, r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+// deliveries lists the latest deliveries of a subscription, filtered by the
+// status query parameter, up to the limit query parameter
+func (h *WebhookHandler) deliveries(w http.ResponseWriter, r *http.Request) {
+	limit := defaultDeliveryLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeAPIError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(parsed, maxDeliveryLimit)
+	}
+	status := WebhookDeliveryStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", WebhookPending, WebhookDelivered, WebhookFailed:
+	default:
+		writeAPIError(w, http.StatusBadRequest, "unsupported status: "+string(status))
+		return
+	}
+
+	organizationID, _ := tenant.OrganizationID(r.Context())
+	deliveries, err := h.bridge.Deliveries(r.Context(), organizationID, r.PathValue("id"), status, limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []WebhookDelivery{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deliveries": deliveries})
+}
+
+// writeError writes the status of an error of the webhook bridge
+func (h *WebhookHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrSubscriptionNotFound):
+		writeAPIError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrInvalidSubscription):
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrTooManySubscriptions):
+		writeAPIError(w, http.StatusConflict, err.Error())
+	default:
+		h.logger.Error("webhook API request failed", zap.Error(err))
+		writeAPIError(w, http.StatusInternalServerError, "internal error")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,436 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+)
+
+// BackfillJob re-consumes a range of a topic into a handler, e.g. to rebuild
+// the state a consumer derives from events
+type BackfillJob struct {
+	// Name of the job; a job run again with the same name resumes from its
+	// checkpoints
+	Name  string
+	Topic string
+
+	// Range of the records of every partition: from From and FromOffset,
+	// until before Until and UntilOffset. Zero values span from the first
+	// retained record to the last record when the job starts.
+	From        time.Time
+	Until       time.Time
+	FromOffset  int64
+	UntilOffset int64
+
+	// Filters; empty matches every record. Event types and organizations
+	// are read from the envelope of records, or default to the topic and
+	// the tenant headers.
+	EventTypes      []string
+	OrganizationIDs []string
+
+	// Records handled per second; 0 does not throttle
+	Rate int
+}
+
+// BackfillProgress is the progress of a backfill job
+type BackfillProgress struct {
+	Job       string `json:"job"`
+	Topic     string `json:"topic"`
+	Handled   int64  `json:"handled"`
+	Filtered  int64  `json:"filtered"`
+	Failed    int64  `json:"failed"`    // Records whose handler failed permanently
+	Remaining int64  `json:"remaining"` // Offsets of the range not reached
+}
+
+// Backfiller runs backfill jobs. Records are handled one at a time per
+// partition, in order, with the tenant and trace context of their headers
+// and their payload decoded, like those of a Consumer.
+// A handler failing with a Permanent error skips its record; other errors
+// stop the job at that record. Progress is checkpointed as the offsets of
+// the consumer group <GroupPrefix><job name>, every CheckpointInterval and
+// when the job stops, so a job run again resumes where it stopped.
+type Backfiller struct {
+	client *kgo.Client
+	admin  *kadm.Client
+	store  ObjectStore // Of claim-checked payloads, if set
+	config *Config
+	logger *zap.Logger
+}
+
+// NewBackfiller creates a backfiller of the cluster of config
+func NewBackfiller(config *Config, logger *zap.Logger) (*Backfiller, error) {
+	if len(config.Brokers) == 0 {
+		return nil, fmt.Errorf("invalid event bus config: kafka brokers are required")
+	}
+	if err := config.Backfill.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid backfill config: %w", err)
+	}
+	if err := config.Payload.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid payload config: %w", err)
+	}
+
+	opts := []kgo.Opt{kgo.SeedBrokers(config.Brokers...)}
+	if config.ClientID != "" {
+		opts = append(opts, kgo.ClientID(config.ClientID))
+	}
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+
+	return &Backfiller{
+		client: client,
+		admin:  kadm.NewClient(client),
+		config: config,
+		logger: logger,
+	}, nil
+}
+
+// SetObjectStore sets the object storage claim-checked payloads are fetched
+// from
+func (b *Backfiller) SetObjectStore(store ObjectStore) {
+	b.store = store
+}
+
+// Close closes the client
+func (b *Backfiller) Close() {
+	b.client.Close()
+}
+
+// backfill is the state of a running job
+type backfill struct {
+	job        *BackfillJob
+	group      string
+	partitions map[int32]*offsetRange
+	eventTypes map[string]bool
+	orgs       map[string]bool
+	progress   *BackfillProgress
+	next       time.Time // Time the next record may be handled, when throttled
+}
+
+// Run runs a job until its range is handled, the handler fails or ctx is
+// done, and returns its progress. The job ends once no record arrives for
+// PollTimeout, e.g. when the range ends with transaction markers.
+func (b *Backfiller) Run(ctx context.Context, job *BackfillJob, handler Handler) (*BackfillProgress, error) {
+	if err := job.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid backfill job: %w", err)
+	}
+
+	run := &backfill{
+		job:        job,
+		group:      b.config.Backfill.GroupPrefix + job.Name,
+		eventTypes: toSet(job.EventTypes),
+		orgs:       toSet(job.OrganizationIDs),
+		progress:   &BackfillProgress{Job: job.Name, Topic: job.Topic},
+	}
+	partitions, err := b.ranges(ctx, job, run.group)
+	if err != nil {
+		return nil, err
+	}
+	run.partitions = partitions
+
+	consume := make(map[int32]kgo.Offset)
+	for partition, r := range partitions {
+		if r.next < r.end {
+			consume[partition] = kgo.NewOffset().At(r.next)
+		}
+	}
+	b.logger.Info("starting backfill",
+		zap.String("job", job.Name),
+		zap.String("topic", job.Topic),
+		zap.Int("partitions", len(consume)))
+	if len(consume) > 0 {
+		err = b.consume(ctx, run, consume, handler)
+	}
+
+	// Checkpoint, even a job that failed part way
+	if checkpointErr := b.checkpoint(context.WithoutCancel(ctx), run); checkpointErr != nil {
+		err = errors.Join(err, checkpointErr)
+	}
+	b.logger.Info("backfill stopped",
+		zap.String("job", job.Name),
+		zap.Int64("handled", run.progress.Handled),
+		zap.Int64("filtered", run.progress.Filtered),
+		zap.Int64("failed", run.progress.Failed),
+		zap.Int64("remaining", run.progress.Remaining),
+		zap.Error(err))
+	return run.progress, err
+}
+
+// ranges returns the range left of each partition: from the checkpoint of
+// the job, or the start of its range, to the end of its range
+func (b *Backfiller) ranges(ctx context.Context, job *BackfillJob, group string) (map[int32]*offsetRange, error) {
+	topics, err := b.admin.ListTopics(ctx, job.Topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topic: %w", err)
+	}
+	if !topics.Has(job.Topic) {
+		return nil, fmt.Errorf("%w: %s", ErrTopicNotFound, job.Topic)
+	}
+
+	starts, err := b.admin.ListStartOffsets(ctx, job.Topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list start offsets: %w", err)
+	}
+	ends, err := b.admin.ListEndOffsets(ctx, job.Topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list end offsets: %w", err)
+	}
+	var fromTime, untilTime kadm.ListedOffsets
+	if !job.From.IsZero() {
+		if fromTime, err = b.admin.ListOffsetsAfterMilli(ctx, job.From.UnixMilli(), job.Topic); err != nil {
+			return nil, fmt.Errorf("failed to list offsets after %s: %w", job.From, err)
+		}
+	}
+	if !job.Until.IsZero() {
+		if untilTime, err = b.admin.ListOffsetsAfterMilli(ctx, job.Until.UnixMilli(), job.Topic); err != nil {
+			return nil, fmt.Errorf("failed to list offsets after %s: %w", job.Until, err)
+		}
+	}
+	checkpoints, err := b.admin.FetchOffsetsForTopics(ctx, group, job.Topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch checkpoints: %w", err)
+	}
+
+	ranges := make(map[int32]*offsetRange)
+	var listErr error
+	ends.Each(func(end kadm.ListedOffset) {
+		start, ok := starts.Lookup(end.Topic, end.Partition)
+		if end.Err != nil || !ok || start.Err != nil {
+			listErr = errors.Join(listErr, end.Err, start.Err)
+			return
+		}
+		r := &offsetRange{start: start.Offset, next: start.Offset, end: end.Offset}
+		if job.FromOffset > r.next {
+			r.next = job.FromOffset
+		}
+		if o, ok := fromTime.Lookup(end.Topic, end.Partition); ok && o.Err == nil && o.Offset > r.next {
+			r.next = o.Offset
+		}
+		if job.UntilOffset > 0 && job.UntilOffset < r.end {
+			r.end = job.UntilOffset
+		}
+		if o, ok := untilTime.Lookup(end.Topic, end.Partition); ok && o.Err == nil && o.Offset < r.end {
+			r.end = o.Offset
+		}
+		if c, ok := checkpoints.Lookup(end.Topic, end.Partition); ok && c.Err == nil && c.At > r.next {
+			r.next = c.At
+		}
+		if r.next > r.end {
+			r.next = r.end
+		}
+		ranges[end.Partition] = r
+	})
+	if listErr != nil {
+		return nil, fmt.Errorf("failed to list offsets: %w", listErr)
+	}
+	return ranges, nil
+}
+
+// consume handles the records of the ranges, checkpointing periodically
+func (b *Backfiller) consume(ctx context.Context, run *backfill, consume map[int32]kgo.Offset, handler Handler) error {
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(b.config.Brokers...),
+		kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{run.job.Topic: consume}),
+	}
+	if b.config.ClientID != "" {
+		opts = append(opts, kgo.ClientID(b.config.ClientID))
+	}
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create kafka client: %w", err)
+	}
+	defer client.Close()
+
+	lastCheckpoint := time.Now()
+	for {
+		pending := false
+		for _, r := range run.partitions {
+			if r.next < r.end {
+				pending = true
+			}
+		}
+		if !pending {
+			return nil
+		}
+
+		pollCtx, cancel := context.WithTimeout(ctx, b.config.Backfill.PollTimeout)
+		fetches := client.PollFetches(pollCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := fetches.Err0(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("failed to fetch records: %w", err)
+		}
+		if fetches.NumRecords() == 0 {
+			return nil
+		}
+
+		var stopped error
+		fetches.EachRecord(func(kr *kgo.Record) {
+			r := run.partitions[kr.Partition]
+			if stopped != nil || r == nil || kr.Offset < r.next || kr.Offset >= r.end {
+				return
+			}
+			if stopped = b.handle(ctx, run, newRecord(kr), handler); stopped != nil {
+				return
+			}
+			r.next = kr.Offset + 1
+		})
+		if stopped != nil {
+			return stopped
+		}
+
+		if time.Since(lastCheckpoint) >= b.config.Backfill.CheckpointInterval {
+			if err := b.checkpoint(ctx, run); err != nil {
+				b.logger.Warn("failed to checkpoint backfill", zap.String("job", run.job.Name), zap.Error(err))
+			}
+			lastCheckpoint = time.Now()
+		}
+	}
+}
+
+// handle decodes, filters, throttles and handles a record. It fails when
+// the payload cannot be fetched or the handler fails with an error that is
+// not permanent, or ctx is done.
+func (b *Backfiller) handle(ctx context.Context, run *backfill, record *Record, handler Handler) error {
+	// Payloads are decoded first, as filters read the envelope
+	err := DecodePayload(ctx, record, b.store, b.config.Payload.MaxDecodedBytes)
+	if err == nil {
+		if !run.matches(record) {
+			run.progress.Filtered++
+			backfillRecordsTotal.WithLabelValues(run.job.Name, "filtered").Inc()
+			return nil
+		}
+
+		if run.job.Rate > 0 {
+			now := time.Now()
+			if run.next.After(now) {
+				if !sleep(ctx, run.next.Sub(now)) {
+					return ctx.Err()
+				}
+			} else {
+				run.next = now
+			}
+			run.next = run.next.Add(time.Second / time.Duration(run.job.Rate))
+		}
+
+		err = handler(recordContext(ctx, record), record)
+	}
+	if err != nil {
+		if !IsPermanent(err) {
+			return fmt.Errorf("failed to handle record %d of partition %d: %w", record.Offset, record.Partition, err)
+		}
+		run.progress.Failed++
+		backfillRecordsTotal.WithLabelValues(run.job.Name, "failed").Inc()
+		b.logger.Warn("skipping record failing permanently",
+			zap.String("job", run.job.Name),
+			zap.Int32("partition", record.Partition),
+			zap.Int64("offset", record.Offset),
+			zap.Error(err))
+		return nil
+	}
+	run.progress.Handled++
+	backfillRecordsTotal.WithLabelValues(run.job.Name, "handled").Inc()
+	return nil
+}
+
+// matches reports whether a record passes the filters of the job
+func (run *backfill) matches(record *Record) bool {
+	if len(run.eventTypes) == 0 && len(run.orgs) == 0 {
+		return true
+	}
+
+	var envelope struct {
+		EventType      string `json:"event_type"`
+		OrganizationID string `json:"organization_id"`
+	}
+	value := record.Value
+	if len(value) > 5 && value[0] == magicByte && value[5] == '{' {
+		value = value[5:]
+	}
+	_ = json.Unmarshal(value, &envelope)
+
+	if len(run.eventTypes) > 0 {
+		eventType := envelope.EventType
+		if eventType == "" {
+			eventType = record.Topic
+		}
+		if !run.eventTypes[eventType] {
+			return false
+		}
+	}
+	if len(run.orgs) > 0 {
+		organizationID := envelope.OrganizationID
+		if organizationID == "" {
+			if t, ok := tenant.ExtractTenant(tenant.MapCarrier(record.Headers)); ok {
+				organizationID = t.OrganizationID
+			}
+		}
+		if !run.orgs[organizationID] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkpoint commits the progress of a job and updates its remaining offsets
+func (b *Backfiller) checkpoint(ctx context.Context, run *backfill) error {
+	offsets := make(kadm.Offsets)
+	run.progress.Remaining = 0
+	for partition, r := range run.partitions {
+		offsets.Add(kadm.Offset{Topic: run.job.Topic, Partition: partition, At: r.next, LeaderEpoch: -1})
+		run.progress.Remaining += r.end - r.next
+	}
+	backfillRemaining.WithLabelValues(run.job.Name).Set(float64(run.progress.Remaining))
+
+	commitCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if _, err := b.admin.CommitOffsets(commitCtx, run.group, offsets); err != nil {
+		return fmt.Errorf("failed to checkpoint backfill: %w", err)
+	}
+	return nil
+}
+
+// Validate validates a backfill job
+func (j *BackfillJob) Validate() error {
+	if j.Name == "" {
+		return fmt.Errorf("job name is required")
+	}
+	if j.Topic == "" {
+		return fmt.Errorf("topic is required")
+	}
+	if j.FromOffset < 0 || j.UntilOffset < 0 {
+		return fmt.Errorf("offsets must not be negative")
+	}
+	if j.UntilOffset > 0 && j.UntilOffset <= j.FromOffset {
+		return fmt.Errorf("until offset must be after from offset")
+	}
+	if !j.Until.IsZero() && !j.Until.After(j.From) {
+		return fmt.Errorf("until must be after from")
+	}
+	if j.Rate < 0 {
+		return fmt.Errorf("rate must not be negative")
+	}
+	return nil
+}
+
+// toSet converts a list to a set
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// EventBus publishes messages to the event bus. It is implemented by
+// Producer for Kafka and JetStreamBus for NATS JetStream, which keep the same
+// topics, keys, headers and envelopes, and write a message published once
+// exactly once.
+type EventBus interface {
+	// Publish serializes value for topic and publishes it, see
+	// Producer.Publish
+	Publish(ctx context.Context, topic string, key string, value interface{}) error
+
+	// PublishMessage publishes a serialized message and waits for it to be
+	// written
+	PublishMessage(ctx context.Context, msg *Message) error
+
+	// SetObjectStore sets the object storage of claim-checked payloads
+	SetObjectStore(store ObjectStore)
+
+	// Close flushes pending messages and closes the bus
+	Close(ctx context.Context) error
+}
+
+// EventConsumer consumes events until the context of Run is done. It is
+// implemented by Consumer for Kafka and JetStreamConsumer for NATS JetStream.
+type EventConsumer interface {
+	Run(ctx context.Context) error
+}
+
+// NewEventBus creates the event bus of Config.Transport
+func NewEventBus(config *Config, logger *zap.Logger) (EventBus, error) {
+	switch config.Transport {
+	case "", TransportKafka:
+		producer, err := NewProducer(config, logger)
+		if err != nil {
+			return nil, err
+		}
+		return producer, nil
+	case TransportNATS:
+		jetStream, err := NewJetStreamBus(config, logger)
+		if err != nil {
+			return nil, err
+		}
+		return jetStream, nil
+	default:
+		return nil, fmt.Errorf("invalid event bus config: unsupported transport: %s", config.Transport)
+	}
+}
+
+// NewEventConsumer creates a consumer of Consumer.Topics on bus, created by
+// NewEventBus, handling records with handler. Dead-lettered records are
+// published with bus.
+func NewEventConsumer(config *Config, bus EventBus, handler Handler, logger *zap.Logger) (EventConsumer, error) {
+	switch bus := bus.(type) {
+	case *Producer:
+		consumer, err := NewConsumer(config, bus, handler, logger)
+		if err != nil {
+			return nil, err
+		}
+		return consumer, nil
+	case *JetStreamBus:
+		consumer, err := NewJetStreamConsumer(config, bus, handler, logger)
+		if err != nil {
+			return nil, err
+		}
+		return consumer, nil
+	default:
+		return nil, fmt.Errorf("unsupported event bus: %T", bus)
+	}
+}
+
+// serializerOf returns the serializer of bus
+func serializerOf(bus EventBus) Serializer {
+	switch bus := bus.(type) {
+	case *Producer:
+		return bus.serializer
+	case *JetStreamBus:
+		return bus.serializer
+	default:
+		return JSONSerializer{}
+	}
+}
+
+// deserializerOf returns the deserializer matching the serializer of bus
+func deserializerOf(bus EventBus) Deserializer {
+	if deserializer, ok := serializerOf(bus).(Deserializer); ok {
+		return deserializer
+	}
+	return JSONSerializer{}
+}
@@ -0,0 +1,667 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package events publishes DictaMesh events to the event bus, Kafka or NATS
+// JetStream
+package events
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Transports of the event bus
+const (
+	TransportKafka = "kafka"
+	TransportNATS  = "nats" // NATS JetStream
+)
+
+// Acknowledgements a write requires from the brokers
+const (
+	AcksAll    = "all"    // Every in-sync replica
+	AcksLeader = "leader" // The partition leader only
+	AcksNone   = "none"   // No acknowledgement
+)
+
+// Compression codecs of record batches
+const (
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+	CompressionSnappy = "snappy"
+	CompressionLZ4    = "lz4"
+	CompressionZstd   = "zstd"
+)
+
+// Compatibility levels of Schema Registry subjects
+const (
+	CompatibilityBackward           = "BACKWARD"
+	CompatibilityBackwardTransitive = "BACKWARD_TRANSITIVE"
+	CompatibilityForward            = "FORWARD"
+	CompatibilityForwardTransitive  = "FORWARD_TRANSITIVE"
+	CompatibilityFull               = "FULL"
+	CompatibilityFullTransitive     = "FULL_TRANSITIVE"
+	CompatibilityNone               = "NONE"
+)
+
+// Config configures the event bus client
+type Config struct {
+	// Transport of the event bus: kafka or nats; defaults to kafka
+	Transport string
+
+	// Seed brokers of the Kafka cluster
+	Brokers []string
+
+	// NATS JetStream, when Transport is nats
+	NATS NATSConfig
+
+	// ClientID identifies the client in broker logs and quotas
+	ClientID string
+
+	// Producer configuration
+	Producer ProducerConfig
+
+	// Compression and size limits of message values
+	Payload PayloadConfig
+
+	// Topic and key of events by event type, e.g. billing.invoice.paid, or
+	// prefix, e.g. billing.*
+	Topics map[string]TopicConfig
+
+	// Consumer configuration
+	Consumer ConsumerConfig
+
+	// Dead-letter topics of the consumers
+	DeadLetter DeadLetterConfig
+
+	// Confluent Schema Registry. When set, messages of topics with a schema
+	// are serialized with it.
+	SchemaRegistryURL string
+	Schemas           SchemaConfig
+
+	// Transactional outbox relay
+	Outbox OutboxConfig
+
+	// Consumer lag monitoring
+	Lag LagConfig
+
+	// Deduplication of consumed events
+	Idempotency IdempotencyConfig
+
+	// Backfill jobs
+	Backfill BackfillConfig
+
+	// Webhook bridge delivering events to the endpoints of organizations
+	Webhooks WebhookConfig
+}
+
+// ProducerConfig configures publishing
+type ProducerConfig struct {
+	// Acks is the acknowledgement a write waits for: all, leader or none
+	Acks string
+
+	// Idempotent writes are deduplicated by the brokers, so a write retried
+	// after a lost response is not stored twice and ordering per partition
+	// is kept across retries. Requires Acks all.
+	Idempotent bool
+
+	// Compression of record batches: none, gzip, snappy, lz4 or zstd
+	Compression string
+
+	// Batching
+	Linger             time.Duration // Time a partition waits for more records before sending a batch
+	MaxMessageBytes    int           // Maximum size of a batch, at most the max.message.bytes of the topics
+	MaxBufferedRecords int           // Records buffered before publishing blocks
+
+	// Timeouts
+	RequestTimeout  time.Duration // Time the brokers have to answer a produce request
+	DeliveryTimeout time.Duration // Time a record has to be written, retries included; 0 for no limit
+}
+
+// NATSConfig configures the NATS JetStream transport. Topics are published
+// as subjects of the same name to a stream holding them; keys and headers
+// are carried as message headers.
+type NATSConfig struct {
+	// Server URLs, e.g. nats://nats:4222
+	URLs []string
+
+	// Credentials file of the connection, if the servers require one
+	CredentialsFile string
+
+	// Stream storing the events, created or updated on connect
+	Stream string
+
+	// Subjects of the stream, e.g. billing.>; every topic published,
+	// dead-letter topics included, must match one
+	Subjects []string
+
+	// Replicas of the stream, at most the servers of the cluster
+	Replicas int
+
+	// Time events are retained; 0 keeps them
+	MaxAge time.Duration
+
+	// Time the stream remembers the ID of a message, dropping messages
+	// published again with the same ID within it
+	DuplicateWindow time.Duration
+
+	// Time a consumer has to acknowledge a message before it is delivered
+	// again; handlers waiting to retry extend it
+	AckWait time.Duration
+
+	Timeout time.Duration // Of a request to the servers
+}
+
+// PayloadConfig configures the encoding of message values. Values are
+// compressed one by one, ahead of the batch compression of Kafka, so that
+// large values fit the limits of the bus; consumers decode them.
+type PayloadConfig struct {
+	// Compression of values larger than CompressAbove bytes: none or gzip
+	Compression   string
+	CompressAbove int
+
+	// Maximum size of a message, key and headers included; 0 is the limit
+	// of the transport: Producer.MaxMessageBytes on Kafka, the maximum
+	// payload of the servers on NATS
+	MaxBytes int
+
+	// Overflow of messages larger than the limit after compression: reject,
+	// or claim-check to store their value in object storage and publish a
+	// reference instead
+	Overflow string
+
+	// Prefix of the object keys of claim-checked values
+	ClaimCheckPrefix string
+
+	// Maximum size of a value once decompressed or fetched by consumers;
+	// larger values fail permanently, so a small compressed record cannot
+	// exhaust their memory
+	MaxDecodedBytes int
+}
+
+// SchemaConfig configures the schemas of the topics
+type SchemaConfig struct {
+	// Directory of the schema files, named after their topic:
+	// <topic>.avsc for Avro, <topic>.schema.json for JSON Schema
+	Directory string
+
+	// AutoRegister registers schemas missing from the registry; otherwise
+	// they must be registered already
+	AutoRegister bool
+
+	// Compatibility level set on the subjects, e.g. BACKWARD; empty keeps
+	// the level of the registry
+	Compatibility string
+
+	// Registry basic auth
+	Username string
+	Password string
+
+	Timeout time.Duration // Of a registry request
+}
+
+// ConsumerConfig configures consuming
+type ConsumerConfig struct {
+	GroupID string   // Consumer group
+	Topics  []string // Topics consumed
+
+	// Consume the tenant topics of Topics too, see TenantTopic, including
+	// those created after the consumer starts
+	TenantTopics bool
+
+	// Retry of failed handlers. Messages whose handler fails MaxAttempts
+	// times, or with a permanent error, are routed to the dead-letter topic.
+	Retry RetryConfig
+}
+
+// RetryConfig configures retry behavior
+type RetryConfig struct {
+	MaxAttempts     int
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          bool
+}
+
+// Backoff returns the wait before retry attempt, counted from 1: the
+// interval grows by Multiplier per attempt up to MaxInterval, with jitter
+// within half the interval either way
+func (c RetryConfig) Backoff(attempt int) time.Duration {
+	multiplier := c.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	// Clamped before converting, since the interval of late attempts
+	// overflows a Duration; the bound leaves room for the jitter
+	limit := float64(math.MaxInt64 / 2)
+	if c.MaxInterval > 0 && float64(c.MaxInterval) < limit {
+		limit = float64(c.MaxInterval)
+	}
+	interval := float64(c.InitialInterval) * math.Pow(multiplier, float64(attempt-1))
+	if interval > limit {
+		interval = limit
+	}
+	wait := time.Duration(interval)
+	if c.Jitter && wait > 0 {
+		wait = time.Duration(rand.Int63n(int64(wait))) + wait/2
+	}
+	return wait
+}
+
+// DeadLetterConfig configures dead-letter topics. The dead-letter topic of a
+// topic and consumer group is <topic>.<group><Suffix>.
+type DeadLetterConfig struct {
+	Suffix string
+
+	// ReplayGroup is the consumer group whose offsets record how far each
+	// dead-letter topic was replayed
+	ReplayGroup string
+
+	// How often the depth of the dead-letter topics is measured
+	MonitorInterval time.Duration
+
+	// Bearer tokens accepted by the dead-letter API
+	APIKeys []string
+}
+
+// LagConfig configures the consumer lag monitor
+type LagConfig struct {
+	// Consumer groups measured; empty measures every group of the cluster
+	Groups []string
+
+	// Time between measurements
+	Interval time.Duration
+
+	// Topic of the lag events published after each measurement, one per
+	// group; empty publishes none
+	Topic string
+}
+
+// IdempotencyConfig configures the deduplication of consumed events
+type IdempotencyConfig struct {
+	// Time an event is claimed by its consumer; a consumer that dies while
+	// processing an event holds it until its claim expires
+	ClaimTTL time.Duration
+
+	// Time processed events are remembered
+	Retention time.Duration
+}
+
+// BackfillConfig configures backfill jobs
+type BackfillConfig struct {
+	// Prefix of the consumer groups holding the checkpoints of jobs
+	GroupPrefix string
+
+	// Time between checkpoints of a running job
+	CheckpointInterval time.Duration
+
+	// Time without records after which a job ends
+	PollTimeout time.Duration
+}
+
+// WebhookConfig configures the webhook bridge
+type WebhookConfig struct {
+	// Retry of failed deliveries. Deliveries failing MaxAttempts times are
+	// given up.
+	Retry RetryConfig
+
+	// Consecutive deliveries given up after which a subscription is disabled
+	DisableAfter int
+
+	// Subscriptions an organization may have
+	MaxSubscriptions int
+
+	// AllowHTTP accepts http:// endpoints, for development; otherwise
+	// endpoints must use HTTPS
+	AllowHTTP bool
+
+	// AllowPrivateNetworks accepts endpoints on loopback, private and
+	// link-local addresses, for development; otherwise endpoints must
+	// resolve to public addresses, when subscribing and when delivering
+	AllowPrivateNetworks bool
+
+	Timeout      time.Duration // Of a delivery request
+	PollInterval time.Duration // Time between polls for due deliveries when idle
+	BatchSize    int           // Deliveries sent per poll
+	Retention    time.Duration // Time finished deliveries are kept; 0 keeps them
+}
+
+// OutboxConfig configures the relay of the transactional outbox
+type OutboxConfig struct {
+	PollInterval time.Duration // Time between polls of the outbox when idle
+	BatchSize    int           // Events relayed per transaction
+	Retention    time.Duration // Time published events are kept; 0 keeps them
+}
+
+// DefaultConfig returns default event bus configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Transport: TransportKafka,
+		ClientID:  "dictamesh",
+		NATS: NATSConfig{
+			Stream:          "DICTAMESH",
+			Subjects:        []string{"billing.>", "dictamesh.>", "events.>", "notifications.>"},
+			Replicas:        1,
+			MaxAge:          7 * 24 * time.Hour,
+			DuplicateWindow: 2 * time.Minute,
+			AckWait:         30 * time.Second,
+			Timeout:         10 * time.Second,
+		},
+		Producer: ProducerConfig{
+			Acks:               AcksAll,
+			Idempotent:         true,
+			Compression:        CompressionLZ4,
+			Linger:             5 * time.Millisecond,
+			MaxMessageBytes:    1000012,
+			MaxBufferedRecords: 10000,
+			RequestTimeout:     10 * time.Second,
+			DeliveryTimeout:    2 * time.Minute,
+		},
+		Payload: PayloadConfig{
+			Compression:      CompressionGzip,
+			CompressAbove:    64 * 1024,
+			Overflow:         OverflowReject,
+			ClaimCheckPrefix: "events/",
+			MaxDecodedBytes:  64 << 20,
+		},
+		Consumer: ConsumerConfig{
+			Retry: RetryConfig{
+				MaxAttempts:     5,
+				InitialInterval: time.Second,
+				MaxInterval:     time.Minute,
+				Multiplier:      2,
+				Jitter:          true,
+			},
+		},
+		DeadLetter: DeadLetterConfig{
+			Suffix:          ".dlq",
+			ReplayGroup:     "dictamesh-dlq-replay",
+			MonitorInterval: 30 * time.Second,
+		},
+		Schemas: SchemaConfig{
+			Timeout: 10 * time.Second,
+		},
+		Outbox: OutboxConfig{
+			PollInterval: time.Second,
+			BatchSize:    100,
+			Retention:    7 * 24 * time.Hour,
+		},
+		Lag: LagConfig{
+			Interval: 30 * time.Second,
+			Topic:    "events.consumer.lag",
+		},
+		Idempotency: IdempotencyConfig{
+			ClaimTTL:  5 * time.Minute,
+			Retention: 7 * 24 * time.Hour,
+		},
+		Backfill: BackfillConfig{
+			GroupPrefix:        "dictamesh-backfill-",
+			CheckpointInterval: 10 * time.Second,
+			PollTimeout:        10 * time.Second,
+		},
+		Webhooks: WebhookConfig{
+			Retry: RetryConfig{
+				MaxAttempts:     8,
+				InitialInterval: time.Minute,
+				MaxInterval:     time.Hour,
+				Multiplier:      2,
+				Jitter:          true,
+			},
+			DisableAfter:     5,
+			MaxSubscriptions: 20,
+			Timeout:          10 * time.Second,
+			PollInterval:     time.Second,
+			BatchSize:        50,
+			Retention:        30 * 24 * time.Hour,
+		},
+	}
+}
+
+// Validate validates the configuration
+func (c *Config) Validate() error {
+	switch c.Transport {
+	case "", TransportKafka:
+		if len(c.Brokers) == 0 {
+			return fmt.Errorf("kafka brokers are required")
+		}
+		if err := c.Producer.Validate(); err != nil {
+			return fmt.Errorf("invalid producer config: %w", err)
+		}
+	case TransportNATS:
+		if err := c.NATS.Validate(); err != nil {
+			return fmt.Errorf("invalid nats config: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported transport: %s", c.Transport)
+	}
+	if c.SchemaRegistryURL != "" {
+		if _, err := url.ParseRequestURI(c.SchemaRegistryURL); err != nil {
+			return fmt.Errorf("invalid schema registry URL: %w", err)
+		}
+		if err := c.Schemas.Validate(); err != nil {
+			return fmt.Errorf("invalid schema config: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate validates the producer configuration
+func (c *ProducerConfig) Validate() error {
+	switch c.Acks {
+	case AcksAll, AcksLeader, AcksNone:
+	default:
+		return fmt.Errorf("unsupported acks: %s", c.Acks)
+	}
+	if c.Idempotent && c.Acks != AcksAll {
+		return fmt.Errorf("idempotent writes require acks %s", AcksAll)
+	}
+
+	switch c.Compression {
+	case "", CompressionNone, CompressionGzip, CompressionSnappy, CompressionLZ4, CompressionZstd:
+	default:
+		return fmt.Errorf("unsupported compression: %s", c.Compression)
+	}
+
+	if c.Linger < 0 {
+		return fmt.Errorf("linger must not be negative")
+	}
+	if c.MaxMessageBytes <= 0 {
+		return fmt.Errorf("max message bytes must be positive")
+	}
+	if c.MaxBufferedRecords <= 0 {
+		return fmt.Errorf("max buffered records must be positive")
+	}
+	if c.RequestTimeout <= 0 {
+		return fmt.Errorf("request timeout must be positive")
+	}
+	if c.DeliveryTimeout < 0 {
+		return fmt.Errorf("delivery timeout must not be negative")
+	}
+	return nil
+}
+
+// Validate validates the payload configuration
+func (c *PayloadConfig) Validate() error {
+	switch c.Compression {
+	case "", CompressionNone, CompressionGzip:
+	default:
+		return fmt.Errorf("unsupported payload compression: %s", c.Compression)
+	}
+	if c.CompressAbove < 0 {
+		return fmt.Errorf("compress above must not be negative")
+	}
+	if c.MaxBytes < 0 {
+		return fmt.Errorf("max bytes must not be negative")
+	}
+	if c.MaxDecodedBytes <= 0 {
+		return fmt.Errorf("max decoded bytes must be positive")
+	}
+	switch c.Overflow {
+	case "", OverflowReject:
+	case OverflowClaimCheck:
+		if c.ClaimCheckPrefix == "" {
+			return fmt.Errorf("claim check prefix is required")
+		}
+	default:
+		return fmt.Errorf("unsupported overflow: %s", c.Overflow)
+	}
+	return nil
+}
+
+// Validate validates the NATS configuration
+func (c *NATSConfig) Validate() error {
+	if len(c.URLs) == 0 {
+		return fmt.Errorf("nats URLs are required")
+	}
+	if c.Stream == "" || strings.ContainsAny(c.Stream, ".*> ") {
+		return fmt.Errorf("invalid stream name: %q", c.Stream)
+	}
+	if len(c.Subjects) == 0 {
+		return fmt.Errorf("stream subjects are required")
+	}
+	if c.Replicas < 1 || c.Replicas > 5 {
+		return fmt.Errorf("replicas must be between 1 and 5")
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("max age must not be negative")
+	}
+	if c.DuplicateWindow <= 0 {
+		return fmt.Errorf("duplicate window must be positive")
+	}
+	if c.AckWait <= 0 {
+		return fmt.Errorf("ack wait must be positive")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	return nil
+}
+
+// Validate validates the consumer configuration
+func (c *ConsumerConfig) Validate() error {
+	if c.GroupID == "" {
+		return fmt.Errorf("consumer group is required")
+	}
+	if len(c.Topics) == 0 {
+		return fmt.Errorf("topics are required")
+	}
+	if c.Retry.MaxAttempts <= 0 {
+		return fmt.Errorf("max attempts must be positive")
+	}
+	if c.Retry.InitialInterval < 0 || c.Retry.MaxInterval < 0 {
+		return fmt.Errorf("retry intervals must not be negative")
+	}
+	return nil
+}
+
+// Validate validates the dead-letter configuration
+func (c *DeadLetterConfig) Validate() error {
+	if c.Suffix == "" || strings.ContainsAny(c.Suffix, "/ ") {
+		return fmt.Errorf("invalid dead-letter topic suffix: %q", c.Suffix)
+	}
+	if c.ReplayGroup == "" {
+		return fmt.Errorf("replay group is required")
+	}
+	if c.MonitorInterval <= 0 {
+		return fmt.Errorf("monitor interval must be positive")
+	}
+	return nil
+}
+
+// Validate validates the schema configuration
+func (c *SchemaConfig) Validate() error {
+	if c.Directory == "" {
+		return fmt.Errorf("schema directory is required")
+	}
+	switch c.Compatibility {
+	case "", CompatibilityBackward, CompatibilityBackwardTransitive,
+		CompatibilityForward, CompatibilityForwardTransitive,
+		CompatibilityFull, CompatibilityFullTransitive, CompatibilityNone:
+	default:
+		return fmt.Errorf("unsupported compatibility level: %s", c.Compatibility)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	return nil
+}
+
+// Validate validates the outbox configuration
+func (c *OutboxConfig) Validate() error {
+	if c.PollInterval <= 0 {
+		return fmt.Errorf("poll interval must be positive")
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("batch size must be positive")
+	}
+	if c.Retention < 0 {
+		return fmt.Errorf("retention must not be negative")
+	}
+	return nil
+}
+
+// Validate validates the lag monitor configuration
+func (c *LagConfig) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("lag interval must be positive")
+	}
+	return nil
+}
+
+// Validate validates the idempotency configuration
+func (c *IdempotencyConfig) Validate() error {
+	if c.ClaimTTL <= 0 {
+		return fmt.Errorf("claim TTL must be positive")
+	}
+	if c.Retention <= 0 {
+		return fmt.Errorf("retention must be positive")
+	}
+	return nil
+}
+
+// Validate validates the backfill configuration
+func (c *BackfillConfig) Validate() error {
+	if c.GroupPrefix == "" {
+		return fmt.Errorf("group prefix is required")
+	}
+	if c.CheckpointInterval <= 0 {
+		return fmt.Errorf("checkpoint interval must be positive")
+	}
+	if c.PollTimeout <= 0 {
+		return fmt.Errorf("poll timeout must be positive")
+	}
+	return nil
+}
+
+// Validate validates the webhook configuration
+func (c *WebhookConfig) Validate() error {
+	if c.Retry.MaxAttempts <= 0 {
+		return fmt.Errorf("max attempts must be positive")
+	}
+	if c.Retry.InitialInterval < 0 || c.Retry.MaxInterval < 0 {
+		return fmt.Errorf("retry intervals must not be negative")
+	}
+	if c.DisableAfter <= 0 {
+		return fmt.Errorf("disable after must be positive")
+	}
+	if c.MaxSubscriptions <= 0 {
+		return fmt.Errorf("max subscriptions must be positive")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	if c.PollInterval <= 0 {
+		return fmt.Errorf("poll interval must be positive")
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("batch size must be positive")
+	}
+	if c.Retention < 0 {
+		return fmt.Errorf("retention must not be negative")
+	}
+	return nil
+}
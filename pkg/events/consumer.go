@@ -0,0 +1,322 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/zap"
+)
+
+// Record is a message received from the event bus
+type Record struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Timestamp time.Time
+}
+
+// Handler handles the records of a consumer. Failed records are retried;
+// errors wrapped with Permanent are not.
+type Handler func(ctx context.Context, record *Record) error
+
+// permanentError is a handler error that retrying does not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a handler error as permanent, such as a record that fails
+// to decode: the record is routed to the dead-letter topic without retries
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Consumer consumes topics as a consumer group. Records are handled one at a
+// time per partition; a failed handler is retried with backoff, blocking its
+// partition, and a record whose handler exhausts its attempts is routed to
+// the dead-letter topic of its topic so the partition moves on. Offsets are
+// committed once records are handled or dead-lettered, so records are
+// delivered at least once.
+//
+// Handlers run with the tenant and trace context of the record headers, and
+// receive the values of compressed and claim-checked records decoded.
+type Consumer struct {
+	client    *kgo.Client
+	processor *processor
+	logger    *zap.Logger
+}
+
+// NewConsumer creates a consumer of Consumer.Topics handling records with
+// handler. Dead-lettered records are published with producer.
+func NewConsumer(config *Config, producer *Producer, handler Handler, logger *zap.Logger) (*Consumer, error) {
+	if len(config.Brokers) == 0 {
+		return nil, fmt.Errorf("invalid event bus config: kafka brokers are required")
+	}
+	if err := config.Consumer.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid consumer config: %w", err)
+	}
+	if err := config.DeadLetter.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid dead-letter config: %w", err)
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(config.Brokers...),
+		kgo.ConsumerGroup(config.Consumer.GroupID),
+		kgo.DisableAutoCommit(),
+		kgo.BlockRebalanceOnPoll(),
+	}
+	if config.Consumer.TenantTopics {
+		patterns := make([]string, len(config.Consumer.Topics))
+		for i, topic := range config.Consumer.Topics {
+			patterns[i] = "^" + regexp.QuoteMeta(topic) + "(" + regexp.QuoteMeta(tenantTopicInfix) + "[^.]+)?$"
+		}
+		opts = append(opts, kgo.ConsumeTopics(patterns...), kgo.ConsumeRegex())
+	} else {
+		opts = append(opts, kgo.ConsumeTopics(config.Consumer.Topics...))
+	}
+	if config.ClientID != "" {
+		opts = append(opts, kgo.ClientID(config.ClientID))
+	}
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+
+	return &Consumer{
+		client: client,
+		processor: &processor{
+			handler: handler,
+			publish: func(ctx context.Context, msg *Message) error {
+				_, err := producer.PublishSync(ctx, msg)
+				return err
+			},
+			payloads: producer.payloads,
+			config:   config,
+			logger:   logger,
+		},
+		logger: logger,
+	}, nil
+}
+
+// Run consumes until ctx is done, then leaves the group
+func (c *Consumer) Run(ctx context.Context) error {
+	defer c.client.Close()
+
+	for {
+		fetches := c.client.PollFetches(ctx)
+		if fetches.IsClientClosed() || ctx.Err() != nil {
+			return nil
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			c.logger.Warn("failed to fetch events",
+				zap.String("topic", topic),
+				zap.Int32("partition", partition),
+				zap.Error(err))
+		})
+
+		var handled []*kgo.Record
+		var stopped error
+		fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+			for _, record := range p.Records {
+				if stopped != nil {
+					return
+				}
+				if err := c.processor.process(ctx, newRecord(record), nil); err != nil {
+					stopped = err
+					return
+				}
+				handled = append(handled, record)
+			}
+		})
+
+		if len(handled) > 0 {
+			// The context may be done; commit what was handled regardless
+			commitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			if err := c.client.CommitRecords(commitCtx, handled...); err != nil {
+				c.logger.Error("failed to commit event offsets", zap.Error(err))
+			}
+			cancel()
+		}
+		c.client.AllowRebalance()
+		if stopped != nil {
+			return nil
+		}
+	}
+}
+
+// processor handles records for a consumer: payloads are decoded, failed
+// handlers are retried with backoff, and records whose attempts are
+// exhausted are published to their dead-letter topic
+type processor struct {
+	handler  Handler
+	publish  func(ctx context.Context, msg *Message) error // Publishes dead-lettered records
+	payloads *payloadCodec                                 // Of the bus publishing dead-lettered records
+	config   *Config
+	logger   *zap.Logger
+}
+
+// process handles a record, retrying failed attempts, and dead-letters it
+// once its attempts are exhausted. heartbeat, if not nil, is called before
+// each wait. It fails only when ctx is done.
+func (p *processor) process(ctx context.Context, record *Record, heartbeat func()) error {
+	handlerCtx := recordContext(ctx, record)
+	retry := p.config.Consumer.Retry
+	wait := func(d time.Duration) bool {
+		if heartbeat != nil {
+			heartbeat()
+		}
+		return sleep(ctx, d)
+	}
+
+	var err error
+	attempt, waits := 0, 0
+	for attempt < retry.MaxAttempts {
+		attempt++
+		if err = p.handle(handlerCtx, record); err == nil {
+			handledTotal.WithLabelValues(record.Topic, "success").Inc()
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if IsPermanent(err) {
+			break
+		}
+		handledTotal.WithLabelValues(record.Topic, "retry").Inc()
+
+		// An event claimed by another consumer is waited for without using up
+		// attempts, until that consumer settles it or its claim expires
+		if errors.Is(err, ErrInProgress) {
+			attempt--
+			waits++
+			p.logger.Debug("event is being processed by another consumer, waiting",
+				zap.String("topic", record.Topic),
+				zap.Int64("offset", record.Offset))
+			if !wait(retry.Backoff(waits)) {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		if attempt < retry.MaxAttempts {
+			p.logger.Warn("failed to handle event, retrying",
+				zap.String("topic", record.Topic),
+				zap.Int64("offset", record.Offset),
+				zap.Int("attempt", attempt),
+				zap.Error(err))
+			if !wait(retry.Backoff(attempt)) {
+				return ctx.Err()
+			}
+		}
+	}
+
+	handledTotal.WithLabelValues(record.Topic, "dead_letter").Inc()
+	p.logger.Error("routing event to the dead-letter topic",
+		zap.String("topic", record.Topic),
+		zap.Int32("partition", record.Partition),
+		zap.Int64("offset", record.Offset),
+		zap.Int("attempts", attempt),
+		zap.Error(err))
+
+	// The partition does not move on until the record is dead-lettered
+	for failures := 1; ; failures++ {
+		dlqErr := p.deadLetter(ctx, record, err, attempt)
+		if dlqErr == nil {
+			return nil
+		}
+		p.logger.Error("failed to publish to the dead-letter topic", zap.Error(dlqErr))
+		if !wait(retry.Backoff(failures)) {
+			return ctx.Err()
+		}
+	}
+}
+
+// handle decodes the payload of a record, once, and handles it
+func (p *processor) handle(ctx context.Context, record *Record) error {
+	if err := DecodePayload(ctx, record, p.payloads.store, p.payloads.config.MaxDecodedBytes); err != nil {
+		return err
+	}
+	return p.handler(ctx, record)
+}
+
+// deadLetter publishes a record to its dead-letter topic, with its headers
+// and the headers describing its origin and failure
+func (p *processor) deadLetter(ctx context.Context, record *Record, cause error, attempts int) error {
+	headers := make(map[string]string, len(record.Headers)+7)
+	for key, value := range record.Headers {
+		headers[key] = value
+	}
+	headers[HeaderDLQTopic] = record.Topic
+	headers[HeaderDLQPartition] = fmt.Sprint(record.Partition)
+	headers[HeaderDLQOffset] = fmt.Sprint(record.Offset)
+	headers[HeaderDLQGroup] = p.config.Consumer.GroupID
+	headers[HeaderDLQError] = cause.Error()
+	headers[HeaderDLQAttempts] = fmt.Sprint(attempts)
+	headers[HeaderDLQFailedAt] = time.Now().UTC().Format(time.RFC3339)
+
+	return p.publish(ctx, &Message{
+		Topic:     p.config.DeadLetter.Topic(record.Topic, p.config.Consumer.GroupID),
+		Key:       record.Key,
+		Value:     record.Value,
+		Headers:   headers,
+		Timestamp: record.Timestamp,
+	})
+}
+
+// newRecord converts a Kafka record
+func newRecord(kr *kgo.Record) *Record {
+	record := &Record{
+		Topic:     kr.Topic,
+		Partition: kr.Partition,
+		Offset:    kr.Offset,
+		Key:       kr.Key,
+		Value:     kr.Value,
+		Headers:   make(map[string]string, len(kr.Headers)),
+		Timestamp: kr.Timestamp,
+	}
+	for _, header := range kr.Headers {
+		record.Headers[header.Key] = string(header.Value)
+	}
+	return record
+}
+
+// recordContext returns ctx with the tenant and trace context of a record
+func recordContext(ctx context.Context, record *Record) context.Context {
+	ctx = tenant.Extract(ctx, tenant.MapCarrier(record.Headers))
+	return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier(record.Headers))
+}
+
+// sleep waits for d and reports whether ctx is still live
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,368 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+)
+
+// Headers of dead-lettered records, describing their origin and failure
+const (
+	HeaderDLQTopic     = "X-DictaMesh-DLQ-Topic"
+	HeaderDLQPartition = "X-DictaMesh-DLQ-Partition"
+	HeaderDLQOffset    = "X-DictaMesh-DLQ-Offset"
+	HeaderDLQGroup     = "X-DictaMesh-DLQ-Group"
+	HeaderDLQError     = "X-DictaMesh-DLQ-Error"
+	HeaderDLQAttempts  = "X-DictaMesh-DLQ-Attempts"
+	HeaderDLQFailedAt  = "X-DictaMesh-DLQ-Failed-At"
+
+	// HeaderDLQReplays counts the replays of a record, so records that keep
+	// failing after replays stand out
+	HeaderDLQReplays = "X-DictaMesh-DLQ-Replays"
+)
+
+var (
+	// ErrNotDeadLetterTopic is returned for replays of topics that are not
+	// dead-letter topics
+	ErrNotDeadLetterTopic = errors.New("not a dead-letter topic")
+
+	// ErrTopicNotFound is returned for replays of topics that do not exist
+	ErrTopicNotFound = errors.New("topic not found")
+)
+
+// Topic returns the dead-letter topic of a topic and consumer group
+func (c *DeadLetterConfig) Topic(topic, group string) string {
+	return topic + "." + group + c.Suffix
+}
+
+// DeadLetterQueue describes a dead-letter topic
+type DeadLetterQueue struct {
+	Topic    string `json:"topic"`
+	Depth    int64  `json:"depth"`    // Records not replayed yet
+	Retained int64  `json:"retained"` // Records retained by the topic, replayed or not
+}
+
+// ReplayResult is the outcome of a replay
+type ReplayResult struct {
+	Topic     string `json:"topic"`
+	Replayed  int    `json:"replayed"`
+	Remaining int64  `json:"remaining"`
+}
+
+// DeadLetters inspects and replays the dead-letter topics. Replays publish
+// records back to the topic they failed on, for their consumers to handle
+// again once the cause is fixed, and record their progress as the offsets of
+// the ReplayGroup consumer group, so each record is replayed once.
+type DeadLetters struct {
+	client   *kgo.Client
+	admin    *kadm.Client
+	producer *Producer
+	config   *Config
+	logger   *zap.Logger
+
+	mu sync.Mutex // Serializes replays
+}
+
+// NewDeadLetters creates a client of the dead-letter topics of the cluster
+// of config. Replayed records are published with producer.
+func NewDeadLetters(config *Config, producer *Producer, logger *zap.Logger) (*DeadLetters, error) {
+	if len(config.Brokers) == 0 {
+		return nil, fmt.Errorf("invalid event bus config: kafka brokers are required")
+	}
+	if err := config.DeadLetter.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid dead-letter config: %w", err)
+	}
+
+	opts := []kgo.Opt{kgo.SeedBrokers(config.Brokers...)}
+	if config.ClientID != "" {
+		opts = append(opts, kgo.ClientID(config.ClientID))
+	}
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+
+	return &DeadLetters{
+		client:   client,
+		admin:    kadm.NewClient(client),
+		producer: producer,
+		config:   config,
+		logger:   logger,
+	}, nil
+}
+
+// Close closes the client
+func (d *DeadLetters) Close() {
+	d.client.Close()
+}
+
+// Monitor measures the depth of the dead-letter topics every
+// MonitorInterval until ctx is done
+func (d *DeadLetters) Monitor(ctx context.Context) {
+	ticker := time.NewTicker(d.config.DeadLetter.MonitorInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.List(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Warn("failed to measure dead-letter topics", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// List returns the dead-letter topics with their depth
+func (d *DeadLetters) List(ctx context.Context) ([]DeadLetterQueue, error) {
+	topics, err := d.admin.ListTopics(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list topics: %w", err)
+	}
+	var names []string
+	for _, name := range topics.Names() {
+		if strings.HasSuffix(name, d.config.DeadLetter.Suffix) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	ranges, err := d.ranges(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+
+	queues := make([]DeadLetterQueue, 0, len(names))
+	for _, name := range names {
+		queue := DeadLetterQueue{Topic: name}
+		for _, r := range ranges[name] {
+			queue.Depth += r.end - r.next
+			queue.Retained += r.end - r.start
+		}
+		dlqDepth.WithLabelValues(name).Set(float64(queue.Depth))
+		queues = append(queues, queue)
+	}
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Topic < queues[j].Topic })
+	return queues, nil
+}
+
+// offsetRange is the range of a partition left to replay
+type offsetRange struct {
+	start int64 // First retained offset
+	next  int64 // Next offset to replay
+	end   int64 // Offset after the last record
+}
+
+// ranges returns the offsets left to replay per topic and partition
+func (d *DeadLetters) ranges(ctx context.Context, topics ...string) (map[string]map[int32]*offsetRange, error) {
+	starts, err := d.admin.ListStartOffsets(ctx, topics...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list start offsets: %w", err)
+	}
+	ends, err := d.admin.ListEndOffsets(ctx, topics...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list end offsets: %w", err)
+	}
+	committed, err := d.admin.FetchOffsetsForTopics(ctx, d.config.DeadLetter.ReplayGroup, topics...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch replay offsets: %w", err)
+	}
+
+	ranges := make(map[string]map[int32]*offsetRange, len(topics))
+	var listErr error
+	ends.Each(func(end kadm.ListedOffset) {
+		start, ok := starts.Lookup(end.Topic, end.Partition)
+		if end.Err != nil || !ok || start.Err != nil {
+			listErr = errors.Join(listErr, end.Err, start.Err)
+			return
+		}
+		r := &offsetRange{start: start.Offset, next: start.Offset, end: end.Offset}
+		if c, ok := committed.Lookup(end.Topic, end.Partition); ok && c.Err == nil && c.At > r.next {
+			r.next = c.At
+		}
+		if r.next > r.end {
+			r.next = r.end
+		}
+		if ranges[end.Topic] == nil {
+			ranges[end.Topic] = make(map[int32]*offsetRange)
+		}
+		ranges[end.Topic][end.Partition] = r
+	})
+	if listErr != nil {
+		return nil, fmt.Errorf("failed to list offsets: %w", listErr)
+	}
+	return ranges, nil
+}
+
+// Replay publishes up to limit records of a dead-letter topic back to the
+// topics they failed on, oldest first per partition, and returns how many
+// were replayed; without a limit, every record not replayed yet is. Records
+// keep their key, value and headers, except the dead-letter headers.
+func (d *DeadLetters) Replay(ctx context.Context, topic string, limit int) (*ReplayResult, error) {
+	if !strings.HasSuffix(topic, d.config.DeadLetter.Suffix) {
+		return nil, fmt.Errorf("%w: %s", ErrNotDeadLetterTopic, topic)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	topics, err := d.admin.ListTopics(ctx, topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topic: %w", err)
+	}
+	if !topics.Has(topic) {
+		return nil, fmt.Errorf("%w: %s", ErrTopicNotFound, topic)
+	}
+
+	ranges, err := d.ranges(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	partitions := ranges[topic]
+
+	result := &ReplayResult{Topic: topic}
+	consume := make(map[int32]kgo.Offset)
+	for partition, r := range partitions {
+		if r.next < r.end {
+			consume[partition] = kgo.NewOffset().At(r.next)
+		}
+	}
+	if len(consume) > 0 {
+		err = d.replay(ctx, topic, partitions, consume, limit, result)
+	}
+
+	// Record the progress, even of a replay that failed part way
+	offsets := make(kadm.Offsets)
+	for partition, r := range partitions {
+		offsets.Add(kadm.Offset{Topic: topic, Partition: partition, At: r.next, LeaderEpoch: -1})
+		result.Remaining += r.end - r.next
+	}
+	commitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if _, commitErr := d.admin.CommitOffsets(commitCtx, d.config.DeadLetter.ReplayGroup, offsets); commitErr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to commit replay offsets: %w", commitErr))
+	}
+	dlqDepth.WithLabelValues(topic).Set(float64(result.Remaining))
+
+	d.logger.Info("replayed dead-letter topic",
+		zap.String("topic", topic),
+		zap.Int("replayed", result.Replayed),
+		zap.Int64("remaining", result.Remaining),
+		zap.Error(err))
+	return result, err
+}
+
+// replay consumes the partitions of a dead-letter topic from their next
+// offset and publishes their records back, advancing the ranges
+func (d *DeadLetters) replay(
+	ctx context.Context,
+	topic string,
+	partitions map[int32]*offsetRange,
+	consume map[int32]kgo.Offset,
+	limit int,
+	result *ReplayResult,
+) error {
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(d.config.Brokers...),
+		kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{topic: consume}),
+	}
+	if d.config.ClientID != "" {
+		opts = append(opts, kgo.ClientID(d.config.ClientID))
+	}
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create kafka client: %w", err)
+	}
+	defer client.Close()
+
+	for {
+		if limit > 0 && result.Replayed >= limit {
+			return nil
+		}
+		pending := false
+		for _, r := range partitions {
+			if r.next < r.end {
+				pending = true
+			}
+		}
+		if !pending {
+			return nil
+		}
+
+		// Records below the end offsets may be missing, e.g. transaction
+		// markers; a poll returning nothing ends the replay
+		pollCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		fetches := client.PollFetches(pollCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := fetches.Err0(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("failed to fetch dead-lettered records: %w", err)
+		}
+		if fetches.NumRecords() == 0 {
+			return nil
+		}
+
+		var replayErr error
+		fetches.EachRecord(func(kr *kgo.Record) {
+			r := partitions[kr.Partition]
+			if replayErr != nil || r == nil || kr.Offset < r.next || kr.Offset >= r.end {
+				return
+			}
+			if limit > 0 && result.Replayed >= limit {
+				return
+			}
+			if replayErr = d.republish(ctx, newRecord(kr)); replayErr != nil {
+				return
+			}
+			r.next = kr.Offset + 1
+			result.Replayed++
+			dlqReplayedTotal.WithLabelValues(topic).Inc()
+		})
+		if replayErr != nil {
+			return replayErr
+		}
+	}
+}
+
+// republish publishes a dead-lettered record to the topic it failed on
+func (d *DeadLetters) republish(ctx context.Context, record *Record) error {
+	origin := record.Headers[HeaderDLQTopic]
+	if origin == "" {
+		return fmt.Errorf("record %d of %s has no %s header", record.Offset, record.Topic, HeaderDLQTopic)
+	}
+
+	replays, _ := strconv.Atoi(record.Headers[HeaderDLQReplays])
+	headers := make(map[string]string, len(record.Headers))
+	for key, value := range record.Headers {
+		if !strings.HasPrefix(key, "X-DictaMesh-DLQ-") {
+			headers[key] = value
+		}
+	}
+	headers[HeaderDLQReplays] = strconv.Itoa(replays + 1)
+
+	_, err := d.producer.PublishSync(ctx, &Message{
+		Topic:   origin,
+		Key:     record.Key,
+		Value:   record.Value,
+		Headers: headers,
+	})
+	return err
+}
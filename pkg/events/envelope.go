@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// SpecVersion is the version of the envelope format
+const SpecVersion = "1.0"
+
+// Envelope is the canonical form of the events of the bus, modeled on
+// CloudEvents. The event itself is Data; the envelope carries what every
+// consumer needs to deduplicate, route and trace it.
+type Envelope struct {
+	SpecVersion    string          `json:"specversion"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Source         string          `json:"source"` // Publishing service, e.g. dictamesh/billing
+	OccurredAt     time.Time       `json:"occurred_at"`
+	OrganizationID string          `json:"organization_id,omitempty"`
+	TraceParent    string          `json:"traceparent,omitempty"` // W3C trace context of the publisher
+	TraceState     string          `json:"tracestate,omitempty"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// NewEnvelope wraps data, encoded as JSON unless it is a []byte, in an
+// envelope with a new event ID. The organization and trace context are taken
+// from ctx.
+func NewEnvelope(ctx context.Context, eventType, source string, data interface{}) (*Envelope, error) {
+	payload, err := encode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	envelope := &Envelope{
+		SpecVersion: SpecVersion,
+		EventID:     uuid.NewString(),
+		EventType:   eventType,
+		Source:      source,
+		OccurredAt:  time.Now().UTC(),
+		Data:        payload,
+	}
+	if t, ok := tenant.FromContext(ctx); ok {
+		envelope.OrganizationID = t.OrganizationID
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	envelope.TraceParent = carrier.Get("traceparent")
+	envelope.TraceState = carrier.Get("tracestate")
+	return envelope, nil
+}
+
+// Decode decodes the data of the event into out
+func (e *Envelope) Decode(out interface{}) error {
+	if err := json.Unmarshal(e.Data, out); err != nil {
+		return fmt.Errorf("invalid %s event data: %w", e.EventType, err)
+	}
+	return nil
+}
+
+// Context returns ctx with the trace context and organization of the event,
+// so handling the event continues the trace of its publisher
+func (e *Envelope) Context(ctx context.Context) context.Context {
+	if e.TraceParent != "" {
+		ctx = propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{
+			"traceparent": e.TraceParent,
+			"tracestate":  e.TraceState,
+		})
+	}
+	if e.OrganizationID != "" {
+		if _, ok := tenant.FromContext(ctx); !ok {
+			ctx = tenant.WithOrganization(ctx, e.OrganizationID)
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,298 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrInProgress is returned when claiming an event another consumer is
+// processing. Consumers wait for such events without using up attempts, so
+// they are not dead-lettered while the other consumer is at work.
+var ErrInProgress = errors.New("event is being processed by another consumer")
+
+// IdempotencyStore records the events processed by consumers, per scope:
+// usually the consumer group, as each group processes every event
+type IdempotencyStore interface {
+	// Claim claims an event for processing until ClaimTTL. It returns false
+	// for an event processed already, and ErrInProgress while another
+	// consumer holds a claim.
+	Claim(ctx context.Context, scope, key string) (bool, error)
+
+	// Complete records a claimed event processed
+	Complete(ctx context.Context, scope, key string) error
+
+	// Release drops the claim of an event that failed, so it is processed
+	// again when redelivered
+	Release(ctx context.Context, scope, key string) error
+}
+
+// idempotencyKey is the context key of the event processed by Idempotent
+type idempotencyKey struct{}
+
+type processing struct {
+	scope string
+	key   string
+}
+
+// Idempotent wraps handler to process each event once per scope. Events are
+// identified by their HeaderDedupKey, or the event_id of their value; events
+// without either are handled as they come. Redelivered events that were
+// processed are skipped, events that fail are released to be retried, and
+// events claimed by another consumer fail with ErrInProgress; consumers
+// wait for them until that consumer is done or its claim expires.
+//
+// An event is recorded processed after its handler returns, so a consumer
+// that dies in between processes it again. Handlers writing to the database
+// of a PostgresIdempotencyStore close that gap with CompleteTx.
+func Idempotent(store IdempotencyStore, scope string, logger *zap.Logger, handler Handler) Handler {
+	return func(ctx context.Context, record *Record) error {
+		key := EventKey(record)
+		if key == "" {
+			return handler(ctx, record)
+		}
+
+		claimed, err := store.Claim(ctx, scope, key)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			idempotencyTotal.WithLabelValues(scope, "duplicate").Inc()
+			logger.Debug("skipping processed event",
+				zap.String("scope", scope),
+				zap.String("key", key),
+				zap.String("topic", record.Topic),
+				zap.Int64("offset", record.Offset))
+			return nil
+		}
+
+		// The claim is settled even when ctx is done
+		settleCtx := context.WithoutCancel(ctx)
+		if err := handler(context.WithValue(ctx, idempotencyKey{}, processing{scope: scope, key: key}), record); err != nil {
+			if releaseErr := store.Release(settleCtx, scope, key); releaseErr != nil {
+				logger.Warn("failed to release event claim",
+					zap.String("scope", scope),
+					zap.String("key", key),
+					zap.Error(releaseErr))
+			}
+			return err
+		}
+
+		idempotencyTotal.WithLabelValues(scope, "processed").Inc()
+		if err := store.Complete(settleCtx, scope, key); err != nil {
+			// The event was processed; failing would process it again
+			logger.Error("failed to record processed event",
+				zap.String("scope", scope),
+				zap.String("key", key),
+				zap.Error(err))
+		}
+		return nil
+	}
+}
+
+// EventKey returns the key identifying the event of a record: its
+// HeaderDedupKey, or the event_id of its JSON value, plain or in the wire
+// format of the schema registry. It is empty when the record has neither.
+func EventKey(record *Record) string {
+	if key := record.Headers[HeaderDedupKey]; key != "" {
+		return key
+	}
+
+	value := record.Value
+	if len(value) > 5 && value[0] == magicByte && value[5] == '{' {
+		value = value[5:]
+	}
+	var event struct {
+		EventID string `json:"event_id"`
+	}
+	if err := json.Unmarshal(value, &event); err != nil {
+		return ""
+	}
+	return event.EventID
+}
+
+// ============================================================================
+// PostgreSQL
+// ============================================================================
+
+// PostgresIdempotencyStore records processed events in
+// dictamesh_event_idempotency
+type PostgresIdempotencyStore struct {
+	db     *gorm.DB
+	config *IdempotencyConfig
+}
+
+// NewPostgresIdempotencyStore creates an idempotency store in db
+func NewPostgresIdempotencyStore(db *gorm.DB, config *IdempotencyConfig) (*PostgresIdempotencyStore, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid idempotency config: %w", err)
+	}
+	return &PostgresIdempotencyStore{db: db, config: config}, nil
+}
+
+// Claim implements IdempotencyStore. Expired claims of events not processed
+// are taken over.
+func (s *PostgresIdempotencyStore) Claim(ctx context.Context, scope, key string) (bool, error) {
+	result := s.db.WithContext(ctx).Exec(`
+		INSERT INTO dictamesh_event_idempotency (scope, event_key, claimed_until)
+		VALUES (?, ?, NOW() + ? * INTERVAL '1 millisecond')
+		ON CONFLICT (scope, event_key) DO UPDATE SET claimed_until = EXCLUDED.claimed_until
+		WHERE dictamesh_event_idempotency.processed_at IS NULL
+			AND dictamesh_event_idempotency.claimed_until < NOW()`,
+		scope, key, s.config.ClaimTTL.Milliseconds())
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim event: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	var processed bool
+	if err := s.db.WithContext(ctx).Raw(`
+		SELECT processed_at IS NOT NULL FROM dictamesh_event_idempotency
+		WHERE scope = ? AND event_key = ?`, scope, key).Scan(&processed).Error; err != nil {
+		return false, fmt.Errorf("failed to claim event: %w", err)
+	}
+	if processed {
+		return false, nil
+	}
+	return false, ErrInProgress
+}
+
+// Complete implements IdempotencyStore
+func (s *PostgresIdempotencyStore) Complete(ctx context.Context, scope, key string) error {
+	return s.complete(s.db.WithContext(ctx), scope, key)
+}
+
+// CompleteTx records the event handled by Idempotent with ctx processed in
+// tx, so the event is recorded processed if and only if the changes of its
+// handler commit. It does nothing outside Idempotent handlers.
+func (s *PostgresIdempotencyStore) CompleteTx(ctx context.Context, tx *gorm.DB) error {
+	p, ok := ctx.Value(idempotencyKey{}).(processing)
+	if !ok {
+		return nil
+	}
+	return s.complete(tx.WithContext(ctx), p.scope, p.key)
+}
+
+func (s *PostgresIdempotencyStore) complete(db *gorm.DB, scope, key string) error {
+	if err := db.Exec(`
+		INSERT INTO dictamesh_event_idempotency (scope, event_key, claimed_until, processed_at)
+		VALUES (?, ?, NOW(), NOW())
+		ON CONFLICT (scope, event_key) DO UPDATE
+		SET processed_at = COALESCE(dictamesh_event_idempotency.processed_at, NOW())`,
+		scope, key).Error; err != nil {
+		return fmt.Errorf("failed to record processed event: %w", err)
+	}
+	return nil
+}
+
+// Release implements IdempotencyStore
+func (s *PostgresIdempotencyStore) Release(ctx context.Context, scope, key string) error {
+	if err := s.db.WithContext(ctx).Exec(`
+		DELETE FROM dictamesh_event_idempotency
+		WHERE scope = ? AND event_key = ? AND processed_at IS NULL`, scope, key).Error; err != nil {
+		return fmt.Errorf("failed to release event claim: %w", err)
+	}
+	return nil
+}
+
+// Cleanup deletes the events processed before Retention and the claims
+// expired as long ago, and returns how many were deleted. It should run
+// periodically, e.g. as a scheduler job.
+func (s *PostgresIdempotencyStore) Cleanup(ctx context.Context) (int64, error) {
+	cutoff := time.Now().Add(-s.config.Retention)
+	result := s.db.WithContext(ctx).Exec(`
+		DELETE FROM dictamesh_event_idempotency
+		WHERE processed_at < ? OR (processed_at IS NULL AND claimed_until < ?)`, cutoff, cutoff)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete processed events: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// ============================================================================
+// Redis
+// ============================================================================
+
+// RedisIdempotencyStore records processed events as Redis keys, which
+// expire after ClaimTTL while claimed and after Retention once processed
+type RedisIdempotencyStore struct {
+	client redis.UniversalClient
+	config *IdempotencyConfig
+}
+
+// NewRedisIdempotencyStore creates an idempotency store in Redis
+func NewRedisIdempotencyStore(client redis.UniversalClient, config *IdempotencyConfig) (*RedisIdempotencyStore, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid idempotency config: %w", err)
+	}
+	return &RedisIdempotencyStore{client: client, config: config}, nil
+}
+
+// Values of the keys of events
+const (
+	redisClaimed   = "claimed"
+	redisProcessed = "processed"
+)
+
+// Release only events still claimed
+var redisReleaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+func redisIdempotencyKey(scope, key string) string {
+	return "dictamesh:events:idempotency:" + scope + ":" + key
+}
+
+// Claim implements IdempotencyStore
+func (s *RedisIdempotencyStore) Claim(ctx context.Context, scope, key string) (bool, error) {
+	redisKey := redisIdempotencyKey(scope, key)
+	claimed, err := s.client.SetNX(ctx, redisKey, redisClaimed, s.config.ClaimTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim event: %w", err)
+	}
+	if claimed {
+		return true, nil
+	}
+
+	state, err := s.client.Get(ctx, redisKey).Result()
+	switch {
+	case errors.Is(err, redis.Nil):
+		// The claim expired in between
+		return s.Claim(ctx, scope, key)
+	case err != nil:
+		return false, fmt.Errorf("failed to claim event: %w", err)
+	case state == redisProcessed:
+		return false, nil
+	default:
+		return false, ErrInProgress
+	}
+}
+
+// Complete implements IdempotencyStore
+func (s *RedisIdempotencyStore) Complete(ctx context.Context, scope, key string) error {
+	if err := s.client.Set(ctx, redisIdempotencyKey(scope, key), redisProcessed, s.config.Retention).Err(); err != nil {
+		return fmt.Errorf("failed to record processed event: %w", err)
+	}
+	return nil
+}
+
+// Release implements IdempotencyStore
+func (s *RedisIdempotencyStore) Release(ctx context.Context, scope, key string) error {
+	if err := redisReleaseScript.Run(ctx, s.client, []string{redisIdempotencyKey(scope, key)}, redisClaimed).Err(); err != nil {
+		return fmt.Errorf("failed to release event claim: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,390 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// HeaderKey carries the key of a message over NATS JetStream, which has no
+// message keys
+const HeaderKey = "X-DictaMesh-Key"
+
+// jetStreamOverhead is the room left in the maximum payload of the servers
+// for the header framing and message ID of a message
+const jetStreamOverhead = 512
+
+// jetStreamRetry is the backoff of publishes retried after a timeout or
+// while the stream has no leader
+var jetStreamRetry = RetryConfig{
+	InitialInterval: 100 * time.Millisecond,
+	MaxInterval:     5 * time.Second,
+	Multiplier:      2,
+	Jitter:          true,
+}
+
+// JetStreamBus publishes messages to a NATS JetStream stream, each topic as
+// the subject of the same name. Publishes wait for the stream to store the
+// message and are retried with the same message ID until
+// Producer.DeliveryTimeout, so the stream drops the copies of a retry within
+// DuplicateWindow: a message published once is stored once. Envelopes are
+// identified by their event ID and outbox events by their dedup key, so
+// publishing them again to the same topic within the window is dropped too.
+//
+// Messages carry the tenant and trace context of the context they are
+// published with as headers, and their key in HeaderKey.
+type JetStreamBus struct {
+	conn       *nats.Conn
+	js         jetstream.JetStream
+	config     *Config
+	serializer Serializer
+	payloads   *payloadCodec
+	logger     *zap.Logger
+}
+
+// NewJetStreamBus connects to the NATS servers of config and creates or
+// updates the stream of the events
+func NewJetStreamBus(config *Config, logger *zap.Logger) (*JetStreamBus, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid event bus config: %w", err)
+	}
+	if config.Transport != TransportNATS {
+		return nil, fmt.Errorf("invalid event bus config: transport is not %s", TransportNATS)
+	}
+
+	serializer, err := newSerializer(config)
+	if err != nil {
+		return nil, err
+	}
+	payloads, err := newPayloadCodec(&config.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []nats.Option{
+		nats.Timeout(config.NATS.Timeout),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logger.Warn("disconnected from nats", zap.Error(err))
+			}
+		}),
+	}
+	if config.ClientID != "" {
+		opts = append(opts, nats.Name(config.ClientID))
+	}
+	if config.NATS.CredentialsFile != "" {
+		opts = append(opts, nats.UserCredentials(config.NATS.CredentialsFile))
+	}
+	conn, err := nats.Connect(strings.Join(config.NATS.URLs, ","), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.NATS.Timeout)
+	defer cancel()
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       config.NATS.Stream,
+		Subjects:   config.NATS.Subjects,
+		Retention:  jetstream.LimitsPolicy,
+		Storage:    jetstream.FileStorage,
+		Replicas:   config.NATS.Replicas,
+		MaxAge:     config.NATS.MaxAge,
+		Duplicates: config.NATS.DuplicateWindow,
+	}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create stream %s: %w", config.NATS.Stream, err)
+	}
+
+	return &JetStreamBus{
+		conn:       conn,
+		js:         js,
+		config:     config,
+		serializer: serializer,
+		payloads:   payloads,
+		logger:     logger,
+	}, nil
+}
+
+// SetObjectStore sets the object storage of claim-checked payloads, see
+// PayloadConfig.Overflow. It must be set before publishing; consumers
+// created with the bus fetch claim-checked payloads from it.
+func (b *JetStreamBus) SetObjectStore(store ObjectStore) {
+	b.payloads.store = store
+}
+
+// Publish publishes value to topic and waits for it to be stored. value is
+// serialized as with Producer.Publish. Publish implements EventBus and the
+// EventBus interface of the billing package.
+func (b *JetStreamBus) Publish(ctx context.Context, topic string, key string, value interface{}) error {
+	data, err := b.serializer.Serialize(ctx, topic, value)
+	if err != nil {
+		return fmt.Errorf("failed to encode event for %s: %w", topic, err)
+	}
+
+	var id string
+	if envelope, ok := value.(*Envelope); ok {
+		id = envelope.EventID
+	}
+	return b.send(ctx, &Message{
+		Topic: topic,
+		Key:   []byte(key),
+		Value: data,
+	}, id)
+}
+
+// PublishMessage publishes a message and waits for it to be stored. It is
+// identified by its HeaderDedupKey, if set. Tenant and trace headers of ctx
+// are added unless the message sets them; Timestamp is ignored, the stream
+// stamps messages as it stores them. Messages larger than the maximum
+// payload of the servers after compression fail with a MessageTooLargeError,
+// unless claim-checked.
+func (b *JetStreamBus) PublishMessage(ctx context.Context, msg *Message) error {
+	return b.send(ctx, msg, msg.Headers[HeaderDedupKey])
+}
+
+// send publishes a message identified by id within its topic, or by a new
+// ID if empty
+func (b *JetStreamBus) send(ctx context.Context, msg *Message, id string) error {
+	start := time.Now()
+
+	encoded, err := b.payloads.encode(ctx, msg, int(b.conn.MaxPayload())-jetStreamOverhead)
+	if err == nil {
+		if id == "" {
+			id = uuid.NewString()
+		}
+		err = b.publish(ctx, natsMessage(encoded), msg.Topic+"/"+id)
+	}
+
+	publishDuration.WithLabelValues(msg.Topic).Observe(time.Since(start).Seconds())
+	if err != nil {
+		publishedTotal.WithLabelValues(msg.Topic, "error").Inc()
+		return fmt.Errorf("failed to publish to %s: %w", msg.Topic, err)
+	}
+	publishedTotal.WithLabelValues(msg.Topic, "success").Inc()
+	publishedBytes.WithLabelValues(msg.Topic).Add(float64(len(encoded.Key) + len(encoded.Value)))
+	return nil
+}
+
+// natsMessage converts a message, with its headers, to the message of its
+// subject
+func natsMessage(msg *Message) *nats.Msg {
+	natsMsg := &nats.Msg{
+		Subject: msg.Topic,
+		Data:    msg.Value,
+		Header:  make(nats.Header, len(msg.Headers)+1),
+	}
+	for key, value := range msg.Headers {
+		natsMsg.Header[key] = []string{value}
+	}
+	if len(msg.Key) > 0 {
+		natsMsg.Header[HeaderKey] = []string{string(msg.Key)}
+	}
+	return natsMsg
+}
+
+// publish publishes a message with its ID, retrying failures the stream did
+// not answer with an error until DeliveryTimeout or ctx is done
+func (b *JetStreamBus) publish(ctx context.Context, msg *nats.Msg, id string) error {
+	if timeout := b.config.Producer.DeliveryTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, b.config.NATS.Timeout)
+		_, err := b.js.PublishMsg(attemptCtx, msg, jetstream.WithMsgID(id))
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		var apiErr *jetstream.APIError
+		if errors.As(err, &apiErr) {
+			return err
+		}
+		b.logger.Debug("failed to publish event, retrying",
+			zap.String("topic", msg.Subject),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+		if !sleep(ctx, jetStreamRetry.Backoff(attempt)) {
+			return err
+		}
+	}
+}
+
+// Close drains the connection, waiting for pending publishes until ctx is
+// done
+func (b *JetStreamBus) Close(ctx context.Context) error {
+	closed := make(chan struct{})
+	b.conn.SetClosedHandler(func(*nats.Conn) { close(closed) })
+	if err := b.conn.Drain(); err != nil {
+		b.conn.Close()
+		return err
+	}
+
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		b.conn.Close()
+		return ctx.Err()
+	}
+}
+
+// JetStreamConsumer consumes topics from a NATS JetStream stream with a
+// durable consumer named after the consumer group. It handles records like
+// Consumer: one at a time, in stream order, retrying failed handlers with
+// backoff and routing records whose attempts are exhausted to their
+// dead-letter topic. Records are acknowledged once handled or dead-lettered,
+// so they are delivered at least once; a record not acknowledged within
+// AckWait, such as one of a consumer that died, is delivered again.
+//
+// Records carry their stream sequence as Offset, in partition 0.
+type JetStreamConsumer struct {
+	consumer  jetstream.Consumer
+	processor *processor
+	logger    *zap.Logger
+}
+
+// NewJetStreamConsumer creates or updates the durable consumer of
+// Consumer.GroupID on the stream of bus, handling records with handler.
+// Dead-lettered records are published with bus.
+func NewJetStreamConsumer(config *Config, bus *JetStreamBus, handler Handler, logger *zap.Logger) (*JetStreamConsumer, error) {
+	if err := config.Consumer.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid consumer config: %w", err)
+	}
+	if strings.ContainsAny(config.Consumer.GroupID, ".*> ") {
+		return nil, fmt.Errorf("invalid consumer config: consumer group %q is not a valid durable name", config.Consumer.GroupID)
+	}
+	if err := config.DeadLetter.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid dead-letter config: %w", err)
+	}
+
+	subjects := config.Consumer.Topics
+	if config.Consumer.TenantTopics {
+		subjects = make([]string, 0, 2*len(config.Consumer.Topics))
+		for _, topic := range config.Consumer.Topics {
+			subjects = append(subjects, topic, topic+tenantTopicInfix+"*")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.NATS.Timeout)
+	defer cancel()
+	consumer, err := bus.js.CreateOrUpdateConsumer(ctx, config.NATS.Stream, jetstream.ConsumerConfig{
+		Durable:        config.Consumer.GroupID,
+		FilterSubjects: subjects,
+		DeliverPolicy:  jetstream.DeliverAllPolicy,
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		AckWait:        config.NATS.AckWait,
+		MaxAckPending:  1, // Keeps records in order
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer %s: %w", config.Consumer.GroupID, err)
+	}
+
+	return &JetStreamConsumer{
+		consumer: consumer,
+		processor: &processor{
+			handler:  handler,
+			publish:  bus.PublishMessage,
+			payloads: bus.payloads,
+			config:   config,
+			logger:   logger,
+		},
+		logger: logger,
+	}, nil
+}
+
+// Run consumes until ctx is done
+func (c *JetStreamConsumer) Run(ctx context.Context) error {
+	messages, err := c.consumer.Messages()
+	if err != nil {
+		return fmt.Errorf("failed to consume events: %w", err)
+	}
+	defer messages.Stop()
+	stop := context.AfterFunc(ctx, messages.Stop)
+	defer stop()
+
+	// Fetch errors in a row, backed off so a lasting failure does not spin
+	failures := 0
+	for {
+		msg, err := messages.Next()
+		if errors.Is(err, jetstream.ErrMsgIteratorClosed) || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			failures++
+			c.logger.Warn("failed to fetch events", zap.Int("failures", failures), zap.Error(err))
+			if !sleep(ctx, c.processor.config.Consumer.Retry.Backoff(failures)) {
+				return nil
+			}
+			continue
+		}
+		failures = 0
+
+		record, err := newJetStreamRecord(msg)
+		if err != nil {
+			c.logger.Error("failed to read event metadata", zap.Error(err))
+			continue
+		}
+		// The record is delivered again if ctx is done before it is handled
+		if err := c.processor.process(ctx, record, func() { _ = msg.InProgress() }); err != nil {
+			return nil
+		}
+
+		// The context may be done; acknowledge what was handled regardless
+		ackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		if err := msg.DoubleAck(ackCtx); err != nil {
+			c.logger.Error("failed to acknowledge event",
+				zap.String("topic", record.Topic),
+				zap.Int64("offset", record.Offset),
+				zap.Error(err))
+		}
+		cancel()
+	}
+}
+
+// newJetStreamRecord converts a JetStream message
+func newJetStreamRecord(msg jetstream.Msg) (*Record, error) {
+	metadata, err := msg.Metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &Record{
+		Topic:     msg.Subject(),
+		Offset:    int64(metadata.Sequence.Stream),
+		Value:     msg.Data(),
+		Headers:   make(map[string]string, len(msg.Headers())),
+		Timestamp: metadata.Timestamp,
+	}
+	for key, values := range msg.Headers() {
+		if len(values) == 0 || key == jetstream.MsgIDHeader {
+			continue
+		}
+		if key == HeaderKey {
+			record.Key = []byte(values[0])
+			continue
+		}
+		record.Headers[key] = values[0]
+	}
+	return record, nil
+}
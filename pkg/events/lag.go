@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+)
+
+// Lag events
+const (
+	LagEventType = "events.consumer.lag"
+	lagSource    = "dictamesh/events"
+)
+
+// GroupLag is the lag of a consumer group, published as the data of lag
+// events
+type GroupLag struct {
+	Group      string           `json:"group"`
+	State      string           `json:"state"`   // Stable, Empty, PreparingRebalance...
+	Lag        int64            `json:"lag"`     // Records not consumed yet, over every partition
+	MaxLag     int64            `json:"max_lag"` // Of the partition furthest behind
+	Topics     map[string]int64 `json:"topics"`  // Lag per topic
+	Partitions []PartitionLag   `json:"partitions"`
+	MeasuredAt time.Time        `json:"measured_at"`
+}
+
+// PartitionLag is the lag of a consumer group on a partition
+type PartitionLag struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Committed int64  `json:"committed"` // Committed offset, -1 without a commit
+	End       int64  `json:"end"`       // Offset after the last record
+	Lag       int64  `json:"lag"`
+}
+
+// LagMonitor measures the lag of consumer groups: how many records of their
+// topics they have not consumed yet, from their committed offsets. Each
+// measurement updates the lag gauges and publishes a lag event per group, so
+// rules of the notification engine can alert when a group falls behind.
+type LagMonitor struct {
+	client   *kgo.Client
+	admin    *kadm.Client
+	producer *Producer
+	config   *Config
+	logger   *zap.Logger
+
+	mu         sync.Mutex
+	groups     map[string]bool    // Groups with gauges set
+	partitions map[[3]string]bool // Group, topic and partition with gauges set
+}
+
+// NewLagMonitor creates a lag monitor of Lag.Groups. Lag events are
+// published with producer; a nil producer publishes none.
+func NewLagMonitor(config *Config, producer *Producer, logger *zap.Logger) (*LagMonitor, error) {
+	if len(config.Brokers) == 0 {
+		return nil, fmt.Errorf("invalid event bus config: kafka brokers are required")
+	}
+	if err := config.Lag.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid lag config: %w", err)
+	}
+
+	opts := []kgo.Opt{kgo.SeedBrokers(config.Brokers...)}
+	if config.ClientID != "" {
+		opts = append(opts, kgo.ClientID(config.ClientID))
+	}
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+
+	return &LagMonitor{
+		client:     client,
+		admin:      kadm.NewClient(client),
+		producer:   producer,
+		config:     config,
+		logger:     logger,
+		groups:     make(map[string]bool),
+		partitions: make(map[[3]string]bool),
+	}, nil
+}
+
+// Close closes the client
+func (m *LagMonitor) Close() {
+	m.client.Close()
+}
+
+// Run measures the lag every Lag.Interval until ctx is done
+func (m *LagMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Lag.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			m.logger.Warn("failed to monitor consumer lag", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce measures the lag, updates the gauges and publishes the lag events
+func (m *LagMonitor) RunOnce(ctx context.Context) ([]GroupLag, error) {
+	lags, err := m.Measure(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.observe(lags)
+
+	if m.producer == nil || m.config.Lag.Topic == "" {
+		return lags, nil
+	}
+	for i := range lags {
+		envelope, err := NewEnvelope(ctx, LagEventType, lagSource, &lags[i])
+		if err != nil {
+			return lags, err
+		}
+		if err := m.producer.Publish(ctx, m.config.Lag.Topic, lags[i].Group, envelope); err != nil {
+			return lags, fmt.Errorf("failed to publish lag of %s: %w", lags[i].Group, err)
+		}
+	}
+	return lags, nil
+}
+
+// Measure returns the lag of the monitored groups, sorted by group. Groups
+// that fail to be described or to fetch their offsets are skipped, as are
+// partitions whose end offset fails to be listed.
+func (m *LagMonitor) Measure(ctx context.Context) ([]GroupLag, error) {
+	described, err := m.admin.Lag(ctx, m.config.Lag.Groups...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure consumer lag: %w", err)
+	}
+
+	now := time.Now().UTC()
+	lags := make([]GroupLag, 0, len(described))
+	described.Each(func(d kadm.DescribedGroupLag) {
+		if d.DescribeErr != nil || d.FetchErr != nil {
+			m.logger.Warn("failed to measure consumer group lag",
+				zap.String("group", d.Group),
+				zap.NamedError("describe_error", d.DescribeErr),
+				zap.NamedError("fetch_error", d.FetchErr))
+			return
+		}
+
+		lag := GroupLag{
+			Group:      d.Group,
+			State:      d.State,
+			Topics:     make(map[string]int64),
+			MeasuredAt: now,
+		}
+		for _, p := range d.Lag.Sorted() {
+			if p.Err != nil || p.Lag < 0 {
+				continue
+			}
+			lag.Partitions = append(lag.Partitions, PartitionLag{
+				Topic:     p.Topic,
+				Partition: p.Partition,
+				Committed: p.Commit.At,
+				End:       p.End.Offset,
+				Lag:       p.Lag,
+			})
+			lag.Topics[p.Topic] += p.Lag
+			lag.Lag += p.Lag
+			if p.Lag > lag.MaxLag {
+				lag.MaxLag = p.Lag
+			}
+		}
+		lags = append(lags, lag)
+	})
+	sort.Slice(lags, func(i, j int) bool { return lags[i].Group < lags[j].Group })
+	return lags, nil
+}
+
+// observe sets the lag gauges, removing those of groups and partitions no
+// longer measured
+func (m *LagMonitor) observe(lags []GroupLag) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	groups := make(map[string]bool, len(lags))
+	partitions := make(map[[3]string]bool)
+	for _, lag := range lags {
+		groups[lag.Group] = true
+		consumerGroupLag.WithLabelValues(lag.Group).Set(float64(lag.Lag))
+		for _, p := range lag.Partitions {
+			labels := [3]string{lag.Group, p.Topic, strconv.Itoa(int(p.Partition))}
+			partitions[labels] = true
+			consumerLag.WithLabelValues(labels[:]...).Set(float64(p.Lag))
+		}
+	}
+
+	for group := range m.groups {
+		if !groups[group] {
+			consumerGroupLag.DeleteLabelValues(group)
+		}
+	}
+	for labels := range m.partitions {
+		if !partitions[labels] {
+			consumerLag.DeleteLabelValues(labels[:]...)
+		}
+	}
+	m.groups, m.partitions = groups, partitions
+}
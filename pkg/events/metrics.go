@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	publishedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_events_published_total",
+			Help: "Messages published by topic and result",
+		},
+		[]string{"topic", "result"},
+	)
+
+	publishedBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_events_published_bytes_total",
+			Help: "Key and value bytes of the messages written by topic",
+		},
+		[]string{"topic"},
+	)
+
+	publishDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dictamesh_events_publish_duration_seconds",
+			Help:    "Time from publishing a message to its delivery report by topic",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+		},
+		[]string{"topic"},
+	)
+
+	bufferedRecords = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "dictamesh_events_producer_buffered_records",
+			Help: "Records buffered by the producer and not yet written",
+		},
+	)
+
+	outboxRelayedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_events_outbox_relayed_total",
+			Help: "Outbox events relayed by result",
+		},
+		[]string{"result"},
+	)
+
+	outboxPending = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "dictamesh_events_outbox_pending",
+			Help: "Outbox events committed and not yet published",
+		},
+	)
+
+	outboxOldestPending = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "dictamesh_events_outbox_oldest_pending_seconds",
+			Help: "Age of the oldest outbox event not yet published",
+		},
+	)
+
+	handledTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_events_handled_total",
+			Help: "Handler attempts of consumed messages by topic and result",
+		},
+		[]string{"topic", "result"},
+	)
+
+	dlqDepth = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dictamesh_events_dlq_depth",
+			Help: "Messages of a dead-letter topic not yet replayed",
+		},
+		[]string{"topic"},
+	)
+
+	dlqReplayedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_events_dlq_replayed_total",
+			Help: "Dead-lettered messages replayed by dead-letter topic",
+		},
+		[]string{"topic"},
+	)
+
+	consumerLag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dictamesh_events_consumer_lag",
+			Help: "Records not yet consumed by consumer group, topic and partition",
+		},
+		[]string{"group", "topic", "partition"},
+	)
+
+	consumerGroupLag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dictamesh_events_consumer_group_lag",
+			Help: "Records not yet consumed by consumer group, over all partitions",
+		},
+		[]string{"group"},
+	)
+
+	idempotencyTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_events_idempotency_total",
+			Help: "Events of idempotent handlers by scope and result",
+		},
+		[]string{"scope", "result"},
+	)
+
+	backfillRecordsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_events_backfill_records_total",
+			Help: "Records of backfill jobs by job and result",
+		},
+		[]string{"job", "result"},
+	)
+
+	backfillRemaining = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dictamesh_events_backfill_remaining",
+			Help: "Offsets left to backfill by job, as of the last checkpoint",
+		},
+		[]string{"job"},
+	)
+
+	webhookDeliveriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_events_webhook_deliveries_total",
+			Help: "Webhook delivery attempts by result",
+		},
+		[]string{"result"},
+	)
+
+	webhookDeliveryDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "dictamesh_events_webhook_delivery_duration_seconds",
+			Help:    "Time webhook endpoints take to answer a delivery",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+		},
+	)
+
+	webhookPending = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "dictamesh_events_webhook_pending",
+			Help: "Webhook deliveries pending, including those waiting to retry",
+		},
+	)
+
+	webhookDisabledTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "dictamesh_events_webhook_disabled_total",
+			Help: "Webhook subscriptions disabled after failing",
+		},
+	)
+
+	payloadsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_events_payloads_total",
+			Help: "Message values encoded by topic and result",
+		},
+		[]string{"topic", "result"},
+	)
+)
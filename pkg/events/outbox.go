@@ -0,0 +1,272 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HeaderDedupKey carries the dedup key of messages relayed from the outbox.
+// Consumers drop messages whose key they have seen, since a relay that fails
+// to record a publish publishes the message again.
+const HeaderDedupKey = "X-DictaMesh-Dedup-Key"
+
+// OutboxRecord is an event of the transactional outbox
+type OutboxRecord struct {
+	ID          int64             `gorm:"primaryKey"`
+	DedupKey    string            `gorm:"type:varchar(255);not null"`
+	Topic       string            `gorm:"type:varchar(255);not null"`
+	MessageKey  string            `gorm:"type:varchar(255);not null"`
+	Payload     []byte            `gorm:"type:bytea;not null"`
+	Headers     map[string]string `gorm:"type:jsonb;serializer:json"`
+	CreatedAt   time.Time
+	PublishedAt *time.Time
+	Attempts    int
+	LastError   string `gorm:"type:text"`
+}
+
+// TableName specifies the table name
+func (OutboxRecord) TableName() string {
+	return "dictamesh_event_outbox"
+}
+
+// Enqueue writes an event to the outbox in tx, so it is published if and only
+// if tx commits. value is encoded as JSON unless it is a []byte, and is
+// serialized with the schema of topic when relayed. The dedup key is the
+// event ID of an *Envelope, or a new ID; an event enqueued twice is stored
+// once. The tenant and trace context of ctx are kept as headers.
+func Enqueue(ctx context.Context, tx *gorm.DB, topic, key string, value interface{}) error {
+	payload, err := encode(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode event for %s: %w", topic, err)
+	}
+
+	record := &OutboxRecord{
+		DedupKey:   uuid.NewString(),
+		Topic:      topic,
+		MessageKey: key,
+		Payload:    payload,
+		Headers:    contextHeaders(ctx),
+		CreatedAt:  time.Now(),
+	}
+	if envelope, ok := value.(*Envelope); ok && envelope.EventID != "" {
+		record.DedupKey = envelope.EventID
+	}
+
+	if err := tx.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "dedup_key"}}, DoNothing: true}).
+		Create(record).Error; err != nil {
+		return fmt.Errorf("failed to enqueue event for %s: %w", topic, err)
+	}
+	return nil
+}
+
+// OutboxRelay publishes the committed events of the outbox. Instances claim
+// batches with FOR UPDATE SKIP LOCKED and hold them until published, so each
+// event is relayed by one instance at a time; events are marked published in
+// the claiming transaction. Events are published in outbox order, and keep
+// their order per key as long as their publishing succeeds.
+//
+// An event whose publish succeeded is published again if its transaction
+// then fails to commit. It carries its dedup key in HeaderDedupKey, so
+// consumers that drop seen keys process each event once.
+type OutboxRelay struct {
+	db     *gorm.DB
+	bus    EventBus
+	config *OutboxConfig
+	logger *zap.Logger
+}
+
+// NewOutboxRelay creates a relay publishing the outbox of db to bus
+func NewOutboxRelay(db *gorm.DB, bus EventBus, config *OutboxConfig, logger *zap.Logger) (*OutboxRelay, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid outbox config: %w", err)
+	}
+
+	return &OutboxRelay{
+		db:     db,
+		bus:    bus,
+		config: config,
+		logger: logger,
+	}, nil
+}
+
+// Run relays the outbox until ctx is done. Full batches are followed by the
+// next one right away; otherwise the outbox is polled every PollInterval.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		relayed, err := r.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Error("failed to relay outbox events", zap.Error(err))
+		}
+		if err == nil && relayed == r.config.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce relays a batch of pending events and returns how many were
+// claimed. Events that fail to publish stay pending with their error and
+// are retried by the next batch. Once the outbox is drained, published
+// events older than Retention are deleted.
+func (r *OutboxRelay) RunOnce(ctx context.Context) (int, error) {
+	var claimed int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var pending []OutboxRecord
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL").
+			Order("id").
+			Limit(r.config.BatchSize).
+			Find(&pending).Error; err != nil {
+			return fmt.Errorf("failed to fetch pending events: %w", err)
+		}
+		claimed = len(pending)
+		if claimed == 0 {
+			return nil
+		}
+
+		errs := r.publish(ctx, pending)
+
+		now := time.Now()
+		published := make([]int64, 0, len(pending))
+		for i := range pending {
+			if errs[i] == nil {
+				published = append(published, pending[i].ID)
+				outboxRelayedTotal.WithLabelValues("success").Inc()
+				continue
+			}
+			outboxRelayedTotal.WithLabelValues("error").Inc()
+			if err := tx.Model(&OutboxRecord{}).
+				Where("id = ?", pending[i].ID).
+				Updates(map[string]interface{}{
+					"attempts":   gorm.Expr("attempts + 1"),
+					"last_error": errs[i].Error(),
+				}).Error; err != nil {
+				return fmt.Errorf("failed to record publish failure: %w", err)
+			}
+		}
+		if len(published) == 0 {
+			return nil
+		}
+		if err := tx.Model(&OutboxRecord{}).
+			Where("id IN ?", published).
+			Update("published_at", now).Error; err != nil {
+			return fmt.Errorf("failed to mark events published: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return claimed, err
+	}
+
+	if claimed < r.config.BatchSize {
+		if err := r.cleanup(ctx); err != nil {
+			return claimed, err
+		}
+	}
+	return claimed, r.observe(ctx)
+}
+
+// publish publishes a batch of events and returns the error of each. Kafka
+// batches are published at once; other buses publish one event at a time.
+func (r *OutboxRelay) publish(ctx context.Context, records []OutboxRecord) []error {
+	errs := make([]error, len(records))
+	producer, batched := r.bus.(*Producer)
+	serializer := serializerOf(r.bus)
+	var wg sync.WaitGroup
+	for i := range records {
+		record := &records[i]
+		value, err := serializer.Serialize(ctx, record.Topic, record.Payload)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to serialize event: %w", err)
+			continue
+		}
+
+		headers := make(map[string]string, len(record.Headers)+1)
+		for k, v := range record.Headers {
+			headers[k] = v
+		}
+		headers[HeaderDedupKey] = record.DedupKey
+
+		msg := &Message{
+			Topic:   record.Topic,
+			Key:     []byte(record.MessageKey),
+			Value:   value,
+			Headers: headers,
+		}
+		if !batched {
+			errs[i] = r.bus.PublishMessage(ctx, msg)
+			continue
+		}
+
+		wg.Add(1)
+		index := i
+		producer.PublishAsync(ctx, msg, func(report *DeliveryReport) {
+			errs[index] = report.Err
+			wg.Done()
+		})
+	}
+	wg.Wait()
+	return errs
+}
+
+// cleanup deletes a batch of the published events older than Retention
+func (r *OutboxRelay) cleanup(ctx context.Context) error {
+	if r.config.Retention <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-r.config.Retention)
+	if err := r.db.WithContext(ctx).Exec(`
+		DELETE FROM dictamesh_event_outbox
+		WHERE id IN (
+			SELECT id FROM dictamesh_event_outbox
+			WHERE published_at < ?
+			ORDER BY published_at
+			LIMIT ?)`, cutoff, r.config.BatchSize).Error; err != nil {
+		return fmt.Errorf("failed to delete published events: %w", err)
+	}
+	return nil
+}
+
+// observe updates the backlog metrics of the outbox
+func (r *OutboxRelay) observe(ctx context.Context) error {
+	var backlog struct {
+		Count  int64
+		Oldest *time.Time
+	}
+	if err := r.db.WithContext(ctx).Model(&OutboxRecord{}).
+		Select("COUNT(*) AS count, MIN(created_at) AS oldest").
+		Where("published_at IS NULL").
+		Scan(&backlog).Error; err != nil {
+		return fmt.Errorf("failed to measure outbox backlog: %w", err)
+	}
+
+	outboxPending.Set(float64(backlog.Count))
+	if backlog.Oldest == nil {
+		outboxOldestPending.Set(0)
+	} else {
+		outboxOldestPending.Set(time.Since(*backlog.Oldest).Seconds())
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// KeyStrategy derives the message key of events, which picks their
+// partition: events with the same key keep their order
+type KeyStrategy string
+
+const (
+	// KeyByOrganization keys events by organization, ordering the events of
+	// an organization
+	KeyByOrganization KeyStrategy = "organization"
+
+	// KeyByEntity keys events by organization and aggregate, ordering the
+	// events of each aggregate and spreading an organization over partitions
+	KeyByEntity KeyStrategy = "entity"
+)
+
+// tenantTopicInfix separates a topic from the organization of its isolated
+// tenant topic
+const tenantTopicInfix = ".tenant."
+
+// validTopicName matches the characters Kafka accepts in topic names
+var validTopicName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// TopicConfig configures the topic and key of the events of an event type
+type TopicConfig struct {
+	// Topic of the events; defaults to the event type
+	Topic string
+
+	// Key strategy; defaults to KeyByOrganization
+	Key KeyStrategy
+
+	// Top-level field of the event data holding the ID of its aggregate,
+	// e.g. invoice_id, for KeyByEntity. Events without it are keyed by
+	// organization.
+	EntityField string
+
+	// Organizations whose events go to their own topic, see TenantTopic,
+	// isolating large customers from the others
+	IsolatedOrganizations []string
+}
+
+// TenantTopic returns the topic of the events of an isolated organization
+func TenantTopic(topic, organizationID string) string {
+	return topic + tenantTopicInfix + organizationID
+}
+
+// baseTopic returns the topic a tenant topic isolates, or topic itself
+func baseTopic(topic string) string {
+	i := strings.LastIndex(topic, tenantTopicInfix)
+	if i < 0 || strings.Contains(topic[i+len(tenantTopicInfix):], ".") {
+		return topic
+	}
+	return topic[:i]
+}
+
+// Partitioner routes events to their topic and key following the TopicConfig
+// of their event type. Event types are configured by name, e.g.
+// billing.invoice.paid, or by prefix, e.g. billing.*; the longest match
+// applies. Events of other types go to the topic of their type, keyed by
+// organization.
+type Partitioner struct {
+	topics   map[string]TopicConfig
+	isolated map[string]map[string]bool // By event type pattern, then organization
+}
+
+// NewPartitioner creates a partitioner of topics, usually Config.Topics
+func NewPartitioner(topics map[string]TopicConfig) (*Partitioner, error) {
+	p := &Partitioner{
+		topics:   make(map[string]TopicConfig, len(topics)),
+		isolated: make(map[string]map[string]bool),
+	}
+	for pattern, config := range topics {
+		if err := config.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid topic config of %s: %w", pattern, err)
+		}
+		p.topics[pattern] = config
+		if len(config.IsolatedOrganizations) > 0 {
+			p.isolated[pattern] = make(map[string]bool, len(config.IsolatedOrganizations))
+			for _, organizationID := range config.IsolatedOrganizations {
+				p.isolated[pattern][organizationID] = true
+			}
+		}
+	}
+	return p, nil
+}
+
+// Route returns the topic and key of an event. Events of an organization
+// moved to or from isolation change topic, so their order is only kept on
+// either side of the move.
+func (p *Partitioner) Route(envelope *Envelope) (topic, key string) {
+	pattern, config := p.config(envelope.EventType)
+
+	topic = config.Topic
+	if topic == "" {
+		topic = envelope.EventType
+	}
+	if envelope.OrganizationID != "" && p.isolated[pattern][envelope.OrganizationID] {
+		topic = TenantTopic(topic, envelope.OrganizationID)
+	}
+
+	key = envelope.OrganizationID
+	if config.Key == KeyByEntity {
+		if entityID := entityOf(envelope.Data, config.EntityField); entityID != "" {
+			key = envelope.OrganizationID + "/" + entityID
+		}
+	}
+	return topic, key
+}
+
+// config returns the config of an event type and its pattern
+func (p *Partitioner) config(eventType string) (string, TopicConfig) {
+	if config, ok := p.topics[eventType]; ok {
+		return eventType, config
+	}
+	prefix := eventType
+	for {
+		i := strings.LastIndex(prefix, ".")
+		if i < 0 {
+			return "", TopicConfig{}
+		}
+		prefix = prefix[:i]
+		if config, ok := p.topics[prefix+".*"]; ok {
+			return prefix + ".*", config
+		}
+	}
+}
+
+// entityOf returns a top-level string or number field of event data
+func entityOf(data json.RawMessage, field string) string {
+	var fields map[string]json.RawMessage
+	if field == "" || json.Unmarshal(data, &fields) != nil {
+		return ""
+	}
+	value, ok := fields[field]
+	if !ok {
+		return ""
+	}
+	var id string
+	if json.Unmarshal(value, &id) == nil {
+		return id
+	}
+	var number json.Number
+	if json.Unmarshal(value, &number) == nil {
+		return number.String()
+	}
+	return ""
+}
+
+// Validate validates the topic configuration
+func (c *TopicConfig) Validate() error {
+	if c.Topic != "" && !validTopicName.MatchString(c.Topic) {
+		return fmt.Errorf("invalid topic name: %q", c.Topic)
+	}
+	switch c.Key {
+	case "", KeyByOrganization:
+	case KeyByEntity:
+		if c.EntityField == "" {
+			return fmt.Errorf("entity field is required to key by entity")
+		}
+	default:
+		return fmt.Errorf("unsupported key strategy: %s", c.Key)
+	}
+	for _, organizationID := range c.IsolatedOrganizations {
+		if !validTopicName.MatchString(organizationID) || strings.Contains(organizationID, ".") {
+			return fmt.Errorf("invalid isolated organization: %q", organizationID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+// Headers of encoded payloads
+const (
+	// HeaderContentEncoding carries the compression of a message value
+	HeaderContentEncoding = "X-DictaMesh-Content-Encoding"
+
+	// HeaderClaimCheck carries the object key of a payload stored in object
+	// storage in place of a message too large to publish
+	HeaderClaimCheck = "X-DictaMesh-Claim-Check"
+
+	// HeaderClaimCheckSHA256 carries the hex SHA-256 of the stored payload
+	HeaderClaimCheckSHA256 = "X-DictaMesh-Claim-Check-SHA256"
+)
+
+// Overflow strategies of messages too large to publish
+const (
+	OverflowReject     = "reject"      // Fail the publish with a MessageTooLargeError
+	OverflowClaimCheck = "claim-check" // Store the payload and publish a reference
+)
+
+// ErrMessageTooLarge is matched by MessageTooLargeError
+var ErrMessageTooLarge = errors.New("message too large")
+
+// MessageTooLargeError is returned for a message larger than the limit of
+// the bus after compression, unless it is claim-checked
+type MessageTooLargeError struct {
+	Topic string
+	Size  int // Bytes of key, value and headers, compressed if it was
+	Limit int // Bytes accepted by the bus
+}
+
+func (e *MessageTooLargeError) Error() string {
+	return fmt.Sprintf("message of %d bytes to %s exceeds the limit of %d bytes", e.Size, e.Topic, e.Limit)
+}
+
+// Is reports whether target is ErrMessageTooLarge
+func (e *MessageTooLargeError) Is(target error) bool {
+	return target == ErrMessageTooLarge
+}
+
+// ObjectStore stores the payloads of claim-checked messages. The object
+// storage of the billing package implements it.
+type ObjectStore interface {
+	// Put stores an object and returns its URL
+	Put(ctx context.Context, key string, data []byte, contentType string) (string, error)
+	// Get reads an object
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// ClaimCheck references a payload stored in object storage. It is the data
+// of the reference event published in place of an envelope too large to
+// publish, under "claim_check", or the value of other references.
+type ClaimCheck struct {
+	Key    string `json:"key"`
+	URL    string `json:"url,omitempty"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// payloadCodec compresses, checks and claim-checks the values of the
+// messages of a bus, and decodes those of its consumers
+type payloadCodec struct {
+	config *PayloadConfig
+	store  ObjectStore // Of claim-checked payloads, if set
+}
+
+// newPayloadCodec creates the payload codec of config
+func newPayloadCodec(config *PayloadConfig) (*payloadCodec, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid payload config: %w", err)
+	}
+	return &payloadCodec{config: config}, nil
+}
+
+// encode returns msg with the tenant and trace headers of ctx, its value
+// compressed when larger than CompressAbove, and claim-checked when the
+// message is still larger than limit, or MaxBytes if set. Values encoded
+// already, e.g. of dead-lettered records being replayed, are kept.
+func (c *payloadCodec) encode(ctx context.Context, msg *Message, limit int) (*Message, error) {
+	if c.config.MaxBytes > 0 {
+		limit = c.config.MaxBytes
+	}
+
+	headers := contextHeaders(ctx)
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+	encoded := *msg
+	encoded.Headers = headers
+
+	_, compressed := headers[HeaderContentEncoding]
+	_, claimChecked := headers[HeaderClaimCheck]
+	if c.config.Compression == CompressionGzip && !compressed && !claimChecked &&
+		len(msg.Value) > c.config.CompressAbove {
+		value, err := gzipValue(msg.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress message to %s: %w", msg.Topic, err)
+		}
+		// Values that compress poorly are sent as they are
+		if len(value) < len(msg.Value) {
+			encoded.Value = value
+			headers[HeaderContentEncoding] = CompressionGzip
+			payloadsTotal.WithLabelValues(msg.Topic, "compressed").Inc()
+		}
+	}
+
+	size := messageSize(&encoded)
+	if limit <= 0 || size <= limit {
+		return &encoded, nil
+	}
+	if c.config.Overflow != OverflowClaimCheck || c.store == nil || claimChecked {
+		payloadsTotal.WithLabelValues(msg.Topic, "too_large").Inc()
+		return nil, &MessageTooLargeError{Topic: msg.Topic, Size: size, Limit: limit}
+	}
+	return c.claimCheck(ctx, msg, headers)
+}
+
+// claimCheck stores the value of msg and returns the reference message
+// published in its place, with headers
+func (c *payloadCodec) claimCheck(ctx context.Context, msg *Message, headers map[string]string) (*Message, error) {
+	digest := sha256.Sum256(msg.Value)
+	check := ClaimCheck{
+		Key:    c.config.ClaimCheckPrefix + msg.Topic + "/" + uuid.NewString(),
+		Size:   len(msg.Value),
+		SHA256: hex.EncodeToString(digest[:]),
+	}
+	url, err := c.store.Put(ctx, check.Key, msg.Value, "application/octet-stream")
+	if err != nil {
+		return nil, fmt.Errorf("failed to store claim-checked payload of %s: %w", msg.Topic, err)
+	}
+	check.URL = url
+
+	value, err := claimCheckValue(msg.Value, &check)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode claim check of %s: %w", msg.Topic, err)
+	}
+	// The stored payload keeps the encoding it was published with
+	if encoding, ok := msg.Headers[HeaderContentEncoding]; ok {
+		headers[HeaderContentEncoding] = encoding
+	} else {
+		delete(headers, HeaderContentEncoding)
+	}
+	headers[HeaderClaimCheck] = check.Key
+	headers[HeaderClaimCheckSHA256] = check.SHA256
+	payloadsTotal.WithLabelValues(msg.Topic, "claim_checked").Inc()
+
+	return &Message{
+		Topic:     msg.Topic,
+		Key:       msg.Key,
+		Value:     value,
+		Headers:   headers,
+		Timestamp: msg.Timestamp,
+	}, nil
+}
+
+// claimCheckValue returns the value of the reference to a stored payload:
+// the envelope of the payload with the claim check as data, so consumers can
+// route it without fetching the payload, or the claim check itself
+func claimCheckValue(payload []byte, check *ClaimCheck) ([]byte, error) {
+	var envelope Envelope
+	if json.Unmarshal(payload, &envelope) != nil || envelope.EventID == "" {
+		return json.Marshal(check)
+	}
+	data, err := json.Marshal(map[string]*ClaimCheck{"claim_check": check})
+	if err != nil {
+		return nil, err
+	}
+	envelope.Data = data
+	return json.Marshal(&envelope)
+}
+
+// DecodePayload restores the value of a record published compressed or
+// claim-checked, fetching claim-checked payloads from store, and drops the
+// headers describing its encoding. Consumers and backfillers decode records
+// before their handler runs; other readers of topics call it themselves,
+// with Payload.MaxDecodedBytes as maxBytes. Payloads that cannot be decoded,
+// or that are larger than maxBytes once decoded, fail permanently; a failure
+// to fetch a payload does not.
+func DecodePayload(ctx context.Context, record *Record, store ObjectStore, maxBytes int) error {
+	if key, ok := record.Headers[HeaderClaimCheck]; ok {
+		if store == nil {
+			return Permanent(fmt.Errorf("claim-checked payload %s without an object store", key))
+		}
+		payload, err := store.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to fetch claim-checked payload %s: %w", key, err)
+		}
+		if len(payload) > maxBytes {
+			return Permanent(fmt.Errorf("claim-checked payload %s exceeds %d bytes", key, maxBytes))
+		}
+		digest := sha256.Sum256(payload)
+		if hex.EncodeToString(digest[:]) != record.Headers[HeaderClaimCheckSHA256] {
+			return Permanent(fmt.Errorf("claim-checked payload %s does not match its digest", key))
+		}
+		record.Value = payload
+		delete(record.Headers, HeaderClaimCheck)
+		delete(record.Headers, HeaderClaimCheckSHA256)
+	}
+
+	switch encoding := record.Headers[HeaderContentEncoding]; encoding {
+	case "":
+	case CompressionGzip:
+		value, err := gunzipValue(record.Value, maxBytes)
+		if err != nil {
+			return Permanent(fmt.Errorf("failed to decompress payload: %w", err))
+		}
+		record.Value = value
+		delete(record.Headers, HeaderContentEncoding)
+	default:
+		return Permanent(fmt.Errorf("unsupported content encoding: %s", encoding))
+	}
+	return nil
+}
+
+// messageSize returns the bytes of the key, value and headers of a message
+func messageSize(msg *Message) int {
+	size := len(msg.Key) + len(msg.Value)
+	for key, value := range msg.Headers {
+		size += len(key) + len(value)
+	}
+	return size
+}
+
+func gzipValue(value []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(value); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gunzipValue decompresses a value of at most maxBytes
+func gunzipValue(value []byte, maxBytes int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(value))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	decoded, err := io.ReadAll(io.LimitReader(r, int64(maxBytes)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) > maxBytes {
+		return nil, fmt.Errorf("value exceeds %d bytes", maxBytes)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,294 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/zap"
+)
+
+// Message is an event to publish
+type Message struct {
+	Topic     string
+	Key       []byte // Partitioning key; messages with the same key keep their order
+	Value     []byte
+	Headers   map[string]string
+	Timestamp time.Time // Defaults to the time of publishing
+}
+
+// DeliveryReport is the outcome of publishing a message. Err is nil once the
+// message is written with the acknowledgement configured.
+type DeliveryReport struct {
+	Message   *Message
+	Partition int32
+	Offset    int64
+	Timestamp time.Time
+	Err       error
+}
+
+// DeliveryHandler receives the delivery report of a message published with
+// PublishAsync. Handlers run on the goroutine of the Kafka client, in the
+// order messages of a partition are written, and must not block.
+type DeliveryHandler func(report *DeliveryReport)
+
+// Producer publishes messages to Kafka. With idempotent writes, the brokers
+// deduplicate the retries of the producer, so a message published once is
+// written once and in order per partition, even when a response is lost.
+//
+// Messages carry the tenant and trace context of the context they are
+// published with as headers.
+type Producer struct {
+	client     *kgo.Client
+	config     *Config
+	serializer Serializer
+	payloads   *payloadCodec
+	logger     *zap.Logger
+}
+
+// NewProducer creates a producer for the brokers of config. With a schema
+// registry, the schemas of the topics are checked and registered first, and
+// a schema the registry rejects fails the producer.
+func NewProducer(config *Config, logger *zap.Logger) (*Producer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid event bus config: %w", err)
+	}
+
+	serializer, err := newSerializer(config)
+	if err != nil {
+		return nil, err
+	}
+	payloads, err := newPayloadCodec(&config.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := kgo.NewClient(producerOptions(config)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+
+	return &Producer{
+		client:     client,
+		config:     config,
+		serializer: serializer,
+		payloads:   payloads,
+		logger:     logger,
+	}, nil
+}
+
+// SetObjectStore sets the object storage of claim-checked payloads, see
+// PayloadConfig.Overflow. It must be set before publishing; consumers
+// created with the producer fetch claim-checked payloads from it.
+func (p *Producer) SetObjectStore(store ObjectStore) {
+	p.payloads.store = store
+}
+
+// newSerializer returns the serializer of config: the schema registry if
+// configured, JSON otherwise
+func newSerializer(config *Config) (Serializer, error) {
+	if config.SchemaRegistryURL == "" {
+		return JSONSerializer{}, nil
+	}
+	registry := NewSchemaRegistry(config.SchemaRegistryURL, &config.Schemas)
+	serde, err := NewSchemaSerde(context.Background(), registry, &config.Schemas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schemas: %w", err)
+	}
+	return serde, nil
+}
+
+// producerOptions maps the configuration to Kafka client options
+func producerOptions(config *Config) []kgo.Opt {
+	producer := config.Producer
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(config.Brokers...),
+		kgo.ProducerLinger(producer.Linger),
+		kgo.ProducerBatchMaxBytes(int32(producer.MaxMessageBytes)),
+		kgo.MaxBufferedRecords(producer.MaxBufferedRecords),
+		kgo.ProduceRequestTimeout(producer.RequestTimeout),
+		kgo.RecordDeliveryTimeout(producer.DeliveryTimeout),
+	}
+	if config.ClientID != "" {
+		opts = append(opts, kgo.ClientID(config.ClientID))
+	}
+
+	switch producer.Acks {
+	case AcksAll:
+		opts = append(opts, kgo.RequiredAcks(kgo.AllISRAcks()))
+	case AcksLeader:
+		opts = append(opts, kgo.RequiredAcks(kgo.LeaderAck()))
+	case AcksNone:
+		opts = append(opts, kgo.RequiredAcks(kgo.NoAck()))
+	}
+	if !producer.Idempotent {
+		opts = append(opts, kgo.DisableIdempotentWrite())
+	}
+
+	switch producer.Compression {
+	case CompressionGzip:
+		opts = append(opts, kgo.ProducerBatchCompression(kgo.GzipCompression()))
+	case CompressionSnappy:
+		opts = append(opts, kgo.ProducerBatchCompression(kgo.SnappyCompression()))
+	case CompressionLZ4:
+		opts = append(opts, kgo.ProducerBatchCompression(kgo.Lz4Compression()))
+	case CompressionZstd:
+		opts = append(opts, kgo.ProducerBatchCompression(kgo.ZstdCompression()))
+	default:
+		opts = append(opts, kgo.ProducerBatchCompression(kgo.NoCompression()))
+	}
+	return opts
+}
+
+// Publish publishes value to topic and waits for it to be written. value is
+// serialized with the schema of the topic, or as JSON; a []byte value is
+// taken as JSON encoded already. Publish implements EventBus and the EventBus
+// interface of the billing package.
+func (p *Producer) Publish(ctx context.Context, topic string, key string, value interface{}) error {
+	data, err := p.serializer.Serialize(ctx, topic, value)
+	if err != nil {
+		return fmt.Errorf("failed to encode event for %s: %w", topic, err)
+	}
+
+	_, err = p.PublishSync(ctx, &Message{
+		Topic: topic,
+		Key:   []byte(key),
+		Value: data,
+	})
+	return err
+}
+
+// PublishMessage publishes a message and waits for it to be written. It
+// implements EventBus.
+func (p *Producer) PublishMessage(ctx context.Context, msg *Message) error {
+	_, err := p.PublishSync(ctx, msg)
+	return err
+}
+
+// PublishSync publishes a message and waits for its delivery report. A
+// message is abandoned when ctx is done before it is sent; once sent, its
+// outcome is awaited.
+func (p *Producer) PublishSync(ctx context.Context, msg *Message) (*DeliveryReport, error) {
+	done := make(chan *DeliveryReport, 1)
+	p.PublishAsync(ctx, msg, func(report *DeliveryReport) {
+		done <- report
+	})
+
+	report := <-done
+	if report.Err != nil {
+		return report, fmt.Errorf("failed to publish to %s: %w", msg.Topic, report.Err)
+	}
+	return report, nil
+}
+
+// PublishAsync buffers a message for publishing and returns. handler, if not
+// nil, receives its delivery report; failures of messages published without
+// a handler are logged. PublishAsync blocks while MaxBufferedRecords are
+// buffered, until ctx is done, and while the value of a message too large
+// to publish is claim-checked.
+//
+// Messages larger than MaxMessageBytes after compression fail with a
+// MessageTooLargeError before being buffered, unless claim-checked.
+func (p *Producer) PublishAsync(ctx context.Context, msg *Message, handler DeliveryHandler) {
+	start := time.Now()
+	encoded, err := p.payloads.encode(ctx, msg, p.config.Producer.MaxMessageBytes)
+	if err != nil {
+		report := &DeliveryReport{Message: msg, Partition: -1, Offset: -1, Err: err}
+		p.observe(report, 0, time.Since(start), handler == nil)
+		if handler != nil {
+			handler(report)
+		}
+		return
+	}
+
+	p.client.Produce(ctx, p.record(ctx, encoded), func(record *kgo.Record, err error) {
+		report := &DeliveryReport{
+			Message:   msg,
+			Partition: record.Partition,
+			Offset:    record.Offset,
+			Timestamp: record.Timestamp,
+			Err:       err,
+		}
+		p.observe(report, len(record.Key)+len(record.Value), time.Since(start), handler == nil)
+		if handler != nil {
+			handler(report)
+		}
+	})
+}
+
+// record builds the Kafka record of a message. Tenant and trace headers of
+// ctx are added unless the message sets them.
+func (p *Producer) record(ctx context.Context, msg *Message) *kgo.Record {
+	headers := contextHeaders(ctx)
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+
+	record := &kgo.Record{
+		Topic:     msg.Topic,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Timestamp: msg.Timestamp,
+	}
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: key, Value: []byte(headers[key])})
+	}
+	return record
+}
+
+// contextHeaders returns the tenant and trace headers of ctx
+func contextHeaders(ctx context.Context) map[string]string {
+	headers := make(map[string]string, 4)
+	tenant.Inject(ctx, tenant.MapCarrier(headers))
+	propagation.TraceContext{}.Inject(ctx, propagation.MapCarrier(headers))
+	return headers
+}
+
+// observe records the metrics of a delivery report of a message whose key
+// and value were written as written bytes, and logs failures that no handler
+// receives
+func (p *Producer) observe(report *DeliveryReport, written int, elapsed time.Duration, log bool) {
+	topic := report.Message.Topic
+	publishDuration.WithLabelValues(topic).Observe(elapsed.Seconds())
+	bufferedRecords.Set(float64(p.client.BufferedProduceRecords()))
+
+	if report.Err != nil {
+		publishedTotal.WithLabelValues(topic, "error").Inc()
+		if log {
+			p.logger.Error("failed to publish event",
+				zap.String("topic", topic),
+				zap.ByteString("key", report.Message.Key),
+				zap.Error(report.Err))
+		}
+		return
+	}
+	publishedTotal.WithLabelValues(topic, "success").Inc()
+	publishedBytes.WithLabelValues(topic).Add(float64(written))
+}
+
+// Flush waits until the messages buffered are written or failed, or ctx is
+// done
+func (p *Producer) Flush(ctx context.Context) error {
+	return p.client.Flush(ctx)
+}
+
+// Close flushes the buffered messages and closes the producer. Messages not
+// written when ctx is done are failed.
+func (p *Producer) Close(ctx context.Context) error {
+	err := p.Flush(ctx)
+	p.client.Close()
+	return err
+}
@@ -0,0 +1,246 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Schema types of the registry
+const (
+	SchemaAvro = "AVRO"
+	SchemaJSON = "JSON"
+)
+
+// ErrSchemaNotFound is returned for subjects and schemas the registry does
+// not have
+var ErrSchemaNotFound = errors.New("schema not found")
+
+// ErrIncompatibleSchema is wrapped by the errors of schemas incompatible with
+// the latest version of their subject
+var ErrIncompatibleSchema = errors.New("incompatible schema")
+
+// Schema is a schema of the registry
+type Schema struct {
+	ID      int
+	Type    string // AVRO or JSON
+	Schema  string
+	Subject string
+	Version int
+}
+
+// SchemaRegistry is a client of a Confluent Schema Registry. Schemas looked
+// up by ID are cached, since IDs are immutable.
+type SchemaRegistry struct {
+	url    string
+	config *SchemaConfig
+	client *http.Client
+
+	mu   sync.RWMutex
+	byID map[int]*Schema
+}
+
+// NewSchemaRegistry creates a client of the registry at registryURL
+func NewSchemaRegistry(registryURL string, config *SchemaConfig) *SchemaRegistry {
+	return &SchemaRegistry{
+		url:    strings.TrimSuffix(registryURL, "/"),
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		byID:   make(map[int]*Schema),
+	}
+}
+
+// schemaBody is a schema in registry requests and responses. The schema type
+// is omitted for Avro.
+type schemaBody struct {
+	ID         int    `json:"id,omitempty"`
+	Subject    string `json:"subject,omitempty"`
+	Version    int    `json:"version,omitempty"`
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+func newSchemaBody(schema *Schema) *schemaBody {
+	body := &schemaBody{Schema: schema.Schema}
+	if schema.Type != SchemaAvro {
+		body.SchemaType = schema.Type
+	}
+	return body
+}
+
+func (b *schemaBody) toSchema() *Schema {
+	schemaType := b.SchemaType
+	if schemaType == "" {
+		schemaType = SchemaAvro
+	}
+	return &Schema{
+		ID:      b.ID,
+		Type:    schemaType,
+		Schema:  b.Schema,
+		Subject: b.Subject,
+		Version: b.Version,
+	}
+}
+
+// Register registers schema under subject and returns its ID. Registering a
+// schema already registered returns its existing ID.
+func (r *SchemaRegistry) Register(ctx context.Context, subject string, schema *Schema) (int, error) {
+	var resp struct {
+		ID int `json:"id"`
+	}
+	if err := r.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions",
+		newSchemaBody(schema), &resp); err != nil {
+		return 0, fmt.Errorf("failed to register schema of %s: %w", subject, err)
+	}
+	return resp.ID, nil
+}
+
+// Lookup returns the version of subject with schema, or ErrSchemaNotFound
+func (r *SchemaRegistry) Lookup(ctx context.Context, subject string, schema *Schema) (*Schema, error) {
+	var resp schemaBody
+	if err := r.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject),
+		newSchemaBody(schema), &resp); err != nil {
+		return nil, fmt.Errorf("failed to look up schema of %s: %w", subject, err)
+	}
+	return resp.toSchema(), nil
+}
+
+// Latest returns the latest version of subject, or ErrSchemaNotFound
+func (r *SchemaRegistry) Latest(ctx context.Context, subject string) (*Schema, error) {
+	var resp schemaBody
+	if err := r.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/latest",
+		nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch latest schema of %s: %w", subject, err)
+	}
+	return resp.toSchema(), nil
+}
+
+// SchemaByID returns the schema with id
+func (r *SchemaRegistry) SchemaByID(ctx context.Context, id int) (*Schema, error) {
+	r.mu.RLock()
+	schema, ok := r.byID[id]
+	r.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	var resp schemaBody
+	if err := r.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch schema %d: %w", id, err)
+	}
+	resp.ID = id
+	schema = resp.toSchema()
+
+	r.mu.Lock()
+	r.byID[id] = schema
+	r.mu.Unlock()
+	return schema, nil
+}
+
+// CheckCompatibility checks schema against the latest version of subject,
+// under the compatibility level of the subject. Schemas of new subjects are
+// compatible.
+func (r *SchemaRegistry) CheckCompatibility(ctx context.Context, subject string, schema *Schema) error {
+	var resp struct {
+		IsCompatible bool     `json:"is_compatible"`
+		Messages     []string `json:"messages"`
+	}
+	err := r.do(ctx, http.MethodPost,
+		"/compatibility/subjects/"+url.PathEscape(subject)+"/versions/latest?verbose=true",
+		newSchemaBody(schema), &resp)
+	if errors.Is(err, ErrSchemaNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check compatibility of %s: %w", subject, err)
+	}
+	if !resp.IsCompatible {
+		return fmt.Errorf("%w with %s: %s", ErrIncompatibleSchema, subject, strings.Join(resp.Messages, "; "))
+	}
+	return nil
+}
+
+// SetCompatibility sets the compatibility level of subject
+func (r *SchemaRegistry) SetCompatibility(ctx context.Context, subject string, level string) error {
+	body := map[string]string{"compatibility": level}
+	if err := r.do(ctx, http.MethodPut, "/config/"+url.PathEscape(subject), body, nil); err != nil {
+		return fmt.Errorf("failed to set compatibility of %s: %w", subject, err)
+	}
+	return nil
+}
+
+// registryError is an error response of the registry
+type registryError struct {
+	Status  int
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+func (e *registryError) Error() string {
+	return fmt.Sprintf("schema registry returned %d (%d): %s", e.Status, e.Code, e.Message)
+}
+
+// Unwrap maps the not found responses of subjects, versions and schemas to
+// ErrSchemaNotFound
+func (e *registryError) Unwrap() error {
+	if e.Status == http.StatusNotFound {
+		return ErrSchemaNotFound
+	}
+	return nil
+}
+
+// do sends a registry request and decodes its response into out, if not nil
+func (r *SchemaRegistry) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.url+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	}
+	if r.config.Username != "" {
+		req.SetBasicAuth(r.config.Username, r.config.Password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("schema registry request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		regErr := &registryError{Status: resp.StatusCode}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		if json.Unmarshal(data, regErr) != nil {
+			regErr.Message = string(data)
+		}
+		return regErr
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid schema registry response: %w", err)
+	}
+	return nil
+}
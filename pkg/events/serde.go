@@ -0,0 +1,266 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Serializer encodes the message values of a topic
+type Serializer interface {
+	Serialize(ctx context.Context, topic string, value interface{}) ([]byte, error)
+}
+
+// Deserializer decodes the message values of a topic into out
+type Deserializer interface {
+	Deserialize(ctx context.Context, topic string, data []byte, out interface{}) error
+}
+
+// JSONSerializer encodes values as plain JSON. []byte values are passed
+// through, as encoded already.
+type JSONSerializer struct{}
+
+// Serialize implements Serializer
+func (JSONSerializer) Serialize(_ context.Context, _ string, value interface{}) ([]byte, error) {
+	return encode(value)
+}
+
+// Deserialize implements Deserializer
+func (JSONSerializer) Deserialize(_ context.Context, _ string, data []byte, out interface{}) error {
+	return json.Unmarshal(data, out)
+}
+
+// magicByte starts values in the Confluent wire format: the magic byte, the
+// schema ID as a big-endian uint32, then the encoded value
+const magicByte = 0
+
+// Schema file extensions
+const (
+	avroExtension = ".avsc"
+	jsonExtension = ".schema.json"
+)
+
+// codec encodes and decodes values of a registered schema
+type codec struct {
+	id   int
+	avro *goavro.Codec      // Avro schemas
+	json *jsonschema.Schema // JSON schemas
+}
+
+// SchemaSerde serializes values with the registry schema of their topic, in
+// the Confluent wire format, and deserializes values of any registered
+// schema. Values are converted to and from JSON, so Avro records map to the
+// JSON encoding of Go values. Topics without a schema use plain JSON.
+type SchemaSerde struct {
+	registry *SchemaRegistry
+	topics   map[string]*codec // By topic, loaded at startup
+
+	mu  sync.RWMutex
+	ids map[int]*codec // By schema ID, loaded when first read
+}
+
+// NewSchemaSerde loads the schemas of config.Directory and checks each
+// against the registry subject of its topic, <topic>-value: schemas
+// incompatible with the latest version fail. Schemas not registered are
+// registered with AutoRegister, and fail otherwise.
+func NewSchemaSerde(ctx context.Context, registry *SchemaRegistry, config *SchemaConfig) (*SchemaSerde, error) {
+	files, err := os.ReadDir(config.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema directory: %w", err)
+	}
+
+	s := &SchemaSerde{
+		registry: registry,
+		topics:   make(map[string]*codec),
+		ids:      make(map[int]*codec),
+	}
+	for _, file := range files {
+		name := file.Name()
+		schema := &Schema{}
+		var topic string
+		switch {
+		case strings.HasSuffix(name, avroExtension):
+			topic, schema.Type = strings.TrimSuffix(name, avroExtension), SchemaAvro
+		case strings.HasSuffix(name, jsonExtension):
+			topic, schema.Type = strings.TrimSuffix(name, jsonExtension), SchemaJSON
+		default:
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(config.Directory, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema of %s: %w", topic, err)
+		}
+		schema.Schema = string(data)
+
+		c, err := s.load(ctx, topic, schema, config)
+		if err != nil {
+			return nil, err
+		}
+		s.topics[topic] = c
+		s.ids[c.id] = c
+	}
+	return s, nil
+}
+
+// load checks and registers the schema of a topic
+func (s *SchemaSerde) load(ctx context.Context, topic string, schema *Schema, config *SchemaConfig) (*codec, error) {
+	subject := topic + "-value"
+	if config.Compatibility != "" {
+		if err := s.registry.SetCompatibility(ctx, subject, config.Compatibility); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.registry.CheckCompatibility(ctx, subject, schema); err != nil {
+		return nil, err
+	}
+
+	if config.AutoRegister {
+		id, err := s.registry.Register(ctx, subject, schema)
+		if err != nil {
+			return nil, err
+		}
+		schema.ID = id
+	} else {
+		registered, err := s.registry.Lookup(ctx, subject, schema)
+		if err != nil {
+			return nil, fmt.Errorf("schema of %s is not registered: %w", topic, err)
+		}
+		schema.ID = registered.ID
+	}
+
+	return compile(schema)
+}
+
+// compile compiles a registered schema
+func compile(schema *Schema) (*codec, error) {
+	c := &codec{id: schema.ID}
+	var err error
+	switch schema.Type {
+	case SchemaAvro:
+		c.avro, err = goavro.NewCodecForStandardJSONFull(schema.Schema)
+	case SchemaJSON:
+		c.json, err = jsonschema.CompileString(fmt.Sprintf("schema-%d.json", schema.ID), schema.Schema)
+	default:
+		err = fmt.Errorf("unsupported schema type: %s", schema.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %d: %w", schema.ID, err)
+	}
+	return c, nil
+}
+
+// Serialize implements Serializer. Values that do not conform to the schema
+// of their topic fail; tenant topics have the schema of the topic they
+// isolate.
+func (s *SchemaSerde) Serialize(_ context.Context, topic string, value interface{}) ([]byte, error) {
+	data, err := encode(value)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := s.topics[baseTopic(topic)]
+	if !ok {
+		return data, nil
+	}
+
+	out := make([]byte, 5, len(data)+5)
+	out[0] = magicByte
+	binary.BigEndian.PutUint32(out[1:], uint32(c.id))
+
+	if c.avro != nil {
+		native, _, err := c.avro.NativeFromTextual(data)
+		if err != nil {
+			return nil, fmt.Errorf("value does not match schema of %s: %w", topic, err)
+		}
+		return c.avro.BinaryFromNative(out, native)
+	}
+
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.json.Validate(doc); err != nil {
+		return nil, fmt.Errorf("value does not match schema of %s: %w", topic, err)
+	}
+	return append(out, data...), nil
+}
+
+// Deserialize implements Deserializer. Values not in the wire format are
+// decoded as plain JSON.
+func (s *SchemaSerde) Deserialize(ctx context.Context, topic string, data []byte, out interface{}) error {
+	if len(data) < 5 || data[0] != magicByte {
+		return json.Unmarshal(data, out)
+	}
+
+	c, err := s.codec(ctx, int(binary.BigEndian.Uint32(data[1:5])))
+	if err != nil {
+		return err
+	}
+	payload := data[5:]
+	if c.avro != nil {
+		native, _, err := c.avro.NativeFromBinary(payload)
+		if err != nil {
+			return fmt.Errorf("invalid Avro value of %s: %w", topic, err)
+		}
+		if payload, err = c.avro.TextualFromNative(nil, native); err != nil {
+			return fmt.Errorf("invalid Avro value of %s: %w", topic, err)
+		}
+	}
+	return json.Unmarshal(payload, out)
+}
+
+// codec returns the codec of a schema ID, fetching the schema when first
+// seen
+func (s *SchemaSerde) codec(ctx context.Context, id int) (*codec, error) {
+	s.mu.RLock()
+	c, ok := s.ids[id]
+	s.mu.RUnlock()
+	if ok {
+		return c, nil
+	}
+
+	schema, err := s.registry.SchemaByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c, err = compile(schema); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.ids[id] = c
+	s.mu.Unlock()
+	return c, nil
+}
+
+// decodeJSON decodes a JSON document for schema validation, keeping the
+// precision of numbers
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON value: %w", err)
+	}
+	return doc, nil
+}
+
+// encode encodes a value as JSON, passing []byte through
+func encode(value interface{}) ([]byte, error) {
+	if data, ok := value.([]byte); ok {
+		return data, nil
+	}
+	return json.Marshal(value)
+}
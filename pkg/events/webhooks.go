@@ -0,0 +1,718 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Headers of webhook deliveries
+const (
+	// WebhookSignatureHeader carries "t=<unix time>,v1=<hex HMAC-SHA256>" of
+	// the timestamp and body joined by a period, keyed by the subscription
+	// secret
+	WebhookSignatureHeader = "X-DictaMesh-Signature"
+
+	// WebhookDeliveryHeader carries the delivery ID, the same for every
+	// attempt, for receivers to drop duplicate deliveries
+	WebhookDeliveryHeader = "X-DictaMesh-Delivery"
+
+	// WebhookEventHeader carries the event type
+	WebhookEventHeader = "X-DictaMesh-Event"
+)
+
+var (
+	// ErrSubscriptionNotFound is returned for unknown webhook subscriptions,
+	// and for those of other organizations
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+
+	// ErrInvalidSubscription is returned for subscriptions with an invalid
+	// endpoint or event types
+	ErrInvalidSubscription = errors.New("invalid webhook subscription")
+
+	// ErrTooManySubscriptions is returned when an organization has
+	// MaxSubscriptions already
+	ErrTooManySubscriptions = errors.New("too many webhook subscriptions")
+)
+
+// blockedNetworks are the ranges webhook endpoints may not resolve to, besides
+// loopback, private, link-local, multicast and unspecified addresses
+var blockedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // This network
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, reaching any IPv4 address
+}
+
+// WebhookDeliveryStatus is the state of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "pending"   // Not sent yet, or waiting to retry
+	WebhookDelivered WebhookDeliveryStatus = "delivered" // Accepted by the endpoint
+	WebhookFailed    WebhookDeliveryStatus = "failed"    // Given up after MaxAttempts
+)
+
+// WebhookSubscription subscribes an endpoint of an organization to event
+// types, by name, e.g. billing.invoice.created, or by prefix, e.g.
+// billing.*
+type WebhookSubscription struct {
+	ID             string   `gorm:"type:uuid;primaryKey" json:"id"`
+	OrganizationID string   `gorm:"type:varchar(255);not null" json:"organization_id"`
+	URL            string   `gorm:"type:text;not null" json:"url"`
+	EventTypes     []string `gorm:"type:jsonb;serializer:json;not null" json:"event_types"`
+	Description    string   `gorm:"type:text" json:"description,omitempty"`
+
+	// Secret signing the deliveries; only returned when subscribing
+	Secret string `gorm:"type:varchar(255);not null" json:"-"`
+
+	// Deliveries given up in a row; a delivery accepted resets it
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      string     `gorm:"type:text" json:"disabled_reason,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name
+func (WebhookSubscription) TableName() string {
+	return "dictamesh_webhook_subscriptions"
+}
+
+// Matches reports whether the subscription covers an event type
+func (s *WebhookSubscription) Matches(eventType string) bool {
+	for _, pattern := range s.EventTypes {
+		if pattern == eventType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(eventType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is an event delivered to a subscription, with the outcome
+// of its last attempt
+type WebhookDelivery struct {
+	ID             string                `gorm:"type:uuid;primaryKey" json:"id"`
+	SubscriptionID string                `gorm:"type:uuid;not null" json:"subscription_id"`
+	OrganizationID string                `gorm:"type:varchar(255);not null" json:"organization_id"`
+	EventID        string                `gorm:"type:varchar(255);not null" json:"event_id"`
+	EventType      string                `gorm:"type:varchar(255);not null" json:"event_type"`
+	Payload        []byte                `gorm:"type:bytea;not null" json:"-"`
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(20);not null" json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at"`
+	LastStatusCode int                   `json:"last_status_code,omitempty"`
+	LastError      string                `gorm:"type:text" json:"last_error,omitempty"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at,omitempty"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
+
+// TableName specifies the table name
+func (WebhookDelivery) TableName() string {
+	return "dictamesh_webhook_deliveries"
+}
+
+// WebhookBridge delivers the events of the bus to the webhook endpoints of
+// organizations. Handle, run by a consumer of the topics to deliver, records
+// a delivery per enabled subscription of the organization of each event;
+// Run posts due deliveries, signed with the secret of their subscription.
+//
+// Failed deliveries are retried with backoff, or after the Retry-After of
+// the endpoint if longer, and given up after MaxAttempts. A subscription
+// whose deliveries are given up DisableAfter times in a row, or whose
+// endpoint answers 410 Gone, is disabled: its events are no longer recorded
+// and its pending deliveries wait until it is enabled again. Deliveries are
+// sent at least once, in no particular order.
+type WebhookBridge struct {
+	db           *gorm.DB
+	client       *http.Client
+	deserializer Deserializer // Of the bus the events are consumed from
+	config       *WebhookConfig
+	logger       *zap.Logger
+}
+
+// NewWebhookBridge creates a webhook bridge storing subscriptions and
+// deliveries in db, for events consumed from bus
+func NewWebhookBridge(db *gorm.DB, bus EventBus, config *WebhookConfig, logger *zap.Logger) (*WebhookBridge, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid webhook config: %w", err)
+	}
+
+	// Endpoints are checked again when dialed, since the addresses they
+	// resolve to may have changed since subscribing
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivateNetworks {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddress(addrPort.Addr()) {
+				return fmt.Errorf("webhook endpoint address %s is not public", addrPort.Addr())
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // Endpoints are dialed directly, past the address check
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookBridge{
+		db: db,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+			// A redirect is a failed delivery; endpoints are updated instead
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		deserializer: deserializerOf(bus),
+		config:       config,
+		logger:       logger,
+	}, nil
+}
+
+// Subscribe creates a subscription of OrganizationID with a new ID and
+// secret
+func (b *WebhookBridge) Subscribe(ctx context.Context, subscription *WebhookSubscription) error {
+	if err := b.validate(ctx, subscription); err != nil {
+		return err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	subscription.ID = uuid.NewString()
+	subscription.Secret = "whsec_" + hex.EncodeToString(secret)
+	subscription.ConsecutiveFailures = 0
+	subscription.DisabledAt = nil
+	subscription.DisabledReason = ""
+
+	// Subscriptions of an organization are created one at a time, so
+	// concurrent calls cannot exceed MaxSubscriptions
+	return b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))",
+			"dictamesh_webhook_subscriptions:"+subscription.OrganizationID).Error; err != nil {
+			return fmt.Errorf("failed to lock webhook subscriptions: %w", err)
+		}
+
+		var count int64
+		if err := tx.Model(&WebhookSubscription{}).
+			Where("organization_id = ?", subscription.OrganizationID).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count webhook subscriptions: %w", err)
+		}
+		if count >= int64(b.config.MaxSubscriptions) {
+			return fmt.Errorf("%w: at most %d per organization", ErrTooManySubscriptions, b.config.MaxSubscriptions)
+		}
+
+		if err := tx.Create(subscription).Error; err != nil {
+			return fmt.Errorf("failed to create webhook subscription: %w", err)
+		}
+		return nil
+	})
+}
+
+// validate validates the endpoint and event types of a subscription.
+// Endpoints must resolve to public addresses only, unless
+// AllowPrivateNetworks is set.
+func (b *WebhookBridge) validate(ctx context.Context, subscription *WebhookSubscription) error {
+	if subscription.OrganizationID == "" {
+		return fmt.Errorf("%w: organization is required", ErrInvalidSubscription)
+	}
+
+	endpoint, err := url.Parse(subscription.URL)
+	if err != nil || endpoint.Host == "" {
+		return fmt.Errorf("%w: invalid URL: %q", ErrInvalidSubscription, subscription.URL)
+	}
+	switch {
+	case endpoint.Scheme == "https":
+	case endpoint.Scheme == "http" && b.config.AllowHTTP:
+	default:
+		return fmt.Errorf("%w: URL must use https", ErrInvalidSubscription)
+	}
+	if endpoint.User != nil {
+		return fmt.Errorf("%w: URL must not carry credentials", ErrInvalidSubscription)
+	}
+	if !b.config.AllowPrivateNetworks {
+		addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", endpoint.Hostname())
+		if err != nil || len(addrs) == 0 {
+			return fmt.Errorf("%w: host does not resolve: %q", ErrInvalidSubscription, endpoint.Hostname())
+		}
+		for _, addr := range addrs {
+			if !publicAddress(addr) {
+				return fmt.Errorf("%w: host resolves to a non-public address: %q", ErrInvalidSubscription, endpoint.Hostname())
+			}
+		}
+	}
+
+	if len(subscription.EventTypes) == 0 {
+		return fmt.Errorf("%w: event types are required", ErrInvalidSubscription)
+	}
+	for _, pattern := range subscription.EventTypes {
+		eventType, _ := strings.CutSuffix(pattern, ".*")
+		if !validTopicName.MatchString(eventType) {
+			return fmt.Errorf("%w: invalid event type: %q", ErrInvalidSubscription, pattern)
+		}
+	}
+	return nil
+}
+
+// publicAddress reports whether addr is a public unicast address, which
+// webhook endpoints may be delivered to
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range blockedNetworks {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Subscriptions returns the subscriptions of an organization, oldest first
+func (b *WebhookBridge) Subscriptions(ctx context.Context, organizationID string) ([]WebhookSubscription, error) {
+	var subscriptions []WebhookSubscription
+	if err := b.db.WithContext(ctx).
+		Where("organization_id = ?", organizationID).
+		Order("created_at").
+		Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// Subscription returns a subscription of an organization
+func (b *WebhookBridge) Subscription(ctx context.Context, organizationID, id string) (*WebhookSubscription, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	var subscription WebhookSubscription
+	err := b.db.WithContext(ctx).
+		Where("id = ? AND organization_id = ?", id, organizationID).
+		First(&subscription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	return &subscription, nil
+}
+
+// Unsubscribe deletes a subscription of an organization with its deliveries
+func (b *WebhookBridge) Unsubscribe(ctx context.Context, organizationID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrSubscriptionNotFound
+	}
+
+	result := b.db.WithContext(ctx).
+		Where("id = ? AND organization_id = ?", id, organizationID).
+		Delete(&WebhookSubscription{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// Enable enables a disabled subscription of an organization, resuming its
+// pending deliveries
+func (b *WebhookBridge) Enable(ctx context.Context, organizationID, id string) (*WebhookSubscription, error) {
+	subscription, err := b.Subscription(ctx, organizationID, id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.DisabledAt == nil {
+		return subscription, nil
+	}
+
+	if err := b.db.WithContext(ctx).Model(subscription).Updates(map[string]interface{}{
+		"consecutive_failures": 0,
+		"disabled_at":          nil,
+		"disabled_reason":      "",
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to enable webhook subscription: %w", err)
+	}
+	subscription.ConsecutiveFailures = 0
+	subscription.DisabledAt = nil
+	subscription.DisabledReason = ""
+	return subscription, nil
+}
+
+// Deliveries returns the latest deliveries of a subscription of an
+// organization, newest first, filtered by status unless empty
+func (b *WebhookBridge) Deliveries(
+	ctx context.Context,
+	organizationID, id string,
+	status WebhookDeliveryStatus,
+	limit int,
+) ([]WebhookDelivery, error) {
+	if _, err := b.Subscription(ctx, organizationID, id); err != nil {
+		return nil, err
+	}
+
+	query := b.db.WithContext(ctx).Where("subscription_id = ?", id)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var deliveries []WebhookDelivery
+	if err := query.Order("created_at DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Handle records the deliveries of an enveloped event to the enabled
+// subscriptions of its organization. It is the Handler of the consumer of
+// the topics to deliver; a redelivered event is recorded once. Records are
+// decoded with the schema of their topic and delivered as JSON; those that
+// are not envelopes fail permanently.
+func (b *WebhookBridge) Handle(ctx context.Context, record *Record) error {
+	var envelope Envelope
+	if err := b.deserializer.Deserialize(ctx, record.Topic, record.Value, &envelope); err != nil {
+		return Permanent(fmt.Errorf("failed to decode event envelope: %w", err))
+	}
+	if envelope.EventID == "" || envelope.EventType == "" {
+		return Permanent(fmt.Errorf("event is not enveloped"))
+	}
+	if envelope.OrganizationID == "" {
+		return nil
+	}
+	payload, err := json.Marshal(&envelope)
+	if err != nil {
+		return Permanent(fmt.Errorf("failed to encode event envelope: %w", err))
+	}
+
+	var subscriptions []WebhookSubscription
+	if err := b.db.WithContext(ctx).
+		Where("organization_id = ? AND disabled_at IS NULL", envelope.OrganizationID).
+		Find(&subscriptions).Error; err != nil {
+		return fmt.Errorf("failed to find webhook subscriptions: %w", err)
+	}
+
+	now := time.Now()
+	var deliveries []WebhookDelivery
+	for i := range subscriptions {
+		if !subscriptions[i].Matches(envelope.EventType) {
+			continue
+		}
+		deliveries = append(deliveries, WebhookDelivery{
+			ID:             uuid.NewString(),
+			SubscriptionID: subscriptions[i].ID,
+			OrganizationID: envelope.OrganizationID,
+			EventID:        envelope.EventID,
+			EventType:      envelope.EventType,
+			Payload:        payload,
+			Status:         WebhookPending,
+			NextAttemptAt:  now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := b.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}}, DoNothing: true}).
+		Create(&deliveries).Error; err != nil {
+		return fmt.Errorf("failed to record webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// Run sends due deliveries until ctx is done. Full batches are followed by
+// the next one right away; otherwise deliveries are polled every
+// PollInterval.
+func (b *WebhookBridge) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := b.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			b.logger.Error("failed to send webhook deliveries", zap.Error(err))
+		}
+		if err == nil && sent == b.config.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// webhookAttempt is the outcome of posting a delivery
+type webhookAttempt struct {
+	statusCode int
+	retryAfter time.Duration
+	err        error
+}
+
+// RunOnce sends a batch of due deliveries of enabled subscriptions and
+// returns how many were claimed. Instances claim deliveries with FOR UPDATE
+// SKIP LOCKED and hold them until their outcome is recorded, so each is sent
+// by one instance at a time. Once no more are due, finished deliveries older
+// than Retention are deleted.
+func (b *WebhookBridge) RunOnce(ctx context.Context) (int, error) {
+	var claimed int
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var due []WebhookDelivery
+		if err := tx.Raw(`
+			SELECT d.* FROM dictamesh_webhook_deliveries d
+			JOIN dictamesh_webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = ? AND d.next_attempt_at <= ? AND s.disabled_at IS NULL
+			ORDER BY d.next_attempt_at
+			LIMIT ?
+			FOR UPDATE OF d SKIP LOCKED`, WebhookPending, time.Now(), b.config.BatchSize).
+			Scan(&due).Error; err != nil {
+			return fmt.Errorf("failed to fetch due webhook deliveries: %w", err)
+		}
+		claimed = len(due)
+		if claimed == 0 {
+			return nil
+		}
+
+		ids := make([]string, 0, len(due))
+		for i := range due {
+			ids = append(ids, due[i].SubscriptionID)
+		}
+		var subscriptions []WebhookSubscription
+		if err := tx.Where("id IN ?", ids).Find(&subscriptions).Error; err != nil {
+			return fmt.Errorf("failed to fetch webhook subscriptions: %w", err)
+		}
+		byID := make(map[string]*WebhookSubscription, len(subscriptions))
+		for i := range subscriptions {
+			byID[subscriptions[i].ID] = &subscriptions[i]
+		}
+
+		attempts := make([]webhookAttempt, len(due))
+		var wg sync.WaitGroup
+		for i := range due {
+			subscription, ok := byID[due[i].SubscriptionID]
+			if !ok {
+				continue
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				attempts[i] = b.post(ctx, subscription, &due[i])
+			}(i)
+		}
+		wg.Wait()
+
+		for i := range due {
+			subscription, ok := byID[due[i].SubscriptionID]
+			if !ok {
+				continue
+			}
+			if err := b.record(tx, subscription, &due[i], &attempts[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return claimed, err
+	}
+
+	if claimed < b.config.BatchSize {
+		if err := b.cleanup(ctx); err != nil {
+			return claimed, err
+		}
+	}
+	return claimed, b.observe(ctx)
+}
+
+// post signs and posts a delivery to the endpoint of its subscription
+func (b *WebhookBridge) post(ctx context.Context, subscription *WebhookSubscription, delivery *WebhookDelivery) webhookAttempt {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return webhookAttempt{err: fmt.Errorf("invalid webhook request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DictaMesh-Webhook/1.0")
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(subscription.Secret, time.Now(), delivery.Payload))
+
+	start := time.Now()
+	resp, err := b.client.Do(req)
+	webhookDeliveryDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		return webhookAttempt{err: fmt.Errorf("webhook request failed: %w", err)}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	attempt := webhookAttempt{statusCode: resp.StatusCode}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		attempt.err = fmt.Errorf("webhook endpoint returned %d", resp.StatusCode)
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			attempt.retryAfter = time.Duration(seconds) * time.Second
+		}
+	}
+	return attempt
+}
+
+// record records the outcome of an attempt of a delivery, and the failures
+// of its subscription
+func (b *WebhookBridge) record(tx *gorm.DB, subscription *WebhookSubscription, delivery *WebhookDelivery, attempt *webhookAttempt) error {
+	now := time.Now()
+	attempts := delivery.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":         attempts,
+		"last_status_code": attempt.statusCode,
+		"last_error":       "",
+		"last_attempt_at":  now,
+	}
+
+	if attempt.err == nil {
+		webhookDeliveriesTotal.WithLabelValues("success").Inc()
+		updates["status"] = WebhookDelivered
+		updates["delivered_at"] = now
+		if err := tx.Model(delivery).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to record webhook delivery: %w", err)
+		}
+		if err := tx.Model(&WebhookSubscription{}).
+			Where("id = ? AND consecutive_failures > 0", subscription.ID).
+			Update("consecutive_failures", 0).Error; err != nil {
+			return fmt.Errorf("failed to reset webhook failures: %w", err)
+		}
+		return nil
+	}
+
+	updates["last_error"] = attempt.err.Error()
+	gone := attempt.statusCode == http.StatusGone
+	if attempts < b.config.Retry.MaxAttempts && !gone {
+		webhookDeliveriesTotal.WithLabelValues("retry").Inc()
+		wait := b.config.Retry.Backoff(attempts)
+		if attempt.retryAfter > wait {
+			wait = attempt.retryAfter
+		}
+		updates["next_attempt_at"] = now.Add(wait)
+		if err := tx.Model(delivery).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to record webhook delivery: %w", err)
+		}
+		return nil
+	}
+
+	webhookDeliveriesTotal.WithLabelValues("failed").Inc()
+	updates["status"] = WebhookFailed
+	if err := tx.Model(delivery).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	if err := tx.Model(&WebhookSubscription{}).
+		Where("id = ?", subscription.ID).
+		Update("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error; err != nil {
+		return fmt.Errorf("failed to record webhook failure: %w", err)
+	}
+
+	reason := fmt.Sprintf("%d consecutive deliveries failed", b.config.DisableAfter)
+	disable := tx.Model(&WebhookSubscription{}).Where("id = ? AND disabled_at IS NULL", subscription.ID)
+	if gone {
+		reason = "endpoint returned 410 Gone"
+	} else {
+		disable = disable.Where("consecutive_failures >= ?", b.config.DisableAfter)
+	}
+	result := disable.Updates(map[string]interface{}{
+		"disabled_at":     now,
+		"disabled_reason": reason,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to disable webhook subscription: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		webhookDisabledTotal.Inc()
+		b.logger.Warn("disabled webhook subscription",
+			zap.String("subscription_id", subscription.ID),
+			zap.String("organization_id", subscription.OrganizationID),
+			zap.String("reason", reason))
+	}
+	return nil
+}
+
+// cleanup deletes a batch of the finished deliveries older than Retention
+func (b *WebhookBridge) cleanup(ctx context.Context) error {
+	if b.config.Retention <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-b.config.Retention)
+	if err := b.db.WithContext(ctx).Exec(`
+		DELETE FROM dictamesh_webhook_deliveries
+		WHERE id IN (
+			SELECT id FROM dictamesh_webhook_deliveries
+			WHERE status <> ? AND created_at < ?
+			ORDER BY created_at
+			LIMIT ?)`, WebhookPending, cutoff, b.config.BatchSize).Error; err != nil {
+		return fmt.Errorf("failed to delete finished webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// observe updates the backlog metric of the deliveries
+func (b *WebhookBridge) observe(ctx context.Context) error {
+	var pending int64
+	if err := b.db.WithContext(ctx).Model(&WebhookDelivery{}).
+		Where("status = ?", WebhookPending).
+		Count(&pending).Error; err != nil {
+		return fmt.Errorf("failed to count pending webhook deliveries: %w", err)
+	}
+	webhookPending.Set(float64(pending))
+	return nil
+}
+
+// SignWebhook returns the signature header value of a delivery. Receivers
+// recompute the HMAC-SHA256 of "<t>.<body>" with the subscription secret,
+// compare it in constant time and reject stale timestamps.
+func SignWebhook(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package ratelimit
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_gateway_ratelimit_requests_total",
+			Help: "Requests checked by the rate limiter by scope, tier and result",
+		},
+		[]string{"scope", "tier", "result"},
+	)
+
+	checkDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dictamesh_gateway_ratelimit_check_duration_seconds",
+			Help:    "Rate limit check latency by scope",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 2, 12),
+		},
+		[]string{"scope"},
+	)
+)
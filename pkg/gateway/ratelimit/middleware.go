@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+)
+
+// KeyFunc returns the bucket key of a request
+type KeyFunc func(r *http.Request) string
+
+// DefaultKey keys requests by organization, then by client address.
+// Credentials are only trusted once tenant.Middleware has resolved them to
+// an organization: keying anonymous requests by an unverified API key would
+// give a client a fresh bucket for every key it makes up.
+func DefaultKey(r *http.Request) string {
+	if t, ok := tenant.FromContext(r.Context()); ok {
+		return "org:" + t.OrganizationID
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// MiddlewareConfig configures the rate limit HTTP middleware
+type MiddlewareConfig struct {
+	// Plans resolves the limit of each request (defaults to DefaultPlans,
+	// which trusts the tier of the tenant; see OrganizationPlans)
+	Plans PlanResolver
+
+	// Key returns the bucket key (defaults to DefaultKey)
+	Key KeyFunc
+
+	// Scope separates buckets of different APIs (e.g., "graphql", "billing")
+	Scope string
+
+	// Logger logs limiter failures (optional)
+	Logger *zap.Logger
+}
+
+// Middleware returns HTTP middleware enforcing rate limits. It must run after
+// tenant.Middleware so the tenant is available in the request context.
+func (l *Limiter) Middleware(config MiddlewareConfig) func(http.Handler) http.Handler {
+	if config.Plans == nil {
+		config.Plans = DefaultPlans()
+	}
+	if config.Key == nil {
+		config.Key = DefaultKey
+	}
+	if config.Scope == "" {
+		config.Scope = "api"
+	}
+	if config.Logger == nil {
+		config.Logger = zap.NewNop()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t, _ := tenant.FromContext(r.Context())
+
+			limit, err := config.Plans.Limit(r.Context(), t)
+			if err != nil {
+				config.Logger.Warn("failed to resolve rate limit", zap.Error(err))
+				l.fail(w, r, next, config.Scope, "unknown")
+				return
+			}
+			tier := tierLabel(t, limit)
+
+			start := time.Now()
+			result, err := l.Allow(r.Context(), config.Scope+":"+config.Key(r), limit, 1)
+			checkDuration.WithLabelValues(config.Scope).Observe(time.Since(start).Seconds())
+			if err != nil {
+				config.Logger.Warn("rate limit check failed", zap.Error(err))
+				l.fail(w, r, next, config.Scope, tier)
+				return
+			}
+
+			if !limit.Unlimited() {
+				setHeaders(w.Header(), result)
+			}
+
+			if !result.Allowed {
+				requestsTotal.WithLabelValues(config.Scope, tier, "limited").Inc()
+				writeLimited(w, result)
+				return
+			}
+
+			requestsTotal.WithLabelValues(config.Scope, tier, "allowed").Inc()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tierLabel returns the metric label of the tier of a resolved limit. Only
+// the built-in tiers are used as labels, so custom plan slugs cannot grow
+// the number of series.
+func tierLabel(t *tenant.Tenant, limit Limit) string {
+	if t == nil {
+		return "anonymous"
+	}
+	switch limit.Tier {
+	case tenant.TierFree, tenant.TierStarter, tenant.TierProfessional, tenant.TierEnterprise:
+		return string(limit.Tier)
+	}
+	return "unknown"
+}
+
+// fail handles limiter errors according to FailOpen
+func (l *Limiter) fail(w http.ResponseWriter, r *http.Request, next http.Handler, scope, tier string) {
+	requestsTotal.WithLabelValues(scope, tier, "error").Inc()
+	if l.config.FailOpen {
+		next.ServeHTTP(w, r)
+		return
+	}
+	http.Error(w, "rate limiter unavailable", http.StatusServiceUnavailable)
+}
+
+// setHeaders writes the RateLimit headers (IETF draft-ietf-httpapi-ratelimit-headers)
+func setHeaders(h http.Header, result *Result) {
+	h.Set("RateLimit-Limit", strconv.Itoa(result.Limit.Burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", result.Limit.Burst, ceilSeconds(result.Limit.Window())))
+}
+
+func writeLimited(w http.ResponseWriter, result *Result) {
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":       "rate limit exceeded",
+		"retry_after": ceilSeconds(result.RetryAfter),
+	})
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+)
+
+// Plan feature keys holding rate limits in SubscriptionPlan.Features
+const (
+	FeatureRateLimitRPS   = "rate_limit_rps"
+	FeatureRateLimitBurst = "rate_limit_burst"
+)
+
+// PlanResolver returns the limit that applies to a tenant. t is nil for
+// anonymous requests.
+type PlanResolver interface {
+	Limit(ctx context.Context, t *tenant.Tenant) (Limit, error)
+}
+
+// StaticPlans maps subscription tiers to limits. It trusts the tier of the
+// tenant, so it must only be used when tenants are resolved from verified
+// credentials; see OrganizationPlans otherwise.
+type StaticPlans struct {
+	Tiers     map[tenant.Tier]Limit
+	Anonymous Limit
+}
+
+// DefaultPlans returns the default per-tier limits, matching the seeded
+// subscription plans
+func DefaultPlans() *StaticPlans {
+	return &StaticPlans{
+		Tiers: map[tenant.Tier]Limit{
+			tenant.TierFree:         {Rate: 5, Burst: 20},
+			tenant.TierStarter:      {Rate: 50, Burst: 100},
+			tenant.TierProfessional: {Rate: 200, Burst: 400},
+			tenant.TierEnterprise:   {Rate: 1000, Burst: 2000},
+		},
+		Anonymous: Limit{Rate: 1, Burst: 10},
+	}
+}
+
+// Limit implements PlanResolver. Unknown tiers get the free tier limit.
+func (p *StaticPlans) Limit(_ context.Context, t *tenant.Tenant) (Limit, error) {
+	if t == nil {
+		return p.Anonymous, nil
+	}
+	return p.tierLimit(t.SubscriptionTier), nil
+}
+
+// tierLimit returns the limit of a tier, or the free tier limit for unknown
+// tiers
+func (p *StaticPlans) tierLimit(tier tenant.Tier) Limit {
+	limit, ok := p.Tiers[tier]
+	if !ok {
+		tier = tenant.TierFree
+		limit = p.Tiers[tier]
+	}
+	limit.Tier = tier
+	return limit
+}
+
+// Plan is the subscription plan of an organization
+type Plan struct {
+	Tier     tenant.Tier            // Plan slug
+	Features map[string]interface{} // Plan features, holding its rate limits
+}
+
+// PlanLookup returns the plan of the current subscription of an
+// organization, or nil when it has none
+type PlanLookup func(ctx context.Context, organizationID string) (*Plan, error)
+
+// maxCachedPlans bounds the plans cached by OrganizationPlans; the cache is
+// cleared when it is full
+const maxCachedPlans = 10000
+
+// OrganizationPlans resolves the limit of a tenant from the plan of its
+// organization's subscription, looked up by organization ID. The tier the
+// tenant carries is ignored, since callers may claim any tier in the
+// propagation headers. Plans without rate limit features get the static
+// limit of their tier; organizations without a subscription get the free
+// tier limit.
+type OrganizationPlans struct {
+	lookup PlanLookup
+	static *StaticPlans
+	ttl    time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]cachedPlan
+}
+
+type cachedPlan struct {
+	limit     Limit
+	expiresAt time.Time
+}
+
+// NewOrganizationPlans creates a resolver looking up plans with lookup and
+// caching their limits for ttl. static holds the anonymous and per-tier
+// limits (defaults to DefaultPlans).
+func NewOrganizationPlans(lookup PlanLookup, static *StaticPlans, ttl time.Duration) *OrganizationPlans {
+	if static == nil {
+		static = DefaultPlans()
+	}
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &OrganizationPlans{
+		lookup: lookup,
+		static: static,
+		ttl:    ttl,
+		cache:  make(map[string]cachedPlan),
+	}
+}
+
+// Limit implements PlanResolver
+func (p *OrganizationPlans) Limit(ctx context.Context, t *tenant.Tenant) (Limit, error) {
+	if t == nil || t.OrganizationID == "" {
+		return p.static.Anonymous, nil
+	}
+
+	p.mu.RLock()
+	cached, ok := p.cache[t.OrganizationID]
+	p.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.limit, nil
+	}
+
+	plan, err := p.lookup(ctx, t.OrganizationID)
+	if err != nil {
+		return Limit{}, err
+	}
+	limit := p.static.tierLimit(tenant.TierFree)
+	if plan != nil {
+		if fromFeatures, ok := LimitFromFeatures(plan.Features); ok {
+			limit = fromFeatures
+			limit.Tier = plan.Tier
+		} else {
+			limit = p.static.tierLimit(plan.Tier)
+		}
+	}
+
+	p.mu.Lock()
+	if len(p.cache) >= maxCachedPlans {
+		p.cache = make(map[string]cachedPlan)
+	}
+	p.cache[t.OrganizationID] = cachedPlan{limit: limit, expiresAt: time.Now().Add(p.ttl)}
+	p.mu.Unlock()
+	return limit, nil
+}
+
+// LimitFromFeatures reads a limit from plan features. ok is false when the
+// plan does not define one.
+func LimitFromFeatures(features map[string]interface{}) (limit Limit, ok bool) {
+	rps, rpsOK := number(features[FeatureRateLimitRPS])
+	burst, burstOK := number(features[FeatureRateLimitBurst])
+	if !rpsOK {
+		return Limit{}, false
+	}
+	if !burstOK {
+		burst = rps * 2
+	}
+	return Limit{Rate: rps, Burst: int(burst)}, true
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package ratelimit provides distributed token-bucket rate limiting for the
+// public HTTP and GraphQL APIs, with limits derived from subscription plans
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+)
+
+// Limit is a token bucket: Rate tokens are added per second up to Burst
+type Limit struct {
+	Rate  float64     // Sustained requests per second
+	Burst int         // Maximum bucket size
+	Tier  tenant.Tier // Plan tier the limit was resolved for (empty for anonymous requests)
+}
+
+// Unlimited reports whether the limit disables rate limiting
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+// Window returns the time needed to refill an empty bucket
+func (l Limit) Window() time.Duration {
+	if l.Unlimited() {
+		return 0
+	}
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
+
+// Result is the outcome of a rate limit check
+type Result struct {
+	Allowed    bool
+	Limit      Limit
+	Remaining  int
+	RetryAfter time.Duration // Time until the request would be allowed
+	ResetAfter time.Duration // Time until the bucket is full again
+}
+
+// Config configures the limiter
+type Config struct {
+	// KeyPrefix namespaces bucket keys in Redis
+	KeyPrefix string
+
+	// FailOpen allows requests when Redis is unavailable
+	FailOpen bool
+
+	// Timeout bounds a single Redis check
+	Timeout time.Duration
+}
+
+// DefaultConfig returns default limiter configuration
+func DefaultConfig() Config {
+	return Config{
+		KeyPrefix: "dictamesh:ratelimit:",
+		FailOpen:  true,
+		Timeout:   50 * time.Millisecond,
+	}
+}
+
+// Validate checks if the configuration is valid
+func (c Config) Validate() error {
+	if c.KeyPrefix == "" {
+		return fmt.Errorf("key prefix is required")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	return nil
+}
+
+// tokenBucketScript refills and takes tokens atomically. Bucket state is a
+// hash of the token count and the last refill time in milliseconds.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local retry = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+else
+	retry = math.ceil((cost - tokens) * 1000 / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+
+return {allowed, math.floor(tokens), retry, math.ceil((burst - tokens) * 1000 / rate)}
+`)
+
+// Limiter checks token buckets stored in Redis, so limits are shared by all
+// gateway instances
+type Limiter struct {
+	client redis.UniversalClient
+	config Config
+}
+
+// NewLimiter creates a new Redis backed limiter
+func NewLimiter(client redis.UniversalClient, config Config) (*Limiter, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rate limit config: %w", err)
+	}
+	return &Limiter{client: client, config: config}, nil
+}
+
+// Allow takes cost tokens from the bucket identified by key
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit, cost int) (*Result, error) {
+	if limit.Unlimited() {
+		return &Result{Allowed: true, Limit: limit, Remaining: math.MaxInt32}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, l.config.Timeout)
+	defer cancel()
+
+	now := time.Now().UnixMilli()
+	values, err := tokenBucketScript.Run(ctx, l.client,
+		[]string{l.config.KeyPrefix + key},
+		limit.Rate, limit.Burst, now, cost,
+	).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	return &Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		ResetAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
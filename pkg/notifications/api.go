@@ -0,0 +1,878 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package notifications
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrTemplateNotFound is returned for unknown templates and revisions
+	ErrTemplateNotFound = errors.New("template not found")
+
+	// ErrInvalidTemplate is returned for template content that fails to
+	// parse or references undeclared variables
+	ErrInvalidTemplate = errors.New("invalid template")
+)
+
+// Pagination of list endpoints
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// TemplateStore stores the templates managed through the API
+type TemplateStore interface {
+	// Save stores a template as a new revision, creating it without an ID
+	Save(ctx context.Context, tmpl *NotificationTemplate) error
+	Get(ctx context.Context, templateID string) (*NotificationTemplate, error)
+	GetByName(ctx context.Context, name string) (*NotificationTemplate, error)
+	List(ctx context.Context, limit, offset int) ([]NotificationTemplate, error)
+	Revisions(ctx context.Context, templateID string) ([]NotificationTemplate, error)
+	Disable(ctx context.Context, templateID string) error
+}
+
+// NotificationRequest is a notification submitted to the API. Its content
+// is rendered from the template named TemplateCode, or TemplateID, with Data
+// as variables; without a template, Subject and Body are sent as given.
+// Recipient types, channels and priorities are case-insensitive; "urgent" is
+// an alias of CRITICAL.
+type NotificationRequest struct {
+	RecipientID   string                 `json:"recipient_id"`
+	RecipientType string                 `json:"recipient_type"`
+	TemplateCode  string                 `json:"template_code,omitempty"`
+	TemplateID    string                 `json:"template_id,omitempty"`
+	Channels      []string               `json:"channels"`
+	Priority      string                 `json:"priority,omitempty"`
+	Category      string                 `json:"category,omitempty"`
+	Locale        string                 `json:"locale,omitempty"`
+	Subject       string                 `json:"subject,omitempty"`
+	Body          string                 `json:"body,omitempty"`
+	BodyHTML      string                 `json:"body_html,omitempty"`
+	Data          map[string]interface{} `json:"data,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	ScheduledAt   *time.Time             `json:"scheduled_at,omitempty"`
+	TraceID       string                 `json:"trace_id,omitempty"`
+}
+
+// APIHandler serves the HTTP API of the notification service:
+//
+//	POST   /api/v1/notifications
+//	POST   /api/v1/notifications/bulk
+//	GET    /api/v1/notifications/{id}
+//	GET    /api/v1/templates
+//	POST   /api/v1/templates
+//	GET    /api/v1/templates/{id}
+//	PUT    /api/v1/templates/{id}
+//	DELETE /api/v1/templates/{id}
+//	GET    /api/v1/templates/{id}/revisions
+//	GET    /api/v1/suppressions
+//	POST   /api/v1/suppressions
+//	DELETE /api/v1/suppressions/{id}
+//
+// Requests authenticate with one of API.APIKeys as a bearer token.
+type APIHandler struct {
+	service   *NotificationService
+	tracker   *DeliveryTracker
+	templates TemplateStore
+	config    *Config
+	logger    *zap.Logger
+	mux       *http.ServeMux
+}
+
+// NewAPIHandler creates the API handler. Without a template store, the
+// template routes are not served and notifications cannot name a template.
+func NewAPIHandler(
+	service *NotificationService,
+	tracker *DeliveryTracker,
+	templates TemplateStore,
+	config *Config,
+	logger *zap.Logger,
+) *APIHandler {
+	h := &APIHandler{
+		service:   service,
+		tracker:   tracker,
+		templates: templates,
+		config:    config,
+		logger:    logger,
+		mux:       http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("POST /api/v1/notifications", h.send)
+	h.mux.HandleFunc("POST /api/v1/notifications/bulk", h.sendBulk)
+	h.mux.HandleFunc("GET /api/v1/notifications/{id}", h.getNotification)
+	if templates != nil {
+		h.mux.HandleFunc("GET /api/v1/templates", h.listTemplates)
+		h.mux.HandleFunc("POST /api/v1/templates", h.createTemplate)
+		h.mux.HandleFunc("GET /api/v1/templates/{id}", h.getTemplate)
+		h.mux.HandleFunc("PUT /api/v1/templates/{id}", h.updateTemplate)
+		h.mux.HandleFunc("DELETE /api/v1/templates/{id}", h.disableTemplate)
+		h.mux.HandleFunc("GET /api/v1/templates/{id}/revisions", h.listRevisions)
+	}
+	h.mux.HandleFunc("GET /api/v1/suppressions", h.listSuppressions)
+	h.mux.HandleFunc("POST /api/v1/suppressions", h.addSuppression)
+	h.mux.HandleFunc("DELETE /api/v1/suppressions/{id}", h.removeSuppression)
+
+	return h
+}
+
+// NewAPIServer creates an HTTP server for handler on API.ListenAddress
+func NewAPIServer(config *APIConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              config.ListenAddress,
+		Handler:           handler,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      config.WriteTimeout,
+	}
+}
+
+// ServeHTTP implements http.Handler. Requests without a valid API key are
+// rejected. Notifications submitted continue the trace of the traceparent
+// header of the request.
+func (h *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authenticated(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="notifications"`)
+		writeAPIError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	h.mux.ServeHTTP(w, r.WithContext(ExtractTrace(r.Context(), r.Header)))
+}
+
+// authenticated reports whether a request carries one of the API keys
+func (h *APIHandler) authenticated(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	for _, key := range h.config.API.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *APIHandler) send(w http.ResponseWriter, r *http.Request) {
+	var req NotificationRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	notification, err := h.notification(r.Context(), &req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if err := h.service.Submit(r.Context(), notification); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"id":     notification.ID,
+		"status": notification.Status,
+	})
+}
+
+// bulkResult is the outcome of one notification of a bulk request
+type bulkResult struct {
+	Index int    `json:"index"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// sendBulk submits notifications one by one; invalid ones are reported and
+// do not stop the others
+func (h *APIHandler) sendBulk(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Notifications []NotificationRequest `json:"notifications"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if len(req.Notifications) == 0 || len(req.Notifications) > h.config.API.MaxBulkSize {
+		writeAPIError(w, http.StatusBadRequest,
+			fmt.Sprintf("between 1 and %d notifications are required", h.config.API.MaxBulkSize))
+		return
+	}
+
+	results := make([]bulkResult, len(req.Notifications))
+	accepted := 0
+	for i := range req.Notifications {
+		results[i].Index = i
+		notification, err := h.notification(r.Context(), &req.Notifications[i])
+		if err == nil {
+			err = h.service.Submit(r.Context(), notification)
+		}
+		if err != nil {
+			results[i].Error = h.publicError(err)
+			continue
+		}
+		results[i].ID = notification.ID
+		accepted++
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"total_requested": len(req.Notifications),
+		"total_accepted":  accepted,
+		"total_rejected":  len(req.Notifications) - accepted,
+		"results":         results,
+	})
+}
+
+// notificationResponse is the status of a notification
+type notificationResponse struct {
+	ID               string            `json:"id"`
+	Status           Status            `json:"status"`
+	RecipientType    RecipientType     `json:"recipient_type"`
+	RecipientID      string            `json:"recipient_id"`
+	Priority         Priority          `json:"priority"`
+	Channels         []Channel         `json:"channels"`
+	SelectedChannel  Channel           `json:"selected_channel,omitempty"`
+	Category         string            `json:"category,omitempty"`
+	TemplateID       string            `json:"template_id,omitempty"`
+	TemplateRevision int               `json:"template_revision,omitempty"`
+	Locale           string            `json:"locale,omitempty"`
+	ScheduledAt      time.Time         `json:"scheduled_at"`
+	SentAt           *time.Time        `json:"sent_at,omitempty"`
+	DeliveredAt      *time.Time        `json:"delivered_at,omitempty"`
+	ReadAt           *time.Time        `json:"read_at,omitempty"`
+	Error            string            `json:"error,omitempty"`
+	RetryCount       int               `json:"retry_count"`
+	NextRetry        *time.Time        `json:"next_retry_at,omitempty"`
+	Attempts         []attemptResponse `json:"attempts"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// attemptResponse is a delivery attempt of a notification
+type attemptResponse struct {
+	AttemptNumber     int        `json:"attempt_number"`
+	Channel           Channel    `json:"channel"`
+	Provider          string     `json:"provider"`
+	Status            Status     `json:"status"`
+	ProviderMessageID string     `json:"provider_message_id,omitempty"`
+	Error             string     `json:"error,omitempty"`
+	StartedAt         time.Time  `json:"started_at"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
+
+func (h *APIHandler) getNotification(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	notification, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	attempts, err := h.tracker.Attempts(r.Context(), notification.ID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	resp := notificationResponse{
+		ID:               notification.ID,
+		Status:           notification.Status,
+		RecipientType:    notification.RecipientType,
+		RecipientID:      notification.RecipientID,
+		Priority:         notification.Priority,
+		Channels:         notification.Channels,
+		SelectedChannel:  notification.SelectedChannel,
+		Category:         notification.Category,
+		TemplateID:       notification.TemplateID,
+		TemplateRevision: notification.TemplateRevision,
+		Locale:           notification.Locale,
+		ScheduledAt:      notification.ScheduledAt,
+		SentAt:           notification.SentAt,
+		DeliveredAt:      notification.DeliveredAt,
+		ReadAt:           notification.ReadAt,
+		Error:            notification.Error,
+		RetryCount:       notification.RetryCount,
+		NextRetry:        notification.NextRetry,
+		Attempts:         make([]attemptResponse, len(attempts)),
+		CreatedAt:        notification.CreatedAt,
+		UpdatedAt:        notification.UpdatedAt,
+	}
+	for i, attempt := range attempts {
+		resp.Attempts[i] = attemptResponse{
+			AttemptNumber:     attempt.AttemptNumber,
+			Channel:           attempt.Channel,
+			Provider:          attempt.Provider,
+			Status:            attempt.Status,
+			ProviderMessageID: attempt.ProviderMessageID,
+			Error:             attempt.Error,
+			StartedAt:         attempt.StartedAt,
+			CompletedAt:       attempt.CompletedAt,
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// templateContent is the content of a template for a channel or locale
+type templateContent struct {
+	Subject  string                 `json:"subject,omitempty"`
+	Body     string                 `json:"body"`
+	BodyHTML string                 `json:"body_html,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+// templateBody is a template as written and read through the API
+type templateBody struct {
+	ID            string                      `json:"id,omitempty"`
+	Name          string                      `json:"name"`
+	Description   string                      `json:"description,omitempty"`
+	Channels      map[Channel]templateContent `json:"channels"`
+	Translations  map[string]templateContent  `json:"translations,omitempty"`
+	Variables     []string                    `json:"variables,omitempty"`
+	SchemaVersion string                      `json:"schema_version,omitempty"`
+	Version       string                      `json:"version,omitempty"`
+	Revision      int                         `json:"revision,omitempty"`
+	Enabled       *bool                       `json:"enabled,omitempty"`
+	Tags          []string                    `json:"tags,omitempty"`
+	CreatedBy     string                      `json:"created_by,omitempty"`
+	CreatedAt     *time.Time                  `json:"created_at,omitempty"`
+	UpdatedAt     *time.Time                  `json:"updated_at,omitempty"`
+}
+
+func (h *APIHandler) listTemplates(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	templates, err := h.templates.List(r.Context(), limit, offset)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	data := make([]templateBody, len(templates))
+	for i := range templates {
+		data[i] = toTemplateBody(&templates[i])
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+}
+
+func (h *APIHandler) createTemplate(w http.ResponseWriter, r *http.Request) {
+	h.saveTemplate(w, r, "", http.StatusCreated)
+}
+
+func (h *APIHandler) updateTemplate(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if _, err := h.templates.Get(r.Context(), id); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.saveTemplate(w, r, id, http.StatusOK)
+}
+
+// saveTemplate validates a template body and saves it as a new revision of
+// the template id, or as a new template
+func (h *APIHandler) saveTemplate(w http.ResponseWriter, r *http.Request, id string, status int) {
+	var body templateBody
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Name) == "" {
+		writeAPIError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if len(body.Channels) == 0 {
+		writeAPIError(w, http.StatusBadRequest, "content for at least one channel is required")
+		return
+	}
+	for channel := range body.Channels {
+		if _, err := parseChannel(string(channel)); err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	existing, err := h.templates.GetByName(r.Context(), body.Name)
+	if err != nil && !errors.Is(err, ErrTemplateNotFound) {
+		h.writeError(w, err)
+		return
+	}
+	if existing != nil && existing.ID != id {
+		writeAPIError(w, http.StatusConflict, "a template named "+body.Name+" already exists")
+		return
+	}
+
+	tmpl := fromTemplateBody(&body)
+	tmpl.ID = id
+	if err := h.templates.Save(r.Context(), tmpl); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	writeJSON(w, status, toTemplateBody(tmpl))
+}
+
+func (h *APIHandler) getTemplate(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	tmpl, err := h.templates.Get(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toTemplateBody(tmpl))
+}
+
+func (h *APIHandler) disableTemplate(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.templates.Disable(r.Context(), id); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) listRevisions(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	revisions, err := h.templates.Revisions(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	data := make([]templateBody, len(revisions))
+	for i := range revisions {
+		data[i] = toTemplateBody(&revisions[i])
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+}
+
+// suppressionBody is a suppression as written and read through the API. An
+// empty channel suppresses the address on every channel.
+type suppressionBody struct {
+	ID             string            `json:"id,omitempty"`
+	Channel        string            `json:"channel"`
+	Address        string            `json:"address"`
+	Reason         SuppressionReason `json:"reason,omitempty"`
+	Source         string            `json:"source,omitempty"`
+	NotificationID string            `json:"notification_id,omitempty"`
+	ExpiresAt      *time.Time        `json:"expires_at,omitempty"`
+	CreatedAt      *time.Time        `json:"created_at,omitempty"`
+	UpdatedAt      *time.Time        `json:"updated_at,omitempty"`
+}
+
+// listSuppressions lists suppressions, filtered by the channel and address
+// query parameters; the channel "*" selects global suppressions
+func (h *APIHandler) listSuppressions(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+	channel := r.URL.Query().Get("channel")
+	if channel != "" && channel != "*" {
+		parsed, err := parseChannel(channel)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		channel = string(parsed)
+	}
+
+	suppressions, err := h.tracker.Suppressions().List(r.Context(), channel, r.URL.Query().Get("address"), limit, offset)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	data := make([]suppressionBody, len(suppressions))
+	for i := range suppressions {
+		data[i] = toSuppressionBody(&suppressions[i])
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+}
+
+// addSuppression suppresses an address, MANUAL unless a reason is given
+func (h *APIHandler) addSuppression(w http.ResponseWriter, r *http.Request) {
+	var body suppressionBody
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	suppression := &Suppression{
+		Address:   body.Address,
+		Reason:    SuppressionReason(strings.ToUpper(string(body.Reason))),
+		Source:    "api",
+		ExpiresAt: body.ExpiresAt,
+	}
+	if body.Channel != "" {
+		channel, err := parseChannel(body.Channel)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		suppression.Channel = channel
+	}
+	switch suppression.Reason {
+	case "":
+		suppression.Reason = SuppressionManual
+	case SuppressionHardBounce, SuppressionComplaint, SuppressionUnsubscribe, SuppressionManual:
+	default:
+		h.writeError(w, invalid("unsupported suppression reason: %s", body.Reason))
+		return
+	}
+	if strings.TrimSpace(body.Address) == "" {
+		h.writeError(w, invalid("address is required"))
+		return
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		h.writeError(w, invalid("expires_at must be in the future"))
+		return
+	}
+
+	if err := h.tracker.Suppressions().Add(r.Context(), suppression, AuditActorAPI); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toSuppressionBody(suppression))
+}
+
+func (h *APIHandler) removeSuppression(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.tracker.Suppressions().Remove(r.Context(), id, AuditActorAPI, ""); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// toSuppressionBody converts a suppression to its API representation
+func toSuppressionBody(suppression *Suppression) suppressionBody {
+	return suppressionBody{
+		ID:             suppression.ID,
+		Channel:        string(suppression.Channel),
+		Address:        suppression.Address,
+		Reason:         suppression.Reason,
+		Source:         suppression.Source,
+		NotificationID: suppression.NotificationID,
+		ExpiresAt:      suppression.ExpiresAt,
+		CreatedAt:      &suppression.CreatedAt,
+		UpdatedAt:      &suppression.UpdatedAt,
+	}
+}
+
+// requestError is an invalid request, reported to the client as given
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// invalid returns a 400 requestError
+func invalid(format string, args ...interface{}) error {
+	return &requestError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+// notification validates a request and converts it to a notification,
+// resolving its template
+func (h *APIHandler) notification(ctx context.Context, req *NotificationRequest) (*Notification, error) {
+	if req.RecipientID == "" {
+		return nil, invalid("recipient_id is required")
+	}
+	recipientType, err := parseRecipientType(req.RecipientType)
+	if err != nil {
+		return nil, err
+	}
+	priority, err := parsePriority(req.Priority)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Channels) == 0 {
+		return nil, invalid("at least one channel is required")
+	}
+	channels := make([]Channel, 0, len(req.Channels))
+	for _, name := range req.Channels {
+		channel, err := parseChannel(name)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+
+	notification := &Notification{
+		RecipientType: recipientType,
+		RecipientID:   req.RecipientID,
+		Subject:       req.Subject,
+		Body:          req.Body,
+		BodyHTML:      req.BodyHTML,
+		Data:          req.Data,
+		Priority:      priority,
+		Channels:      channels,
+		Category:      req.Category,
+		Locale:        req.Locale,
+		Metadata:      req.Metadata,
+		TraceID:       req.TraceID,
+	}
+	if req.ScheduledAt != nil {
+		notification.ScheduledAt = *req.ScheduledAt
+	}
+
+	if req.TemplateCode == "" && req.TemplateID == "" {
+		if req.Body == "" {
+			return nil, invalid("body is required without a template")
+		}
+		return notification, nil
+	}
+	if h.templates == nil {
+		return nil, invalid("templates are not supported")
+	}
+
+	var tmpl *NotificationTemplate
+	if req.TemplateID != "" {
+		if _, err := uuid.Parse(req.TemplateID); err != nil {
+			return nil, invalid("invalid template_id: %s", req.TemplateID)
+		}
+		tmpl, err = h.templates.Get(ctx, req.TemplateID)
+	} else {
+		tmpl, err = h.templates.GetByName(ctx, req.TemplateCode)
+	}
+	if errors.Is(err, ErrTemplateNotFound) {
+		return nil, &requestError{status: http.StatusUnprocessableEntity, message: err.Error()}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !tmpl.Enabled {
+		return nil, &requestError{status: http.StatusUnprocessableEntity, message: "template disabled: " + tmpl.Name}
+	}
+	var missing []string
+	for _, name := range tmpl.Variables {
+		if _, ok := req.Data[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, invalid("missing template variables: %s", strings.Join(missing, ", "))
+	}
+
+	notification.TemplateID = tmpl.ID
+	return notification, nil
+}
+
+// parseRecipientType parses a recipient type case-insensitively
+func parseRecipientType(value string) (RecipientType, error) {
+	recipientType := RecipientType(strings.ToUpper(value))
+	switch recipientType {
+	case RecipientTypeUser, RecipientTypeRole, RecipientTypeGroup, RecipientTypeSystem, RecipientTypeOrganization:
+		return recipientType, nil
+	case "":
+		return "", invalid("recipient_type is required")
+	default:
+		return "", invalid("unsupported recipient_type: %s", value)
+	}
+}
+
+// parsePriority parses a priority case-insensitively, NORMAL when empty
+func parsePriority(value string) (Priority, error) {
+	switch priority := Priority(strings.ToUpper(value)); priority {
+	case "":
+		return PriorityNormal, nil
+	case "URGENT":
+		return PriorityCritical, nil
+	case PriorityCritical, PriorityHigh, PriorityNormal, PriorityLow:
+		return priority, nil
+	default:
+		return "", invalid("unsupported priority: %s", value)
+	}
+}
+
+// parseChannel parses a channel case-insensitively
+func parseChannel(value string) (Channel, error) {
+	switch channel := Channel(strings.ToUpper(value)); channel {
+	case ChannelEmail, ChannelSMS, ChannelPush, ChannelSlack, ChannelWebhook,
+		ChannelInApp, ChannelBrowserPush, ChannelPagerDuty:
+		return channel, nil
+	case "INAPP":
+		return ChannelInApp, nil
+	default:
+		return "", invalid("unsupported channel: %s", value)
+	}
+}
+
+// toTemplateBody converts a template to its API representation
+func toTemplateBody(tmpl *NotificationTemplate) templateBody {
+	enabled := tmpl.Enabled
+	body := templateBody{
+		ID:            tmpl.ID,
+		Name:          tmpl.Name,
+		Description:   tmpl.Description,
+		Channels:      make(map[Channel]templateContent, len(tmpl.Channels)),
+		Variables:     tmpl.Variables,
+		SchemaVersion: tmpl.SchemaVersion,
+		Version:       tmpl.Version,
+		Revision:      tmpl.Revision,
+		Enabled:       &enabled,
+		Tags:          tmpl.Tags,
+		CreatedBy:     tmpl.CreatedBy,
+	}
+	if !tmpl.CreatedAt.IsZero() {
+		body.CreatedAt = &tmpl.CreatedAt
+		body.UpdatedAt = &tmpl.UpdatedAt
+	}
+	for channel, content := range tmpl.Channels {
+		body.Channels[channel] = templateContent(content)
+	}
+	if len(tmpl.Translations) > 0 {
+		body.Translations = make(map[string]templateContent, len(tmpl.Translations))
+		for key, content := range tmpl.Translations {
+			body.Translations[key] = templateContent{
+				Subject:  content.Subject,
+				Body:     content.Body,
+				BodyHTML: content.BodyHTML,
+			}
+		}
+	}
+	return body
+}
+
+// fromTemplateBody converts an API template to a template, enabled unless
+// disabled explicitly
+func fromTemplateBody(body *templateBody) *NotificationTemplate {
+	tmpl := &NotificationTemplate{
+		Name:          body.Name,
+		Description:   body.Description,
+		Channels:      make(map[Channel]ChannelTemplate, len(body.Channels)),
+		Variables:     body.Variables,
+		SchemaVersion: body.SchemaVersion,
+		Version:       body.Version,
+		Enabled:       body.Enabled == nil || *body.Enabled,
+		Tags:          body.Tags,
+		CreatedBy:     body.CreatedBy,
+	}
+	for channel, content := range body.Channels {
+		normalized, _ := parseChannel(string(channel))
+		tmpl.Channels[normalized] = ChannelTemplate(content)
+	}
+	if len(body.Translations) > 0 {
+		tmpl.Translations = make(map[string]LocalizedTemplate, len(body.Translations))
+		for key, content := range body.Translations {
+			tmpl.Translations[key] = LocalizedTemplate{
+				Subject:  content.Subject,
+				Body:     content.Body,
+				BodyHTML: content.BodyHTML,
+			}
+		}
+	}
+	return tmpl
+}
+
+// writeError maps an error to a response. Internal errors are logged and
+// not exposed.
+func (h *APIHandler) writeError(w http.ResponseWriter, err error) {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		writeAPIError(w, reqErr.status, reqErr.message)
+	case errors.Is(err, ErrNotificationNotFound), errors.Is(err, ErrTemplateNotFound),
+		errors.Is(err, ErrSuppressionNotFound):
+		writeAPIError(w, http.StatusNotFound, "not found")
+	case errors.Is(err, ErrInvalidTemplate):
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.logger.Error("notification API request failed", zap.Error(err))
+		writeAPIError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+}
+
+// publicError returns the message of an error reported per notification of
+// a bulk request
+func (h *APIHandler) publicError(err error) string {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return err.Error()
+	}
+	h.logger.Error("bulk notification failed", zap.Error(err))
+	return http.StatusText(http.StatusInternalServerError)
+}
+
+// pathID returns the ID of the resource of a request, writing a 400
+// response when it is not a UUID
+func pathID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid ID: "+id)
+		return "", false
+	}
+	return id, true
+}
+
+// pagination reads the limit and offset query parameters, writing a 400
+// response when they are invalid
+func pagination(w http.ResponseWriter, r *http.Request) (limit, offset int, ok bool) {
+	limit, offset = defaultPageSize, 0
+
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxPageSize {
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageSize))
+			return 0, 0, false
+		}
+		limit = v
+	}
+
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			writeAPIError(w, http.StatusBadRequest, "offset must not be negative")
+			return 0, 0, false
+		}
+		offset = v
+	}
+
+	return limit, offset, true
+}
+
+// decodeJSON decodes a JSON request body of up to 10MB, leaving room for
+// bulk requests, writing a 400 response on failure
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 10<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
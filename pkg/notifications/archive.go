@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// Archiver stores archived rows in cold storage. Keys are stable for the
+// same rows, so writing an object again replaces it.
+type Archiver interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+}
+
+// S3Archiver writes archives to an S3 bucket, or to S3-compatible storage
+type S3Archiver struct {
+	config *ArchiveConfig
+	client *http.Client
+	signer *v4.Signer
+	base   string
+}
+
+// NewS3Archiver creates an archiver for the bucket of config
+func NewS3Archiver(config *ArchiveConfig) *S3Archiver {
+	base := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", config.S3Bucket, config.S3Region)
+	if config.S3Endpoint != "" {
+		base = strings.TrimSuffix(config.S3Endpoint, "/") + "/" + config.S3Bucket
+	}
+
+	return &S3Archiver{
+		config: config,
+		client: &http.Client{Timeout: 5 * time.Minute},
+		signer: v4.NewSigner(),
+		base:   base,
+	}
+}
+
+// Put implements Archiver
+func (a *S3Archiver) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if prefix := strings.Trim(a.config.S3Prefix, "/"); prefix != "" {
+		key = prefix + "/" + key
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut,
+		a.base+"/"+(&url.URL{Path: key}).EscapedPath(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	hash := sha256.Sum256(data)
+	payloadHash := hex.EncodeToString(hash[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err := a.signer.SignHTTP(ctx, a.credentials(), req, payloadHash,
+		"s3", a.config.S3Region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign S3 request: %w", err)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("S3 request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<12))
+		return fmt.Errorf("S3 returned %d for %s: %s", resp.StatusCode, key, body)
+	}
+	return nil
+}
+
+// credentials returns the configured credentials, or those of the environment
+func (a *S3Archiver) credentials() aws.Credentials {
+	if a.config.AccessKeyID != "" {
+		return aws.Credentials{
+			AccessKeyID:     a.config.AccessKeyID,
+			SecretAccessKey: a.config.SecretAccessKey,
+		}
+	}
+	return aws.Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Audit event types
+const (
+	AuditNotificationSuppressed = "notification.suppressed" // A channel was skipped for a suppressed address
+	AuditSuppressionAdded       = "suppression.added"
+	AuditSuppressionRemoved     = "suppression.removed"
+)
+
+// Audit actor types
+const (
+	AuditActorSystem    = "system"    // The dispatcher
+	AuditActorProvider  = "provider"  // Provider feedback
+	AuditActorRecipient = "recipient" // An unsubscribe link
+	AuditActorAPI       = "api"       // An API client
+)
+
+// recordAudit writes an entry to the audit trail
+func recordAudit(ctx context.Context, db *gorm.DB, event *AuditEvent) error {
+	model := models.AuditModel{
+		ID:        uuid.New(),
+		EventType: event.EventType,
+		Details:   models.JSONB(event.Details),
+		Timestamp: event.Timestamp,
+		TraceID:   event.TraceID,
+	}
+	if event.NotificationID != "" {
+		id, err := uuid.Parse(event.NotificationID)
+		if err != nil {
+			return fmt.Errorf("invalid notification ID: %w", err)
+		}
+		model.NotificationID = &id
+	}
+	if event.ActorType != "" {
+		model.ActorType = &event.ActorType
+	}
+	if event.ActorID != "" {
+		model.ActorID = &event.ActorID
+	}
+	if model.Timestamp.IsZero() {
+		model.Timestamp = time.Now()
+	}
+
+	if err := db.WithContext(ctx).Create(&model).Error; err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	event.ID = model.ID.String()
+	return nil
+}
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package email delivers notifications by email through SMTP, AWS SES or
+// SendGrid.
+package email
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+// maxWebhookBody bounds the size of feedback webhook requests
+const maxWebhookBody = 1 << 20
+
+// NewProvider creates the email provider selected by config.Provider
+func NewProvider(config *notifications.EmailConfig) (notifications.DeliveryProvider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch config.Provider {
+	case "smtp":
+		return NewSMTPProvider(config), nil
+	case "ses":
+		return NewSESProvider(config), nil
+	case "sendgrid":
+		return NewSendGridProvider(config), nil
+	default:
+		return nil, fmt.Errorf("unsupported email provider: %s", config.Provider)
+	}
+}
+
+// newHTTPClient creates the client of API providers, keeping connections to
+// the provider open between messages
+func newHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 32
+	transport.MaxIdleConnsPerHost = 16
+	transport.IdleConnTimeout = 90 * time.Second
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
+
+// rejected wraps a provider error that retrying cannot fix
+func rejected(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", notifications.ErrRejected, fmt.Sprintf(format, args...))
+}
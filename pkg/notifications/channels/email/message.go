@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+	"github.com/google/uuid"
+)
+
+// notificationHeader carries the notification ID of a message
+const notificationHeader = "X-DictaMesh-Notification-ID"
+
+// traceHeader carries the W3C trace context of the delivery of a message, so
+// that feedback and replies can be correlated with its trace
+const traceHeader = "Traceparent"
+
+// oneClickUnsubscribe marks the unsubscribe link of a message as accepting
+// RFC 8058 one-click POST requests
+const oneClickUnsubscribe = "List-Unsubscribe=One-Click"
+
+// message is an email rendered from a notification
+type message struct {
+	from           *mail.Address
+	to             *mail.Address
+	replyTo        *mail.Address
+	subject        string
+	text           string
+	html           string
+	messageID      string
+	notificationID string
+	unsubscribeURL string
+	traceparent    string
+}
+
+// newMessage renders a notification as an email from the configured sender,
+// carrying the trace context of ctx
+func newMessage(
+	ctx context.Context,
+	config *notifications.EmailConfig,
+	notification *notifications.Notification,
+) (*message, error) {
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address: %w", err)
+	}
+	if notification.RecipientAddress == "" {
+		return nil, rejected("notification %s has no recipient address", notification.ID)
+	}
+	to, err := mail.ParseAddress(notification.RecipientAddress)
+	if err != nil {
+		return nil, rejected("invalid recipient address %q", notification.RecipientAddress)
+	}
+
+	msg := &message{
+		from:           from,
+		to:             to,
+		subject:        notification.Subject,
+		text:           notification.Body,
+		html:           notification.BodyHTML,
+		messageID:      fmt.Sprintf("<%s@%s>", uuid.NewString(), domain(from.Address)),
+		notificationID: notification.ID,
+		unsubscribeURL: notification.UnsubscribeURL,
+		traceparent:    notifications.TraceParent(ctx),
+	}
+	if config.ReplyTo != "" {
+		if msg.replyTo, err = mail.ParseAddress(config.ReplyTo); err != nil {
+			return nil, fmt.Errorf("invalid reply-to address: %w", err)
+		}
+	}
+	return msg, nil
+}
+
+// domain returns the domain of an address
+func domain(address string) string {
+	if i := strings.LastIndexByte(address, '@'); i >= 0 {
+		return address[i+1:]
+	}
+	return "localhost"
+}
+
+// bytes renders the message in RFC 5322 format. The output keeps DKIM
+// signatures valid across relays: headers appear once and in a fixed order,
+// non-ASCII header text is encoded, lines end in CRLF and bodies are
+// quoted-printable so no line exceeds 76 characters and is rewrapped.
+func (m *message) bytes(date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+
+	header := func(name, value string) {
+		buf.WriteString(name)
+		buf.WriteString(": ")
+		buf.WriteString(value)
+		buf.WriteString("\r\n")
+	}
+	header("From", m.from.String())
+	header("To", m.to.String())
+	if m.replyTo != nil {
+		header("Reply-To", m.replyTo.String())
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", m.subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", m.messageID)
+	header("MIME-Version", "1.0")
+	if m.notificationID != "" {
+		header(notificationHeader, m.notificationID)
+	}
+	if m.traceparent != "" {
+		header(traceHeader, m.traceparent)
+	}
+	if m.unsubscribeURL != "" {
+		header("List-Unsubscribe", "<"+m.unsubscribeURL+">")
+		header("List-Unsubscribe-Post", oneClickUnsubscribe)
+	}
+
+	if m.html == "" {
+		header("Content-Type", "text/plain; charset=UTF-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, m.text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	header("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{
+		"boundary": parts.Boundary(),
+	}))
+	buf.WriteString("\r\n")
+
+	alternatives := []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", m.text},
+		{"text/html; charset=UTF-8", m.html},
+	}
+	for _, alternative := range alternatives {
+		if alternative.content == "" {
+			continue
+		}
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {alternative.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(part, alternative.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes text quoted-printable encoded with CRLF line
+// endings
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
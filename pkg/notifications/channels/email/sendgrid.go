@@ -0,0 +1,293 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+const (
+	sendGridEndpoint = "https://api.sendgrid.com"
+
+	sendGridSignatureHeader = "X-Twilio-Email-Event-Webhook-Signature"
+	sendGridTimestampHeader = "X-Twilio-Email-Event-Webhook-Timestamp"
+)
+
+// SendGridProvider delivers email through the SendGrid v3 API. SendGrid
+// builds the MIME message and signs it with the DKIM keys of the
+// authenticated sending domain.
+type SendGridProvider struct {
+	config   *notifications.EmailConfig
+	client   *http.Client
+	endpoint string
+}
+
+// NewSendGridProvider creates a new SendGrid provider
+func NewSendGridProvider(config *notifications.EmailConfig) *SendGridProvider {
+	return &SendGridProvider{
+		config:   config,
+		client:   newHTTPClient(config.Timeout),
+		endpoint: sendGridEndpoint,
+	}
+}
+
+// Name returns the provider name
+func (p *SendGridProvider) Name() string {
+	return "sendgrid"
+}
+
+// GetChannel returns the channel of the provider
+func (p *SendGridProvider) GetChannel() notifications.Channel {
+	return notifications.ChannelEmail
+}
+
+// sendGridAddress is an address of the SendGrid API
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+// sendGridMail is the body of the SendGrid mail send call
+type sendGridMail struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	ReplyTo          *sendGridAddress          `json:"reply_to,omitempty"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+	CustomArgs       map[string]string         `json:"custom_args,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Send delivers a notification by email
+func (p *SendGridProvider) Send(
+	ctx context.Context,
+	notification *notifications.Notification,
+) (*notifications.DeliveryResult, error) {
+	msg, err := newMessage(ctx, p.config, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	mail := sendGridMail{
+		Personalizations: []sendGridPersonalization{{
+			To: []sendGridAddress{{Email: msg.to.Address, Name: msg.to.Name}},
+		}},
+		From:    sendGridAddress{Email: msg.from.Address, Name: msg.from.Name},
+		Subject: msg.subject,
+		Headers: map[string]string{notificationHeader: msg.notificationID},
+		CustomArgs: map[string]string{
+			"notification_id": msg.notificationID,
+		},
+	}
+	if msg.traceparent != "" {
+		mail.Headers[traceHeader] = msg.traceparent
+	}
+	if msg.unsubscribeURL != "" {
+		mail.Headers["List-Unsubscribe"] = "<" + msg.unsubscribeURL + ">"
+		mail.Headers["List-Unsubscribe-Post"] = oneClickUnsubscribe
+	}
+	if msg.replyTo != nil {
+		mail.ReplyTo = &sendGridAddress{Email: msg.replyTo.Address, Name: msg.replyTo.Name}
+	}
+	// SendGrid requires text/plain to come first
+	if msg.text != "" {
+		mail.Content = append(mail.Content, sendGridContent{Type: "text/plain", Value: msg.text})
+	}
+	if msg.html != "" {
+		mail.Content = append(mail.Content, sendGridContent{Type: "text/html", Value: msg.html})
+	}
+	if len(mail.Content) == 0 {
+		return nil, rejected("notification %s has no content", notification.ID)
+	}
+
+	body, err := json.Marshal(mail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	resp, err := p.do(ctx, http.MethodPost, "/v3/mail/send", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, sendGridFailure(resp)
+	}
+
+	messageID := resp.Header.Get("X-Message-Id")
+	return &notifications.DeliveryResult{
+		Status:            notifications.StatusSent,
+		ProviderMessageID: messageID,
+		ProviderResponse: map[string]interface{}{
+			"message_id": messageID,
+		},
+	}, nil
+}
+
+// HealthCheck verifies the API key is accepted
+func (p *SendGridProvider) HealthCheck(ctx context.Context) error {
+	resp, err := p.do(ctx, http.MethodGet, "/v3/scopes", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return sendGridFailure(resp)
+	}
+	return nil
+}
+
+// do sends an authenticated request to the SendGrid API
+func (p *SendGridProvider) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.SendGrid.APIKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("SendGrid request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// sendGridFailure converts an unsuccessful SendGrid response to an error.
+// Rate limits and server errors can be retried; other client errors cannot.
+func sendGridFailure(resp *http.Response) error {
+	var body struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, maxWebhookBody)).Decode(&body)
+
+	messages := make([]string, 0, len(body.Errors))
+	for _, e := range body.Errors {
+		messages = append(messages, e.Message)
+	}
+	detail := strings.Join(messages, "; ")
+
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return rejected("SendGrid %d: %s", resp.StatusCode, detail)
+	}
+	return fmt.Errorf("SendGrid returned %d: %s", resp.StatusCode, detail)
+}
+
+// sendGridEvent is an event of the SendGrid event webhook
+type sendGridEvent struct {
+	Email       string `json:"email"`
+	Timestamp   int64  `json:"timestamp"`
+	Event       string `json:"event"`
+	Type        string `json:"type"` // "bounce" or "blocked" for bounce events
+	Reason      string `json:"reason"`
+	SGMessageID string `json:"sg_message_id"`
+}
+
+// ParseFeedback parses the events SendGrid posts to the feedback webhook.
+// With SendGrid.WebhookVerificationKey set, unsigned requests are refused.
+func (p *SendGridProvider) ParseFeedback(r *http.Request) ([]notifications.DeliveryEvent, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook: %w", err)
+	}
+	if err := p.verifySignature(r, body); err != nil {
+		return nil, err
+	}
+
+	var received []sendGridEvent
+	if err := json.Unmarshal(body, &received); err != nil {
+		return nil, fmt.Errorf("invalid SendGrid events: %w", err)
+	}
+
+	var events []notifications.DeliveryEvent
+	for _, e := range received {
+		event := notifications.DeliveryEvent{
+			// sg_message_id is the X-Message-Id followed by a filter suffix
+			ProviderMessageID: strings.SplitN(e.SGMessageID, ".", 2)[0],
+			Recipient:         e.Email,
+			Reason:            e.Reason,
+			OccurredAt:        time.Unix(e.Timestamp, 0),
+		}
+
+		switch e.Event {
+		case "delivered":
+			event.Type = notifications.DeliveryEventDelivered
+		case "bounce":
+			event.Type = notifications.DeliveryEventBounced
+			event.Permanent = e.Type != "blocked"
+		case "dropped":
+			// Dropped messages go to suppressed or invalid addresses
+			event.Type = notifications.DeliveryEventBounced
+			event.Permanent = true
+		case "spamreport":
+			event.Type = notifications.DeliveryEventComplained
+			event.Reason = "spamreport"
+		default:
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// verifySignature checks the ECDSA signature of a signed event webhook
+func (p *SendGridProvider) verifySignature(r *http.Request, body []byte) error {
+	key := p.config.SendGrid.WebhookVerificationKey
+	if key == "" {
+		return nil
+	}
+
+	der, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return fmt.Errorf("invalid SendGrid verification key: %w", err)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return fmt.Errorf("invalid SendGrid verification key: %w", err)
+	}
+	publicKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("SendGrid verification key is not an ECDSA key")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(r.Header.Get(sendGridSignatureHeader))
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("missing or invalid SendGrid signature")
+	}
+
+	hash := sha256.Sum256(append([]byte(r.Header.Get(sendGridTimestampHeader)), body...))
+	if !ecdsa.VerifyASN1(publicKey, hash[:], signature) {
+		return fmt.Errorf("invalid SendGrid signature")
+	}
+	return nil
+}
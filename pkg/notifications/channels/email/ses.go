@@ -0,0 +1,304 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+// SESProvider delivers email through the AWS SES v2 API. Messages are sent
+// raw so the headers are the same as with SMTP; SES signs them with the
+// DKIM keys of the verified sending domain.
+type SESProvider struct {
+	config   *notifications.EmailConfig
+	client   *http.Client
+	signer   *v4.Signer
+	endpoint string
+	sns      *snsVerifier
+}
+
+// NewSESProvider creates a new SES provider. Without an access key in the
+// configuration, the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN environment variables are used.
+func NewSESProvider(config *notifications.EmailConfig) *SESProvider {
+	client := newHTTPClient(config.Timeout)
+
+	return &SESProvider{
+		config:   config,
+		client:   client,
+		signer:   v4.NewSigner(),
+		endpoint: fmt.Sprintf("https://email.%s.amazonaws.com", config.SES.Region),
+		sns:      newSNSVerifier(client),
+	}
+}
+
+// Name returns the provider name
+func (p *SESProvider) Name() string {
+	return "ses"
+}
+
+// GetChannel returns the channel of the provider
+func (p *SESProvider) GetChannel() notifications.Channel {
+	return notifications.ChannelEmail
+}
+
+// sesSendRequest is the body of the SES v2 SendEmail call
+type sesSendRequest struct {
+	FromEmailAddress     string         `json:"FromEmailAddress"`
+	Destination          sesDestination `json:"Destination"`
+	ReplyToAddresses     []string       `json:"ReplyToAddresses,omitempty"`
+	Content              sesContent     `json:"Content"`
+	ConfigurationSetName string         `json:"ConfigurationSetName,omitempty"`
+}
+
+type sesDestination struct {
+	ToAddresses []string `json:"ToAddresses"`
+}
+
+type sesContent struct {
+	Raw sesRawMessage `json:"Raw"`
+}
+
+type sesRawMessage struct {
+	Data []byte `json:"Data"` // Base64 encoded by encoding/json
+}
+
+// sesError is the error body of the SES API
+type sesError struct {
+	Message string `json:"message"`
+}
+
+// Send delivers a notification by email
+func (p *SESProvider) Send(
+	ctx context.Context,
+	notification *notifications.Notification,
+) (*notifications.DeliveryResult, error) {
+	msg, err := newMessage(ctx, p.config, notification)
+	if err != nil {
+		return nil, err
+	}
+	data, err := msg.bytes(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to render message: %w", err)
+	}
+
+	request := sesSendRequest{
+		FromEmailAddress:     msg.from.Address,
+		Destination:          sesDestination{ToAddresses: []string{msg.to.Address}},
+		Content:              sesContent{Raw: sesRawMessage{Data: data}},
+		ConfigurationSetName: p.config.SES.ConfigurationSet,
+	}
+	if msg.replyTo != nil {
+		request.ReplyToAddresses = []string{msg.replyTo.Address}
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	resp, err := p.do(ctx, http.MethodPost, "/v2/email/outbound-emails", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, sesFailure(resp)
+	}
+
+	var result struct {
+		MessageID string `json:"MessageId"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode SES response: %w", err)
+	}
+
+	return &notifications.DeliveryResult{
+		Status:            notifications.StatusSent,
+		ProviderMessageID: result.MessageID,
+		ProviderResponse: map[string]interface{}{
+			"message_id": result.MessageID,
+		},
+	}, nil
+}
+
+// HealthCheck verifies the credentials can reach the SES account
+func (p *SESProvider) HealthCheck(ctx context.Context) error {
+	resp, err := p.do(ctx, http.MethodGet, "/v2/email/account", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return sesFailure(resp)
+	}
+	return nil
+}
+
+// do sends a signed request to the SES API
+func (p *SESProvider) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	hash := sha256.Sum256(body)
+	if err := p.signer.SignHTTP(ctx, p.credentials(), req, hex.EncodeToString(hash[:]),
+		"ses", p.config.SES.Region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign SES request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("SES request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// credentials returns the configured credentials, or those of the environment
+func (p *SESProvider) credentials() aws.Credentials {
+	if p.config.SES.AccessKeyID != "" {
+		return aws.Credentials{
+			AccessKeyID:     p.config.SES.AccessKeyID,
+			SecretAccessKey: p.config.SES.SecretAccessKey,
+		}
+	}
+	return aws.Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// sesFailure converts an unsuccessful SES response to an error. Throttling
+// and server errors can be retried; other client errors, such as rejected
+// messages or unverified senders, cannot.
+func sesFailure(resp *http.Response) error {
+	var body sesError
+	_ = json.NewDecoder(io.LimitReader(resp.Body, maxWebhookBody)).Decode(&body)
+	errorType := resp.Header.Get("X-Amzn-ErrorType")
+
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return rejected("SES %d %s: %s", resp.StatusCode, errorType, body.Message)
+	}
+	return fmt.Errorf("SES returned %d %s: %s", resp.StatusCode, errorType, body.Message)
+}
+
+// sesFeedback is an SES bounce, complaint or delivery notification
+type sesFeedback struct {
+	NotificationType string `json:"notificationType"`
+	EventType        string `json:"eventType"` // Set instead when published by a configuration set
+	Mail             struct {
+		MessageID string `json:"messageId"`
+	} `json:"mail"`
+	Bounce *struct {
+		BounceType        string    `json:"bounceType"`
+		BounceSubType     string    `json:"bounceSubType"`
+		Timestamp         time.Time `json:"timestamp"`
+		BouncedRecipients []struct {
+			EmailAddress   string `json:"emailAddress"`
+			DiagnosticCode string `json:"diagnosticCode"`
+		} `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint *struct {
+		ComplaintFeedbackType string    `json:"complaintFeedbackType"`
+		Timestamp             time.Time `json:"timestamp"`
+		ComplainedRecipients  []struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"complainedRecipients"`
+	} `json:"complaint"`
+	Delivery *struct {
+		Timestamp  time.Time `json:"timestamp"`
+		Recipients []string  `json:"recipients"`
+	} `json:"delivery"`
+}
+
+// ParseFeedback parses the SES notifications SNS posts to the feedback
+// webhook, after verifying the SNS signature. Subscription confirmations are
+// confirmed and return no events.
+func (p *SESProvider) ParseFeedback(r *http.Request) ([]notifications.DeliveryEvent, error) {
+	notification, err := p.sns.verify(r.Context(), io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		return nil, err
+	}
+	if topic := p.config.SES.FeedbackTopicARN; topic != "" && notification.TopicArn != topic {
+		return nil, fmt.Errorf("unexpected SNS topic %s", notification.TopicArn)
+	}
+
+	switch notification.Type {
+	case "SubscriptionConfirmation":
+		return nil, p.sns.confirm(r.Context(), notification)
+	case "Notification":
+	default:
+		return nil, nil
+	}
+
+	var feedback sesFeedback
+	if err := json.Unmarshal([]byte(notification.Message), &feedback); err != nil {
+		return nil, fmt.Errorf("invalid SES notification: %w", err)
+	}
+
+	kind := feedback.NotificationType
+	if kind == "" {
+		kind = feedback.EventType
+	}
+	messageID := feedback.Mail.MessageID
+
+	var events []notifications.DeliveryEvent
+	switch {
+	case kind == "Bounce" && feedback.Bounce != nil:
+		for _, recipient := range feedback.Bounce.BouncedRecipients {
+			reason := recipient.DiagnosticCode
+			if reason == "" {
+				reason = feedback.Bounce.BounceType + "/" + feedback.Bounce.BounceSubType
+			}
+			events = append(events, notifications.DeliveryEvent{
+				Type:              notifications.DeliveryEventBounced,
+				ProviderMessageID: messageID,
+				Recipient:         recipient.EmailAddress,
+				Permanent:         feedback.Bounce.BounceType == "Permanent",
+				Reason:            reason,
+				OccurredAt:        feedback.Bounce.Timestamp,
+			})
+		}
+	case kind == "Complaint" && feedback.Complaint != nil:
+		for _, recipient := range feedback.Complaint.ComplainedRecipients {
+			events = append(events, notifications.DeliveryEvent{
+				Type:              notifications.DeliveryEventComplained,
+				ProviderMessageID: messageID,
+				Recipient:         recipient.EmailAddress,
+				Reason:            feedback.Complaint.ComplaintFeedbackType,
+				OccurredAt:        feedback.Complaint.Timestamp,
+			})
+		}
+	case kind == "Delivery" && feedback.Delivery != nil:
+		for _, recipient := range feedback.Delivery.Recipients {
+			events = append(events, notifications.DeliveryEvent{
+				Type:              notifications.DeliveryEventDelivered,
+				ProviderMessageID: messageID,
+				Recipient:         recipient,
+				OccurredAt:        feedback.Delivery.Timestamp,
+			})
+		}
+	}
+
+	return events, nil
+}
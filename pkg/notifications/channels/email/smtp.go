@@ -0,0 +1,269 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+// SMTPProvider delivers email through an SMTP server, reusing a pool of
+// authenticated connections
+type SMTPProvider struct {
+	config *notifications.EmailConfig
+
+	// idle holds open connections; slots bounds the open connections
+	idle  chan *smtpConn
+	slots chan struct{}
+}
+
+// smtpConn is a pooled SMTP connection
+type smtpConn struct {
+	conn     net.Conn
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+// NewSMTPProvider creates a new SMTP provider. Connections are opened on
+// demand, up to SMTP.MaxConnections.
+func NewSMTPProvider(config *notifications.EmailConfig) *SMTPProvider {
+	size := config.SMTP.MaxConnections
+	if size < 1 {
+		size = 1
+	}
+
+	return &SMTPProvider{
+		config: config,
+		idle:   make(chan *smtpConn, size),
+		slots:  make(chan struct{}, size),
+	}
+}
+
+// Name returns the provider name
+func (p *SMTPProvider) Name() string {
+	return "smtp"
+}
+
+// GetChannel returns the channel of the provider
+func (p *SMTPProvider) GetChannel() notifications.Channel {
+	return notifications.ChannelEmail
+}
+
+// Send delivers a notification by email
+func (p *SMTPProvider) Send(
+	ctx context.Context,
+	notification *notifications.Notification,
+) (*notifications.DeliveryResult, error) {
+	msg, err := newMessage(ctx, p.config, notification)
+	if err != nil {
+		return nil, err
+	}
+	data, err := msg.bytes(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to render message: %w", err)
+	}
+
+	c, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.transmit(c, msg, data); err != nil {
+		p.discard(c)
+		return nil, classifySMTPError(err)
+	}
+	p.release(c)
+
+	// Servers do not return a message ID; bounces reference the Message-ID
+	return &notifications.DeliveryResult{
+		Status:            notifications.StatusSent,
+		ProviderMessageID: msg.messageID,
+	}, nil
+}
+
+// HealthCheck verifies a pooled connection responds
+func (p *SMTPProvider) HealthCheck(ctx context.Context) error {
+	c, err := p.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	if err := p.extendDeadline(c); err != nil {
+		p.discard(c)
+		return err
+	}
+	if err := c.client.Noop(); err != nil {
+		p.discard(c)
+		return fmt.Errorf("SMTP server not responding: %w", err)
+	}
+	p.release(c)
+	return nil
+}
+
+// Close closes the idle connections
+func (p *SMTPProvider) Close() error {
+	for {
+		select {
+		case c := <-p.idle:
+			_ = c.client.Quit()
+			<-p.slots
+		default:
+			return nil
+		}
+	}
+}
+
+// transmit sends a message over a connection
+func (p *SMTPProvider) transmit(c *smtpConn, msg *message, data []byte) error {
+	if err := p.extendDeadline(c); err != nil {
+		return err
+	}
+	if err := c.client.Mail(msg.from.Address); err != nil {
+		return err
+	}
+	if err := c.client.Rcpt(msg.to.Address); err != nil {
+		return err
+	}
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// acquire returns an idle connection, or opens one if the pool has room
+func (p *SMTPProvider) acquire(ctx context.Context) (*smtpConn, error) {
+	for {
+		select {
+		case c := <-p.idle:
+			if p.usable(c) {
+				return c, nil
+			}
+			p.discard(c)
+			continue
+		default:
+		}
+
+		select {
+		case c := <-p.idle:
+			if p.usable(c) {
+				return c, nil
+			}
+			p.discard(c)
+		case p.slots <- struct{}{}:
+			c, err := p.dial(ctx)
+			if err != nil {
+				<-p.slots
+				return nil, err
+			}
+			return c, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// usable reports whether an idle connection may be reused: it has not been
+// idle too long and the server still answers a reset
+func (p *SMTPProvider) usable(c *smtpConn) bool {
+	if p.config.SMTP.IdleTimeout > 0 && time.Since(c.lastUsed) >= p.config.SMTP.IdleTimeout {
+		return false
+	}
+	if err := p.extendDeadline(c); err != nil {
+		return false
+	}
+	return c.client.Reset() == nil
+}
+
+// extendDeadline allows a connection another SMTP.Timeout
+func (p *SMTPProvider) extendDeadline(c *smtpConn) error {
+	if p.config.SMTP.Timeout <= 0 {
+		return nil
+	}
+	return c.conn.SetDeadline(time.Now().Add(p.config.SMTP.Timeout))
+}
+
+// release returns a connection to the pool
+func (p *SMTPProvider) release(c *smtpConn) {
+	c.lastUsed = time.Now()
+	p.idle <- c
+}
+
+// discard closes a connection and frees its slot
+func (p *SMTPProvider) discard(c *smtpConn) {
+	_ = c.client.Close()
+	<-p.slots
+}
+
+// dial opens an authenticated connection. Port 465 uses implicit TLS; other
+// ports upgrade with STARTTLS when UseTLS is set.
+func (p *SMTPProvider) dial(ctx context.Context) (*smtpConn, error) {
+	config := p.config.SMTP
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	tlsConfig := &tls.Config{ServerName: config.Host, MinVersion: tls.VersionTLS12}
+
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	var conn net.Conn
+	var err error
+	if config.Port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	c := &smtpConn{conn: conn, lastUsed: time.Now()}
+	if err := p.extendDeadline(c); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+
+	if config.UseTLS && config.Port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if config.Username != "" {
+		auth := smtp.PlainAuth("", config.Username, config.Password, config.Host)
+		if err := client.Auth(auth); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	c.client = client
+	return c, nil
+}
+
+// classifySMTPError marks permanent (5xx) SMTP replies as rejections
+func classifySMTPError(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return rejected("SMTP %d %s", reply.Code, reply.Msg)
+	}
+	return fmt.Errorf("SMTP delivery failed: %w", err)
+}
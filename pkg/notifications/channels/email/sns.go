@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package email
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// snsHost matches the hosts SNS signing certificates and subscription
+// confirmations are served from
+var snsHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// snsMessage is a message SNS posts to an HTTP subscription
+type snsMessage struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+	SubscribeURL     string `json:"SubscribeURL"`
+}
+
+// snsVerifier verifies the signatures of SNS messages, caching the signing
+// certificates
+type snsVerifier struct {
+	client *http.Client
+	certs  sync.Map // SigningCertURL -> *x509.Certificate
+}
+
+// newSNSVerifier creates a new SNS signature verifier
+func newSNSVerifier(client *http.Client) *snsVerifier {
+	return &snsVerifier{client: client}
+}
+
+// verify decodes an SNS message and checks its signature
+func (v *snsVerifier) verify(ctx context.Context, body io.Reader) (*snsMessage, error) {
+	var msg snsMessage
+	if err := json.NewDecoder(body).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("invalid SNS message: %w", err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNS signature encoding: %w", err)
+	}
+
+	var algorithm x509.SignatureAlgorithm
+	switch msg.SignatureVersion {
+	case "1":
+		algorithm = x509.SHA1WithRSA
+	case "2":
+		algorithm = x509.SHA256WithRSA
+	default:
+		return nil, fmt.Errorf("unsupported SNS signature version %q", msg.SignatureVersion)
+	}
+
+	cert, err := v.certificate(ctx, msg.SigningCertURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := cert.CheckSignature(algorithm, []byte(msg.stringToSign()), signature); err != nil {
+		return nil, fmt.Errorf("invalid SNS signature: %w", err)
+	}
+
+	return &msg, nil
+}
+
+// confirm confirms the subscription of the feedback webhook to a topic
+func (v *snsVerifier) confirm(ctx context.Context, msg *snsMessage) error {
+	if err := checkSNSURL(msg.SubscribeURL); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, msg.SubscribeURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to confirm SNS subscription: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("SNS subscription confirmation returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// certificate returns the signing certificate at certURL
+func (v *snsVerifier) certificate(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	if cert, ok := v.certs.Load(certURL); ok {
+		return cert.(*x509.Certificate), nil
+	}
+	if err := checkSNSURL(certURL); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, certURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch SNS certificate: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SNS certificate request returned %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SNS certificate: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid SNS certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNS certificate: %w", err)
+	}
+
+	v.certs.Store(certURL, cert)
+	return cert, nil
+}
+
+// checkSNSURL rejects URLs not served by SNS over HTTPS
+func checkSNSURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || !snsHost.MatchString(u.Hostname()) {
+		return fmt.Errorf("untrusted SNS URL %q", rawURL)
+	}
+	return nil
+}
+
+// stringToSign builds the canonical form of a message SNS signs
+func (m *snsMessage) stringToSign() string {
+	fields := [][2]string{{"Message", m.Message}, {"MessageId", m.MessageID}}
+	switch m.Type {
+	case "SubscriptionConfirmation", "UnsubscribeConfirmation":
+		fields = append(fields,
+			[2]string{"SubscribeURL", m.SubscribeURL},
+			[2]string{"Timestamp", m.Timestamp},
+			[2]string{"Token", m.Token})
+	default:
+		if m.Subject != "" {
+			fields = append(fields, [2]string{"Subject", m.Subject})
+		}
+		fields = append(fields, [2]string{"Timestamp", m.Timestamp})
+	}
+	fields = append(fields,
+		[2]string{"TopicArn", m.TopicArn},
+		[2]string{"Type", m.Type})
+
+	var b strings.Builder
+	for _, field := range fields {
+		b.WriteString(field[0])
+		b.WriteString("\n")
+		b.WriteString(field[1])
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,273 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package inapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Pagination of the inbox
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// UserFunc returns the authenticated user of a request
+type UserFunc func(r *http.Request) (string, error)
+
+// Handler serves the inbox of the authenticated user:
+//
+//	GET  {prefix}/notifications?limit=...&offset=...&unread=true
+//	GET  {prefix}/notifications/unread-count
+//	POST {prefix}/notifications/{id}/read
+//	POST {prefix}/notifications/read
+//	POST {prefix}/notifications/read-all
+//	GET  {prefix}/stream
+//
+// The stream is a server-sent event stream of inbox Events, opened with an
+// unread event carrying the unread count, for live notification badges.
+//
+// Mount it with http.StripPrefix, e.g.
+// mux.Handle("/api/v1/inbox/", http.StripPrefix("/api/v1/inbox", handler))
+type Handler struct {
+	inbox     *Inbox
+	user      UserFunc
+	keepAlive time.Duration
+	mux       *http.ServeMux
+}
+
+// NewHandler creates an inbox handler identifying users with user
+func NewHandler(inbox *Inbox, user UserFunc) *Handler {
+	keepAlive := inbox.config.WebSocketPingTime
+	if keepAlive <= 0 {
+		keepAlive = 30 * time.Second
+	}
+
+	h := &Handler{
+		inbox:     inbox,
+		user:      user,
+		keepAlive: keepAlive,
+		mux:       http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("GET /notifications", h.list)
+	h.mux.HandleFunc("GET /notifications/unread-count", h.unreadCount)
+	h.mux.HandleFunc("POST /notifications/{id}/read", h.markRead)
+	h.mux.HandleFunc("POST /notifications/read", h.markManyRead)
+	h.mux.HandleFunc("POST /notifications/read-all", h.markAllRead)
+	h.mux.HandleFunc("GET /stream", h.stream)
+
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// userID returns the user of a request, writing a 401 response without one
+func (h *Handler) userID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, err := h.user(r)
+	if err != nil || userID == "" {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return "", false
+	}
+	return userID, true
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	items, err := h.inbox.List(r.Context(), userID, limit, offset, unreadOnly)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	unread, err := h.inbox.UnreadCount(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": items, "unread_count": unread})
+}
+
+func (h *Handler) unreadCount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	unread, err := h.inbox.UnreadCount(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"unread_count": unread})
+}
+
+func (h *Handler) markRead(w http.ResponseWriter, r *http.Request) {
+	h.markIDsRead(w, r, []string{r.PathValue("id")})
+}
+
+func (h *Handler) markManyRead(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > maxPageSize {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("between 1 and %d ids are required", maxPageSize))
+		return
+	}
+	h.markIDsRead(w, r, req.IDs)
+}
+
+func (h *Handler) markIDsRead(w http.ResponseWriter, r *http.Request, ids []string) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	marked, err := h.inbox.MarkRead(r.Context(), userID, ids...)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"marked": marked})
+}
+
+func (h *Handler) markAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	marked, err := h.inbox.MarkAllRead(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"marked": marked})
+}
+
+// stream sends the changes of the inbox of the user as server-sent events
+// until the client disconnects
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	ctx := r.Context()
+	events, closeStream, err := h.inbox.Subscribe(ctx, userID)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "inbox stream unavailable")
+		return
+	}
+	defer closeStream()
+
+	unread, err := h.inbox.UnreadCount(ctx, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if !writeEvent(w, Event{Type: EventUnread, UnreadCount: unread}) {
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(h.keepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case event, ok := <-events:
+			if !ok || !writeEvent(w, event) {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes an inbox event as a server-sent event named by its type
+func writeEvent(w http.ResponseWriter, event Event) bool {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return true
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err == nil
+}
+
+// pagination reads the limit and offset query parameters, writing a 400
+// response when they are invalid
+func pagination(w http.ResponseWriter, r *http.Request) (limit, offset int, ok bool) {
+	limit, offset = defaultPageSize, 0
+
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxPageSize {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageSize))
+			return 0, 0, false
+		}
+		limit = v
+	}
+
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			writeError(w, http.StatusBadRequest, "offset must not be negative")
+			return 0, 0, false
+		}
+		offset = v
+	}
+
+	return limit, offset, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
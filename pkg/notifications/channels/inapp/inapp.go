@@ -0,0 +1,269 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package inapp delivers notifications to the inbox of users in the
+// application. The notifications table is the inbox: delivered IN_APP rows
+// are listed and marked read through the Inbox, and new ones are announced
+// to live streams over Redis pub/sub.
+package inapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+	"github.com/click2-run/dictamesh/pkg/notifications/models"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// streamPrefix prefixes the pub/sub channel of the inbox of each user
+const streamPrefix = "dictamesh:notifications:inbox:"
+
+// Event types announced on the inbox stream
+const (
+	EventCreated = "created"  // A notification was delivered
+	EventRead    = "read"     // Notifications were marked read
+	EventReadAll = "read_all" // All notifications were marked read
+	EventUnread  = "unread"   // The unread count, sent when a stream opens
+)
+
+// Item is a notification in the inbox of a user
+type Item struct {
+	ID        string                 `json:"id"`
+	Subject   string                 `json:"subject"`
+	Body      string                 `json:"body"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Category  string                 `json:"category,omitempty"`
+	Priority  string                 `json:"priority"`
+	CreatedAt time.Time              `json:"created_at"`
+	ReadAt    *time.Time             `json:"read_at,omitempty"`
+}
+
+// Event is a change of the inbox of a user
+type Event struct {
+	Type        string   `json:"type"`
+	Item        *Item    `json:"item,omitempty"`
+	IDs         []string `json:"ids,omitempty"`
+	UnreadCount int64    `json:"unread_count"`
+}
+
+// Inbox lists the in-app notifications of users and tracks their read
+// state in the read_at column of the notifications table
+type Inbox struct {
+	db     *gorm.DB
+	client redis.UniversalClient
+	config *notifications.InAppConfig
+}
+
+// NewInbox creates an inbox announcing changes through client
+func NewInbox(db *gorm.DB, client redis.UniversalClient, config *notifications.InAppConfig) *Inbox {
+	return &Inbox{db: db, client: client, config: config}
+}
+
+// List returns the in-app notifications of a user, newest first. Only
+// notifications of the last PersistenceDays are listed.
+func (i *Inbox) List(ctx context.Context, userID string, limit, offset int, unreadOnly bool) ([]Item, error) {
+	query := i.query(ctx, userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var rows []models.NotificationModel
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inbox: %w", err)
+	}
+
+	items := make([]Item, len(rows))
+	for n := range rows {
+		items[n] = toItem(&rows[n])
+	}
+	return items, nil
+}
+
+// UnreadCount returns the number of unread in-app notifications of a user
+func (i *Inbox) UnreadCount(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	if err := i.query(ctx, userID).Where("read_at IS NULL").Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead marks notifications of a user read, returning how many were
+// unread. IDs of other users' notifications are ignored.
+func (i *Inbox) MarkRead(ctx context.Context, userID string, ids ...string) (int64, error) {
+	parsed := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		value, err := uuid.Parse(id)
+		if err != nil {
+			return 0, fmt.Errorf("invalid notification ID %q: %w", id, err)
+		}
+		parsed = append(parsed, value)
+	}
+	if len(parsed) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	result := i.query(ctx, userID).
+		Where("id IN ? AND read_at IS NULL", parsed).
+		Updates(map[string]interface{}{"read_at": now, "updated_at": now})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		i.announce(ctx, userID, Event{Type: EventRead, IDs: ids})
+	}
+	return result.RowsAffected, nil
+}
+
+// MarkAllRead marks every notification of a user read, returning how many
+// were unread
+func (i *Inbox) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	now := time.Now()
+	result := i.query(ctx, userID).
+		Where("read_at IS NULL").
+		Updates(map[string]interface{}{"read_at": now, "updated_at": now})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		i.announce(ctx, userID, Event{Type: EventReadAll})
+	}
+	return result.RowsAffected, nil
+}
+
+// Subscribe streams the changes of the inbox of a user until ctx is done or
+// the returned function is called
+func (i *Inbox) Subscribe(ctx context.Context, userID string) (<-chan Event, func() error, error) {
+	sub := i.client.Subscribe(ctx, streamPrefix+userID)
+	// Wait for the subscription, so no change is missed after returning
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, nil, fmt.Errorf("failed to subscribe to inbox: %w", err)
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		for msg := range sub.Channel() {
+			var event Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				_ = sub.Close()
+				return
+			}
+		}
+	}()
+	return events, sub.Close, nil
+}
+
+// announce publishes a change of the inbox of a user with its unread count.
+// Announcements are best effort; streams catch up on their next event.
+func (i *Inbox) announce(ctx context.Context, userID string, event Event) {
+	if count, err := i.UnreadCount(ctx, userID); err == nil {
+		event.UnreadCount = count
+		if event.Type == EventCreated {
+			// The announced notification is marked delivered after Send
+			event.UnreadCount++
+		}
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	_ = i.client.Publish(ctx, streamPrefix+userID, payload).Err()
+}
+
+// query selects the delivered in-app notifications of a user within the
+// persistence window
+func (i *Inbox) query(ctx context.Context, userID string) *gorm.DB {
+	query := i.db.WithContext(ctx).Model(&models.NotificationModel{}).
+		Where("recipient_type = ? AND recipient_id = ? AND selected_channel = ? AND status IN ?",
+			notifications.RecipientTypeUser, userID, notifications.ChannelInApp,
+			[]notifications.Status{notifications.StatusSent, notifications.StatusDelivered})
+	if days := i.config.PersistenceDays; days > 0 {
+		query = query.Where("created_at >= ?", time.Now().AddDate(0, 0, -days))
+	}
+	return query
+}
+
+// toItem converts a notification row to an inbox item
+func toItem(model *models.NotificationModel) Item {
+	return Item{
+		ID:        model.ID.String(),
+		Subject:   model.Subject,
+		Body:      model.Body,
+		Data:      model.Data,
+		Category:  model.Category,
+		Priority:  model.Priority,
+		CreatedAt: model.CreatedAt,
+		ReadAt:    model.ReadAt,
+	}
+}
+
+// Provider delivers notifications to the inbox of their recipient. The
+// notification row is the inbox entry, so delivery announces it to the live
+// streams of the recipient and completes.
+type Provider struct {
+	inbox *Inbox
+}
+
+// NewProvider creates an in-app provider delivering to inbox
+func NewProvider(inbox *Inbox) *Provider {
+	return &Provider{inbox: inbox}
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return "inapp"
+}
+
+// GetChannel returns the channel of the provider
+func (p *Provider) GetChannel() notifications.Channel {
+	return notifications.ChannelInApp
+}
+
+// Send delivers a notification to the inbox of its recipient user
+func (p *Provider) Send(
+	ctx context.Context,
+	notification *notifications.Notification,
+) (*notifications.DeliveryResult, error) {
+	if notification.RecipientType != notifications.RecipientTypeUser {
+		return nil, fmt.Errorf("%w: in-app notifications need a user recipient, got %s",
+			notifications.ErrRejected, notification.RecipientType)
+	}
+
+	item := Item{
+		ID:        notification.ID,
+		Subject:   notification.Subject,
+		Body:      notification.Body,
+		Data:      notification.Data,
+		Category:  notification.Category,
+		Priority:  string(notification.Priority),
+		CreatedAt: notification.CreatedAt,
+	}
+	p.inbox.announce(ctx, notification.RecipientID, Event{Type: EventCreated, Item: &item})
+
+	return &notifications.DeliveryResult{
+		Status:            notifications.StatusDelivered,
+		ProviderMessageID: notification.ID,
+	}, nil
+}
+
+// HealthCheck checks Redis is reachable
+func (p *Provider) HealthCheck(ctx context.Context) error {
+	if err := p.inbox.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("in-app stream unavailable: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package pagerduty opens and resolves PagerDuty incidents through the
+// Events API v2.
+package pagerduty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+const (
+	eventsAPI = "https://events.pagerduty.com/v2/enqueue"
+	restAPI   = "https://api.pagerduty.com"
+)
+
+// severities maps notification priorities to PagerDuty severities
+var severities = map[notifications.Priority]string{
+	notifications.PriorityCritical: "critical",
+	notifications.PriorityHigh:     "error",
+	notifications.PriorityNormal:   "warning",
+	notifications.PriorityLow:      "info",
+}
+
+// Provider sends notifications as PagerDuty alert events. Notifications of
+// an incident share its dedup key, so PagerDuty groups them into one open
+// incident and a resolve notification closes it. Incidents are routed to the
+// service of their escalation policy, or the integration key's service.
+type Provider struct {
+	config *notifications.PagerDutyConfig
+	client *http.Client
+	events string
+	api    string
+}
+
+// NewProvider creates a PagerDuty provider
+func NewProvider(config *notifications.PagerDutyConfig) (*Provider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		events: eventsAPI,
+		api:    restAPI,
+	}, nil
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return "pagerduty"
+}
+
+// GetChannel returns the channel of the provider
+func (p *Provider) GetChannel() notifications.Channel {
+	return notifications.ChannelPagerDuty
+}
+
+// event is an Events API v2 event
+type event struct {
+	RoutingKey  string   `json:"routing_key"`
+	EventAction string   `json:"event_action"`
+	DedupKey    string   `json:"dedup_key"`
+	Payload     *payload `json:"payload,omitempty"`
+	Client      string   `json:"client,omitempty"`
+}
+
+// payload describes the alert of a trigger event
+type payload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// Send triggers or resolves the incident of a notification
+func (p *Provider) Send(
+	ctx context.Context,
+	notification *notifications.Notification,
+) (*notifications.DeliveryResult, error) {
+	routingKey, err := p.routingKey(notification)
+	if err != nil {
+		return nil, err
+	}
+
+	ev := event{
+		RoutingKey:  routingKey,
+		EventAction: metadata(notification, notifications.MetadataIncidentAction),
+		DedupKey:    DedupKey(notification),
+		Client:      "DictaMesh",
+	}
+	switch ev.EventAction {
+	case "":
+		ev.EventAction = notifications.IncidentTrigger
+	case notifications.IncidentTrigger, notifications.IncidentResolve:
+	default:
+		return nil, fmt.Errorf("%w: unsupported incident action %s", notifications.ErrRejected, ev.EventAction)
+	}
+	if ev.EventAction == notifications.IncidentTrigger {
+		ev.Payload = p.payload(notification)
+	}
+
+	var result struct {
+		Status   string `json:"status"`
+		Message  string `json:"message"`
+		DedupKey string `json:"dedup_key"`
+	}
+	if err := p.enqueue(ctx, &ev, &result); err != nil {
+		return nil, err
+	}
+
+	return &notifications.DeliveryResult{
+		Status:            notifications.StatusSent,
+		ProviderMessageID: result.DedupKey,
+		ProviderResponse: map[string]interface{}{
+			"event_action": ev.EventAction,
+			"status":       result.Status,
+			"message":      result.Message,
+		},
+	}, nil
+}
+
+// DedupKey returns the dedup key of the incident of a notification: its
+// incident key, else its rule and event, else the notification itself
+func DedupKey(notification *notifications.Notification) string {
+	if key := metadata(notification, notifications.MetadataIncidentKey); key != "" {
+		return key
+	}
+	if notification.RuleID != "" && notification.EventID != "" {
+		return notification.RuleID + ":" + notification.EventID
+	}
+	return notification.ID
+}
+
+// routingKey returns the routing key of the escalation policy of a
+// notification, or the integration key
+func (p *Provider) routingKey(notification *notifications.Notification) (string, error) {
+	policy := metadata(notification, notifications.MetadataEscalationPolicy)
+	if policy == "" {
+		return p.config.IntegrationKey, nil
+	}
+	key, ok := p.config.EscalationPolicies[policy]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown escalation policy %s", notifications.ErrRejected, policy)
+	}
+	return key, nil
+}
+
+// payload describes a notification as a PagerDuty alert, with its severity
+// mapped from its priority
+func (p *Provider) payload(notification *notifications.Notification) *payload {
+	summary := notification.Subject
+	if summary == "" {
+		summary = notification.Body
+	}
+	severity, ok := severities[notification.Priority]
+	if !ok {
+		severity = p.config.DefaultSeverity
+	}
+	if severity == "" {
+		severity = "warning"
+	}
+	source := p.config.Source
+	if source == "" {
+		source = "dictamesh"
+	}
+
+	details := make(map[string]interface{}, len(notification.Data)+1)
+	for key, value := range notification.Data {
+		details[key] = value
+	}
+	if notification.Subject != "" && notification.Body != "" {
+		details["body"] = notification.Body
+	}
+
+	pl := &payload{
+		Summary:       truncate(summary, 1024),
+		Source:        source,
+		Severity:      severity,
+		Component:     notification.Category,
+		Group:         metadata(notification, "rule"),
+		Class:         metadata(notification, "event_type"),
+		CustomDetails: details,
+	}
+	if !notification.CreatedAt.IsZero() {
+		pl.Timestamp = notification.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	return pl
+}
+
+// enqueue posts an event to the Events API
+func (p *Provider) enqueue(ctx context.Context, ev *event, out interface{}) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to encode PagerDuty event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.events, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("PagerDuty request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode PagerDuty response: %w", err)
+		}
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("PagerDuty returned %d: %s", resp.StatusCode, data)
+	default:
+		// Invalid events and routing keys
+		return fmt.Errorf("%w: PagerDuty returned %d: %s", notifications.ErrRejected, resp.StatusCode, data)
+	}
+}
+
+// HealthCheck verifies the REST API key is accepted. Without one, routing
+// keys cannot be checked without sending an event.
+func (p *Provider) HealthCheck(ctx context.Context) error {
+	if p.config.APIKey == "" {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.api+"/abilities", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token token="+p.config.APIKey)
+	req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("PagerDuty request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("PagerDuty API returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// metadata returns a string value of the metadata of a notification
+func metadata(notification *notifications.Notification, key string) string {
+	value, _ := notification.Metadata[key].(string)
+	return value
+}
+
+// truncate shortens text to the character limit of a payload field
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,279 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+const (
+	apnsProductionHost = "https://api.push.apple.com"
+	apnsSandboxHost    = "https://api.sandbox.push.apple.com"
+
+	// apnsTokenExpiry is how long a provider token is reused. APNs rejects
+	// tokens older than an hour and refreshes more often than every 20
+	// minutes.
+	apnsTokenExpiry = 50 * time.Minute
+)
+
+// apnsSender sends through the APNs HTTP/2 API, authenticating with a
+// signing key (token auth) or a client certificate
+type apnsSender struct {
+	config *notifications.APNsConfig
+	client *http.Client
+	host   string
+	key    *ecdsa.PrivateKey
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// newAPNsSender creates an APNs sender. Token auth is used when a signing key
+// is configured; otherwise the certificate file, a PEM bundle of the
+// certificate and its private key, authenticates the connection.
+func newAPNsSender(config *notifications.APNsConfig, timeout time.Duration) (*apnsSender, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+
+	s := &apnsSender{
+		config: config,
+		client: &http.Client{Transport: transport, Timeout: timeout},
+		host:   apnsSandboxHost,
+	}
+	if config.Production {
+		s.host = apnsProductionHost
+	}
+
+	if config.AuthKeyFile != "" {
+		key, err := loadAPNsKey(config.AuthKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.key = key
+		return s, nil
+	}
+
+	certificate, err := loadAPNsCertificate(config.CertificateFile, config.CertificatePassword)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	return s, nil
+}
+
+// loadAPNsKey reads a .p8 signing key
+func loadAPNsKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read APNs auth key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid APNs auth key: not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid APNs auth key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("APNs auth key is not an ECDSA key")
+	}
+	return key, nil
+}
+
+// loadAPNsCertificate reads a PEM bundle of a client certificate and its
+// private key, decrypting the key with password when it is encrypted
+func loadAPNsCertificate(path, password string) (tls.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to read APNs certificate: %w", err)
+	}
+
+	var certPEM, keyPEM []byte
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			certPEM = append(certPEM, pem.EncodeToMemory(block)...)
+			continue
+		}
+		// Keys exported from Keychain use legacy PEM encryption
+		if x509.IsEncryptedPEMBlock(block) {
+			der, err := x509.DecryptPEMBlock(block, []byte(password))
+			if err != nil {
+				return tls.Certificate{}, fmt.Errorf("failed to decrypt APNs certificate key: %w", err)
+			}
+			block = &pem.Block{Type: block.Type, Bytes: der}
+		}
+		keyPEM = pem.EncodeToMemory(block)
+	}
+
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("invalid APNs certificate: %w", err)
+	}
+	return certificate, nil
+}
+
+// send pushes a notification to a device and returns the apns-id of the
+// notification
+func (s *apnsSender) send(ctx context.Context, token string, notification *notifications.Notification) (string, error) {
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{
+				"title": notification.Subject,
+				"body":  notification.Body,
+			},
+			"sound": "default",
+		},
+	}
+	// Custom data sits beside the aps dictionary, which it cannot replace
+	for key, value := range dataPayload(notification) {
+		if key != "aps" {
+			payload[key] = value
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode APNs payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		s.host+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apns-topic", s.config.BundleID)
+	req.Header.Set("apns-push-type", "alert")
+	if notification.Priority == notifications.PriorityCritical || notification.Priority == notifications.PriorityHigh {
+		req.Header.Set("apns-priority", "10")
+	} else {
+		req.Header.Set("apns-priority", "5")
+	}
+	if key := collapseKey(notification); key != "" {
+		req.Header.Set("apns-collapse-id", key)
+	}
+	if s.key != nil {
+		providerToken, err := s.providerToken()
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Authorization", "bearer "+providerToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("APNs request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", apnsFailure(resp)
+	}
+	return resp.Header.Get("apns-id"), nil
+}
+
+// healthCheck verifies the APNs host is reachable with the configured
+// credentials. Any response from APNs, even to an empty request, proves it.
+func (s *apnsSender) healthCheck(ctx context.Context) error {
+	if s.key != nil {
+		if _, err := s.providerToken(); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.host, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("APNs request failed: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// apnsFailure converts an unsuccessful APNs response to an error, marking
+// tokens of uninstalled apps and malformed tokens as invalid
+func apnsFailure(resp *http.Response) error {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body)
+
+	switch {
+	case resp.StatusCode == http.StatusGone,
+		body.Reason == "BadDeviceToken",
+		body.Reason == "Unregistered",
+		body.Reason == "DeviceTokenNotForTopic":
+		return fmt.Errorf("%w: APNs %s", errInvalidToken, body.Reason)
+	}
+	return fmt.Errorf("APNs returned %d: %s", resp.StatusCode, body.Reason)
+}
+
+// providerToken returns a cached provider token, signing a new ES256 JWT
+// when it expires
+func (s *apnsSender) providerToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "kid": s.config.KeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{"iss": s.config.TeamID, "iat": now.Unix()})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	hash := sha256.Sum256([]byte(unsigned))
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign APNs token: %w", err)
+	}
+	// JWS signatures are the fixed-width concatenation of r and s
+	signature := append(fixedBytes(r, 32), fixedBytes(sig, 32)...)
+
+	s.token = unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+	s.expiresAt = now.Add(apnsTokenExpiry)
+	return s.token, nil
+}
+
+// fixedBytes encodes n big-endian, left-padded to size bytes
+func fixedBytes(n *big.Int, size int) []byte {
+	out := make([]byte, size)
+	return n.FillBytes(out)
+}
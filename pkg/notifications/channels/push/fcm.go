@@ -0,0 +1,275 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+const (
+	fcmEndpoint = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+	fcmScope    = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// fcmServiceAccount is the JSON key of a Google service account
+type fcmServiceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// fcmSender sends through the FCM HTTP v1 API, authenticating with OAuth2
+// access tokens of a service account
+type fcmSender struct {
+	config   *notifications.FCMConfig
+	client   *http.Client
+	account  fcmServiceAccount
+	key      *rsa.PrivateKey
+	endpoint string
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// newFCMSender creates an FCM sender from the service account key file
+func newFCMSender(config *notifications.FCMConfig, client *http.Client) (*fcmSender, error) {
+	data, err := os.ReadFile(config.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FCM credentials: %w", err)
+	}
+	var account fcmServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("invalid FCM credentials: %w", err)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid FCM credentials: no private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FCM private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("FCM private key is not an RSA key")
+	}
+
+	projectID := config.ProjectID
+	if projectID == "" {
+		projectID = account.ProjectID
+	}
+
+	return &fcmSender{
+		config:   config,
+		client:   client,
+		account:  account,
+		key:      key,
+		endpoint: fmt.Sprintf(fcmEndpoint, url.PathEscape(projectID)),
+	}, nil
+}
+
+// fcmMessage is the body of the FCM send call
+type fcmMessage struct {
+	Message struct {
+		Token        string            `json:"token"`
+		Notification fcmNotification   `json:"notification"`
+		Data         map[string]string `json:"data,omitempty"`
+		Android      *fcmAndroid       `json:"android,omitempty"`
+		Webpush      *fcmWebpush       `json:"webpush,omitempty"`
+	} `json:"message"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+type fcmAndroid struct {
+	CollapseKey string `json:"collapse_key,omitempty"`
+	Priority    string `json:"priority,omitempty"`
+}
+
+type fcmWebpush struct {
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// fcmError is the error body of the FCM API
+type fcmError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// send pushes a notification to a device and returns the FCM message name
+func (s *fcmSender) send(ctx context.Context, token string, notification *notifications.Notification) (string, error) {
+	var msg fcmMessage
+	msg.Message.Token = token
+	msg.Message.Notification = fcmNotification{Title: notification.Subject, Body: notification.Body}
+	msg.Message.Data = dataPayload(notification)
+
+	priority := "NORMAL"
+	if s.config.Priority == "high" || notification.Priority == notifications.PriorityCritical ||
+		notification.Priority == notifications.PriorityHigh {
+		priority = "HIGH"
+	}
+	key := collapseKey(notification)
+	msg.Message.Android = &fcmAndroid{CollapseKey: key, Priority: priority}
+	if key != "" {
+		// Web push replaces messages with the same topic
+		msg.Message.Webpush = &fcmWebpush{Headers: map[string]string{"Topic": key}}
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode FCM message: %w", err)
+	}
+
+	accessToken, err := s.token(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("FCM request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fcmFailure(resp)
+	}
+
+	var result struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode FCM response: %w", err)
+	}
+	return result.Name, nil
+}
+
+// healthCheck verifies an access token can be obtained
+func (s *fcmSender) healthCheck(ctx context.Context) error {
+	_, err := s.token(ctx)
+	return err
+}
+
+// fcmFailure converts an unsuccessful FCM response to an error, marking
+// tokens of uninstalled apps and other projects as invalid
+func fcmFailure(resp *http.Response) error {
+	var body fcmError
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body)
+
+	for _, detail := range body.Error.Details {
+		switch detail.ErrorCode {
+		case "UNREGISTERED", "SENDER_ID_MISMATCH":
+			return fmt.Errorf("%w: FCM %s", errInvalidToken, detail.ErrorCode)
+		}
+	}
+	if body.Error.Status == "INVALID_ARGUMENT" && strings.Contains(body.Error.Message, "registration token") {
+		return fmt.Errorf("%w: %s", errInvalidToken, body.Error.Message)
+	}
+	return fmt.Errorf("FCM returned %d %s: %s", resp.StatusCode, body.Error.Status, body.Error.Message)
+}
+
+// token returns a cached access token, requesting a new one from the token
+// endpoint with a signed JWT assertion when it expires
+func (s *fcmSender) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accessToken != "" && time.Now().Before(s.expiresAt) {
+		return s.accessToken, nil
+	}
+
+	now := time.Now()
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   s.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   s.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) +
+		"." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign FCM assertion: %w", err)
+	}
+	assertion := unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("FCM token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("FCM token request returned %d", resp.StatusCode)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode FCM token: %w", err)
+	}
+
+	s.accessToken = result.AccessToken
+	// Renew a minute early so tokens do not expire in flight
+	s.expiresAt = now.Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+	return s.accessToken, nil
+}
@@ -0,0 +1,201 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package push delivers notifications to the registered devices of their
+// recipients through Firebase Cloud Messaging and the Apple Push
+// Notification service.
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+// errInvalidToken is wrapped by sender errors for tokens the push service
+// no longer accepts
+var errInvalidToken = errors.New("invalid push token")
+
+// sender delivers a notification to a single device
+type sender interface {
+	send(ctx context.Context, token string, notification *notifications.Notification) (string, error)
+	healthCheck(ctx context.Context) error
+}
+
+// Provider delivers push notifications to every device of the recipient,
+// pruning tokens the push services reject as invalid
+type Provider struct {
+	config  *notifications.PushConfig
+	tokens  *notifications.PushTokenStore
+	senders map[notifications.PushPlatform]sender
+}
+
+// NewProvider creates a push provider for the enabled push services
+func NewProvider(config *notifications.PushConfig, tokens *notifications.PushTokenStore) (*Provider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: config.Timeout}
+	senders := make(map[notifications.PushPlatform]sender)
+
+	if config.FCM.Enabled {
+		fcm, err := newFCMSender(&config.FCM, client)
+		if err != nil {
+			return nil, err
+		}
+		senders[notifications.PushPlatformAndroid] = fcm
+		senders[notifications.PushPlatformWeb] = fcm
+	}
+	if config.APNs.Enabled {
+		apns, err := newAPNsSender(&config.APNs, config.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		senders[notifications.PushPlatformIOS] = apns
+	}
+
+	return &Provider{
+		config:  config,
+		tokens:  tokens,
+		senders: senders,
+	}, nil
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return "push"
+}
+
+// GetChannel returns the channel of the provider
+func (p *Provider) GetChannel() notifications.Channel {
+	return notifications.ChannelPush
+}
+
+// deviceResult is the outcome of a push to one device
+type deviceResult struct {
+	token     string
+	messageID string
+	err       error
+}
+
+// Send pushes a notification to all devices of its recipient, several at a
+// time. It succeeds when at least one device accepted it.
+func (p *Provider) Send(
+	ctx context.Context,
+	notification *notifications.Notification,
+) (*notifications.DeliveryResult, error) {
+	devices, err := p.tokens.PushTokens(ctx, notification.RecipientID)
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("%w: recipient %s has no push tokens", notifications.ErrRejected, notification.RecipientID)
+	}
+
+	concurrency := p.config.MaxConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+	results := make([]deviceResult, len(devices))
+
+	var wg sync.WaitGroup
+	for i, device := range devices {
+		s, ok := p.senders[device.Platform]
+		if !ok {
+			results[i] = deviceResult{
+				token: device.Token,
+				err:   fmt.Errorf("no push service for platform %q", device.Platform),
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, token string, s sender) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			messageID, err := s.send(ctx, token, notification)
+			results[i] = deviceResult{token: token, messageID: messageID, err: err}
+		}(i, device.Token, s)
+	}
+	wg.Wait()
+
+	var messageIDs, failures []string
+	pruned := 0
+	for _, result := range results {
+		if result.err == nil {
+			messageIDs = append(messageIDs, result.messageID)
+			continue
+		}
+		failures = append(failures, result.err.Error())
+		if errors.Is(result.err, errInvalidToken) {
+			if err := p.tokens.InvalidatePushToken(ctx, result.token); err == nil {
+				pruned++
+			}
+		}
+	}
+
+	if len(messageIDs) == 0 {
+		err := fmt.Errorf("no device accepted the notification: %s", strings.Join(failures, "; "))
+		if pruned == len(devices) {
+			err = fmt.Errorf("%w: %v", notifications.ErrRejected, err)
+		}
+		return nil, err
+	}
+
+	return &notifications.DeliveryResult{
+		Status:            notifications.StatusSent,
+		ProviderMessageID: messageIDs[0],
+		ProviderResponse: map[string]interface{}{
+			"devices":     len(devices),
+			"sent":        len(messageIDs),
+			"failed":      len(failures),
+			"pruned":      pruned,
+			"message_ids": messageIDs,
+		},
+	}, nil
+}
+
+// HealthCheck verifies every enabled push service is reachable
+func (p *Provider) HealthCheck(ctx context.Context) error {
+	checked := make(map[sender]bool)
+	for _, s := range p.senders {
+		if checked[s] {
+			continue
+		}
+		checked[s] = true
+		if err := s.healthCheck(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collapseKey groups the notifications of a category on a device, so only
+// the latest one is shown while the device is offline
+func collapseKey(notification *notifications.Notification) string {
+	key := notification.Category
+	// APNs limits collapse IDs to 64 bytes
+	if len(key) > 64 {
+		key = key[:64]
+	}
+	return key
+}
+
+// dataPayload converts notification data to the string map push services
+// accept
+func dataPayload(notification *notifications.Notification) map[string]string {
+	data := map[string]string{"notification_id": notification.ID}
+	for key, value := range notification.Data {
+		data[key] = fmt.Sprint(value)
+	}
+	return data
+}
@@ -0,0 +1,299 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package slack delivers notifications to Slack channels through an incoming
+// webhook or the Web API of a bot.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+const slackAPI = "https://slack.com/api"
+
+// slackPermanentErrors are the Web API errors of channels that will not
+// accept messages from the bot
+var slackPermanentErrors = map[string]bool{
+	"channel_not_found": true,
+	"not_in_channel":    true,
+	"is_archived":       true,
+	"msg_too_long":      true,
+	"invalid_blocks":    true,
+	"no_text":           true,
+}
+
+// Provider delivers notifications as Block Kit messages. With a bot token,
+// messages are posted to the channel in the recipient address, or the
+// default channel; an incoming webhook posts to its own channel.
+type Provider struct {
+	config    *notifications.SlackConfig
+	client    *http.Client
+	api       string
+	templates map[string]*template.Template
+}
+
+// NewProvider creates a Slack provider, parsing its Block Kit templates
+func NewProvider(config *notifications.SlackConfig) (*Provider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*template.Template, len(config.BlockTemplates))
+	for id, source := range config.BlockTemplates {
+		tmpl, err := template.New(id).Funcs(templateFuncs).Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Slack blocks template %s: %w", id, err)
+		}
+		templates[id] = tmpl
+	}
+
+	return &Provider{
+		config:    config,
+		client:    &http.Client{Timeout: config.Timeout},
+		api:       slackAPI,
+		templates: templates,
+	}, nil
+}
+
+// templateFuncs are available to Block Kit templates. json encodes a value
+// as a JSON literal, so text can be embedded in blocks safely.
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return "slack"
+}
+
+// GetChannel returns the channel of the provider
+func (p *Provider) GetChannel() notifications.Channel {
+	return notifications.ChannelSlack
+}
+
+// slackMessage is the body of a webhook or chat.postMessage call
+type slackMessage struct {
+	Channel   string            `json:"channel,omitempty"`
+	Text      string            `json:"text"`
+	Blocks    []json.RawMessage `json:"blocks,omitempty"`
+	Username  string            `json:"username,omitempty"`
+	IconEmoji string            `json:"icon_emoji,omitempty"`
+}
+
+// Send posts a notification to Slack
+func (p *Provider) Send(
+	ctx context.Context,
+	notification *notifications.Notification,
+) (*notifications.DeliveryResult, error) {
+	blocks, err := p.renderBlocks(notification)
+	if err != nil {
+		return nil, err
+	}
+
+	// Text is the fallback of notifications and screen readers
+	text := notification.Subject
+	if text == "" {
+		text = notification.Body
+	}
+	msg := slackMessage{
+		Text:      text,
+		Blocks:    blocks,
+		Username:  p.config.Username,
+		IconEmoji: p.config.IconEmoji,
+	}
+
+	if p.config.BotToken == "" {
+		return p.sendWebhook(ctx, &msg)
+	}
+
+	msg.Channel = notification.RecipientAddress
+	if msg.Channel == "" {
+		msg.Channel = p.config.DefaultChannel
+	}
+	if msg.Channel == "" {
+		return nil, fmt.Errorf("%w: no Slack channel for notification %s", notifications.ErrRejected, notification.ID)
+	}
+
+	var result struct {
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+	if err := p.call(ctx, "chat.postMessage", &msg, &result); err != nil {
+		return nil, err
+	}
+
+	return &notifications.DeliveryResult{
+		Status:            notifications.StatusSent,
+		ProviderMessageID: result.Channel + ":" + result.TS,
+		ProviderResponse: map[string]interface{}{
+			"channel": result.Channel,
+			"ts":      result.TS,
+		},
+	}, nil
+}
+
+// sendWebhook posts a message to the incoming webhook
+func (p *Provider) sendWebhook(ctx context.Context, msg *slackMessage) (*notifications.DeliveryResult, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	notifications.InjectTrace(ctx, req.Header)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Slack webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	reason, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return &notifications.DeliveryResult{Status: notifications.StatusSent}, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, fmt.Errorf("Slack webhook returned %d: %s", resp.StatusCode, reason)
+	default:
+		// Removed webhooks, archived channels and invalid payloads
+		return nil, fmt.Errorf("%w: Slack webhook returned %d: %s", notifications.ErrRejected, resp.StatusCode, reason)
+	}
+}
+
+// HealthCheck verifies the bot token is accepted. Incoming webhooks cannot
+// be checked without posting a message.
+func (p *Provider) HealthCheck(ctx context.Context) error {
+	if p.config.BotToken == "" {
+		return nil
+	}
+	return p.call(ctx, "auth.test", struct{}{}, nil)
+}
+
+// call invokes a Web API method and decodes its response into out, if not
+// nil
+func (p *Provider) call(ctx context.Context, method string, in interface{}, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to encode Slack request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.api+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.BotToken)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	notifications.InjectTrace(ctx, req.Header)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Slack request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Slack %s returned %d (retry after %s)", method, resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read Slack response: %w", err)
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("failed to decode Slack response: %w", err)
+	}
+	if !status.OK {
+		if slackPermanentErrors[status.Error] {
+			return fmt.Errorf("%w: Slack %s: %s", notifications.ErrRejected, method, status.Error)
+		}
+		return fmt.Errorf("Slack %s failed: %s", method, status.Error)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode Slack response: %w", err)
+		}
+	}
+	return nil
+}
+
+// renderBlocks renders the Block Kit blocks of a notification with the
+// template of its notification template, or the default layout
+func (p *Provider) renderBlocks(notification *notifications.Notification) ([]json.RawMessage, error) {
+	tmpl, ok := p.templates[notification.TemplateID]
+	if !ok {
+		return defaultBlocks(notification), nil
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, notification); err != nil {
+		return nil, fmt.Errorf("failed to render Slack blocks: %w", err)
+	}
+	var blocks []json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &blocks); err != nil {
+		return nil, fmt.Errorf("Slack blocks template %s is not a JSON array: %w", notification.TemplateID, err)
+	}
+	return blocks, nil
+}
+
+// defaultBlocks lays a notification out as a header, its body and a context
+// line with its priority
+func defaultBlocks(notification *notifications.Notification) []json.RawMessage {
+	var blocks []interface{}
+	if notification.Subject != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": truncate(notification.Subject, 150)},
+		})
+	}
+	if notification.Body != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": truncate(notification.Body, 3000)},
+		})
+	}
+	if notification.Priority != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "context",
+			"elements": []interface{}{
+				map[string]interface{}{"type": "mrkdwn", "text": "*Priority:* " + strings.ToLower(string(notification.Priority))},
+			},
+		})
+	}
+
+	raw := make([]json.RawMessage, 0, len(blocks))
+	for _, block := range blocks {
+		data, _ := json.Marshal(block)
+		raw = append(raw, data)
+	}
+	return raw
+}
+
+// truncate shortens text to the character limit of a block field
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
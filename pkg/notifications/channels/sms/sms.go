@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package sms delivers notifications by SMS through Twilio or AWS SNS.
+package sms
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+// maxWebhookBody bounds the size of delivery receipt requests
+const maxWebhookBody = 1 << 20
+
+// e164 matches phone numbers in E.164 format
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// NewProvider creates the SMS provider selected by config.Provider
+func NewProvider(config *notifications.SMSConfig) (notifications.DeliveryProvider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch config.Provider {
+	case "twilio":
+		return NewTwilioProvider(config), nil
+	case "sns":
+		return NewSNSProvider(config), nil
+	default:
+		return nil, fmt.Errorf("unsupported SMS provider: %s", config.Provider)
+	}
+}
+
+// ValidateE164 checks that a phone number is in E.164 format, e.g.
+// +14155550100
+func ValidateE164(number string) error {
+	if !e164.MatchString(number) {
+		return fmt.Errorf("%w: %q is not an E.164 phone number", notifications.ErrRejected, number)
+	}
+	return nil
+}
+
+// sms is a text message rendered from a notification
+type sms struct {
+	to       string
+	sender   string
+	body     string
+	segments int
+}
+
+// newSMS renders a notification as a text message. The body is truncated to
+// MaxLength characters.
+func newSMS(config *notifications.SMSConfig, notification *notifications.Notification) (*sms, error) {
+	if err := ValidateE164(notification.RecipientAddress); err != nil {
+		return nil, err
+	}
+
+	body := notification.Body
+	if body == "" {
+		body = notification.Subject
+	}
+	if body == "" {
+		return nil, fmt.Errorf("%w: notification %s has no content", notifications.ErrRejected, notification.ID)
+	}
+	if runes := []rune(body); config.MaxLength > 0 && len(runes) > config.MaxLength {
+		body = string(runes[:config.MaxLength-1]) + "…"
+	}
+
+	return &sms{
+		to:       notification.RecipientAddress,
+		sender:   senderFor(config, notification.RecipientAddress),
+		body:     body,
+		segments: segments(body),
+	}, nil
+}
+
+// senderFor returns the sender ID configured for the calling code of a
+// number, or the default sender
+func senderFor(config *notifications.SMSConfig, number string) string {
+	sender, matched := "", 0
+	for prefix, id := range config.SenderIDs {
+		if strings.HasPrefix(number, prefix) && len(prefix) > matched {
+			sender, matched = id, len(prefix)
+		}
+	}
+	if sender != "" {
+		return sender
+	}
+	if config.From != "" {
+		return config.From
+	}
+	return config.Twilio.FromNumber
+}
+
+// gsm7 holds the characters of the GSM 03.38 basic character set
+const gsm7 = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsm7Extended holds the characters that take two GSM 03.38 septets
+const gsm7Extended = "^{}\\[~]|€\f"
+
+// segments returns the number of message parts a body is sent as: up to 160
+// GSM characters (153 per part when split), or 70 UCS-2 characters (67 per
+// part) when the body has characters outside the GSM alphabet
+func segments(body string) int {
+	septets := 0
+	for _, r := range body {
+		switch {
+		case strings.ContainsRune(gsm7, r):
+			septets++
+		case strings.ContainsRune(gsm7Extended, r):
+			septets += 2
+		default:
+			return parts(len([]rune(body)), 70, 67)
+		}
+	}
+	return parts(septets, 160, 153)
+}
+
+// parts returns the number of parts of units characters
+func parts(units, single, multi int) int {
+	if units <= single {
+		return 1
+	}
+	return (units + multi - 1) / multi
+}
+
+// costMetadata returns the metadata recorded on delivery attempts for
+// billing. Providers add the price when they report it.
+func costMetadata(msg *sms) map[string]interface{} {
+	return map[string]interface{}{
+		"segments": msg.segments,
+	}
+}
+
+// newHTTPClient creates the client of the provider API, keeping connections
+// open between messages
+func newHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 16
+	transport.IdleConnTimeout = 90 * time.Second
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package sms
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+// SNSProvider delivers SMS through AWS SNS. SNS reports delivery status and
+// prices to CloudWatch Logs only, so attempts stay SENT and carry the
+// segment count as cost metadata.
+type SNSProvider struct {
+	config   *notifications.SMSConfig
+	client   *http.Client
+	signer   *v4.Signer
+	endpoint string
+}
+
+// NewSNSProvider creates a new SNS provider. Without an access key in the
+// configuration, the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN environment variables are used.
+func NewSNSProvider(config *notifications.SMSConfig) *SNSProvider {
+	return &SNSProvider{
+		config:   config,
+		client:   newHTTPClient(config.Timeout),
+		signer:   v4.NewSigner(),
+		endpoint: fmt.Sprintf("https://sns.%s.amazonaws.com/", config.SNS.Region),
+	}
+}
+
+// Name returns the provider name
+func (p *SNSProvider) Name() string {
+	return "sns"
+}
+
+// GetChannel returns the channel of the provider
+func (p *SNSProvider) GetChannel() notifications.Channel {
+	return notifications.ChannelSMS
+}
+
+// snsError is the error body of the SNS API
+type snsError struct {
+	Error struct {
+		Type    string `xml:"Type"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Error"`
+}
+
+// Send delivers a notification by SMS
+func (p *SNSProvider) Send(
+	ctx context.Context,
+	notification *notifications.Notification,
+) (*notifications.DeliveryResult, error) {
+	msg, err := newSMS(p.config, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"Action":      {"Publish"},
+		"PhoneNumber": {msg.to},
+		"Message":     {msg.body},
+	}
+	attribute := 0
+	setAttribute := func(name, value string) {
+		attribute++
+		prefix := fmt.Sprintf("MessageAttributes.entry.%d.", attribute)
+		form.Set(prefix+"Name", name)
+		form.Set(prefix+"Value.DataType", "String")
+		form.Set(prefix+"Value.StringValue", value)
+	}
+	if p.config.SNS.SMSType != "" {
+		setAttribute("AWS.SNS.SMS.SMSType", p.config.SNS.SMSType)
+	}
+	// Numeric senders are origination numbers; others are alphanumeric IDs
+	if msg.sender != "" {
+		if strings.HasPrefix(msg.sender, "+") {
+			setAttribute("AWS.MM.SMS.OriginationNumber", msg.sender)
+		} else {
+			setAttribute("AWS.SNS.SMS.SenderID", msg.sender)
+		}
+	}
+
+	var result struct {
+		MessageID string `xml:"PublishResult>MessageId"`
+	}
+	if err := p.call(ctx, form, &result); err != nil {
+		return nil, err
+	}
+
+	response := costMetadata(msg)
+	response["message_id"] = result.MessageID
+
+	return &notifications.DeliveryResult{
+		Status:            notifications.StatusSent,
+		ProviderMessageID: result.MessageID,
+		ProviderResponse:  response,
+	}, nil
+}
+
+// HealthCheck verifies the credentials can read the SMS settings
+func (p *SNSProvider) HealthCheck(ctx context.Context) error {
+	return p.call(ctx, url.Values{"Action": {"GetSMSAttributes"}}, nil)
+}
+
+// call invokes an action of the SNS query API and decodes its XML result
+// into out, if not nil
+func (p *SNSProvider) call(ctx context.Context, form url.Values, out interface{}) error {
+	form.Set("Version", "2010-03-31")
+	body := form.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	hash := sha256.Sum256([]byte(body))
+	if err := p.signer.SignHTTP(ctx, p.credentials(), req, hex.EncodeToString(hash[:]),
+		"sns", p.config.SNS.Region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign SNS request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("SNS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookBody))
+	if err != nil {
+		return fmt.Errorf("failed to read SNS response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var failure snsError
+		_ = xml.Unmarshal(data, &failure)
+		// Sender faults, such as invalid numbers, cannot be fixed by retrying
+		if failure.Error.Type == "Sender" && resp.StatusCode != http.StatusTooManyRequests &&
+			failure.Error.Code != "Throttling" {
+			return fmt.Errorf("%w: SNS %s: %s", notifications.ErrRejected, failure.Error.Code, failure.Error.Message)
+		}
+		return fmt.Errorf("SNS returned %d %s: %s", resp.StatusCode, failure.Error.Code, failure.Error.Message)
+	}
+
+	if out != nil {
+		if err := xml.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode SNS response: %w", err)
+		}
+	}
+	return nil
+}
+
+// credentials returns the configured credentials, or those of the environment
+func (p *SNSProvider) credentials() aws.Credentials {
+	if p.config.SNS.AccessKeyID != "" {
+		return aws.Credentials{
+			AccessKeyID:     p.config.SNS.AccessKeyID,
+			SecretAccessKey: p.config.SNS.SecretAccessKey,
+		}
+	}
+	return aws.Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
@@ -0,0 +1,272 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+const (
+	twilioEndpoint = "https://api.twilio.com/2010-04-01"
+
+	twilioSignatureHeader = "X-Twilio-Signature"
+)
+
+// twilioPermanentErrors are the Twilio error codes of numbers that will not
+// accept messages: unknown destinations, landlines and opted out recipients
+var twilioPermanentErrors = map[string]bool{
+	"21211": true,
+	"21610": true,
+	"21614": true,
+	"30005": true,
+	"30006": true,
+}
+
+// TwilioProvider delivers SMS through the Twilio Messages API
+type TwilioProvider struct {
+	config   *notifications.SMSConfig
+	client   *http.Client
+	endpoint string
+}
+
+// NewTwilioProvider creates a new Twilio provider
+func NewTwilioProvider(config *notifications.SMSConfig) *TwilioProvider {
+	return &TwilioProvider{
+		config:   config,
+		client:   newHTTPClient(config.Timeout),
+		endpoint: twilioEndpoint,
+	}
+}
+
+// Name returns the provider name
+func (p *TwilioProvider) Name() string {
+	return "twilio"
+}
+
+// GetChannel returns the channel of the provider
+func (p *TwilioProvider) GetChannel() notifications.Channel {
+	return notifications.ChannelSMS
+}
+
+// twilioMessage is a message resource of the Twilio API
+type twilioMessage struct {
+	SID         string  `json:"sid"`
+	Status      string  `json:"status"`
+	NumSegments string  `json:"num_segments"`
+	Price       *string `json:"price"`
+	PriceUnit   string  `json:"price_unit"`
+}
+
+// Send delivers a notification by SMS
+func (p *TwilioProvider) Send(
+	ctx context.Context,
+	notification *notifications.Notification,
+) (*notifications.DeliveryResult, error) {
+	msg, err := newSMS(p.config, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"To":   {msg.to},
+		"From": {msg.sender},
+		"Body": {msg.body},
+	}
+	if p.config.Twilio.StatusCallbackURL != "" {
+		form.Set("StatusCallback", p.config.Twilio.StatusCallbackURL)
+	}
+
+	resp, err := p.do(ctx, http.MethodPost, "/Messages.json", form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, twilioFailure(resp)
+	}
+
+	var message twilioMessage
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		return nil, fmt.Errorf("failed to decode Twilio response: %w", err)
+	}
+
+	response := costMetadata(msg)
+	response["sid"] = message.SID
+	response["status"] = message.Status
+	addPrice(response, &message)
+
+	return &notifications.DeliveryResult{
+		Status:            notifications.StatusSent,
+		ProviderMessageID: message.SID,
+		ProviderResponse:  response,
+	}, nil
+}
+
+// HealthCheck verifies the account credentials are accepted
+func (p *TwilioProvider) HealthCheck(ctx context.Context) error {
+	resp, err := p.do(ctx, http.MethodGet, ".json", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return twilioFailure(resp)
+	}
+	return nil
+}
+
+// do sends an authenticated request for a resource of the account
+func (p *TwilioProvider) do(ctx context.Context, method, path string, form url.Values) (*http.Response, error) {
+	endpoint := fmt.Sprintf("%s/Accounts/%s%s", p.endpoint, p.config.Twilio.AccountSID, path)
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(p.config.Twilio.AccountSID, p.config.Twilio.AuthToken)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Twilio request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// twilioFailure converts an unsuccessful Twilio response to an error. Rate
+// limits and server errors can be retried; other client errors, such as
+// invalid numbers, cannot.
+func twilioFailure(resp *http.Response) error {
+	var body struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, maxWebhookBody)).Decode(&body)
+
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: Twilio %d error %d: %s",
+			notifications.ErrRejected, resp.StatusCode, body.Code, body.Message)
+	}
+	return fmt.Errorf("Twilio returned %d error %d: %s", resp.StatusCode, body.Code, body.Message)
+}
+
+// addPrice records the price of a message once Twilio reports it. Twilio
+// reports prices as negative amounts.
+func addPrice(metadata map[string]interface{}, message *twilioMessage) {
+	if message.Price == nil || *message.Price == "" {
+		return
+	}
+	metadata["price"] = strings.TrimPrefix(*message.Price, "-")
+	metadata["price_unit"] = message.PriceUnit
+	if message.NumSegments != "" {
+		metadata["segments"] = message.NumSegments
+	}
+}
+
+// ParseFeedback parses the delivery receipts Twilio posts to
+// Twilio.StatusCallbackURL, after verifying their signature. Final receipts
+// carry the price of the message.
+func (p *TwilioProvider) ParseFeedback(r *http.Request) ([]notifications.DeliveryEvent, error) {
+	if p.config.Twilio.StatusCallbackURL == "" {
+		return nil, fmt.Errorf("Twilio status callback URL is not configured")
+	}
+	r.Body = http.MaxBytesReader(nil, r.Body, maxWebhookBody)
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("invalid Twilio callback: %w", err)
+	}
+	if !p.validSignature(r.Header.Get(twilioSignatureHeader), r.PostForm) {
+		return nil, fmt.Errorf("invalid Twilio signature")
+	}
+
+	sid := r.PostForm.Get("MessageSid")
+	event := notifications.DeliveryEvent{
+		ProviderMessageID: sid,
+		Recipient:         r.PostForm.Get("To"),
+		OccurredAt:        time.Now(),
+	}
+
+	errorCode := r.PostForm.Get("ErrorCode")
+	switch r.PostForm.Get("MessageStatus") {
+	case "delivered":
+		event.Type = notifications.DeliveryEventDelivered
+	case "undelivered", "failed":
+		event.Type = notifications.DeliveryEventBounced
+		event.Permanent = twilioPermanentErrors[errorCode]
+		event.Reason = "Twilio error " + errorCode
+	default:
+		// Intermediate statuses (queued, sending, sent) change nothing
+		return nil, nil
+	}
+
+	// Receipts do not carry the price; a failed lookup leaves it out
+	if message, err := p.fetch(r.Context(), sid); err == nil {
+		event.Metadata = map[string]interface{}{}
+		addPrice(event.Metadata, message)
+	}
+
+	return []notifications.DeliveryEvent{event}, nil
+}
+
+// fetch retrieves a message
+func (p *TwilioProvider) fetch(ctx context.Context, sid string) (*twilioMessage, error) {
+	resp, err := p.do(ctx, http.MethodGet, "/Messages/"+url.PathEscape(sid)+".json", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, twilioFailure(resp)
+	}
+
+	var message twilioMessage
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		return nil, fmt.Errorf("failed to decode Twilio message: %w", err)
+	}
+	return &message, nil
+}
+
+// validSignature checks a Twilio request signature: the HMAC-SHA1, keyed
+// with the auth token, of the callback URL followed by the sorted form
+// parameters
+func (p *TwilioProvider) validSignature(signature string, form url.Values) bool {
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mac := hmac.New(sha1.New, []byte(p.config.Twilio.AuthToken))
+	mac.Write([]byte(p.config.Twilio.StatusCallbackURL))
+	for _, key := range keys {
+		for _, value := range form[key] {
+			mac.Write([]byte(key))
+			mac.Write([]byte(value))
+		}
+	}
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,245 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package webhook delivers notifications as signed JSON payloads posted to
+// configured HTTP endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+const (
+	// SignatureHeader carries "t=<unix time>,v1=<hex HMAC-SHA256>" of the
+	// timestamp and body joined by a period
+	SignatureHeader = "X-DictaMesh-Signature"
+
+	// DeliveryHeader carries the notification ID, for receivers to drop
+	// duplicate deliveries
+	DeliveryHeader = "X-DictaMesh-Delivery"
+)
+
+// Provider delivers notifications to the endpoint named by their recipient
+// address, retrying transient failures with exponential backoff
+type Provider struct {
+	config *notifications.WebhookConfig
+	client *http.Client
+}
+
+// NewProvider creates a webhook provider
+func NewProvider(config *notifications.WebhookConfig) (*Provider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return "webhook"
+}
+
+// GetChannel returns the channel of the provider
+func (p *Provider) GetChannel() notifications.Channel {
+	return notifications.ChannelWebhook
+}
+
+// Payload is the JSON body posted to endpoints
+type Payload struct {
+	ID        string                 `json:"id"`
+	EventID   string                 `json:"event_id,omitempty"`
+	Priority  string                 `json:"priority"`
+	Category  string                 `json:"category,omitempty"`
+	Subject   string                 `json:"subject"`
+	Body      string                 `json:"body"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	TraceID   string                 `json:"trace_id,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Send posts a notification to its endpoint
+func (p *Provider) Send(
+	ctx context.Context,
+	notification *notifications.Notification,
+) (*notifications.DeliveryResult, error) {
+	endpoint, ok := p.config.Endpoints[notification.RecipientAddress]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown webhook endpoint %q", notifications.ErrRejected, notification.RecipientAddress)
+	}
+	secret := endpoint.Secret
+	if secret == "" {
+		secret = p.config.Secret
+	}
+
+	body, err := json.Marshal(Payload{
+		ID:        notification.ID,
+		EventID:   notification.EventID,
+		Priority:  string(notification.Priority),
+		Category:  notification.Category,
+		Subject:   notification.Subject,
+		Body:      notification.Body,
+		Data:      notification.Data,
+		TraceID:   notification.TraceID,
+		CreatedAt: notification.CreatedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	attempts := p.config.Retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			wait := p.backoff(attempt, lastErr)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		status, err := p.post(ctx, &endpoint, secret, notification.ID, body)
+		if err == nil {
+			return &notifications.DeliveryResult{
+				Status: notifications.StatusSent,
+				ProviderResponse: map[string]interface{}{
+					"status_code": status,
+					"attempts":    attempt + 1,
+				},
+			}, nil
+		}
+		if !isRetryable(err) {
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("webhook delivery failed after %d attempts: %w", attempts, lastErr)
+}
+
+// HealthCheck reports no error; endpoints belong to their receivers and are
+// checked by delivering to them
+func (p *Provider) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// retryAfterError is a transient failure, with the wait the endpoint asked
+// for, if any
+type retryAfterError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// isRetryable reports whether a failed post may succeed later
+func isRetryable(err error) bool {
+	var retryable *retryAfterError
+	return errors.As(err, &retryable)
+}
+
+// post signs and posts a payload, returning the status code of the endpoint.
+// Network errors, rate limits and server errors are retryable; other client
+// errors reject the notification.
+func (p *Provider) post(
+	ctx context.Context,
+	endpoint *notifications.WebhookEndpoint,
+	secret string,
+	deliveryID string,
+	body []byte,
+) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid webhook request: %v", notifications.ErrRejected, err)
+	}
+	for name, value := range endpoint.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DictaMesh-Webhook/1.0")
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(SignatureHeader, Sign(secret, time.Now(), body))
+	notifications.InjectTrace(ctx, req.Header)
+
+	switch p.config.Auth.Type {
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+p.config.Auth.Token)
+	case "apikey":
+		req.Header.Set("X-API-Key", p.config.Auth.Token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, &retryAfterError{err: fmt.Errorf("webhook request failed: %w", err)}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp.StatusCode, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 ||
+		resp.StatusCode == http.StatusRequestTimeout:
+		return resp.StatusCode, &retryAfterError{
+			err:        fmt.Errorf("webhook endpoint returned %d", resp.StatusCode),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	default:
+		return resp.StatusCode, fmt.Errorf("%w: webhook endpoint returned %d", notifications.ErrRejected, resp.StatusCode)
+	}
+}
+
+// backoff returns the wait before a retry, unless the endpoint asked for
+// longer
+func (p *Provider) backoff(attempt int, lastErr error) time.Duration {
+	wait := p.config.Retry.Backoff(attempt)
+
+	var retryable *retryAfterError
+	if errors.As(lastErr, &retryable) && retryable.retryAfter > wait {
+		wait = retryable.retryAfter
+	}
+	return wait
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Sign returns the signature header value of a payload. Receivers recompute
+// the HMAC-SHA256 of "<t>.<body>" with the endpoint secret, compare it in
+// constant time and reject stale timestamps.
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -5,6 +5,9 @@ package notifications
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
+	"net/url"
 	"time"
 )
 
@@ -19,6 +22,7 @@ type Config struct {
 	// Kafka configuration
 	KafkaBootstrapServers []string
 	KafkaConsumerGroup    string
+	KafkaEventTopics      []string // Topics of the events rules are matched against
 
 	// Channel configurations
 	Channels ChannelConfig
@@ -27,7 +31,16 @@ type Config struct {
 	Processing ProcessingConfig
 
 	// Rate limiting
-	RateLimits RateLimitConfig
+	RateLimits RateLimitConfig `reload:"true"`
+
+	// HTTP API
+	API APIConfig
+
+	// Suppression lists
+	Suppression SuppressionConfig
+
+	// Archival and deletion of old notifications
+	Retention RetentionConfig
 
 	// Observability
 	Observability ObservabilityConfig
@@ -48,7 +61,7 @@ type ChannelConfig struct {
 // EmailConfig configures email delivery
 type EmailConfig struct {
 	Enabled  bool
-	Provider string // smtp | ses | sendgrid | mailgun
+	Provider string // smtp | ses | sendgrid
 
 	// SMTP configuration
 	SMTP SMTPConfig
@@ -64,6 +77,7 @@ type EmailConfig struct {
 	ReplyTo         string
 	MaxAttachments  int
 	MaxAttachmentMB int
+	Timeout         time.Duration // HTTP timeout of API providers
 
 	// Rate limiting
 	RateLimit RateLimitDefinition
@@ -76,6 +90,11 @@ type SMTPConfig struct {
 	Username string
 	Password string
 	UseTLS   bool
+
+	// Connection pool
+	MaxConnections int           // Connections kept open to the server
+	IdleTimeout    time.Duration // Idle connections older than this are closed
+	Timeout        time.Duration // Dial and command timeout
 }
 
 // SESConfig configures AWS SES
@@ -84,17 +103,24 @@ type SESConfig struct {
 	AccessKeyID     string
 	SecretAccessKey string
 	ConfigurationSet string
+
+	// SNS topic the bounce and complaint notifications are published to;
+	// notifications of other topics are ignored when set
+	FeedbackTopicARN string
 }
 
 // SendGridConfig configures SendGrid
 type SendGridConfig struct {
 	APIKey string
+
+	// Base64 public key verifying the signed event webhook (optional)
+	WebhookVerificationKey string
 }
 
 // SMSConfig configures SMS delivery
 type SMSConfig struct {
 	Enabled  bool
-	Provider string // twilio | sns | messagebird
+	Provider string // twilio | sns
 
 	// Twilio configuration
 	Twilio TwilioConfig
@@ -104,7 +130,12 @@ type SMSConfig struct {
 
 	// Common settings
 	From      string
-	MaxLength int
+	MaxLength int           // Longer messages are truncated (0 for no limit)
+	Timeout   time.Duration // HTTP timeout of the provider API
+
+	// Sender IDs by country calling code, e.g. "+44": "DictaMesh", used
+	// instead of From for numbers with that prefix. The longest prefix wins.
+	SenderIDs map[string]string
 
 	// Rate limiting
 	RateLimit RateLimitDefinition
@@ -115,6 +146,10 @@ type TwilioConfig struct {
 	AccountSID string
 	AuthToken  string
 	FromNumber string
+
+	// Public URL of the delivery receipt webhook; Twilio reports message
+	// status changes to it and signs them for this URL
+	StatusCallbackURL string
 }
 
 // SNSConfig configures AWS SNS
@@ -122,6 +157,7 @@ type SNSConfig struct {
 	Region          string
 	AccessKeyID     string
 	SecretAccessKey string
+	SMSType         string // Transactional | Promotional
 }
 
 // PushConfig configures push notifications
@@ -137,6 +173,10 @@ type PushConfig struct {
 	// Web Push
 	WebPush WebPushConfig
 
+	// Delivery
+	MaxConcurrency int           // Devices of a recipient sent to in parallel
+	Timeout        time.Duration // HTTP timeout of FCM and APNs requests
+
 	// Rate limiting
 	RateLimit RateLimitDefinition
 }
@@ -144,16 +184,19 @@ type PushConfig struct {
 // FCMConfig configures Firebase Cloud Messaging
 type FCMConfig struct {
 	Enabled         bool
-	CredentialsFile string
-	ProjectID       string
+	CredentialsFile string // Service account JSON key
+	ProjectID       string // Defaults to the project of the service account
 	Priority        string // high | normal
 }
 
-// APNsConfig configures Apple Push Notification Service
+// APNsConfig configures Apple Push Notification Service. Token based
+// authentication uses AuthKeyFile, KeyID and TeamID; otherwise
+// CertificateFile holds a PEM certificate and its key.
 type APNsConfig struct {
 	Enabled             bool
 	CertificateFile     string
 	CertificatePassword string
+	AuthKeyFile         string // .p8 signing key
 	KeyID               string
 	TeamID              string
 	BundleID            string
@@ -183,6 +226,13 @@ type SlackConfig struct {
 	Username       string
 	IconEmoji      string
 
+	// Block Kit templates by notification template ID: text/template
+	// sources rendering a JSON array of blocks
+	BlockTemplates map[string]string
+
+	// Request timeout
+	Timeout time.Duration
+
 	// Rate limiting
 	RateLimit RateLimitDefinition
 }
@@ -200,13 +250,26 @@ type WebhookConfig struct {
 	// Authentication
 	Auth WebhookAuthConfig
 
+	// Endpoints by name; notifications address an endpoint by its name
+	Endpoints map[string]WebhookEndpoint
+
+	// Signing secret of endpoints without their own
+	Secret string
+
 	// Rate limiting
 	RateLimit RateLimitDefinition
 }
 
+// WebhookEndpoint configures a webhook receiver
+type WebhookEndpoint struct {
+	URL     string
+	Secret  string // HMAC-SHA256 signing secret
+	Headers map[string]string
+}
+
 // WebhookAuthConfig configures webhook authentication
 type WebhookAuthConfig struct {
-	Type  string // none | bearer | apikey | oauth2
+	Type  string // none | bearer | apikey
 	Token string
 }
 
@@ -214,8 +277,8 @@ type WebhookAuthConfig struct {
 type InAppConfig struct {
 	Enabled bool
 
-	// Transport mechanism
-	Transport string // websocket | sse | longpoll
+	// Transport mechanism of the live inbox stream
+	Transport string // sse
 
 	// Persistence settings
 	PersistenceDays int
@@ -223,7 +286,7 @@ type InAppConfig struct {
 
 	// WebSocket settings
 	WebSocketPath     string
-	WebSocketPingTime time.Duration
+	WebSocketPingTime time.Duration // Also the keep-alive interval of SSE streams
 }
 
 // BrowserPushConfig configures browser push notifications
@@ -241,11 +304,20 @@ type PagerDutyConfig struct {
 	Enabled bool
 
 	// API configuration
-	APIKey         string
-	IntegrationKey string
+	APIKey         string // REST API key, used for health checks
+	IntegrationKey string // Events v2 routing key of the default service
+
+	// Events v2 routing keys by escalation policy name. Rules name the
+	// policy of their incidents; each key routes to a service escalating
+	// through that policy.
+	EscalationPolicies map[string]string
 
 	// Default settings
-	DefaultSeverity string // critical | error | warning | info
+	DefaultSeverity string // critical | error | warning | info, for notifications without a priority
+	Source          string // Payload source of incidents
+
+	// Request timeout
+	Timeout time.Duration
 }
 
 // ProcessingConfig configures notification processing
@@ -257,6 +329,10 @@ type ProcessingConfig struct {
 	QueueBufferSize int
 	QueueTimeout    time.Duration
 
+	// Dispatch settings
+	PollInterval time.Duration // How often due notifications are claimed
+	StaleAfter   time.Duration // Claimed notifications untouched this long are claimed again
+
 	// Batch processing
 	BatchEnabled      bool
 	BatchMaxSize      int
@@ -269,6 +345,12 @@ type ProcessingConfig struct {
 	// Template rendering
 	TemplateTimeout time.Duration
 	TemplateCaching bool
+
+	// How often rules are reloaded from the database
+	RuleRefreshInterval time.Duration
+
+	// How often due rule schedules are fired
+	ScheduleInterval time.Duration
 }
 
 // RetryConfig configures retry behavior
@@ -290,8 +372,17 @@ type RateLimitConfig struct {
 	// System-wide rate limits
 	SystemLimits map[Channel]RateLimitDefinition
 
-	// Category rate limits
+	// Category rate limits, per recipient on each channel
 	CategoryLimits map[string]RateLimitDefinition
+
+	// What happens to notifications over a limit
+	Overflow OverflowAction
+
+	// Deliver notifications when Redis is unavailable
+	FailOpen bool
+
+	// How often limits of the rate limits table are reloaded
+	RefreshInterval time.Duration
 }
 
 // RateLimitDefinition defines a rate limit
@@ -300,6 +391,87 @@ type RateLimitDefinition struct {
 	Duration time.Duration
 }
 
+// Unlimited reports whether the definition sets no limit
+func (d RateLimitDefinition) Unlimited() bool {
+	return d.Count <= 0 || d.Duration <= 0
+}
+
+// APIConfig configures the HTTP API
+type APIConfig struct {
+	Enabled       bool
+	ListenAddress string
+
+	// Bearer tokens accepted by the API
+	APIKeys []string
+
+	// Notifications accepted per bulk request
+	MaxBulkSize int
+
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// SuppressionConfig configures the suppression lists
+type SuppressionConfig struct {
+	Enabled bool
+
+	// Suppress addresses after hard bounces and complaints reported by
+	// provider feedback webhooks
+	FromFeedback bool
+
+	// URL of the unsubscribe handler, linked from emails, and the secret
+	// signing its tokens
+	UnsubscribeURL    string
+	UnsubscribeSecret string
+}
+
+// Retention strategies
+const (
+	RetentionDelete     = "delete"     // Delete archived notifications in batches
+	RetentionPartitions = "partitions" // Drop the partitions of archived notifications
+)
+
+// RetentionConfig configures the archival and deletion of notifications,
+// with their delivery attempts and audit entries
+type RetentionConfig struct {
+	Enabled bool
+
+	// Age after which notifications are archived and deleted
+	MaxAge time.Duration
+
+	// delete | partitions
+	Strategy string
+
+	// Rows archived and deleted per batch
+	BatchSize int
+
+	// How often the retention worker runs
+	Interval time.Duration
+
+	// Monthly partitions of the notifications table created in advance
+	PartitionsAhead int
+
+	// Cold storage of archived rows; without it rows are deleted unarchived
+	Archive ArchiveConfig
+}
+
+// ArchiveConfig configures the S3 bucket archived rows are written to
+type ArchiveConfig struct {
+	Enabled bool
+
+	S3Bucket string
+	S3Prefix string
+	S3Region string
+
+	// Endpoint of S3-compatible storage, addressed path-style
+	S3Endpoint string
+
+	// Without an access key, the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+	// and AWS_SESSION_TOKEN environment variables are used
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
 // ObservabilityConfig configures observability
 type ObservabilityConfig struct {
 	// Metrics
@@ -312,8 +484,27 @@ type ObservabilityConfig struct {
 	TracingSampler  float64
 
 	// Logging
-	LogLevel  string // debug | info | warn | error
-	LogFormat string // json | text
+	LogLevel  string `reload:"true"` // debug | info | warn | error
+	LogFormat string                 // json | text
+}
+
+// Backoff returns the wait before retry attempt, counted from 1: the
+// interval grows by Multiplier per attempt up to MaxInterval, with jitter
+// within half the interval either way
+func (c RetryConfig) Backoff(attempt int) time.Duration {
+	multiplier := c.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	wait := time.Duration(float64(c.InitialInterval) * math.Pow(multiplier, float64(attempt-1)))
+	if c.MaxInterval > 0 && wait > c.MaxInterval {
+		wait = c.MaxInterval
+	}
+	if c.Jitter && wait > 0 {
+		wait = time.Duration(rand.Int63n(int64(wait))) + wait/2
+	}
+	return wait
 }
 
 // Validate validates the configuration
@@ -344,6 +535,313 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("at least one notification channel must be enabled")
 	}
 
+	if c.Channels.Email.Enabled {
+		if err := c.Channels.Email.Validate(); err != nil {
+			return fmt.Errorf("invalid email channel: %w", err)
+		}
+	}
+
+	if c.Channels.SMS.Enabled {
+		if err := c.Channels.SMS.Validate(); err != nil {
+			return fmt.Errorf("invalid SMS channel: %w", err)
+		}
+	}
+
+	if c.Channels.Push.Enabled {
+		if err := c.Channels.Push.Validate(); err != nil {
+			return fmt.Errorf("invalid push channel: %w", err)
+		}
+	}
+
+	if c.Channels.Slack.Enabled {
+		if err := c.Channels.Slack.Validate(); err != nil {
+			return fmt.Errorf("invalid Slack channel: %w", err)
+		}
+	}
+
+	if c.Channels.Webhook.Enabled {
+		if err := c.Channels.Webhook.Validate(); err != nil {
+			return fmt.Errorf("invalid webhook channel: %w", err)
+		}
+	}
+
+	if c.Channels.PagerDuty.Enabled {
+		if err := c.Channels.PagerDuty.Validate(); err != nil {
+			return fmt.Errorf("invalid PagerDuty channel: %w", err)
+		}
+	}
+
+	if c.Channels.InApp.Enabled {
+		if c.RedisURL == "" {
+			return fmt.Errorf("Redis URL is required for the in-app channel")
+		}
+		if err := c.Channels.InApp.Validate(); err != nil {
+			return fmt.Errorf("invalid in-app channel: %w", err)
+		}
+	}
+
+	if c.API.Enabled {
+		if err := c.API.Validate(); err != nil {
+			return fmt.Errorf("invalid API: %w", err)
+		}
+	}
+
+	if c.Suppression.Enabled {
+		if err := c.Suppression.Validate(); err != nil {
+			return fmt.Errorf("invalid suppression: %w", err)
+		}
+	}
+
+	if c.Retention.Enabled {
+		if err := c.Retention.Validate(); err != nil {
+			return fmt.Errorf("invalid retention: %w", err)
+		}
+	}
+
+	if c.RateLimits.Enabled {
+		if c.RedisURL == "" {
+			return fmt.Errorf("Redis URL is required for rate limiting")
+		}
+		if err := c.RateLimits.Validate(); err != nil {
+			return fmt.Errorf("invalid rate limits: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the in-app channel configuration
+func (c *InAppConfig) Validate() error {
+	switch c.Transport {
+	case "", "sse":
+	default:
+		return fmt.Errorf("unsupported in-app transport: %s", c.Transport)
+	}
+	if c.PersistenceDays < 0 {
+		return fmt.Errorf("persistence days must not be negative")
+	}
+	return nil
+}
+
+// Validate validates the API configuration
+func (c *APIConfig) Validate() error {
+	if c.ListenAddress == "" {
+		return fmt.Errorf("listen address is required")
+	}
+	if len(c.APIKeys) == 0 {
+		return fmt.Errorf("at least one API key is required")
+	}
+	for _, key := range c.APIKeys {
+		if len(key) < 16 {
+			return fmt.Errorf("API keys must be at least 16 characters")
+		}
+	}
+	if c.MaxBulkSize < 1 {
+		return fmt.Errorf("max bulk size must be at least 1")
+	}
+	return nil
+}
+
+// Validate validates the retention configuration
+func (c *RetentionConfig) Validate() error {
+	if c.MaxAge < 24*time.Hour {
+		return fmt.Errorf("max age must be at least a day")
+	}
+	switch c.Strategy {
+	case RetentionDelete, RetentionPartitions:
+	default:
+		return fmt.Errorf("unsupported strategy: %s", c.Strategy)
+	}
+	if c.BatchSize < 1 {
+		return fmt.Errorf("batch size must be positive")
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if c.PartitionsAhead < 0 {
+		return fmt.Errorf("partitions ahead must not be negative")
+	}
+	if c.Archive.Enabled {
+		if c.Archive.S3Bucket == "" {
+			return fmt.Errorf("archive S3 bucket is required")
+		}
+		if c.Archive.S3Region == "" {
+			return fmt.Errorf("archive S3 region is required")
+		}
+		if c.Archive.S3Endpoint != "" {
+			if _, err := url.ParseRequestURI(c.Archive.S3Endpoint); err != nil {
+				return fmt.Errorf("invalid archive S3 endpoint: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate validates the suppression configuration
+func (c *SuppressionConfig) Validate() error {
+	if c.UnsubscribeURL == "" {
+		return nil
+	}
+	if _, err := url.ParseRequestURI(c.UnsubscribeURL); err != nil {
+		return fmt.Errorf("invalid unsubscribe URL: %w", err)
+	}
+	if len(c.UnsubscribeSecret) < 32 {
+		return fmt.Errorf("unsubscribe secret must be at least 32 characters")
+	}
+	return nil
+}
+
+// Validate validates the rate limit configuration
+func (c *RateLimitConfig) Validate() error {
+	switch c.Overflow {
+	case "", OverflowDrop, OverflowDefer, OverflowDowngrade:
+	default:
+		return fmt.Errorf("unsupported overflow action: %s", c.Overflow)
+	}
+	for _, limits := range []map[Channel]RateLimitDefinition{c.UserLimits, c.SystemLimits} {
+		for channel, limit := range limits {
+			if limit.Count < 0 || limit.Duration < 0 {
+				return fmt.Errorf("negative rate limit for channel %s", channel)
+			}
+		}
+	}
+	for category, limit := range c.CategoryLimits {
+		if limit.Count < 0 || limit.Duration < 0 {
+			return fmt.Errorf("negative rate limit for category %s", category)
+		}
+	}
+	return nil
+}
+
+// Validate validates the email channel configuration
+func (c *EmailConfig) Validate() error {
+	if c.From == "" {
+		return fmt.Errorf("from address is required")
+	}
+
+	switch c.Provider {
+	case "smtp":
+		if c.SMTP.Host == "" {
+			return fmt.Errorf("SMTP host is required")
+		}
+		if c.SMTP.MaxConnections < 1 {
+			return fmt.Errorf("SMTP max connections must be at least 1")
+		}
+	case "ses":
+		if c.SES.Region == "" {
+			return fmt.Errorf("SES region is required")
+		}
+	case "sendgrid":
+		if c.SendGrid.APIKey == "" {
+			return fmt.Errorf("SendGrid API key is required")
+		}
+	default:
+		return fmt.Errorf("unsupported email provider: %s", c.Provider)
+	}
+
+	return nil
+}
+
+// Validate validates the SMS channel configuration
+func (c *SMSConfig) Validate() error {
+	switch c.Provider {
+	case "twilio":
+		if c.Twilio.AccountSID == "" || c.Twilio.AuthToken == "" {
+			return fmt.Errorf("Twilio account SID and auth token are required")
+		}
+		if c.Twilio.FromNumber == "" && c.From == "" && len(c.SenderIDs) == 0 {
+			return fmt.Errorf("a sender number or ID is required")
+		}
+	case "sns":
+		if c.SNS.Region == "" {
+			return fmt.Errorf("SNS region is required")
+		}
+	default:
+		return fmt.Errorf("unsupported SMS provider: %s", c.Provider)
+	}
+
+	return nil
+}
+
+// Validate validates the push channel configuration
+func (c *PushConfig) Validate() error {
+	if !c.FCM.Enabled && !c.APNs.Enabled {
+		return fmt.Errorf("FCM or APNs must be enabled")
+	}
+	if c.FCM.Enabled && c.FCM.CredentialsFile == "" {
+		return fmt.Errorf("FCM credentials file is required")
+	}
+	if c.APNs.Enabled {
+		if c.APNs.BundleID == "" {
+			return fmt.Errorf("APNs bundle ID is required")
+		}
+		tokenAuth := c.APNs.AuthKeyFile != "" && c.APNs.KeyID != "" && c.APNs.TeamID != ""
+		if !tokenAuth && c.APNs.CertificateFile == "" {
+			return fmt.Errorf("APNs auth key, key ID and team ID, or a certificate, are required")
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the Slack configuration
+func (c *SlackConfig) Validate() error {
+	if c.WebhookURL == "" && c.BotToken == "" {
+		return fmt.Errorf("Slack webhook URL or bot token is required")
+	}
+	if c.WebhookURL != "" {
+		if _, err := url.ParseRequestURI(c.WebhookURL); err != nil {
+			return fmt.Errorf("invalid Slack webhook URL: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the PagerDuty configuration
+func (c *PagerDutyConfig) Validate() error {
+	if c.IntegrationKey == "" {
+		return fmt.Errorf("PagerDuty integration key is required")
+	}
+	for name, key := range c.EscalationPolicies {
+		if key == "" {
+			return fmt.Errorf("routing key is required for escalation policy %s", name)
+		}
+	}
+
+	switch c.DefaultSeverity {
+	case "", "critical", "error", "warning", "info":
+	default:
+		return fmt.Errorf("unsupported PagerDuty severity: %s", c.DefaultSeverity)
+	}
+
+	return nil
+}
+
+// Validate validates the webhook configuration
+func (c *WebhookConfig) Validate() error {
+	if len(c.Endpoints) == 0 {
+		return fmt.Errorf("at least one webhook endpoint is required")
+	}
+	for name, endpoint := range c.Endpoints {
+		if _, err := url.ParseRequestURI(endpoint.URL); err != nil {
+			return fmt.Errorf("invalid URL for webhook endpoint %s: %w", name, err)
+		}
+		if endpoint.Secret == "" && c.Secret == "" {
+			return fmt.Errorf("signing secret is required for webhook endpoint %s", name)
+		}
+	}
+
+	switch c.Auth.Type {
+	case "", "none":
+	case "bearer", "apikey":
+		if c.Auth.Token == "" {
+			return fmt.Errorf("webhook auth token is required")
+		}
+	default:
+		return fmt.Errorf("unsupported webhook auth type: %s", c.Auth.Type)
+	}
+
 	return nil
 }
 
@@ -351,16 +849,73 @@ func (c *Config) Validate() error {
 func DefaultConfig() *Config {
 	return &Config{
 		KafkaConsumerGroup: "dictamesh-notifications",
+		Channels: ChannelConfig{
+			Email: EmailConfig{
+				Provider: "smtp",
+				SMTP: SMTPConfig{
+					Port:           587,
+					UseTLS:         true,
+					MaxConnections: 4,
+					IdleTimeout:    1 * time.Minute,
+					Timeout:        30 * time.Second,
+				},
+				Timeout: 30 * time.Second,
+			},
+			Push: PushConfig{
+				MaxConcurrency: 16,
+				Timeout:        30 * time.Second,
+				FCM: FCMConfig{
+					Priority: "high",
+				},
+			},
+			SMS: SMSConfig{
+				Provider:  "twilio",
+				MaxLength: 1600,
+				Timeout:   30 * time.Second,
+				SNS: SNSConfig{
+					SMSType: "Transactional",
+				},
+			},
+			Slack: SlackConfig{
+				Timeout: 10 * time.Second,
+			},
+			PagerDuty: PagerDutyConfig{
+				DefaultSeverity: "warning",
+				Source:          "dictamesh",
+				Timeout:         10 * time.Second,
+			},
+			InApp: InAppConfig{
+				Transport:         "sse",
+				PersistenceDays:   90,
+				WebSocketPingTime: 30 * time.Second,
+			},
+			Webhook: WebhookConfig{
+				Timeout: 10 * time.Second,
+				Retry: RetryConfig{
+					MaxAttempts:     4,
+					InitialInterval: 1 * time.Second,
+					MaxInterval:     30 * time.Second,
+					Multiplier:      2.0,
+					Jitter:          true,
+				},
+			},
+		},
 		Processing: ProcessingConfig{
 			WorkerCount:       10,
 			QueueBufferSize:   1000,
 			QueueTimeout:      30 * time.Second,
+			PollInterval:      1 * time.Second,
+			StaleAfter:        5 * time.Minute,
 			BatchEnabled:      true,
 			BatchMaxSize:      100,
 			BatchMaxWait:      5 * time.Minute,
 			BatchFlushTicker:  1 * time.Minute,
 			TemplateTimeout:   5 * time.Second,
 			TemplateCaching:   true,
+
+			RuleRefreshInterval: 1 * time.Minute,
+			ScheduleInterval:    15 * time.Second,
+
 			Retry: RetryConfig{
 				MaxAttempts:     3,
 				InitialInterval: 1 * time.Second,
@@ -369,8 +924,27 @@ func DefaultConfig() *Config {
 				Jitter:          true,
 			},
 		},
+		Suppression: SuppressionConfig{
+			Enabled:      true,
+			FromFeedback: true,
+		},
+		Retention: RetentionConfig{
+			MaxAge:          90 * 24 * time.Hour,
+			Strategy:        RetentionDelete,
+			BatchSize:       1000,
+			Interval:        1 * time.Hour,
+			PartitionsAhead: 3,
+			Archive: ArchiveConfig{
+				Enabled:  true,
+				S3Prefix: "notifications",
+				S3Region: "us-east-1",
+			},
+		},
 		RateLimits: RateLimitConfig{
-			Enabled: true,
+			Enabled:         true,
+			Overflow:        OverflowDefer,
+			FailOpen:        true,
+			RefreshInterval: 1 * time.Minute,
 			UserLimits: map[Channel]RateLimitDefinition{
 				ChannelEmail: {Count: 100, Duration: 1 * time.Hour},
 				ChannelSMS:   {Count: 10, Duration: 1 * time.Hour},
@@ -382,6 +956,12 @@ func DefaultConfig() *Config {
 				ChannelPush:  {Count: 50000, Duration: 1 * time.Hour},
 			},
 		},
+		API: APIConfig{
+			ListenAddress: ":8080",
+			MaxBulkSize:   1000,
+			ReadTimeout:   30 * time.Second,
+			WriteTimeout:  30 * time.Second,
+		},
 		Observability: ObservabilityConfig{
 			MetricsEnabled:  true,
 			MetricsPort:     9090,
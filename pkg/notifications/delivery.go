@@ -0,0 +1,363 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications/models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrRejected is wrapped by provider errors that retrying cannot fix, such as
+// an invalid recipient address
+var ErrRejected = errors.New("rejected by provider")
+
+// DeliveryProvider delivers notifications of a channel through an external
+// provider
+type DeliveryProvider interface {
+	// Name identifies the provider in delivery attempts, e.g. "ses"
+	Name() string
+	GetChannel() Channel
+
+	// Send delivers a notification to its recipient address
+	Send(ctx context.Context, notification *Notification) (*DeliveryResult, error)
+
+	HealthCheck(ctx context.Context) error
+}
+
+// DeliveryResult is the outcome of a notification handed to a provider
+type DeliveryResult struct {
+	Status            Status // StatusSent, or StatusDelivered for synchronous channels
+	ProviderMessageID string
+	ProviderResponse  map[string]interface{}
+}
+
+// DeliveryEventType is the kind of feedback a provider reports for a sent
+// message
+type DeliveryEventType string
+
+const (
+	DeliveryEventDelivered  DeliveryEventType = "DELIVERED"
+	DeliveryEventBounced    DeliveryEventType = "BOUNCED"
+	DeliveryEventComplained DeliveryEventType = "COMPLAINED"
+)
+
+// DeliveryEvent is feedback about a sent message, received from a provider
+// webhook
+type DeliveryEvent struct {
+	Type              DeliveryEventType
+	ProviderMessageID string
+	Recipient         string
+	Permanent         bool // Hard bounce; the address will not accept mail
+	Reason            string
+	OccurredAt        time.Time
+
+	// Metadata merged into the metadata of the delivery attempt, such as
+	// the cost of the message
+	Metadata map[string]interface{}
+}
+
+// FeedbackReceiver is implemented by providers that report deliveries,
+// bounces and complaints through webhooks
+type FeedbackReceiver interface {
+	// ParseFeedback verifies and parses a webhook request. Requests carrying
+	// no feedback, such as subscription confirmations, return no events.
+	ParseFeedback(r *http.Request) ([]DeliveryEvent, error)
+}
+
+// DeliveryTracker delivers notifications through the provider of their
+// channel and records every attempt and its feedback in the delivery table
+type DeliveryTracker struct {
+	db           *gorm.DB
+	config       *Config
+	logger       *zap.Logger
+	providers    map[Channel]DeliveryProvider
+	suppressions *SuppressionList
+}
+
+// NewDeliveryTracker creates a delivery tracker for providers, one per channel
+func NewDeliveryTracker(
+	db *gorm.DB,
+	config *Config,
+	logger *zap.Logger,
+	providers ...DeliveryProvider,
+) *DeliveryTracker {
+	byChannel := make(map[Channel]DeliveryProvider, len(providers))
+	for _, provider := range providers {
+		byChannel[provider.GetChannel()] = provider
+	}
+
+	return &DeliveryTracker{
+		db:           db,
+		config:       config,
+		logger:       logger,
+		providers:    byChannel,
+		suppressions: NewSuppressionList(db, config, logger),
+	}
+}
+
+// Suppressions returns the suppression list checked before delivery
+func (t *DeliveryTracker) Suppressions() *SuppressionList {
+	return t.suppressions
+}
+
+// Deliver sends a notification over its selected channel as attempt number
+// attempt. Errors wrapping ErrRejected should not be retried. The provider
+// call runs in a client span, which providers propagate with InjectTrace.
+func (t *DeliveryTracker) Deliver(
+	ctx context.Context,
+	notification *Notification,
+	attempt int,
+) (*DeliveryResult, error) {
+	provider, ok := t.providers[notification.SelectedChannel]
+	if !ok {
+		return nil, fmt.Errorf("no delivery provider for channel %s", notification.SelectedChannel)
+	}
+	notificationID, err := uuid.Parse(notification.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification ID: %w", err)
+	}
+
+	delivery := &models.DeliveryModel{
+		ID:             uuid.New(),
+		NotificationID: notificationID,
+		Channel:        string(notification.SelectedChannel),
+		Provider:       provider.Name(),
+		Status:         string(StatusSending),
+		AttemptNumber:  attempt,
+		StartedAt:      time.Now(),
+	}
+	if err := t.db.WithContext(ctx).Create(delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to record delivery attempt: %w", err)
+	}
+
+	sendCtx, span := tracer.Start(ctx, "notifications.deliver",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("notification.id", notification.ID),
+			attribute.String("notification.channel", string(notification.SelectedChannel)),
+			attribute.String("notification.provider", provider.Name()),
+			attribute.Int("notification.attempt", attempt),
+		))
+	result, sendErr := provider.Send(sendCtx, notification)
+	if sendErr != nil {
+		recordError(sendCtx, sendErr)
+	} else {
+		span.SetAttributes(attribute.String("notification.provider_message_id", result.ProviderMessageID))
+	}
+	span.End()
+
+	updates := map[string]interface{}{"completed_at": time.Now()}
+	if sendErr != nil {
+		status := StatusFailed
+		if errors.Is(sendErr, ErrRejected) {
+			status = StatusRejected
+		}
+		updates["status"] = status
+		updates["error"] = sendErr.Error()
+	} else {
+		updates["status"] = result.Status
+		updates["success"] = true
+		updates["provider_message_id"] = result.ProviderMessageID
+		updates["provider_response"] = models.JSONB(result.ProviderResponse)
+	}
+
+	// The message may be out already; a failed update must not cause a resend
+	if err := t.db.WithContext(ctx).Model(delivery).Updates(updates).Error; err != nil {
+		t.logger.Error("failed to record delivery result",
+			zap.String("notification_id", notification.ID),
+			zap.String("provider", provider.Name()),
+			zap.Error(err))
+	}
+
+	if sendErr != nil {
+		return nil, fmt.Errorf("%s delivery failed: %w", provider.Name(), sendErr)
+	}
+	return result, nil
+}
+
+// Attempts returns the delivery attempts of a notification, oldest first
+func (t *DeliveryTracker) Attempts(ctx context.Context, notificationID string) ([]DeliveryAttempt, error) {
+	id, err := uuid.Parse(notificationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification ID: %w", err)
+	}
+
+	var deliveries []models.DeliveryModel
+	err = t.db.WithContext(ctx).
+		Where("notification_id = ?", id).
+		Order("attempt_number, started_at").
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch delivery attempts: %w", err)
+	}
+
+	attempts := make([]DeliveryAttempt, len(deliveries))
+	for i, delivery := range deliveries {
+		attempts[i] = DeliveryAttempt{
+			ID:                delivery.ID.String(),
+			NotificationID:    delivery.NotificationID.String(),
+			Channel:           Channel(delivery.Channel),
+			Provider:          delivery.Provider,
+			Status:            Status(delivery.Status),
+			AttemptNumber:     delivery.AttemptNumber,
+			StartedAt:         delivery.StartedAt,
+			CompletedAt:       delivery.CompletedAt,
+			Success:           delivery.Success,
+			Error:             delivery.Error,
+			ProviderResponse:  delivery.ProviderResponse,
+			ProviderMessageID: delivery.ProviderMessageID,
+			Metadata:          delivery.Metadata,
+		}
+	}
+	return attempts, nil
+}
+
+// ApplyFeedback records provider feedback on the delivery attempts of the
+// messages it concerns. Feedback for unknown messages is ignored.
+func (t *DeliveryTracker) ApplyFeedback(ctx context.Context, provider string, events []DeliveryEvent) error {
+	for _, event := range events {
+		if err := t.applyEvent(ctx, provider, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyEvent records a single feedback event
+func (t *DeliveryTracker) applyEvent(ctx context.Context, provider string, event DeliveryEvent) error {
+	var delivery models.DeliveryModel
+	err := t.db.WithContext(ctx).
+		Where("provider = ? AND provider_message_id = ?", provider, event.ProviderMessageID).
+		Order("started_at DESC").
+		First(&delivery).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		t.logger.Debug("feedback for unknown message",
+			zap.String("provider", provider),
+			zap.String("provider_message_id", event.ProviderMessageID))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch delivery attempt: %w", err)
+	}
+
+	metadata := delivery.Metadata
+	if metadata == nil {
+		metadata = models.JSONB{}
+	}
+	for key, value := range event.Metadata {
+		metadata[key] = value
+	}
+	updates := map[string]interface{}{}
+
+	switch event.Type {
+	case DeliveryEventDelivered:
+		// A late delivery report does not override a bounce
+		if delivery.Status == string(StatusSent) {
+			updates["status"] = StatusDelivered
+		}
+		metadata["delivered_at"] = event.OccurredAt
+	case DeliveryEventBounced:
+		updates["status"] = StatusBounced
+		updates["success"] = false
+		updates["error"] = event.Reason
+		metadata["bounced_at"] = event.OccurredAt
+		metadata["bounce_permanent"] = event.Permanent
+	case DeliveryEventComplained:
+		metadata["complained_at"] = event.OccurredAt
+		metadata["complaint"] = event.Reason
+	default:
+		return fmt.Errorf("unknown delivery event type: %s", event.Type)
+	}
+	updates["metadata"] = metadata
+
+	if err := t.db.WithContext(ctx).Model(&delivery).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update delivery attempt: %w", err)
+	}
+
+	if reason, ok := suppresses(event); ok {
+		return t.suppress(ctx, provider, &delivery, event.Recipient, reason)
+	}
+	return nil
+}
+
+// suppresses returns the reason an event means nothing more should be sent
+// to its recipient: a hard bounce or a complaint
+func suppresses(event DeliveryEvent) (SuppressionReason, bool) {
+	switch {
+	case event.Type == DeliveryEventComplained:
+		return SuppressionComplaint, true
+	case event.Type == DeliveryEventBounced && event.Permanent:
+		return SuppressionHardBounce, true
+	default:
+		return "", false
+	}
+}
+
+// suppress adds the recipient of a delivery to the suppression list of its
+// channel, with Suppression.FromFeedback
+func (t *DeliveryTracker) suppress(
+	ctx context.Context,
+	provider string,
+	delivery *models.DeliveryModel,
+	address string,
+	reason SuppressionReason,
+) error {
+	if !t.config.Suppression.Enabled || !t.config.Suppression.FromFeedback || address == "" {
+		return nil
+	}
+
+	return t.suppressions.Add(ctx, &Suppression{
+		Channel:        Channel(delivery.Channel),
+		Address:        address,
+		Reason:         reason,
+		Source:         provider,
+		NotificationID: delivery.NotificationID.String(),
+	}, AuditActorProvider)
+}
+
+// WebhookHandler returns a handler receiving the feedback webhooks of a
+// provider implementing FeedbackReceiver
+func (t *DeliveryTracker) WebhookHandler(provider DeliveryProvider) (http.Handler, error) {
+	receiver, ok := provider.(FeedbackReceiver)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not receive feedback", provider.Name())
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		events, err := receiver.ParseFeedback(r)
+		if err != nil {
+			t.logger.Warn("invalid feedback webhook",
+				zap.String("provider", provider.Name()),
+				zap.Error(err))
+			http.Error(w, "invalid webhook", http.StatusBadRequest)
+			return
+		}
+
+		// Failing makes the provider deliver the webhook again
+		if err := t.ApplyFeedback(r.Context(), provider.Name(), events); err != nil {
+			t.logger.Error("failed to apply feedback",
+				zap.String("provider", provider.Name()),
+				zap.Error(err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}), nil
+}
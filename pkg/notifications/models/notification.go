@@ -20,6 +20,10 @@ type NotificationModel struct {
 	RuleID     *uuid.UUID `gorm:"type:uuid"`
 	TemplateID *uuid.UUID `gorm:"type:uuid"`
 
+	// Rendering
+	TemplateRevision *int   `gorm:"type:integer"`
+	Locale           string `gorm:"type:varchar(10)"`
+
 	// Recipient information
 	RecipientType string `gorm:"type:varchar(50);not null;index:idx_recipient"`
 	RecipientID   string `gorm:"type:varchar(255);not null;index:idx_recipient"`
@@ -34,6 +38,7 @@ type NotificationModel struct {
 	Priority        string        `gorm:"type:varchar(20);not null"`
 	Channels        StringArray   `gorm:"type:text[]"`
 	SelectedChannel string        `gorm:"type:varchar(50)"`
+	Category        string        `gorm:"type:varchar(100)"`
 
 	// Status tracking
 	Status string `gorm:"type:varchar(20);not null;default:'pending';index:idx_status"`
@@ -47,6 +52,7 @@ type NotificationModel struct {
 	// Metadata
 	Metadata JSONB  `gorm:"type:jsonb"`
 	TraceID  string `gorm:"type:varchar(64);index"`
+	SpanID   string `gorm:"type:varchar(16)"`
 
 	// Error tracking
 	Error       string     `gorm:"type:text"`
@@ -78,6 +84,7 @@ type TemplateModel struct {
 
 	// Lifecycle
 	Version   string    `gorm:"type:varchar(50);default:'1.0.0'"`
+	Revision  int       `gorm:"not null;default:0"` // Latest TemplateRevisionModel
 	Enabled   bool      `gorm:"default:true"`
 	CreatedAt time.Time `gorm:"not null;default:now()"`
 	UpdatedAt time.Time `gorm:"not null;default:now()"`
@@ -92,6 +99,27 @@ func (TemplateModel) TableName() string {
 	return "dictamesh_notification_templates"
 }
 
+// TemplateRevisionModel represents an immutable revision of a notification
+// template
+type TemplateRevisionModel struct {
+	TemplateID uuid.UUID `gorm:"type:uuid;primary_key"`
+	Revision   int       `gorm:"primary_key;autoIncrement:false"`
+
+	// Content at the revision
+	Channels     JSONB  `gorm:"type:jsonb;not null"`
+	Translations JSONB  `gorm:"type:jsonb"`
+	Variables    JSONB  `gorm:"type:jsonb"`
+	Version      string `gorm:"type:varchar(50)"`
+
+	CreatedAt time.Time `gorm:"not null;default:now()"`
+	CreatedBy string    `gorm:"type:varchar(255)"`
+}
+
+// TableName overrides the table name for GORM
+func (TemplateRevisionModel) TableName() string {
+	return "dictamesh_notification_template_revisions"
+}
+
 // RuleModel represents the database model for notification rules
 type RuleModel struct {
 	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -123,6 +151,11 @@ type RuleModel struct {
 	TemplateID   *uuid.UUID `gorm:"type:uuid"`
 	TemplateVars JSONB      `gorm:"type:jsonb"`
 
+	// Incidents
+	IncidentKey      string `gorm:"type:text"`
+	ResolvePattern   string `gorm:"type:text"`
+	EscalationPolicy string `gorm:"type:varchar(100)"`
+
 	// Lifecycle
 	Enabled    bool       `gorm:"default:true;index"`
 	ValidFrom  time.Time  `gorm:"not null;default:now()"`
@@ -137,6 +170,26 @@ func (RuleModel) TableName() string {
 	return "dictamesh_notification_rules"
 }
 
+// RuleScheduleModel represents the database model for the next activation
+// of a recurring rule
+type RuleScheduleModel struct {
+	RuleID uuid.UUID `gorm:"type:uuid;primary_key"`
+
+	// Schedule and timezone the activation was computed from
+	Spec string `gorm:"type:text;not null"`
+
+	NextRunAt    time.Time `gorm:"not null;index:idx_rule_schedule_next"`
+	LastRunAt    *time.Time
+	LastInstance string `gorm:"type:varchar(255)"`
+
+	UpdatedAt time.Time `gorm:"not null;default:now()"`
+}
+
+// TableName overrides the table name for GORM
+func (RuleScheduleModel) TableName() string {
+	return "dictamesh_notification_rule_schedules"
+}
+
 // DeliveryModel represents the database model for delivery attempts
 type DeliveryModel struct {
 	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -257,6 +310,28 @@ func (RateLimitModel) TableName() string {
 	return "dictamesh_notification_rate_limits"
 }
 
+// SuppressionModel represents the database model for suppressed addresses
+type SuppressionModel struct {
+	ID uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+
+	// Empty channel suppresses the address on every channel
+	Channel string `gorm:"type:varchar(50);not null;default:'';uniqueIndex:idx_suppression_address,priority:2"`
+	Address string `gorm:"type:varchar(320);not null;uniqueIndex:idx_suppression_address,priority:1"`
+
+	Reason         string     `gorm:"type:varchar(20);not null"`
+	Source         string     `gorm:"type:varchar(100)"`
+	NotificationID *uuid.UUID `gorm:"type:uuid"`
+	ExpiresAt      *time.Time
+
+	CreatedAt time.Time `gorm:"not null;default:now()"`
+	UpdatedAt time.Time `gorm:"not null;default:now()"`
+}
+
+// TableName overrides the table name for GORM
+func (SuppressionModel) TableName() string {
+	return "dictamesh_notification_suppressions"
+}
+
 // AuditModel represents the database model for audit logs
 type AuditModel struct {
 	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -0,0 +1,306 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPreferencesNotFound is returned for users without preferences
+var ErrPreferencesNotFound = errors.New("preferences not found")
+
+// preferenceColumns are the columns written by SavePreferences. Push tokens
+// are managed by the PushTokenStore.
+var preferenceColumns = []string{
+	"enabled", "timezone", "locale", "email", "phone", "channel_prefs",
+	"quiet_hours_enabled", "quiet_hours_start", "quiet_hours_end",
+	"quiet_hours_allow_critical", "category_prefs", "updated_at",
+}
+
+// PreferencesStore manages the notification preferences of users. The
+// NotificationService enforces them when dispatching.
+type PreferencesStore struct {
+	db *gorm.DB
+}
+
+// NewPreferencesStore creates a new preferences store
+func NewPreferencesStore(db *gorm.DB) *PreferencesStore {
+	return &PreferencesStore{db: db}
+}
+
+// DefaultPreferences returns the preferences of a user who set none: all
+// notifications enabled, in UTC and English
+func DefaultPreferences(userID string) *UserPreferences {
+	return &UserPreferences{
+		UserID:   userID,
+		Enabled:  true,
+		Timezone: "UTC",
+		Locale:   "en",
+		QuietHours: QuietHours{
+			Timezone:      "UTC",
+			AllowCritical: true,
+		},
+	}
+}
+
+// GetPreferences returns the preferences of a user, or
+// ErrPreferencesNotFound
+func (s *PreferencesStore) GetPreferences(ctx context.Context, userID string) (*UserPreferences, error) {
+	return getPreferences(s.db.WithContext(ctx), userID)
+}
+
+// SavePreferences creates or replaces the preferences of a user, keeping
+// their push tokens
+func (s *PreferencesStore) SavePreferences(ctx context.Context, prefs *UserPreferences) error {
+	return savePreferences(s.db.WithContext(ctx), prefs)
+}
+
+// UpdatePreferences applies update to the preferences of a user, starting
+// from DefaultPreferences if the user has none, and saves them
+func (s *PreferencesStore) UpdatePreferences(
+	ctx context.Context,
+	userID string,
+	update func(prefs *UserPreferences) error,
+) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		prefs, err := getPreferences(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if errors.Is(err, ErrPreferencesNotFound) {
+			prefs = DefaultPreferences(userID)
+		} else if err != nil {
+			return err
+		}
+
+		if err := update(prefs); err != nil {
+			return err
+		}
+		prefs.UserID = userID
+		return savePreferences(tx, prefs)
+	})
+}
+
+// SetChannelPreference opts a user in or out of a channel, or sets their
+// address on it
+func (s *PreferencesStore) SetChannelPreference(
+	ctx context.Context,
+	userID string,
+	channel Channel,
+	pref ChannelPreference,
+) error {
+	return s.UpdatePreferences(ctx, userID, func(prefs *UserPreferences) error {
+		if prefs.ChannelPrefs == nil {
+			prefs.ChannelPrefs = make(map[Channel]ChannelPreference)
+		}
+		prefs.ChannelPrefs[channel] = pref
+		return nil
+	})
+}
+
+// SetCategoryPreference sets the preferences of a user for a category of
+// notifications
+func (s *PreferencesStore) SetCategoryPreference(
+	ctx context.Context,
+	userID string,
+	category string,
+	pref CategoryPreference,
+) error {
+	return s.UpdatePreferences(ctx, userID, func(prefs *UserPreferences) error {
+		if prefs.CategoryPrefs == nil {
+			prefs.CategoryPrefs = make(map[string]CategoryPreference)
+		}
+		prefs.CategoryPrefs[category] = pref
+		return nil
+	})
+}
+
+// SetQuietHours sets the quiet hours of a user
+func (s *PreferencesStore) SetQuietHours(ctx context.Context, userID string, quiet QuietHours) error {
+	return s.UpdatePreferences(ctx, userID, func(prefs *UserPreferences) error {
+		prefs.QuietHours = quiet
+		if quiet.Timezone != "" {
+			prefs.Timezone = quiet.Timezone
+		}
+		return nil
+	})
+}
+
+// DeletePreferences deletes the preferences of a user, push tokens included
+func (s *PreferencesStore) DeletePreferences(ctx context.Context, userID string) error {
+	result := s.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.PreferencesModel{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete preferences: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrPreferencesNotFound
+	}
+	return nil
+}
+
+// Validate checks the preferences can be enforced
+func (p *UserPreferences) Validate() error {
+	if p.UserID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", p.Timezone, err)
+	}
+	if p.QuietHours.Enabled {
+		if _, err := time.Parse("15:04", p.QuietHours.StartTime); err != nil {
+			return fmt.Errorf("invalid quiet hours start %q, want HH:MM", p.QuietHours.StartTime)
+		}
+		if _, err := time.Parse("15:04", p.QuietHours.EndTime); err != nil {
+			return fmt.Errorf("invalid quiet hours end %q, want HH:MM", p.QuietHours.EndTime)
+		}
+		if p.QuietHours.StartTime == p.QuietHours.EndTime {
+			return fmt.Errorf("quiet hours start and end are equal")
+		}
+		if p.QuietHours.Timezone != "" && p.QuietHours.Timezone != p.Timezone {
+			return fmt.Errorf("quiet hours timezone must match the timezone of the user")
+		}
+	}
+	for category, pref := range p.CategoryPrefs {
+		if _, ok := priorityRank[pref.MinPriority]; pref.MinPriority != "" && !ok {
+			return fmt.Errorf("invalid minimum priority %q of category %s", pref.MinPriority, category)
+		}
+	}
+	return nil
+}
+
+// getPreferences loads the preferences of a user
+func getPreferences(db *gorm.DB, userID string) (*UserPreferences, error) {
+	var model models.PreferencesModel
+	err := db.Where("user_id = ?", userID).First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPreferencesNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch preferences: %w", err)
+	}
+	return toUserPreferences(&model)
+}
+
+// savePreferences validates and upserts the preferences of a user
+func savePreferences(db *gorm.DB, prefs *UserPreferences) error {
+	if prefs.Timezone == "" {
+		prefs.Timezone = prefs.QuietHours.Timezone
+	}
+	if prefs.Timezone == "" {
+		prefs.Timezone = "UTC"
+	}
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+
+	model, err := toPreferencesModel(prefs)
+	if err != nil {
+		return err
+	}
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = model.CreatedAt
+
+	// Every column is written, so disabled settings are not replaced by
+	// their column defaults
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns(preferenceColumns),
+	}).
+		Select(append([]string{"user_id", "created_at"}, preferenceColumns...)).
+		Create(model).Error
+	if err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+
+	prefs.UpdatedAt = model.UpdatedAt
+	return nil
+}
+
+// toUserPreferences converts a preferences row to UserPreferences
+func toUserPreferences(model *models.PreferencesModel) (*UserPreferences, error) {
+	prefs := &UserPreferences{
+		UserID:   model.UserID,
+		Enabled:  model.Enabled,
+		Timezone: model.Timezone,
+		Locale:   model.Locale,
+		Email:    model.Email,
+		Phone:    model.Phone,
+		QuietHours: QuietHours{
+			Enabled:       model.QuietHoursEnabled,
+			Timezone:      model.Timezone,
+			AllowCritical: model.QuietHoursAllowCritical,
+		},
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
+	if model.QuietHoursStart != nil && model.QuietHoursEnd != nil {
+		prefs.QuietHours.StartTime = model.QuietHoursStart.Format("15:04")
+		prefs.QuietHours.EndTime = model.QuietHoursEnd.Format("15:04")
+	}
+	for token := range model.PushTokens {
+		prefs.PushTokens = append(prefs.PushTokens, token)
+	}
+	sort.Strings(prefs.PushTokens)
+	if err := decodeJSONB(model.ChannelPrefs, &prefs.ChannelPrefs); err != nil {
+		return nil, fmt.Errorf("invalid channel preferences of %s: %w", model.UserID, err)
+	}
+	if err := decodeJSONB(model.CategoryPrefs, &prefs.CategoryPrefs); err != nil {
+		return nil, fmt.Errorf("invalid category preferences of %s: %w", model.UserID, err)
+	}
+	return prefs, nil
+}
+
+// toPreferencesModel converts UserPreferences to a preferences row
+func toPreferencesModel(prefs *UserPreferences) (*models.PreferencesModel, error) {
+	model := &models.PreferencesModel{
+		UserID:                  prefs.UserID,
+		Enabled:                 prefs.Enabled,
+		Timezone:                prefs.Timezone,
+		Locale:                  prefs.Locale,
+		Email:                   prefs.Email,
+		Phone:                   prefs.Phone,
+		QuietHoursEnabled:       prefs.QuietHours.Enabled,
+		QuietHoursAllowCritical: prefs.QuietHours.AllowCritical,
+	}
+	if model.Locale == "" {
+		model.Locale = "en"
+	}
+	if start, err := time.Parse("15:04", prefs.QuietHours.StartTime); err == nil {
+		model.QuietHoursStart = &start
+	}
+	if end, err := time.Parse("15:04", prefs.QuietHours.EndTime); err == nil {
+		model.QuietHoursEnd = &end
+	}
+
+	var err error
+	if model.ChannelPrefs, err = encodeJSONB(prefs.ChannelPrefs); err != nil {
+		return nil, fmt.Errorf("failed to encode channel preferences: %w", err)
+	}
+	if model.CategoryPrefs, err = encodeJSONB(prefs.CategoryPrefs); err != nil {
+		return nil, fmt.Errorf("failed to encode category preferences: %w", err)
+	}
+	return model, nil
+}
+
+// encodeJSONB encodes a value into a JSONB column, an empty object for nil
+func encodeJSONB(value interface{}) (models.JSONB, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	out := models.JSONB{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = models.JSONB{}
+	}
+	return out, nil
+}
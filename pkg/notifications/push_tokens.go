@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PushTokenStore manages the push tokens of users, kept in the push_tokens
+// column of their preferences as an object keyed by token
+type PushTokenStore struct {
+	db *gorm.DB
+}
+
+// NewPushTokenStore creates a new push token store
+func NewPushTokenStore(db *gorm.DB) *PushTokenStore {
+	return &PushTokenStore{db: db}
+}
+
+// RegisterPushToken registers a device of a user. A token registered to
+// another user moves to this one, as the device changed hands.
+func (s *PushTokenStore) RegisterPushToken(
+	ctx context.Context,
+	userID string,
+	token string,
+	platform PushPlatform,
+) error {
+	switch platform {
+	case PushPlatformAndroid, PushPlatformIOS, PushPlatformWeb:
+	default:
+		return fmt.Errorf("unsupported push platform: %s", platform)
+	}
+	if token == "" {
+		return fmt.Errorf("push token is required")
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := removeToken(tx.Where("user_id <> ?", userID), token); err != nil {
+			return err
+		}
+
+		var prefs models.PreferencesModel
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).
+			First(&prefs).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			prefs = models.PreferencesModel{UserID: userID, Enabled: true}
+			if err := tx.Create(&prefs).Error; err != nil {
+				return fmt.Errorf("failed to create preferences: %w", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to fetch preferences: %w", err)
+		}
+
+		tokens := prefs.PushTokens
+		if tokens == nil {
+			tokens = models.JSONB{}
+		}
+		tokens[token] = map[string]interface{}{
+			"platform":      platform,
+			"registered_at": time.Now().UTC(),
+		}
+
+		if err := tx.Model(&prefs).Updates(map[string]interface{}{
+			"push_tokens": tokens,
+			"updated_at":  time.Now(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to save push token: %w", err)
+		}
+		return nil
+	})
+}
+
+// InvalidatePushToken removes a token from whichever user registered it, on
+// sign out or once a push service rejects it as invalid
+func (s *PushTokenStore) InvalidatePushToken(ctx context.Context, token string) error {
+	return removeToken(s.db.WithContext(ctx), token)
+}
+
+// removeToken removes a token from the preferences matched by query
+func removeToken(query *gorm.DB, token string) error {
+	err := query.
+		Model(&models.PreferencesModel{}).
+		Where("jsonb_exists(push_tokens, ?)", token).
+		Updates(map[string]interface{}{
+			"push_tokens": gorm.Expr("push_tokens - ?::text", token),
+			"updated_at":  time.Now(),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to remove push token: %w", err)
+	}
+	return nil
+}
+
+// PushTokens returns the devices of a user, oldest registration first
+func (s *PushTokenStore) PushTokens(ctx context.Context, userID string) ([]PushToken, error) {
+	var prefs models.PreferencesModel
+	err := s.db.WithContext(ctx).
+		Select("user_id", "push_tokens").
+		Where("user_id = ?", userID).
+		First(&prefs).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch push tokens: %w", err)
+	}
+
+	tokens := make([]PushToken, 0, len(prefs.PushTokens))
+	for token, value := range prefs.PushTokens {
+		entry, _ := value.(map[string]interface{})
+		pushToken := PushToken{Token: token}
+		if platform, ok := entry["platform"].(string); ok {
+			pushToken.Platform = PushPlatform(platform)
+		}
+		if registeredAt, ok := entry["registered_at"].(string); ok {
+			pushToken.RegisteredAt, _ = time.Parse(time.RFC3339Nano, registeredAt)
+		}
+		tokens = append(tokens, pushToken)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].RegisteredAt.Before(tokens[j].RegisteredAt)
+	})
+
+	return tokens, nil
+}
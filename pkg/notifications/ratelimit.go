@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Rate limit scopes, as stored in the scope column of the rate limits table
+const (
+	RateLimitScopeUser     = "user"     // Notifications of a recipient on a channel
+	RateLimitScopeSystem   = "system"   // All notifications on a channel
+	RateLimitScopeCategory = "category" // Notifications of a category to a recipient on a channel
+)
+
+// OverflowAction is what happens to a notification over a rate limit
+type OverflowAction string
+
+const (
+	OverflowDrop      OverflowAction = "drop"      // Cancel the notification
+	OverflowDefer     OverflowAction = "defer"     // Deliver it once the window allows
+	OverflowDowngrade OverflowAction = "downgrade" // Deliver it over the next eligible channel, else defer
+)
+
+// RateLimiter enforces rate limits before notifications are delivered
+type RateLimiter interface {
+	// Allow counts a notification against every limit that applies to it
+	// on channel. Notifications over a limit are not counted against any.
+	Allow(ctx context.Context, notification *Notification, channel Channel) (*RateLimitDecision, error)
+}
+
+// RateLimitDecision is the outcome of a rate limit check
+type RateLimitDecision struct {
+	Allowed bool
+
+	// Limit exceeded by a notification that is not allowed
+	Scope      string
+	Limit      RateLimitDefinition
+	RetryAfter time.Duration // Time until the window has room again
+}
+
+var throttledTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "dictamesh_notifications_throttled_total",
+		Help: "Notifications over a rate limit by scope, channel and overflow action",
+	},
+	[]string{"scope", "channel", "action"},
+)
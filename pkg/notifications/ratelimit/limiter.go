@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package ratelimit enforces notification rate limits with sliding windows
+// stored in Redis, so limits are shared by all notification dispatchers.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+	"github.com/click2-run/dictamesh/pkg/notifications/models"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// KeyPrefix namespaces window keys in Redis. Its hash tag places every
+// window in one cluster slot, so a notification is checked against all its
+// limits atomically.
+const KeyPrefix = "dictamesh:notifications:{ratelimit}:"
+
+// checkTimeout bounds a single Redis check
+const checkTimeout = 100 * time.Millisecond
+
+// slidingWindowScript counts a member in the windows of KEYS, or in none if
+// any is full. Windows are sorted sets of members scored by the time they
+// were counted in milliseconds. ARGV holds the current time, the member and
+// the maximum count and window length of each key. It returns the index of
+// the full window, 0 if none, and the milliseconds until it has room.
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local member = ARGV[2]
+
+for i, key in ipairs(KEYS) do
+	local max = tonumber(ARGV[1 + i * 2])
+	local window = tonumber(ARGV[2 + i * 2])
+	redis.call("ZREMRANGEBYSCORE", key, "-inf", now - window)
+	if not redis.call("ZSCORE", key, member) then
+		local count = redis.call("ZCARD", key)
+		if count >= max then
+			local oldest = redis.call("ZRANGE", key, count - max, count - max, "WITHSCORES")
+			return {i, tonumber(oldest[2]) + window - now}
+		end
+	end
+end
+
+for i, key in ipairs(KEYS) do
+	redis.call("ZADD", key, now, member)
+	redis.call("PEXPIRE", key, tonumber(ARGV[2 + i * 2]))
+end
+return {0, 0}
+`)
+
+// Limiter implements notifications.RateLimiter. Limits come from the
+// RateLimits configuration; rows of the rate limits table override them for
+// a scope and channel, a row with a scope ID for that user or category only.
+// Disabled rows lift the limit they override.
+type Limiter struct {
+	client redis.UniversalClient
+	db     *gorm.DB
+	config *notifications.Config
+	logger *zap.Logger
+
+	mu        sync.RWMutex
+	overrides map[override]notifications.RateLimitDefinition
+	loadedAt  time.Time
+}
+
+// override identifies the limit a row of the rate limits table overrides
+type override struct {
+	scope   string
+	scopeID string
+	channel notifications.Channel
+}
+
+// window is a limit applying to a notification and the key of its window
+type window struct {
+	scope string
+	key   string
+	limit notifications.RateLimitDefinition
+}
+
+// NewLimiter creates a limiter keeping its windows in client
+func NewLimiter(
+	client redis.UniversalClient,
+	db *gorm.DB,
+	config *notifications.Config,
+	logger *zap.Logger,
+) *Limiter {
+	return &Limiter{
+		client: client,
+		db:     db,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Allow counts a notification against its user, system and category limits
+// on channel. A notification already counted, such as a retry, is not
+// counted twice.
+func (l *Limiter) Allow(
+	ctx context.Context,
+	notification *notifications.Notification,
+	channel notifications.Channel,
+) (*notifications.RateLimitDecision, error) {
+	if err := l.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	windows := l.windows(notification, channel)
+	if len(windows) == 0 {
+		return &notifications.RateLimitDecision{Allowed: true}, nil
+	}
+
+	keys := make([]string, len(windows))
+	args := []interface{}{time.Now().UnixMilli(), notification.ID}
+	for i, w := range windows {
+		keys[i] = w.key
+		args = append(args, w.limit.Count, w.limit.Duration.Milliseconds())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	values, err := slidingWindowScript.Run(ctx, l.client, keys, args...).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check rate limits: %w", err)
+	}
+	if len(values) != 2 || values[0] < 0 || values[0] > int64(len(windows)) {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+	if values[0] == 0 {
+		return &notifications.RateLimitDecision{Allowed: true}, nil
+	}
+
+	full := windows[values[0]-1]
+	return &notifications.RateLimitDecision{
+		Scope:      full.scope,
+		Limit:      full.limit,
+		RetryAfter: time.Duration(values[1]) * time.Millisecond,
+	}, nil
+}
+
+// windows returns the limits applying to a notification on channel
+func (l *Limiter) windows(notification *notifications.Notification, channel notifications.Channel) []window {
+	limits := l.config.RateLimits
+	recipient := string(notification.RecipientType) + ":" + notification.RecipientID
+
+	var windows []window
+	add := func(scope, scopeID, key string, limit notifications.RateLimitDefinition, ok bool) {
+		if def, found := l.override(scope, scopeID, channel); found {
+			limit, ok = def, true
+		}
+		if ok && !limit.Unlimited() {
+			windows = append(windows, window{scope: scope, key: KeyPrefix + key, limit: limit})
+		}
+	}
+
+	limit, ok := limits.UserLimits[channel]
+	add(notifications.RateLimitScopeUser, notification.RecipientID,
+		fmt.Sprintf("user:%s:%s", recipient, channel), limit, ok)
+
+	limit, ok = limits.SystemLimits[channel]
+	add(notifications.RateLimitScopeSystem, "",
+		fmt.Sprintf("system:%s", channel), limit, ok)
+
+	if notification.Category != "" {
+		limit, ok = limits.CategoryLimits[notification.Category]
+		add(notifications.RateLimitScopeCategory, notification.Category,
+			fmt.Sprintf("category:%s:%s:%s", notification.Category, recipient, channel), limit, ok)
+	}
+	return windows
+}
+
+// override returns the limit of the rate limits table for a scope ID, or for
+// the whole scope
+func (l *Limiter) override(scope, scopeID string, channel notifications.Channel) (notifications.RateLimitDefinition, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if scopeID != "" {
+		if limit, ok := l.overrides[override{scope: scope, scopeID: scopeID, channel: channel}]; ok {
+			return limit, true
+		}
+	}
+	if scope == notifications.RateLimitScopeCategory {
+		// Category rows always name their category
+		return notifications.RateLimitDefinition{}, false
+	}
+	limit, ok := l.overrides[override{scope: scope, channel: channel}]
+	return limit, ok
+}
+
+// refresh reloads the rate limits table every RefreshInterval. When a reload
+// fails, the limits loaded before stay in force.
+func (l *Limiter) refresh(ctx context.Context) error {
+	interval := l.config.RateLimits.RefreshInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	l.mu.RLock()
+	loadedAt := l.loadedAt
+	l.mu.RUnlock()
+	if time.Since(loadedAt) < interval {
+		return nil
+	}
+
+	var rows []models.RateLimitModel
+	if err := l.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		if loadedAt.IsZero() {
+			return fmt.Errorf("failed to load rate limits: %w", err)
+		}
+		l.logger.Warn("failed to reload rate limits", zap.Error(err))
+		l.mu.Lock()
+		l.loadedAt = time.Now()
+		l.mu.Unlock()
+		return nil
+	}
+
+	overrides := make(map[override]notifications.RateLimitDefinition, len(rows))
+	for _, row := range rows {
+		// Scopes are seeded in upper case
+		key := override{scope: strings.ToLower(row.Scope), channel: notifications.Channel(row.Channel)}
+		if row.ScopeID != nil {
+			key.scopeID = *row.ScopeID
+		}
+		var limit notifications.RateLimitDefinition
+		if row.Enabled {
+			limit = notifications.RateLimitDefinition{
+				Count:    row.MaxCount,
+				Duration: time.Duration(row.WindowSeconds) * time.Second,
+			}
+		}
+		overrides[key] = limit
+	}
+
+	l.mu.Lock()
+	l.overrides = overrides
+	l.loadedAt = time.Now()
+	l.mu.Unlock()
+	return nil
+}
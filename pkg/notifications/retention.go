@@ -0,0 +1,533 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package notifications
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Tables covered by the retention worker
+const (
+	notificationsTable = "dictamesh_notifications"
+	deliveryTable      = "dictamesh_notification_delivery"
+	auditTable         = "dictamesh_notification_audit"
+)
+
+var (
+	retentionArchived = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_notifications_retention_archived_rows_total",
+			Help: "Rows written to the archive by table",
+		},
+		[]string{"table"},
+	)
+
+	retentionDeleted = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_notifications_retention_deleted_rows_total",
+			Help: "Rows deleted by the retention worker by table",
+		},
+		[]string{"table"},
+	)
+
+	retentionDroppedPartitions = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "dictamesh_notifications_retention_dropped_partitions_total",
+			Help: "Notification partitions dropped after archiving",
+		},
+	)
+
+	retentionPosition = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dictamesh_notifications_retention_position_timestamp_seconds",
+			Help: "Creation time of the last row archived and deleted by table",
+		},
+		[]string{"table"},
+	)
+
+	retentionLastSuccess = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "dictamesh_notifications_retention_last_success_timestamp_seconds",
+			Help: "Time the last retention run finished",
+		},
+	)
+)
+
+// retentionLockKey is the advisory lock held by the running retention worker
+var retentionLockKey = func() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("dictamesh:notifications:retention"))
+	return int64(h.Sum64())
+}()
+
+// RetentionWorker archives notifications older than Retention.MaxAge, with
+// their delivery attempts and audit entries, as gzipped NDJSON and deletes
+// them in batches. Only notifications in a final status are deleted row by
+// row; the partitions strategy drops whole monthly partitions instead, once
+// every row of the partition is archived. Audit entries of no notification
+// are archived and deleted by their own age.
+type RetentionWorker struct {
+	db       *gorm.DB
+	config   *Config
+	logger   *zap.Logger
+	archiver Archiver
+}
+
+// NewRetentionWorker creates a retention worker. Without an archiver, an
+// S3Archiver for Retention.Archive is used when archival is enabled.
+func NewRetentionWorker(db *gorm.DB, config *Config, archiver Archiver, logger *zap.Logger) *RetentionWorker {
+	if archiver == nil && config.Retention.Archive.Enabled {
+		archiver = NewS3Archiver(&config.Retention.Archive)
+	}
+
+	return &RetentionWorker{
+		db:       db,
+		config:   config,
+		logger:   logger,
+		archiver: archiver,
+	}
+}
+
+// Run runs the worker every Retention.Interval until ctx is done
+func (w *RetentionWorker) Run(ctx context.Context) {
+	interval := w.config.Retention.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("notification retention failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce creates the partitions of the coming months, then archives and
+// deletes expired rows. One instance runs at a time; on the others RunOnce
+// returns without doing anything.
+func (w *RetentionWorker) RunOnce(ctx context.Context) error {
+	return w.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var locked bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", retentionLockKey).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("failed to acquire retention lock: %w", err)
+		}
+		if !locked {
+			w.logger.Debug("notification retention running on another instance")
+			return nil
+		}
+		defer func() {
+			// Release even when ctx was canceled during the run
+			if err := conn.WithContext(context.Background()).
+				Exec("SELECT pg_advisory_unlock(?)", retentionLockKey).Error; err != nil {
+				w.logger.Warn("failed to release retention lock", zap.Error(err))
+			}
+		}()
+
+		return w.run(ctx, time.Now())
+	})
+}
+
+// run performs a retention run at now
+func (w *RetentionWorker) run(ctx context.Context, now time.Time) error {
+	partitions, err := w.partitions(ctx)
+	if err != nil {
+		return err
+	}
+	if partitions != nil {
+		if err := w.createPartitions(ctx, partitions, now); err != nil {
+			return err
+		}
+	}
+
+	cutoff := now.Add(-w.config.Retention.MaxAge)
+	if w.config.Retention.Strategy == RetentionPartitions {
+		if partitions == nil {
+			return fmt.Errorf("%s is not partitioned", notificationsTable)
+		}
+		err = w.dropPartitions(ctx, partitions, cutoff)
+	} else {
+		err = w.deleteNotifications(ctx, cutoff)
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.deleteAudit(ctx, cutoff); err != nil {
+		return err
+	}
+
+	retentionLastSuccess.SetToCurrentTime()
+	w.logger.Info("notification retention finished", zap.Time("cutoff", cutoff))
+	return nil
+}
+
+// archivedRow is a row with its JSON representation
+type archivedRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Line      string
+}
+
+// deleteNotifications archives and deletes notifications in a final status
+// created before cutoff, oldest first
+func (w *RetentionWorker) deleteNotifications(ctx context.Context, cutoff time.Time) error {
+	batchSize := w.config.Retention.BatchSize
+	final := []string{string(StatusSent), string(StatusDelivered), string(StatusFailed), string(StatusCancelled)}
+
+	for {
+		var rows []archivedRow
+		err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			err := tx.Raw(`SELECT id, created_at, row_to_json(n)::text AS line FROM `+notificationsTable+` n
+				WHERE created_at < ? AND status IN ?
+				ORDER BY created_at, id
+				LIMIT ?
+				FOR UPDATE SKIP LOCKED`, cutoff, final, batchSize).
+				Scan(&rows).Error
+			if err != nil {
+				return fmt.Errorf("failed to fetch expired notifications: %w", err)
+			}
+			if len(rows) == 0 {
+				return nil
+			}
+			if err := w.archiveBatch(ctx, tx, rows); err != nil {
+				return err
+			}
+
+			result := tx.Exec("DELETE FROM "+notificationsTable+" WHERE created_at < ? AND id IN ?", cutoff, rowIDs(rows))
+			if result.Error != nil {
+				return fmt.Errorf("failed to delete notifications: %w", result.Error)
+			}
+			retentionDeleted.WithLabelValues(notificationsTable).Add(float64(result.RowsAffected))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(rows) < batchSize {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// archiveBatch archives notifications with their delivery attempts and
+// audit entries, and deletes the attempts and entries. The archive objects
+// are keyed by the first notification of the batch.
+func (w *RetentionWorker) archiveBatch(ctx context.Context, tx *gorm.DB, rows []archivedRow) error {
+	first := rows[0]
+	ids := rowIDs(rows)
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = row.Line
+	}
+	if err := w.archive(ctx, notificationsTable, first, lines); err != nil {
+		return err
+	}
+
+	related := []struct{ table, order string }{
+		{deliveryTable, "notification_id, attempt_number"},
+		{auditTable, "notification_id, timestamp"},
+	}
+	for _, r := range related {
+		var lines []string
+		err := tx.Table(r.table+" r").
+			Where("notification_id IN ?", ids).
+			Order(r.order).
+			Pluck("row_to_json(r)::text", &lines).Error
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", r.table, err)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		if err := w.archive(ctx, r.table, first, lines); err != nil {
+			return err
+		}
+
+		result := tx.Exec("DELETE FROM "+r.table+" WHERE notification_id IN ?", ids)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete %s: %w", r.table, result.Error)
+		}
+		retentionDeleted.WithLabelValues(r.table).Add(float64(result.RowsAffected))
+	}
+
+	retentionPosition.WithLabelValues(notificationsTable).Set(float64(rows[len(rows)-1].CreatedAt.Unix()))
+	return nil
+}
+
+// deleteAudit archives and deletes the audit entries of no notification
+// recorded before cutoff
+func (w *RetentionWorker) deleteAudit(ctx context.Context, cutoff time.Time) error {
+	batchSize := w.config.Retention.BatchSize
+
+	for {
+		var rows []archivedRow
+		err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			err := tx.Raw(`SELECT id, timestamp AS created_at, row_to_json(a)::text AS line FROM `+auditTable+` a
+				WHERE notification_id IS NULL AND timestamp < ?
+				ORDER BY timestamp, id
+				LIMIT ?
+				FOR UPDATE SKIP LOCKED`, cutoff, batchSize).
+				Scan(&rows).Error
+			if err != nil {
+				return fmt.Errorf("failed to fetch expired audit entries: %w", err)
+			}
+			if len(rows) == 0 {
+				return nil
+			}
+
+			lines := make([]string, len(rows))
+			for i, row := range rows {
+				lines[i] = row.Line
+			}
+			if err := w.archive(ctx, auditTable, rows[0], lines); err != nil {
+				return err
+			}
+
+			result := tx.Exec("DELETE FROM "+auditTable+" WHERE id IN ?", rowIDs(rows))
+			if result.Error != nil {
+				return fmt.Errorf("failed to delete audit entries: %w", result.Error)
+			}
+			retentionDeleted.WithLabelValues(auditTable).Add(float64(result.RowsAffected))
+			retentionPosition.WithLabelValues(auditTable).Set(float64(rows[len(rows)-1].CreatedAt.Unix()))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(rows) < batchSize {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// archive writes rows of a table to the archive as gzipped NDJSON, under
+// table/dt=<day of first>/<ID of first>.ndjson.gz, so a batch archived again
+// replaces its object
+func (w *RetentionWorker) archive(ctx context.Context, table string, first archivedRow, lines []string) error {
+	if w.archiver == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	for _, line := range lines {
+		gz.Write([]byte(line))
+		gz.Write([]byte{'\n'})
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress archive: %w", err)
+	}
+
+	key := fmt.Sprintf("%s/dt=%s/%s.ndjson.gz", table, first.CreatedAt.UTC().Format("2006-01-02"), first.ID)
+	if err := w.archiver.Put(ctx, key, buf.Bytes(), "application/x-ndjson"); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", table, err)
+	}
+	retentionArchived.WithLabelValues(table).Add(float64(len(lines)))
+	return nil
+}
+
+// partition is a range partition of the notifications table
+type partition struct {
+	name     string
+	from, to time.Time
+}
+
+// partitionBound matches the bounds of range partitions of one column
+var partitionBound = regexp.MustCompile(`^FOR VALUES FROM \('([^']+)'\) TO \('([^']+)'\)$`)
+
+// partitions returns the range partitions of the notifications table by
+// start, or nil when the table is not partitioned. The default partition
+// is left out.
+func (w *RetentionWorker) partitions(ctx context.Context) ([]partition, error) {
+	var kind string
+	err := w.db.WithContext(ctx).
+		Raw("SELECT relkind FROM pg_class WHERE oid = to_regclass(?)", notificationsTable).
+		Scan(&kind).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect %s: %w", notificationsTable, err)
+	}
+	if kind != "p" {
+		return nil, nil
+	}
+
+	var rows []struct {
+		Name  string
+		Bound string
+	}
+	err = w.db.WithContext(ctx).Raw(`SELECT c.relname AS name, pg_get_expr(c.relpartbound, c.oid) AS bound
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = to_regclass(?)`, notificationsTable).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions of %s: %w", notificationsTable, err)
+	}
+
+	partitions := make([]partition, 0, len(rows))
+	for _, row := range rows {
+		match := partitionBound.FindStringSubmatch(row.Bound)
+		if match == nil {
+			continue
+		}
+		from, err := parseBound(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("partition %s: %w", row.Name, err)
+		}
+		to, err := parseBound(match[2])
+		if err != nil {
+			return nil, fmt.Errorf("partition %s: %w", row.Name, err)
+		}
+		partitions = append(partitions, partition{name: row.Name, from: from, to: to})
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].from.Before(partitions[j].from) })
+	return partitions, nil
+}
+
+// parseBound parses a timestamp partition bound as printed by PostgreSQL
+func parseBound(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05-07", "2006-01-02 15:04:05-07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid partition bound %q", value)
+}
+
+// createPartitions creates the monthly partitions of the current month and
+// the PartitionsAhead months after it that no partition covers yet
+func (w *RetentionWorker) createPartitions(ctx context.Context, partitions []partition, now time.Time) error {
+	month := time.Date(now.UTC().Year(), now.UTC().Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i <= w.config.Retention.PartitionsAhead; i++ {
+		from := month.AddDate(0, i, 0)
+		to := from.AddDate(0, 1, 0)
+		covered := false
+		for _, p := range partitions {
+			if !from.Before(p.from) && from.Before(p.to) {
+				covered = true
+				break
+			}
+		}
+		if covered {
+			continue
+		}
+
+		name := fmt.Sprintf("%s_y%04dm%02d", notificationsTable, from.Year(), from.Month())
+		err := w.db.WithContext(ctx).Exec(fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+			name, notificationsTable, from.Format(time.RFC3339), to.Format(time.RFC3339))).Error
+		if err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", name, err)
+		}
+		w.logger.Info("notification partition created", zap.String("partition", name))
+	}
+	return nil
+}
+
+// dropPartitions archives the partitions ending before cutoff and drops
+// them, deleting the delivery attempts and audit entries of their rows
+func (w *RetentionWorker) dropPartitions(ctx context.Context, partitions []partition, cutoff time.Time) error {
+	for _, p := range partitions {
+		if p.to.After(cutoff) {
+			continue
+		}
+		if err := w.drainPartition(ctx, p); err != nil {
+			return err
+		}
+
+		table := quoteIdentifier(p.name)
+		err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("ALTER TABLE " + notificationsTable + " DETACH PARTITION " + table).Error; err != nil {
+				return err
+			}
+			return tx.Exec("DROP TABLE " + table).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to drop partition %s: %w", p.name, err)
+		}
+		retentionDroppedPartitions.Inc()
+		w.logger.Info("notification partition dropped", zap.String("partition", p.name))
+	}
+	return nil
+}
+
+// drainPartition archives every row of a partition in batches, oldest
+// first. The rows stay until the partition is dropped.
+func (w *RetentionWorker) drainPartition(ctx context.Context, p partition) error {
+	batchSize := w.config.Retention.BatchSize
+	var last *archivedRow
+
+	for {
+		query := w.db.WithContext(ctx).
+			Table(quoteIdentifier(p.name) + " n").
+			Select("id, created_at, row_to_json(n)::text AS line").
+			Order("created_at, id").
+			Limit(batchSize)
+		if last != nil {
+			query = query.Where("(created_at, id) > (?, ?)", last.CreatedAt, last.ID)
+		}
+		var rows []archivedRow
+		if err := query.Scan(&rows).Error; err != nil {
+			return fmt.Errorf("failed to read partition %s: %w", p.name, err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return w.archiveBatch(ctx, tx, rows)
+		})
+		if err != nil {
+			return err
+		}
+		if len(rows) < batchSize {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		last = &rows[len(rows)-1]
+	}
+}
+
+// rowIDs returns the IDs of rows
+func rowIDs(rows []archivedRow) []uuid.UUID {
+	ids := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	return ids
+}
+
+// quoteIdentifier quotes a table name for SQL
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
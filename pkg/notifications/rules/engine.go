@@ -0,0 +1,536 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package rules matches events of the event bus against notification rules
+// and queues the notifications they trigger. Rules with cron and interval
+// schedules are fired by a job of pkg/scheduler instead (see RegisterJobs).
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+	"github.com/click2-run/dictamesh/pkg/notifications/models"
+	"github.com/google/cel-go/cel"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// tracer traces rule evaluation; the notifications submitted continue its
+// spans
+var tracer = otel.Tracer("notifications/rules")
+
+// RecipientResolver expands role and group recipients into user IDs
+type RecipientResolver interface {
+	UsersInRole(ctx context.Context, role string) ([]string, error)
+	UsersInGroup(ctx context.Context, group string) ([]string, error)
+}
+
+// LineageRecorder records that a consumer was derived from catalog entities
+// of an organization. *lineage.Service of pkg/database implements it.
+type LineageRecorder interface {
+	RecordDerivation(ctx context.Context, organizationID, consumerKind, consumerID string, catalogIDs []string) error
+}
+
+// Engine evaluates enabled rules against events and submits the
+// notifications they trigger to the NotificationService
+type Engine struct {
+	db       *gorm.DB
+	config   *notifications.Config
+	logger   *zap.Logger
+	service  *notifications.NotificationService
+	resolver RecipientResolver
+	lineage  LineageRecorder
+	env      *cel.Env
+
+	mu    sync.RWMutex
+	rules []*compiledRule
+}
+
+// NewEngine creates a rule engine. Without a resolver, role and group
+// recipients are notified as such, addressed by their name.
+func NewEngine(
+	db *gorm.DB,
+	config *notifications.Config,
+	logger *zap.Logger,
+	service *notifications.NotificationService,
+	resolver RecipientResolver,
+) (*Engine, error) {
+	env, err := newEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	return &Engine{
+		db:       db,
+		config:   config,
+		logger:   logger,
+		service:  service,
+		resolver: resolver,
+		env:      env,
+	}, nil
+}
+
+// SetLineage records notifications triggered by events about a catalog
+// entity (carrying entity_id and an organization) as derived from it
+func (e *Engine) SetLineage(recorder LineageRecorder) {
+	e.lineage = recorder
+}
+
+// Load compiles the enabled rules. Rules that fail to compile are logged and
+// left out, so one broken rule does not stop the others.
+func (e *Engine) Load(ctx context.Context) error {
+	var rules []models.RuleModel
+	if err := e.db.WithContext(ctx).Where("enabled = ?", true).Find(&rules).Error; err != nil {
+		return fmt.Errorf("failed to load notification rules: %w", err)
+	}
+
+	compiled := make([]*compiledRule, 0, len(rules))
+	for i := range rules {
+		rule, err := compile(e.env, &rules[i])
+		if err != nil {
+			e.logger.Error("failed to compile notification rule",
+				zap.String("rule", rules[i].Name),
+				zap.Error(err))
+			continue
+		}
+		if _, ok := e.config.Channels.PagerDuty.EscalationPolicies[rule.escalation]; rule.escalation != "" && !ok {
+			e.logger.Error("notification rule names an unknown escalation policy",
+				zap.String("rule", rules[i].Name),
+				zap.String("escalation_policy", rule.escalation))
+			continue
+		}
+		compiled = append(compiled, rule)
+	}
+
+	e.mu.Lock()
+	e.rules = compiled
+	e.mu.Unlock()
+
+	e.logger.Debug("notification rules loaded", zap.Int("rules", len(compiled)))
+	return nil
+}
+
+// Run loads the rules and consumes the event topics until ctx is done,
+// reloading the rules every RuleRefreshInterval
+func (e *Engine) Run(ctx context.Context) error {
+	if len(e.config.KafkaEventTopics) == 0 {
+		return fmt.Errorf("no event topics configured")
+	}
+	if err := e.Load(ctx); err != nil {
+		return err
+	}
+
+	go e.refresh(ctx)
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     e.config.KafkaBootstrapServers,
+		GroupID:     e.config.KafkaConsumerGroup,
+		GroupTopics: e.config.KafkaEventTopics,
+		MinBytes:    1,
+		MaxBytes:    10e6,
+	})
+	defer reader.Close()
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			e.logger.Warn("failed to read event", zap.Error(err))
+			time.Sleep(time.Second)
+			continue
+		}
+
+		event, err := decodeEvent(msg)
+		if err != nil {
+			// A malformed event will not improve on redelivery
+			e.logger.Warn("skipping malformed event",
+				zap.String("topic", msg.Topic),
+				zap.Int64("offset", msg.Offset),
+				zap.Error(err))
+		} else if err := e.Handle(ctx, event); err != nil {
+			// Leave the offset uncommitted so the event is redelivered
+			e.logger.Error("failed to handle event",
+				zap.String("event_id", event.EventID),
+				zap.Error(err))
+			if ctx.Err() != nil {
+				return nil
+			}
+			time.Sleep(time.Second)
+			continue
+		}
+
+		if err := reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			e.logger.Warn("failed to commit event offset", zap.Error(err))
+		}
+	}
+}
+
+// refresh reloads the rules periodically until ctx is done
+func (e *Engine) refresh(ctx context.Context) {
+	interval := e.config.Processing.RuleRefreshInterval
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Load(ctx); err != nil {
+				e.logger.Error("failed to reload notification rules", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Handle matches an event against the rules and submits a notification per
+// recipient of each matching rule. Events matching the resolve pattern of a
+// rule submit resolve notifications on its PagerDuty channel instead. Events
+// are delivered at least once, so notifications already created for the
+// event, rule and recipient are not created again. Rules with a cron or
+// interval schedule are fired by the schedule job and do not match events.
+// Handling continues the trace of the event.
+func (e *Engine) Handle(ctx context.Context, event *notifications.NotificationEvent) (err error) {
+	ctx = notifications.ContextWithTrace(ctx, event.TraceID, event.SpanID)
+	ctx, span := tracer.Start(ctx, "notifications.rules.handle",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("event.id", event.EventID),
+			attribute.String("event.type", event.EventType),
+		))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	e.mu.RLock()
+	rules := e.rules
+	e.mu.RUnlock()
+
+	vars := activation(event)
+	now := time.Now()
+
+	for _, rule := range rules {
+		if rule.recurring != nil {
+			continue
+		}
+
+		action := notifications.IncidentTrigger
+		matched, err := rule.resolves(event, vars, now)
+		if matched {
+			action = notifications.IncidentResolve
+		} else if err == nil {
+			matched, err = rule.matches(event, vars, now)
+		}
+		var incident string
+		if err == nil && matched && rule.hasChannel(notifications.ChannelPagerDuty) {
+			incident, err = rule.incident(event, vars)
+		}
+		if err != nil {
+			e.logger.Warn("failed to evaluate notification rule",
+				zap.String("rule", rule.name),
+				zap.String("event_id", event.EventID),
+				zap.Error(err))
+			continue
+		}
+		if !matched {
+			continue
+		}
+		span.AddEvent("rule matched", trace.WithAttributes(
+			attribute.String("rule", rule.name),
+			attribute.String("action", action),
+		))
+
+		recipients, err := e.recipients(ctx, rule, vars)
+		if err != nil {
+			return fmt.Errorf("failed to resolve recipients of rule %s: %w", rule.name, err)
+		}
+		for _, recipient := range recipients {
+			if err := e.submit(ctx, rule, event, recipient, action, incident); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recipient is a resolved notification recipient
+type recipient struct {
+	kind notifications.RecipientType
+	id   string
+}
+
+// recipients resolves the recipients of a rule for an event
+func (e *Engine) recipients(ctx context.Context, rule *compiledRule, vars map[string]interface{}) ([]recipient, error) {
+	selector := rule.selector
+	var result []recipient
+	seen := make(map[recipient]bool)
+	add := func(kind notifications.RecipientType, id string) {
+		r := recipient{kind: kind, id: id}
+		if id != "" && !seen[r] {
+			seen[r] = true
+			result = append(result, r)
+		}
+	}
+
+	for _, userID := range selector.UserIDs {
+		add(notifications.RecipientTypeUser, userID)
+	}
+
+	for _, role := range selector.Roles {
+		if e.resolver == nil {
+			add(notifications.RecipientTypeRole, role)
+			continue
+		}
+		users, err := e.resolver.UsersInRole(ctx, role)
+		if err != nil {
+			return nil, err
+		}
+		for _, userID := range users {
+			add(notifications.RecipientTypeUser, userID)
+		}
+	}
+
+	for _, group := range selector.Groups {
+		if e.resolver == nil {
+			add(notifications.RecipientTypeGroup, group)
+			continue
+		}
+		users, err := e.resolver.UsersInGroup(ctx, group)
+		if err != nil {
+			return nil, err
+		}
+		for _, userID := range users {
+			add(notifications.RecipientTypeUser, userID)
+		}
+	}
+
+	if rule.dynamic != nil {
+		users, err := rule.dynamicRecipients(vars)
+		if err != nil {
+			return nil, err
+		}
+		for _, userID := range users {
+			add(notifications.RecipientTypeUser, userID)
+		}
+	}
+
+	return result, nil
+}
+
+// submit queues the notification of a rule for a recipient, unless it was
+// queued before. Notifications of rules with a PagerDuty channel carry the
+// incident they trigger or resolve. Notifications due outside the delivery
+// window of the rule are deferred to its next start.
+func (e *Engine) submit(
+	ctx context.Context,
+	rule *compiledRule,
+	event *notifications.NotificationEvent,
+	to recipient,
+	action string,
+	incident string,
+) error {
+	var existing int64
+	err := e.db.WithContext(ctx).
+		Model(&models.NotificationModel{}).
+		Where("event_id = ? AND rule_id = ? AND recipient_type = ? AND recipient_id = ?",
+			event.EventID, rule.id, to.kind, to.id).
+		Count(&existing).Error
+	if err != nil {
+		return fmt.Errorf("failed to check existing notifications: %w", err)
+	}
+	if existing > 0 {
+		return nil
+	}
+
+	// Template variables complement the event data
+	data := make(map[string]interface{}, len(event.Data)+len(rule.templateVars))
+	for key, value := range event.Data {
+		data[key] = value
+	}
+	for key, value := range rule.templateVars {
+		data[key] = value
+	}
+
+	notification := &notifications.Notification{
+		EventID:       event.EventID,
+		RuleID:        rule.id.String(),
+		RecipientType: to.kind,
+		RecipientID:   to.id,
+		Data:          data,
+		Priority:      rule.priority,
+		Channels:      rule.channels,
+		Category:      event.Domain,
+		TraceID:       event.TraceID,
+		Metadata: map[string]interface{}{
+			"event_type": event.EventType,
+			"rule":       rule.name,
+		},
+	}
+	if rule.templateID != nil {
+		notification.TemplateID = rule.templateID.String()
+	} else {
+		notification.Subject = event.EventType
+	}
+	if rule.schedule != nil && rule.schedule.Type == notifications.ScheduleTime && rule.schedule.Time != nil {
+		notification.ScheduledAt = *rule.schedule.Time
+	}
+	if rule.window != nil {
+		due := notification.ScheduledAt
+		if due.IsZero() {
+			due = time.Now()
+		}
+		notification.ScheduledAt = rule.window.next(due, rule.location)
+	}
+	if incident != "" {
+		notification.Metadata[notifications.MetadataIncidentKey] = incident
+		notification.Metadata[notifications.MetadataIncidentAction] = action
+		if rule.escalation != "" {
+			notification.Metadata[notifications.MetadataEscalationPolicy] = rule.escalation
+		}
+	}
+	if action == notifications.IncidentResolve {
+		// Only the incident is resolved; other channels were notified once
+		notification.Channels = []notifications.Channel{notifications.ChannelPagerDuty}
+		notification.ScheduledAt = time.Time{}
+	}
+
+	if err := e.service.Submit(ctx, notification); err != nil {
+		return fmt.Errorf("failed to submit notification of rule %s: %w", rule.name, err)
+	}
+	e.recordLineage(ctx, event, notification)
+	return nil
+}
+
+// recordLineage records a notification triggered by an entity event as
+// derived from the entity. Failures are only logged, as the notification is
+// already queued.
+func (e *Engine) recordLineage(ctx context.Context, event *notifications.NotificationEvent, notification *notifications.Notification) {
+	if e.lineage == nil || event.OrganizationID == "" {
+		return
+	}
+	entityID, _ := event.Data["entity_id"].(string)
+	if entityID == "" {
+		return
+	}
+
+	err := e.lineage.RecordDerivation(ctx, event.OrganizationID, "notification", notification.ID, []string{entityID})
+	if err != nil {
+		e.logger.Warn("failed to record notification lineage",
+			zap.String("notification_id", notification.ID),
+			zap.String("entity_id", entityID),
+			zap.Error(err),
+		)
+	}
+}
+
+// busEvent is the envelope of events published on the bus. Events in the
+// canonical envelope of the events package carry a specversion and their
+// payload in data; older events are flat, with the payload alongside.
+type busEvent struct {
+	SpecVersion    string          `json:"specversion"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	OccurredAt     time.Time       `json:"occurred_at"`
+	Source         string          `json:"source"`
+	OrganizationID string          `json:"organization_id"`
+	TraceID        string          `json:"trace_id"`
+	TraceParent    string          `json:"traceparent"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// decodeEvent decodes an event of the bus. The event type defaults to the
+// topic and its domain is the first segment of the type, e.g. "billing" for
+// "billing.invoice.created". Topics matched by rules must carry JSON, plain
+// or in the JSON Schema wire format of the schema registry; the schema ID is
+// skipped, as rules read events as untyped maps.
+func decodeEvent(msg kafka.Message) (*notifications.NotificationEvent, error) {
+	value := msg.Value
+	if len(value) > 5 && value[0] == 0 && value[5] == '{' {
+		value = value[5:]
+	}
+
+	var envelope busEvent
+	if err := json.Unmarshal(value, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+	payload := value
+	if envelope.SpecVersion != "" {
+		payload = envelope.Data
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+	if envelope.EventID == "" {
+		return nil, errors.New("event has no event_id")
+	}
+
+	event := &notifications.NotificationEvent{
+		EventID:        envelope.EventID,
+		EventType:      envelope.EventType,
+		Timestamp:      envelope.OccurredAt,
+		SourceSystem:   envelope.Source,
+		OrganizationID: envelope.OrganizationID,
+		Data:           data,
+		TraceID:        envelope.TraceID,
+	}
+	if event.EventType == "" {
+		event.EventType = msg.Topic
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = msg.Time
+	}
+	event.Domain, _, _ = strings.Cut(event.EventType, ".")
+
+	// The traceparent header wins over the one of the envelope, which
+	// records the publisher of the event rather than of the message
+	traceParent := envelope.TraceParent
+	for _, header := range msg.Headers {
+		if header.Key == "traceparent" {
+			traceParent = string(header.Value)
+		}
+	}
+	if traceParent != "" {
+		// traceparent is version-traceid-spanid-flags; its span is the
+		// producer's when the envelope names the same trace
+		parts := strings.Split(traceParent, "-")
+		if len(parts) == 4 && (event.TraceID == "" || event.TraceID == parts[1]) {
+			event.TraceID = parts[1]
+			event.SpanID = parts[2]
+		}
+	}
+	return event, nil
+}
+
+// decode decodes a JSONB column into a typed value
+func decode(value models.JSONB, out interface{}) error {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,281 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package rules
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+	"github.com/click2-run/dictamesh/pkg/notifications/models"
+	"github.com/click2-run/dictamesh/pkg/scheduler"
+	"github.com/google/cel-go/cel"
+	"github.com/google/uuid"
+)
+
+// newEnv creates the CEL environment of rule expressions. Expressions see
+// the event as a map:
+//
+//	event.id, event.type, event.domain, event.source, event.timestamp,
+//	event.trace_id and event.data, the decoded event payload
+func newEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("event", cel.MapType(cel.StringType, cel.DynType)),
+	)
+}
+
+// compiledRule is a rule with its expressions compiled
+type compiledRule struct {
+	id           uuid.UUID
+	name         string
+	priority     notifications.Priority
+	channels     []notifications.Channel
+	domains      map[string]bool
+	eventTypes   map[string]bool
+	pattern      cel.Program // nil matches every event
+	selector     notifications.RecipientSelector
+	dynamic      cel.Program // Dynamic recipient expression
+	schedule     *notifications.Schedule
+	recurring    scheduler.Schedule // Cron or interval schedule, fired by the schedule job
+	spec         string             // Schedule and timezone of recurring
+	location     *time.Location
+	window       *window
+	templateID   *uuid.UUID
+	templateVars map[string]interface{}
+	incidentKey  cel.Program // nil keys incidents by event ID
+	resolve      cel.Program // Resolve pattern, nil when incidents are not resolved
+	escalation   string
+	validFrom    time.Time
+	validUntil   *time.Time
+}
+
+// compile compiles a rule. Patterns must evaluate to a bool, dynamic
+// recipient expressions to a user ID or a list of user IDs and incident keys
+// to a string. Resolving incidents needs an incident key and a PagerDuty
+// channel. Schedules and delivery windows are evaluated in the timezone of
+// the rule.
+func compile(env *cel.Env, rule *models.RuleModel) (*compiledRule, error) {
+	compiled := &compiledRule{
+		id:           rule.ID,
+		name:         rule.Name,
+		priority:     notifications.Priority(rule.Priority),
+		domains:      toSet(rule.Domains),
+		eventTypes:   toSet(rule.EventTypes),
+		templateID:   rule.TemplateID,
+		templateVars: rule.TemplateVars,
+		escalation:   rule.EscalationPolicy,
+		validFrom:    rule.ValidFrom,
+		validUntil:   rule.ValidUntil,
+	}
+	for _, channel := range rule.Channels {
+		compiled.channels = append(compiled.channels, notifications.Channel(channel))
+	}
+	if err := decode(rule.RecipientSelector, &compiled.selector); err != nil {
+		return nil, fmt.Errorf("invalid recipient selector: %w", err)
+	}
+	location, err := time.LoadLocation(rule.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %w", err)
+	}
+	compiled.location = location
+	if rule.Schedule != nil {
+		compiled.schedule = &notifications.Schedule{}
+		if err := decode(rule.Schedule, compiled.schedule); err != nil {
+			return nil, fmt.Errorf("invalid schedule: %w", err)
+		}
+		if err := compiled.compileSchedule(); err != nil {
+			return nil, fmt.Errorf("invalid schedule: %w", err)
+		}
+	}
+
+	if pattern := strings.TrimSpace(rule.EventPattern); pattern != "" && pattern != "true" {
+		program, err := compileExpression(env, pattern, cel.BoolType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event pattern: %w", err)
+		}
+		compiled.pattern = program
+	}
+
+	if compiled.selector.Type == "dynamic" {
+		program, err := compileExpression(env, compiled.selector.Expression,
+			cel.StringType, cel.ListType(cel.StringType))
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient expression: %w", err)
+		}
+		compiled.dynamic = program
+	}
+
+	if key := strings.TrimSpace(rule.IncidentKey); key != "" {
+		program, err := compileExpression(env, key, cel.StringType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid incident key: %w", err)
+		}
+		compiled.incidentKey = program
+	}
+
+	if pattern := strings.TrimSpace(rule.ResolvePattern); pattern != "" {
+		if compiled.incidentKey == nil {
+			return nil, fmt.Errorf("resolve pattern requires an incident key")
+		}
+		if !compiled.hasChannel(notifications.ChannelPagerDuty) {
+			return nil, fmt.Errorf("resolve pattern requires the %s channel", notifications.ChannelPagerDuty)
+		}
+		program, err := compileExpression(env, pattern, cel.BoolType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resolve pattern: %w", err)
+		}
+		compiled.resolve = program
+	}
+
+	return compiled, nil
+}
+
+// compileExpression compiles an expression, checking it returns one of the
+// wanted types. Expressions of dynamic type are accepted and checked at
+// evaluation.
+func compileExpression(env *cel.Env, expression string, want ...*cel.Type) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	out := ast.OutputType()
+	valid := out.IsExactType(cel.DynType)
+	for _, t := range want {
+		valid = valid || out.IsExactType(t)
+	}
+	if !valid {
+		return nil, fmt.Errorf("expression returns %s", out)
+	}
+	return env.Program(ast)
+}
+
+// matches reports whether an event triggers the rule at now
+func (r *compiledRule) matches(event *notifications.NotificationEvent, activation map[string]interface{}, now time.Time) (bool, error) {
+	if !r.applies(event, now) {
+		return false, nil
+	}
+	if r.pattern == nil {
+		return true, nil
+	}
+	return evalPattern(r.pattern, activation)
+}
+
+// resolves reports whether an event resolves the incident of its key at now
+func (r *compiledRule) resolves(event *notifications.NotificationEvent, activation map[string]interface{}, now time.Time) (bool, error) {
+	if r.resolve == nil || !r.applies(event, now) {
+		return false, nil
+	}
+	return evalPattern(r.resolve, activation)
+}
+
+// applies reports whether the rule is valid at now and covers the domain and
+// type of an event
+func (r *compiledRule) applies(event *notifications.NotificationEvent, now time.Time) bool {
+	if !r.valid(now) {
+		return false
+	}
+	if len(r.domains) > 0 && !r.domains[event.Domain] {
+		return false
+	}
+	if len(r.eventTypes) > 0 && !r.eventTypes[event.EventType] {
+		return false
+	}
+	return true
+}
+
+// valid reports whether the rule is valid at now
+func (r *compiledRule) valid(now time.Time) bool {
+	return !now.Before(r.validFrom) && (r.validUntil == nil || now.Before(*r.validUntil))
+}
+
+// evalPattern evaluates a pattern against an event
+func evalPattern(pattern cel.Program, activation map[string]interface{}) (bool, error) {
+	out, _, err := pattern.Eval(activation)
+	if err != nil {
+		// Missing fields of events the pattern was not written for
+		if strings.Contains(err.Error(), "no such key") {
+			return false, nil
+		}
+		return false, err
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("event pattern returned %T, not bool", out.Value())
+	}
+	return matched, nil
+}
+
+// incident returns the incident key of an event: the rule ID and the value
+// of the incident key expression, or the event ID
+func (r *compiledRule) incident(event *notifications.NotificationEvent, activation map[string]interface{}) (string, error) {
+	if r.incidentKey == nil {
+		return r.id.String() + ":" + event.EventID, nil
+	}
+	out, _, err := r.incidentKey.Eval(activation)
+	if err != nil {
+		return "", fmt.Errorf("failed to evaluate incident key: %w", err)
+	}
+	key, ok := out.Value().(string)
+	if !ok || key == "" {
+		return "", fmt.Errorf("incident key returned %v, not a string", out.Value())
+	}
+	return r.id.String() + ":" + key, nil
+}
+
+// hasChannel reports whether the rule notifies on channel
+func (r *compiledRule) hasChannel(channel notifications.Channel) bool {
+	for _, c := range r.channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// dynamicRecipients evaluates the recipient expression of the rule
+func (r *compiledRule) dynamicRecipients(activation map[string]interface{}) ([]string, error) {
+	out, _, err := r.dynamic.Eval(activation)
+	if err != nil {
+		return nil, err
+	}
+	if userID, ok := out.Value().(string); ok {
+		return []string{userID}, nil
+	}
+	native, err := out.ConvertToNative(reflect.TypeOf([]string{}))
+	if err != nil {
+		return nil, fmt.Errorf("recipient expression did not return user IDs: %w", err)
+	}
+	return native.([]string), nil
+}
+
+// activation returns the variables of an event for CEL evaluation
+func activation(event *notifications.NotificationEvent) map[string]interface{} {
+	data := event.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"event": map[string]interface{}{
+			"id":        event.EventID,
+			"type":      event.EventType,
+			"domain":    event.Domain,
+			"source":    event.SourceSystem,
+			"timestamp": event.Timestamp,
+			"trace_id":  event.TraceID,
+			"data":      data,
+		},
+	}
+}
+
+// toSet converts a list to a set
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,354 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package rules
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+	"github.com/click2-run/dictamesh/pkg/notifications/models"
+	"github.com/click2-run/dictamesh/pkg/scheduler"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// compileSchedule compiles the recurring schedule and delivery window of
+// the rule
+func (r *compiledRule) compileSchedule() error {
+	schedule := r.schedule
+	switch schedule.Type {
+	case "", notifications.ScheduleImmediate:
+	case notifications.ScheduleCron:
+		spec, err := scheduler.ParseSchedule(schedule.Cron)
+		if err != nil {
+			return fmt.Errorf("invalid cron expression: %w", err)
+		}
+		r.recurring = spec
+		r.spec = schedule.Cron
+	case notifications.ScheduleInterval:
+		if schedule.Interval < time.Second {
+			return fmt.Errorf("interval must be at least a second")
+		}
+		r.spec = scheduler.Every(schedule.Interval)
+		spec, err := scheduler.ParseSchedule(r.spec)
+		if err != nil {
+			return fmt.Errorf("invalid interval: %w", err)
+		}
+		r.recurring = spec
+	case notifications.ScheduleTime:
+		if schedule.Time == nil {
+			return fmt.Errorf("time schedule has no time")
+		}
+	default:
+		return fmt.Errorf("unsupported schedule type: %s", schedule.Type)
+	}
+	if r.recurring != nil {
+		r.spec += " " + r.location.String()
+	}
+
+	if schedule.Window != nil {
+		window, err := parseWindow(schedule.Window)
+		if err != nil {
+			return fmt.Errorf("invalid delivery window: %w", err)
+		}
+		r.window = window
+	}
+	return nil
+}
+
+// window is a compiled delivery window
+type window struct {
+	start    int // Minute of the day
+	duration time.Duration
+	days     map[time.Weekday]bool // nil for every day
+}
+
+// parseWindow compiles a delivery window. Windows ending before they start
+// span midnight.
+func parseWindow(w *notifications.DeliveryWindow) (*window, error) {
+	start, err := time.Parse("15:04", w.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time %q", w.StartTime)
+	}
+	end, err := time.Parse("15:04", w.EndTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end time %q", w.EndTime)
+	}
+
+	compiled := &window{
+		start:    start.Hour()*60 + start.Minute(),
+		duration: end.Sub(start),
+	}
+	if compiled.duration <= 0 {
+		compiled.duration += 24 * time.Hour
+	}
+	if len(w.Days) > 0 {
+		compiled.days = make(map[time.Weekday]bool, len(w.Days))
+		for _, day := range w.Days {
+			if day < time.Sunday || day > time.Saturday {
+				return nil, fmt.Errorf("invalid day %d", day)
+			}
+			compiled.days[day] = true
+		}
+	}
+	return compiled, nil
+}
+
+// next returns t when it falls in the window in location, else the next
+// start of the window. Days select the day a window starts on.
+func (w *window) next(t time.Time, location *time.Location) time.Time {
+	local := t.In(location)
+	// From yesterday, whose window may span midnight into today
+	for day := -1; day <= 7; day++ {
+		date := local.AddDate(0, 0, day)
+		if w.days != nil && !w.days[date.Weekday()] {
+			continue
+		}
+		start := time.Date(date.Year(), date.Month(), date.Day(), w.start/60, w.start%60, 0, 0, location)
+		if start.After(local) {
+			return start
+		}
+		if local.Before(start.Add(w.duration)) {
+			return t
+		}
+	}
+	return t
+}
+
+// JobRuleSchedules is the pkg/scheduler job firing the cron and interval
+// schedules of notification rules
+const JobRuleSchedules = "notifications.rule_schedules"
+
+// RegisterJobs registers the job firing the rules of engine with cron and
+// interval schedules with the scheduler, to run every ScheduleInterval. Each
+// run claims the due activations from the rule schedule table and submits a
+// notification per recipient of each. The job lock runs it on one instance
+// at a time; due rows are also claimed with row locks, so instances whose
+// scheduler has no locker do not fire an activation twice. Notifications are
+// keyed by the activation, so an activation fired again after a failure does
+// not notify twice. Activations missed while no instance ran the job are
+// skipped.
+func RegisterJobs(s *scheduler.Scheduler, engine *Engine) error {
+	hostname, _ := os.Hostname()
+	job := &scheduleJob{
+		engine:   engine,
+		instance: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+
+	err := s.Register(scheduler.Job{
+		Name:     JobRuleSchedules,
+		Schedule: scheduler.Every(job.interval()),
+		Timeout:  job.missedAfter(),
+		Run:      job.run,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register rule schedule job: %w", err)
+	}
+	return nil
+}
+
+// scheduleJob fires the due activations of the rules of an engine
+type scheduleJob struct {
+	engine   *Engine
+	instance string
+	loadedAt time.Time
+}
+
+// run reloads the rules every RuleRefreshInterval, as the engine may not be
+// consuming events on this instance, and fires the activations due now
+func (j *scheduleJob) run(ctx context.Context) error {
+	e := j.engine
+	refresh := e.config.Processing.RuleRefreshInterval
+	if j.loadedAt.IsZero() || (refresh > 0 && time.Since(j.loadedAt) >= refresh) {
+		if err := e.Load(ctx); err != nil {
+			return err
+		}
+		j.loadedAt = time.Now()
+	}
+	return j.fireDue(ctx, time.Now())
+}
+
+// interval returns ScheduleInterval, 15 seconds by default
+func (j *scheduleJob) interval() time.Duration {
+	if interval := j.engine.config.Processing.ScheduleInterval; interval > 0 {
+		return interval
+	}
+	return 15 * time.Second
+}
+
+// missedAfter returns how late an activation may fire: two intervals, at
+// least a minute
+func (j *scheduleJob) missedAfter() time.Duration {
+	if missedAfter := 2 * j.interval(); missedAfter > time.Minute {
+		return missedAfter
+	}
+	return time.Minute
+}
+
+// fireDue fires the activations of the loaded rules due at now. Activations
+// that fail are left due and fired again on the next run, until they are
+// missed.
+func (j *scheduleJob) fireDue(ctx context.Context, now time.Time) error {
+	e := j.engine
+	e.mu.RLock()
+	rules := e.rules
+	e.mu.RUnlock()
+
+	scheduled := make(map[uuid.UUID]*compiledRule)
+	ids := make([]uuid.UUID, 0, len(rules))
+	for _, rule := range rules {
+		if rule.recurring != nil {
+			scheduled[rule.id] = rule
+			ids = append(ids, rule.id)
+		}
+	}
+	if len(scheduled) == 0 {
+		return nil
+	}
+	if err := j.sync(ctx, scheduled, now); err != nil {
+		return err
+	}
+
+	// Activations later than this were missed
+	missedAfter := j.missedAfter()
+
+	return e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var due []models.RuleScheduleModel
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("rule_id IN ? AND next_run_at <= ?", ids, now).
+			Find(&due).Error
+		if err != nil {
+			return fmt.Errorf("failed to fetch due rule schedules: %w", err)
+		}
+
+		for i := range due {
+			row := &due[i]
+			rule := scheduled[row.RuleID]
+			if row.Spec != rule.spec {
+				// Changed by an instance with newer rules
+				continue
+			}
+
+			if now.Sub(row.NextRunAt) > missedAfter {
+				e.logger.Warn("skipping missed rule activation",
+					zap.String("rule", rule.name),
+					zap.Time("activation", row.NextRunAt))
+			} else if err := j.fire(ctx, rule, row.NextRunAt); err != nil {
+				e.logger.Error("failed to fire notification rule",
+					zap.String("rule", rule.name),
+					zap.Time("activation", row.NextRunAt),
+					zap.Error(err))
+				continue
+			}
+
+			// The activation after this one, or after now when behind
+			next := rule.recurring.Next(row.NextRunAt.In(rule.location))
+			if !next.After(now) {
+				next = rule.recurring.Next(now.In(rule.location))
+			}
+			err := tx.Model(row).Updates(map[string]interface{}{
+				"next_run_at":   next,
+				"last_run_at":   row.NextRunAt,
+				"last_instance": j.instance,
+				"updated_at":    now,
+			}).Error
+			if err != nil {
+				return fmt.Errorf("failed to update rule schedule: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// sync creates the schedule rows of new rules and reschedules rules whose
+// schedule or timezone changed
+func (j *scheduleJob) sync(ctx context.Context, scheduled map[uuid.UUID]*compiledRule, now time.Time) error {
+	rows := make([]models.RuleScheduleModel, 0, len(scheduled))
+	for _, rule := range scheduled {
+		rows = append(rows, models.RuleScheduleModel{
+			RuleID:    rule.id,
+			Spec:      rule.spec,
+			NextRunAt: rule.recurring.Next(now.In(rule.location)),
+			UpdatedAt: now,
+		})
+	}
+
+	err := j.engine.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "rule_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"spec", "next_run_at", "updated_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			gorm.Expr("dictamesh_notification_rule_schedules.spec <> excluded.spec"),
+		}},
+	}).Create(&rows).Error
+	if err != nil {
+		return fmt.Errorf("failed to sync rule schedules: %w", err)
+	}
+	return nil
+}
+
+// fire submits the notifications of an activation of a rule. Activations
+// are events of the rule's name, from the "scheduler" source, with the
+// activation time as scheduled_at. Each activation starts a trace.
+func (j *scheduleJob) fire(ctx context.Context, rule *compiledRule, at time.Time) (err error) {
+	if !rule.valid(at) {
+		return nil
+	}
+
+	ctx, span := tracer.Start(ctx, "notifications.rules.schedule",
+		trace.WithNewRoot(),
+		trace.WithAttributes(
+			attribute.String("rule", rule.name),
+			attribute.String("activation", at.UTC().Format(time.RFC3339)),
+		))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	e := j.engine
+	event := &notifications.NotificationEvent{
+		EventID:      fmt.Sprintf("schedule:%s:%d", rule.id, at.Unix()),
+		EventType:    rule.name,
+		Timestamp:    at,
+		SourceSystem: "scheduler",
+		Data: map[string]interface{}{
+			"scheduled_at": at.UTC().Format(time.RFC3339),
+		},
+	}
+	vars := activation(event)
+
+	var incident string
+	if rule.hasChannel(notifications.ChannelPagerDuty) {
+		if incident, err = rule.incident(event, vars); err != nil {
+			return err
+		}
+	}
+
+	recipients, err := e.recipients(ctx, rule, vars)
+	if err != nil {
+		return fmt.Errorf("failed to resolve recipients: %w", err)
+	}
+	for _, recipient := range recipients {
+		if err := e.submit(ctx, rule, event, recipient, notifications.IncidentTrigger, incident); err != nil {
+			return err
+		}
+	}
+
+	e.logger.Debug("notification rule fired",
+		zap.String("rule", rule.name),
+		zap.Time("activation", at),
+		zap.Int("recipients", len(recipients)))
+	return nil
+}
@@ -0,0 +1,989 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications/models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// priorities lists the priorities from the most to the least urgent
+var priorities = []Priority{PriorityCritical, PriorityHigh, PriorityNormal, PriorityLow}
+
+// priorityRank orders priorities, the most urgent highest
+var priorityRank = map[Priority]int{
+	PriorityLow:      0,
+	PriorityNormal:   1,
+	PriorityHigh:     2,
+	PriorityCritical: 3,
+}
+
+// ErrNotificationNotFound is returned for unknown notifications
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// Renderer renders the content of notifications created from a template
+// for their selected channel and locale
+type Renderer interface {
+	RenderNotification(ctx context.Context, notification *Notification) error
+}
+
+// NotificationService dispatches pending notifications: it claims due rows
+// of the notifications table by priority, selects a channel from their rule
+// and the preferences of their recipient, delivers them through the
+// DeliveryTracker and schedules retries with exponential backoff
+type NotificationService struct {
+	db       *gorm.DB
+	config   *Config
+	logger   *zap.Logger
+	tracker  *DeliveryTracker
+	renderer Renderer
+	limiter  RateLimiter
+
+	// Claimed notifications waiting for a worker, one queue per priority
+	queues map[Priority]chan *models.NotificationModel
+	wake   chan struct{}
+
+	// stopping stops claiming and picking up notifications; cancelSends
+	// aborts in-flight deliveries once the shutdown deadline passes
+	stopping    chan struct{}
+	sendCtx     context.Context
+	cancelSends context.CancelFunc
+	pollerDone  chan struct{}
+	workers     sync.WaitGroup
+	started     bool
+	stopOnce    sync.Once
+}
+
+// NewNotificationService creates a notification service delivering through
+// tracker. Without a renderer, notifications created from a template fail;
+// without a limiter, notifications are not rate limited.
+func NewNotificationService(
+	db *gorm.DB,
+	config *Config,
+	logger *zap.Logger,
+	tracker *DeliveryTracker,
+	renderer Renderer,
+	limiter RateLimiter,
+) *NotificationService {
+	queues := make(map[Priority]chan *models.NotificationModel, len(priorities))
+	for _, priority := range priorities {
+		queues[priority] = make(chan *models.NotificationModel, config.Processing.QueueBufferSize)
+	}
+	sendCtx, cancelSends := context.WithCancel(context.Background())
+
+	return &NotificationService{
+		db:          db,
+		config:      config,
+		logger:      logger,
+		tracker:     tracker,
+		renderer:    renderer,
+		limiter:     limiter,
+		queues:      queues,
+		wake:        make(chan struct{}, 1),
+		stopping:    make(chan struct{}),
+		sendCtx:     sendCtx,
+		cancelSends: cancelSends,
+		pollerDone:  make(chan struct{}),
+	}
+}
+
+// Start starts the poller and the workers
+func (s *NotificationService) Start() {
+	workers := s.config.Processing.WorkerCount
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		s.workers.Add(1)
+		go s.work()
+	}
+	go s.poll()
+	s.started = true
+
+	s.logger.Info("notification service started", zap.Int("workers", workers))
+}
+
+// Stop stops claiming notifications and waits for in-flight deliveries to
+// finish. Claimed notifications no worker picked up are released. Once ctx
+// is done, in-flight deliveries are cancelled.
+func (s *NotificationService) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopping) })
+	if !s.started {
+		return nil
+	}
+	<-s.pollerDone
+
+	drained := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		s.cancelSends()
+		<-drained
+		err = fmt.Errorf("notification service stopped before in-flight deliveries finished: %w", ctx.Err())
+	}
+	s.cancelSends()
+
+	if releaseErr := s.release(); releaseErr != nil && err == nil {
+		err = releaseErr
+	}
+
+	s.logger.Info("notification service stopped")
+	return err
+}
+
+// Submit stores a notification for delivery and wakes the poller. The
+// notification is delivered at its ScheduledAt time, or immediately. Its
+// dispatch continues the trace of ctx, or of its TraceID and SpanID.
+func (s *NotificationService) Submit(ctx context.Context, notification *Notification) error {
+	ctx = ContextWithTrace(ctx, notification.TraceID, notification.SpanID)
+	ctx, span := tracer.Start(ctx, "notifications.submit", trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+
+	model := &models.NotificationModel{
+		ID:            uuid.New(),
+		EventID:       notification.EventID,
+		RecipientType: string(notification.RecipientType),
+		RecipientID:   notification.RecipientID,
+		Subject:       notification.Subject,
+		Body:          notification.Body,
+		BodyHTML:      notification.BodyHTML,
+		Data:          models.JSONB(notification.Data),
+		Priority:      string(notification.Priority),
+		Category:      notification.Category,
+		Status:        string(StatusPending),
+		ScheduledAt:   notification.ScheduledAt,
+		Metadata:      models.JSONB(notification.Metadata),
+		TraceID:       notification.TraceID,
+		Locale:        notification.Locale,
+	}
+	if notification.TemplateRevision > 0 {
+		revision := notification.TemplateRevision
+		model.TemplateRevision = &revision
+	}
+	for _, channel := range notification.Channels {
+		model.Channels = append(model.Channels, string(channel))
+	}
+	if model.Priority == "" {
+		model.Priority = string(PriorityNormal)
+	}
+	if model.ScheduledAt.IsZero() {
+		model.ScheduledAt = time.Now()
+	}
+	if sc := span.SpanContext(); sc.IsValid() {
+		if model.TraceID == "" {
+			model.TraceID = sc.TraceID().String()
+		}
+		if model.TraceID == sc.TraceID().String() {
+			model.SpanID = sc.SpanID().String()
+		}
+	}
+	if notification.RuleID != "" {
+		ruleID, err := uuid.Parse(notification.RuleID)
+		if err != nil {
+			return fmt.Errorf("invalid rule ID: %w", err)
+		}
+		model.RuleID = &ruleID
+	}
+	if notification.TemplateID != "" {
+		templateID, err := uuid.Parse(notification.TemplateID)
+		if err != nil {
+			return fmt.Errorf("invalid template ID: %w", err)
+		}
+		model.TemplateID = &templateID
+	}
+
+	if err := s.db.WithContext(ctx).Create(model).Error; err != nil {
+		err = fmt.Errorf("failed to create notification: %w", err)
+		recordError(ctx, err)
+		return err
+	}
+	notification.ID = model.ID.String()
+	notification.Status = StatusPending
+	notification.TraceID = model.TraceID
+	notification.SpanID = model.SpanID
+	span.SetAttributes(
+		attribute.String("notification.id", notification.ID),
+		attribute.String("notification.priority", model.Priority),
+	)
+
+	s.wakeUp()
+	return nil
+}
+
+// Get returns a notification
+func (s *NotificationService) Get(ctx context.Context, id string) (*Notification, error) {
+	notificationID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification ID: %w", err)
+	}
+
+	var model models.NotificationModel
+	err = s.db.WithContext(ctx).First(&model, "id = ?", notificationID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notification: %w", err)
+	}
+	return toNotification(&model), nil
+}
+
+// poll claims due notifications every PollInterval, or when woken, until the
+// service stops
+func (s *NotificationService) poll() {
+	defer close(s.pollerDone)
+
+	interval := s.config.Processing.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.claim(); err != nil {
+			s.logger.Error("failed to claim notifications", zap.Error(err))
+		}
+
+		select {
+		case <-s.stopping:
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// claim marks due notifications QUEUED, most urgent first, and queues them
+// for the workers, up to the free queue capacity. Notifications left QUEUED
+// or SENDING for StaleAfter, by a dispatcher that died, are claimed again.
+func (s *NotificationService) claim() error {
+	limit := s.config.Processing.QueueBufferSize
+	for _, queue := range s.queues {
+		if free := cap(queue) - len(queue); free < limit {
+			limit = free
+		}
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	staleAfter := s.config.Processing.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = 5 * time.Minute
+	}
+
+	var claimed []models.NotificationModel
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status IN ? AND scheduled_at <= ? AND (next_retry_at IS NULL OR next_retry_at <= ?))"+
+				" OR (status IN ? AND updated_at < ?)",
+				[]string{string(StatusPending), string(StatusRetrying)}, now, now,
+				[]string{string(StatusQueued), string(StatusSending)}, now.Add(-staleAfter)).
+			Order("CASE priority WHEN 'CRITICAL' THEN 0 WHEN 'HIGH' THEN 1 WHEN 'NORMAL' THEN 2 ELSE 3 END, scheduled_at").
+			Limit(limit).
+			Find(&claimed).Error
+		if err != nil {
+			return fmt.Errorf("failed to fetch due notifications: %w", err)
+		}
+		if len(claimed) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(claimed))
+		for i := range claimed {
+			ids[i] = claimed[i].ID
+		}
+		if err := tx.Model(&models.NotificationModel{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":     StatusQueued,
+				"updated_at": now,
+			}).Error; err != nil {
+			return fmt.Errorf("failed to queue notifications: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(claimed) > 0 {
+		s.traceClaim(now, claimed)
+	}
+
+	for i := range claimed {
+		notification := &claimed[i]
+		queue, ok := s.queues[Priority(notification.Priority)]
+		if !ok {
+			queue = s.queues[PriorityNormal]
+		}
+		queue <- notification
+	}
+	return nil
+}
+
+// traceClaim records a span for a claim, linked to the spans that submitted
+// the claimed notifications, since a claim continues many traces at once
+func (s *NotificationService) traceClaim(start time.Time, claimed []models.NotificationModel) {
+	links := make([]trace.Link, 0, len(claimed))
+	for i := range claimed {
+		if parent, ok := spanContext(claimed[i].TraceID, claimed[i].SpanID); ok {
+			links = append(links, trace.Link{
+				SpanContext: parent,
+				Attributes:  []attribute.KeyValue{attribute.String("notification.id", claimed[i].ID.String())},
+			})
+		}
+	}
+
+	_, span := tracer.Start(context.Background(), "notifications.claim",
+		trace.WithTimestamp(start),
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("notifications.claimed", len(claimed))))
+	span.End()
+}
+
+// work processes queued notifications until the service stops
+func (s *NotificationService) work() {
+	defer s.workers.Done()
+
+	for {
+		notification := s.next()
+		if notification == nil {
+			return
+		}
+		s.process(s.sendCtx, notification)
+	}
+}
+
+// next returns the most urgent queued notification, waiting for one, or nil
+// once the service stops
+func (s *NotificationService) next() *models.NotificationModel {
+	for {
+		select {
+		case <-s.stopping:
+			return nil
+		default:
+		}
+
+		// Take from the most urgent non-empty queue
+		for _, priority := range priorities {
+			select {
+			case notification := <-s.queues[priority]:
+				return notification
+			default:
+			}
+		}
+
+		select {
+		case <-s.stopping:
+			return nil
+		case notification := <-s.queues[PriorityCritical]:
+			return notification
+		case notification := <-s.queues[PriorityHigh]:
+			return notification
+		case notification := <-s.queues[PriorityNormal]:
+			return notification
+		case notification := <-s.queues[PriorityLow]:
+			return notification
+		}
+	}
+}
+
+// release returns notifications claimed but never picked up to PENDING, so
+// the next dispatcher delivers them without waiting for StaleAfter
+func (s *NotificationService) release() error {
+	var ids []uuid.UUID
+	for _, priority := range priorities {
+	drain:
+		for {
+			select {
+			case notification := <-s.queues[priority]:
+				ids = append(ids, notification.ID)
+			default:
+				break drain
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	err := s.db.Model(&models.NotificationModel{}).
+		Where("id IN ? AND status = ?", ids, StatusQueued).
+		Updates(map[string]interface{}{
+			"status":     StatusPending,
+			"updated_at": time.Now(),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to release queued notifications: %w", err)
+	}
+	return nil
+}
+
+// process delivers a claimed notification over the first channel its rule
+// and recipient allow. A channel whose provider rejects the notification is
+// skipped for the next one; other failures are retried on the same channel.
+// The dispatch span is a child of the span that submitted the notification.
+func (s *NotificationService) process(ctx context.Context, model *models.NotificationModel) {
+	ctx = ContextWithTrace(ctx, model.TraceID, model.SpanID)
+	ctx, span := tracer.Start(ctx, "notifications.dispatch",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("notification.id", model.ID.String()),
+			attribute.String("notification.priority", model.Priority),
+			attribute.Int("notification.attempt", model.RetryCount+1),
+		))
+	defer span.End()
+
+	notification := toNotification(model)
+	logger := s.logger.With(zap.String("notification_id", notification.ID))
+
+	route, err := s.route(ctx, model, notification)
+	if err != nil {
+		s.fail(ctx, model, err, false)
+		return
+	}
+	if route.deferUntil != nil {
+		s.update(ctx, model, map[string]interface{}{
+			"status":       StatusPending,
+			"scheduled_at": *route.deferUntil,
+		})
+		logger.Debug("notification deferred",
+			zap.String("reason", route.reason),
+			zap.Time("until", *route.deferUntil))
+		return
+	}
+	if route.channel == "" {
+		s.update(ctx, model, map[string]interface{}{
+			"status": StatusCancelled,
+			"error":  route.reason,
+		})
+		logger.Info("notification cancelled", zap.String("reason", route.reason))
+		return
+	}
+
+	span.SetAttributes(attribute.String("notification.channel", string(route.channel)))
+	notification.SelectedChannel = route.channel
+	notification.RecipientAddress = route.address
+	notification.UnsubscribeURL = s.tracker.suppressions.UnsubscribeURL(route.channel, route.address)
+	updates := map[string]interface{}{
+		"status":           StatusSending,
+		"selected_channel": route.channel,
+	}
+
+	if notification.TemplateID != "" {
+		if notification.Locale == "" {
+			notification.Locale = route.locale
+		}
+		if s.renderer == nil {
+			s.fail(ctx, model, errors.New("no renderer for templated notification"), true)
+			return
+		}
+		if err := s.renderer.RenderNotification(ctx, notification); err != nil {
+			s.fail(ctx, model, fmt.Errorf("failed to render notification: %w", err), false)
+			return
+		}
+		// Keep the rendered content so the notification can be reproduced
+		updates["subject"] = notification.Subject
+		updates["body"] = notification.Body
+		updates["body_html"] = notification.BodyHTML
+		updates["template_revision"] = notification.TemplateRevision
+		updates["locale"] = notification.Locale
+	}
+	s.update(ctx, model, updates)
+
+	result, err := s.tracker.Deliver(ctx, notification, model.RetryCount+1)
+	if err == nil {
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status":        result.Status,
+			"sent_at":       now,
+			"error":         "",
+			"next_retry_at": nil,
+		}
+		if result.Status == StatusDelivered {
+			updates["delivered_at"] = now
+		}
+		s.update(ctx, model, updates)
+		return
+	}
+
+	if errors.Is(err, ErrRejected) {
+		// Fall back to the next channel on the following claim
+		rejected := append(rejectedChannels(model), string(route.channel))
+		metadata := model.Metadata
+		if metadata == nil {
+			metadata = models.JSONB{}
+		}
+		metadata["rejected_channels"] = rejected
+		recordError(ctx, err)
+		s.update(ctx, model, map[string]interface{}{
+			"status":   StatusPending,
+			"error":    err.Error(),
+			"metadata": metadata,
+		})
+		logger.Warn("channel rejected notification",
+			zap.String("channel", string(route.channel)),
+			zap.Error(err))
+		s.wakeUp()
+		return
+	}
+
+	s.fail(ctx, model, err, false)
+}
+
+// fail schedules a retry of a notification whose delivery failed, or marks
+// it FAILED once MaxAttempts is reached or the failure is permanent
+func (s *NotificationService) fail(ctx context.Context, model *models.NotificationModel, err error, permanent bool) {
+	recordError(ctx, err)
+	retry := s.config.Processing.Retry
+	attempts := model.RetryCount + 1
+
+	if permanent || attempts >= retry.MaxAttempts {
+		s.update(ctx, model, map[string]interface{}{
+			"status":        StatusFailed,
+			"error":         err.Error(),
+			"retry_count":   attempts,
+			"next_retry_at": nil,
+		})
+		s.logger.Error("notification failed",
+			zap.String("notification_id", model.ID.String()),
+			zap.Int("attempts", attempts),
+			zap.Error(err))
+		return
+	}
+
+	nextRetry := time.Now().Add(retry.Backoff(attempts))
+	s.update(ctx, model, map[string]interface{}{
+		"status":        StatusRetrying,
+		"error":         err.Error(),
+		"retry_count":   attempts,
+		"next_retry_at": nextRetry,
+	})
+	s.logger.Warn("notification delivery failed, retrying",
+		zap.String("notification_id", model.ID.String()),
+		zap.Int("attempts", attempts),
+		zap.Time("next_retry_at", nextRetry),
+		zap.Error(err))
+}
+
+// update saves changes to a notification. A failed update is logged only:
+// the notification becomes stale and is claimed again.
+func (s *NotificationService) update(ctx context.Context, model *models.NotificationModel, updates map[string]interface{}) {
+	updates["updated_at"] = time.Now()
+	// Updates must land even when in-flight deliveries were cancelled
+	if ctx.Err() != nil {
+		ctx = context.Background()
+	}
+	if err := s.db.WithContext(ctx).Model(model).Updates(updates).Error; err != nil {
+		s.logger.Error("failed to update notification",
+			zap.String("notification_id", model.ID.String()),
+			zap.Error(err))
+	}
+}
+
+// wakeUp makes the poller claim notifications without waiting for the next
+// tick
+func (s *NotificationService) wakeUp() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// route is the channel selected for a notification. With no channel, the
+// notification is cancelled for reason, or deferred for reason.
+type route struct {
+	channel    Channel
+	address    string
+	locale     string
+	deferUntil *time.Time
+	reason     string
+}
+
+// route selects the channel of a notification: the first of its channels,
+// then the fallback channels of its rule, that has a provider, was not
+// rejected before, is allowed by the preferences of the recipient, has an
+// address that is not suppressed and is within its rate limits. Recipients
+// other than users and organizations take their ID as address. Over a rate
+// limit, the notification is dropped, deferred or downgraded to the next
+// channel per RateLimits.Overflow.
+func (s *NotificationService) route(
+	ctx context.Context,
+	model *models.NotificationModel,
+	notification *Notification,
+) (*route, error) {
+	candidates := notification.Channels
+	if model.RuleID != nil {
+		var rule models.RuleModel
+		err := s.db.WithContext(ctx).Select("id", "fallback_channels").First(&rule, "id = ?", *model.RuleID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to fetch notification rule: %w", err)
+		}
+		for _, channel := range rule.FallbackChannels {
+			candidates = append(candidates, Channel(channel))
+		}
+	}
+
+	var prefs *UserPreferences
+	if notification.RecipientType == RecipientTypeUser || notification.RecipientType == RecipientTypeOrganization {
+		var err error
+		if prefs, err = s.preferences(ctx, notification.RecipientID); err != nil {
+			return nil, err
+		}
+	}
+
+	var allowed map[Channel]bool
+	if prefs != nil {
+		if !prefs.Enabled {
+			return &route{reason: "recipient disabled notifications"}, nil
+		}
+		if category, ok := prefs.CategoryPrefs[notification.Category]; ok && notification.Category != "" {
+			if !category.Enabled {
+				return &route{reason: fmt.Sprintf("recipient disabled %s notifications", notification.Category)}, nil
+			}
+			if category.MinPriority != "" && priorityRank[notification.Priority] < priorityRank[category.MinPriority] {
+				return &route{reason: fmt.Sprintf("priority below %s minimum of recipient", category.MinPriority)}, nil
+			}
+			if len(category.Channels) > 0 {
+				allowed = make(map[Channel]bool, len(category.Channels))
+				for _, channel := range category.Channels {
+					allowed[channel] = true
+				}
+			}
+		}
+		if until := quietUntil(&prefs.QuietHours, notification.Priority, time.Now()); until != nil {
+			return &route{deferUntil: until, reason: "quiet hours"}, nil
+		}
+	}
+
+	rejected := make(map[Channel]bool)
+	for _, channel := range rejectedChannels(model) {
+		rejected[Channel(channel)] = true
+	}
+
+	// First channel over a rate limit, and the earliest time any has room
+	var throttledChannel Channel
+	var throttled *RateLimitDecision
+	var retryAfter time.Duration
+	// First channel skipped for a suppressed address
+	var suppressed Channel
+
+	seen := make(map[Channel]bool)
+	for _, channel := range candidates {
+		if seen[channel] || rejected[channel] {
+			continue
+		}
+		seen[channel] = true
+		if _, ok := s.tracker.providers[channel]; !ok {
+			continue
+		}
+		if allowed != nil && !allowed[channel] {
+			continue
+		}
+
+		address := notification.RecipientID
+		if prefs != nil {
+			var ok bool
+			if address, ok = channelAddress(prefs, channel); !ok {
+				continue
+			}
+		}
+
+		skip, err := s.suppressed(ctx, notification, channel, address)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			if suppressed == "" {
+				suppressed = channel
+			}
+			continue
+		}
+
+		decision, err := s.allow(ctx, notification, channel)
+		if err != nil {
+			return nil, err
+		}
+		if !decision.Allowed {
+			if throttled == nil {
+				throttledChannel, throttled, retryAfter = channel, decision, decision.RetryAfter
+			} else if decision.RetryAfter < retryAfter {
+				retryAfter = decision.RetryAfter
+			}
+			if s.config.RateLimits.Overflow == OverflowDowngrade {
+				continue
+			}
+			return s.throttle(throttledChannel, throttled, retryAfter), nil
+		}
+		if throttled != nil {
+			throttledTotal.WithLabelValues(throttled.Scope, string(throttledChannel), string(OverflowDowngrade)).Inc()
+		}
+
+		r := &route{channel: channel, address: address}
+		if prefs != nil {
+			r.locale = prefs.Locale
+		}
+		return r, nil
+	}
+
+	if throttled != nil {
+		return s.throttle(throttledChannel, throttled, retryAfter), nil
+	}
+	if suppressed != "" {
+		return &route{reason: fmt.Sprintf("address suppressed on %s", suppressed)}, nil
+	}
+	return &route{reason: "no eligible channel"}, nil
+}
+
+// suppressed reports whether the address of a notification is suppressed on
+// channel, recording the skipped channel in the audit trail
+func (s *NotificationService) suppressed(
+	ctx context.Context,
+	notification *Notification,
+	channel Channel,
+	address string,
+) (bool, error) {
+	if !s.config.Suppression.Enabled || address == "" {
+		return false, nil
+	}
+
+	suppression, err := s.tracker.suppressions.Check(ctx, channel, address)
+	if err != nil {
+		return false, err
+	}
+	if suppression == nil {
+		return false, nil
+	}
+	suppressedTotal.WithLabelValues(string(channel), string(suppression.Reason)).Inc()
+
+	err = recordAudit(ctx, s.db, &AuditEvent{
+		NotificationID: notification.ID,
+		EventType:      AuditNotificationSuppressed,
+		ActorType:      AuditActorSystem,
+		Details: map[string]interface{}{
+			"suppression_id": suppression.ID,
+			"channel":        channel,
+			"address":        suppression.Address,
+			"reason":         suppression.Reason,
+		},
+		TraceID: notification.TraceID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// allow checks the rate limits of a notification on channel. Critical
+// notifications are never throttled. When the limiter fails, notifications
+// are allowed with RateLimits.FailOpen and retried otherwise.
+func (s *NotificationService) allow(
+	ctx context.Context,
+	notification *Notification,
+	channel Channel,
+) (*RateLimitDecision, error) {
+	if s.limiter == nil || !s.config.RateLimits.Enabled || notification.Priority == PriorityCritical {
+		return &RateLimitDecision{Allowed: true}, nil
+	}
+
+	decision, err := s.limiter.Allow(ctx, notification, channel)
+	if err != nil {
+		if s.config.RateLimits.FailOpen {
+			s.logger.Warn("rate limit check failed, allowing notification",
+				zap.String("notification_id", notification.ID),
+				zap.Error(err))
+			return &RateLimitDecision{Allowed: true}, nil
+		}
+		return nil, fmt.Errorf("failed to check rate limits: %w", err)
+	}
+	return decision, nil
+}
+
+// throttle drops or defers a notification over a rate limit on channel.
+// Downgraded notifications are deferred until retryAfter once no other
+// channel has room.
+func (s *NotificationService) throttle(channel Channel, decision *RateLimitDecision, retryAfter time.Duration) *route {
+	action := s.config.RateLimits.Overflow
+	if action != OverflowDrop {
+		action = OverflowDefer
+	}
+	throttledTotal.WithLabelValues(decision.Scope, string(channel), string(action)).Inc()
+
+	reason := fmt.Sprintf("over %s rate limit on %s", decision.Scope, channel)
+	if action == OverflowDrop {
+		return &route{reason: reason}
+	}
+	until := time.Now().Add(retryAfter)
+	return &route{deferUntil: &until, reason: reason}
+}
+
+// channelAddress returns the address of a user on a channel, and whether the
+// user accepts notifications on it. Push tokens and Slack default channels
+// need no address.
+func channelAddress(prefs *UserPreferences, channel Channel) (string, bool) {
+	pref, hasPref := prefs.ChannelPrefs[channel]
+	if hasPref && !pref.Enabled {
+		return "", false
+	}
+	if pref.Address != "" {
+		return pref.Address, true
+	}
+
+	switch channel {
+	case ChannelEmail:
+		return prefs.Email, prefs.Email != ""
+	case ChannelSMS:
+		return prefs.Phone, prefs.Phone != ""
+	case ChannelWebhook:
+		return "", false
+	default:
+		return "", true
+	}
+}
+
+// quietUntil returns the end of the quiet hours now falls in, or nil when
+// the notification may be delivered now. Critical notifications break
+// through quiet hours that allow them.
+func quietUntil(quiet *QuietHours, priority Priority, now time.Time) *time.Time {
+	if !quiet.Enabled || (priority == PriorityCritical && quiet.AllowCritical) {
+		return nil
+	}
+	start, err := time.Parse("15:04", quiet.StartTime)
+	if err != nil {
+		return nil
+	}
+	end, err := time.Parse("15:04", quiet.EndTime)
+	if err != nil {
+		return nil
+	}
+	location, err := time.LoadLocation(quiet.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	var quietNow bool
+	if startMinute <= endMinute {
+		quietNow = minute >= startMinute && minute < endMinute
+	} else {
+		// Quiet hours spanning midnight
+		quietNow = minute >= startMinute || minute < endMinute
+	}
+	if !quietNow {
+		return nil
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, location)
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return &until
+}
+
+// preferences loads the preferences of a user, or nil when the user has
+// none
+func (s *NotificationService) preferences(ctx context.Context, userID string) (*UserPreferences, error) {
+	prefs, err := getPreferences(s.db.WithContext(ctx), userID)
+	if errors.Is(err, ErrPreferencesNotFound) {
+		return nil, nil
+	}
+	return prefs, err
+}
+
+// decodeJSONB decodes a JSONB column into a typed value
+func decodeJSONB(value models.JSONB, out interface{}) error {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// rejectedChannels returns the channels whose providers rejected a
+// notification
+func rejectedChannels(model *models.NotificationModel) []string {
+	var channels []string
+	if list, ok := model.Metadata["rejected_channels"].([]interface{}); ok {
+		for _, channel := range list {
+			if name, ok := channel.(string); ok {
+				channels = append(channels, name)
+			}
+		}
+	}
+	if list, ok := model.Metadata["rejected_channels"].([]string); ok {
+		channels = append(channels, list...)
+	}
+	return channels
+}
+
+// toNotification converts a notification row to a Notification
+func toNotification(model *models.NotificationModel) *Notification {
+	notification := &Notification{
+		ID:              model.ID.String(),
+		EventID:         model.EventID,
+		RecipientType:   RecipientType(model.RecipientType),
+		RecipientID:     model.RecipientID,
+		Subject:         model.Subject,
+		Body:            model.Body,
+		BodyHTML:        model.BodyHTML,
+		Data:            model.Data,
+		Priority:        Priority(model.Priority),
+		SelectedChannel: Channel(model.SelectedChannel),
+		Category:        model.Category,
+		Status:          Status(model.Status),
+		ScheduledAt:     model.ScheduledAt,
+		SentAt:          model.SentAt,
+		DeliveredAt:     model.DeliveredAt,
+		ReadAt:          model.ReadAt,
+		Error:           model.Error,
+		RetryCount:      model.RetryCount,
+		NextRetry:       model.NextRetryAt,
+		Metadata:        model.Metadata,
+		TraceID:         model.TraceID,
+		SpanID:          model.SpanID,
+		Locale:          model.Locale,
+		CreatedAt:       model.CreatedAt,
+		UpdatedAt:       model.UpdatedAt,
+	}
+	if model.RuleID != nil {
+		notification.RuleID = model.RuleID.String()
+	}
+	if model.TemplateID != nil {
+		notification.TemplateID = model.TemplateID.String()
+	}
+	if model.TemplateRevision != nil {
+		notification.TemplateRevision = *model.TemplateRevision
+	}
+	for _, channel := range model.Channels {
+		notification.Channels = append(notification.Channels, Channel(channel))
+	}
+	return notification
+}
@@ -0,0 +1,371 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package notifications
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications/models"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SuppressionReason is why an address is suppressed
+type SuppressionReason string
+
+const (
+	SuppressionHardBounce  SuppressionReason = "HARD_BOUNCE" // The address does not exist
+	SuppressionComplaint   SuppressionReason = "COMPLAINT"   // The recipient reported a message as spam
+	SuppressionUnsubscribe SuppressionReason = "UNSUBSCRIBE" // The recipient followed an unsubscribe link
+	SuppressionManual      SuppressionReason = "MANUAL"      // Suppressed by an operator
+)
+
+var (
+	// ErrSuppressionNotFound is returned for unknown suppressions
+	ErrSuppressionNotFound = errors.New("suppression not found")
+
+	// ErrInvalidUnsubscribeToken is returned for unsubscribe tokens that
+	// were not signed with the unsubscribe secret
+	ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+)
+
+var suppressedTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "dictamesh_notifications_suppressed_total",
+		Help: "Channels skipped for a suppressed address by channel and reason",
+	},
+	[]string{"channel", "reason"},
+)
+
+// Suppression is an address nothing is delivered to on a channel, or on
+// every channel when Channel is empty
+type Suppression struct {
+	ID             string
+	Channel        Channel
+	Address        string
+	Reason         SuppressionReason
+	Source         string // Provider or actor that suppressed the address
+	NotificationID string // Notification whose feedback suppressed the address
+	ExpiresAt      *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// SuppressionList stores suppressed addresses. Addresses are compared
+// case-insensitively.
+type SuppressionList struct {
+	db     *gorm.DB
+	config *Config
+	logger *zap.Logger
+}
+
+// NewSuppressionList creates a suppression list
+func NewSuppressionList(db *gorm.DB, config *Config, logger *zap.Logger) *SuppressionList {
+	return &SuppressionList{db: db, config: config, logger: logger}
+}
+
+// Add suppresses an address, replacing the reason and expiry of an existing
+// suppression of the address on the same channel
+func (l *SuppressionList) Add(ctx context.Context, suppression *Suppression, actorType string) error {
+	address := normalizeAddress(suppression.Address)
+	if address == "" {
+		return fmt.Errorf("suppression address is required")
+	}
+	switch suppression.Reason {
+	case SuppressionHardBounce, SuppressionComplaint, SuppressionUnsubscribe, SuppressionManual:
+	default:
+		return fmt.Errorf("unsupported suppression reason: %s", suppression.Reason)
+	}
+
+	now := time.Now()
+	model := models.SuppressionModel{
+		ID:        uuid.New(),
+		Channel:   string(suppression.Channel),
+		Address:   address,
+		Reason:    string(suppression.Reason),
+		Source:    suppression.Source,
+		ExpiresAt: suppression.ExpiresAt,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if suppression.NotificationID != "" {
+		id, err := uuid.Parse(suppression.NotificationID)
+		if err != nil {
+			return fmt.Errorf("invalid notification ID: %w", err)
+		}
+		model.NotificationID = &id
+	}
+
+	err := l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "address"}, {Name: "channel"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"reason", "source", "notification_id", "expires_at", "updated_at",
+			}),
+		}).Create(&model).Error
+		if err != nil {
+			return fmt.Errorf("failed to add suppression: %w", err)
+		}
+		// The ID of the existing row on conflict
+		if err := tx.Select("id", "created_at").
+			First(&model, "address = ? AND channel = ?", model.Address, model.Channel).Error; err != nil {
+			return fmt.Errorf("failed to fetch suppression: %w", err)
+		}
+
+		return recordAudit(ctx, tx, &AuditEvent{
+			NotificationID: suppression.NotificationID,
+			EventType:      AuditSuppressionAdded,
+			ActorType:      actorType,
+			ActorID:        suppression.Source,
+			Details: map[string]interface{}{
+				"suppression_id": model.ID.String(),
+				"channel":        model.Channel,
+				"address":        model.Address,
+				"reason":         model.Reason,
+			},
+			Timestamp: now,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	suppression.ID = model.ID.String()
+	suppression.Address = address
+	suppression.CreatedAt = model.CreatedAt
+	suppression.UpdatedAt = now
+	l.logger.Info("address suppressed",
+		zap.String("channel", model.Channel),
+		zap.String("reason", model.Reason),
+		zap.String("source", model.Source))
+	return nil
+}
+
+// Remove lifts a suppression
+func (l *SuppressionList) Remove(ctx context.Context, id string, actorType, actorID string) error {
+	suppressionID, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("invalid suppression ID: %w", err)
+	}
+
+	return l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var model models.SuppressionModel
+		err := tx.Clauses(clause.Returning{}).Where("id = ?", suppressionID).Delete(&model).Error
+		if err != nil {
+			return fmt.Errorf("failed to remove suppression: %w", err)
+		}
+		if model.Address == "" {
+			return ErrSuppressionNotFound
+		}
+
+		return recordAudit(ctx, tx, &AuditEvent{
+			EventType: AuditSuppressionRemoved,
+			ActorType: actorType,
+			ActorID:   actorID,
+			Details: map[string]interface{}{
+				"suppression_id": id,
+				"channel":        model.Channel,
+				"address":        model.Address,
+				"reason":         model.Reason,
+			},
+		})
+	})
+}
+
+// Check returns the suppression of an address on channel, either for the
+// channel or global, or nil when the address is not suppressed
+func (l *SuppressionList) Check(ctx context.Context, channel Channel, address string) (*Suppression, error) {
+	var model models.SuppressionModel
+	err := l.db.WithContext(ctx).
+		Where("address = ? AND channel IN ?", normalizeAddress(address), []string{string(channel), ""}).
+		Where("(expires_at IS NULL OR expires_at > ?)", time.Now()).
+		Order("channel DESC").
+		First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check suppression list: %w", err)
+	}
+	return toSuppression(&model), nil
+}
+
+// List returns suppressions, newest first. A non-empty channel or address
+// filters them; the channel "*" selects global suppressions.
+func (l *SuppressionList) List(ctx context.Context, channel, address string, limit, offset int) ([]Suppression, error) {
+	query := l.db.WithContext(ctx)
+	switch channel {
+	case "":
+	case "*":
+		query = query.Where("channel = ''")
+	default:
+		query = query.Where("channel = ?", channel)
+	}
+	if address != "" {
+		query = query.Where("address = ?", normalizeAddress(address))
+	}
+
+	var rows []models.SuppressionModel
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list suppressions: %w", err)
+	}
+	suppressions := make([]Suppression, len(rows))
+	for i := range rows {
+		suppressions[i] = *toSuppression(&rows[i])
+	}
+	return suppressions, nil
+}
+
+// UnsubscribeURL returns the link suppressing an address on channel, or an
+// empty string without an UnsubscribeURL
+func (l *SuppressionList) UnsubscribeURL(channel Channel, address string) string {
+	if l.config.Suppression.UnsubscribeURL == "" {
+		return ""
+	}
+	separator := "?"
+	if strings.Contains(l.config.Suppression.UnsubscribeURL, "?") {
+		separator = "&"
+	}
+	return l.config.Suppression.UnsubscribeURL + separator + "token=" + l.UnsubscribeToken(channel, address)
+}
+
+// UnsubscribeToken signs a channel and address. Tokens do not expire, as
+// unsubscribe links must keep working in old messages.
+func (l *SuppressionList) UnsubscribeToken(channel Channel, address string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(string(channel) + "\n" + normalizeAddress(address)))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(l.sign(payload))
+}
+
+// Unsubscribe suppresses the address of an unsubscribe token
+func (l *SuppressionList) Unsubscribe(ctx context.Context, token string) (*Suppression, error) {
+	channel, address, err := l.parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	suppression := &Suppression{
+		Channel: channel,
+		Address: address,
+		Reason:  SuppressionUnsubscribe,
+		Source:  "unsubscribe",
+	}
+	if err := l.Add(ctx, suppression, AuditActorRecipient); err != nil {
+		return nil, err
+	}
+	return suppression, nil
+}
+
+// parseToken verifies an unsubscribe token and returns its channel and
+// address
+func (l *SuppressionList) parseToken(token string) (Channel, string, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || l.config.Suppression.UnsubscribeSecret == "" {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, l.sign(payload)) {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	channel, address, ok := strings.Cut(string(decoded), "\n")
+	if !ok || address == "" {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	return Channel(channel), address, nil
+}
+
+// sign returns the HMAC-SHA256 of an unsubscribe token payload
+func (l *SuppressionList) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(l.config.Suppression.UnsubscribeSecret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// unsubscribePage is the page of the unsubscribe handler
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body>
+{{if .Done}}<p>You have been unsubscribed.</p>
+{{else if .Invalid}}<p>This unsubscribe link is invalid.</p>
+{{else}}<form method="post"><p>Stop receiving these notifications?</p><button type="submit">Unsubscribe</button></form>
+{{end}}</body>
+</html>
+`))
+
+// UnsubscribeHandler serves the unsubscribe links of UnsubscribeURL. GET
+// asks for confirmation, so link scanners do not unsubscribe recipients;
+// POST unsubscribes, including RFC 8058 one-click requests.
+func (l *SuppressionList) UnsubscribeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		page := struct{ Done, Invalid bool }{}
+		status := http.StatusOK
+
+		switch r.Method {
+		case http.MethodGet:
+			_, _, err := l.parseToken(token)
+			page.Invalid = err != nil
+		case http.MethodPost:
+			_, err := l.Unsubscribe(r.Context(), token)
+			switch {
+			case errors.Is(err, ErrInvalidUnsubscribeToken):
+				page.Invalid = true
+				status = http.StatusBadRequest
+			case err != nil:
+				l.logger.Error("failed to unsubscribe", zap.Error(err))
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			default:
+				page.Done = true
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		_ = unsubscribePage.Execute(w, page)
+	})
+}
+
+// normalizeAddress trims and lower-cases an address
+func normalizeAddress(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// toSuppression converts a suppression row
+func toSuppression(model *models.SuppressionModel) *Suppression {
+	suppression := &Suppression{
+		ID:        model.ID.String(),
+		Channel:   Channel(model.Channel),
+		Address:   model.Address,
+		Reason:    SuppressionReason(model.Reason),
+		Source:    model.Source,
+		ExpiresAt: model.ExpiresAt,
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
+	if model.NotificationID != nil {
+		suppression.NotificationID = model.NotificationID.String()
+	}
+	return suppression
+}
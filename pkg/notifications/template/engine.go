@@ -0,0 +1,508 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package template renders notification templates. Every save of a template
+// is kept as an immutable revision, and notifications record the revision
+// and locale they were rendered from, so their content can be reproduced.
+package template
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+	"github.com/click2-run/dictamesh/pkg/notifications/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNotFound is returned for unknown templates and revisions
+	ErrNotFound = notifications.ErrTemplateNotFound
+
+	// ErrDisabled is returned when rendering the latest revision of a
+	// disabled template
+	ErrDisabled = errors.New("template disabled")
+
+	// ErrInvalidTemplate is returned for content that fails to parse or
+	// references undeclared variables
+	ErrInvalidTemplate = notifications.ErrInvalidTemplate
+
+	// ErrNoContent is returned when a template has no content for a channel
+	ErrNoContent = errors.New("no template content for channel")
+
+	// ErrMissingVariables is returned when declared variables are not given
+	ErrMissingVariables = errors.New("missing template variables")
+)
+
+// Engine stores template revisions and renders them. Revisions never change,
+// so compiled revisions are cached when TemplateCaching is enabled.
+type Engine struct {
+	db     *gorm.DB
+	config *notifications.Config
+	logger *zap.Logger
+
+	mu    sync.RWMutex
+	cache map[revisionKey]*compiledTemplate
+}
+
+// revisionKey identifies a template revision
+type revisionKey struct {
+	templateID uuid.UUID
+	revision   int
+}
+
+// NewEngine creates a template engine
+func NewEngine(db *gorm.DB, config *notifications.Config, logger *zap.Logger) *Engine {
+	return &Engine{
+		db:     db,
+		config: config,
+		logger: logger,
+		cache:  make(map[revisionKey]*compiledTemplate),
+	}
+}
+
+// Save validates a template and stores its content as a new revision,
+// creating the template when it has no ID. The ID and Revision of tmpl are
+// set to those saved.
+func (e *Engine) Save(ctx context.Context, tmpl *notifications.NotificationTemplate) error {
+	if _, err := compile(tmpl); err != nil {
+		return err
+	}
+
+	channels, err := toJSONB(tmpl.Channels)
+	if err != nil {
+		return fmt.Errorf("failed to encode template channels: %w", err)
+	}
+	translations, err := toJSONB(tmpl.Translations)
+	if err != nil {
+		return fmt.Errorf("failed to encode template translations: %w", err)
+	}
+	// Variables are keyed by name, leaving room for per-variable settings
+	variables := make(models.JSONB, len(tmpl.Variables))
+	for _, name := range tmpl.Variables {
+		variables[name] = map[string]interface{}{}
+	}
+
+	return e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		model := models.TemplateModel{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			CreatedBy: tmpl.CreatedBy,
+		}
+		exists := tmpl.ID != ""
+		if exists {
+			id, err := uuid.Parse(tmpl.ID)
+			if err != nil {
+				return fmt.Errorf("invalid template ID: %w", err)
+			}
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&model, "id = ?", id).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %s", ErrNotFound, tmpl.ID)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to fetch template: %w", err)
+			}
+		}
+
+		model.Name = tmpl.Name
+		model.Description = tmpl.Description
+		model.Channels = channels
+		model.Translations = translations
+		model.Variables = variables
+		model.SchemaVersion = tmpl.SchemaVersion
+		model.Version = tmpl.Version
+		model.Enabled = tmpl.Enabled
+		model.Tags = tmpl.Tags
+		model.Revision++
+		model.UpdatedAt = now
+
+		if exists {
+			err = tx.Save(&model).Error
+		} else {
+			// Select every column so a disabled template is not created with
+			// the enabled default
+			err = tx.Select("*").Create(&model).Error
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save template: %w", err)
+		}
+
+		revision := models.TemplateRevisionModel{
+			TemplateID:   model.ID,
+			Revision:     model.Revision,
+			Channels:     channels,
+			Translations: translations,
+			Variables:    variables,
+			Version:      tmpl.Version,
+			CreatedAt:    now,
+			CreatedBy:    tmpl.CreatedBy,
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return fmt.Errorf("failed to create template revision: %w", err)
+		}
+
+		tmpl.ID = model.ID.String()
+		tmpl.Revision = model.Revision
+		tmpl.CreatedAt = model.CreatedAt
+		tmpl.UpdatedAt = now
+		return nil
+	})
+}
+
+// Revisions returns the revisions of a template, the latest first
+func (e *Engine) Revisions(ctx context.Context, templateID string) ([]notifications.NotificationTemplate, error) {
+	id, err := uuid.Parse(templateID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template ID: %w", err)
+	}
+
+	var model models.TemplateModel
+	err = e.db.WithContext(ctx).First(&model, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, templateID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch template: %w", err)
+	}
+
+	var revisions []models.TemplateRevisionModel
+	err = e.db.WithContext(ctx).
+		Where("template_id = ?", id).
+		Order("revision DESC").
+		Find(&revisions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch template revisions: %w", err)
+	}
+
+	result := make([]notifications.NotificationTemplate, 0, len(revisions))
+	for i := range revisions {
+		tmpl, err := toTemplate(&model, &revisions[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *tmpl)
+	}
+	return result, nil
+}
+
+// Get returns the latest revision of a template
+func (e *Engine) Get(ctx context.Context, templateID string) (*notifications.NotificationTemplate, error) {
+	id, err := uuid.Parse(templateID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template ID: %w", err)
+	}
+	return e.latest(e.db.WithContext(ctx).Where("id = ?", id), templateID)
+}
+
+// GetByName returns the latest revision of the template named name
+func (e *Engine) GetByName(ctx context.Context, name string) (*notifications.NotificationTemplate, error) {
+	return e.latest(e.db.WithContext(ctx).Where("name = ?", name), name)
+}
+
+// List returns the latest revision of templates, ordered by name
+func (e *Engine) List(ctx context.Context, limit, offset int) ([]notifications.NotificationTemplate, error) {
+	var templates []models.TemplateModel
+	err := e.db.WithContext(ctx).Order("name").Limit(limit).Offset(offset).Find(&templates).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	if len(templates) == 0 {
+		return nil, nil
+	}
+
+	latest := make([][]interface{}, len(templates))
+	for i, model := range templates {
+		latest[i] = []interface{}{model.ID, model.Revision}
+	}
+	var revisions []models.TemplateRevisionModel
+	err = e.db.WithContext(ctx).Where("(template_id, revision) IN ?", latest).Find(&revisions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch template revisions: %w", err)
+	}
+	byTemplate := make(map[uuid.UUID]*models.TemplateRevisionModel, len(revisions))
+	for i := range revisions {
+		byTemplate[revisions[i].TemplateID] = &revisions[i]
+	}
+
+	result := make([]notifications.NotificationTemplate, 0, len(templates))
+	for i := range templates {
+		revision, ok := byTemplate[templates[i].ID]
+		if !ok {
+			continue
+		}
+		tmpl, err := toTemplate(&templates[i], revision)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *tmpl)
+	}
+	return result, nil
+}
+
+// Disable disables a template, so notifications can no longer be created
+// from its latest revision. Templates are never deleted, as notifications
+// keep referencing the revisions they were rendered from.
+func (e *Engine) Disable(ctx context.Context, templateID string) error {
+	id, err := uuid.Parse(templateID)
+	if err != nil {
+		return fmt.Errorf("invalid template ID: %w", err)
+	}
+
+	result := e.db.WithContext(ctx).Model(&models.TemplateModel{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"enabled": false, "updated_at": time.Now()})
+	if result.Error != nil {
+		return fmt.Errorf("failed to disable template: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, templateID)
+	}
+	return nil
+}
+
+// latest returns the latest revision of the template selected by query
+func (e *Engine) latest(query *gorm.DB, ref string) (*notifications.NotificationTemplate, error) {
+	var model models.TemplateModel
+	err := query.First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch template: %w", err)
+	}
+
+	var revision models.TemplateRevisionModel
+	err = e.db.WithContext(query.Statement.Context).
+		First(&revision, "template_id = ? AND revision = ?", model.ID, model.Revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: revision %d of %s", ErrNotFound, model.Revision, model.Name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch template revision: %w", err)
+	}
+	return toTemplate(&model, &revision)
+}
+
+// Render renders the latest revision of a template for a channel and locale
+func (e *Engine) Render(
+	ctx context.Context,
+	templateID string,
+	channel notifications.Channel,
+	locale string,
+	vars map[string]interface{},
+) (*Rendered, error) {
+	return e.RenderRevision(ctx, templateID, 0, channel, locale, vars)
+}
+
+// RenderRevision renders a revision of a template for a channel and locale.
+// Revision 0 is the latest revision, which must be enabled; earlier
+// revisions render regardless, to reproduce notifications sent from them.
+func (e *Engine) RenderRevision(
+	ctx context.Context,
+	templateID string,
+	revision int,
+	channel notifications.Channel,
+	locale string,
+	vars map[string]interface{},
+) (*Rendered, error) {
+	compiled, err := e.load(ctx, templateID, revision)
+	if err != nil {
+		return nil, err
+	}
+	return e.execute(ctx, compiled, channel, locale, vars)
+}
+
+// Preview renders a template without saving it, validating it as Save does
+func (e *Engine) Preview(
+	ctx context.Context,
+	tmpl *notifications.NotificationTemplate,
+	channel notifications.Channel,
+	locale string,
+	vars map[string]interface{},
+) (*Rendered, error) {
+	compiled, err := compile(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	return e.execute(ctx, compiled, channel, locale, vars)
+}
+
+// RenderNotification renders the content of a notification from its
+// template for its selected channel and locale, with its data as variables.
+// Notifications rendered before render their recorded revision again.
+func (e *Engine) RenderNotification(ctx context.Context, notification *notifications.Notification) error {
+	rendered, err := e.RenderRevision(ctx, notification.TemplateID, notification.TemplateRevision,
+		notification.SelectedChannel, notification.Locale, notification.Data)
+	if err != nil {
+		return err
+	}
+
+	notification.Subject = rendered.Subject
+	notification.Body = rendered.Body
+	notification.BodyHTML = rendered.BodyHTML
+	notification.TemplateRevision = rendered.Revision
+	if len(rendered.Data) > 0 {
+		data := make(map[string]interface{}, len(notification.Data)+len(rendered.Data))
+		for key, value := range rendered.Data {
+			data[key] = value
+		}
+		for key, value := range notification.Data {
+			data[key] = value
+		}
+		notification.Data = data
+	}
+	return nil
+}
+
+// execute renders a compiled template, giving up after TemplateTimeout
+func (e *Engine) execute(
+	ctx context.Context,
+	compiled *compiledTemplate,
+	channel notifications.Channel,
+	locale string,
+	vars map[string]interface{},
+) (*Rendered, error) {
+	timeout := e.config.Processing.TemplateTimeout
+	if timeout <= 0 {
+		return compiled.render(channel, locale, vars)
+	}
+
+	type result struct {
+		rendered *Rendered
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		rendered, err := compiled.render(channel, locale, vars)
+		done <- result{rendered, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.rendered, r.err
+	case <-timer.C:
+		return nil, fmt.Errorf("rendering template %s timed out after %s", compiled.id, timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// load returns a compiled template revision, the latest when revision is 0
+func (e *Engine) load(ctx context.Context, templateID string, revision int) (*compiledTemplate, error) {
+	id, err := uuid.Parse(templateID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template ID: %w", err)
+	}
+
+	var model models.TemplateModel
+	err = e.db.WithContext(ctx).First(&model, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, templateID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch template: %w", err)
+	}
+	if revision == 0 {
+		if !model.Enabled {
+			return nil, fmt.Errorf("%w: %s", ErrDisabled, model.Name)
+		}
+		revision = model.Revision
+	}
+
+	key := revisionKey{templateID: id, revision: revision}
+	e.mu.RLock()
+	compiled, ok := e.cache[key]
+	e.mu.RUnlock()
+	if ok {
+		return compiled, nil
+	}
+
+	var stored models.TemplateRevisionModel
+	err = e.db.WithContext(ctx).
+		First(&stored, "template_id = ? AND revision = ?", id, revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: revision %d of %s", ErrNotFound, revision, model.Name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch template revision: %w", err)
+	}
+
+	tmpl, err := toTemplate(&model, &stored)
+	if err != nil {
+		return nil, err
+	}
+	if compiled, err = compile(tmpl); err != nil {
+		return nil, fmt.Errorf("failed to compile revision %d of %s: %w", revision, model.Name, err)
+	}
+
+	if e.config.Processing.TemplateCaching {
+		e.mu.Lock()
+		e.cache[key] = compiled
+		e.mu.Unlock()
+	}
+	return compiled, nil
+}
+
+// toTemplate converts a template revision to a NotificationTemplate
+func toTemplate(model *models.TemplateModel, revision *models.TemplateRevisionModel) (*notifications.NotificationTemplate, error) {
+	tmpl := &notifications.NotificationTemplate{
+		ID:            model.ID.String(),
+		Name:          model.Name,
+		Description:   model.Description,
+		SchemaVersion: model.SchemaVersion,
+		Version:       revision.Version,
+		Revision:      revision.Revision,
+		Enabled:       model.Enabled,
+		CreatedAt:     revision.CreatedAt,
+		UpdatedAt:     revision.CreatedAt,
+		CreatedBy:     revision.CreatedBy,
+		Tags:          model.Tags,
+	}
+	if err := fromJSONB(revision.Channels, &tmpl.Channels); err != nil {
+		return nil, fmt.Errorf("invalid channels of template %s: %w", model.Name, err)
+	}
+	if err := fromJSONB(revision.Translations, &tmpl.Translations); err != nil {
+		return nil, fmt.Errorf("invalid translations of template %s: %w", model.Name, err)
+	}
+	for name := range revision.Variables {
+		tmpl.Variables = append(tmpl.Variables, name)
+	}
+	sort.Strings(tmpl.Variables)
+	return tmpl, nil
+}
+
+// toJSONB encodes a value into a JSONB column
+func toJSONB(value interface{}) (models.JSONB, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var out models.JSONB
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// fromJSONB decodes a JSONB column into a typed value
+func fromJSONB(value models.JSONB, out interface{}) error {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,300 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	"github.com/click2-run/dictamesh/pkg/notifications"
+)
+
+// Rendered is the content of a template rendered for a channel and locale
+type Rendered struct {
+	TemplateID string
+	Revision   int
+	Channel    notifications.Channel
+	Locale     string // Translation used; empty for the channel content
+	Subject    string
+	Body       string
+	BodyHTML   string
+	Data       map[string]interface{} // Static data of the channel content
+}
+
+// content is compiled channel or translation content. Parts missing from a
+// translation fall back to the channel content.
+type content struct {
+	subject  *texttemplate.Template
+	body     *texttemplate.Template
+	bodyHTML *htmltemplate.Template
+}
+
+// compiledTemplate is a template revision with its content compiled
+type compiledTemplate struct {
+	id           string
+	revision     int
+	variables    []string
+	channels     map[notifications.Channel]*content
+	data         map[notifications.Channel]map[string]interface{}
+	translations map[string]*content
+}
+
+// compile compiles the content of a template. Content that fails to parse
+// or references variables the template does not declare is rejected.
+func compile(tmpl *notifications.NotificationTemplate) (*compiledTemplate, error) {
+	if len(tmpl.Channels) == 0 {
+		return nil, fmt.Errorf("%w: template has no channel content", ErrInvalidTemplate)
+	}
+
+	declared := make(map[string]bool, len(tmpl.Variables))
+	for _, name := range tmpl.Variables {
+		declared[name] = true
+	}
+
+	compiled := &compiledTemplate{
+		id:           tmpl.ID,
+		revision:     tmpl.Revision,
+		variables:    append([]string(nil), tmpl.Variables...),
+		channels:     make(map[notifications.Channel]*content, len(tmpl.Channels)),
+		data:         make(map[notifications.Channel]map[string]interface{}, len(tmpl.Channels)),
+		translations: make(map[string]*content, len(tmpl.Translations)),
+	}
+	sort.Strings(compiled.variables)
+
+	for channel, source := range tmpl.Channels {
+		c, err := compileContent(string(channel), source.Subject, source.Body, source.BodyHTML, declared)
+		if err != nil {
+			return nil, err
+		}
+		compiled.channels[channel] = c
+		compiled.data[channel] = source.Data
+	}
+
+	for key, source := range tmpl.Translations {
+		if _, channel, ok := strings.Cut(key, "/"); ok {
+			if _, exists := tmpl.Channels[notifications.Channel(channel)]; !exists {
+				return nil, fmt.Errorf("%w: translation %s has no %s content to translate", ErrInvalidTemplate, key, channel)
+			}
+		}
+		c, err := compileContent(key, source.Subject, source.Body, source.BodyHTML, declared)
+		if err != nil {
+			return nil, err
+		}
+		compiled.translations[key] = c
+	}
+
+	return compiled, nil
+}
+
+// compileContent parses the subject and body as text templates and the HTML
+// body as an HTML template, which escapes the values it renders
+func compileContent(name, subject, body, bodyHTML string, declared map[string]bool) (*content, error) {
+	c := &content{}
+	refs := make(map[string]bool)
+
+	parseText := func(part, source string) (*texttemplate.Template, error) {
+		if source == "" {
+			return nil, nil
+		}
+		t, err := texttemplate.New(name + "/" + part).Option("missingkey=error").Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+		if t.Tree != nil {
+			references(t.Tree.Root, true, refs)
+		}
+		return t, nil
+	}
+
+	var err error
+	if c.subject, err = parseText("subject", subject); err != nil {
+		return nil, err
+	}
+	if c.body, err = parseText("body", body); err != nil {
+		return nil, err
+	}
+	if bodyHTML != "" {
+		t, err := htmltemplate.New(name + "/body_html").Option("missingkey=error").Parse(bodyHTML)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+		if t.Tree != nil {
+			references(t.Tree.Root, true, refs)
+		}
+		c.bodyHTML = t
+	}
+
+	var undeclared []string
+	for ref := range refs {
+		if !declared[ref] {
+			undeclared = append(undeclared, ref)
+		}
+	}
+	if len(undeclared) > 0 {
+		sort.Strings(undeclared)
+		return nil, fmt.Errorf("%w: %s uses undeclared variables %s",
+			ErrInvalidTemplate, name, strings.Join(undeclared, ", "))
+	}
+	return c, nil
+}
+
+// references collects the variables a template reads from its data: fields
+// of the root dot, and fields of $ anywhere. Inside range and with the dot
+// is another value, so only $ references count there.
+func references(node parse.Node, root bool, refs map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			references(child, root, refs)
+		}
+	case *parse.ActionNode:
+		references(n.Pipe, root, refs)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				references(arg, root, refs)
+			}
+		}
+	case *parse.FieldNode:
+		if root {
+			refs[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			refs[n.Ident[1]] = true
+		}
+	case *parse.ChainNode:
+		references(n.Node, root, refs)
+	case *parse.TemplateNode:
+		references(n.Pipe, root, refs)
+	case *parse.IfNode:
+		references(n.Pipe, root, refs)
+		references(n.List, root, refs)
+		references(n.ElseList, root, refs)
+	case *parse.RangeNode:
+		references(n.Pipe, root, refs)
+		references(n.List, false, refs)
+		references(n.ElseList, root, refs)
+	case *parse.WithNode:
+		references(n.Pipe, root, refs)
+		references(n.List, false, refs)
+		references(n.ElseList, root, refs)
+	}
+}
+
+// render renders the content of a channel in locale, checking every declared
+// variable is given
+func (t *compiledTemplate) render(
+	channel notifications.Channel,
+	locale string,
+	vars map[string]interface{},
+) (*Rendered, error) {
+	base, ok := t.channels[channel]
+	if !ok {
+		return nil, fmt.Errorf("%w: template %s has no %s content", ErrNoContent, t.id, channel)
+	}
+
+	var missing []string
+	for _, name := range t.variables {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingVariables, strings.Join(missing, ", "))
+	}
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+
+	subject, body, bodyHTML := base.subject, base.body, base.bodyHTML
+	translation, resolved := t.translation(channel, locale)
+	if translation != nil {
+		if translation.subject != nil {
+			subject = translation.subject
+		}
+		if translation.body != nil {
+			body = translation.body
+		}
+		if translation.bodyHTML != nil {
+			bodyHTML = translation.bodyHTML
+		}
+	}
+
+	rendered := &Rendered{
+		TemplateID: t.id,
+		Revision:   t.revision,
+		Channel:    channel,
+		Locale:     resolved,
+		Data:       t.data[channel],
+	}
+	var err error
+	if rendered.Subject, err = executeText(subject, vars); err != nil {
+		return nil, fmt.Errorf("failed to render subject: %w", err)
+	}
+	if rendered.Body, err = executeText(body, vars); err != nil {
+		return nil, fmt.Errorf("failed to render body: %w", err)
+	}
+	if bodyHTML != nil {
+		var buf bytes.Buffer
+		if err := bodyHTML.Execute(&buf, vars); err != nil {
+			return nil, fmt.Errorf("failed to render HTML body: %w", err)
+		}
+		rendered.BodyHTML = buf.String()
+	}
+	return rendered, nil
+}
+
+// executeText renders a text template, or nothing when there is none
+func executeText(t *texttemplate.Template, vars map[string]interface{}) (string, error) {
+	if t == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// translation returns the translation of a channel for the first locale of
+// the fallback chain of locale with one, and that locale. "pt-BR" looks up
+// "pt-BR/EMAIL", "pt-BR", "pt/EMAIL" and "pt" for email.
+func (t *compiledTemplate) translation(channel notifications.Channel, locale string) (*content, string) {
+	for _, candidate := range fallbackLocales(locale) {
+		if c, ok := t.translations[candidate+"/"+string(channel)]; ok {
+			return c, candidate
+		}
+		if c, ok := t.translations[candidate]; ok {
+			return c, candidate
+		}
+	}
+	return nil, ""
+}
+
+// fallbackLocales returns a locale followed by its parents, e.g. "pt-BR"
+// and "pt"
+func fallbackLocales(locale string) []string {
+	locale = strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+	if locale == "" {
+		return nil
+	}
+	locales := []string{locale}
+	for i := strings.LastIndex(locale, "-"); i > 0; i = strings.LastIndex(locale, "-") {
+		locale = locale[:i]
+		locales = append(locales, locale)
+	}
+	return locales
+}
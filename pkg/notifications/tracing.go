@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package notifications
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer traces notifications from their submission to the provider calls
+// delivering them. Spans are exported by the tracer provider of the process.
+var tracer = otel.Tracer("notifications")
+
+// propagator carries trace context in W3C traceparent headers, which is how
+// events and HTTP requests carry it into DictaMesh
+var propagator = propagation.TraceContext{}
+
+// InjectTrace sets the traceparent header of an outgoing request from the
+// span of ctx. Providers call it on every request they make.
+func InjectTrace(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// TraceParent returns the traceparent header value of the span of ctx, or ""
+// when ctx has no span. Email providers send it as a message header.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// ExtractTrace returns ctx with the remote span of the traceparent header of
+// an incoming request as parent
+func ExtractTrace(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// ContextWithTrace returns ctx with the remote span traceID/spanID as parent,
+// such as the span of a consumed event. ctx is returned unchanged when it has
+// a span already or the IDs are not valid hex IDs.
+func ContextWithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	parent, ok := spanContext(traceID, spanID)
+	if !ok {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, parent)
+}
+
+// spanContext parses a remote span context. Remote spans were recorded by
+// their producer, so they are marked sampled.
+func spanContext(traceID, spanID string) (trace.SpanContext, bool) {
+	tid, err := trace.TraceIDFromHex(traceID)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	sid, err := trace.SpanIDFromHex(spanID)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}), true
+}
+
+// recordError records err on the span of ctx and marks the span failed
+func recordError(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
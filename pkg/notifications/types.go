@@ -45,6 +45,17 @@ const (
 	StatusFailed    Status = "FAILED"
 	StatusRetrying  Status = "RETRYING"
 	StatusCancelled Status = "CANCELLED"
+	StatusBounced   Status = "BOUNCED"  // Delivery attempt rejected by the recipient server
+	StatusRejected  Status = "REJECTED" // Delivery attempt refused by the provider
+)
+
+// PushPlatform identifies the platform of a push token
+type PushPlatform string
+
+const (
+	PushPlatformAndroid PushPlatform = "android" // Delivered through FCM
+	PushPlatformIOS     PushPlatform = "ios"     // Delivered through APNs
+	PushPlatformWeb     PushPlatform = "web"     // FCM web registration tokens
 )
 
 // RecipientType defines the type of notification recipient
@@ -55,6 +66,10 @@ const (
 	RecipientTypeRole   RecipientType = "ROLE"
 	RecipientTypeGroup  RecipientType = "GROUP"
 	RecipientTypeSystem RecipientType = "SYSTEM"
+
+	// Organizations are addressed through the preferences stored under
+	// their ID, like users
+	RecipientTypeOrganization RecipientType = "ORGANIZATION"
 )
 
 // Notification represents a notification instance
@@ -62,13 +77,17 @@ type Notification struct {
 	ID string
 
 	// Source tracking
-	EventID    string
-	RuleID     string
-	TemplateID string
+	EventID          string
+	RuleID           string
+	TemplateID       string
+	TemplateRevision int    // Revision rendered; 0 renders the latest
+	Locale           string // Locale rendered, e.g. "pt-BR"
 
 	// Recipient information
-	RecipientType RecipientType
-	RecipientID   string
+	RecipientType    RecipientType
+	RecipientID      string
+	RecipientAddress string // Channel address resolved from preferences, e.g. an email address
+	UnsubscribeURL   string // Link suppressing the address on the selected channel
 
 	// Content
 	Subject  string
@@ -80,6 +99,7 @@ type Notification struct {
 	Priority        Priority
 	Channels        []Channel
 	SelectedChannel Channel
+	Category        string // Preference category, e.g. "billing"; groups push notifications
 
 	// Status tracking
 	Status Status
@@ -98,11 +118,26 @@ type Notification struct {
 	// Metadata
 	Metadata map[string]interface{}
 	TraceID  string
+	SpanID   string // Span that submitted the notification
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+// Metadata keys of incident notifications, which open and resolve incidents
+// on incident channels such as PagerDuty
+const (
+	MetadataIncidentKey      = "incident_key"      // Deduplicates the notifications of an incident
+	MetadataIncidentAction   = "incident_action"   // IncidentTrigger or IncidentResolve
+	MetadataEscalationPolicy = "escalation_policy" // Escalation policy routing the incident
+)
+
+// Incident actions of incident notifications
+const (
+	IncidentTrigger = "trigger" // Open the incident, or add to it while open
+	IncidentResolve = "resolve" // Resolve the incident
+)
+
 // NotificationTemplate defines a reusable notification template
 type NotificationTemplate struct {
 	ID          string
@@ -112,7 +147,8 @@ type NotificationTemplate struct {
 	// Multi-channel content
 	Channels map[Channel]ChannelTemplate
 
-	// Localization support
+	// Localization support, keyed by locale ("pt-BR") or locale and
+	// channel ("pt-BR/EMAIL")
 	Translations map[string]LocalizedTemplate
 
 	// Template metadata
@@ -121,6 +157,7 @@ type NotificationTemplate struct {
 
 	// Lifecycle
 	Version   string
+	Revision  int // Incremented on every save
 	Enabled   bool
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -176,6 +213,12 @@ type NotificationRule struct {
 	TemplateID   string
 	TemplateVars map[string]interface{}
 
+	// Incidents: events sharing an IncidentKey (CEL, evaluating to a string)
+	// belong to one incident, resolved by events matching ResolvePattern
+	IncidentKey      string
+	ResolvePattern   string
+	EscalationPolicy string // Name of a PagerDuty escalation policy
+
 	// Lifecycle
 	Enabled    bool
 	ValidFrom  time.Time
@@ -198,6 +241,14 @@ type RecipientSelector struct {
 	Expression string
 }
 
+// Schedule types
+const (
+	ScheduleImmediate = "immediate"
+	ScheduleCron      = "cron"
+	ScheduleInterval  = "interval"
+	ScheduleTime      = "time"
+)
+
 // Schedule defines when notifications should be delivered
 type Schedule struct {
 	Type string // immediate | cron | interval | time
@@ -210,6 +261,17 @@ type Schedule struct {
 
 	// Specific time (for one-time scheduled notifications)
 	Time *time.Time
+
+	// Window notifications are delivered in, in the timezone of the rule
+	Window *DeliveryWindow
+}
+
+// DeliveryWindow restricts delivery to hours of the day and days of the
+// week. Notifications due outside the window are deferred to its next start.
+type DeliveryWindow struct {
+	StartTime string         // Format: "HH:MM"
+	EndTime   string         // Format: "HH:MM"
+	Days      []time.Weekday // Every day when empty
 }
 
 // UserPreferences stores user notification preferences
@@ -239,6 +301,13 @@ type UserPreferences struct {
 	UpdatedAt time.Time
 }
 
+// PushToken is a device registered to receive push notifications
+type PushToken struct {
+	Token        string
+	Platform     PushPlatform
+	RegisteredAt time.Time
+}
+
 // ChannelPreference defines per-channel preferences
 type ChannelPreference struct {
 	Enabled bool
@@ -338,8 +407,9 @@ type NotificationEvent struct {
 	Timestamp time.Time
 
 	// Event source
-	Domain       string
-	SourceSystem string
+	Domain         string
+	SourceSystem   string
+	OrganizationID string // Billing organization the event belongs to, if any
 
 	// Event data
 	Data map[string]interface{}
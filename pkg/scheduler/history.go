@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RunStatus is the outcome of a job run
+type RunStatus string
+
+const (
+	RunStatusSucceeded RunStatus = "succeeded"
+	RunStatusFailed    RunStatus = "failed"
+)
+
+// Run is a recorded job execution
+type Run struct {
+	ID         string    `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	JobName    string    `gorm:"type:varchar(255);not null"`
+	Instance   string    `gorm:"type:varchar(255);not null"`
+	Status     RunStatus `gorm:"type:varchar(50);not null"`
+	Trigger    string    `gorm:"type:varchar(50);not null"` // schedule | manual
+	StartedAt  time.Time `gorm:"not null"`
+	FinishedAt time.Time `gorm:"not null"`
+	DurationMs int64     `gorm:"not null"`
+	Error      *string   `gorm:"type:text"`
+}
+
+// TableName returns the table name
+func (Run) TableName() string {
+	return "dictamesh_scheduler_runs"
+}
+
+// History stores job run history
+type History interface {
+	Record(ctx context.Context, run *Run) error
+	Runs(ctx context.Context, jobName string, limit int) ([]Run, error)
+}
+
+// ============================================================================
+// Database history
+// ============================================================================
+
+// DBHistory stores runs in the dictamesh_scheduler_runs table
+type DBHistory struct {
+	db *gorm.DB
+}
+
+// NewDBHistory creates a database backed history
+func NewDBHistory(db *gorm.DB) *DBHistory {
+	return &DBHistory{db: db}
+}
+
+// Record implements History
+func (h *DBHistory) Record(ctx context.Context, run *Run) error {
+	if err := h.db.WithContext(ctx).Create(run).Error; err != nil {
+		return fmt.Errorf("failed to record job run: %w", err)
+	}
+	return nil
+}
+
+// Runs implements History, returning the most recent runs first
+func (h *DBHistory) Runs(ctx context.Context, jobName string, limit int) ([]Run, error) {
+	var runs []Run
+	err := h.db.WithContext(ctx).
+		Where("job_name = ?", jobName).
+		Order("started_at DESC").
+		Limit(limit).
+		Find(&runs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job runs: %w", err)
+	}
+	return runs, nil
+}
+
+// Prune deletes runs that started before the cutoff
+func (h *DBHistory) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result := h.db.WithContext(ctx).Where("started_at < ?", before).Delete(&Run{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune job runs: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// ============================================================================
+// In-memory history
+// ============================================================================
+
+// MemoryHistory keeps the most recent runs of each job in memory
+type MemoryHistory struct {
+	mu    sync.RWMutex
+	size  int
+	runs  map[string][]Run
+	count int64
+}
+
+// NewMemoryHistory creates an in-memory history keeping size runs per job
+func NewMemoryHistory(size int) *MemoryHistory {
+	if size <= 0 {
+		size = 100
+	}
+	return &MemoryHistory{
+		size: size,
+		runs: make(map[string][]Run),
+	}
+}
+
+// Record implements History
+func (h *MemoryHistory) Record(_ context.Context, run *Run) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.count++
+	if run.ID == "" {
+		run.ID = fmt.Sprintf("%d", h.count)
+	}
+
+	runs := append(h.runs[run.JobName], *run)
+	if len(runs) > h.size {
+		runs = runs[len(runs)-h.size:]
+	}
+	h.runs[run.JobName] = runs
+	return nil
+}
+
+// Runs implements History, returning the most recent runs first
+func (h *MemoryHistory) Runs(_ context.Context, jobName string, limit int) ([]Run, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	runs := h.runs[jobName]
+	if limit <= 0 || limit > len(runs) {
+		limit = len(runs)
+	}
+
+	out := make([]Run, 0, limit)
+	for i := len(runs) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, runs[i])
+	}
+	return out, nil
+}
@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Locker provides distributed mutual exclusion so that a job runs on a
+// single instance at a time
+type Locker interface {
+	// TryLock acquires the lock for key without blocking. It returns
+	// acquired=false when another instance holds the lock.
+	TryLock(ctx context.Context, key string) (lock Lock, acquired bool, err error)
+}
+
+// Lock is a held distributed lock
+type Lock interface {
+	Unlock(ctx context.Context) error
+}
+
+// ============================================================================
+// PostgreSQL advisory locks
+// ============================================================================
+
+// PostgresLocker uses session-level PostgreSQL advisory locks. Each held lock
+// pins one connection of the pool until it is released.
+type PostgresLocker struct {
+	db *sql.DB
+}
+
+// NewPostgresLocker creates a new advisory lock based locker
+func NewPostgresLocker(db *sql.DB) *PostgresLocker {
+	return &PostgresLocker{db: db}
+}
+
+// TryLock implements Locker
+func (l *PostgresLocker) TryLock(ctx context.Context, key string) (Lock, bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	id := advisoryKey(key)
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", id).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	return &postgresLock{conn: conn, id: id}, true, nil
+}
+
+type postgresLock struct {
+	conn *sql.Conn
+	id   int64
+}
+
+func (l *postgresLock) Unlock(ctx context.Context) error {
+	defer l.conn.Close()
+
+	if _, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.id); err != nil {
+		return fmt.Errorf("failed to release advisory lock: %w", err)
+	}
+	return nil
+}
+
+// advisoryKey maps a lock name to a 64-bit advisory lock key
+func advisoryKey(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("dictamesh:scheduler:" + key))
+	return int64(h.Sum64())
+}
+
+// ============================================================================
+// Redis
+// ============================================================================
+
+// RedisLocker uses Redis keys with a lease that is extended while the lock
+// is held. If the holder dies, the lock expires after the lease TTL.
+type RedisLocker struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+// NewRedisLocker creates a new Redis based locker with the given lease TTL
+func NewRedisLocker(client redis.UniversalClient, ttl time.Duration) *RedisLocker {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return &RedisLocker{client: client, ttl: ttl}
+}
+
+// Release and extend only when the lock is still owned by this token
+var (
+	redisUnlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	redisExtendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// TryLock implements Locker
+func (l *RedisLocker) TryLock(ctx context.Context, key string) (Lock, bool, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, false, err
+	}
+
+	redisKey := "dictamesh:scheduler:lock:" + key
+	acquired, err := l.client.SetNX(ctx, redisKey, token, l.ttl).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire redis lock: %w", err)
+	}
+	if !acquired {
+		return nil, false, nil
+	}
+
+	lock := &redisLock{
+		client: l.client,
+		key:    redisKey,
+		token:  token,
+		stop:   make(chan struct{}),
+	}
+	go lock.keepAlive(l.ttl)
+
+	return lock, true, nil
+}
+
+type redisLock struct {
+	client redis.UniversalClient
+	key    string
+	token  string
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// keepAlive extends the lease until the lock is released
+func (l *redisLock) keepAlive(ttl time.Duration) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), ttl/3)
+			_ = redisExtendScript.Run(ctx, l.client, []string{l.key}, l.token, ttl.Milliseconds()).Err()
+			cancel()
+		}
+	}
+}
+
+func (l *redisLock) Unlock(ctx context.Context) error {
+	l.once.Do(func() { close(l.stop) })
+
+	if err := redisUnlockScript.Run(ctx, l.client, []string{l.key}, l.token).Err(); err != nil {
+		return fmt.Errorf("failed to release redis lock: %w", err)
+	}
+	return nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package scheduler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	jobRuns = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_scheduler_job_runs_total",
+			Help: "Total job runs by job and status",
+		},
+		[]string{"job", "status"},
+	)
+
+	jobDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dictamesh_scheduler_job_duration_seconds",
+			Help:    "Job run duration by job",
+			Buckets: prometheus.ExponentialBuckets(0.01, 4, 10),
+		},
+		[]string{"job"},
+	)
+
+	jobLastSuccess = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dictamesh_scheduler_job_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful run by job",
+		},
+		[]string{"job"},
+	)
+
+	jobSkipped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_scheduler_job_skipped_total",
+			Help: "Job activations skipped because the lock was held elsewhere",
+		},
+		[]string{"job"},
+	)
+
+	jobRunning = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dictamesh_scheduler_job_running",
+			Help: "Whether a job is currently running on this instance",
+		},
+		[]string{"job"},
+	)
+)
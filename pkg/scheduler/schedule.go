@@ -0,0 +1,35 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule computes the next activation time of a job
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+var parser = cron.NewParser(
+	cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// ParseSchedule parses a standard 5-field cron expression ("0 6 * * *"), a
+// descriptor ("@daily", "@hourly") or an interval ("@every 1h")
+func ParseSchedule(expr string) (Schedule, error) {
+	schedule, err := parser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schedule %q: %w", expr, err)
+	}
+	return schedule, nil
+}
+
+// Every returns an interval schedule expression for d
+func Every(d time.Duration) string {
+	return "@every " + d.String()
+}
@@ -0,0 +1,356 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package scheduler runs periodic framework jobs (billing cycles, usage
+// aggregation, dunning, digests, re-embedding) on cron schedules with
+// distributed locking, run history and metrics
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrJobNotFound is returned for unknown job names
+	ErrJobNotFound = errors.New("job not found")
+
+	// ErrLocked is returned by RunNow when another instance holds the job lock
+	ErrLocked = errors.New("job is locked by another instance")
+)
+
+// JobFunc is the work performed by a job
+type JobFunc func(ctx context.Context) error
+
+// Job is a periodic unit of work
+type Job struct {
+	// Name uniquely identifies the job and its distributed lock
+	// (e.g., "billing.usage_aggregation")
+	Name string
+
+	// Schedule is a cron expression, descriptor or "@every <duration>"
+	Schedule string
+
+	// Timeout bounds a single run (defaults to Config.DefaultTimeout)
+	Timeout time.Duration
+
+	// Run performs the work
+	Run JobFunc
+}
+
+// JobInfo describes a registered job
+type JobInfo struct {
+	Name     string
+	Schedule string
+	NextRun  time.Time
+	Running  bool
+}
+
+// Config configures the scheduler
+type Config struct {
+	// Location is the time zone cron expressions are evaluated in
+	Location *time.Location
+
+	// DefaultTimeout bounds runs of jobs without an explicit timeout
+	DefaultTimeout time.Duration
+
+	// Instance identifies this process in run history (defaults to hostname-pid)
+	Instance string
+}
+
+// DefaultConfig returns default scheduler configuration
+func DefaultConfig() Config {
+	hostname, _ := os.Hostname()
+
+	return Config{
+		Location:       time.UTC,
+		DefaultTimeout: 30 * time.Minute,
+		Instance:       fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Validate checks if the configuration is valid
+func (c Config) Validate() error {
+	if c.Location == nil {
+		return fmt.Errorf("location is required")
+	}
+	if c.DefaultTimeout <= 0 {
+		return fmt.Errorf("default timeout must be positive")
+	}
+	if c.Instance == "" {
+		return fmt.Errorf("instance is required")
+	}
+	return nil
+}
+
+type entry struct {
+	job      Job
+	schedule Schedule
+
+	mu      sync.Mutex
+	running bool
+	next    time.Time
+}
+
+// Scheduler runs registered jobs on their schedules. Every instance of a
+// service runs the scheduler; the Locker ensures each activation executes on
+// one instance only.
+type Scheduler struct {
+	config  Config
+	locker  Locker
+	history History
+	logger  *zap.Logger
+
+	mu      sync.RWMutex
+	jobs    map[string]*entry
+	started bool
+	wg      sync.WaitGroup
+}
+
+// NewScheduler creates a new scheduler. locker may be nil for single
+// instance deployments; history defaults to an in-memory history.
+func NewScheduler(config Config, locker Locker, history History, logger *zap.Logger) (*Scheduler, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scheduler config: %w", err)
+	}
+	if history == nil {
+		history = NewMemoryHistory(0)
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &Scheduler{
+		config:  config,
+		locker:  locker,
+		history: history,
+		logger:  logger,
+		jobs:    make(map[string]*entry),
+	}, nil
+}
+
+// Register adds a job. Jobs must be registered before Start.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" {
+		return fmt.Errorf("job name is required")
+	}
+	if job.Run == nil {
+		return fmt.Errorf("job %s has no run function", job.Name)
+	}
+
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule for job %s: %w", job.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return fmt.Errorf("cannot register job %s after the scheduler started", job.Name)
+	}
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+
+	s.jobs[job.Name] = &entry{job: job, schedule: schedule}
+	return nil
+}
+
+// Start runs the scheduler until ctx is canceled and waits for running jobs
+// to finish. Activations missed while the process was down are not replayed.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.started = true
+	entries := make([]*entry, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		entries = append(entries, e)
+	}
+	s.mu.Unlock()
+
+	s.logger.Info("scheduler started", zap.Int("jobs", len(entries)), zap.String("instance", s.config.Instance))
+
+	for _, e := range entries {
+		s.wg.Add(1)
+		go func(e *entry) {
+			defer s.wg.Done()
+			s.loop(ctx, e)
+		}(e)
+	}
+
+	<-ctx.Done()
+	s.wg.Wait()
+	s.logger.Info("scheduler stopped")
+}
+
+// loop waits for each activation of a job and runs it. Activations that
+// fire while the previous run is still in progress are skipped.
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	for {
+		now := time.Now().In(s.config.Location)
+		next := e.schedule.Next(now)
+
+		e.mu.Lock()
+		e.next = next
+		e.mu.Unlock()
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.execute(ctx, e, "schedule"); err != nil && !errors.Is(err, ErrLocked) {
+			s.logger.Error("scheduled job failed", zap.String("job", e.job.Name), zap.Error(err))
+		}
+	}
+}
+
+// RunNow runs a job immediately, honoring the distributed lock
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	s.mu.RLock()
+	e, ok := s.jobs[name]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+
+	return s.execute(ctx, e, "manual")
+}
+
+// Jobs lists registered jobs sorted by name
+func (s *Scheduler) Jobs() []JobInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]JobInfo, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		e.mu.Lock()
+		out = append(out, JobInfo{
+			Name:     e.job.Name,
+			Schedule: e.job.Schedule,
+			NextRun:  e.next,
+			Running:  e.running,
+		})
+		e.mu.Unlock()
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Runs returns the most recent runs of a job
+func (s *Scheduler) Runs(ctx context.Context, name string, limit int) ([]Run, error) {
+	return s.history.Runs(ctx, name, limit)
+}
+
+// execute runs a job once under its local and distributed locks
+func (s *Scheduler) execute(ctx context.Context, e *entry, trigger string) error {
+	name := e.job.Name
+
+	e.mu.Lock()
+	if e.running {
+		e.mu.Unlock()
+		jobSkipped.WithLabelValues(name).Inc()
+		return ErrLocked
+	}
+	e.running = true
+	e.mu.Unlock()
+
+	defer func() {
+		e.mu.Lock()
+		e.running = false
+		e.mu.Unlock()
+	}()
+
+	if s.locker != nil {
+		lock, acquired, err := s.locker.TryLock(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to acquire lock for job %s: %w", name, err)
+		}
+		if !acquired {
+			jobSkipped.WithLabelValues(name).Inc()
+			s.logger.Debug("job locked by another instance", zap.String("job", name))
+			return ErrLocked
+		}
+		defer func() {
+			// Release even when ctx was canceled during the run
+			unlockCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := lock.Unlock(unlockCtx); err != nil {
+				s.logger.Warn("failed to release job lock", zap.String("job", name), zap.Error(err))
+			}
+		}()
+	}
+
+	timeout := e.job.Timeout
+	if timeout <= 0 {
+		timeout = s.config.DefaultTimeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	jobRunning.WithLabelValues(name).Set(1)
+	defer jobRunning.WithLabelValues(name).Set(0)
+
+	started := time.Now()
+	runErr := safeRun(runCtx, e.job.Run)
+	finished := time.Now()
+	duration := finished.Sub(started)
+
+	run := &Run{
+		JobName:    name,
+		Instance:   s.config.Instance,
+		Status:     RunStatusSucceeded,
+		Trigger:    trigger,
+		StartedAt:  started,
+		FinishedAt: finished,
+		DurationMs: duration.Milliseconds(),
+	}
+	if runErr != nil {
+		msg := runErr.Error()
+		run.Status = RunStatusFailed
+		run.Error = &msg
+	}
+
+	jobRuns.WithLabelValues(name, string(run.Status)).Inc()
+	jobDuration.WithLabelValues(name).Observe(duration.Seconds())
+	if runErr == nil {
+		jobLastSuccess.WithLabelValues(name).Set(float64(finished.Unix()))
+	}
+
+	// History is best effort and must not fail the run
+	recordCtx, recordCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer recordCancel()
+	if err := s.history.Record(recordCtx, run); err != nil {
+		s.logger.Warn("failed to record job run", zap.String("job", name), zap.Error(err))
+	}
+
+	s.logger.Info("job finished",
+		zap.String("job", name),
+		zap.String("status", string(run.Status)),
+		zap.Duration("duration", duration),
+	)
+
+	return runErr
+}
+
+// safeRun converts job panics into errors
+func safeRun(ctx context.Context, fn JobFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// AWSConfig configures the AWS Secrets Manager and KMS providers. Credentials
+// are taken from the default AWS credential chain (env, shared config, IRSA).
+type AWSConfig struct {
+	Region   string
+	Profile  string
+	Endpoint string // Custom endpoint (e.g., LocalStack)
+}
+
+// loadAWSConfig builds an aws.Config from AWSConfig
+func loadAWSConfig(ctx context.Context, config AWSConfig) (aws.Config, error) {
+	opts := []func(*awsconfig.LoadOptions) error{}
+
+	region := config.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	if config.Profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(config.Profile))
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return cfg, nil
+}
+
+// SecretsManagerProvider resolves awssm://name#field references from AWS Secrets Manager
+type SecretsManagerProvider struct {
+	client *secretsmanager.Client
+}
+
+// NewSecretsManagerProvider creates a new AWS Secrets Manager provider
+func NewSecretsManagerProvider(ctx context.Context, config AWSConfig) (*SecretsManagerProvider, error) {
+	cfg, err := loadAWSConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	client := secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		if config.Endpoint != "" {
+			o.BaseEndpoint = aws.String(config.Endpoint)
+		}
+	})
+
+	return &SecretsManagerProvider{client: client}, nil
+}
+
+// Scheme returns the reference scheme handled by the provider
+func (p *SecretsManagerProvider) Scheme() Scheme {
+	return SchemeSecretsManager
+}
+
+// GetSecret reads a secret by name or ARN. When the key carries a #field
+// selector the secret string is decoded as a JSON object and the field returned.
+func (p *SecretsManagerProvider) GetSecret(ctx context.Context, key string) (*Secret, error) {
+	name, field := splitField(key)
+
+	out, err := p.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		var notFound *smtypes.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get secret value: %w", err)
+	}
+
+	value := aws.ToString(out.SecretString)
+	if out.SecretString == nil {
+		value = string(out.SecretBinary)
+	}
+
+	if field != "" {
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &data); err != nil {
+			return nil, fmt.Errorf("failed to decode secret JSON: %w", err)
+		}
+		value, err = selectField(data, field)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Secret{
+		Value:       value,
+		Version:     aws.ToString(out.VersionId),
+		RetrievedAt: time.Now(),
+	}, nil
+}
+
+// KMSProvider resolves kms://<base64 ciphertext> references by decrypting the
+// ciphertext with AWS KMS. The key ID is embedded in the ciphertext blob.
+type KMSProvider struct {
+	client *kms.Client
+}
+
+// NewKMSProvider creates a new AWS KMS provider
+func NewKMSProvider(ctx context.Context, config AWSConfig) (*KMSProvider, error) {
+	cfg, err := loadAWSConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	client := kms.NewFromConfig(cfg, func(o *kms.Options) {
+		if config.Endpoint != "" {
+			o.BaseEndpoint = aws.String(config.Endpoint)
+		}
+	})
+
+	return &KMSProvider{client: client}, nil
+}
+
+// Scheme returns the reference scheme handled by the provider
+func (p *KMSProvider) Scheme() Scheme {
+	return SchemeKMS
+}
+
+// GetSecret decrypts the base64 encoded ciphertext in key
+func (p *KMSProvider) GetSecret(ctx context.Context, key string) (*Secret, error) {
+	blob, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+
+	out, err := p.client.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob: blob,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+
+	return &Secret{
+		Value:       string(out.Plaintext),
+		Version:     aws.ToString(out.KeyId),
+		RetrievedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package secrets
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// EnvProvider resolves env://VAR_NAME references from the process environment
+type EnvProvider struct{}
+
+// NewEnvProvider creates a new environment variable provider
+func NewEnvProvider() *EnvProvider {
+	return &EnvProvider{}
+}
+
+// Scheme returns the reference scheme handled by the provider
+func (p *EnvProvider) Scheme() Scheme {
+	return SchemeEnv
+}
+
+// GetSecret reads the environment variable named by key
+func (p *EnvProvider) GetSecret(_ context.Context, key string) (*Secret, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &Secret{
+		Value:       value,
+		RetrievedAt: time.Now(),
+	}, nil
+}
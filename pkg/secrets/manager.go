@@ -0,0 +1,307 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RotationFunc is invoked when a cached secret changes value
+type RotationFunc func(ref string, newValue string)
+
+// Config represents secrets manager configuration
+type Config struct {
+	// CacheTTL is how long a resolved secret is served from cache
+	CacheTTL time.Duration
+
+	// RefreshInterval is how often cached secrets are re-fetched to detect rotation
+	RefreshInterval time.Duration
+
+	// Vault configuration (optional)
+	Vault VaultConfig
+
+	// AWS configuration (optional)
+	AWS AWSConfig
+}
+
+// DefaultConfig returns default secrets manager configuration
+func DefaultConfig() *Config {
+	return &Config{
+		CacheTTL:        15 * time.Minute,
+		RefreshInterval: 5 * time.Minute,
+		Vault:           DefaultVaultConfig(),
+		AWS:             AWSConfig{Region: os.Getenv("AWS_REGION")},
+	}
+}
+
+// cachedSecret is a cache entry for a resolved reference
+type cachedSecret struct {
+	secret    *Secret
+	expiresAt time.Time
+}
+
+// Manager resolves secret references through registered providers, caches
+// the results and notifies subscribers when secrets are rotated
+type Manager struct {
+	config *Config
+	logger *zap.Logger
+
+	providers map[Scheme]Provider
+
+	mu        sync.RWMutex
+	cache     map[string]*cachedSecret
+	callbacks map[string][]RotationFunc
+}
+
+// NewManager creates a new secrets manager. The environment provider is
+// always registered as a fallback.
+func NewManager(config *Config, logger *zap.Logger, providers ...Provider) *Manager {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	m := &Manager{
+		config:    config,
+		logger:    logger,
+		providers: make(map[Scheme]Provider),
+		cache:     make(map[string]*cachedSecret),
+		callbacks: make(map[string][]RotationFunc),
+	}
+
+	m.Register(NewEnvProvider())
+	for _, p := range providers {
+		m.Register(p)
+	}
+
+	return m
+}
+
+// NewManagerFromConfig creates a manager with the providers enabled by config:
+// Vault when an address is set, and AWS Secrets Manager and KMS when a region is set
+func NewManagerFromConfig(ctx context.Context, config *Config, logger *zap.Logger) (*Manager, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	var providers []Provider
+
+	if config.Vault.Address != "" {
+		vault, err := NewVaultProvider(config.Vault)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create vault provider: %w", err)
+		}
+		providers = append(providers, vault)
+	}
+
+	if config.AWS.Region != "" {
+		sm, err := NewSecretsManagerProvider(ctx, config.AWS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create secrets manager provider: %w", err)
+		}
+		k, err := NewKMSProvider(ctx, config.AWS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create kms provider: %w", err)
+		}
+		providers = append(providers, sm, k)
+	}
+
+	return NewManager(config, logger, providers...), nil
+}
+
+// Register adds or replaces a provider for its scheme
+func (m *Manager) Register(p Provider) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.providers[p.Scheme()] = p
+}
+
+// Resolve returns the value of a configuration entry. Plain values are returned
+// unchanged; references are resolved through the matching provider.
+func (m *Manager) Resolve(ctx context.Context, value string) (string, error) {
+	ref, ok := ParseReference(value)
+	if !ok {
+		return value, nil
+	}
+
+	secret, err := m.Get(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return secret.Value, nil
+}
+
+// ResolveAll resolves every pointer in place, stopping at the first error
+func (m *Manager) ResolveAll(ctx context.Context, values ...*string) error {
+	for _, v := range values {
+		if v == nil || *v == "" {
+			continue
+		}
+		resolved, err := m.Resolve(ctx, *v)
+		if err != nil {
+			return err
+		}
+		*v = resolved
+	}
+	return nil
+}
+
+// Get returns the secret for a reference, using the cache when fresh
+func (m *Manager) Get(ctx context.Context, ref Reference) (*Secret, error) {
+	key := ref.String()
+
+	m.mu.RLock()
+	entry, ok := m.cache[key]
+	m.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.secret, nil
+	}
+
+	secret, err := m.fetch(ctx, ref)
+	if err != nil {
+		// Serve stale values rather than failing when the backend is unavailable
+		if ok {
+			m.logger.Warn("serving stale secret after refresh failure",
+				zap.String("ref", key),
+				zap.Error(err),
+			)
+			return entry.secret, nil
+		}
+		return nil, err
+	}
+
+	m.store(key, secret)
+	return secret, nil
+}
+
+// OnRotate registers a callback invoked when the secret behind ref changes.
+// The reference is added to the refresh set even if it has not been resolved yet.
+func (m *Manager) OnRotate(value string, fn RotationFunc) {
+	ref, ok := ParseReference(value)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := ref.String()
+	m.callbacks[key] = append(m.callbacks[key], fn)
+}
+
+// Invalidate drops a reference from the cache, forcing the next Get to fetch it
+func (m *Manager) Invalidate(value string) {
+	ref, ok := ParseReference(value)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.cache, ref.String())
+}
+
+// Start periodically refreshes cached and watched secrets until ctx is canceled
+func (m *Manager) Start(ctx context.Context) {
+	if m.config.RefreshInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Refresh(ctx)
+		}
+	}
+}
+
+// Refresh re-fetches every cached or watched secret and fires rotation
+// callbacks for values that changed
+func (m *Manager) Refresh(ctx context.Context) {
+	m.mu.RLock()
+	keys := make(map[string]struct{}, len(m.cache)+len(m.callbacks))
+	for key := range m.cache {
+		keys[key] = struct{}{}
+	}
+	for key := range m.callbacks {
+		keys[key] = struct{}{}
+	}
+	m.mu.RUnlock()
+
+	for key := range keys {
+		ref, _ := ParseReference(key)
+
+		secret, err := m.fetch(ctx, ref)
+		if err != nil {
+			m.logger.Warn("failed to refresh secret", zap.String("ref", key), zap.Error(err))
+			continue
+		}
+
+		m.mu.RLock()
+		previous, cached := m.cache[key]
+		m.mu.RUnlock()
+
+		m.store(key, secret)
+
+		if cached && previous.secret.Value != secret.Value {
+			m.logger.Info("secret rotated",
+				zap.String("ref", key),
+				zap.String("version", secret.Version),
+			)
+			m.notify(key, secret.Value)
+		}
+	}
+}
+
+func (m *Manager) fetch(ctx context.Context, ref Reference) (*Secret, error) {
+	m.mu.RLock()
+	p, ok := m.providers[ref.Scheme]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("no secrets provider registered for scheme %q", ref.Scheme)
+	}
+
+	secret, err := p.GetSecret(ctx, ref.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve secret %s: %w", ref, err)
+	}
+
+	if secret.RetrievedAt.IsZero() {
+		secret.RetrievedAt = time.Now()
+	}
+	return secret, nil
+}
+
+func (m *Manager) store(key string, secret *Secret) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache[key] = &cachedSecret{
+		secret:    secret,
+		expiresAt: time.Now().Add(m.config.CacheTTL),
+	}
+}
+
+func (m *Manager) notify(key, value string) {
+	m.mu.RLock()
+	callbacks := append([]RotationFunc(nil), m.callbacks[key]...)
+	m.mu.RUnlock()
+
+	for _, fn := range callbacks {
+		fn(key, value)
+	}
+}
@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package secrets provides a pluggable secrets provider abstraction for DictaMesh.
+// Configuration values may hold secret references (e.g. "vault://billing/stripe#api_key")
+// that are resolved at startup through HashiCorp Vault, AWS Secrets Manager, AWS KMS
+// or environment variables, cached, and refreshed with rotation callbacks.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a secret does not exist in the provider
+var ErrNotFound = errors.New("secret not found")
+
+// Scheme identifies the provider responsible for a secret reference
+type Scheme string
+
+const (
+	SchemeEnv            Scheme = "env"
+	SchemeVault          Scheme = "vault"
+	SchemeSecretsManager Scheme = "awssm"
+	SchemeKMS            Scheme = "kms"
+)
+
+// Secret represents a resolved secret value
+type Secret struct {
+	Value       string
+	Version     string
+	RetrievedAt time.Time
+}
+
+// Provider retrieves secrets from a backing store
+type Provider interface {
+	// Scheme returns the reference scheme handled by the provider
+	Scheme() Scheme
+
+	// GetSecret retrieves the secret identified by key (the reference without scheme)
+	GetSecret(ctx context.Context, key string) (*Secret, error)
+}
+
+// Reference is a parsed secret reference of the form scheme://key
+type Reference struct {
+	Scheme Scheme
+	Key    string
+}
+
+// String returns the reference in scheme://key form
+func (r Reference) String() string {
+	return fmt.Sprintf("%s://%s", r.Scheme, r.Key)
+}
+
+// ParseReference parses a secret reference. It returns false when value is a
+// plain (literal) configuration value rather than a reference.
+func ParseReference(value string) (Reference, bool) {
+	idx := strings.Index(value, "://")
+	if idx <= 0 {
+		return Reference{}, false
+	}
+
+	scheme := Scheme(value[:idx])
+	switch scheme {
+	case SchemeEnv, SchemeVault, SchemeSecretsManager, SchemeKMS:
+	default:
+		return Reference{}, false
+	}
+
+	key := value[idx+3:]
+	if key == "" {
+		return Reference{}, false
+	}
+
+	return Reference{Scheme: scheme, Key: key}, true
+}
+
+// IsReference reports whether value is a secret reference
+func IsReference(value string) bool {
+	_, ok := ParseReference(value)
+	return ok
+}
+
+// splitField splits "path#field" into its path and optional field
+func splitField(key string) (string, string) {
+	if idx := strings.LastIndex(key, "#"); idx >= 0 {
+		return key[:idx], key[idx+1:]
+	}
+	return key, ""
+}
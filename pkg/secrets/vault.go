@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultConfig configures the HashiCorp Vault provider
+type VaultConfig struct {
+	Address   string        // Vault server address (e.g., https://vault:8200)
+	Token     string        // Static token (token auth)
+	Namespace string        // Vault Enterprise namespace
+	MountPath string        // KV v2 mount path (default "secret")
+	Timeout   time.Duration // HTTP request timeout
+
+	// Kubernetes auth (used when Token is empty)
+	KubernetesRole      string
+	KubernetesMountPath string // default "kubernetes"
+	KubernetesTokenPath string // default service account token path
+}
+
+// DefaultVaultConfig returns default Vault configuration read from the
+// standard VAULT_* environment variables
+func DefaultVaultConfig() VaultConfig {
+	return VaultConfig{
+		Address:             os.Getenv("VAULT_ADDR"),
+		Token:               os.Getenv("VAULT_TOKEN"),
+		Namespace:           os.Getenv("VAULT_NAMESPACE"),
+		MountPath:           "secret",
+		Timeout:             10 * time.Second,
+		KubernetesMountPath: "kubernetes",
+		KubernetesTokenPath: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+	}
+}
+
+// VaultProvider resolves vault://path#field references from a KV v2 engine
+type VaultProvider struct {
+	config VaultConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewVaultProvider creates a new Vault provider
+func NewVaultProvider(config VaultConfig) (*VaultProvider, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("vault address is required")
+	}
+	if config.Token == "" && config.KubernetesRole == "" {
+		return nil, fmt.Errorf("vault token or kubernetes role is required")
+	}
+	if config.MountPath == "" {
+		config.MountPath = "secret"
+	}
+
+	return &VaultProvider{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		token:  config.Token,
+	}, nil
+}
+
+// Scheme returns the reference scheme handled by the provider
+func (p *VaultProvider) Scheme() Scheme {
+	return SchemeVault
+}
+
+// GetSecret reads a KV v2 secret. The key has the form "path#field"; when the
+// field is omitted the secret must contain exactly one field.
+func (p *VaultProvider) GetSecret(ctx context.Context, key string) (*Secret, error) {
+	path, field := splitField(key)
+
+	token, err := p.authToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/data/%s",
+		strings.TrimRight(p.config.Address, "/"),
+		strings.Trim(p.config.MountPath, "/"),
+		strings.TrimLeft(path, "/"),
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setHeaders(req, token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode == http.StatusForbidden && p.config.Token == "":
+		// Kubernetes token expired; force a new login on next call
+		p.mu.Lock()
+		p.token = ""
+		p.mu.Unlock()
+		return nil, fmt.Errorf("vault returned status %d", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("vault returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Data     map[string]interface{} `json:"data"`
+			Metadata struct {
+				Version int `json:"version"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	value, err := selectField(body.Data.Data, field)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Secret{
+		Value:       value,
+		Version:     strconv.Itoa(body.Data.Metadata.Version),
+		RetrievedAt: time.Now(),
+	}, nil
+}
+
+// authToken returns a static token or logs in with the Kubernetes auth method
+func (p *VaultProvider) authToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" {
+		return p.token, nil
+	}
+
+	jwt, err := os.ReadFile(p.config.KubernetesTokenPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"role": p.config.KubernetesRole,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal login request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/auth/%s/login",
+		strings.TrimRight(p.config.Address, "/"),
+		strings.Trim(p.config.KubernetesMountPath, "/"),
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create login request: %w", err)
+	}
+	p.setHeaders(req, "")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault login failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault login returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault login response: %w", err)
+	}
+
+	p.token = body.Auth.ClientToken
+	return p.token, nil
+}
+
+func (p *VaultProvider) setHeaders(req *http.Request, token string) {
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if p.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.config.Namespace)
+	}
+}
+
+// selectField returns the named field of a secret payload as a string
+func selectField(data map[string]interface{}, field string) (string, error) {
+	if field == "" {
+		if len(data) != 1 {
+			return "", fmt.Errorf("secret has %d fields; a #field selector is required", len(data))
+		}
+		for _, v := range data {
+			return fmt.Sprint(v), nil
+		}
+	}
+
+	v, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("field %q: %w", field, ErrNotFound)
+	}
+	return fmt.Sprint(v), nil
+}
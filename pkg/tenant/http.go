@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package tenant
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidAPIKey is returned by APIKeyResolver for unknown API keys
+var ErrInvalidAPIKey = errors.New("tenant: invalid API key")
+
+// Resolver resolves the tenant of an incoming request, typically from an API
+// key or a verified session token. It returns (nil, nil) when the request is
+// anonymous.
+type Resolver interface {
+	Resolve(r *http.Request) (*Tenant, error)
+}
+
+// ResolverFunc adapts a function to the Resolver interface
+type ResolverFunc func(r *http.Request) (*Tenant, error)
+
+// Resolve calls f(r)
+func (f ResolverFunc) Resolve(r *http.Request) (*Tenant, error) {
+	return f(r)
+}
+
+// HeaderResolver resolves tenants from propagation headers. It must only be
+// used behind a trusted gateway that strips these headers from external traffic.
+var HeaderResolver = ResolverFunc(func(r *http.Request) (*Tenant, error) {
+	t, ok := ExtractTenant(HeaderCarrier(r.Header))
+	if !ok {
+		return nil, nil
+	}
+	return t, nil
+})
+
+// APIKeyResolver resolves tenants from bearer API keys issued to
+// organizations. keys maps each API key to the ID of its organization.
+// Requests without a bearer token are anonymous; unknown keys fail with
+// ErrInvalidAPIKey. The tenant carries no tier or features: services look
+// up the entitlements of the organization themselves.
+func APIKeyResolver(keys map[string]string) Resolver {
+	return ResolverFunc(func(r *http.Request) (*Tenant, error) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			return nil, nil
+		}
+
+		// Compare against every key so the response time does not reveal
+		// which keys exist
+		var organizationID string
+		for key, orgID := range keys {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+				organizationID = orgID
+			}
+		}
+		if organizationID == "" {
+			return nil, ErrInvalidAPIKey
+		}
+		return New(organizationID, ""), nil
+	})
+}
+
+// StripHeaders removes the propagation headers from h. Servers facing
+// external traffic call it before resolving the tenant so that callers
+// cannot inject a tenant into downstream services.
+func StripHeaders(h http.Header) {
+	h.Del(HeaderOrganizationID)
+	h.Del(HeaderSubscriptionTier)
+	h.Del(HeaderFeatureFlags)
+}
+
+// MiddlewareConfig configures the tenant HTTP middleware
+type MiddlewareConfig struct {
+	// Resolver resolves the tenant of each request. It is required: use
+	// HeaderResolver only behind a gateway that strips propagation headers.
+	Resolver Resolver
+
+	// Required rejects requests without a tenant with 401 Unauthorized
+	Required bool
+
+	// ErrorHandler writes the response when resolution fails or a tenant is missing
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// Middleware returns HTTP middleware that resolves the tenant of each request
+// and stores it in the request context. It panics when config.Resolver is nil.
+func Middleware(config MiddlewareConfig) func(http.Handler) http.Handler {
+	if config.Resolver == nil {
+		panic("tenant: middleware requires a resolver")
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = defaultErrorHandler
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t, err := config.Resolver.Resolve(r)
+			if err != nil {
+				config.ErrorHandler(w, r, err)
+				return
+			}
+
+			if t == nil {
+				if config.Required {
+					config.ErrorHandler(w, r, ErrMissingTenant)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := t.Validate(); err != nil {
+				config.ErrorHandler(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), t)))
+		})
+	}
+}
+
+func defaultErrorHandler(w http.ResponseWriter, _ *http.Request, err error) {
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
+
+// Transport is an http.RoundTripper that injects the tenant from the request
+// context into outbound request headers. Adapter and service clients wrap
+// their transport with it so calls propagate the tenant automatically.
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// NewTransport wraps base (or http.DefaultTransport when nil)
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if _, ok := FromContext(r.Context()); !ok {
+		return base.RoundTrip(r)
+	}
+
+	// RoundTrippers must not mutate the caller's request
+	out := r.Clone(r.Context())
+	Inject(r.Context(), HeaderCarrier(out.Header))
+	return base.RoundTrip(out)
+}
+
+// WithOrganization returns a context carrying a minimal tenant for the
+// organization. It is intended for background jobs that act on behalf of a
+// single organization and have no request context to inherit from.
+func WithOrganization(ctx context.Context, organizationID string) context.Context {
+	return NewContext(ctx, New(organizationID, ""))
+}
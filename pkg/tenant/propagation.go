@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Propagation header keys used on HTTP requests and Kafka message headers
+const (
+	HeaderOrganizationID   = "X-DictaMesh-Organization-ID"
+	HeaderSubscriptionTier = "X-DictaMesh-Subscription-Tier"
+	HeaderFeatureFlags     = "X-DictaMesh-Features"
+)
+
+// Carrier is a transport-agnostic key/value store for tenant propagation.
+// It mirrors the OpenTelemetry TextMapCarrier so that the same adapters can
+// be used for HTTP headers and Kafka record headers.
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// HeaderCarrier adapts http.Header to the Carrier interface
+type HeaderCarrier http.Header
+
+// Get returns the value for key
+func (c HeaderCarrier) Get(key string) string {
+	return http.Header(c).Get(key)
+}
+
+// Set stores value under key
+func (c HeaderCarrier) Set(key, value string) {
+	http.Header(c).Set(key, value)
+}
+
+// MapCarrier adapts a string map to the Carrier interface. Kafka consumers
+// and producers convert their record headers to and from a MapCarrier.
+type MapCarrier map[string]string
+
+// Get returns the value for key
+func (c MapCarrier) Get(key string) string {
+	return c[key]
+}
+
+// Set stores value under key
+func (c MapCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+// Inject writes the tenant stored in ctx into the carrier. It is a no-op when
+// ctx carries no tenant.
+func Inject(ctx context.Context, carrier Carrier) {
+	t, ok := FromContext(ctx)
+	if !ok {
+		return
+	}
+
+	carrier.Set(HeaderOrganizationID, t.OrganizationID)
+	if t.SubscriptionTier != "" {
+		carrier.Set(HeaderSubscriptionTier, string(t.SubscriptionTier))
+	}
+	if features := t.EnabledFeatures(); len(features) > 0 {
+		carrier.Set(HeaderFeatureFlags, strings.Join(features, ","))
+	}
+}
+
+// Extract reads a tenant from the carrier and returns a context carrying it.
+// When the carrier holds no organization ID, ctx is returned unchanged.
+func Extract(ctx context.Context, carrier Carrier) context.Context {
+	t, ok := ExtractTenant(carrier)
+	if !ok {
+		return ctx
+	}
+	return NewContext(ctx, t)
+}
+
+// ExtractTenant reads a tenant from the carrier
+func ExtractTenant(carrier Carrier) (*Tenant, bool) {
+	orgID := strings.TrimSpace(carrier.Get(HeaderOrganizationID))
+	if orgID == "" {
+		return nil, false
+	}
+
+	t := &Tenant{
+		OrganizationID:   orgID,
+		SubscriptionTier: Tier(strings.TrimSpace(carrier.Get(HeaderSubscriptionTier))),
+		FeatureFlags:     make(map[string]bool),
+	}
+
+	if raw := carrier.Get(HeaderFeatureFlags); raw != "" {
+		for _, f := range strings.Split(raw, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				t.FeatureFlags[f] = true
+			}
+		}
+	}
+
+	return t, true
+}
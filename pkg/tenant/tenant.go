@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package tenant provides a typed tenant context carrier that is propagated
+// across HTTP handlers, Kafka messages and outbound adapter calls, so that
+// services no longer need to thread organization IDs through every signature.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// Tier represents the subscription tier of a tenant
+type Tier string
+
+const (
+	TierFree         Tier = "free"
+	TierStarter      Tier = "starter"
+	TierProfessional Tier = "professional"
+	TierEnterprise   Tier = "enterprise"
+)
+
+// ErrMissingTenant is returned when a tenant is required but absent from the context
+var ErrMissingTenant = errors.New("tenant: no tenant in context")
+
+// Tenant carries the identity and entitlements of the calling organization
+type Tenant struct {
+	// OrganizationID is the billing organization ID (UUID string)
+	OrganizationID string
+
+	// SubscriptionTier is the plan slug of the organization's active subscription
+	SubscriptionTier Tier
+
+	// FeatureFlags holds the enabled/disabled state of named features
+	FeatureFlags map[string]bool
+}
+
+// New creates a tenant for the given organization
+func New(organizationID string, tier Tier, features ...string) *Tenant {
+	t := &Tenant{
+		OrganizationID:   organizationID,
+		SubscriptionTier: tier,
+		FeatureFlags:     make(map[string]bool, len(features)),
+	}
+	for _, f := range features {
+		t.FeatureFlags[f] = true
+	}
+	return t
+}
+
+// HasFeature reports whether the named feature is enabled for the tenant
+func (t *Tenant) HasFeature(name string) bool {
+	if t == nil {
+		return false
+	}
+	return t.FeatureFlags[name]
+}
+
+// EnabledFeatures returns the sorted names of all enabled features
+func (t *Tenant) EnabledFeatures() []string {
+	if t == nil {
+		return nil
+	}
+	features := make([]string, 0, len(t.FeatureFlags))
+	for name, enabled := range t.FeatureFlags {
+		if enabled {
+			features = append(features, name)
+		}
+	}
+	sort.Strings(features)
+	return features
+}
+
+// Validate checks that the tenant carries the minimum required identity
+func (t *Tenant) Validate() error {
+	if t == nil || t.OrganizationID == "" {
+		return errors.New("tenant: organization ID is required")
+	}
+	return nil
+}
+
+// Clone returns a deep copy of the tenant
+func (t *Tenant) Clone() *Tenant {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	c.FeatureFlags = make(map[string]bool, len(t.FeatureFlags))
+	for k, v := range t.FeatureFlags {
+		c.FeatureFlags[k] = v
+	}
+	return &c
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the tenant
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant stored in ctx, if any
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(contextKey{}).(*Tenant)
+	return t, ok && t != nil
+}
+
+// Require returns the tenant stored in ctx or ErrMissingTenant
+func Require(ctx context.Context) (*Tenant, error) {
+	t, ok := FromContext(ctx)
+	if !ok {
+		return nil, ErrMissingTenant
+	}
+	return t, nil
+}
+
+// OrganizationID returns the organization ID of the tenant in ctx or ErrMissingTenant
+func OrganizationID(ctx context.Context) (string, error) {
+	t, err := Require(ctx)
+	if err != nil {
+		return "", err
+	}
+	if t.OrganizationID == "" {
+		return "", ErrMissingTenant
+	}
+	return t.OrganizationID, nil
+}
@@ -0,0 +1,196 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Command graphql-gateway serves the unified DictaMesh GraphQL API
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	dmconfig "github.com/click2-run/dictamesh/pkg/config"
+	"github.com/click2-run/dictamesh/pkg/gateway/ratelimit"
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/backend"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/complexity"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/config"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/pubsub"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/resolvers"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/schema"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/server"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var files []string
+	if path := os.Getenv("DICTAMESH_GATEWAY_CONFIG"); path != "" {
+		files = strings.Split(path, ",")
+	}
+
+	loader := dmconfig.NewLoader(dmconfig.Options{
+		Files:     files,
+		EnvPrefix: "DICTAMESH_GATEWAY",
+	})
+
+	cfg := config.DefaultConfig()
+	if err := loader.RegisterFlags(flag.CommandLine, cfg); err != nil {
+		log.Fatalf("failed to register flags: %v", err)
+	}
+	flag.Parse()
+
+	if err := loader.Load(ctx, cfg); err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	level := zap.NewAtomicLevel()
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		log.Fatalf("invalid log level %q: %v", cfg.LogLevel, err)
+	}
+	zapConfig := zap.NewProductionConfig()
+	zapConfig.Level = level
+	logger, err := zapConfig.Build()
+	if err != nil {
+		log.Fatalf("failed to create logger: %v", err)
+	}
+	defer logger.Sync()
+
+	if err := run(ctx, loader, cfg, level, logger); err != nil {
+		logger.Fatal("gateway failed", zap.Error(err))
+	}
+}
+
+func run(ctx context.Context, loader *dmconfig.Loader, cfg *config.Config, level zap.AtomicLevel, logger *zap.Logger) error {
+	services := backend.NewServices(cfg.Services)
+
+	var hub *pubsub.Hub
+	if cfg.Subscriptions.Enabled {
+		topics := append([]string{cfg.Subscriptions.EntityTopic}, cfg.Subscriptions.BillingTopics...)
+		hub = pubsub.NewHub(cfg.Subscriptions.KafkaBrokers, topics, logger)
+		go hub.Start(ctx)
+	}
+
+	resolver := resolvers.NewResolver(services, hub, cfg.Subscriptions, logger)
+	gqlSchema, err := graphql.ParseSchema(schema.SDL, resolver,
+		graphql.UseStringDescriptions(),
+		graphql.MaxParallelism(20),
+	)
+	if err != nil {
+		return err
+	}
+
+	analyzer, err := complexity.NewAnalyzer(schema.SDL, cfg.Limits.DefaultListSize)
+	if err != nil {
+		return err
+	}
+
+	srv := server.New(gqlSchema, services, analyzer, cfg, logger)
+
+	if cfg.RateLimit.Enabled {
+		redisClient := redis.NewClient(&redis.Options{
+			Addr:     cfg.RateLimit.RedisAddr,
+			Password: cfg.RateLimit.RedisPassword,
+		})
+		defer redisClient.Close()
+
+		limiterConfig := ratelimit.DefaultConfig()
+		limiterConfig.FailOpen = cfg.RateLimit.FailOpen
+		limiter, err := ratelimit.NewLimiter(redisClient, limiterConfig)
+		if err != nil {
+			return err
+		}
+		srv.UseRateLimit(limiter.Middleware(ratelimit.MiddlewareConfig{
+			Plans:  ratelimit.NewOrganizationPlans(billingPlans(services.Billing), nil, cfg.RateLimit.PlanRefresh),
+			Scope:  "graphql",
+			Logger: logger,
+		}))
+	}
+
+	// Query limits and log level are hot reloadable
+	loader.OnChange(func(change dmconfig.Change) {
+		if len(change.Ignored) > 0 {
+			logger.Warn("configuration changes require restart", zap.Strings("paths", change.Ignored))
+		}
+
+		loader.RLock()
+		limits, logLevel := cfg.Limits, cfg.LogLevel
+		loader.RUnlock()
+
+		srv.SetLimits(limits)
+		if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+			logger.Warn("invalid log level", zap.String("level", logLevel), zap.Error(err))
+		}
+		logger.Info("configuration reloaded", zap.Strings("paths", change.Updated))
+	})
+	go loader.Watch(ctx, func(err error) {
+		logger.Error("configuration reload failed", zap.Error(err))
+	})
+
+	httpServer := &http.Server{
+		Addr:    cfg.ListenAddr,
+		Handler: srv.Handler(),
+	}
+
+	metricsServer := &http.Server{
+		Addr:    cfg.MetricsAddr,
+		Handler: promhttp.Handler(),
+	}
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("metrics server failed", zap.Error(err))
+		}
+	}()
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("graphql gateway listening", zap.String("addr", cfg.ListenAddr))
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down graphql gateway")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	_ = metricsServer.Shutdown(shutdownCtx)
+	return httpServer.Shutdown(shutdownCtx)
+}
+
+// billingPlans looks up the plan of an organization's subscription in the
+// billing API. Only the organization is forwarded, not the tier the caller
+// claims.
+func billingPlans(billing *backend.BillingService) ratelimit.PlanLookup {
+	return func(ctx context.Context, organizationID string) (*ratelimit.Plan, error) {
+		entitlements, err := billing.Entitlements(tenant.WithOrganization(ctx, organizationID))
+		if errors.Is(err, backend.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		slug, _ := entitlements["plan"].(string)
+		features, _ := entitlements["features"].(map[string]interface{})
+		return &ratelimit.Plan{Tier: tenant.Tier(slug), Features: features}, nil
+	}
+}
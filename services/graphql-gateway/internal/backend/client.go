@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package backend provides HTTP clients for the service APIs composed by the gateway
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+)
+
+// ErrNotFound is returned when a resource does not exist
+var ErrNotFound = errors.New("not found")
+
+// Object is a decoded JSON object returned by a service API
+type Object = map[string]interface{}
+
+// Error is returned for non-2xx responses
+type Error struct {
+	Service    string
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s returned %d: %s", e.Service, e.StatusCode, e.Message)
+}
+
+// Client calls a single service API. The tenant and the caller's credentials
+// are forwarded on every request.
+type Client struct {
+	service string
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a new service client
+func NewClient(service, baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		service: service,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http: &http.Client{
+			Timeout:   timeout,
+			Transport: tenant.NewTransport(nil),
+		},
+	}
+}
+
+// Get performs a GET request and decodes the response into out
+func (c *Client) Get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	if len(query) > 0 {
+		path = path + "?" + query.Encode()
+	}
+	return c.Do(ctx, http.MethodGet, path, nil, out)
+}
+
+// Post performs a POST request with a JSON body
+func (c *Client) Post(ctx context.Context, path string, body, out interface{}) error {
+	return c.Do(ctx, http.MethodPost, path, body, out)
+}
+
+// Do performs a request against the service API
+func (c *Client) Do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	// Absolute URLs point at source systems registered in the catalog; the
+	// caller's credentials are only forwarded to the service itself
+	target := path
+	external := strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+	if !external {
+		target = c.baseURL + path
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if auth := AuthorizationFromContext(ctx); auth != "" && !external {
+		req.Header.Set("Authorization", auth)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", c.service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return &Error{Service: c.service, StatusCode: resp.StatusCode, Message: errorMessage(data)}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to decode %s response: %w", c.service, err)
+	}
+	return nil
+}
+
+// List decodes list responses that are either a JSON array or an object
+// wrapping the array in "data" or "items"
+func List(raw interface{}) []Object {
+	var items []interface{}
+	switch v := raw.(type) {
+	case []interface{}:
+		items = v
+	case map[string]interface{}:
+		for _, key := range []string{"data", "items"} {
+			if list, ok := v[key].([]interface{}); ok {
+				items = list
+				break
+			}
+		}
+	}
+
+	out := make([]Object, 0, len(items))
+	for _, item := range items {
+		if obj, ok := item.(map[string]interface{}); ok {
+			out = append(out, obj)
+		}
+	}
+	return out
+}
+
+func errorMessage(data []byte) string {
+	var body struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &body) == nil {
+		if body.Error != "" {
+			return body.Error
+		}
+		if body.Message != "" {
+			return body.Message
+		}
+	}
+	return strings.TrimSpace(string(data))
+}
+
+type authorizationKey struct{}
+
+// WithAuthorization stores the caller's Authorization header for forwarding
+func WithAuthorization(ctx context.Context, authorization string) context.Context {
+	return context.WithValue(ctx, authorizationKey{}, authorization)
+}
+
+// AuthorizationFromContext returns the forwarded Authorization header
+func AuthorizationFromContext(ctx context.Context) string {
+	auth, _ := ctx.Value(authorizationKey{}).(string)
+	return auth
+}
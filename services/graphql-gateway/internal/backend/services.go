@@ -0,0 +1,311 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/config"
+)
+
+// Services bundles the clients of every composed service API
+type Services struct {
+	Catalog       *CatalogService
+	Adapters      *AdapterService
+	Billing       *BillingService
+	Notifications *NotificationService
+}
+
+// NewServices creates clients for all configured services
+func NewServices(cfg config.ServicesConfig) *Services {
+	return &Services{
+		Catalog:       &CatalogService{client: NewClient("catalog", cfg.CatalogURL, cfg.Timeout)},
+		Adapters:      &AdapterService{client: NewClient("adapters", cfg.AdapterURL, cfg.Timeout)},
+		Billing:       &BillingService{client: NewClient("billing", cfg.BillingURL, cfg.Timeout)},
+		Notifications: &NotificationService{client: NewClient("notifications", cfg.NotificationsURL, cfg.Timeout)},
+	}
+}
+
+// EntityFilter filters catalog entity listings
+type EntityFilter struct {
+	EntityType   string
+	SourceSystem string
+	Limit        int
+	Offset       int
+}
+
+// CatalogService calls the metadata catalog API
+type CatalogService struct {
+	client *Client
+}
+
+// Entity returns a catalog entity by catalog ID
+func (s *CatalogService) Entity(ctx context.Context, id string) (Object, error) {
+	var out Object
+	if err := s.client.Get(ctx, "/api/v1/entities/"+url.PathEscape(id), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EntitiesByIDs returns catalog entities by catalog ID in a single request
+func (s *CatalogService) EntitiesByIDs(ctx context.Context, ids []string) ([]Object, error) {
+	query := url.Values{}
+	query.Set("ids", strings.Join(ids, ","))
+
+	var out interface{}
+	if err := s.client.Get(ctx, "/api/v1/entities", query, &out); err != nil {
+		return nil, err
+	}
+	return List(out), nil
+}
+
+// Entities lists catalog entities
+func (s *CatalogService) Entities(ctx context.Context, filter EntityFilter) ([]Object, error) {
+	query := url.Values{}
+	if filter.EntityType != "" {
+		query.Set("type", filter.EntityType)
+	}
+	if filter.SourceSystem != "" {
+		query.Set("source", filter.SourceSystem)
+	}
+	query.Set("limit", strconv.Itoa(filter.Limit))
+	query.Set("offset", strconv.Itoa(filter.Offset))
+
+	var out interface{}
+	if err := s.client.Get(ctx, "/api/v1/entities", query, &out); err != nil {
+		return nil, err
+	}
+	return List(out), nil
+}
+
+// Search performs a catalog search
+func (s *CatalogService) Search(ctx context.Context, q string, limit int) ([]Object, error) {
+	query := url.Values{}
+	query.Set("q", q)
+	query.Set("limit", strconv.Itoa(limit))
+
+	var out interface{}
+	if err := s.client.Get(ctx, "/api/v1/entities/search", query, &out); err != nil {
+		return nil, err
+	}
+	return List(out), nil
+}
+
+// Relationships lists the current relationships of an entity
+func (s *CatalogService) Relationships(ctx context.Context, entityID, relationshipType string) ([]Object, error) {
+	query := url.Values{}
+	if relationshipType != "" {
+		query.Set("type", relationshipType)
+	}
+
+	var out interface{}
+	if err := s.client.Get(ctx, "/api/v1/entities/"+url.PathEscape(entityID)+"/relationships", query, &out); err != nil {
+		return nil, err
+	}
+	return List(out), nil
+}
+
+// Schemas lists registered entity schemas
+func (s *CatalogService) Schemas(ctx context.Context, entityType string) ([]Object, error) {
+	query := url.Values{}
+	if entityType != "" {
+		query.Set("entity_type", entityType)
+	}
+
+	var out interface{}
+	if err := s.client.Get(ctx, "/api/v1/schemas", query, &out); err != nil {
+		return nil, err
+	}
+	return List(out), nil
+}
+
+// SourceData fetches the live representation of an entity from its source
+// system using the API location registered in the catalog
+func (s *CatalogService) SourceData(ctx context.Context, entity Object) (interface{}, error) {
+	baseURL, _ := entity["api_base_url"].(string)
+	template, _ := entity["api_path_template"].(string)
+	sourceID, _ := entity["source_entity_id"].(string)
+	if baseURL == "" || template == "" {
+		return nil, nil
+	}
+
+	path := strings.ReplaceAll(template, "{id}", url.PathEscape(sourceID))
+	target := strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(path, "/")
+
+	var out interface{}
+	if err := s.client.Get(ctx, target, nil, &out); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch source data: %w", err)
+	}
+	return out, nil
+}
+
+// AdapterService calls the adapter registry API
+type AdapterService struct {
+	client *Client
+}
+
+// Adapters lists registered adapters
+func (s *AdapterService) Adapters(ctx context.Context) ([]Object, error) {
+	var out interface{}
+	if err := s.client.Get(ctx, "/api/v1/adapters", nil, &out); err != nil {
+		return nil, err
+	}
+	return List(out), nil
+}
+
+// Adapter returns a registered adapter by name
+func (s *AdapterService) Adapter(ctx context.Context, name string) (Object, error) {
+	var out Object
+	if err := s.client.Get(ctx, "/api/v1/adapters/"+url.PathEscape(name), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Health returns the health of every adapter in a single request
+func (s *AdapterService) Health(ctx context.Context) ([]Object, error) {
+	var out interface{}
+	if err := s.client.Get(ctx, "/api/v1/adapters/health", nil, &out); err != nil {
+		return nil, err
+	}
+	return List(out), nil
+}
+
+// BillingService calls the billing API for the tenant in the context
+type BillingService struct {
+	client *Client
+}
+
+// Organization returns a billing organization
+func (s *BillingService) Organization(ctx context.Context, id string) (Object, error) {
+	var out Object
+	if err := s.client.Get(ctx, "/api/v1/billing/organizations/"+url.PathEscape(id), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Subscriptions lists the organization's subscriptions
+func (s *BillingService) Subscriptions(ctx context.Context) ([]Object, error) {
+	var out interface{}
+	if err := s.client.Get(ctx, "/api/v1/billing/subscriptions", nil, &out); err != nil {
+		return nil, err
+	}
+	return List(out), nil
+}
+
+// CancelSubscription cancels a subscription
+func (s *BillingService) CancelSubscription(ctx context.Context, id string, atPeriodEnd bool) (Object, error) {
+	body := map[string]interface{}{"cancel_at_period_end": atPeriodEnd}
+
+	var out Object
+	if err := s.client.Post(ctx, "/api/v1/billing/subscriptions/"+url.PathEscape(id)+"/cancel", body, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Invoices lists the organization's invoices
+func (s *BillingService) Invoices(ctx context.Context, limit, offset int) ([]Object, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+
+	var out interface{}
+	if err := s.client.Get(ctx, "/api/v1/billing/invoices", query, &out); err != nil {
+		return nil, err
+	}
+	return List(out), nil
+}
+
+// Invoice returns an invoice
+func (s *BillingService) Invoice(ctx context.Context, id string) (Object, error) {
+	var out Object
+	if err := s.client.Get(ctx, "/api/v1/billing/invoices/"+url.PathEscape(id), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PayInvoice pays an invoice with the default payment method
+func (s *BillingService) PayInvoice(ctx context.Context, id string) (Object, error) {
+	var out Object
+	if err := s.client.Post(ctx, "/api/v1/billing/invoices/"+url.PathEscape(id)+"/pay", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Payments lists the organization's payments
+func (s *BillingService) Payments(ctx context.Context, limit, offset int) ([]Object, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+
+	var out interface{}
+	if err := s.client.Get(ctx, "/api/v1/billing/payments", query, &out); err != nil {
+		return nil, err
+	}
+	return List(out), nil
+}
+
+// CurrentUsage returns usage for the current billing period
+func (s *BillingService) CurrentUsage(ctx context.Context) (Object, error) {
+	var out Object
+	if err := s.client.Get(ctx, "/api/v1/billing/usage/current", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Entitlements returns the plan and features of the organization's current
+// subscription
+func (s *BillingService) Entitlements(ctx context.Context) (Object, error) {
+	var out Object
+	if err := s.client.Get(ctx, "/api/v1/billing/entitlements", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NotificationService calls the notifications API
+type NotificationService struct {
+	client *Client
+}
+
+// Send submits a notification
+func (s *NotificationService) Send(ctx context.Context, request map[string]interface{}) (Object, error) {
+	var out Object
+	if err := s.client.Post(ctx, "/api/v1/notifications", request, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Notification returns a notification and its delivery status
+func (s *NotificationService) Notification(ctx context.Context, id string) (Object, error) {
+	var out Object
+	if err := s.client.Get(ctx, "/api/v1/notifications/"+url.PathEscape(id), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Templates lists notification templates
+func (s *NotificationService) Templates(ctx context.Context) ([]Object, error) {
+	var out interface{}
+	if err := s.client.Get(ctx, "/api/v1/templates", nil, &out); err != nil {
+		return nil, err
+	}
+	return List(out), nil
+}
@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package complexity estimates the cost of GraphQL operations before they
+// are executed, so expensive queries can be rejected and metered.
+package complexity
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// listSizeArguments are the arguments that bound the size of list fields
+var listSizeArguments = []string{"limit", "first", "last"}
+
+// Estimate is the estimated cost of an operation
+type Estimate struct {
+	OperationName string
+	OperationType string // query | mutation | subscription
+	Complexity    int
+	Depth         int
+}
+
+// Analyzer estimates operation cost against a schema
+type Analyzer struct {
+	schema          *ast.Schema
+	defaultListSize atomic.Int64
+}
+
+// NewAnalyzer creates a new analyzer for the schema SDL. defaultListSize is
+// the assumed size of list fields without a limit argument.
+func NewAnalyzer(sdl string, defaultListSize int) (*Analyzer, error) {
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: "schema.graphql", Input: sdl})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+
+	a := &Analyzer{schema: schema}
+	a.defaultListSize.Store(int64(defaultListSize))
+	return a, nil
+}
+
+// SetDefaultListSize updates the assumed list size (hot reload)
+func (a *Analyzer) SetDefaultListSize(size int) {
+	a.defaultListSize.Store(int64(size))
+}
+
+// Analyze parses and validates the query and estimates the cost of the
+// selected operation. Every field costs 1; the cost of a list field's
+// selection is multiplied by its limit argument or the default list size.
+func (a *Analyzer) Analyze(query, operationName string, variables map[string]interface{}) (*Estimate, error) {
+	doc, errs := gqlparser.LoadQuery(a.schema, query)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var op *ast.OperationDefinition
+	if operationName != "" {
+		op = doc.Operations.ForName(operationName)
+	} else if len(doc.Operations) == 1 {
+		op = doc.Operations[0]
+	}
+	if op == nil {
+		return nil, fmt.Errorf("operation %q not found", operationName)
+	}
+
+	cost, depth := a.selectionCost(op.SelectionSet, variables, 1)
+
+	return &Estimate{
+		OperationName: op.Name,
+		OperationType: strings.ToLower(string(op.Operation)),
+		Complexity:    cost,
+		Depth:         depth,
+	}, nil
+}
+
+func (a *Analyzer) selectionCost(set ast.SelectionSet, vars map[string]interface{}, depth int) (int, int) {
+	total, maxDepth := 0, 0
+
+	for _, selection := range set {
+		var cost, d int
+
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			childCost, childDepth := a.selectionCost(s.SelectionSet, vars, depth+1)
+			cost = 1 + childCost*a.multiplier(s, vars)
+			d = depth
+			if childDepth > d {
+				d = childDepth
+			}
+		case *ast.InlineFragment:
+			cost, d = a.selectionCost(s.SelectionSet, vars, depth)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				cost, d = a.selectionCost(s.Definition.SelectionSet, vars, depth)
+			}
+		}
+
+		total += cost
+		if d > maxDepth {
+			maxDepth = d
+		}
+	}
+
+	return total, maxDepth
+}
+
+// multiplier returns the expected number of items of a list field
+func (a *Analyzer) multiplier(field *ast.Field, vars map[string]interface{}) int {
+	if field.Definition == nil || field.Definition.Type.Elem == nil {
+		return 1
+	}
+
+	for _, name := range listSizeArguments {
+		arg := field.Arguments.ForName(name)
+		if arg == nil {
+			continue
+		}
+		value, err := arg.Value.Value(vars)
+		if err != nil {
+			continue
+		}
+		switch n := value.(type) {
+		case int64:
+			if n > 0 {
+				return int(n)
+			}
+		case float64:
+			if n > 0 {
+				return int(n)
+			}
+		case int:
+			if n > 0 {
+				return n
+			}
+		}
+	}
+
+	// Fall back to the schema default of the limit argument, if any
+	for _, name := range listSizeArguments {
+		def := field.Definition.Arguments.ForName(name)
+		if def == nil || def.DefaultValue == nil {
+			continue
+		}
+		if value, err := def.DefaultValue.Value(nil); err == nil {
+			if n, ok := value.(int64); ok && n > 0 {
+				return int(n)
+			}
+		}
+	}
+
+	return int(a.defaultListSize.Load())
+}
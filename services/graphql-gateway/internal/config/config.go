@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package config holds the GraphQL gateway configuration
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config represents the GraphQL gateway configuration
+type Config struct {
+	// HTTP server
+	ListenAddr      string
+	ShutdownTimeout time.Duration
+
+	// Caller authentication
+	Auth AuthConfig
+
+	// Backend service APIs
+	Services ServicesConfig
+
+	// Query limits
+	Limits LimitsConfig `reload:"true"`
+
+	// Subscriptions
+	Subscriptions SubscriptionsConfig
+
+	// Rate limiting
+	RateLimit RateLimitConfig
+
+	// Observability
+	MetricsAddr string
+	LogLevel    string `reload:"true"`
+}
+
+// AuthConfig configures how callers are authenticated
+type AuthConfig struct {
+	// APIKeys maps each bearer API key to the ID of its organization. Requests
+	// without a key are anonymous.
+	APIKeys map[string]string
+}
+
+// ServicesConfig contains the base URLs of the composed service APIs
+type ServicesConfig struct {
+	CatalogURL       string
+	AdapterURL       string
+	BillingURL       string
+	NotificationsURL string
+	Timeout          time.Duration
+}
+
+// LimitsConfig contains query cost limits
+type LimitsConfig struct {
+	MaxComplexity   int // Maximum estimated query cost
+	MaxDepth        int // Maximum selection depth
+	DefaultListSize int // Assumed size of list fields without a limit argument
+}
+
+// SubscriptionsConfig configures GraphQL subscriptions backed by Kafka
+type SubscriptionsConfig struct {
+	Enabled       bool
+	KafkaBrokers  []string
+	EntityTopic   string
+	BillingTopics []string
+	KeepAlive     time.Duration // Interval between SSE keep-alive comments
+}
+
+// RateLimitConfig configures per-organization rate limiting backed by Redis
+type RateLimitConfig struct {
+	Enabled       bool
+	RedisAddr     string
+	RedisPassword string
+	FailOpen      bool          // Allow requests when Redis is unavailable
+	PlanRefresh   time.Duration // Time the plan of an organization is cached before it is looked up again
+}
+
+// DefaultConfig returns default gateway configuration
+func DefaultConfig() *Config {
+	return &Config{
+		ListenAddr:      ":8080",
+		ShutdownTimeout: 15 * time.Second,
+
+		Services: ServicesConfig{
+			CatalogURL:       "http://metadata-catalog:8080",
+			AdapterURL:       "http://metadata-catalog:8080",
+			BillingURL:       "http://billing:8080",
+			NotificationsURL: "http://notifications:8080",
+			Timeout:          10 * time.Second,
+		},
+
+		Limits: LimitsConfig{
+			MaxComplexity:   1000,
+			MaxDepth:        10,
+			DefaultListSize: 20,
+		},
+
+		Subscriptions: SubscriptionsConfig{
+			Enabled:      true,
+			KafkaBrokers: []string{"localhost:9092"},
+			EntityTopic:  "dictamesh.entity.changed",
+			BillingTopics: []string{
+				"billing.subscription.created",
+				"billing.subscription.updated",
+				"billing.subscription.canceled",
+				"billing.invoice.created",
+				"billing.invoice.paid",
+				"billing.invoice.overdue",
+				"billing.payment.succeeded",
+				"billing.payment.failed",
+				"billing.usage.threshold_reached",
+				"billing.credit.applied",
+			},
+			KeepAlive: 15 * time.Second,
+		},
+
+		RateLimit: RateLimitConfig{
+			Enabled:     true,
+			RedisAddr:   "localhost:6379",
+			FailOpen:    true,
+			PlanRefresh: 5 * time.Minute,
+		},
+
+		MetricsAddr: ":9090",
+		LogLevel:    "info",
+	}
+}
+
+// Validate checks if the configuration is valid
+func (c *Config) Validate() error {
+	if c.ListenAddr == "" {
+		return fmt.Errorf("listen address is required")
+	}
+
+	for key, orgID := range c.Auth.APIKeys {
+		if len(key) < 16 {
+			return fmt.Errorf("API keys must be at least 16 characters")
+		}
+		if orgID == "" {
+			return fmt.Errorf("API keys must name an organization")
+		}
+	}
+
+	if c.Services.CatalogURL == "" {
+		return fmt.Errorf("catalog service URL is required")
+	}
+
+	if c.Limits.MaxComplexity <= 0 {
+		return fmt.Errorf("max complexity must be positive")
+	}
+
+	if c.Limits.MaxDepth <= 0 {
+		return fmt.Errorf("max depth must be positive")
+	}
+
+	if c.Limits.DefaultListSize <= 0 {
+		return fmt.Errorf("default list size must be positive")
+	}
+
+	if c.Subscriptions.Enabled && len(c.Subscriptions.KafkaBrokers) == 0 {
+		return fmt.Errorf("kafka brokers are required when subscriptions are enabled")
+	}
+
+	if c.RateLimit.Enabled && c.RateLimit.RedisAddr == "" {
+		return fmt.Errorf("redis address is required when rate limiting is enabled")
+	}
+
+	return nil
+}
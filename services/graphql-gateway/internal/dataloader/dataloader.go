@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package dataloader batches and caches keyed lookups made while resolving a
+// single GraphQL request, avoiding N+1 calls to the service APIs.
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchFunc loads values for a batch of keys. Missing keys are simply absent
+// from the returned map.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Options configures a Loader
+type Options struct {
+	// Wait is how long the loader collects keys before dispatching a batch
+	Wait time.Duration
+
+	// MaxBatch dispatches early once this many keys are pending (0 = unlimited)
+	MaxBatch int
+}
+
+// DefaultOptions returns default loader options
+func DefaultOptions() Options {
+	return Options{
+		Wait:     2 * time.Millisecond,
+		MaxBatch: 100,
+	}
+}
+
+// result is the outcome of loading a single key
+type result[V any] struct {
+	value V
+	found bool
+	err   error
+	done  chan struct{}
+}
+
+// batch collects keys until it is dispatched
+type batch[K comparable, V any] struct {
+	keys    []K
+	results map[K]*result[V]
+	timer   *time.Timer
+}
+
+// Loader batches Load calls made within a short window into one BatchFunc
+// call and caches results for its lifetime. A Loader is scoped to one request.
+type Loader[K comparable, V any] struct {
+	fetch BatchFunc[K, V]
+	opts  Options
+
+	mu      sync.Mutex
+	cache   map[K]*result[V]
+	pending *batch[K, V]
+}
+
+// New creates a new Loader
+func New[K comparable, V any](fetch BatchFunc[K, V], opts Options) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch: fetch,
+		opts:  opts,
+		cache: make(map[K]*result[V]),
+	}
+}
+
+// Load returns the value for key, batching it with concurrent calls. The
+// second return value reports whether the key was found.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, bool, error) {
+	l.mu.Lock()
+
+	if r, ok := l.cache[key]; ok {
+		l.mu.Unlock()
+		return l.wait(ctx, r)
+	}
+
+	r := &result[V]{done: make(chan struct{})}
+	l.cache[key] = r
+
+	if l.pending == nil {
+		b := &batch[K, V]{results: make(map[K]*result[V])}
+		b.timer = time.AfterFunc(l.opts.Wait, func() { l.dispatch(ctx, b) })
+		l.pending = b
+	}
+
+	b := l.pending
+	b.keys = append(b.keys, key)
+	b.results[key] = r
+
+	full := l.opts.MaxBatch > 0 && len(b.keys) >= l.opts.MaxBatch
+	l.mu.Unlock()
+
+	if full && b.timer.Stop() {
+		go l.dispatch(ctx, b)
+	}
+
+	return l.wait(ctx, r)
+}
+
+// Prime stores a value in the cache, e.g. when a list query already returned it
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.cache[key]; ok {
+		return
+	}
+	r := &result[V]{value: value, found: true, done: make(chan struct{})}
+	close(r.done)
+	l.cache[key] = r
+}
+
+func (l *Loader[K, V]) wait(ctx context.Context, r *result[V]) (V, bool, error) {
+	select {
+	case <-r.done:
+		return r.value, r.found, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, false, ctx.Err()
+	}
+}
+
+func (l *Loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	l.mu.Lock()
+	if l.pending == b {
+		l.pending = nil
+	}
+	l.mu.Unlock()
+
+	values, err := l.fetch(ctx, b.keys)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, r := range b.results {
+		if err != nil {
+			r.err = err
+			// Failed keys are retried by later loads
+			delete(l.cache, key)
+		} else {
+			r.value, r.found = values[key]
+		}
+		close(r.done)
+	}
+}
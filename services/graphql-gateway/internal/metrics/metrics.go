@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package metrics defines the GraphQL gateway Prometheus metrics
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// GraphQLRequests counts GraphQL operations by type and outcome
+	GraphQLRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_graphql_requests_total",
+			Help: "Total GraphQL operations by operation type and status",
+		},
+		[]string{"operation_type", "status"},
+	)
+
+	// GraphQLDuration observes operation execution time
+	GraphQLDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dictamesh_graphql_request_duration_seconds",
+			Help:    "GraphQL operation duration by operation type",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+		},
+		[]string{"operation_type"},
+	)
+
+	// GraphQLComplexity observes the estimated cost of accepted operations
+	GraphQLComplexity = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dictamesh_graphql_query_complexity",
+			Help:    "Estimated GraphQL operation complexity by operation type",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+		},
+		[]string{"operation_type"},
+	)
+
+	// GraphQLComplexityRejected counts operations rejected by cost limits
+	GraphQLComplexityRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_graphql_complexity_rejected_total",
+			Help: "GraphQL operations rejected by complexity or depth limits",
+		},
+		[]string{"reason"},
+	)
+
+	// GraphQLOperations counts operations and their cost per organization.
+	// Billing aggregates it into the graphql_operations usage metric. Only
+	// organizations authenticated by an API key are recorded.
+	GraphQLOperations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_billing_graphql_operations_total",
+			Help: "Total GraphQL operations by organization",
+		},
+		[]string{"organization_id", "operation_type"},
+	)
+
+	// GraphQLComplexityTotal accumulates operation cost per organization
+	GraphQLComplexityTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dictamesh_billing_graphql_complexity_total",
+			Help: "Total estimated GraphQL complexity by organization",
+		},
+		[]string{"organization_id"},
+	)
+
+	// ActiveSubscriptions tracks open GraphQL subscriptions
+	ActiveSubscriptions = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "dictamesh_graphql_active_subscriptions",
+			Help: "Number of open GraphQL subscriptions",
+		},
+	)
+
+	// DataloaderBatchSize observes the number of keys per dataloader batch
+	DataloaderBatchSize = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dictamesh_graphql_dataloader_batch_size",
+			Help:    "Keys per dataloader batch by loader",
+			Buckets: prometheus.LinearBuckets(1, 10, 10),
+		},
+		[]string{"loader"},
+	)
+)
@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package pubsub fans Kafka events out to GraphQL subscriptions
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+)
+
+// Event is a message received from the event bus
+type Event struct {
+	Topic          string
+	Key            string
+	Value          []byte
+	OrganizationID string
+	Time           time.Time
+}
+
+// subscriber is a single GraphQL subscription
+type subscriber struct {
+	topics map[string]bool
+	ch     chan Event
+}
+
+// Hub consumes topics once per gateway instance and fans events out to
+// in-memory subscribers. Slow subscribers drop events rather than blocking
+// the consumer.
+type Hub struct {
+	brokers []string
+	topics  []string
+	groupID string
+	logger  *zap.Logger
+
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+// NewHub creates a hub consuming the given topics. Each gateway instance uses
+// its own consumer group so every instance sees every event.
+func NewHub(brokers, topics []string, logger *zap.Logger) *Hub {
+	hostname, _ := os.Hostname()
+
+	return &Hub{
+		brokers:     brokers,
+		topics:      topics,
+		groupID:     fmt.Sprintf("dictamesh-graphql-gateway-%s", hostname),
+		logger:      logger,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Start consumes every topic until ctx is canceled
+func (h *Hub) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, topic := range h.topics {
+		wg.Add(1)
+		go func(topic string) {
+			defer wg.Done()
+			h.consume(ctx, topic)
+		}(topic)
+	}
+	wg.Wait()
+}
+
+func (h *Hub) consume(ctx context.Context, topic string) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     h.brokers,
+		GroupID:     h.groupID,
+		Topic:       topic,
+		StartOffset: kafka.LastOffset,
+		MinBytes:    1,
+		MaxBytes:    10e6,
+	})
+	defer reader.Close()
+
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			h.logger.Warn("failed to read event", zap.String("topic", topic), zap.Error(err))
+			time.Sleep(time.Second)
+			continue
+		}
+
+		headers := make(map[string]string, len(msg.Headers))
+		for _, header := range msg.Headers {
+			headers[header.Key] = string(header.Value)
+		}
+
+		event := Event{
+			Topic: msg.Topic,
+			Key:   string(msg.Key),
+			Value: msg.Value,
+			Time:  msg.Time,
+		}
+		if t, ok := tenant.ExtractTenant(tenant.MapCarrier(headers)); ok {
+			event.OrganizationID = t.OrganizationID
+		}
+
+		h.publish(event)
+	}
+}
+
+// publish delivers an event to matching subscribers
+func (h *Hub) publish(event Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers {
+		if !sub.topics[event.Topic] {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			h.logger.Debug("dropping event for slow subscriber", zap.String("topic", event.Topic))
+		}
+	}
+}
+
+// Subscribe returns a channel of events for the given topics. The channel is
+// closed when ctx is canceled.
+func (h *Hub) Subscribe(ctx context.Context, topics []string) <-chan Event {
+	sub := &subscriber{
+		topics: make(map[string]bool, len(topics)),
+		ch:     make(chan Event, 64),
+	}
+	for _, topic := range topics {
+		sub.topics[topic] = true
+	}
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		delete(h.subscribers, sub)
+		h.mu.Unlock()
+		close(sub.ch)
+	}()
+
+	return sub.ch
+}
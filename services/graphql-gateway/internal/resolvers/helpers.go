@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package resolvers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/backend"
+)
+
+// JSON is the JSON scalar
+type JSON struct {
+	Value interface{}
+}
+
+// ImplementsGraphQLType maps JSON to the JSON scalar
+func (JSON) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+// UnmarshalGraphQL accepts any input value
+func (j *JSON) UnmarshalGraphQL(input interface{}) error {
+	j.Value = input
+	return nil
+}
+
+// MarshalJSON encodes the wrapped value
+func (j JSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Value)
+}
+
+// jsonPtr wraps a value in a nullable JSON scalar
+func jsonPtr(v interface{}) *JSON {
+	if v == nil {
+		return nil
+	}
+	return &JSON{Value: v}
+}
+
+// str returns a field as a string ("" when missing)
+func str(m backend.Object, key string) string {
+	switch v := m[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		if v == float64(int64(v)) {
+			return fmt.Sprintf("%d", int64(v))
+		}
+		return fmt.Sprint(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// optStr returns a field as a nullable string
+func optStr(m backend.Object, key string) *string {
+	if _, ok := m[key]; !ok || m[key] == nil {
+		return nil
+	}
+	s := str(m, key)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// id returns a field as a GraphQL ID
+func id(m backend.Object, key string) graphql.ID {
+	return graphql.ID(str(m, key))
+}
+
+// optID returns a field as a nullable GraphQL ID
+func optID(m backend.Object, key string) *graphql.ID {
+	s := optStr(m, key)
+	if s == nil {
+		return nil
+	}
+	v := graphql.ID(*s)
+	return &v
+}
+
+// boolean returns a field as a bool (false when missing)
+func boolean(m backend.Object, key string) bool {
+	b, _ := m[key].(bool)
+	return b
+}
+
+// optInt returns a field as a nullable Int
+func optInt(m backend.Object, key string) *int32 {
+	f, ok := m[key].(float64)
+	if !ok {
+		return nil
+	}
+	v := int32(f)
+	return &v
+}
+
+// optFloat returns a field as a nullable Float
+func optFloat(m backend.Object, key string) *float64 {
+	f, ok := m[key].(float64)
+	if !ok {
+		return nil
+	}
+	return &f
+}
+
+// stringList returns a field as a list of strings
+func stringList(m backend.Object, key string) []string {
+	items, _ := m[key].([]interface{})
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		out = append(out, fmt.Sprint(item))
+	}
+	return out
+}
+
+// intArg returns a defaulted Int argument, or def when it is not positive
+func intArg(v int32, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return int(v)
+}
+
+// strArg returns a nullable String argument or ""
+func strArg(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
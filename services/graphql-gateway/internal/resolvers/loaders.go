@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package resolvers
+
+import (
+	"context"
+
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/backend"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/dataloader"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/metrics"
+)
+
+// Loaders holds the per-request dataloaders
+type Loaders struct {
+	Entities      *dataloader.Loader[string, backend.Object]
+	Subscriptions *dataloader.Loader[string, backend.Object]
+	AdapterHealth *dataloader.Loader[string, backend.Object]
+}
+
+// NewLoaders creates dataloaders for a single request
+func NewLoaders(services *backend.Services) *Loaders {
+	opts := dataloader.DefaultOptions()
+
+	return &Loaders{
+		// Relationship endpoints are resolved in one catalog call per batch
+		Entities: dataloader.New(func(ctx context.Context, ids []string) (map[string]backend.Object, error) {
+			metrics.DataloaderBatchSize.WithLabelValues("entities").Observe(float64(len(ids)))
+
+			entities, err := services.Catalog.EntitiesByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			return indexBy(entities, "id"), nil
+		}, opts),
+
+		// The billing API lists all subscriptions of the tenant; one call
+		// serves every invoice in the response
+		Subscriptions: dataloader.New(func(ctx context.Context, ids []string) (map[string]backend.Object, error) {
+			metrics.DataloaderBatchSize.WithLabelValues("subscriptions").Observe(float64(len(ids)))
+
+			subscriptions, err := services.Billing.Subscriptions(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return indexBy(subscriptions, "id"), nil
+		}, opts),
+
+		// Adapter health is reported for all adapters at once
+		AdapterHealth: dataloader.New(func(ctx context.Context, names []string) (map[string]backend.Object, error) {
+			metrics.DataloaderBatchSize.WithLabelValues("adapter_health").Observe(float64(len(names)))
+
+			health, err := services.Adapters.Health(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return indexBy(health, "name"), nil
+		}, opts),
+	}
+}
+
+type loadersKey struct{}
+
+// WithLoaders stores request dataloaders in the context
+func WithLoaders(ctx context.Context, loaders *Loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, loaders)
+}
+
+// loadersFrom returns the request dataloaders
+func loadersFrom(ctx context.Context) *Loaders {
+	loaders, _ := ctx.Value(loadersKey{}).(*Loaders)
+	return loaders
+}
+
+func indexBy(objects []backend.Object, key string) map[string]backend.Object {
+	out := make(map[string]backend.Object, len(objects))
+	for _, obj := range objects {
+		out[str(obj, key)] = obj
+	}
+	return out
+}
@@ -0,0 +1,303 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package resolvers implements the gateway GraphQL schema on top of the
+// catalog, adapter, billing and notification service APIs
+package resolvers
+
+import (
+	"context"
+	"errors"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"go.uber.org/zap"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/backend"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/config"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/pubsub"
+)
+
+// Resolver is the root resolver for queries, mutations and subscriptions
+type Resolver struct {
+	services *backend.Services
+	hub      *pubsub.Hub
+	subs     config.SubscriptionsConfig
+	logger   *zap.Logger
+}
+
+// NewResolver creates the root resolver. hub may be nil when subscriptions
+// are disabled.
+func NewResolver(services *backend.Services, hub *pubsub.Hub, subs config.SubscriptionsConfig, logger *zap.Logger) *Resolver {
+	return &Resolver{
+		services: services,
+		hub:      hub,
+		subs:     subs,
+		logger:   logger,
+	}
+}
+
+// notFound converts backend.ErrNotFound into a nil result
+func notFound(err error) error {
+	if errors.Is(err, backend.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// ---------------------------------------------------------------------------
+// Catalog
+// ---------------------------------------------------------------------------
+
+// Entity resolves Query.entity
+func (r *Resolver) Entity(ctx context.Context, args struct{ ID graphql.ID }) (*EntityResolver, error) {
+	entity, err := r.services.Catalog.Entity(ctx, string(args.ID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return r.entity(ctx, entity), nil
+}
+
+// Entities resolves Query.entities
+func (r *Resolver) Entities(ctx context.Context, args struct {
+	Type   *string
+	Source *string
+	Limit  int32
+	Offset int32
+}) ([]*EntityResolver, error) {
+	entities, err := r.services.Catalog.Entities(ctx, backend.EntityFilter{
+		EntityType:   strArg(args.Type),
+		SourceSystem: strArg(args.Source),
+		Limit:        intArg(args.Limit, 20),
+		Offset:       intArg(args.Offset, 0),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.entities(ctx, entities), nil
+}
+
+// SearchEntities resolves Query.searchEntities
+func (r *Resolver) SearchEntities(ctx context.Context, args struct {
+	Query string
+	Limit int32
+}) ([]*EntityResolver, error) {
+	entities, err := r.services.Catalog.Search(ctx, args.Query, intArg(args.Limit, 20))
+	if err != nil {
+		return nil, err
+	}
+	return r.entities(ctx, entities), nil
+}
+
+// Schemas resolves Query.schemas
+func (r *Resolver) Schemas(ctx context.Context, args struct{ EntityType *string }) ([]*EntitySchemaResolver, error) {
+	schemas, err := r.services.Catalog.Schemas(ctx, strArg(args.EntityType))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*EntitySchemaResolver, len(schemas))
+	for i, s := range schemas {
+		out[i] = &EntitySchemaResolver{m: s}
+	}
+	return out, nil
+}
+
+// entity wraps a catalog object and primes the entity loader with it
+func (r *Resolver) entity(ctx context.Context, m backend.Object) *EntityResolver {
+	if loaders := loadersFrom(ctx); loaders != nil {
+		loaders.Entities.Prime(str(m, "id"), m)
+	}
+	return &EntityResolver{root: r, m: m}
+}
+
+func (r *Resolver) entities(ctx context.Context, objects []backend.Object) []*EntityResolver {
+	out := make([]*EntityResolver, len(objects))
+	for i, m := range objects {
+		out[i] = r.entity(ctx, m)
+	}
+	return out
+}
+
+// ---------------------------------------------------------------------------
+// Adapters
+// ---------------------------------------------------------------------------
+
+// Adapters resolves Query.adapters
+func (r *Resolver) Adapters(ctx context.Context) ([]*AdapterResolver, error) {
+	adapters, err := r.services.Adapters.Adapters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*AdapterResolver, len(adapters))
+	for i, a := range adapters {
+		out[i] = &AdapterResolver{m: a}
+	}
+	return out, nil
+}
+
+// Adapter resolves Query.adapter
+func (r *Resolver) Adapter(ctx context.Context, args struct{ Name string }) (*AdapterResolver, error) {
+	adapter, err := r.services.Adapters.Adapter(ctx, args.Name)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &AdapterResolver{m: adapter}, nil
+}
+
+// ---------------------------------------------------------------------------
+// Billing
+// ---------------------------------------------------------------------------
+
+// Organization resolves Query.organization for the current tenant
+func (r *Resolver) Organization(ctx context.Context) (*OrganizationResolver, error) {
+	orgID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := r.services.Billing.Organization(ctx, orgID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &OrganizationResolver{root: r, m: org}, nil
+}
+
+// Invoices resolves Query.invoices
+func (r *Resolver) Invoices(ctx context.Context, args struct {
+	Limit  int32
+	Offset int32
+}) ([]*InvoiceResolver, error) {
+	return r.invoices(ctx, intArg(args.Limit, 20), intArg(args.Offset, 0))
+}
+
+func (r *Resolver) invoices(ctx context.Context, limit, offset int) ([]*InvoiceResolver, error) {
+	invoices, err := r.services.Billing.Invoices(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*InvoiceResolver, len(invoices))
+	for i, inv := range invoices {
+		out[i] = &InvoiceResolver{m: inv}
+	}
+	return out, nil
+}
+
+// Invoice resolves Query.invoice
+func (r *Resolver) Invoice(ctx context.Context, args struct{ ID graphql.ID }) (*InvoiceResolver, error) {
+	invoice, err := r.services.Billing.Invoice(ctx, string(args.ID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &InvoiceResolver{m: invoice}, nil
+}
+
+// Payments resolves Query.payments
+func (r *Resolver) Payments(ctx context.Context, args struct {
+	Limit  int32
+	Offset int32
+}) ([]*PaymentResolver, error) {
+	payments, err := r.services.Billing.Payments(ctx, intArg(args.Limit, 20), intArg(args.Offset, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*PaymentResolver, len(payments))
+	for i, p := range payments {
+		out[i] = &PaymentResolver{m: p}
+	}
+	return out, nil
+}
+
+// CurrentUsage resolves Query.currentUsage
+func (r *Resolver) CurrentUsage(ctx context.Context) (*JSON, error) {
+	usage, err := r.services.Billing.CurrentUsage(ctx)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return jsonPtr(usage), nil
+}
+
+// CancelSubscription resolves Mutation.cancelSubscription
+func (r *Resolver) CancelSubscription(ctx context.Context, args struct {
+	ID          graphql.ID
+	AtPeriodEnd bool
+}) (*BillingSubscriptionResolver, error) {
+	sub, err := r.services.Billing.CancelSubscription(ctx, string(args.ID), args.AtPeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+	return &BillingSubscriptionResolver{m: sub}, nil
+}
+
+// PayInvoice resolves Mutation.payInvoice
+func (r *Resolver) PayInvoice(ctx context.Context, args struct{ ID graphql.ID }) (*PaymentResolver, error) {
+	payment, err := r.services.Billing.PayInvoice(ctx, string(args.ID))
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentResolver{m: payment}, nil
+}
+
+// ---------------------------------------------------------------------------
+// Notifications
+// ---------------------------------------------------------------------------
+
+// Notification resolves Query.notification
+func (r *Resolver) Notification(ctx context.Context, args struct{ ID graphql.ID }) (*NotificationResolver, error) {
+	n, err := r.services.Notifications.Notification(ctx, string(args.ID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &NotificationResolver{m: n}, nil
+}
+
+// NotificationTemplates resolves Query.notificationTemplates
+func (r *Resolver) NotificationTemplates(ctx context.Context) ([]*NotificationTemplateResolver, error) {
+	templates, err := r.services.Notifications.Templates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*NotificationTemplateResolver, len(templates))
+	for i, t := range templates {
+		out[i] = &NotificationTemplateResolver{m: t}
+	}
+	return out, nil
+}
+
+// SendNotificationInput is the input of Mutation.sendNotification
+type SendNotificationInput struct {
+	RecipientID   graphql.ID
+	RecipientType string
+	TemplateCode  string
+	Channels      *[]string
+	Priority      string
+	Data          *JSON
+}
+
+// SendNotification resolves Mutation.sendNotification
+func (r *Resolver) SendNotification(ctx context.Context, args struct{ Input SendNotificationInput }) (*NotificationResolver, error) {
+	in := args.Input
+
+	request := map[string]interface{}{
+		"recipient_id":   string(in.RecipientID),
+		"recipient_type": in.RecipientType,
+		"template_code":  in.TemplateCode,
+		"priority":       in.Priority,
+	}
+	if in.Channels != nil {
+		request["channels"] = *in.Channels
+	}
+	if in.Data != nil {
+		request["data"] = in.Data.Value
+	}
+
+	n, err := r.services.Notifications.Send(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &NotificationResolver{m: n}, nil
+}
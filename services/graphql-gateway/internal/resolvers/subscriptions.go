@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"go.uber.org/zap"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/metrics"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/pubsub"
+)
+
+// ErrSubscriptionsDisabled is returned when subscriptions are not enabled
+var ErrSubscriptionsDisabled = errors.New("subscriptions are disabled")
+
+// EntityChanged resolves Subscription.entityChanged
+func (r *Resolver) EntityChanged(ctx context.Context, args struct{ EntityType *string }) (<-chan *EventResolver, error) {
+	if r.hub == nil {
+		return nil, ErrSubscriptionsDisabled
+	}
+
+	entityType := strArg(args.EntityType)
+	return r.stream(ctx, []string{r.subs.EntityTopic}, func(m map[string]interface{}, _ pubsub.Event) bool {
+		return entityType == "" || str(m, "entity_type") == entityType
+	}), nil
+}
+
+// BillingEvents resolves Subscription.billingEvents. Only events of the
+// caller's organization are delivered.
+func (r *Resolver) BillingEvents(ctx context.Context, args struct{ Types *[]string }) (<-chan *EventResolver, error) {
+	if r.hub == nil {
+		return nil, ErrSubscriptionsDisabled
+	}
+
+	orgID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requested types are restricted to the configured billing topics
+	topics := r.subs.BillingTopics
+	if args.Types != nil && len(*args.Types) > 0 {
+		allowed := make(map[string]bool, len(topics))
+		for _, topic := range topics {
+			allowed[topic] = true
+		}
+		topics = nil
+		for _, t := range *args.Types {
+			if allowed[t] {
+				topics = append(topics, t)
+			}
+		}
+	}
+
+	return r.stream(ctx, topics, func(m map[string]interface{}, event pubsub.Event) bool {
+		if event.OrganizationID != "" {
+			return event.OrganizationID == orgID
+		}
+		return str(m, "organization_id") == orgID
+	}), nil
+}
+
+// stream subscribes to the hub and converts matching events into resolvers
+func (r *Resolver) stream(ctx context.Context, topics []string, match func(map[string]interface{}, pubsub.Event) bool) <-chan *EventResolver {
+	events := r.hub.Subscribe(ctx, topics)
+	out := make(chan *EventResolver)
+
+	metrics.ActiveSubscriptions.Inc()
+	go func() {
+		defer metrics.ActiveSubscriptions.Dec()
+		defer close(out)
+
+		for event := range events {
+			var m map[string]interface{}
+			if err := json.Unmarshal(event.Value, &m); err != nil {
+				r.logger.Debug("skipping undecodable event", zap.String("topic", event.Topic), zap.Error(err))
+				continue
+			}
+			if !match(m, event) {
+				continue
+			}
+
+			select {
+			case out <- &EventResolver{m: m, topic: event.Topic}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// EventResolver resolves EntityEvent and BillingEvent
+type EventResolver struct {
+	m     map[string]interface{}
+	topic string
+}
+
+func (e *EventResolver) EventID() graphql.ID        { return id(e.m, "event_id") }
+func (e *EventResolver) EntityType() string         { return str(e.m, "entity_type") }
+func (e *EventResolver) EntityID() string           { return str(e.m, "entity_id") }
+func (e *EventResolver) OrganizationID() graphql.ID { return id(e.m, "organization_id") }
+func (e *EventResolver) OccurredAt() *string        { return optStr(e.m, "occurred_at") }
+func (e *EventResolver) Payload() *JSON             { return jsonPtr(map[string]interface{}(e.m)) }
+
+// EventType returns the event type, falling back to the topic name
+func (e *EventResolver) EventType() string {
+	if t := str(e.m, "event_type"); t != "" {
+		return t
+	}
+	return e.topic
+}
@@ -0,0 +1,283 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package resolvers
+
+import (
+	"context"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/backend"
+)
+
+// EntityResolver resolves catalog entities
+type EntityResolver struct {
+	root *Resolver
+	m    backend.Object
+}
+
+func (e *EntityResolver) ID() graphql.ID              { return id(e.m, "id") }
+func (e *EntityResolver) EntityType() string          { return str(e.m, "entity_type") }
+func (e *EntityResolver) Domain() string              { return str(e.m, "domain") }
+func (e *EntityResolver) SourceSystem() string        { return str(e.m, "source_system") }
+func (e *EntityResolver) SourceEntityID() string      { return str(e.m, "source_entity_id") }
+func (e *EntityResolver) Status() string              { return str(e.m, "status") }
+func (e *EntityResolver) ContainsPii() bool           { return boolean(e.m, "contains_pii") }
+func (e *EntityResolver) DataClassification() *string { return optStr(e.m, "data_classification") }
+func (e *EntityResolver) SchemaVersion() *string      { return optStr(e.m, "schema_version") }
+
+// Data fetches the live entity from its source system
+func (e *EntityResolver) Data(ctx context.Context) (*JSON, error) {
+	data, err := e.root.services.Catalog.SourceData(ctx, e.m)
+	if err != nil {
+		return nil, err
+	}
+	return jsonPtr(data), nil
+}
+
+// Relationships lists the entity's current relationships
+func (e *EntityResolver) Relationships(ctx context.Context, args struct{ Type *string }) ([]*RelationshipResolver, error) {
+	rels, err := e.root.services.Catalog.Relationships(ctx, str(e.m, "id"), strArg(args.Type))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*RelationshipResolver, len(rels))
+	for i, rel := range rels {
+		out[i] = &RelationshipResolver{root: e.root, m: rel}
+	}
+	return out, nil
+}
+
+// RelationshipResolver resolves entity relationships
+type RelationshipResolver struct {
+	root *Resolver
+	m    backend.Object
+}
+
+func (r *RelationshipResolver) ID() graphql.ID           { return id(r.m, "id") }
+func (r *RelationshipResolver) RelationshipType() string { return str(r.m, "relationship_type") }
+func (r *RelationshipResolver) Cardinality() *string     { return optStr(r.m, "relationship_cardinality") }
+func (r *RelationshipResolver) ValidFrom() *string       { return optStr(r.m, "valid_from") }
+func (r *RelationshipResolver) Metadata() *JSON          { return jsonPtr(r.m["relationship_metadata"]) }
+
+// Subject resolves the subject entity through the entity dataloader
+func (r *RelationshipResolver) Subject(ctx context.Context) (*EntityResolver, error) {
+	return r.load(ctx, str(r.m, "subject_catalog_id"))
+}
+
+// Object resolves the object entity through the entity dataloader
+func (r *RelationshipResolver) Object(ctx context.Context) (*EntityResolver, error) {
+	return r.load(ctx, str(r.m, "object_catalog_id"))
+}
+
+func (r *RelationshipResolver) load(ctx context.Context, catalogID string) (*EntityResolver, error) {
+	if catalogID == "" {
+		return nil, nil
+	}
+
+	var (
+		entity backend.Object
+		found  bool
+		err    error
+	)
+	if loaders := loadersFrom(ctx); loaders != nil {
+		entity, found, err = loaders.Entities.Load(ctx, catalogID)
+	} else {
+		entity, err = r.root.services.Catalog.Entity(ctx, catalogID)
+		found = entity != nil
+	}
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &EntityResolver{root: r.root, m: entity}, nil
+}
+
+// EntitySchemaResolver resolves entity schemas
+type EntitySchemaResolver struct {
+	m backend.Object
+}
+
+func (s *EntitySchemaResolver) ID() graphql.ID           { return id(s.m, "id") }
+func (s *EntitySchemaResolver) EntityType() string       { return str(s.m, "entity_type") }
+func (s *EntitySchemaResolver) Version() string          { return str(s.m, "version") }
+func (s *EntitySchemaResolver) SchemaFormat() string     { return str(s.m, "schema_format") }
+func (s *EntitySchemaResolver) Definition() *JSON        { return jsonPtr(s.m["schema_definition"]) }
+func (s *EntitySchemaResolver) BackwardCompatible() bool { return boolean(s.m, "backward_compatible") }
+func (s *EntitySchemaResolver) PublishedAt() *string     { return optStr(s.m, "published_at") }
+
+// AdapterResolver resolves registered adapters
+type AdapterResolver struct {
+	m backend.Object
+}
+
+func (a *AdapterResolver) Name() string        { return str(a.m, "name") }
+func (a *AdapterResolver) Type() *string       { return optStr(a.m, "type") }
+func (a *AdapterResolver) Version() *string    { return optStr(a.m, "version") }
+func (a *AdapterResolver) Status() *string     { return optStr(a.m, "status") }
+func (a *AdapterResolver) LastSyncAt() *string { return optStr(a.m, "last_sync_at") }
+
+// Health resolves adapter health through the adapter health dataloader
+func (a *AdapterResolver) Health(ctx context.Context) (*AdapterHealthResolver, error) {
+	loaders := loadersFrom(ctx)
+	if loaders == nil {
+		return nil, nil
+	}
+
+	health, found, err := loaders.AdapterHealth.Load(ctx, str(a.m, "name"))
+	if err != nil || !found {
+		return nil, notFound(err)
+	}
+	return &AdapterHealthResolver{m: health}, nil
+}
+
+// AdapterHealthResolver resolves adapter health reports
+type AdapterHealthResolver struct {
+	m backend.Object
+}
+
+func (h *AdapterHealthResolver) Status() string      { return str(h.m, "status") }
+func (h *AdapterHealthResolver) LatencyMs() *float64 { return optFloat(h.m, "latency_ms") }
+func (h *AdapterHealthResolver) ErrorRate() *float64 { return optFloat(h.m, "error_rate") }
+func (h *AdapterHealthResolver) CheckedAt() *string  { return optStr(h.m, "checked_at") }
+func (h *AdapterHealthResolver) Message() *string    { return optStr(h.m, "message") }
+
+// OrganizationResolver resolves billing organizations
+type OrganizationResolver struct {
+	root *Resolver
+	m    backend.Object
+}
+
+func (o *OrganizationResolver) ID() graphql.ID       { return id(o.m, "id") }
+func (o *OrganizationResolver) Name() string         { return str(o.m, "name") }
+func (o *OrganizationResolver) BillingEmail() string { return str(o.m, "billing_email") }
+func (o *OrganizationResolver) Currency() string     { return str(o.m, "currency") }
+func (o *OrganizationResolver) Status() string       { return str(o.m, "status") }
+
+// Subscriptions lists the organization's subscriptions
+func (o *OrganizationResolver) Subscriptions(ctx context.Context) ([]*BillingSubscriptionResolver, error) {
+	subs, err := o.root.services.Billing.Subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	loaders := loadersFrom(ctx)
+	out := make([]*BillingSubscriptionResolver, len(subs))
+	for i, s := range subs {
+		if loaders != nil {
+			loaders.Subscriptions.Prime(str(s, "id"), s)
+		}
+		out[i] = &BillingSubscriptionResolver{m: s}
+	}
+	return out, nil
+}
+
+// Invoices lists the organization's invoices
+func (o *OrganizationResolver) Invoices(ctx context.Context, args struct {
+	Limit  int32
+	Offset int32
+}) ([]*InvoiceResolver, error) {
+	return o.root.invoices(ctx, intArg(args.Limit, 20), intArg(args.Offset, 0))
+}
+
+// BillingSubscriptionResolver resolves subscriptions
+type BillingSubscriptionResolver struct {
+	m backend.Object
+}
+
+func (s *BillingSubscriptionResolver) ID() graphql.ID     { return id(s.m, "id") }
+func (s *BillingSubscriptionResolver) PlanID() graphql.ID { return id(s.m, "plan_id") }
+func (s *BillingSubscriptionResolver) Status() string     { return str(s.m, "status") }
+func (s *BillingSubscriptionResolver) CurrentPeriodStart() *string {
+	return optStr(s.m, "current_period_start")
+}
+func (s *BillingSubscriptionResolver) CurrentPeriodEnd() *string {
+	return optStr(s.m, "current_period_end")
+}
+func (s *BillingSubscriptionResolver) CancelAtPeriodEnd() bool {
+	return boolean(s.m, "cancel_at_period_end")
+}
+func (s *BillingSubscriptionResolver) TrialEnd() *string { return optStr(s.m, "trial_end") }
+
+// Quantity returns the number of seats (defaults to 1)
+func (s *BillingSubscriptionResolver) Quantity() int32 {
+	if q := optInt(s.m, "quantity"); q != nil {
+		return *q
+	}
+	return 1
+}
+
+// InvoiceResolver resolves invoices
+type InvoiceResolver struct {
+	m backend.Object
+}
+
+func (i *InvoiceResolver) ID() graphql.ID        { return id(i.m, "id") }
+func (i *InvoiceResolver) InvoiceNumber() string { return str(i.m, "invoice_number") }
+func (i *InvoiceResolver) Status() string        { return str(i.m, "status") }
+func (i *InvoiceResolver) Currency() string      { return str(i.m, "currency") }
+func (i *InvoiceResolver) Subtotal() string      { return str(i.m, "subtotal") }
+func (i *InvoiceResolver) TaxAmount() string     { return str(i.m, "tax_amount") }
+func (i *InvoiceResolver) TotalAmount() string   { return str(i.m, "total_amount") }
+func (i *InvoiceResolver) AmountDue() string     { return str(i.m, "amount_due") }
+func (i *InvoiceResolver) AmountPaid() string    { return str(i.m, "amount_paid") }
+func (i *InvoiceResolver) InvoiceDate() *string  { return optStr(i.m, "invoice_date") }
+func (i *InvoiceResolver) DueDate() *string      { return optStr(i.m, "due_date") }
+func (i *InvoiceResolver) PaidAt() *string       { return optStr(i.m, "paid_at") }
+func (i *InvoiceResolver) PdfURL() *string       { return optStr(i.m, "pdf_url") }
+func (i *InvoiceResolver) LineItems() *JSON      { return jsonPtr(i.m["line_items"]) }
+
+// Subscription resolves the invoiced subscription through the subscription dataloader
+func (i *InvoiceResolver) Subscription(ctx context.Context) (*BillingSubscriptionResolver, error) {
+	subID := str(i.m, "subscription_id")
+	loaders := loadersFrom(ctx)
+	if subID == "" || loaders == nil {
+		return nil, nil
+	}
+
+	sub, found, err := loaders.Subscriptions.Load(ctx, subID)
+	if err != nil || !found {
+		return nil, notFound(err)
+	}
+	return &BillingSubscriptionResolver{m: sub}, nil
+}
+
+// PaymentResolver resolves payments
+type PaymentResolver struct {
+	m backend.Object
+}
+
+func (p *PaymentResolver) ID() graphql.ID          { return id(p.m, "id") }
+func (p *PaymentResolver) InvoiceID() *graphql.ID  { return optID(p.m, "invoice_id") }
+func (p *PaymentResolver) Amount() string          { return str(p.m, "amount") }
+func (p *PaymentResolver) Currency() string        { return str(p.m, "currency") }
+func (p *PaymentResolver) Status() string          { return str(p.m, "status") }
+func (p *PaymentResolver) Provider() string        { return str(p.m, "provider") }
+func (p *PaymentResolver) FailureMessage() *string { return optStr(p.m, "failure_message") }
+func (p *PaymentResolver) CreatedAt() *string      { return optStr(p.m, "created_at") }
+
+// NotificationResolver resolves notifications
+type NotificationResolver struct {
+	m backend.Object
+}
+
+func (n *NotificationResolver) ID() graphql.ID        { return id(n.m, "id") }
+func (n *NotificationResolver) Status() string        { return str(n.m, "status") }
+func (n *NotificationResolver) TemplateCode() *string { return optStr(n.m, "template_code") }
+func (n *NotificationResolver) Channels() []string    { return stringList(n.m, "channels") }
+func (n *NotificationResolver) CreatedAt() *string    { return optStr(n.m, "created_at") }
+
+// NotificationTemplateResolver resolves notification templates
+type NotificationTemplateResolver struct {
+	m backend.Object
+}
+
+func (t *NotificationTemplateResolver) Code() string      { return str(t.m, "code") }
+func (t *NotificationTemplateResolver) Name() string      { return str(t.m, "name") }
+func (t *NotificationTemplateResolver) Category() *string { return optStr(t.m, "category") }
+func (t *NotificationTemplateResolver) Version() *int32   { return optInt(t.m, "version") }
+func (t *NotificationTemplateResolver) Active() bool      { return boolean(t.m, "active") }
@@ -0,0 +1,12 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package schema embeds the gateway's GraphQL schema definition
+package schema
+
+import _ "embed"
+
+// SDL is the composed GraphQL schema served by the gateway
+//
+//go:embed schema.graphql
+var SDL string
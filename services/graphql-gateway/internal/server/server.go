@@ -0,0 +1,261 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package server serves the GraphQL gateway over HTTP
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"go.uber.org/zap"
+
+	"github.com/click2-run/dictamesh/pkg/tenant"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/backend"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/complexity"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/config"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/metrics"
+	"github.com/click2-run/dictamesh/services/graphql-gateway/internal/resolvers"
+)
+
+// maxRequestBytes bounds the size of a GraphQL request body
+const maxRequestBytes = 1 << 20
+
+// Request is a GraphQL request
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Server handles GraphQL requests
+type Server struct {
+	schema    *graphql.Schema
+	services  *backend.Services
+	analyzer  *complexity.Analyzer
+	keepAlive time.Duration
+	resolver  tenant.Resolver
+	rateLimit func(http.Handler) http.Handler
+	logger    *zap.Logger
+
+	limits atomic.Pointer[config.LimitsConfig]
+}
+
+// New creates a new GraphQL server
+func New(schema *graphql.Schema, services *backend.Services, analyzer *complexity.Analyzer, cfg *config.Config, logger *zap.Logger) *Server {
+	s := &Server{
+		schema:    schema,
+		services:  services,
+		analyzer:  analyzer,
+		keepAlive: cfg.Subscriptions.KeepAlive,
+		resolver:  tenant.APIKeyResolver(cfg.Auth.APIKeys),
+		logger:    logger,
+	}
+	s.SetLimits(cfg.Limits)
+	return s
+}
+
+// SetLimits replaces the query cost limits (hot reload)
+func (s *Server) SetLimits(limits config.LimitsConfig) {
+	s.limits.Store(&limits)
+	s.analyzer.SetDefaultListSize(limits.DefaultListSize)
+}
+
+// UseRateLimit installs rate limiting middleware in front of /graphql. It
+// runs after tenant resolution.
+func (s *Server) UseRateLimit(middleware func(http.Handler) http.Handler) {
+	s.rateLimit = middleware
+}
+
+// Handler returns the HTTP handler serving /graphql and /healthz
+func (s *Server) Handler() http.Handler {
+	var graphqlHandler http.Handler = http.HandlerFunc(s.serveGraphQL)
+	if s.rateLimit != nil {
+		graphqlHandler = s.rateLimit(graphqlHandler)
+	}
+
+	authenticate := tenant.Middleware(tenant.MiddlewareConfig{
+		Resolver: s.resolver,
+		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
+			writeErrors(w, http.StatusUnauthorized, err)
+		},
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", stripTenantHeaders(authenticate(graphqlHandler)))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	return mux
+}
+
+// stripTenantHeaders drops the propagation headers sent by callers, so the
+// tenant only ever comes from their API key
+func stripTenantHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant.StripHeaders(r.Header)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	req, err := decodeRequest(r)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Reject expensive operations before touching any backend
+	estimate, err := s.analyzer.Analyze(req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		metrics.GraphQLRequests.WithLabelValues("unknown", "invalid").Inc()
+		writeErrors(w, http.StatusBadRequest, err)
+		return
+	}
+
+	limits := s.limits.Load()
+	if estimate.Depth > limits.MaxDepth {
+		metrics.GraphQLComplexityRejected.WithLabelValues("depth").Inc()
+		writeErrors(w, http.StatusBadRequest, fmt.Errorf("query depth %d exceeds limit %d", estimate.Depth, limits.MaxDepth))
+		return
+	}
+	if estimate.Complexity > limits.MaxComplexity {
+		metrics.GraphQLComplexityRejected.WithLabelValues("complexity").Inc()
+		writeErrors(w, http.StatusBadRequest, fmt.Errorf("query complexity %d exceeds limit %d", estimate.Complexity, limits.MaxComplexity))
+		return
+	}
+
+	metrics.GraphQLComplexity.WithLabelValues(estimate.OperationType).Observe(float64(estimate.Complexity))
+	// The organization was resolved from a configured API key, which bounds
+	// the label values
+	if orgID, err := tenant.OrganizationID(r.Context()); err == nil {
+		metrics.GraphQLOperations.WithLabelValues(orgID, estimate.OperationType).Inc()
+		metrics.GraphQLComplexityTotal.WithLabelValues(orgID).Add(float64(estimate.Complexity))
+	}
+
+	ctx := backend.WithAuthorization(r.Context(), r.Header.Get("Authorization"))
+	ctx = resolvers.WithLoaders(ctx, resolvers.NewLoaders(s.services))
+
+	if estimate.OperationType == "subscription" {
+		s.serveSubscription(ctx, w, req)
+		return
+	}
+
+	start := time.Now()
+	resp := s.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	metrics.GraphQLDuration.WithLabelValues(estimate.OperationType).Observe(time.Since(start).Seconds())
+
+	status := "success"
+	if len(resp.Errors) > 0 {
+		status = "error"
+	}
+	metrics.GraphQLRequests.WithLabelValues(estimate.OperationType, status).Inc()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.Warn("failed to write response", zap.Error(err))
+	}
+}
+
+// serveSubscription streams subscription results as server-sent events
+func (s *Server) serveSubscription(ctx context.Context, w http.ResponseWriter, req *Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrors(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results, err := s.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		metrics.GraphQLRequests.WithLabelValues("subscription", "error").Inc()
+		writeErrors(w, http.StatusBadRequest, err)
+		return
+	}
+	metrics.GraphQLRequests.WithLabelValues("subscription", "success").Inc()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(s.keepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case result, ok := <-results:
+			if !ok {
+				fmt.Fprint(w, "event: complete\ndata: {}\n\n")
+				flusher.Flush()
+				return
+			}
+
+			data, err := json.Marshal(result)
+			if err != nil {
+				s.logger.Warn("failed to encode subscription result", zap.Error(err))
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: next\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// decodeRequest reads a GraphQL request from a POST body or GET query
+func decodeRequest(r *http.Request) (*Request, error) {
+	var req Request
+
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if vars := r.URL.Query().Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				return nil, fmt.Errorf("failed to decode variables: %w", err)
+			}
+		}
+
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+			return nil, fmt.Errorf("failed to decode request: %w", err)
+		}
+
+	default:
+		return nil, fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	if req.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	return &req, nil
+}
+
+// writeErrors writes a GraphQL error response
+func writeErrors(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{{"message": err.Error()}},
+	})
+}
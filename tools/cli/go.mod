@@ -0,0 +1,18 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+module github.com/click2-run/dictamesh/tools/cli
+
+go 1.21
+
+require (
+	github.com/click2-run/dictamesh/pkg/tenant v0.0.0
+	github.com/spf13/cobra v1.8.0
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+)
+
+replace github.com/click2-run/dictamesh/pkg/tenant => ../../pkg/tenant
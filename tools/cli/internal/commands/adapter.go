@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package commands
+
+import (
+	"net/url"
+
+	"github.com/spf13/cobra"
+)
+
+func newAdapterCommand(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "adapter",
+		Aliases: []string{"adapters"},
+		Short:   "Manage data source adapters",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "List registered adapters",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/adapters", nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).List(out, "name", "type", "version", "status", "last_sync_at")
+			},
+		},
+		&cobra.Command{
+			Use:   "get NAME",
+			Short: "Show an adapter",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/adapters/"+url.PathEscape(args[0]), nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).Object(out)
+			},
+		},
+		newAdapterRegisterCommand(opts),
+		&cobra.Command{
+			Use:   "deregister NAME",
+			Short: "Deregister an adapter",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if err := opts.client().Delete(cmd.Context(), "/api/v1/adapters/"+url.PathEscape(args[0])); err != nil {
+					return err
+				}
+				opts.printer(cmd).Message("adapter %s deregistered", args[0])
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "health [NAME]",
+			Short: "Show adapter health",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var out interface{}
+				if len(args) == 0 {
+					if err := opts.client().Get(cmd.Context(), "/api/v1/adapters/health", nil, &out); err != nil {
+						return err
+					}
+					return opts.printer(cmd).List(out, "name", "status", "latency_ms", "error_rate", "checked_at", "message")
+				}
+
+				path := "/api/v1/adapters/" + url.PathEscape(args[0]) + "/health"
+				if err := opts.client().Get(cmd.Context(), path, nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).Object(out)
+			},
+		},
+	)
+
+	return cmd
+}
+
+func newAdapterRegisterCommand(opts *globalOptions) *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "register -f FILE",
+		Short: "Register an adapter from a JSON definition",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var definition map[string]interface{}
+			if err := readJSONFile(file, &definition); err != nil {
+				return err
+			}
+
+			var out interface{}
+			if err := opts.client().Post(cmd.Context(), "/api/v1/adapters", definition, &out); err != nil {
+				return err
+			}
+			return opts.printer(cmd).Object(out)
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Adapter definition file (JSON, - for stdin)")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
@@ -0,0 +1,293 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package commands
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func newBillingCommand(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "billing",
+		Short: "Billing operations (organizations, subscriptions, invoices, payments, usage)",
+	}
+
+	cmd.AddCommand(
+		newBillingOrganizationCommand(opts),
+		newBillingSubscriptionCommand(opts),
+		newBillingInvoiceCommand(opts),
+		newBillingPaymentCommand(opts),
+		newBillingUsageCommand(opts),
+	)
+
+	return cmd
+}
+
+func newBillingOrganizationCommand(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "organization",
+		Aliases: []string{"org"},
+		Short:   "Manage billing organizations",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get ID",
+		Short: "Show an organization",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var out interface{}
+			if err := opts.client().Get(cmd.Context(), "/api/v1/billing/organizations/"+url.PathEscape(args[0]), nil, &out); err != nil {
+				return err
+			}
+			return opts.printer(cmd).Object(out)
+		},
+	})
+
+	return cmd
+}
+
+func newBillingSubscriptionCommand(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "subscription",
+		Aliases: []string{"subscriptions", "sub"},
+		Short:   "Manage subscriptions",
+	}
+
+	var atPeriodEnd bool
+	cancel := &cobra.Command{
+		Use:   "cancel ID",
+		Short: "Cancel a subscription",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body := map[string]interface{}{"cancel_at_period_end": atPeriodEnd}
+			path := "/api/v1/billing/subscriptions/" + url.PathEscape(args[0]) + "/cancel"
+
+			var out interface{}
+			if err := opts.client().Post(cmd.Context(), path, body, &out); err != nil {
+				return err
+			}
+			opts.printer(cmd).Message("subscription %s canceled", args[0])
+			return nil
+		},
+	}
+	cancel.Flags().BoolVar(&atPeriodEnd, "at-period-end", true, "Cancel at the end of the current billing period")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "List subscriptions of the organization",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/billing/subscriptions", nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).List(out, "id", "plan_id", "status", "current_period_start", "current_period_end")
+			},
+		},
+		&cobra.Command{
+			Use:   "get ID",
+			Short: "Show a subscription",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/billing/subscriptions/"+url.PathEscape(args[0]), nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).Object(out)
+			},
+		},
+		cancel,
+	)
+
+	return cmd
+}
+
+func newBillingInvoiceCommand(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "invoice",
+		Aliases: []string{"invoices"},
+		Short:   "Manage invoices",
+	}
+
+	var limit, offset int
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List invoices of the organization",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			query := url.Values{}
+			query.Set("limit", strconv.Itoa(limit))
+			query.Set("offset", strconv.Itoa(offset))
+
+			var out interface{}
+			if err := opts.client().Get(cmd.Context(), "/api/v1/billing/invoices", query, &out); err != nil {
+				return err
+			}
+			return opts.printer(cmd).List(out, "invoice_number", "status", "total_amount", "amount_due", "currency", "due_date")
+		},
+	}
+	list.Flags().IntVar(&limit, "limit", 20, "Maximum number of invoices")
+	list.Flags().IntVar(&offset, "offset", 0, "Number of invoices to skip")
+
+	var output string
+	pdf := &cobra.Command{
+		Use:   "pdf ID",
+		Short: "Download an invoice PDF",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output == "" {
+				output = args[0] + ".pdf"
+			}
+			f, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", output, err)
+			}
+			defer f.Close()
+
+			path := "/api/v1/billing/invoices/" + url.PathEscape(args[0]) + "/pdf"
+			if err := opts.client().Download(cmd.Context(), path, f); err != nil {
+				return err
+			}
+			opts.printer(cmd).Message("invoice saved to %s", output)
+			return nil
+		},
+	}
+	pdf.Flags().StringVar(&output, "out", "", "Output file (default <ID>.pdf)")
+
+	cmd.AddCommand(
+		list,
+		&cobra.Command{
+			Use:   "get ID",
+			Short: "Show an invoice",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/billing/invoices/"+url.PathEscape(args[0]), nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).Object(out)
+			},
+		},
+		&cobra.Command{
+			Use:   "upcoming",
+			Short: "Preview the upcoming invoice",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/billing/invoices/upcoming", nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).Object(out)
+			},
+		},
+		&cobra.Command{
+			Use:   "pay ID",
+			Short: "Pay an open invoice with the default payment method",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var out interface{}
+				path := "/api/v1/billing/invoices/" + url.PathEscape(args[0]) + "/pay"
+				if err := opts.client().Post(cmd.Context(), path, nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).Object(out)
+			},
+		},
+		pdf,
+	)
+
+	return cmd
+}
+
+func newBillingPaymentCommand(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "payment",
+		Aliases: []string{"payments"},
+		Short:   "Inspect payments",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "List payments of the organization",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/billing/payments", nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).List(out, "id", "invoice_id", "amount", "currency", "status", "provider", "created_at")
+			},
+		},
+		&cobra.Command{
+			Use:   "get ID",
+			Short: "Show a payment",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/billing/payments/"+url.PathEscape(args[0]), nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).Object(out)
+			},
+		},
+	)
+
+	return cmd
+}
+
+func newBillingUsageCommand(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Inspect usage metrics",
+	}
+
+	var from, to string
+	history := &cobra.Command{
+		Use:   "history",
+		Short: "Show usage history",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			query := url.Values{}
+			if from != "" {
+				query.Set("from", from)
+			}
+			if to != "" {
+				query.Set("to", to)
+			}
+
+			var out interface{}
+			if err := opts.client().Get(cmd.Context(), "/api/v1/billing/usage/history", query, &out); err != nil {
+				return err
+			}
+			return opts.printer(cmd).List(out, "metric_type", "quantity", "unit", "period_start", "period_end")
+		},
+	}
+	history.Flags().StringVar(&from, "from", "", "Start date (YYYY-MM-DD)")
+	history.Flags().StringVar(&to, "to", "", "End date (YYYY-MM-DD)")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "current",
+			Short: "Show usage for the current billing period",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/billing/usage/current", nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).Object(out)
+			},
+		},
+		history,
+	)
+
+	return cmd
+}
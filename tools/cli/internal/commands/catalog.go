@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package commands
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func newCatalogCommand(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "catalog",
+		Short: "Query the metadata catalog",
+	}
+
+	var (
+		entityType string
+		source     string
+		limit      int
+	)
+	entities := &cobra.Command{
+		Use:   "entities",
+		Short: "List catalog entities",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			query := url.Values{}
+			if entityType != "" {
+				query.Set("type", entityType)
+			}
+			if source != "" {
+				query.Set("source", source)
+			}
+			query.Set("limit", strconv.Itoa(limit))
+
+			var out interface{}
+			if err := opts.client().Get(cmd.Context(), "/api/v1/entities", query, &out); err != nil {
+				return err
+			}
+			return opts.printer(cmd).List(out, "id", "entity_type", "domain", "source_system", "source_entity_id", "status")
+		},
+	}
+	entities.Flags().StringVar(&entityType, "type", "", "Filter by entity type")
+	entities.Flags().StringVar(&source, "source", "", "Filter by source system")
+	entities.Flags().IntVar(&limit, "limit", 50, "Maximum number of entities")
+
+	var searchLimit int
+	search := &cobra.Command{
+		Use:   "search QUERY",
+		Short: "Search catalog entities",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{}
+			query.Set("q", args[0])
+			query.Set("limit", strconv.Itoa(searchLimit))
+
+			var out interface{}
+			if err := opts.client().Get(cmd.Context(), "/api/v1/entities/search", query, &out); err != nil {
+				return err
+			}
+			return opts.printer(cmd).List(out, "id", "entity_type", "source_system", "source_entity_id", "score")
+		},
+	}
+	search.Flags().IntVar(&searchLimit, "limit", 20, "Maximum number of results")
+
+	cmd.AddCommand(
+		entities,
+		&cobra.Command{
+			Use:   "get TYPE ID",
+			Short: "Show a catalog entity",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var out interface{}
+				path := "/api/v1/entities/" + url.PathEscape(args[0]) + "/" + url.PathEscape(args[1])
+				if err := opts.client().Get(cmd.Context(), path, nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).Object(out)
+			},
+		},
+		search,
+		&cobra.Command{
+			Use:   "relationships TYPE ID",
+			Short: "List relationships of a catalog entity",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var out interface{}
+				path := "/api/v1/entities/" + url.PathEscape(args[0]) + "/" + url.PathEscape(args[1]) + "/relationships"
+				if err := opts.client().Get(cmd.Context(), path, nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).List(out, "relationship_type", "object_entity_type", "object_entity_id", "valid_from")
+			},
+		},
+		&cobra.Command{
+			Use:   "schemas",
+			Short: "List registered entity schemas",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/schemas", nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).List(out, "entity_type", "version", "schema_format", "published_at")
+			},
+		},
+	)
+
+	return cmd
+}
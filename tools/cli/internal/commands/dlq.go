@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package commands
+
+import (
+	"net/url"
+
+	"github.com/spf13/cobra"
+)
+
+func newDLQCommand(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dlq",
+		Short: "Inspect and replay dead-letter topics",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "List dead-letter topics with the messages not yet replayed",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				var out struct {
+					Queues interface{} `json:"queues"`
+				}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/events/dlq", nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).List(out.Queues, "topic", "depth", "retained")
+			},
+		},
+		newDLQReplayCommand(opts),
+	)
+
+	return cmd
+}
+
+func newDLQReplayCommand(opts *globalOptions) *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "replay TOPIC",
+		Short: "Publish dead-lettered messages back to the topics they failed on",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body := map[string]interface{}{"limit": limit}
+
+			var out struct {
+				Replayed  int   `json:"replayed"`
+				Remaining int64 `json:"remaining"`
+			}
+			path := "/api/v1/events/dlq/" + url.PathEscape(args[0]) + "/replay"
+			if err := opts.client().Post(cmd.Context(), path, body, &out); err != nil {
+				return err
+			}
+			opts.printer(cmd).Message("%d messages of %s replayed, %d remaining", out.Replayed, args[0], out.Remaining)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 0, "Maximum number of messages to replay (0 for the server maximum)")
+
+	return cmd
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// newMigrateCommand manages schema migrations through the metadata catalog
+// admin API, which runs them with the service's own database credentials
+func newMigrateCommand(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "migrate",
+		Aliases: []string{"migrations"},
+		Short:   "Manage database schema migrations",
+	}
+
+	var steps int
+	down := &cobra.Command{
+		Use:   "down",
+		Short: "Roll back migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if steps <= 0 {
+				return fmt.Errorf("--steps must be positive")
+			}
+
+			var out interface{}
+			body := map[string]interface{}{"steps": steps}
+			if err := opts.client().Post(cmd.Context(), "/api/v1/admin/migrations/down", body, &out); err != nil {
+				return err
+			}
+			return opts.printer(cmd).Object(out)
+		},
+	}
+	down.Flags().IntVar(&steps, "steps", 1, "Number of migrations to roll back")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "status",
+			Short: "Show the current schema version",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/admin/migrations", nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).Object(out)
+			},
+		},
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply all pending migrations",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				var out interface{}
+				if err := opts.client().Post(cmd.Context(), "/api/v1/admin/migrations/up", nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).Object(out)
+			},
+		},
+		down,
+	)
+
+	return cmd
+}
@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package commands
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newNotifyCommand(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "notify",
+		Aliases: []string{"notifications"},
+		Short:   "Send test notifications and inspect templates",
+	}
+
+	cmd.AddCommand(
+		newNotifyTestCommand(opts),
+		&cobra.Command{
+			Use:   "templates",
+			Short: "List notification templates",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/templates", nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).List(out, "code", "name", "category", "version", "active")
+			},
+		},
+		&cobra.Command{
+			Use:   "status ID",
+			Short: "Show delivery status of a notification",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/notifications/"+url.PathEscape(args[0]), nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).Object(out)
+			},
+		},
+	)
+
+	return cmd
+}
+
+func newNotifyTestCommand(opts *globalOptions) *cobra.Command {
+	var (
+		recipientID   string
+		recipientType string
+		template      string
+		channels      []string
+		priority      string
+		data          []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Send a test notification",
+		Example: `  dictameshctl notify test --recipient user-123 --template invoice.created --channel EMAIL \
+    --data InvoiceNumber=INV-000001 --data Total=10.00`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			payload := make(map[string]interface{}, len(data))
+			for _, d := range data {
+				key, value, ok := strings.Cut(d, "=")
+				if !ok {
+					return fmt.Errorf("invalid --data %q, expected key=value", d)
+				}
+				payload[key] = value
+			}
+			payload["test"] = true
+
+			upper := make([]string, len(channels))
+			for i, c := range channels {
+				upper[i] = strings.ToUpper(c)
+			}
+
+			body := map[string]interface{}{
+				"recipient_id":   recipientID,
+				"recipient_type": strings.ToUpper(recipientType),
+				"template_code":  template,
+				"channels":       upper,
+				"priority":       strings.ToUpper(priority),
+				"data":           payload,
+			}
+
+			var out interface{}
+			if err := opts.client().Post(cmd.Context(), "/api/v1/notifications", body, &out); err != nil {
+				return err
+			}
+			return opts.printer(cmd).Object(out)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&recipientID, "recipient", "", "Recipient ID")
+	flags.StringVar(&recipientType, "recipient-type", "USER", "Recipient type: USER | ROLE | GROUP | SYSTEM")
+	flags.StringVar(&template, "template", "", "Template code")
+	flags.StringSliceVar(&channels, "channel", []string{"EMAIL"}, "Delivery channels (repeatable)")
+	flags.StringVar(&priority, "priority", "NORMAL", "Priority: CRITICAL | HIGH | NORMAL | LOW")
+	flags.StringArrayVar(&data, "data", nil, "Template data (key=value, repeatable)")
+	_ = cmd.MarkFlagRequired("recipient")
+	_ = cmd.MarkFlagRequired("template")
+
+	return cmd
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// printer renders API responses as tables or JSON
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// List prints a list response. Responses may be a JSON array or an object
+// wrapping the array in "data" or "items". columns selects the table columns.
+func (p *printer) List(data interface{}, columns ...string) error {
+	if p.format == "json" {
+		return p.JSON(data)
+	}
+
+	rows := unwrapList(data)
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = strings.ToUpper(c)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, row := range rows {
+		obj, _ := row.(map[string]interface{})
+		values := make([]string, len(columns))
+		for i, c := range columns {
+			values[i] = formatValue(obj[c])
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// Object prints a single object as key/value pairs
+func (p *printer) Object(data interface{}) error {
+	if p.format == "json" {
+		return p.JSON(data)
+	}
+
+	obj, ok := data.(map[string]interface{})
+	if !ok {
+		return p.JSON(data)
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s:\t%s\n", k, formatValue(obj[k]))
+	}
+	return tw.Flush()
+}
+
+// JSON prints data as indented JSON
+func (p *printer) JSON(data interface{}) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+// Message prints a status message
+func (p *printer) Message(format string, args ...interface{}) {
+	if p.format == "json" {
+		return
+	}
+	fmt.Fprintf(p.w, format+"\n", args...)
+}
+
+func unwrapList(data interface{}) []interface{} {
+	switch v := data.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		for _, key := range []string{"data", "items"} {
+			if list, ok := v[key].([]interface{}); ok {
+				return list
+			}
+		}
+	}
+	return nil
+}
+
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "-"
+	case string:
+		return val
+	case float64:
+		if val == float64(int64(val)) {
+			return fmt.Sprintf("%d", int64(val))
+		}
+		return fmt.Sprintf("%g", val)
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(val)
+		return string(data)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// readJSONFile decodes a JSON file ("-" reads stdin)
+func readJSONFile(path string, out interface{}) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if err := json.NewDecoder(r).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
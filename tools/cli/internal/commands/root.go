@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+// Package commands implements the dictameshctl command tree
+package commands
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/click2-run/dictamesh/tools/cli/internal/client"
+)
+
+// globalOptions holds flags shared by every command
+type globalOptions struct {
+	server         string
+	token          string
+	organizationID string
+	output         string
+	timeout        time.Duration
+}
+
+// NewRootCommand creates the dictameshctl root command
+func NewRootCommand() *cobra.Command {
+	opts := &globalOptions{}
+
+	root := &cobra.Command{
+		Use:   "dictameshctl",
+		Short: "Administrative CLI for DictaMesh",
+		Long: `dictameshctl manages a DictaMesh deployment through its service APIs:
+adapters, event topics, billing, notifications, migrations and the metadata catalog.`,
+		SilenceUsage: true,
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.server, "server", getEnv("DICTAMESH_API_URL", "http://localhost:8080"), "API gateway URL (env DICTAMESH_API_URL)")
+	flags.StringVar(&opts.token, "token", os.Getenv("DICTAMESH_API_TOKEN"), "API token (env DICTAMESH_API_TOKEN)")
+	flags.StringVar(&opts.organizationID, "org", os.Getenv("DICTAMESH_ORGANIZATION_ID"), "Organization to act on behalf of (env DICTAMESH_ORGANIZATION_ID)")
+	flags.StringVarP(&opts.output, "output", "o", "table", "Output format: table | json")
+	flags.DurationVar(&opts.timeout, "timeout", 30*time.Second, "Request timeout")
+
+	root.AddCommand(
+		newAdapterCommand(opts),
+		newTopicCommand(opts),
+		newDLQCommand(opts),
+		newBillingCommand(opts),
+		newNotifyCommand(opts),
+		newMigrateCommand(opts),
+		newCatalogCommand(opts),
+	)
+
+	return root
+}
+
+// client creates an API client from the global flags
+func (o *globalOptions) client() *client.Client {
+	return client.New(client.Config{
+		BaseURL:        o.server,
+		Token:          o.token,
+		OrganizationID: o.organizationID,
+		Timeout:        o.timeout,
+	})
+}
+
+// printer creates an output printer from the global flags
+func (o *globalOptions) printer(cmd *cobra.Command) *printer {
+	return &printer{w: cmd.OutOrStdout(), format: o.output}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (C) 2025 Controle Digital Ltda
+
+package commands
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newTopicCommand(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "topic",
+		Aliases: []string{"topics"},
+		Short:   "Manage event topics",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "List topics",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/topics", nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).List(out, "name", "partitions", "replication_factor", "retention_ms")
+			},
+		},
+		&cobra.Command{
+			Use:   "describe NAME",
+			Short: "Describe a topic, including partition offsets and consumer lag",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var out interface{}
+				if err := opts.client().Get(cmd.Context(), "/api/v1/topics/"+url.PathEscape(args[0]), nil, &out); err != nil {
+					return err
+				}
+				return opts.printer(cmd).Object(out)
+			},
+		},
+		newTopicCreateCommand(opts),
+		newTopicDeleteCommand(opts),
+	)
+
+	return cmd
+}
+
+func newTopicCreateCommand(opts *globalOptions) *cobra.Command {
+	var (
+		partitions        int
+		replicationFactor int
+		configs           []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a topic",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			topicConfig := make(map[string]string, len(configs))
+			for _, c := range configs {
+				key, value, ok := strings.Cut(c, "=")
+				if !ok {
+					return fmt.Errorf("invalid --config %q, expected key=value", c)
+				}
+				topicConfig[key] = value
+			}
+
+			body := map[string]interface{}{
+				"name":               args[0],
+				"partitions":         partitions,
+				"replication_factor": replicationFactor,
+				"config":             topicConfig,
+			}
+
+			var out interface{}
+			if err := opts.client().Post(cmd.Context(), "/api/v1/topics", body, &out); err != nil {
+				return err
+			}
+			opts.printer(cmd).Message("topic %s created", args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&partitions, "partitions", 6, "Number of partitions")
+	cmd.Flags().IntVar(&replicationFactor, "replication-factor", 3, "Replication factor")
+	cmd.Flags().StringSliceVar(&configs, "config", nil, "Topic config entries (key=value, repeatable)")
+
+	return cmd
+}
+
+func newTopicDeleteCommand(opts *globalOptions) *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a topic",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !yes {
+				return fmt.Errorf("deleting topic %s is irreversible; pass --yes to confirm", args[0])
+			}
+			if err := opts.client().Delete(cmd.Context(), "/api/v1/topics/"+url.PathEscape(args[0])); err != nil {
+				return err
+			}
+			opts.printer(cmd).Message("topic %s deleted", args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&yes, "yes", false, "Confirm deletion")
+
+	return cmd
+}